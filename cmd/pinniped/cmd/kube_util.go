@@ -7,6 +7,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	conciergeclientset "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned"
+	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/kubeclient"
 )
@@ -31,6 +32,26 @@ func getRealConciergeClientset(clientConfig clientcmd.ClientConfig, apiGroupSuff
 	return client.PinnipedConcierge, nil
 }
 
+// getSupervisorClientsetFunc is a function that can return a clientset for the Supervisor API given a
+// clientConfig and the apiGroupSuffix with which the API is running.
+type getSupervisorClientsetFunc func(clientConfig clientcmd.ClientConfig, apiGroupSuffix string) (supervisorclientset.Interface, error)
+
+// getRealSupervisorClientset returns a real implementation of a supervisorclientset.Interface.
+func getRealSupervisorClientset(clientConfig clientcmd.ClientConfig, apiGroupSuffix string) (supervisorclientset.Interface, error) {
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubeclient.New(
+		kubeclient.WithConfig(restConfig),
+		kubeclient.WithMiddleware(groupsuffix.New(apiGroupSuffix)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return client.PinnipedSupervisor, nil
+}
+
 // newClientConfig returns a clientcmd.ClientConfig given an optional kubeconfig path override and
 // an optional context override.
 func newClientConfig(kubeconfigPathOverride string, currentContextName string) clientcmd.ClientConfig {
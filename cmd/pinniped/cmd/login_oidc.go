@@ -26,6 +26,7 @@ import (
 	"go.pinniped.dev/internal/execcredcache"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tracing"
 	"go.pinniped.dev/pkg/conciergeclient"
 	"go.pinniped.dev/pkg/oidcclient"
 	"go.pinniped.dev/pkg/oidcclient/filesession"
@@ -41,6 +42,7 @@ type oidcLoginCommandDeps struct {
 	lookupEnv     func(string) (string, bool)
 	login         func(string, string, ...oidcclient.Option) (*oidctypes.Token, error)
 	exchangeToken func(context.Context, *conciergeclient.Client, string) (*clientauthv1beta1.ExecCredential, error)
+	newTrace      func(context.Context) (context.Context, string, error)
 }
 
 func oidcLoginCommandRealDeps() oidcLoginCommandDeps {
@@ -50,6 +52,7 @@ func oidcLoginCommandRealDeps() oidcLoginCommandDeps {
 		exchangeToken: func(ctx context.Context, client *conciergeclient.Client, token string) (*clientauthv1beta1.ExecCredential, error) {
 			return client.ExchangeToken(ctx, token)
 		},
+		newTrace: tracing.WithNewTrace,
 	}
 }
 
@@ -139,9 +142,15 @@ func runOIDCLogin(cmd *cobra.Command, deps oidcLoginCommandDeps, flags oidcLogin
 	}
 	sessionCache := filesession.New(flags.sessionCachePath, sessionOptions...)
 
+	// Start a trace for the requests of this login, so they can be found in the Supervisor's traces.
+	ctx, traceID, err := deps.newTrace(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("could not start trace: %w", err)
+	}
+
 	// Initialize the login handler.
 	opts := []oidcclient.Option{
-		oidcclient.WithContext(cmd.Context()),
+		oidcclient.WithContext(ctx),
 		oidcclient.WithLogger(klogr.New()),
 		oidcclient.WithScopes(flags.scopes),
 		oidcclient.WithSessionCache(sessionCache),
@@ -220,7 +229,7 @@ func runOIDCLogin(cmd *cobra.Command, deps oidcLoginCommandDeps, flags oidcLogin
 		}
 	}
 
-	pLogger.Debug("Performing OIDC login", "issuer", flags.issuer, "client id", flags.clientID)
+	pLogger.Debug("Performing OIDC login", "issuer", flags.issuer, "client id", flags.clientID, "trace id", traceID)
 	// Do the basic login to get an OIDC token.
 	token, err := deps.login(flags.issuer, flags.clientID, opts...)
 	if err != nil {
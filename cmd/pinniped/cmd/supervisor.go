@@ -0,0 +1,21 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+//nolint: gochecknoglobals
+var supervisorCmd = &cobra.Command{
+	Use:          "supervisor",
+	Short:        "supervisor",
+	Long:         "Administer a Pinniped Supervisor",
+	SilenceUsage: true, // do not print usage message when commands fail
+}
+
+//nolint: gochecknoinits
+func init() {
+	rootCmd.AddCommand(supervisorCmd)
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	sessionv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/session/v1alpha1"
+	"go.pinniped.dev/internal/groupsuffix"
+	supervisorscheme "go.pinniped.dev/internal/supervisor/scheme"
+)
+
+//nolint: gochecknoinits
+func init() {
+	supervisorCmd.AddCommand(newSupervisorSessionsCommand(getRealSupervisorClientset))
+}
+
+type supervisorSessionsFlags struct {
+	kubeconfigPath            string
+	kubeconfigContextOverride string
+
+	apiGroupSuffix string
+}
+
+type supervisorSessionsListFlags struct {
+	outputFormat string // e.g., yaml, json, text
+
+	username string
+	group    string
+}
+
+func newSupervisorSessionsCommand(getClientset getSupervisorClientsetFunc) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:         cobra.NoArgs,
+		Use:          "sessions",
+		Short:        "List and revoke end user sessions of a Pinniped Supervisor",
+		SilenceUsage: true,
+	}
+	flags := &supervisorSessionsFlags{}
+
+	f := cmd.PersistentFlags()
+	f.StringVar(&flags.kubeconfigPath, "kubeconfig", os.Getenv("KUBECONFIG"), "Path to kubeconfig file")
+	f.StringVar(&flags.kubeconfigContextOverride, "kubeconfig-context", "", "Kubeconfig context name (default: current active context)")
+	f.StringVar(&flags.apiGroupSuffix, "api-group-suffix", groupsuffix.PinnipedDefaultSuffix, "Supervisor API group suffix")
+
+	cmd.AddCommand(
+		newSupervisorSessionsListCommand(getClientset, flags),
+		newSupervisorSessionsRevokeCommand(getClientset, flags),
+	)
+
+	return cmd
+}
+
+func newSupervisorSessionsListCommand(getClientset getSupervisorClientsetFunc, sessionsFlags *supervisorSessionsFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:         cobra.NoArgs,
+		Use:          "list",
+		Short:        "List active sessions",
+		SilenceUsage: true,
+	}
+	flags := &supervisorSessionsListFlags{}
+
+	f := cmd.Flags()
+	f.StringVarP(&flags.outputFormat, "output", "o", "text", "Output format (e.g., 'yaml', 'json', 'text')")
+	f.StringVar(&flags.username, "username", "", "Only list sessions of the user with this downstream username")
+	f.StringVar(&flags.group, "group", "", "Only list sessions of users who are members of this downstream group")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		return runSupervisorSessionsList(cmd.OutOrStdout(), getClientset, sessionsFlags, flags)
+	}
+
+	return cmd
+}
+
+func newSupervisorSessionsRevokeCommand(getClientset getSupervisorClientsetFunc, sessionsFlags *supervisorSessionsFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:         cobra.ExactArgs(1),
+		Use:          "revoke SESSION_ID",
+		Short:        "Revoke a session, which invalidates all of its access and refresh tokens",
+		SilenceUsage: true,
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runSupervisorSessionsRevoke(cmd.OutOrStdout(), getClientset, sessionsFlags, args[0])
+	}
+
+	return cmd
+}
+
+func runSupervisorSessionsList(output io.Writer, getClientset getSupervisorClientsetFunc, sessionsFlags *supervisorSessionsFlags, flags *supervisorSessionsListFlags) error {
+	switch flags.outputFormat {
+	case "text", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format: %q", flags.outputFormat)
+	}
+
+	clientset, err := getClientset(newClientConfig(sessionsFlags.kubeconfigPath, sessionsFlags.kubeconfigContextOverride), sessionsFlags.apiGroupSuffix)
+	if err != nil {
+		return fmt.Errorf("could not configure Kubernetes client: %w", err)
+	}
+
+	fieldSet := fields.Set{}
+	if flags.username != "" {
+		fieldSet[sessionv1alpha1.UsernameFieldLabel] = flags.username
+	}
+	if flags.group != "" {
+		fieldSet[sessionv1alpha1.GroupsFieldLabel] = flags.group
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancelFunc()
+	list, err := clientset.SessionV1alpha1().SupervisorSessions().List(ctx, metav1.ListOptions{
+		FieldSelector: fieldSet.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list sessions: %w", err)
+	}
+
+	switch flags.outputFormat {
+	case "json":
+		return serializeSupervisorSessions(output, sessionsFlags.apiGroupSuffix, list, runtime.ContentTypeJSON)
+	case "yaml":
+		return serializeSupervisorSessions(output, sessionsFlags.apiGroupSuffix, list, runtime.ContentTypeYAML)
+	default:
+		return writeSessionsText(output, list)
+	}
+}
+
+func serializeSupervisorSessions(output io.Writer, apiGroupSuffix string, list *sessionv1alpha1.SupervisorSessionList, contentType string) error {
+	scheme, sessionGV := supervisorscheme.New(apiGroupSuffix)
+	codecs := serializer.NewCodecFactory(scheme)
+	respInfo, ok := runtime.SerializerInfoForMediaType(codecs.SupportedMediaTypes(), contentType)
+	if !ok {
+		return fmt.Errorf("unknown content type: %q", contentType)
+	}
+
+	serializer := respInfo.PrettySerializer
+	if serializer == nil {
+		serializer = respInfo.Serializer
+	}
+
+	// Ensure that these fields are set so that the JSON/YAML output tells the full story.
+	list.APIVersion = sessionGV.String()
+	list.Kind = "SupervisorSessionList"
+	for i := range list.Items {
+		list.Items[i].APIVersion = sessionGV.String()
+		list.Items[i].Kind = "SupervisorSession"
+	}
+
+	return serializer.Encode(list, output)
+}
+
+func writeSessionsText(output io.Writer, list *sessionv1alpha1.SupervisorSessionList) error {
+	if len(list.Items) == 0 {
+		_, err := fmt.Fprintln(output, "No sessions found.")
+		return err
+	}
+
+	w := tabwriter.NewWriter(output, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tGROUPS\tIDENTITY PROVIDER\tCLIENT\tISSUED AT\tEXPIRES AT")
+	for _, s := range list.Items {
+		provider := s.Status.IdentityProvider.Name
+		if s.Status.IdentityProvider.Type != "" {
+			provider = fmt.Sprintf("%s (%s)", s.Status.IdentityProvider.Name, s.Status.IdentityProvider.Type)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Name, s.Status.Username, prettyStrings(s.Status.Groups), provider, s.Status.ClientID,
+			s.Status.IssuedAt.UTC().Format(time.RFC3339), s.Status.ExpiresAt.UTC().Format(time.RFC3339),
+		)
+	}
+	return w.Flush()
+}
+
+func runSupervisorSessionsRevoke(output io.Writer, getClientset getSupervisorClientsetFunc, sessionsFlags *supervisorSessionsFlags, id string) error {
+	clientset, err := getClientset(newClientConfig(sessionsFlags.kubeconfigPath, sessionsFlags.kubeconfigContextOverride), sessionsFlags.apiGroupSuffix)
+	if err != nil {
+		return fmt.Errorf("could not configure Kubernetes client: %w", err)
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancelFunc()
+	if err := clientset.SessionV1alpha1().SupervisorSessions().Delete(ctx, id, metav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not revoke session: %w (was it already revoked or expired?)", err)
+		}
+		return fmt.Errorf("could not revoke session: %w", err)
+	}
+
+	_, err = fmt.Fprintf(output, "Session %s revoked.\n", id)
+	return err
+}
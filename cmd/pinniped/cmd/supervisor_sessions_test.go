@@ -0,0 +1,267 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+
+	sessionv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/session/v1alpha1"
+	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	fakesupervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/here"
+)
+
+func TestSupervisorSessions(t *testing.T) {
+	issuedAt := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	alice := &sessionv1alpha1.SupervisorSession{
+		ObjectMeta: metav1.ObjectMeta{Name: "request-1", CreationTimestamp: metav1.NewTime(issuedAt)},
+		Status: sessionv1alpha1.SupervisorSessionStatus{
+			Username:         "alice",
+			Groups:           []string{"admins", "devs"},
+			Subject:          "https://upstream?sub=alice",
+			IdentityProvider: sessionv1alpha1.SupervisorSessionIdentityProvider{Name: "my-ldap-idp", Type: "ldap"},
+			ClientID:         "pinniped-cli",
+			IssuedAt:         metav1.NewTime(issuedAt),
+			ExpiresAt:        metav1.NewTime(issuedAt.Add(9 * time.Hour)),
+		},
+	}
+	bob := &sessionv1alpha1.SupervisorSession{
+		ObjectMeta: metav1.ObjectMeta{Name: "request-2", CreationTimestamp: metav1.NewTime(issuedAt.Add(5 * time.Minute))},
+		Status: sessionv1alpha1.SupervisorSessionStatus{
+			Username:         "bob",
+			Groups:           []string{"devs"},
+			Subject:          "https://upstream?sub=bob",
+			IdentityProvider: sessionv1alpha1.SupervisorSessionIdentityProvider{Name: "my-oidc-idp", Type: "oidc"},
+			ClientID:         "pinniped-cli",
+			IssuedAt:         metav1.NewTime(issuedAt.Add(5 * time.Minute)),
+			ExpiresAt:        metav1.NewTime(issuedAt.Add(9 * time.Hour)),
+		},
+	}
+
+	tests := []struct {
+		name                   string
+		args                   []string
+		gettingClientsetErr    error
+		listErr                error
+		listResult             []sessionv1alpha1.SupervisorSession
+		wantAPIGroupSuffix     string
+		wantFieldSelector      string
+		wantError              bool
+		wantStdout, wantStderr string
+		wantRemainingSessions  int
+	}{
+		{
+			name: "list help",
+			args: []string{"list", "--help"},
+			wantStdout: here.Doc(`
+				List active sessions
+
+				Usage:
+				  sessions list [flags]
+
+				Flags:
+				      --group string      Only list sessions of users who are members of this downstream group
+				  -h, --help              help for list
+				  -o, --output string     Output format (e.g., 'yaml', 'json', 'text') (default "text")
+				      --username string   Only list sessions of the user with this downstream username
+
+				Global Flags:
+				      --api-group-suffix string     Supervisor API group suffix (default "pinniped.dev")
+				      --kubeconfig string           Path to kubeconfig file
+				      --kubeconfig-context string   Kubeconfig context name (default: current active context)
+			`),
+			wantRemainingSessions: 2,
+		},
+		{
+			name:               "list as text",
+			args:               []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml"},
+			listResult:         []sessionv1alpha1.SupervisorSession{*alice, *bob},
+			wantAPIGroupSuffix: "pinniped.dev",
+			wantStdout: here.Doc(`
+				ID         USERNAME  GROUPS        IDENTITY PROVIDER   CLIENT        ISSUED AT             EXPIRES AT
+				request-1  alice     admins, devs  my-ldap-idp (ldap)  pinniped-cli  2021-06-01T12:00:00Z  2021-06-01T21:00:00Z
+				request-2  bob       devs          my-oidc-idp (oidc)  pinniped-cli  2021-06-01T12:05:00Z  2021-06-01T21:00:00Z
+			`),
+			wantRemainingSessions: 2,
+		},
+		{
+			name:               "list filtered by username as yaml with a custom API group suffix",
+			args:               []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml", "--username", "bob", "-o", "yaml", "--api-group-suffix", "tuna.io"},
+			listResult:         []sessionv1alpha1.SupervisorSession{*bob},
+			wantAPIGroupSuffix: "tuna.io",
+			wantFieldSelector:  "status.username=bob",
+			wantStdout: here.Doc(`
+				apiVersion: session.supervisor.tuna.io/v1alpha1
+				items:
+				- apiVersion: session.supervisor.tuna.io/v1alpha1
+				  kind: SupervisorSession
+				  metadata:
+				    creationTimestamp: "2021-06-01T12:05:00Z"
+				    name: request-2
+				  status:
+				    clientID: pinniped-cli
+				    expiresAt: "2021-06-01T21:00:00Z"
+				    groups:
+				    - devs
+				    identityProvider:
+				      name: my-oidc-idp
+				      type: oidc
+				    issuedAt: "2021-06-01T12:05:00Z"
+				    subject: https://upstream?sub=bob
+				    username: bob
+				kind: SupervisorSessionList
+				metadata: {}
+			`),
+			wantRemainingSessions: 2,
+		},
+		{
+			name:               "list filtered by username and group as json",
+			args:               []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml", "--username", "alice", "--group", "admins", "-o", "json"},
+			listResult:         []sessionv1alpha1.SupervisorSession{*alice},
+			wantAPIGroupSuffix: "pinniped.dev",
+			wantFieldSelector:  "status.groups=admins,status.username=alice",
+			wantStdout: here.Doc(`
+				{
+				  "kind": "SupervisorSessionList",
+				  "apiVersion": "session.supervisor.pinniped.dev/v1alpha1",
+				  "metadata": {`) +
+				// The empty metadata of the list is printed with a line which only contains whitespace.
+				"\n    \n" + here.Doc(`
+				  },
+				  "items": [
+				    {
+				      "kind": "SupervisorSession",
+				      "apiVersion": "session.supervisor.pinniped.dev/v1alpha1",
+				      "metadata": {
+				        "name": "request-1",
+				        "creationTimestamp": "2021-06-01T12:00:00Z"
+				      },
+				      "status": {
+				        "username": "alice",
+				        "groups": [
+				          "admins",
+				          "devs"
+				        ],
+				        "subject": "https://upstream?sub=alice",
+				        "identityProvider": {
+				          "name": "my-ldap-idp",
+				          "type": "ldap"
+				        },
+				        "clientID": "pinniped-cli",
+				        "issuedAt": "2021-06-01T12:00:00Z",
+				        "expiresAt": "2021-06-01T21:00:00Z"
+				      }
+				    }
+				  ]
+				}`),
+			wantRemainingSessions: 2,
+		},
+		{
+			name:                  "list filtered by group with no results",
+			args:                  []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml", "--group", "nobody"},
+			wantAPIGroupSuffix:    "pinniped.dev",
+			wantFieldSelector:     "status.groups=nobody",
+			wantStdout:            "No sessions found.\n",
+			wantRemainingSessions: 2,
+		},
+		{
+			name:                  "list with bad output format",
+			args:                  []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml", "-o", "bogus"},
+			wantError:             true,
+			wantStderr:            "Error: unknown output format: \"bogus\"\n",
+			wantRemainingSessions: 2,
+		},
+		{
+			name:                  "list fails",
+			args:                  []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml"},
+			listErr:               constable.Error("some list error"),
+			wantAPIGroupSuffix:    "pinniped.dev",
+			wantError:             true,
+			wantStderr:            "Error: could not list sessions: some list error\n",
+			wantRemainingSessions: 2,
+		},
+		{
+			name:                  "getting clientset fails",
+			args:                  []string{"list", "--kubeconfig", "testdata/kubeconfig.yaml"},
+			gettingClientsetErr:   constable.Error("some get clientset error"),
+			wantAPIGroupSuffix:    "pinniped.dev",
+			wantError:             true,
+			wantStderr:            "Error: could not configure Kubernetes client: some get clientset error\n",
+			wantRemainingSessions: 2,
+		},
+		{
+			name:                  "revoke",
+			args:                  []string{"revoke", "request-1", "--kubeconfig", "testdata/kubeconfig.yaml"},
+			wantAPIGroupSuffix:    "pinniped.dev",
+			wantStdout:            "Session request-1 revoked.\n",
+			wantRemainingSessions: 1,
+		},
+		{
+			name:                  "revoke unknown session",
+			args:                  []string{"revoke", "request-3", "--kubeconfig", "testdata/kubeconfig.yaml"},
+			wantAPIGroupSuffix:    "pinniped.dev",
+			wantError:             true,
+			wantStderr:            "Error: could not revoke session: supervisorsessions.session.supervisor.pinniped.dev \"request-3\" not found (was it already revoked or expired?)\n",
+			wantRemainingSessions: 2,
+		},
+		{
+			name:                  "revoke requires an argument",
+			args:                  []string{"revoke", "--kubeconfig", "testdata/kubeconfig.yaml"},
+			wantError:             true,
+			wantStderr:            "Error: accepts 1 arg(s), received 0\n",
+			wantRemainingSessions: 2,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fakesupervisorclientset.NewSimpleClientset(alice.DeepCopy(), bob.DeepCopy())
+			clientset.PrependReactor("list", "supervisorsessions", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				require.Equal(t, test.wantFieldSelector, action.(kubetesting.ListAction).GetListRestrictions().Fields.String())
+				if test.listErr != nil {
+					return true, nil, test.listErr
+				}
+				return true, &sessionv1alpha1.SupervisorSessionList{Items: test.listResult}, nil
+			})
+
+			getClientset := func(clientConfig clientcmd.ClientConfig, apiGroupSuffix string) (supervisorclientset.Interface, error) {
+				require.Equal(t, test.wantAPIGroupSuffix, apiGroupSuffix)
+				if test.gettingClientsetErr != nil {
+					return nil, test.gettingClientsetErr
+				}
+				return clientset, nil
+			}
+			cmd := newSupervisorSessionsCommand(getClientset)
+			stdout, stderr := bytes.NewBuffer([]byte{}), bytes.NewBuffer([]byte{})
+			cmd.SetOut(stdout)
+			cmd.SetErr(stderr)
+			cmd.SetArgs(test.args)
+			err := cmd.Execute()
+			if test.wantError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.wantStdout, stdout.String())
+			require.Equal(t, test.wantStderr, stderr.String())
+
+			remaining, err := clientset.Tracker().List(
+				sessionv1alpha1.SchemeGroupVersion.WithResource("supervisorsessions"),
+				sessionv1alpha1.SchemeGroupVersion.WithKind("SupervisorSession"),
+				"",
+			)
+			require.NoError(t, err)
+			require.Len(t, remaining.(*sessionv1alpha1.SupervisorSessionList).Items, test.wantRemainingSessions)
+		})
+	}
+}
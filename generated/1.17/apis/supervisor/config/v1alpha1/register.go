@@ -32,6 +32,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&FederationDomain{},
 		&FederationDomainList{},
+		&ClusterAudience{},
+		&ClusterAudienceList{},
+		&TrustedCluster{},
+		&TrustedClusterList{},
+		&MachineClient{},
+		&MachineClientList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -41,6 +41,140 @@ type FederationDomainTLSSpec struct {
 	SecretName string `json:"secretName,omitempty"`
 }
 
+// FederationDomainIdentityProvidersSpec is a struct that describes how the upstream identity providers are offered
+// to end users of an OIDC Provider.
+type FederationDomainIdentityProvidersSpec struct {
+	// Hidden is an optional list of names of upstream identity providers which should not be listed by this
+	// FederationDomain, neither in its identity provider chooser page nor in its identity provider discovery endpoint.
+	// Hidden identity providers can still be used by clients which ask for them by name, e.g. by using a kubeconfig
+	// which was generated with the `--upstream-identity-provider-name` flag.
+	// +optional
+	// +listType=set
+	Hidden []string `json:"hidden,omitempty"`
+
+	// Default is the optional name of the upstream identity provider which should be used when an authorization
+	// request does not choose one. When there is more than one upstream identity provider and no default is
+	// configured, web browsers will be shown a page where the end user can choose an identity provider.
+	// When the named identity provider does not exist, then it is ignored.
+	// +optional
+	Default string `json:"default,omitempty"`
+}
+
+// FederationDomainTokenLifetimesSpec is a struct that describes the lifetimes of the tokens and sessions issued by
+// an OIDC Provider. Each lifetime is a duration string, e.g. "30m" or "4h".
+type FederationDomainTokenLifetimesSpec struct {
+	// IDToken is how long the ID tokens issued by the token endpoint are valid. Defaults to 2m.
+	// +optional
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	IDToken *metav1.Duration `json:"idToken,omitempty"`
+
+	// AccessToken is how long the access tokens issued by the token endpoint are valid. Defaults to 2m.
+	// +optional
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	AccessToken *metav1.Duration `json:"accessToken,omitempty"`
+
+	// RefreshToken is how long each refresh token issued by the token endpoint is valid. Every refresh issues
+	// a new refresh token, so an end user's session can continue for longer than this, until the AbsoluteSession
+	// lifetime is reached. Defaults to 9h.
+	// +optional
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	RefreshToken *metav1.Duration `json:"refreshToken,omitempty"`
+
+	// AbsoluteSession is how long an end user's session can last after they logged in with the upstream identity
+	// provider, regardless of how many times it is refreshed. After this, the end user must log in again.
+	// Defaults to 9h.
+	// +optional
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	AbsoluteSession *metav1.Duration `json:"absoluteSession,omitempty"`
+}
+
+// FederationDomainSigningKeyRotationSpec is a struct that describes how often an OIDC Provider replaces the key
+// that it uses to sign tokens. Each duration is a duration string, e.g. "30m" or "720h".
+type FederationDomainSigningKeyRotationSpec struct {
+	// KeyLifetime is how long each signing key is used to sign tokens before it is replaced by the next key.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	KeyLifetime metav1.Duration `json:"keyLifetime"`
+
+	// PrePublish is how long before the next signing key starts being used that it is published in the JWKS
+	// endpoint, so that clients which cache the JWKS will already know the next key when they first see a token
+	// which was signed by it.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	PrePublish metav1.Duration `json:"prePublish"`
+
+	// Retire is how long a signing key is still published in the JWKS endpoint after it was replaced, so that
+	// tokens which were signed by it can still be verified until they expire. It should be at least as long as
+	// the lifetime of the ID tokens issued by this FederationDomain.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	Retire metav1.Duration `json:"retire"`
+}
+
+// FederationDomainSignerSpec is a struct that describes a signing key which is kept outside of Kubernetes, e.g.
+// in a hardware security module, by one of the signers configured in the Supervisor's static configuration.
+type FederationDomainSignerSpec struct {
+	// Name is the name of a signer in the Supervisor's static configuration.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// KeyName identifies the signing key within the signer, e.g. the label of a key pair in a PKCS#11 token.
+	// +kubebuilder:validation:MinLength=1
+	KeyName string `json:"keyName"`
+}
+
+// FederationDomainSecretsSpec is a struct that names the Secrets which hold an OIDC Provider's keys, when they
+// are supplied by an administrator instead of being generated by the Supervisor, e.g. to restore the keys of
+// another cluster so that the tokens which it issued stay valid. Each Secret must be in the same namespace as the
+// FederationDomain and must have the same type and format as the Secret which the Supervisor would generate.
+// The Supervisor never updates or deletes these Secrets.
+type FederationDomainSecretsSpec struct {
+	// JWKS names the Secret, of type `secrets.pinniped.dev/federation-domain-jwks`, which holds the keys for
+	// signing and verifying ID tokens. It cannot be used together with Signer or SigningKeyRotation.
+	// +optional
+	JWKS corev1.LocalObjectReference `json:"jwks,omitempty"`
+
+	// TokenSigningKey names the Secret, of type `secrets.pinniped.dev/federation-domain-token-signing-key`,
+	// which holds the key for signing tokens.
+	// +optional
+	TokenSigningKey corev1.LocalObjectReference `json:"tokenSigningKey,omitempty"`
+
+	// StateSigningKey names the Secret, of type `secrets.pinniped.dev/federation-domain-state-signing-key`,
+	// which holds the key for signing state parameters.
+	// +optional
+	StateSigningKey corev1.LocalObjectReference `json:"stateSigningKey,omitempty"`
+
+	// StateEncryptionKey names the Secret, of type `secrets.pinniped.dev/federation-domain-state-encryption-key`,
+	// which holds the key for encrypting state parameters.
+	// +optional
+	StateEncryptionKey corev1.LocalObjectReference `json:"stateEncryptionKey,omitempty"`
+}
+
+// FederationDomainBrandingSpec is a struct that describes how the HTML pages which an OIDC Provider shows to end
+// users, e.g. its login page, are branded.
+type FederationDomainBrandingSpec struct {
+	// ConfigMap names a ConfigMap in the same namespace as the FederationDomain which holds the branding. All of
+	// its keys are optional:
+	//
+	// - `productName` is shown in the title and at the top of each page instead of "Pinniped".
+	//
+	// - `logo` is a PNG, JPEG, GIF, WebP or SVG image of at most 256 KiB, usually in `binaryData`, which is shown
+	// at the top of each page.
+	//
+	// - `primaryColor`, `backgroundColor` and `textColor` are CSS hex colors, e.g. `#1b3951`. The primary color is
+	// used for headings, buttons and links.
+	//
+	// - `supportURL` is an https or mailto URL which is linked at the bottom of each page, so that end users can
+	// get help.
+	//
+	// When the ConfigMap does not exist or is not valid, the pages use the default branding.
+	ConfigMap corev1.LocalObjectReference `json:"configMap"`
+}
+
 // FederationDomainSpec is a struct that describes an OIDC Provider.
 type FederationDomainSpec struct {
 	// Issuer is the OIDC Provider's issuer, per the OIDC Discovery Metadata document, as well as the
@@ -57,6 +191,39 @@ type FederationDomainSpec struct {
 	// TLS configures how this FederationDomain is served over Transport Layer Security (TLS).
 	// +optional
 	TLS *FederationDomainTLSSpec `json:"tls,omitempty"`
+
+	// IdentityProviders configures how the upstream identity providers are offered to end users by this
+	// FederationDomain.
+	// +optional
+	IdentityProviders *FederationDomainIdentityProvidersSpec `json:"identityProviders,omitempty"`
+
+	// TokenLifetimes optionally overrides the default lifetimes of the tokens and sessions issued by this
+	// FederationDomain.
+	// +optional
+	TokenLifetimes *FederationDomainTokenLifetimesSpec `json:"tokenLifetimes,omitempty"`
+
+	// SigningKeyRotation optionally configures this FederationDomain to regularly replace the key that it uses to
+	// sign tokens. When it is not configured, the same signing key is used until its Secret is deleted.
+	// +optional
+	SigningKeyRotation *FederationDomainSigningKeyRotationSpec `json:"signingKeyRotation,omitempty"`
+
+	// Signer optionally configures this FederationDomain to sign tokens with a key which is kept outside of
+	// Kubernetes. When it is not configured, the signing keys are generated by the Supervisor and stored in a
+	// Secret. Signer cannot be used together with SigningKeyRotation, since the keys of a signer are managed by
+	// the signer's operators.
+	// +optional
+	Signer *FederationDomainSignerSpec `json:"signer,omitempty"`
+
+	// Secrets optionally names Secrets which hold this FederationDomain's keys. Any keys which are not named
+	// here are generated by the Supervisor.
+	// +optional
+	Secrets *FederationDomainSecretsSpec `json:"secrets,omitempty"`
+
+	// Branding optionally customizes the HTML pages which this FederationDomain shows to end users, e.g. with
+	// a company's logo and colors. The text of the pages is translated according to each browser's preferred
+	// languages, regardless of the branding.
+	// +optional
+	Branding *FederationDomainBrandingSpec `json:"branding,omitempty"`
 }
 
 // FederationDomainSecrets holds information about this OIDC Provider's secrets.
@@ -83,6 +250,22 @@ type FederationDomainSecrets struct {
 	StateEncryptionKey corev1.LocalObjectReference `json:"stateEncryptionKey,omitempty"`
 }
 
+// FederationDomainTokenLifetimesStatus holds the lifetimes of the tokens and sessions which are in effect for an
+// OIDC Provider, including any defaults.
+type FederationDomainTokenLifetimesStatus struct {
+	// IDToken is how long the ID tokens issued by the token endpoint are valid.
+	IDToken metav1.Duration `json:"idToken"`
+
+	// AccessToken is how long the access tokens issued by the token endpoint are valid.
+	AccessToken metav1.Duration `json:"accessToken"`
+
+	// RefreshToken is how long each refresh token issued by the token endpoint is valid.
+	RefreshToken metav1.Duration `json:"refreshToken"`
+
+	// AbsoluteSession is how long an end user's session can last after they logged in.
+	AbsoluteSession metav1.Duration `json:"absoluteSession"`
+}
+
 // FederationDomainStatus is a struct that describes the actual state of an OIDC Provider.
 type FederationDomainStatus struct {
 	// Status holds an enum that describes the state of this OIDC Provider. Note that this Status can
@@ -103,12 +286,31 @@ type FederationDomainStatus struct {
 	// Secrets contains information about this OIDC Provider's secrets.
 	// +optional
 	Secrets FederationDomainSecrets `json:"secrets,omitempty"`
+
+	// TokenLifetimes holds the lifetimes of the tokens and sessions which are in effect for this OIDC Provider.
+	// It is only set when the Status is Success.
+	// +optional
+	TokenLifetimes *FederationDomainTokenLifetimesStatus `json:"tokenLifetimes,omitempty"`
+
+	// Conditions represent the observations of this OIDC Provider's current state. Each condition is written by
+	// the part of the Supervisor which is responsible for it, e.g. the conditions about the keys are written by
+	// the controllers which generate or load those keys. Status, Message and LastUpdateTime summarize the
+	// conditions about the spec, and are kept for compatibility.
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // FederationDomain describes the configuration of an OIDC provider.
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:resource:categories=pinniped
+// +kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.spec.issuer`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +kubebuilder:subresource:status
 type FederationDomain struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -8,9 +8,144 @@
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAudience) DeepCopyInto(out *ClusterAudience) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAudience.
+func (in *ClusterAudience) DeepCopy() *ClusterAudience {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAudience)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAudience) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAudienceList) DeepCopyInto(out *ClusterAudienceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterAudience, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAudienceList.
+func (in *ClusterAudienceList) DeepCopy() *ClusterAudienceList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAudienceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterAudienceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAudienceSpec) DeepCopyInto(out *ClusterAudienceSpec) {
+	*out = *in
+	if in.AllowedGroups != nil {
+		in, out := &in.AllowedGroups, &out.AllowedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedActorGroups != nil {
+		in, out := &in.AllowedActorGroups, &out.AllowedActorGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Claims != nil {
+		in, out := &in.Claims, &out.Claims
+		*out = make([]ClusterAudienceClaim, len(*in))
+		copy(*out, *in)
+	}
+	if in.GroupsFilter != nil {
+		in, out := &in.GroupsFilter, &out.GroupsFilter
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAudienceSpec.
+func (in *ClusterAudienceSpec) DeepCopy() *ClusterAudienceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAudienceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAudienceStatus) DeepCopyInto(out *ClusterAudienceStatus) {
+	*out = *in
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAudienceStatus.
+func (in *ClusterAudienceStatus) DeepCopy() *ClusterAudienceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAudienceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomain) DeepCopyInto(out *FederationDomain) {
 	*out = *in
@@ -39,6 +174,44 @@ func (in *FederationDomain) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainBrandingSpec) DeepCopyInto(out *FederationDomainBrandingSpec) {
+	*out = *in
+	out.ConfigMap = in.ConfigMap
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainBrandingSpec.
+func (in *FederationDomainBrandingSpec) DeepCopy() *FederationDomainBrandingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainBrandingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainIdentityProvidersSpec) DeepCopyInto(out *FederationDomainIdentityProvidersSpec) {
+	*out = *in
+	if in.Hidden != nil {
+		in, out := &in.Hidden, &out.Hidden
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainIdentityProvidersSpec.
+func (in *FederationDomainIdentityProvidersSpec) DeepCopy() *FederationDomainIdentityProvidersSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainIdentityProvidersSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainList) DeepCopyInto(out *FederationDomainList) {
 	*out = *in
@@ -92,6 +265,61 @@ func (in *FederationDomainSecrets) DeepCopy() *FederationDomainSecrets {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainSecretsSpec) DeepCopyInto(out *FederationDomainSecretsSpec) {
+	*out = *in
+	out.JWKS = in.JWKS
+	out.TokenSigningKey = in.TokenSigningKey
+	out.StateSigningKey = in.StateSigningKey
+	out.StateEncryptionKey = in.StateEncryptionKey
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainSecretsSpec.
+func (in *FederationDomainSecretsSpec) DeepCopy() *FederationDomainSecretsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainSecretsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainSignerSpec) DeepCopyInto(out *FederationDomainSignerSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainSignerSpec.
+func (in *FederationDomainSignerSpec) DeepCopy() *FederationDomainSignerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainSignerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainSigningKeyRotationSpec) DeepCopyInto(out *FederationDomainSigningKeyRotationSpec) {
+	*out = *in
+	out.KeyLifetime = in.KeyLifetime
+	out.PrePublish = in.PrePublish
+	out.Retire = in.Retire
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainSigningKeyRotationSpec.
+func (in *FederationDomainSigningKeyRotationSpec) DeepCopy() *FederationDomainSigningKeyRotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainSigningKeyRotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainSpec) DeepCopyInto(out *FederationDomainSpec) {
 	*out = *in
@@ -100,6 +328,36 @@ func (in *FederationDomainSpec) DeepCopyInto(out *FederationDomainSpec) {
 		*out = new(FederationDomainTLSSpec)
 		**out = **in
 	}
+	if in.IdentityProviders != nil {
+		in, out := &in.IdentityProviders, &out.IdentityProviders
+		*out = new(FederationDomainIdentityProvidersSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenLifetimes != nil {
+		in, out := &in.TokenLifetimes, &out.TokenLifetimes
+		*out = new(FederationDomainTokenLifetimesSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SigningKeyRotation != nil {
+		in, out := &in.SigningKeyRotation, &out.SigningKeyRotation
+		*out = new(FederationDomainSigningKeyRotationSpec)
+		**out = **in
+	}
+	if in.Signer != nil {
+		in, out := &in.Signer, &out.Signer
+		*out = new(FederationDomainSignerSpec)
+		**out = **in
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = new(FederationDomainSecretsSpec)
+		**out = **in
+	}
+	if in.Branding != nil {
+		in, out := &in.Branding, &out.Branding
+		*out = new(FederationDomainBrandingSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -121,6 +379,18 @@ func (in *FederationDomainStatus) DeepCopyInto(out *FederationDomainStatus) {
 		*out = (*in).DeepCopy()
 	}
 	out.Secrets = in.Secrets
+	if in.TokenLifetimes != nil {
+		in, out := &in.TokenLifetimes, &out.TokenLifetimes
+		*out = new(FederationDomainTokenLifetimesStatus)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -149,3 +419,279 @@ func (in *FederationDomainTLSSpec) DeepCopy() *FederationDomainTLSSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainTokenLifetimesSpec) DeepCopyInto(out *FederationDomainTokenLifetimesSpec) {
+	*out = *in
+	if in.IDToken != nil {
+		in, out := &in.IDToken, &out.IDToken
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AccessToken != nil {
+		in, out := &in.AccessToken, &out.AccessToken
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RefreshToken != nil {
+		in, out := &in.RefreshToken, &out.RefreshToken
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AbsoluteSession != nil {
+		in, out := &in.AbsoluteSession, &out.AbsoluteSession
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainTokenLifetimesSpec.
+func (in *FederationDomainTokenLifetimesSpec) DeepCopy() *FederationDomainTokenLifetimesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainTokenLifetimesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainTokenLifetimesStatus) DeepCopyInto(out *FederationDomainTokenLifetimesStatus) {
+	*out = *in
+	out.IDToken = in.IDToken
+	out.AccessToken = in.AccessToken
+	out.RefreshToken = in.RefreshToken
+	out.AbsoluteSession = in.AbsoluteSession
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainTokenLifetimesStatus.
+func (in *FederationDomainTokenLifetimesStatus) DeepCopy() *FederationDomainTokenLifetimesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainTokenLifetimesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineClient) DeepCopyInto(out *MachineClient) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineClient.
+func (in *MachineClient) DeepCopy() *MachineClient {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineClient)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MachineClient) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineClientList) DeepCopyInto(out *MachineClientList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MachineClient, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineClientList.
+func (in *MachineClientList) DeepCopy() *MachineClientList {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineClientList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MachineClientList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineClientSpec) DeepCopyInto(out *MachineClientSpec) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineClientSpec.
+func (in *MachineClientSpec) DeepCopy() *MachineClientSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineClientSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineClientStatus) DeepCopyInto(out *MachineClientStatus) {
+	*out = *in
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineClientStatus.
+func (in *MachineClientStatus) DeepCopy() *MachineClientStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineClientStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedCluster) DeepCopyInto(out *TrustedCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedCluster.
+func (in *TrustedCluster) DeepCopy() *TrustedCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TrustedCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedClusterList) DeepCopyInto(out *TrustedClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TrustedCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedClusterList.
+func (in *TrustedClusterList) DeepCopy() *TrustedClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TrustedClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedClusterSpec) DeepCopyInto(out *TrustedClusterSpec) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TrustedClusterTLSSpec)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedClusterSpec.
+func (in *TrustedClusterSpec) DeepCopy() *TrustedClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedClusterStatus) DeepCopyInto(out *TrustedClusterStatus) {
+	*out = *in
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedClusterStatus.
+func (in *TrustedClusterStatus) DeepCopy() *TrustedClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedClusterTLSSpec) DeepCopyInto(out *TrustedClusterTLSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedClusterTLSSpec.
+func (in *TrustedClusterTLSSpec) DeepCopy() *TrustedClusterTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedClusterTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
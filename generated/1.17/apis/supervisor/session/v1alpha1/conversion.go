@@ -0,0 +1,31 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The fields by which SupervisorSessions can be selected, in addition to metadata.name. A SupervisorSession matches
+// GroupsFieldLabel when the value is one of its groups.
+const (
+	UsernameFieldLabel = "status.username"
+	GroupsFieldLabel   = "status.groups"
+)
+
+func addFieldLabelConversionFuncs(scheme *runtime.Scheme) error {
+	return scheme.AddFieldLabelConversionFunc(SchemeGroupVersion.WithKind("SupervisorSession"), SupervisorSessionFieldLabelConversionFunc)
+}
+
+// SupervisorSessionFieldLabelConversionFunc allows the field selectors which are supported for SupervisorSessions.
+func SupervisorSessionFieldLabelConversionFunc(label, value string) (string, string, error) {
+	switch label {
+	case "metadata.name", UsernameFieldLabel, GroupsFieldLabel:
+		return label, value, nil
+	default:
+		return "", "", fmt.Errorf("field label not supported: %s", label)
+	}
+}
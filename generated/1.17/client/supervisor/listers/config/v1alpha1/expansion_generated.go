@@ -5,6 +5,14 @@
 
 package v1alpha1
 
+// ClusterAudienceListerExpansion allows custom methods to be added to
+// ClusterAudienceLister.
+type ClusterAudienceListerExpansion interface{}
+
+// ClusterAudienceNamespaceListerExpansion allows custom methods to be added to
+// ClusterAudienceNamespaceLister.
+type ClusterAudienceNamespaceListerExpansion interface{}
+
 // FederationDomainListerExpansion allows custom methods to be added to
 // FederationDomainLister.
 type FederationDomainListerExpansion interface{}
@@ -12,3 +20,19 @@ type FederationDomainListerExpansion interface{}
 // FederationDomainNamespaceListerExpansion allows custom methods to be added to
 // FederationDomainNamespaceLister.
 type FederationDomainNamespaceListerExpansion interface{}
+
+// MachineClientListerExpansion allows custom methods to be added to
+// MachineClientLister.
+type MachineClientListerExpansion interface{}
+
+// MachineClientNamespaceListerExpansion allows custom methods to be added to
+// MachineClientNamespaceLister.
+type MachineClientNamespaceListerExpansion interface{}
+
+// TrustedClusterListerExpansion allows custom methods to be added to
+// TrustedClusterLister.
+type TrustedClusterListerExpansion interface{}
+
+// TrustedClusterNamespaceListerExpansion allows custom methods to be added to
+// TrustedClusterNamespaceLister.
+type TrustedClusterNamespaceListerExpansion interface{}
@@ -0,0 +1,88 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=Success;Invalid
+type MachineClientStatusCondition string
+
+const (
+	SuccessMachineClientStatusCondition = MachineClientStatusCondition("Success")
+	InvalidMachineClientStatusCondition = MachineClientStatusCondition("Invalid")
+)
+
+// MachineClientSpec is a struct that describes a confidential client which gets tokens for itself.
+type MachineClientSpec struct {
+	// SecretName is the name of a Secret in the same namespace, of type "secrets.pinniped.dev/machine-client",
+	// which has the client secret in its "clientSecret" key. The client authenticates to the token endpoint with its
+	// client ID, which is the name of the MachineClient, and this client secret using HTTP basic authentication.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// Username is the username of the tokens which are issued to this client.
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// Groups are the groups of the tokens which are issued to this client.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// AllowDelegation allows this client to act on behalf of users using RFC 8693 delegation. The client sends the
+	// user's access token as the subject_token and its own access token as the actor_token of a token exchange
+	// request, and the issued token has an "act" claim which names the client. The ClusterAudience of the requested
+	// audience must also allow the groups of this client in its allowedActorGroups.
+	// +optional
+	AllowDelegation bool `json:"allowDelegation,omitempty"`
+}
+
+// MachineClientStatus is a struct that describes the actual state of a MachineClient.
+type MachineClientStatus struct {
+	// Status holds an enum that describes the state of this MachineClient. Note that this Status can
+	// represent success or failure.
+	// +optional
+	Status MachineClientStatusCondition `json:"status,omitempty"`
+
+	// Message provides human-readable details about the Status.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastUpdateTime holds the time at which the Status was last updated. It is a pointer to get
+	// around some undesirable behavior with respect to the empty metav1.Time value (see
+	// https://github.com/kubernetes/kubernetes/issues/86811).
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// MachineClient is a confidential client which may get tokens for itself from the Supervisor using the OAuth 2.0
+// client credentials grant, e.g. a batch system which has no human user. Its tokens may be exchanged for cluster
+// tokens using RFC 8693 token exchange when the client requests the "pinniped:request-audience" scope.
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped
+// +kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type MachineClient struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec of the client.
+	Spec MachineClientSpec `json:"spec"`
+
+	// Status of the client.
+	Status MachineClientStatus `json:"status,omitempty"`
+}
+
+// List of MachineClient objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type MachineClientList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []MachineClient `json:"items"`
+}
@@ -13,7 +13,10 @@ import (
 
 type ConfigV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterAudiencesGetter
 	FederationDomainsGetter
+	MachineClientsGetter
+	TrustedClustersGetter
 }
 
 // ConfigV1alpha1Client is used to interact with features provided by the config.supervisor.pinniped.dev group.
@@ -21,10 +24,22 @@ type ConfigV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ConfigV1alpha1Client) ClusterAudiences(namespace string) ClusterAudienceInterface {
+	return newClusterAudiences(c, namespace)
+}
+
 func (c *ConfigV1alpha1Client) FederationDomains(namespace string) FederationDomainInterface {
 	return newFederationDomains(c, namespace)
 }
 
+func (c *ConfigV1alpha1Client) MachineClients(namespace string) MachineClientInterface {
+	return newMachineClients(c, namespace)
+}
+
+func (c *ConfigV1alpha1Client) TrustedClusters(namespace string) TrustedClusterInterface {
+	return newTrustedClusters(c, namespace)
+}
+
 // NewForConfig creates a new ConfigV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*ConfigV1alpha1Client, error) {
 	config := *c
@@ -11,8 +11,14 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterAudiences returns a ClusterAudienceInformer.
+	ClusterAudiences() ClusterAudienceInformer
 	// FederationDomains returns a FederationDomainInformer.
 	FederationDomains() FederationDomainInformer
+	// MachineClients returns a MachineClientInformer.
+	MachineClients() MachineClientInformer
+	// TrustedClusters returns a TrustedClusterInformer.
+	TrustedClusters() TrustedClusterInformer
 }
 
 type version struct {
@@ -26,7 +32,22 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterAudiences returns a ClusterAudienceInformer.
+func (v *version) ClusterAudiences() ClusterAudienceInformer {
+	return &clusterAudienceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // FederationDomains returns a FederationDomainInformer.
 func (v *version) FederationDomains() FederationDomainInformer {
 	return &federationDomainInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// MachineClients returns a MachineClientInformer.
+func (v *version) MachineClients() MachineClientInformer {
+	return &machineClientInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// TrustedClusters returns a TrustedClusterInformer.
+func (v *version) TrustedClusters() TrustedClusterInformer {
+	return &trustedClusterInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
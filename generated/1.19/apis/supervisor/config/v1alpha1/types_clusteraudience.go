@@ -0,0 +1,106 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=Success;Duplicate;Invalid
+type ClusterAudienceStatusCondition string
+
+const (
+	SuccessClusterAudienceStatusCondition   = ClusterAudienceStatusCondition("Success")
+	DuplicateClusterAudienceStatusCondition = ClusterAudienceStatusCondition("Duplicate")
+	InvalidClusterAudienceStatusCondition   = ClusterAudienceStatusCondition("Invalid")
+)
+
+// ClusterAudienceClaim is the name of an identity claim which may be included in the tokens for a cluster.
+// +kubebuilder:validation:Enum=username;groups
+type ClusterAudienceClaim string
+
+const (
+	UsernameClusterAudienceClaim = ClusterAudienceClaim("username")
+	GroupsClusterAudienceClaim   = ClusterAudienceClaim("groups")
+)
+
+// ClusterAudienceSpec is a struct that describes which users may receive tokens for a cluster, and what is in them.
+type ClusterAudienceSpec struct {
+	// Audience is the audience of the tokens which the Supervisor issues for this cluster using RFC 8693 token
+	// exchange, e.g. the audience which the cluster's JWTAuthenticator expects. The Supervisor refuses to issue tokens
+	// for an audience which is not configured by any ClusterAudience.
+	// +kubebuilder:validation:MinLength=1
+	Audience string `json:"audience"`
+
+	// AllowedGroups lists the groups whose members may receive tokens for this cluster. A user must belong to at
+	// least one of them. When empty, every user who has logged in to the Supervisor may receive tokens for this
+	// cluster.
+	// +optional
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+
+	// AllowedActorGroups lists the groups whose members may act on behalf of users for this cluster using RFC 8693
+	// delegation, i.e. by sending an actor_token in the token exchange request. An actor must belong to at least one
+	// of them, and the user must still be allowed by AllowedGroups. When empty, delegated tokens are never issued for
+	// this cluster.
+	// +optional
+	AllowedActorGroups []string `json:"allowedActorGroups,omitempty"`
+
+	// Claims lists the identity claims which are included in the tokens for this cluster. When not specified, both
+	// the username and the groups are included.
+	// +optional
+	Claims []ClusterAudienceClaim `json:"claims,omitempty"`
+
+	// GroupsFilter optionally limits the groups claim to the groups which are relevant to this cluster. When
+	// specified, only the user's groups which match at least one of the entries are included. An entry which ends
+	// with "*" matches every group which starts with the rest of the entry, and any other entry must match a group
+	// exactly.
+	// +optional
+	GroupsFilter []string `json:"groupsFilter,omitempty"`
+}
+
+// ClusterAudienceStatus is a struct that describes the actual state of a ClusterAudience.
+type ClusterAudienceStatus struct {
+	// Status holds an enum that describes the state of this ClusterAudience. Note that this Status can
+	// represent success or failure.
+	// +optional
+	Status ClusterAudienceStatusCondition `json:"status,omitempty"`
+
+	// Message provides human-readable details about the Status.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastUpdateTime holds the time at which the Status was last updated. It is a pointer to get
+	// around some undesirable behavior with respect to the empty metav1.Time value (see
+	// https://github.com/kubernetes/kubernetes/issues/86811).
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// ClusterAudience registers a cluster which may receive tokens from the Supervisor using RFC 8693 token exchange.
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped
+// +kubebuilder:printcolumn:name="Audience",type=string,JSONPath=`.spec.audience`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type ClusterAudience struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec of the cluster.
+	Spec ClusterAudienceSpec `json:"spec"`
+
+	// Status of the cluster.
+	Status ClusterAudienceStatus `json:"status,omitempty"`
+}
+
+// List of ClusterAudience objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterAudienceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterAudience `json:"items"`
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SupervisorSession describes the tokens which a client of the Supervisor received as a result of one login of an
+// end user. Its name is the ID of the session.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SupervisorSession struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Status SupervisorSessionStatus
+}
+
+type SupervisorSessionStatus struct {
+	// Username is the downstream username of the end user.
+	Username string
+	// Groups are the downstream group memberships of the end user.
+	Groups []string
+	// Subject is the downstream subject of the end user.
+	Subject string
+	// IdentityProvider is the upstream identity provider with which the end user logged in.
+	IdentityProvider SupervisorSessionIdentityProvider
+	// ClientID is the ID of the client to which the tokens were issued.
+	ClientID string
+	// IssuedAt is the time at which the end user authenticated.
+	IssuedAt metav1.Time
+	// ExpiresAt is the time after which none of the tokens of the session can be used anymore.
+	ExpiresAt metav1.Time
+}
+
+type SupervisorSessionIdentityProvider struct {
+	// Name is the name of the OIDCIdentityProvider or LDAPIdentityProvider.
+	Name string
+	// Type is the type of the identity provider, e.g. "oidc" or "ldap".
+	Type string
+}
+
+// SupervisorSessionList is a list of SupervisorSession objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SupervisorSessionList struct {
+	metav1.TypeMeta
+	metav1.ListMeta
+
+	// Items is a list of SupervisorSession
+	Items []SupervisorSession
+}
@@ -15,10 +15,22 @@ type FakeConfigV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeConfigV1alpha1) ClusterAudiences(namespace string) v1alpha1.ClusterAudienceInterface {
+	return &FakeClusterAudiences{c, namespace}
+}
+
 func (c *FakeConfigV1alpha1) FederationDomains(namespace string) v1alpha1.FederationDomainInterface {
 	return &FakeFederationDomains{c, namespace}
 }
 
+func (c *FakeConfigV1alpha1) MachineClients(namespace string) v1alpha1.MachineClientInterface {
+	return &FakeMachineClients{c, namespace}
+}
+
+func (c *FakeConfigV1alpha1) TrustedClusters(namespace string) v1alpha1.TrustedClusterInterface {
+	return &FakeTrustedClusters{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeConfigV1alpha1) RESTClient() rest.Interface {
@@ -0,0 +1,93 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=Success;Duplicate;Invalid;Error
+type TrustedClusterStatusCondition string
+
+const (
+	SuccessTrustedClusterStatusCondition   = TrustedClusterStatusCondition("Success")
+	DuplicateTrustedClusterStatusCondition = TrustedClusterStatusCondition("Duplicate")
+	InvalidTrustedClusterStatusCondition   = TrustedClusterStatusCondition("Invalid")
+	ErrorTrustedClusterStatusCondition     = TrustedClusterStatusCondition("Error")
+)
+
+// TrustedClusterTLSSpec provides TLS configuration for the connection to a cluster's ServiceAccount issuer.
+type TrustedClusterTLSSpec struct {
+	// X.509 Certificate Authority (base64-encoded PEM bundle). If omitted, a default set of system roots will be trusted.
+	// +optional
+	CertificateAuthorityData string `json:"certificateAuthorityData,omitempty"`
+}
+
+// TrustedClusterSpec is a struct that describes how to verify the ServiceAccount tokens of a cluster.
+type TrustedClusterSpec struct {
+	// Issuer is the issuer of the cluster's ServiceAccount tokens, i.e. the value of the kube-apiserver's
+	// --service-account-issuer flag. The Supervisor uses OIDC discovery of this issuer to get the keys which sign
+	// the tokens, so the discovery endpoints of the cluster must be reachable from the Supervisor.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^https://`
+	Issuer string `json:"issuer"`
+
+	// Audience is the audience which the ServiceAccount tokens must have, e.g. the audience of a projected
+	// ServiceAccount token volume. Tokens which were issued for other audiences are rejected, so this should be
+	// an audience which is only used for the Supervisor.
+	// +kubebuilder:validation:MinLength=1
+	Audience string `json:"audience"`
+
+	// TLS configuration for the connection to the issuer.
+	// +optional
+	TLS *TrustedClusterTLSSpec `json:"tls,omitempty"`
+}
+
+// TrustedClusterStatus is a struct that describes the actual state of a TrustedCluster.
+type TrustedClusterStatus struct {
+	// Status holds an enum that describes the state of this TrustedCluster. Note that this Status can
+	// represent success or failure.
+	// +optional
+	Status TrustedClusterStatusCondition `json:"status,omitempty"`
+
+	// Message provides human-readable details about the Status.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastUpdateTime holds the time at which the Status was last updated. It is a pointer to get
+	// around some undesirable behavior with respect to the empty metav1.Time value (see
+	// https://github.com/kubernetes/kubernetes/issues/86811).
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// TrustedCluster describes a cluster whose ServiceAccounts may exchange their tokens for Supervisor-issued tokens
+// using RFC 8693 token exchange. The name of the TrustedCluster becomes part of the usernames of its ServiceAccounts,
+// e.g. system:serviceaccount:<namespace>:<name>@<trusted cluster name>.
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped
+// +kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.spec.issuer`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type TrustedCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec of the trusted cluster.
+	Spec TrustedClusterSpec `json:"spec"`
+
+	// Status of the trusted cluster.
+	Status TrustedClusterStatus `json:"status,omitempty"`
+}
+
+// List of TrustedCluster objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type TrustedClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []TrustedCluster `json:"items"`
+}
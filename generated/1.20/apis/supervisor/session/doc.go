@@ -0,0 +1,8 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +k8s:deepcopy-gen=package
+// +groupName=session.supervisor.pinniped.dev
+
+// Package session is the internal version of the Pinniped session API.
+package session
@@ -5,4 +5,10 @@
 
 package v1alpha1
 
+type ClusterAudienceExpansion interface{}
+
 type FederationDomainExpansion interface{}
+
+type MachineClientExpansion interface{}
+
+type TrustedClusterExpansion interface{}
@@ -41,8 +41,14 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=config.supervisor.pinniped.dev, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clusteraudiences"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Config().V1alpha1().ClusterAudiences().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("federationdomains"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Config().V1alpha1().FederationDomains().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("machineclients"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Config().V1alpha1().MachineClients().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("trustedclusters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Config().V1alpha1().TrustedClusters().Informer()}, nil
 
 		// Group=idp.supervisor.pinniped.dev, Version=v1alpha1
 	case idpv1alpha1.SchemeGroupVersion.WithResource("ldapidentityproviders"):
@@ -0,0 +1,64 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SupervisorSession describes the tokens which a client of the Supervisor received as a result of one login of an
+// end user. Its name is the ID of the session. Deleting it revokes all of its access and refresh tokens.
+//
+// SupervisorSessions can be listed with the field selectors status.username=<username> and status.groups=<group>.
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:onlyVerbs=get,list,delete
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SupervisorSession struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status SupervisorSessionStatus `json:"status,omitempty"`
+}
+
+type SupervisorSessionStatus struct {
+	// Username is the downstream username of the end user.
+	Username string `json:"username,omitempty"`
+
+	// Groups are the downstream group memberships of the end user.
+	Groups []string `json:"groups,omitempty"`
+
+	// Subject is the downstream subject of the end user.
+	Subject string `json:"subject,omitempty"`
+
+	// IdentityProvider is the upstream identity provider with which the end user logged in.
+	IdentityProvider SupervisorSessionIdentityProvider `json:"identityProvider,omitempty"`
+
+	// ClientID is the ID of the client to which the tokens were issued.
+	ClientID string `json:"clientID,omitempty"`
+
+	// IssuedAt is the time at which the end user authenticated.
+	IssuedAt metav1.Time `json:"issuedAt,omitempty"`
+
+	// ExpiresAt is the time after which none of the tokens of the session can be used anymore.
+	ExpiresAt metav1.Time `json:"expiresAt,omitempty"`
+}
+
+type SupervisorSessionIdentityProvider struct {
+	// Name is the name of the OIDCIdentityProvider or LDAPIdentityProvider.
+	Name string `json:"name,omitempty"`
+
+	// Type is the type of the identity provider, e.g. "oidc" or "ldap".
+	Type string `json:"type,omitempty"`
+}
+
+// SupervisorSessionList is a list of SupervisorSession objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SupervisorSessionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is a list of SupervisorSession
+	Items []SupervisorSession `json:"items"`
+}
@@ -111,7 +111,7 @@ echo "generating API-related code for our public API groups..."
         deepcopy \
         "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
         "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
-        "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 concierge/config:v1alpha1 concierge/authentication:v1alpha1 concierge/login:v1alpha1 concierge/identity:v1alpha1" \
+        "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 supervisor/session:v1alpha1 concierge/config:v1alpha1 concierge/authentication:v1alpha1 concierge/login:v1alpha1 concierge/identity:v1alpha1" \
         --go-header-file "${ROOT}/hack/boilerplate.go.txt" 2>&1 | sed "s|^|gen-api > |"
 )
 
@@ -126,6 +126,15 @@ echo "generating API-related code for our internal API groups..."
         "concierge/login:v1alpha1 concierge/identity:v1alpha1" \
         --go-header-file "${ROOT}/hack/boilerplate.go.txt"  2>&1 | sed "s|^|gen-int-api > |"
 )
+(cd apis &&
+    bash "${GOPATH}/src/k8s.io/code-generator/generate-internal-groups.sh" \
+        deepcopy,defaulter,conversion \
+        "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/client/supervisor" \
+        "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
+        "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
+        "supervisor/session:v1alpha1" \
+        --go-header-file "${ROOT}/hack/boilerplate.go.txt"  2>&1 | sed "s|^|gen-int-api > |"
+)
 
 
 # Tidy up the .../apis module
@@ -147,7 +156,7 @@ echo "generating client code for our public API groups..."
         client,lister,informer \
         "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/client/supervisor" \
         "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
-        "supervisor/config:v1alpha1 supervisor/idp:v1alpha1" \
+        "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 supervisor/session:v1alpha1" \
         --go-header-file "${ROOT}/hack/boilerplate.go.txt"  2>&1 | sed "s|^|gen-client > |"
 )
 
@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+
+# Copyright 2021 the Pinniped contributors. All Rights Reserved.
+# SPDX-License-Identifier: Apache-2.0
+
+#
+# Fails when the runtime image of the Dockerfile is not pinned by digest, so that a release cannot silently pick up
+# whatever its tag points to at build time.
+#
+
+set -euo pipefail
+
+ROOT="$( cd "$( dirname "${BASH_SOURCE[0]}" )/../.." && pwd )"
+
+runtime_image="$(grep -E '^FROM ' "$ROOT/Dockerfile" | tail -1 | cut -d' ' -f2)"
+if ! [[ "$runtime_image" =~ @sha256:[0-9a-f]{64}$ ]]; then
+  echo "The runtime image $runtime_image in the Dockerfile is not pinned by digest."
+  echo "Run hack/update-runtime-image-digest.sh to pin it."
+  exit 1
+fi
@@ -0,0 +1,25 @@
+#!/usr/bin/env bash
+
+# Copyright 2021 the Pinniped contributors. All Rights Reserved.
+# SPDX-License-Identifier: Apache-2.0
+
+#
+# Pins the runtime image of the Dockerfile, which is used by all of Pinniped's server components, to the digest
+# which its tag currently points to. Run this to pick up the fixes of a newer runtime image.
+#
+
+set -euo pipefail
+
+ROOT="$( cd "$( dirname "${BASH_SOURCE[0]}" )/.." && pwd )"
+IMAGE="gcr.io/distroless/cc-debian11:nonroot"
+
+if ! command -v crane >/dev/null; then
+  echo "Please install crane, e.g. 'go install github.com/google/go-containerregistry/cmd/crane@latest'"
+  exit 1
+fi
+
+digest="$(crane digest "$IMAGE")"
+sed -i.bak -E "s|^FROM ${IMAGE}(@sha256:[0-9a-f]{64})?\$|FROM ${IMAGE}@${digest}|" "$ROOT/Dockerfile"
+rm "$ROOT/Dockerfile.bak"
+
+echo "Pinned $IMAGE to $digest"
@@ -0,0 +1,219 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package auditlog records the authentication events of the Supervisor, e.g. logins and the issuing of tokens, as a
+// stream of JSON objects with one event per line. Unlike the logs of package plog, the audit log has a stable format
+// which is meant to be consumed by other programs.
+//
+// Events never carry passwords, tokens, authcodes, or any other secrets. Administrators may additionally choose
+// fields which are redacted from every event, e.g. usernames.
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.pinniped.dev/internal/httputil/sourceip"
+	"go.pinniped.dev/internal/plog"
+)
+
+// EventType names what happened.
+type EventType string
+
+const (
+	// AuthorizeStarted means that a client started a login at the authorize endpoint.
+	AuthorizeStarted EventType = "AuthorizeStarted"
+	// UpstreamAuthenticationSucceeded means that the upstream identity provider authenticated the user.
+	UpstreamAuthenticationSucceeded EventType = "UpstreamAuthenticationSucceeded"
+	// UpstreamAuthenticationFailed means that the upstream identity provider did not authenticate the user.
+	UpstreamAuthenticationFailed EventType = "UpstreamAuthenticationFailed"
+	// LoginRateLimited means that a password login was rejected after too many failed logins.
+	LoginRateLimited EventType = "LoginRateLimited"
+	// TokenIssued means that tokens were issued for an authcode or with the client credentials grant.
+	TokenIssued EventType = "TokenIssued"
+	// TokenRefreshed means that tokens were issued for a refresh token.
+	TokenRefreshed EventType = "TokenRefreshed"
+	// TokenExchanged means that a token for a cluster was issued with the token exchange grant.
+	TokenExchanged EventType = "TokenExchanged"
+	// TokenRequestFailed means that the token endpoint rejected a request.
+	TokenRequestFailed EventType = "TokenRequestFailed"
+	// TokenRevoked means that the access tokens or refresh tokens of a session were revoked.
+	TokenRevoked EventType = "TokenRevoked"
+	// RefreshTokenReuseDetected means that a refresh token was used again after it was exchanged for new tokens, so
+	// it may have been stolen. The TokenRevoked events of the same session follow.
+	RefreshTokenReuseDetected EventType = "RefreshTokenReuseDetected"
+	// SessionGarbageCollected means that the stored data of a session was deleted after it expired.
+	SessionGarbageCollected EventType = "SessionGarbageCollected"
+)
+
+// RedactedValue replaces the values of redacted fields.
+const RedactedValue = "redacted"
+
+// Event is one entry of the audit log. Fields which do not apply to an event are left empty and are omitted.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type EventType `json:"event"`
+
+	// RequestID identifies the downstream session. The same ID is used by all events of a session, from the
+	// authorize request through the token requests which use its authcode and refresh tokens.
+	RequestID string `json:"requestID,omitempty"`
+	// Issuer is the issuer of the FederationDomain which handled the request.
+	Issuer   string `json:"issuer,omitempty"`
+	ClientID string `json:"clientID,omitempty"`
+	SourceIP string `json:"sourceIP,omitempty"`
+
+	UpstreamName string   `json:"upstreamName,omitempty"`
+	UpstreamType string   `json:"upstreamType,omitempty"`
+	Username     string   `json:"username,omitempty"`
+	Groups       []string `json:"groups,omitempty"`
+	// Actor is the username of the client which acts on behalf of the user during token exchange.
+	Actor string `json:"actor,omitempty"`
+
+	GrantType string `json:"grantType,omitempty"`
+	TokenType string `json:"tokenType,omitempty"`
+	Audience  string `json:"audience,omitempty"`
+	// StorageType is the type of the stored session data, for SessionGarbageCollected events.
+	StorageType string `json:"storageType,omitempty"`
+	// Reason briefly explains a failure, e.g. with the name of an OAuth error. It never contains user input.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Logger writes events to an io.Writer.
+type Logger struct {
+	clock    func() time.Time
+	redacted map[string]bool
+
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+// New returns a Logger which writes to out. The redactedFields are the JSON names of the fields of Event whose
+// values are replaced with RedactedValue.
+func New(out io.Writer, redactedFields []string, clock func() time.Time) (*Logger, error) {
+	redactable := redactableFields()
+	redacted := make(map[string]bool, len(redactedFields))
+	for _, field := range redactedFields {
+		if !redactable[field] {
+			return nil, fmt.Errorf("unknown audit log field %q, must be one of %s", field, strings.Join(sortedKeys(redactable), ", "))
+		}
+		redacted[field] = true
+	}
+	return &Logger{clock: clock, redacted: redacted, out: out}, nil
+}
+
+// Record writes the event. The time, and the issuer and source IP of a request context, are filled in when they are
+// not set.
+func (l *Logger) Record(ctx context.Context, event Event) {
+	if event.Time.IsZero() {
+		event.Time = l.clock().UTC()
+	}
+	if info, ok := ctx.Value(requestInfoKey{}).(requestInfo); ok {
+		if event.Issuer == "" {
+			event.Issuer = info.issuer
+		}
+		if event.SourceIP == "" {
+			event.SourceIP = info.sourceIP
+		}
+	}
+
+	line, err := l.marshal(event)
+	if err != nil {
+		plog.Error("could not encode audit event", err, "event", event.Type)
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, err := l.out.Write(line); err != nil {
+		plog.Error("could not write audit event", err, "event", event.Type)
+	}
+}
+
+func (l *Logger) marshal(event Event) ([]byte, error) {
+	if len(l.redacted) > 0 {
+		fields, err := toFields(event)
+		if err != nil {
+			return nil, err
+		}
+		for field := range l.redacted {
+			if _, ok := fields[field]; ok {
+				fields[field] = RedactedValue
+			}
+		}
+		line, err := json.Marshal(fields)
+		return append(line, '\n'), err
+	}
+	line, err := json.Marshal(event)
+	return append(line, '\n'), err
+}
+
+func toFields(event Event) (map[string]interface{}, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// redactableFields returns the JSON names of all fields of Event, except for its time and type.
+func redactableFields() map[string]bool {
+	eventType := reflect.TypeOf(Event{})
+	redactable := make(map[string]bool, eventType.NumField())
+	for i := 0; i < eventType.NumField(); i++ {
+		field := strings.Split(eventType.Field(i).Tag.Get("json"), ",")[0]
+		if field != "time" && field != "event" {
+			redactable[field] = true
+		}
+	}
+	return redactable
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type requestInfoKey struct{}
+
+type requestInfo struct {
+	issuer   string
+	sourceIP string
+}
+
+// WithRequest returns a shallow copy of the request whose context remembers the issuer of the FederationDomain which
+// handles the request, and the IP address of its client, for the events which are recorded while handling it.
+func WithRequest(r *http.Request, issuer string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, requestInfo{
+		issuer:   issuer,
+		sourceIP: sourceip.FromRequest(r),
+	}))
+}
+
+var globalLogger atomic.Value //nolint:gochecknoglobals
+
+// SetGlobalLogger sets the Logger which is used by Record. Events are discarded until it is called, or when it is nil.
+func SetGlobalLogger(logger *Logger) {
+	globalLogger.Store(logger)
+}
+
+// Record writes the event with the global Logger.
+func Record(ctx context.Context, event Event) {
+	if logger, ok := globalLogger.Load().(*Logger); ok && logger != nil {
+		logger.Record(ctx, event)
+	}
+}
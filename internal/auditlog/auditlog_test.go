@@ -0,0 +1,100 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	now := time.Date(2030, time.January, 1, 12, 30, 0, 0, time.FixedZone("somewhere", 3600))
+	clock := func() time.Time { return now }
+
+	event := Event{
+		Type:         TokenIssued,
+		RequestID:    "some-request-id",
+		ClientID:     "pinniped-cli",
+		UpstreamName: "some-ldap",
+		UpstreamType: "ldap",
+		Username:     "alice",
+		Groups:       []string{"group1", "group2"},
+		GrantType:    "authorization_code",
+	}
+
+	t.Run("writes one JSON object per line", func(t *testing.T) {
+		var out bytes.Buffer
+		subject, err := New(&out, nil, clock)
+		require.NoError(t, err)
+
+		subject.Record(context.Background(), event)
+		subject.Record(context.Background(), Event{Type: TokenRevoked, RequestID: "other-request-id", TokenType: "refresh_token"})
+
+		require.Equal(t,
+			`{"time":"2030-01-01T11:30:00Z","event":"TokenIssued","requestID":"some-request-id","clientID":"pinniped-cli",`+
+				`"upstreamName":"some-ldap","upstreamType":"ldap","username":"alice","groups":["group1","group2"],"grantType":"authorization_code"}`+"\n"+
+				`{"time":"2030-01-01T11:30:00Z","event":"TokenRevoked","requestID":"other-request-id","tokenType":"refresh_token"}`+"\n",
+			out.String(),
+		)
+	})
+
+	t.Run("redacts fields which are set", func(t *testing.T) {
+		var out bytes.Buffer
+		subject, err := New(&out, []string{"username", "groups", "sourceIP"}, clock)
+		require.NoError(t, err)
+
+		subject.Record(context.Background(), event)
+
+		require.JSONEq(t,
+			`{"time":"2030-01-01T11:30:00Z","event":"TokenIssued","requestID":"some-request-id","clientID":"pinniped-cli",`+
+				`"upstreamName":"some-ldap","upstreamType":"ldap","username":"redacted","groups":"redacted","grantType":"authorization_code"}`,
+			out.String(),
+		)
+		require.Contains(t, out.String(), "\n")
+	})
+
+	t.Run("fills the issuer and source IP of the request", func(t *testing.T) {
+		var out bytes.Buffer
+		subject, err := New(&out, nil, clock)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "https://example.com/issuer/oauth2/authorize", nil)
+		r.RemoteAddr = "192.0.2.1:12345"
+		r = WithRequest(r, "https://example.com/issuer")
+
+		subject.Record(r.Context(), Event{Type: AuthorizeStarted})
+		subject.Record(r.Context(), Event{Type: AuthorizeStarted, SourceIP: "192.0.2.2"})
+
+		require.Equal(t,
+			`{"time":"2030-01-01T11:30:00Z","event":"AuthorizeStarted","issuer":"https://example.com/issuer","sourceIP":"192.0.2.1"}`+"\n"+
+				`{"time":"2030-01-01T11:30:00Z","event":"AuthorizeStarted","issuer":"https://example.com/issuer","sourceIP":"192.0.2.2"}`+"\n",
+			out.String(),
+		)
+	})
+
+	t.Run("unknown redacted fields", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, []string{"username", "time"}, clock)
+		require.EqualError(t, err, `unknown audit log field "time", must be one of actor, audience, clientID, grantType, groups, issuer, reason, requestID, sourceIP, storageType, tokenType, upstreamName, upstreamType, username`)
+	})
+}
+
+func TestGlobalLogger(t *testing.T) {
+	t.Cleanup(func() { SetGlobalLogger(nil) })
+
+	// Events are discarded before the global logger is set.
+	Record(context.Background(), Event{Type: AuthorizeStarted})
+
+	var out bytes.Buffer
+	subject, err := New(&out, nil, func() time.Time { return time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC) })
+	require.NoError(t, err)
+	SetGlobalLogger(subject)
+
+	Record(context.Background(), Event{Type: AuthorizeStarted})
+	require.Equal(t, `{"time":"2030-01-01T00:00:00Z","event":"AuthorizeStarted"}`+"\n", out.String())
+}
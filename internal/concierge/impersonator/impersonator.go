@@ -51,6 +51,7 @@ import (
 	"go.pinniped.dev/internal/httputil/securityheader"
 	"go.pinniped.dev/internal/kubeclient"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
 	"go.pinniped.dev/internal/valuelesscontext"
 )
 
@@ -135,6 +136,7 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 		if err != nil {
 			return nil, err
 		}
+		tlsprofile.Default().ApplyToSecureServing(serverConfig.SecureServing)
 
 		// Loopback authentication to this server does not really make sense since we just proxy everything to
 		// the Kube API server, thus we replace loopback connection config with one that does direct connections
@@ -565,8 +567,11 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 				r.Header.Del("X-Forwarded-For")
 			}
 
+			inflightRequests.Inc()
+			defer inflightRequests.Dec()
+
 			reverseProxy := httputil.NewSingleHostReverseProxy(serverURL)
-			reverseProxy.Transport = rt
+			reverseProxy.Transport = &measuredRoundTripper{delegate: rt}
 			reverseProxy.FlushInterval = 200 * time.Millisecond // the "watch" verb will not work without this line
 			reverseProxy.ServeHTTP(w, r)
 		})
@@ -0,0 +1,72 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var (
+	upstreamDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "concierge",
+			Name:           "impersonation_proxy_upstream_duration_seconds",
+			Help:           "How long the Kubernetes API server took to start responding to requests from the impersonation proxy.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 2, 16),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"verb"},
+	)
+
+	inflightRequests = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "concierge",
+			Name:           "impersonation_proxy_inflight_requests",
+			Help:           "Number of requests currently being proxied by the impersonation proxy, including open watches and upgraded connections.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	// knownVerbs are the verbs which may be used as metric labels. Non-resource requests use the lowercase HTTP
+	// method as their verb, which could be anything, so all other verbs are counted together.
+	knownVerbs = sets.NewString(
+		"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection", "proxy",
+		"post", "put", "head", "options",
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(upstreamDuration, inflightRequests)
+}
+
+// measuredRoundTripper measures how long the Kubernetes API server took to return the response headers of each
+// proxied request. The time spent streaming the response body is not included, since watches never end.
+type measuredRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+func (m *measuredRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := m.delegate.RoundTrip(r)
+	upstreamDuration.WithLabelValues(verbFrom(r)).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+func verbFrom(r *http.Request) string {
+	requestInfo, ok := request.RequestInfoFrom(r.Context())
+	if !ok || !knownVerbs.Has(requestInfo.Verb) {
+		return "other"
+	}
+	return requestInfo.Verb
+}
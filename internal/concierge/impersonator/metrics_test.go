@@ -0,0 +1,57 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestMeasuredRoundTripper(t *testing.T) {
+	withVerb := func(verb string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces", nil)
+		return r.WithContext(request.WithRequestInfo(r.Context(), &request.RequestInfo{Verb: verb}))
+	}
+
+	histogramCount := func(verb string) uint64 {
+		t.Helper()
+		count, err := testutil.GetHistogramMetricCount(upstreamDuration.WithLabelValues(verb))
+		require.NoError(t, err)
+		return count
+	}
+	deleteCollectionsBefore := histogramCount("deletecollection")
+	othersBefore := histogramCount("other")
+
+	subject := &measuredRoundTripper{delegate: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+		return nil, errors.New("some upstream error")
+	})}
+
+	resp, err := subject.RoundTrip(withVerb("deletecollection"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	errRequest := withVerb("not-a-verb")
+	errRequest.Method = http.MethodPost
+	_, err = subject.RoundTrip(errRequest)
+	require.EqualError(t, err, "some upstream error")
+
+	_, err = subject.RoundTrip(httptest.NewRequest(http.MethodGet, "/api/v1/namespaces", nil))
+	require.NoError(t, err)
+
+	require.Equal(t, deleteCollectionsBefore+1, histogramCount("deletecollection"))
+	require.Equal(t, othersBefore+2, histogramCount("other"))
+}
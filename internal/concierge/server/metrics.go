@@ -0,0 +1,66 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	genericapifilters "k8s.io/apiserver/pkg/endpoints/filters"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/mux"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/apiserver/pkg/server/routes"
+
+	"go.pinniped.dev/internal/dynamiccert"
+	"go.pinniped.dev/internal/tlsprofile"
+)
+
+// prepareMetricsServer opens the listener for the dedicated metrics endpoint and returns a post-start hook which
+// serves it. The endpoint uses the same serving certificate, authentication and authorization as the aggregated API,
+// so clients need to be allowed to "get" the "/metrics" non-resource URL by the Kubernetes API server.
+func prepareMetricsServer(address string, servingCertProvider dynamiccert.Private, config *genericapiserver.Config) (genericapiserver.PostStartHookFunc, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	servingOptions := genericoptions.NewSecureServingOptions()
+	servingOptions.ServerCert.GeneratedCert = servingCertProvider
+	if servingOptions.BindPort, err = strconv.Atoi(port); err != nil || servingOptions.BindPort <= 0 {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+	if host != "" {
+		if servingOptions.BindAddress = net.ParseIP(host); servingOptions.BindAddress == nil {
+			return nil, fmt.Errorf("invalid IP address %q", host)
+		}
+	}
+
+	var servingInfo *genericapiserver.SecureServingInfo
+	if err := servingOptions.ApplyTo(&servingInfo); err != nil {
+		return nil, err
+	}
+	tlsprofile.Default().ApplyToSecureServing(servingInfo)
+	// Also accept client certificates which are trusted by the aggregated API.
+	servingInfo.ClientCA = config.SecureServing.ClientCA
+
+	handler := metricsHandler(config)
+	return func(hookContext genericapiserver.PostStartHookContext) error {
+		_, err := servingInfo.Serve(handler, config.RequestTimeout, hookContext.StopCh)
+		return err
+	}, nil
+}
+
+func metricsHandler(config *genericapiserver.Config) http.Handler {
+	pathRecorderMux := mux.NewPathRecorderMux("pinniped-concierge-metrics")
+	// Serves everything in the legacy registry, i.e. the generic apiserver metrics along with our own.
+	routes.DefaultMetrics{}.Install(pathRecorderMux)
+
+	// Same order as the handler chain of the aggregated API, where the request info is also needed by the error responses.
+	handler := genericapifilters.WithAuthorization(pathRecorderMux, config.Authorization.Authorizer, config.Serializer)
+	handler = genericapifilters.WithAuthentication(handler, config.Authentication.Authenticator, genericapifilters.Unauthorized(config.Serializer), nil)
+	return genericapifilters.WithRequestInfo(handler, genericapiserver.NewRequestInfoResolver(config))
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"go.pinniped.dev/internal/dynamiccert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	metav1.AddToGroupVersion(scheme, schema.GroupVersion{Version: "v1"})
+	config := genericapiserver.NewConfig(serializer.NewCodecFactory(scheme))
+	config.Authentication.Authenticator = authenticator.RequestFunc(func(r *http.Request) (*authenticator.Response, bool, error) {
+		name := r.Header.Get("Test-User")
+		if name == "" {
+			return nil, false, nil
+		}
+		return &authenticator.Response{User: &user.DefaultInfo{Name: name}}, true, nil
+	})
+	config.Authorization.Authorizer = authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser().GetName() == "prometheus" && !a.IsResourceRequest() && a.GetVerb() == "get" && a.GetPath() == "/metrics" {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	})
+	subject := metricsHandler(config)
+
+	tests := []struct {
+		name       string
+		path       string
+		user       string
+		wantStatus int
+	}{
+		{name: "unauthenticated", path: "/metrics", wantStatus: http.StatusUnauthorized},
+		{name: "unauthorized", path: "/metrics", user: "someone-else", wantStatus: http.StatusForbidden},
+		{name: "authorized", path: "/metrics", user: "prometheus", wantStatus: http.StatusOK},
+		{name: "other paths", path: "/healthz", user: "prometheus", wantStatus: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.user != "" {
+				req.Header.Set("Test-User", test.user)
+			}
+			rec := httptest.NewRecorder()
+			subject.ServeHTTP(rec, req)
+			require.Equal(t, test.wantStatus, rec.Code)
+			if test.wantStatus == http.StatusOK {
+				require.Contains(t, rec.Body.String(), "go_goroutines")
+			}
+		})
+	}
+}
+
+func TestPrepareMetricsServer(t *testing.T) {
+	config := genericapiserver.NewConfig(serializer.NewCodecFactory(runtime.NewScheme()))
+	config.SecureServing = &genericapiserver.SecureServingInfo{}
+	servingCert := dynamiccert.NewServingCert("test-metrics-serving-cert")
+
+	_, err := prepareMetricsServer("8444", servingCert, config)
+	require.EqualError(t, err, "address 8444: missing port in address")
+
+	_, err = prepareMetricsServer(":https", servingCert, config)
+	require.EqualError(t, err, `invalid port "https"`)
+
+	_, err = prepareMetricsServer(":0", servingCert, config)
+	require.EqualError(t, err, `invalid port "0"`)
+
+	_, err = prepareMetricsServer("localhost:8444", servingCert, config)
+	require.EqualError(t, err, `invalid IP address "localhost"`)
+
+	// Find a free port, then let the metrics server listen on it.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	hook, err := prepareMetricsServer(address, servingCert, config)
+	require.NoError(t, err)
+	require.NotNil(t, hook)
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	require.NoError(t, hook(genericapiserver.PostStartHookContext{StopCh: stopCh}))
+}
@@ -34,6 +34,7 @@ import (
 	"go.pinniped.dev/internal/issuer"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/registry/credentialrequest"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 // App is an object that represents the pinniped-concierge application.
@@ -105,6 +106,19 @@ func (a *App) runServer(ctx context.Context) error {
 		return fmt.Errorf("could not load config: %w", err)
 	}
 
+	// Every server of the Concierge uses these TLS settings, so they must be set before any of them start. Its webhook
+	// clients only use them when they are configured explicitly, so that upgrading does not break their connections to
+	// webhooks which only offer older cipher suites.
+	tlsProfile, err := tlsprofile.FromSpec(&cfg.TLS)
+	if err != nil {
+		return fmt.Errorf("could not parse tls profile: %w", err)
+	}
+	tlsprofile.SetDefault(tlsProfile)
+	if cfg.TLS.IsSet() {
+		tlsprofile.SetClientDefault(tlsProfile)
+	}
+	plog.Debug("using tls profile", "profile", tlsProfile.String(), "usedByClients", cfg.TLS.IsSet())
+
 	// Discover in which namespace we are installed.
 	podInfo, err := downward.Load(a.downwardAPIPath)
 	if err != nil {
@@ -181,6 +195,15 @@ func (a *App) runServer(ctx context.Context) error {
 		return fmt.Errorf("could not create aggregated API server: %w", err)
 	}
 
+	// Serve the metrics on a dedicated listener when it is configured. They are also served by the aggregated API itself.
+	if cfg.Metrics.Address != "" {
+		startMetricsServer, err := prepareMetricsServer(cfg.Metrics.Address, dynamicServingCertProvider, &aggregatedAPIServerConfig.GenericConfig.Config)
+		if err != nil {
+			return fmt.Errorf("could not configure metrics server: %w", err)
+		}
+		server.GenericAPIServer.AddPostStartHookOrDie("start-metrics-server", startMetricsServer)
+	}
+
 	// Run the server. Its post-start hook will start the controllers.
 	return server.GenericAPIServer.PrepareRun().Run(ctx.Done())
 }
@@ -221,6 +244,7 @@ func getAggregatedAPIServerConfig(
 	if err := recommendedOptions.ApplyTo(serverConfig); err != nil {
 		return nil, err
 	}
+	tlsprofile.Default().ApplyToSecureServing(serverConfig.SecureServing)
 
 	apiServerConfig := &apiserver.Config{
 		GenericConfig: serverConfig,
@@ -8,6 +8,7 @@ package concierge
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"strings"
 
 	"k8s.io/utils/pointer"
@@ -16,6 +17,7 @@ import (
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 const (
@@ -57,6 +59,14 @@ func FromPath(path string) (*Config, error) {
 		return nil, fmt.Errorf("validate names: %w", err)
 	}
 
+	if err := validateMetrics(&config.Metrics); err != nil {
+		return nil, fmt.Errorf("validate metrics: %w", err)
+	}
+
+	if _, err := tlsprofile.FromSpec(&config.TLS); err != nil {
+		return nil, fmt.Errorf("validate tls: %w", err)
+	}
+
 	if err := plog.ValidateAndSetLogLevelGlobally(config.LogLevel); err != nil {
 		return nil, fmt.Errorf("validate log level: %w", err)
 	}
@@ -144,6 +154,16 @@ func validateAPI(apiConfig *APIConfigSpec) error {
 	return nil
 }
 
+func validateMetrics(metrics *MetricsSpec) error {
+	if metrics.Address == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(metrics.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	return nil
+}
+
 func validateAPIGroupSuffix(apiGroupSuffix string) error {
 	return groupsuffix.Validate(apiGroupSuffix)
 }
@@ -13,6 +13,7 @@ import (
 
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 func TestFromPath(t *testing.T) {
@@ -54,6 +55,11 @@ func TestFromPath(t *testing.T) {
 				  image: kube-cert-agent-image
 				  imagePullSecrets: [kube-cert-agent-image-pull-secret]
 				logLevel: debug
+				metrics:
+				  address: ":8444"
+				tls:
+				  profile: modern
+				  curvePreferences: [P256]
 			`),
 			wantConfig: &Config{
 				DiscoveryInfo: DiscoveryInfoSpec{
@@ -87,6 +93,13 @@ func TestFromPath(t *testing.T) {
 					ImagePullSecrets: []string{"kube-cert-agent-image-pull-secret"},
 				},
 				LogLevel: plog.LevelDebug,
+				Metrics: MetricsSpec{
+					Address: ":8444",
+				},
+				TLS: tlsprofile.Spec{
+					Profile:          "modern",
+					CurvePreferences: []string{"P256"},
+				},
 			},
 		},
 		{
@@ -362,6 +375,45 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate apiGroupSuffix: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
 		},
+		{
+			name: "InvalidMetricsAddress",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				metrics:
+				  address: "8444"
+			`),
+			wantError: "validate metrics: invalid address: address 8444: missing port in address",
+		},
+		{
+			name: "InvalidTLSProfile",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				tls:
+				  profile: modern
+				  cipherSuites: [TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256]
+			`),
+			wantError: "validate tls: cipherSuites must not be set when the minimum version is 1.3",
+		},
 	}
 	for _, test := range tests {
 		test := test
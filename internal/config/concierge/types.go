@@ -3,7 +3,10 @@
 
 package concierge
 
-import "go.pinniped.dev/internal/plog"
+import (
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
+)
 
 // Config contains knobs to setup an instance of the Pinniped Concierge.
 type Config struct {
@@ -14,6 +17,10 @@ type Config struct {
 	KubeCertAgentConfig KubeCertAgentSpec `json:"kubeCertAgent"`
 	Labels              map[string]string `json:"labels"`
 	LogLevel            plog.LogLevel     `json:"logLevel"`
+	Metrics             MetricsSpec       `json:"metrics"`
+	// TLS configures the aggregated API, the impersonation proxy, the metrics listener, and the connections to
+	// webhook authenticators.
+	TLS tlsprofile.Spec `json:"tls"`
 }
 
 // DiscoveryInfoSpec contains configuration knobs specific to
@@ -31,6 +38,14 @@ type APIConfigSpec struct {
 	ServingCertificateConfig ServingCertificateConfigSpec `json:"servingCertificate"`
 }
 
+// MetricsSpec configures the listener which serves the Concierge's Prometheus metrics.
+type MetricsSpec struct {
+	// Address is the host and port on which the metrics are served over HTTPS at /metrics, e.g. ":8444". Clients
+	// must be authenticated and authorized by the Kubernetes API server, just like clients of the Concierge's API.
+	// The metrics are not served on a dedicated listener when it is empty.
+	Address string `json:"address,omitempty"`
+}
+
 // NamesConfigSpec configures the names of some Kubernetes resources for the Concierge.
 type NamesConfigSpec struct {
 	ServingCertificateSecret          string `json:"servingCertificateSecret"`
@@ -8,14 +8,39 @@ package supervisor
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"strings"
+	"time"
 
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/yaml"
 
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/groupsuffix"
+	"go.pinniped.dev/internal/httputil/sourceip"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
+)
+
+const (
+	about30Days  = 60 * 60 * 24 * 30
+	aboutAYear   = 60 * 60 * 24 * 365
+	about9Months = 60 * 60 * 24 * 30 * 9
+
+	defaultLoginUsernameFailures      = 5
+	defaultLoginSourceIPFailures      = 50
+	defaultLoginInitialBackoffSeconds = 30
+	defaultLoginMaxBackoffSeconds     = 15 * 60
+	defaultLoginResetAfterSeconds     = 60 * 60
+
+	defaultTracingSampleRatio = 1
+
+	defaultHTTPSAddress = ":8443"
+	defaultHTTPAddress  = ":8080"
+
+	defaultShutdownDelaySeconds       = 10
+	defaultShutdownGracePeriodSeconds = 25
 )
 
 // FromPath loads an Config from a provided local file path, inserts any
@@ -36,7 +61,17 @@ func FromPath(path string) (*Config, error) {
 		config.Labels = make(map[string]string)
 	}
 
+	maybeSetAPIDefaults(&config.APIConfig)
 	maybeSetAPIGroupSuffixDefault(&config.APIGroupSuffix)
+	maybeSetSymmetricKeyRotationDefaults(&config.SymmetricKeyRotation)
+	maybeSetLoginRateLimitsDefaults(&config.LoginRateLimits)
+	maybeSetTracingDefaults(&config.Tracing)
+	maybeSetEndpointsDefaults(&config.Endpoints)
+	maybeSetShutdownDefaults(&config.Shutdown)
+
+	if err := validateAPI(&config.APIConfig); err != nil {
+		return nil, fmt.Errorf("validate api: %w", err)
+	}
 
 	if err := validateAPIGroupSuffix(*config.APIGroupSuffix); err != nil {
 		return nil, fmt.Errorf("validate apiGroupSuffix: %w", err)
@@ -46,6 +81,46 @@ func FromPath(path string) (*Config, error) {
 		return nil, fmt.Errorf("validate names: %w", err)
 	}
 
+	if err := validateSigners(config.Signers); err != nil {
+		return nil, fmt.Errorf("validate signers: %w", err)
+	}
+
+	if err := validateSymmetricKeyRotation(&config.SymmetricKeyRotation); err != nil {
+		return nil, fmt.Errorf("validate symmetricKeyRotation: %w", err)
+	}
+
+	if err := validateLoginRateLimits(&config.LoginRateLimits); err != nil {
+		return nil, fmt.Errorf("validate loginRateLimits: %w", err)
+	}
+
+	if err := validateAudit(&config.Audit); err != nil {
+		return nil, fmt.Errorf("validate audit: %w", err)
+	}
+
+	if err := validateMetrics(&config.Metrics); err != nil {
+		return nil, fmt.Errorf("validate metrics: %w", err)
+	}
+
+	if err := validateTracing(&config.Tracing); err != nil {
+		return nil, fmt.Errorf("validate tracing: %w", err)
+	}
+
+	if err := validateEndpoints(&config.Endpoints); err != nil {
+		return nil, fmt.Errorf("validate endpoints: %w", err)
+	}
+
+	if err := validateTLS(&config.TLS); err != nil {
+		return nil, fmt.Errorf("validate tls: %w", err)
+	}
+
+	if err := validateShutdown(&config.Shutdown); err != nil {
+		return nil, fmt.Errorf("validate shutdown: %w", err)
+	}
+
+	if err := validateTrustedProxies(&config.TrustedProxies, &config.Endpoints); err != nil {
+		return nil, fmt.Errorf("validate trustedProxies: %w", err)
+	}
+
 	if err := plog.ValidateAndSetLogLevelGlobally(config.LogLevel); err != nil {
 		return nil, fmt.Errorf("validate log level: %w", err)
 	}
@@ -53,12 +128,82 @@ func FromPath(path string) (*Config, error) {
 	return &config, nil
 }
 
+func maybeSetAPIDefaults(apiConfig *APIConfigSpec) {
+	if apiConfig.ServingCertificateConfig.DurationSeconds == nil {
+		apiConfig.ServingCertificateConfig.DurationSeconds = pointer.Int64Ptr(aboutAYear)
+	}
+
+	if apiConfig.ServingCertificateConfig.RenewBeforeSeconds == nil {
+		apiConfig.ServingCertificateConfig.RenewBeforeSeconds = pointer.Int64Ptr(about9Months)
+	}
+}
+
 func maybeSetAPIGroupSuffixDefault(apiGroupSuffix **string) {
 	if *apiGroupSuffix == nil {
 		*apiGroupSuffix = pointer.StringPtr(groupsuffix.PinnipedDefaultSuffix)
 	}
 }
 
+func maybeSetSymmetricKeyRotationDefaults(rotation *SymmetricKeyRotationSpec) {
+	if rotation.IntervalSeconds == nil {
+		rotation.IntervalSeconds = pointer.Int64Ptr(about30Days)
+	}
+}
+
+func maybeSetLoginRateLimitsDefaults(limits *LoginRateLimitsSpec) {
+	if limits.UsernameFailures == nil {
+		limits.UsernameFailures = pointer.Int64Ptr(defaultLoginUsernameFailures)
+	}
+	if limits.SourceIPFailures == nil {
+		limits.SourceIPFailures = pointer.Int64Ptr(defaultLoginSourceIPFailures)
+	}
+	if limits.InitialBackoffSeconds == nil {
+		limits.InitialBackoffSeconds = pointer.Int64Ptr(defaultLoginInitialBackoffSeconds)
+	}
+	if limits.MaxBackoffSeconds == nil {
+		limits.MaxBackoffSeconds = pointer.Int64Ptr(defaultLoginMaxBackoffSeconds)
+	}
+	if limits.ResetAfterSeconds == nil {
+		limits.ResetAfterSeconds = pointer.Int64Ptr(defaultLoginResetAfterSeconds)
+	}
+}
+
+func maybeSetTracingDefaults(tracing *TracingSpec) {
+	if tracing.SampleRatio == nil {
+		tracing.SampleRatio = pointer.Float64Ptr(defaultTracingSampleRatio)
+	}
+}
+
+func maybeSetEndpointsDefaults(endpoints *EndpointsSpec) {
+	if endpoints.HTTPS == nil {
+		endpoints.HTTPS = &Endpoint{Network: NetworkTCP, Address: defaultHTTPSAddress}
+	}
+	if endpoints.HTTP == nil {
+		endpoints.HTTP = &Endpoint{Network: NetworkTCP, Address: defaultHTTPAddress}
+	}
+}
+
+func maybeSetShutdownDefaults(shutdown *ShutdownSpec) {
+	if shutdown.DelaySeconds == nil {
+		shutdown.DelaySeconds = pointer.Int64Ptr(defaultShutdownDelaySeconds)
+	}
+	if shutdown.GracePeriodSeconds == nil {
+		shutdown.GracePeriodSeconds = pointer.Int64Ptr(defaultShutdownGracePeriodSeconds)
+	}
+}
+
+func validateAPI(apiConfig *APIConfigSpec) error {
+	if *apiConfig.ServingCertificateConfig.DurationSeconds < *apiConfig.ServingCertificateConfig.RenewBeforeSeconds {
+		return constable.Error("durationSeconds cannot be smaller than renewBeforeSeconds")
+	}
+
+	if *apiConfig.ServingCertificateConfig.RenewBeforeSeconds <= 0 {
+		return constable.Error("renewBefore must be positive")
+	}
+
+	return nil
+}
+
 func validateAPIGroupSuffix(apiGroupSuffix string) error {
 	return groupsuffix.Validate(apiGroupSuffix)
 }
@@ -68,8 +213,156 @@ func validateNames(names *NamesConfigSpec) error {
 	if names.DefaultTLSCertificateSecret == "" {
 		missingNames = append(missingNames, "defaultTLSCertificateSecret")
 	}
+	if names.ServingCertificateSecret == "" {
+		missingNames = append(missingNames, "servingCertificateSecret")
+	}
+	if names.APIService == "" {
+		missingNames = append(missingNames, "apiService")
+	}
 	if len(missingNames) > 0 {
 		return constable.Error("missing required names: " + strings.Join(missingNames, ", "))
 	}
 	return nil
 }
+
+func validateSigners(signers []SignerSpec) error {
+	names := map[string]bool{}
+	for i, signer := range signers {
+		if signer.Name == "" {
+			return fmt.Errorf("signer at index %d is missing a name", i)
+		}
+		if names[signer.Name] {
+			return fmt.Errorf("duplicate signer name %q", signer.Name)
+		}
+		names[signer.Name] = true
+
+		switch {
+		case signer.PKCS11 != nil && signer.Plugin != nil:
+			return fmt.Errorf("signer %q must configure only one of pkcs11 or plugin", signer.Name)
+		case signer.PKCS11 != nil:
+			if signer.PKCS11.ModulePath == "" || signer.PKCS11.TokenLabel == "" || signer.PKCS11.PINFile == "" {
+				return fmt.Errorf("signer %q must configure pkcs11.modulePath, pkcs11.tokenLabel, and pkcs11.pinFile", signer.Name)
+			}
+		case signer.Plugin != nil:
+			if signer.Plugin.Command == "" {
+				return fmt.Errorf("signer %q must configure plugin.command", signer.Name)
+			}
+		default:
+			return fmt.Errorf("signer %q must configure one of pkcs11 or plugin", signer.Name)
+		}
+	}
+	return nil
+}
+
+func validateSymmetricKeyRotation(rotation *SymmetricKeyRotationSpec) error {
+	if *rotation.IntervalSeconds < 0 {
+		return constable.Error("intervalSeconds must not be negative")
+	}
+	return nil
+}
+
+func validateLoginRateLimits(limits *LoginRateLimitsSpec) error {
+	switch {
+	case *limits.UsernameFailures < 0 || *limits.SourceIPFailures < 0:
+		return constable.Error("usernameFailures and sourceIPFailures must not be negative")
+	case *limits.InitialBackoffSeconds <= 0:
+		return constable.Error("initialBackoffSeconds must be positive")
+	case *limits.MaxBackoffSeconds < *limits.InitialBackoffSeconds:
+		return constable.Error("maxBackoffSeconds must not be less than initialBackoffSeconds")
+	case *limits.ResetAfterSeconds < *limits.MaxBackoffSeconds:
+		return constable.Error("resetAfterSeconds must not be less than maxBackoffSeconds")
+	}
+	return nil
+}
+
+func validateAudit(audit *AuditSpec) error {
+	_, err := auditlog.New(ioutil.Discard, audit.RedactedFields, time.Now)
+	return err
+}
+
+func validateMetrics(metrics *MetricsSpec) error {
+	if metrics.Address == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(metrics.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	return nil
+}
+
+func validateTracing(tracing *TracingSpec) error {
+	if *tracing.SampleRatio < 0 || *tracing.SampleRatio > 1 {
+		return constable.Error("sampleRatio must be between 0 and 1")
+	}
+	if tracing.Endpoint == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(tracing.Endpoint); err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if tracing.Insecure && tracing.CAFile != "" {
+		return constable.Error("caFile must not be set when insecure is true")
+	}
+	return nil
+}
+
+func validateEndpoints(endpoints *EndpointsSpec) error {
+	if err := validateEndpoint(endpoints.HTTPS); err != nil {
+		return fmt.Errorf("https: %w", err)
+	}
+	if err := validateEndpoint(endpoints.HTTP); err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	if endpoints.HTTPS.Network == NetworkDisabled && endpoints.HTTP.Network == NetworkDisabled {
+		return constable.Error("https and http must not both be disabled")
+	}
+	return nil
+}
+
+func validateEndpoint(endpoint *Endpoint) error {
+	switch endpoint.Network {
+	case NetworkDisabled:
+		return nil
+	case NetworkTCP:
+		if _, _, err := net.SplitHostPort(endpoint.Address); err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+	case NetworkUnix:
+		if endpoint.Address == "" {
+			return constable.Error("address must be the path of a socket")
+		}
+	default:
+		return fmt.Errorf("unknown network %q, must be one of %s, %s or %s", endpoint.Network, NetworkTCP, NetworkUnix, NetworkDisabled)
+	}
+	return nil
+}
+
+func validateTLS(tlsSpec *TLSSpec) error {
+	_, err := tlsprofile.FromSpec(&tlsSpec.Spec)
+	return err
+}
+
+func validateShutdown(shutdown *ShutdownSpec) error {
+	if *shutdown.DelaySeconds < 0 {
+		return constable.Error("delaySeconds must not be negative")
+	}
+	if *shutdown.GracePeriodSeconds < 0 {
+		return constable.Error("gracePeriodSeconds must not be negative")
+	}
+	return nil
+}
+
+func validateTrustedProxies(trustedProxies *TrustedProxiesSpec, endpoints *EndpointsSpec) error {
+	if _, err := sourceip.ParseTrustedProxies(trustedProxies.CIDRs); err != nil {
+		return err
+	}
+	if len(trustedProxies.CIDRs) > 0 {
+		return nil
+	}
+	for _, endpoint := range []*Endpoint{endpoints.HTTPS, endpoints.HTTP} {
+		if endpoint.Network == NetworkTCP && endpoint.ProxyProtocol {
+			return constable.Error("cidrs must be set when a tcp endpoint uses proxyProtocol")
+		}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 func TestFromPath(t *testing.T) {
@@ -26,12 +27,64 @@ func TestFromPath(t *testing.T) {
 			name: "Happy",
 			yaml: here.Doc(`
 				---
+				api:
+				  servingCertificate:
+				    durationSeconds: 3600
+				    renewBeforeSeconds: 1800
 				apiGroupSuffix: some.suffix.com
 				labels:
 				  myLabelKey1: myLabelValue1
 				  myLabelKey2: myLabelValue2
 				names:
 				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- name: my-hsm
+				  pkcs11:
+				    modulePath: /usr/lib/softhsm/libsofthsm2.so
+				    tokenLabel: my-token
+				    pinFile: /etc/hsm/pin
+				- name: my-kms
+				  plugin:
+				    command: /usr/local/bin/kms-signer
+				    args: [--region, us-east-1]
+				symmetricKeyRotation:
+				  intervalSeconds: 86400
+				loginRateLimits:
+				  usernameFailures: 10
+				  sourceIPFailures: 0
+				  initialBackoffSeconds: 5
+				  maxBackoffSeconds: 300
+				  resetAfterSeconds: 600
+				audit:
+				  file: /var/log/pinniped/audit.log
+				  redactedFields: [username, groups]
+				metrics:
+				  address: ":8081"
+				tracing:
+				  endpoint: otel-collector.observability.svc:4317
+				  caFile: /etc/otel/ca.crt
+				  sampleRatio: 0.25
+				endpoints:
+				  https:
+				    network: unix
+				    address: /var/run/pinniped/https.sock
+				  http:
+				    network: tcp
+				    address: "[::]:8080"
+				    proxyProtocol: true
+				tls:
+				  profile: intermediate
+				  minVersion: "1.2"
+				  curvePreferences: [X25519, P256]
+				  cipherSuites: [TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256]
+				  clientCAFile: /etc/proxy/ca.crt
+				shutdown:
+				  delaySeconds: 3
+				  gracePeriodSeconds: 10
+				trustedProxies:
+				  cidrs: [10.0.0.0/8, "2001:db8::/32"]
 			`),
 			wantConfig: &Config{
 				APIGroupSuffix: pointer.StringPtr("some.suffix.com"),
@@ -39,8 +92,75 @@ func TestFromPath(t *testing.T) {
 					"myLabelKey1": "myLabelValue1",
 					"myLabelKey2": "myLabelValue2",
 				},
+				APIConfig: APIConfigSpec{
+					ServingCertificateConfig: ServingCertificateConfigSpec{
+						DurationSeconds:    pointer.Int64Ptr(3600),
+						RenewBeforeSeconds: pointer.Int64Ptr(1800),
+					},
+				},
 				NamesConfig: NamesConfigSpec{
 					DefaultTLSCertificateSecret: "my-secret-name",
+					ServingCertificateSecret:    "my-serving-secret-name",
+					APIService:                  "my-api-service-name",
+				},
+				Signers: []SignerSpec{
+					{
+						Name: "my-hsm",
+						PKCS11: &PKCS11SignerSpec{
+							ModulePath: "/usr/lib/softhsm/libsofthsm2.so",
+							TokenLabel: "my-token",
+							PINFile:    "/etc/hsm/pin",
+						},
+					},
+					{
+						Name: "my-kms",
+						Plugin: &PluginSignerSpec{
+							Command: "/usr/local/bin/kms-signer",
+							Args:    []string{"--region", "us-east-1"},
+						},
+					},
+				},
+				SymmetricKeyRotation: SymmetricKeyRotationSpec{
+					IntervalSeconds: pointer.Int64Ptr(86400),
+				},
+				LoginRateLimits: LoginRateLimitsSpec{
+					UsernameFailures:      pointer.Int64Ptr(10),
+					SourceIPFailures:      pointer.Int64Ptr(0),
+					InitialBackoffSeconds: pointer.Int64Ptr(5),
+					MaxBackoffSeconds:     pointer.Int64Ptr(300),
+					ResetAfterSeconds:     pointer.Int64Ptr(600),
+				},
+				Audit: AuditSpec{
+					File:           "/var/log/pinniped/audit.log",
+					RedactedFields: []string{"username", "groups"},
+				},
+				Metrics: MetricsSpec{
+					Address: ":8081",
+				},
+				Tracing: TracingSpec{
+					Endpoint:    "otel-collector.observability.svc:4317",
+					CAFile:      "/etc/otel/ca.crt",
+					SampleRatio: pointer.Float64Ptr(0.25),
+				},
+				Endpoints: EndpointsSpec{
+					HTTPS: &Endpoint{Network: "unix", Address: "/var/run/pinniped/https.sock"},
+					HTTP:  &Endpoint{Network: "tcp", Address: "[::]:8080", ProxyProtocol: true},
+				},
+				TLS: TLSSpec{
+					Spec: tlsprofile.Spec{
+						Profile:          "intermediate",
+						MinVersion:       "1.2",
+						CipherSuites:     []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+						CurvePreferences: []string{"X25519", "P256"},
+					},
+					ClientCAFile: "/etc/proxy/ca.crt",
+				},
+				Shutdown: ShutdownSpec{
+					DelaySeconds:       pointer.Int64Ptr(3),
+					GracePeriodSeconds: pointer.Int64Ptr(10),
+				},
+				TrustedProxies: TrustedProxiesSpec{
+					CIDRs: []string{"10.0.0.0/8", "2001:db8::/32"},
 				},
 			},
 		},
@@ -50,21 +170,494 @@ func TestFromPath(t *testing.T) {
 				---
 				names:
 				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
 			`),
 			wantConfig: &Config{
 				APIGroupSuffix: pointer.StringPtr("pinniped.dev"),
 				Labels:         map[string]string{},
+				APIConfig: APIConfigSpec{
+					ServingCertificateConfig: ServingCertificateConfigSpec{
+						DurationSeconds:    pointer.Int64Ptr(60 * 60 * 24 * 365),
+						RenewBeforeSeconds: pointer.Int64Ptr(60 * 60 * 24 * 30 * 9),
+					},
+				},
 				NamesConfig: NamesConfigSpec{
 					DefaultTLSCertificateSecret: "my-secret-name",
+					ServingCertificateSecret:    "my-serving-secret-name",
+					APIService:                  "my-api-service-name",
+				},
+				SymmetricKeyRotation: SymmetricKeyRotationSpec{
+					IntervalSeconds: pointer.Int64Ptr(60 * 60 * 24 * 30),
+				},
+				LoginRateLimits: LoginRateLimitsSpec{
+					UsernameFailures:      pointer.Int64Ptr(5),
+					SourceIPFailures:      pointer.Int64Ptr(50),
+					InitialBackoffSeconds: pointer.Int64Ptr(30),
+					MaxBackoffSeconds:     pointer.Int64Ptr(15 * 60),
+					ResetAfterSeconds:     pointer.Int64Ptr(60 * 60),
+				},
+				Tracing: TracingSpec{
+					SampleRatio: pointer.Float64Ptr(1),
+				},
+				Endpoints: EndpointsSpec{
+					HTTPS: &Endpoint{Network: "tcp", Address: ":8443"},
+					HTTP:  &Endpoint{Network: "tcp", Address: ":8080"},
+				},
+				Shutdown: ShutdownSpec{
+					DelaySeconds:       pointer.Int64Ptr(10),
+					GracePeriodSeconds: pointer.Int64Ptr(25),
 				},
 			},
 		},
 		{
-			name: "Missing defaultTLSCertificateSecret name",
+			name: "Symmetric key rotation is turned off",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				symmetricKeyRotation:
+				  intervalSeconds: 0
+			`),
+			wantConfig: &Config{
+				APIGroupSuffix: pointer.StringPtr("pinniped.dev"),
+				Labels:         map[string]string{},
+				APIConfig: APIConfigSpec{
+					ServingCertificateConfig: ServingCertificateConfigSpec{
+						DurationSeconds:    pointer.Int64Ptr(60 * 60 * 24 * 365),
+						RenewBeforeSeconds: pointer.Int64Ptr(60 * 60 * 24 * 30 * 9),
+					},
+				},
+				NamesConfig: NamesConfigSpec{
+					DefaultTLSCertificateSecret: "my-secret-name",
+					ServingCertificateSecret:    "my-serving-secret-name",
+					APIService:                  "my-api-service-name",
+				},
+				SymmetricKeyRotation: SymmetricKeyRotationSpec{
+					IntervalSeconds: pointer.Int64Ptr(0),
+				},
+				LoginRateLimits: LoginRateLimitsSpec{
+					UsernameFailures:      pointer.Int64Ptr(5),
+					SourceIPFailures:      pointer.Int64Ptr(50),
+					InitialBackoffSeconds: pointer.Int64Ptr(30),
+					MaxBackoffSeconds:     pointer.Int64Ptr(15 * 60),
+					ResetAfterSeconds:     pointer.Int64Ptr(60 * 60),
+				},
+				Tracing: TracingSpec{
+					SampleRatio: pointer.Float64Ptr(1),
+				},
+				Endpoints: EndpointsSpec{
+					HTTPS: &Endpoint{Network: "tcp", Address: ":8443"},
+					HTTP:  &Endpoint{Network: "tcp", Address: ":8080"},
+				},
+				Shutdown: ShutdownSpec{
+					DelaySeconds:       pointer.Int64Ptr(10),
+					GracePeriodSeconds: pointer.Int64Ptr(25),
+				},
+			},
+		},
+		{
+			name: "Negative symmetric key rotation interval",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				symmetricKeyRotation:
+				  intervalSeconds: -1
+			`),
+			wantError: "validate symmetricKeyRotation: intervalSeconds must not be negative",
+		},
+		{
+			name: "Negative login failures",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				loginRateLimits:
+				  sourceIPFailures: -1
+			`),
+			wantError: "validate loginRateLimits: usernameFailures and sourceIPFailures must not be negative",
+		},
+		{
+			name: "Zero initial login backoff",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				loginRateLimits:
+				  initialBackoffSeconds: 0
+			`),
+			wantError: "validate loginRateLimits: initialBackoffSeconds must be positive",
+		},
+		{
+			name: "Max login backoff less than initial backoff",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				loginRateLimits:
+				  initialBackoffSeconds: 60
+				  maxBackoffSeconds: 30
+			`),
+			wantError: "validate loginRateLimits: maxBackoffSeconds must not be less than initialBackoffSeconds",
+		},
+		{
+			name: "Login failures forgotten before the max backoff ends",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				loginRateLimits:
+				  resetAfterSeconds: 60
+			`),
+			wantError: "validate loginRateLimits: resetAfterSeconds must not be less than maxBackoffSeconds",
+		},
+		{
+			name: "Metrics address without a port",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				metrics:
+				  address: localhost
+			`),
+			wantError: "validate metrics: invalid address: address localhost: missing port in address",
+		},
+		{
+			name: "Tracing sample ratio greater than 1",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tracing:
+				  sampleRatio: 1.5
+			`),
+			wantError: "validate tracing: sampleRatio must be between 0 and 1",
+		},
+		{
+			name: "Tracing endpoint without a port",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tracing:
+				  endpoint: otel-collector
+			`),
+			wantError: "validate tracing: invalid endpoint: address otel-collector: missing port in address",
+		},
+		{
+			name: "Insecure tracing with a CA file",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tracing:
+				  endpoint: otel-collector:4317
+				  insecure: true
+				  caFile: /etc/otel/ca.crt
+			`),
+			wantError: "validate tracing: caFile must not be set when insecure is true",
+		},
+		{
+			name: "IPv6 https endpoint",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				endpoints:
+				  https:
+				    network: tcp
+				    address: "[::]:8443"
+			`),
+			wantConfig: &Config{
+				APIGroupSuffix: pointer.StringPtr("pinniped.dev"),
+				Labels:         map[string]string{},
+				APIConfig: APIConfigSpec{
+					ServingCertificateConfig: ServingCertificateConfigSpec{
+						DurationSeconds:    pointer.Int64Ptr(60 * 60 * 24 * 365),
+						RenewBeforeSeconds: pointer.Int64Ptr(60 * 60 * 24 * 30 * 9),
+					},
+				},
+				NamesConfig: NamesConfigSpec{
+					DefaultTLSCertificateSecret: "my-secret-name",
+					ServingCertificateSecret:    "my-serving-secret-name",
+					APIService:                  "my-api-service-name",
+				},
+				SymmetricKeyRotation: SymmetricKeyRotationSpec{
+					IntervalSeconds: pointer.Int64Ptr(60 * 60 * 24 * 30),
+				},
+				LoginRateLimits: LoginRateLimitsSpec{
+					UsernameFailures:      pointer.Int64Ptr(5),
+					SourceIPFailures:      pointer.Int64Ptr(50),
+					InitialBackoffSeconds: pointer.Int64Ptr(30),
+					MaxBackoffSeconds:     pointer.Int64Ptr(15 * 60),
+					ResetAfterSeconds:     pointer.Int64Ptr(60 * 60),
+				},
+				Tracing: TracingSpec{
+					SampleRatio: pointer.Float64Ptr(1),
+				},
+				Endpoints: EndpointsSpec{
+					HTTPS: &Endpoint{Network: "tcp", Address: "[::]:8443"},
+					HTTP:  &Endpoint{Network: "tcp", Address: ":8080"},
+				},
+				Shutdown: ShutdownSpec{
+					DelaySeconds:       pointer.Int64Ptr(10),
+					GracePeriodSeconds: pointer.Int64Ptr(25),
+				},
+			},
+		},
+		{
+			name: "Both endpoints disabled",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				endpoints:
+				  https:
+				    network: disabled
+				  http:
+				    network: disabled
+			`),
+			wantError: "validate endpoints: https and http must not both be disabled",
+		},
+		{
+			name: "Endpoint with an unknown network",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				endpoints:
+				  http:
+				    network: udp
+				    address: ":8080"
+			`),
+			wantError: `validate endpoints: http: unknown network "udp", must be one of tcp, unix or disabled`,
+		},
+		{
+			name: "TCP endpoint without a port",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				endpoints:
+				  https:
+				    network: tcp
+				    address: localhost
+			`),
+			wantError: "validate endpoints: https: invalid address: address localhost: missing port in address",
+		},
+		{
+			name: "Unix endpoint without a path",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				endpoints:
+				  https:
+				    network: unix
+			`),
+			wantError: "validate endpoints: https: address must be the path of a socket",
+		},
+		{
+			name: "Unknown TLS version",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tls:
+				  minVersion: "1.1"
+			`),
+			wantError: `validate tls: unknown minVersion "1.1", must be 1.2 or 1.3`,
+		},
+		{
+			name: "Cipher suites with TLS 1.3",
 			yaml: here.Doc(`
 				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tls:
+				  minVersion: "1.3"
+				  cipherSuites: [TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256]
+			`),
+			wantError: "validate tls: cipherSuites must not be set when the minimum version is 1.3",
+		},
+		{
+			name: "Insecure cipher suite",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tls:
+				  cipherSuites: [TLS_RSA_WITH_RC4_128_SHA]
+			`),
+			wantError: `validate tls: unknown or insecure TLS 1.2 cipher suite "TLS_RSA_WITH_RC4_128_SHA"`,
+		},
+		{
+			name: "TLS 1.3 cipher suite",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tls:
+				  cipherSuites: [TLS_AES_128_GCM_SHA256]
+			`),
+			wantError: `validate tls: unknown or insecure TLS 1.2 cipher suite "TLS_AES_128_GCM_SHA256"`,
+		},
+		{
+			name: "Unknown TLS profile",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				tls:
+				  profile: old
+			`),
+			wantError: `validate tls: unknown profile "old", must be modern or intermediate`,
+		},
+		{
+			name: "Negative shutdown grace period",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				shutdown:
+				  gracePeriodSeconds: -1
+			`),
+			wantError: "validate shutdown: gracePeriodSeconds must not be negative",
+		},
+		{
+			name: "Negative shutdown delay",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				shutdown:
+				  delaySeconds: -1
+			`),
+			wantError: "validate shutdown: delaySeconds must not be negative",
+		},
+		{
+			name: "Invalid trusted proxy CIDR",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				trustedProxies:
+				  cidrs: [10.0.0.1]
+			`),
+			wantError: `validate trustedProxies: invalid CIDR "10.0.0.1": invalid CIDR address: 10.0.0.1`,
+		},
+		{
+			name: "PROXY protocol without trusted proxies",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				endpoints:
+				  https:
+				    network: tcp
+				    address: ":8443"
+				    proxyProtocol: true
 			`),
-			wantError: "validate names: missing required names: defaultTLSCertificateSecret",
+			wantError: "validate trustedProxies: cidrs must be set when a tcp endpoint uses proxyProtocol",
+		},
+		{
+			name: "Unknown redacted audit field",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				audit:
+				  redactedFields: [password]
+			`),
+			wantError: `validate audit: unknown audit log field "password", must be one of actor, audience, clientID, grantType, groups, issuer, reason, requestID, sourceIP, storageType, tokenType, upstreamName, upstreamType, username`,
+		},
+		{
+			name: "Missing names",
+			yaml: here.Doc(`
+				---
+			`),
+			wantError: "validate names: missing required names: defaultTLSCertificateSecret, servingCertificateSecret, apiService",
+		},
+		{
+			name: "Serving certificate duration is shorter than renewBefore",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				api:
+				  servingCertificate:
+				    durationSeconds: 2000
+				    renewBeforeSeconds: 3000
+			`),
+			wantError: "validate api: durationSeconds cannot be smaller than renewBeforeSeconds",
+		},
+		{
+			name: "Serving certificate renewBefore is not positive",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				api:
+				  servingCertificate:
+				    durationSeconds: 2000
+				    renewBeforeSeconds: -10
+			`),
+			wantError: "validate api: renewBefore must be positive",
 		},
 		{
 			name: "apiGroupSuffix is prefixed with '.'",
@@ -73,9 +666,105 @@ func TestFromPath(t *testing.T) {
 				apiGroupSuffix: .starts.with.dot
 				names:
 				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
 			`),
 			wantError: "validate apiGroupSuffix: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
 		},
+		{
+			name: "signer without a name",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- plugin:
+				    command: /usr/local/bin/kms-signer
+			`),
+			wantError: "validate signers: signer at index 0 is missing a name",
+		},
+		{
+			name: "signers with the same name",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- name: my-signer
+				  plugin:
+				    command: /usr/local/bin/kms-signer
+				- name: my-signer
+				  plugin:
+				    command: /usr/local/bin/other-signer
+			`),
+			wantError: `validate signers: duplicate signer name "my-signer"`,
+		},
+		{
+			name: "signer without a backend",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- name: my-signer
+			`),
+			wantError: `validate signers: signer "my-signer" must configure one of pkcs11 or plugin`,
+		},
+		{
+			name: "signer with two backends",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- name: my-signer
+				  pkcs11:
+				    modulePath: /usr/lib/softhsm/libsofthsm2.so
+				    tokenLabel: my-token
+				    pinFile: /etc/hsm/pin
+				  plugin:
+				    command: /usr/local/bin/kms-signer
+			`),
+			wantError: `validate signers: signer "my-signer" must configure only one of pkcs11 or plugin`,
+		},
+		{
+			name: "pkcs11 signer with missing fields",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- name: my-signer
+				  pkcs11:
+				    modulePath: /usr/lib/softhsm/libsofthsm2.so
+			`),
+			wantError: `validate signers: signer "my-signer" must configure pkcs11.modulePath, pkcs11.tokenLabel, and pkcs11.pinFile`,
+		},
+		{
+			name: "plugin signer without a command",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				  servingCertificateSecret: my-serving-secret-name
+				  apiService: my-api-service-name
+				signers:
+				- name: my-signer
+				  plugin:
+				    args: [--foo]
+			`),
+			wantError: `validate signers: signer "my-signer" must configure plugin.command`,
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -3,17 +3,186 @@
 
 package supervisor
 
-import "go.pinniped.dev/internal/plog"
+import (
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
+)
 
 // Config contains knobs to setup an instance of the Pinniped Supervisor.
 type Config struct {
+	APIConfig      APIConfigSpec     `json:"api"`
 	APIGroupSuffix *string           `json:"apiGroupSuffix,omitempty"`
 	Labels         map[string]string `json:"labels"`
 	NamesConfig    NamesConfigSpec   `json:"names"`
 	LogLevel       plog.LogLevel     `json:"logLevel"`
+	Signers        []SignerSpec      `json:"signers,omitempty"`
+
+	SymmetricKeyRotation SymmetricKeyRotationSpec `json:"symmetricKeyRotation"`
+	LoginRateLimits      LoginRateLimitsSpec      `json:"loginRateLimits"`
+	Audit                AuditSpec                `json:"audit"`
+	Metrics              MetricsSpec              `json:"metrics"`
+	Tracing              TracingSpec              `json:"tracing"`
+	Endpoints            EndpointsSpec            `json:"endpoints"`
+	TLS                  TLSSpec                  `json:"tls"`
+	Shutdown             ShutdownSpec             `json:"shutdown"`
+	TrustedProxies       TrustedProxiesSpec       `json:"trustedProxies"`
+}
+
+// APIConfigSpec configures the Supervisor's aggregated API, which serves its SupervisorSessions.
+type APIConfigSpec struct {
+	ServingCertificateConfig ServingCertificateConfigSpec `json:"servingCertificate"`
+}
+
+// ServingCertificateConfigSpec configures the generated certificate with which the aggregated API is served.
+type ServingCertificateConfigSpec struct {
+	// DurationSeconds is the validity period, in seconds, of the serving certificate and of its CA certificate.
+	// Defaults to 31536000 seconds (1 year).
+	DurationSeconds *int64 `json:"durationSeconds,omitempty"`
+
+	// RenewBeforeSeconds is how long after it is issued the serving certificate is rotated. It must not be longer
+	// than DurationSeconds. Defaults to 23328000 seconds (about 9 months).
+	RenewBeforeSeconds *int64 `json:"renewBeforeSeconds,omitempty"`
 }
 
 // NamesConfigSpec configures the names of some Kubernetes resources for the Supervisor.
 type NamesConfigSpec struct {
 	DefaultTLSCertificateSecret string `json:"defaultTLSCertificateSecret"`
+	ServingCertificateSecret    string `json:"servingCertificateSecret"`
+	APIService                  string `json:"apiService"`
+}
+
+// SymmetricKeyRotationSpec configures the automatic rotation of the generated keys which sign the Supervisor's CSRF
+// cookies and sign and encrypt the state params which it sends to upstream identity providers.
+type SymmetricKeyRotationSpec struct {
+	// IntervalSeconds is how long each key is used before it is replaced. The previous key is still accepted for
+	// as long as the values which were encoded with it are valid. Keys are not rotated when it is zero.
+	IntervalSeconds *int64 `json:"intervalSeconds,omitempty"`
+}
+
+// LoginRateLimitsSpec configures how the Supervisor slows down the guessing of passwords for upstream identity
+// providers, such as LDAP, to which it sends the usernames and passwords of its users.
+type LoginRateLimitsSpec struct {
+	// UsernameFailures is how many failed logins for a username are allowed before further logins for it are
+	// rejected for a while. Zero turns off this limit.
+	UsernameFailures *int64 `json:"usernameFailures,omitempty"`
+	// SourceIPFailures is how many failed logins from an IP address are allowed before further logins from it are
+	// rejected for a while. Zero turns off this limit.
+	SourceIPFailures *int64 `json:"sourceIPFailures,omitempty"`
+	// InitialBackoffSeconds is how long logins are rejected when a limit is first reached. The time doubles with
+	// each further failure, up to MaxBackoffSeconds.
+	InitialBackoffSeconds *int64 `json:"initialBackoffSeconds,omitempty"`
+	MaxBackoffSeconds     *int64 `json:"maxBackoffSeconds,omitempty"`
+	// ResetAfterSeconds is how long after the most recent failure all failures are forgotten.
+	ResetAfterSeconds *int64 `json:"resetAfterSeconds,omitempty"`
+}
+
+// AuditSpec configures the audit log of authentication events, which is written as one JSON object per line.
+type AuditSpec struct {
+	// File is the path of a file to which events are appended. Events are written to stdout when it is empty.
+	File string `json:"file,omitempty"`
+	// RedactedFields are the names of the fields of the events whose values are replaced, e.g. "username".
+	RedactedFields []string `json:"redactedFields,omitempty"`
+}
+
+// MetricsSpec configures the listener which serves the Supervisor's Prometheus metrics.
+type MetricsSpec struct {
+	// Address is the host and port on which the metrics are served over plain HTTP at /metrics, e.g. ":8081".
+	// The metrics are not served when it is empty.
+	Address string `json:"address,omitempty"`
+	// ProxyProtocol allows the connections from trusted proxies to start with a PROXY protocol version 1 or 2 header,
+	// whose source address is then used as the address of the client.
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+}
+
+// TracingSpec configures the export of the Supervisor's OpenTelemetry traces to a collector.
+type TracingSpec struct {
+	// Endpoint is the host and port of an OTLP gRPC collector, e.g. "otel-collector.observability.svc:4317".
+	// Traces are not recorded when it is empty.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure sends the traces without TLS. Otherwise the collector's certificate is verified using CAFile, or the
+	// system's trusted CAs when CAFile is empty.
+	Insecure bool   `json:"insecure,omitempty"`
+	CAFile   string `json:"caFile,omitempty"`
+	// SampleRatio is the fraction of traces which are recorded, from 0 to 1. Defaults to 1. This also applies to the
+	// traces which were started by a caller, whatever the caller decided about recording them.
+	SampleRatio *float64 `json:"sampleRatio,omitempty"`
+}
+
+const (
+	NetworkTCP      = "tcp"
+	NetworkUnix     = "unix"
+	NetworkDisabled = "disabled"
+)
+
+// EndpointsSpec configures the listeners on which the Supervisor serves its OIDC endpoints, /healthz and /readyz.
+type EndpointsSpec struct {
+	// HTTPS defaults to the TCP address ":8443".
+	HTTPS *Endpoint `json:"https,omitempty"`
+	// HTTP defaults to the TCP address ":8080".
+	HTTP *Endpoint `json:"http,omitempty"`
+}
+
+// Endpoint is the network and address of one listener.
+type Endpoint struct {
+	// Network is "tcp", "unix" or "disabled". A disabled listener is not started.
+	Network string `json:"network"`
+	// Address is a host and port for "tcp", e.g. ":8443" or "[::]:8443", or the path of a socket for "unix", e.g.
+	// "/var/run/pinniped/https.sock".
+	Address string `json:"address,omitempty"`
+	// ProxyProtocol allows the connections from trusted proxies to start with a PROXY protocol version 1 or 2 header,
+	// whose source address is then used as the address of the client.
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+}
+
+// TLSSpec configures the TLS of the HTTPS listener and of the Supervisor's connections to upstream identity
+// providers, OpenTelemetry collectors and trusted clusters.
+type TLSSpec struct {
+	tlsprofile.Spec `json:",inline"`
+	// ClientCAFile is the path of a PEM file of CA certificates. When it is set, every client of the HTTPS listener
+	// must present a certificate which is signed by one of them, e.g. a reverse proxy in front of the Supervisor.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+}
+
+// ShutdownSpec configures how the Supervisor stops when it receives SIGTERM or SIGINT.
+type ShutdownSpec struct {
+	// DelaySeconds is how long the Supervisor keeps accepting new connections after it receives the signal, while its
+	// /readyz fails, so that its pod can be removed from the endpoints of its Services and from load balancers before
+	// it stops listening. Defaults to 10.
+	DelaySeconds *int64 `json:"delaySeconds,omitempty"`
+	// GracePeriodSeconds is how long the Supervisor waits for in-flight requests to finish after it stops accepting
+	// new connections. Defaults to 25. The terminationGracePeriodSeconds of the pod must be longer than the sum of
+	// DelaySeconds and GracePeriodSeconds.
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+}
+
+// TrustedProxiesSpec configures which proxies in front of the Supervisor, e.g. load balancers and Ingress controllers,
+// are trusted to report the addresses of their clients.
+type TrustedProxiesSpec struct {
+	// CIDRs are the networks of the trusted proxies, e.g. "10.0.0.0/8". The Forwarded or X-Forwarded-For,
+	// X-Forwarded-Host and X-Forwarded-Proto headers of requests from these networks, and from unix sockets, are used.
+	CIDRs []string `json:"cidrs,omitempty"`
+}
+
+// SignerSpec configures a signer which FederationDomains can use to sign tokens with keys that are kept outside of
+// Kubernetes. Exactly one of PKCS11 or Plugin must be set.
+type SignerSpec struct {
+	Name   string            `json:"name"`
+	PKCS11 *PKCS11SignerSpec `json:"pkcs11,omitempty"`
+	Plugin *PluginSignerSpec `json:"plugin,omitempty"`
+}
+
+// PKCS11SignerSpec configures a signer which uses keys in a PKCS#11 token, e.g. in a hardware security module.
+type PKCS11SignerSpec struct {
+	// ModulePath is the path of the PKCS#11 library which is loaded into the Supervisor.
+	ModulePath string `json:"modulePath"`
+	// TokenLabel is the label of the token which holds the keys.
+	TokenLabel string `json:"tokenLabel"`
+	// PINFile is the path of a file which contains the PIN of the token's user, e.g. from a mounted Secret.
+	PINFile string `json:"pinFile"`
+}
+
+// PluginSignerSpec configures a signer which runs an external program for each of its operations.
+type PluginSignerSpec struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
 }
@@ -8,6 +8,7 @@ import (
 	"context"
 	"sort"
 	"sync"
+	"time"
 
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -105,7 +106,9 @@ func (c *Cache) AuthenticateTokenCredentialRequest(ctx context.Context, req *log
 	ctx = valuelesscontext.New(ctx)
 
 	// Call the selected authenticator.
+	start := time.Now()
 	resp, authenticated, err := val.AuthenticateToken(ctx, req.Spec.Token)
+	observeAuthentication(key, start)
 	if err != nil {
 		return nil, err
 	}
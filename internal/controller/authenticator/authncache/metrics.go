@@ -0,0 +1,35 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package authncache
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var authenticatorDuration = metrics.NewHistogramVec(
+	&metrics.HistogramOpts{
+		Namespace:      "pinniped",
+		Subsystem:      "concierge",
+		Name:           "authenticator_duration_seconds",
+		Help:           "How long each WebhookAuthenticator and JWTAuthenticator took to authenticate tokens.",
+		Buckets:        metrics.ExponentialBuckets(0.001, 2, 14),
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"authenticator_type", "authenticator_name"},
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(authenticatorDuration)
+}
+
+// observeAuthentication measures an authentication with the authenticator of the key, which must have been found in
+// the cache, so that the labels cannot take arbitrary values from requests.
+func observeAuthentication(key Key, start time.Time) {
+	authenticatorDuration.WithLabelValues(key.Kind, key.Name).Observe(time.Since(start).Seconds())
+}
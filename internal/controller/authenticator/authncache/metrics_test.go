@@ -0,0 +1,51 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package authncache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/testutil"
+
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+	"go.pinniped.dev/internal/mocks/mocktokenauthenticator"
+)
+
+func TestAuthenticatorDurationMetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	m := mocktokenauthenticator.NewMockToken(ctrl)
+	m.EXPECT().AuthenticateToken(gomock.Any(), "test-token").Return(nil, false, nil)
+	m.EXPECT().AuthenticateToken(gomock.Any(), "test-token").Return(nil, false, fmt.Errorf("some authenticator error"))
+
+	c := New()
+	c.Store(Key{Kind: "JWTAuthenticator", Name: "test-measured-jwt"}, m)
+
+	request := func(kind, name string) *loginapi.TokenCredentialRequest {
+		return &loginapi.TokenCredentialRequest{Spec: loginapi.TokenCredentialRequestSpec{
+			Authenticator: corev1.TypedLocalObjectReference{Kind: kind, Name: name},
+			Token:         "test-token",
+		}}
+	}
+
+	_, err := c.AuthenticateTokenCredentialRequest(context.Background(), request("JWTAuthenticator", "test-measured-jwt"))
+	require.NoError(t, err)
+	_, err = c.AuthenticateTokenCredentialRequest(context.Background(), request("JWTAuthenticator", "test-measured-jwt"))
+	require.EqualError(t, err, "some authenticator error")
+	_, err = c.AuthenticateTokenCredentialRequest(context.Background(), request("JWTAuthenticator", "test-unmeasured-jwt"))
+	require.ErrorIs(t, err, ErrNoSuchAuthenticator)
+
+	count, err := testutil.GetHistogramMetricCount(authenticatorDuration.WithLabelValues("JWTAuthenticator", "test-measured-jwt"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), count)
+
+	count, err = testutil.GetHistogramMetricCount(authenticatorDuration.WithLabelValues("JWTAuthenticator", "test-unmeasured-jwt"))
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -6,19 +6,23 @@
 package jwtcachefiller
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 
 	"github.com/go-logr/logr"
 	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/server/dynamiccertificates"
 	"k8s.io/apiserver/plugin/pkg/authenticator/token/oidc"
 	"k8s.io/klog/v2"
 
 	auth1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/authentication/v1alpha1"
 	authinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions/authentication/v1alpha1"
+	"go.pinniped.dev/internal/constable"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	pinnipedauthenticator "go.pinniped.dev/internal/controller/authenticator"
 	"go.pinniped.dev/internal/controller/authenticator/authncache"
@@ -32,6 +36,13 @@ const (
 	defaultGroupsClaim   = "groups"
 )
 
+// These are the keys of the user extras which name the actor of a token which has an RFC 8693 "act" claim, e.g.
+// a token which the Supervisor issued to a client acting on behalf of the user.
+const (
+	actorSubjectExtraKey  = "authentication.concierge.pinniped.dev/actor-subject"
+	actorUsernameExtraKey = "authentication.concierge.pinniped.dev/actor-username"
+)
+
 // defaultSupportedSigningAlgos returns the default signing algos that this JWTAuthenticator
 // supports (i.e., if none are supplied by the user).
 func defaultSupportedSigningAlgos() []string {
@@ -180,7 +191,62 @@ func newJWTAuthenticator(spec *auth1alpha1.JWTAuthenticatorSpec) (*jwtAuthentica
 	}
 
 	return &jwtAuthenticator{
-		tokenAuthenticatorCloser: authenticator,
+		tokenAuthenticatorCloser: &actorClaimAuthenticator{tokenAuthenticatorCloser: authenticator},
 		spec:                     spec,
 	}, nil
 }
+
+// actorClaim is the RFC 8693 "act" claim.
+type actorClaim struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+}
+
+// actorClaimAuthenticator maps the "act" claim of an authenticated token into the user extras, so the actor is
+// recorded next to the user.
+type actorClaimAuthenticator struct {
+	tokenAuthenticatorCloser
+}
+
+func (a *actorClaimAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	rsp, authenticated, err := a.tokenAuthenticatorCloser.AuthenticateToken(ctx, token)
+	if err != nil || !authenticated {
+		return rsp, authenticated, err
+	}
+
+	// The signature of the token was already verified by the delegate.
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not parse token: %w", err)
+	}
+	var claims struct {
+		Act *actorClaim `json:"act"`
+	}
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, false, fmt.Errorf("could not read claims of token: %w", err)
+	}
+	if claims.Act == nil {
+		return rsp, true, nil
+	}
+	if claims.Act.Subject == "" {
+		return nil, false, constable.Error("act claim is missing sub")
+	}
+
+	extra := map[string][]string{}
+	for k, v := range rsp.User.GetExtra() {
+		extra[k] = v
+	}
+	extra[actorSubjectExtraKey] = []string{claims.Act.Subject}
+	if claims.Act.Username != "" {
+		extra[actorUsernameExtraKey] = []string{claims.Act.Username}
+	}
+	return &authenticator.Response{
+		Audiences: rsp.Audiences,
+		User: &user.DefaultInfo{
+			Name:   rsp.User.GetName(),
+			UID:    rsp.User.GetUID(),
+			Groups: rsp.User.GetGroups(),
+			Extra:  extra,
+		},
+	}, true, nil
+}
@@ -420,6 +420,7 @@ func TestController(t *testing.T) {
 						groups,
 						tt.wantUsernameClaim,
 						username,
+						test.jwtExtraClaims,
 					)
 
 					// Loop for a while here to allow the underlying OIDC authenticator to initialize itself asynchronously.
@@ -465,6 +466,7 @@ func testTableForAuthenticateTokenTests(
 ) []struct {
 	name              string
 	jwtClaims         func(wellKnownClaims *jwt.Claims, groups *interface{}, username *string)
+	jwtExtraClaims    map[string]interface{}
 	jwtSignature      func(key *interface{}, algo *jose.SignatureAlgorithm, kid *string)
 	wantResponse      *authenticator.Response
 	wantAuthenticated bool
@@ -473,6 +475,7 @@ func testTableForAuthenticateTokenTests(
 	tests := []struct {
 		name              string
 		jwtClaims         func(wellKnownClaims *jwt.Claims, groups *interface{}, username *string)
+		jwtExtraClaims    map[string]interface{}
 		jwtSignature      func(key *interface{}, algo *jose.SignatureAlgorithm, kid *string)
 		wantResponse      *authenticator.Response
 		wantAuthenticated bool
@@ -527,6 +530,48 @@ func testTableForAuthenticateTokenTests(
 			},
 			wantAuthenticated: true,
 		},
+		{
+			name: "good token with an act claim",
+			jwtClaims: func(_ *jwt.Claims, groups *interface{}, username *string) {
+				*groups = []string{group0}
+			},
+			jwtExtraClaims: map[string]interface{}{
+				"act": map[string]interface{}{"sub": "some-actor-subject", "username": "some-actor-username"},
+			},
+			wantResponse: &authenticator.Response{
+				User: &user.DefaultInfo{
+					Name:   goodUsername,
+					Groups: []string{group0},
+					Extra: map[string][]string{
+						"authentication.concierge.pinniped.dev/actor-subject":  {"some-actor-subject"},
+						"authentication.concierge.pinniped.dev/actor-username": {"some-actor-username"},
+					},
+				},
+			},
+			wantAuthenticated: true,
+		},
+		{
+			name: "good token with an act claim which has no username",
+			jwtExtraClaims: map[string]interface{}{
+				"act": map[string]interface{}{"sub": "some-actor-subject"},
+			},
+			wantResponse: &authenticator.Response{
+				User: &user.DefaultInfo{
+					Name: goodUsername,
+					Extra: map[string][]string{
+						"authentication.concierge.pinniped.dev/actor-subject": {"some-actor-subject"},
+					},
+				},
+			},
+			wantAuthenticated: true,
+		},
+		{
+			name: "bad token with an act claim which has no sub",
+			jwtExtraClaims: map[string]interface{}{
+				"act": map[string]interface{}{"username": "some-actor-username"},
+			},
+			wantErrorRegexp: `act claim is missing sub`,
+		},
 		{
 			name: "good token with nbf unset",
 			jwtClaims: func(claims *jwt.Claims, _ *interface{}, username *string) {
@@ -646,6 +691,7 @@ func createJWT(
 	groupsValue interface{},
 	usernameClaim string,
 	usernameValue string,
+	extraClaims map[string]interface{},
 ) string {
 	t.Helper()
 
@@ -662,6 +708,9 @@ func createJWT(
 	if usernameValue != "" {
 		builder = builder.Claims(map[string]interface{}{usernameClaim: usernameValue})
 	}
+	if extraClaims != nil {
+		builder = builder.Claims(extraClaims)
+	}
 	jwt, err := builder.CompactSerialize()
 	require.NoError(t, err)
 
@@ -5,8 +5,11 @@
 package webhookcachefiller
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 
 	"github.com/go-logr/logr"
@@ -25,6 +28,7 @@ import (
 	pinnipedauthenticator "go.pinniped.dev/internal/controller/authenticator"
 	"go.pinniped.dev/internal/controller/authenticator/authncache"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 // New instantiates a new controllerlib.Controller which will populate the provided authncache.Cache.
@@ -91,10 +95,14 @@ func newWebhookAuthenticator(
 	defer func() { _ = os.Remove(temp.Name()) }()
 
 	cluster := &clientcmdapi.Cluster{Server: spec.Endpoint}
-	cluster.CertificateAuthorityData, err = pinnipedauthenticator.CABundle(spec.TLS)
+	caBundle, err := pinnipedauthenticator.CABundle(spec.TLS)
 	if err != nil {
 		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
 	}
+	customDial, err := tlsProfileDial(cluster, caBundle)
+	if err != nil {
+		return nil, err
+	}
 
 	kubeconfig := clientcmdapi.NewConfig()
 	kubeconfig.Clusters["anonymous-cluster"] = cluster
@@ -114,9 +122,40 @@ func newWebhookAuthenticator(
 	// requirements change.
 	var implicitAuds authenticator.Audiences
 
-	// We set this to nil because we would only need this to support some of the
-	// custom proxy stuff used by the API server.
-	var customDial net.DialFunc
-
 	return webhook.New(temp.Name(), version, implicitAuds, *webhook.DefaultRetryBackoff(), customDial)
 }
+
+// tlsProfileDial makes the webhook client use the TLS profile of the Concierge, when one is configured for its
+// clients. The kubeconfig of a webhook cannot configure its cipher suites or curves, so the client is instead told to
+// speak plain HTTP to the webhook's host and port, and the returned dial function does the TLS handshake itself, with
+// the CA bundle and the server name of the original https URL. This means that the client only uses HTTP/1.1, and that
+// a proxy from the environment would be dialed as if it were the webhook, so the handshake with the proxy would fail
+// instead of sending anything in the clear. Without a client profile, and for endpoints which are not https URLs, the
+// client is left to use the CA bundle with its own defaults.
+func tlsProfileDial(cluster *clientcmdapi.Cluster, caBundle []byte) (net.DialFunc, error) {
+	u, err := url.Parse(cluster.Server)
+	if err != nil {
+		return nil, err
+	}
+	profile := tlsprofile.ClientDefault()
+	if profile == nil || u.Scheme != "https" {
+		cluster.CertificateAuthorityData = caBundle
+		return nil, nil
+	}
+
+	config := profile.Config()
+	config.ServerName = u.Hostname()
+	if len(caBundle) > 0 {
+		config.RootCAs = x509.NewCertPool()
+		config.RootCAs.AppendCertsFromPEM(caBundle)
+	}
+
+	u.Scheme = "http"
+	if u.Port() == "" {
+		u.Host += ":443"
+	}
+	cluster.Server = u.String()
+
+	dialer := &tls.Dialer{Config: config}
+	return dialer.DialContext, nil
+}
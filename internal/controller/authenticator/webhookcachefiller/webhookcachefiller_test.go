@@ -5,10 +5,13 @@ package webhookcachefiller
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -25,6 +28,7 @@ import (
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/testlogger"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 func TestController(t *testing.T) {
@@ -175,4 +179,50 @@ func TestNewWebhookAuthenticator(t *testing.T) {
 		require.Nil(t, resp)
 		require.False(t, authenticated)
 	})
+	t.Run("keeps the client's defaults when no TLS profile is configured for clients", func(t *testing.T) {
+		cluster := &clientcmdapi.Cluster{Server: "https://example.com/authenticate"}
+		dial, err := tlsProfileDial(cluster, []byte("some-ca-bundle"))
+		require.NoError(t, err)
+		require.Nil(t, dial)
+		require.Equal(t, &clientcmdapi.Cluster{Server: "https://example.com/authenticate", CertificateAuthorityData: []byte("some-ca-bundle")}, cluster)
+	})
+	t.Run("uses the TLS profile which is configured for clients", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12} //nolint:gosec // the point is to not allow TLS 1.3
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+		intermediate, err := tlsprofile.FromSpec(&tlsprofile.Spec{Profile: tlsprofile.Intermediate})
+		require.NoError(t, err)
+		previous := tlsprofile.ClientDefault()
+		tlsprofile.SetClientDefault(intermediate)
+		t.Cleanup(func() { tlsprofile.SetClientDefault(previous) })
+
+		cluster := &clientcmdapi.Cluster{Server: server.URL + "/authenticate"}
+		dial, err := tlsProfileDial(cluster, caBundle)
+		require.NoError(t, err)
+		require.Equal(t, "http://"+server.Listener.Addr().String()+"/authenticate", cluster.Server)
+		require.Empty(t, cluster.CertificateAuthorityData)
+
+		conn, err := dial(context.Background(), "tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+
+		modern, err := tlsprofile.FromSpec(&tlsprofile.Spec{Profile: tlsprofile.Modern})
+		require.NoError(t, err)
+		tlsprofile.SetClientDefault(modern)
+		dial, err = tlsProfileDial(&clientcmdapi.Cluster{Server: server.URL}, caBundle)
+		require.NoError(t, err)
+		_, err = dial(context.Background(), "tcp", server.Listener.Addr().String())
+		require.EqualError(t, err, "remote error: tls: protocol version not supported")
+	})
+
+	t.Run("does not change endpoints which are not https", func(t *testing.T) {
+		cluster := &clientcmdapi.Cluster{Server: "http://example.com"}
+		dial, err := tlsProfileDial(cluster, []byte("some-ca-bundle"))
+		require.NoError(t, err)
+		require.Nil(t, dial)
+		require.Equal(t, &clientcmdapi.Cluster{Server: "http://example.com", CertificateAuthorityData: []byte("some-ca-bundle")}, cluster)
+	})
 }
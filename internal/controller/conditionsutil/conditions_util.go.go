@@ -10,59 +10,167 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
 )
 
 // Merge merges conditions into conditionsToUpdate. If returns true if it merged any error conditions.
 func Merge(conditions []*v1alpha1.Condition, observedGeneration int64, conditionsToUpdate *[]v1alpha1.Condition, log logr.Logger) bool {
 	hadErrorCondition := false
+	newConditions := make([]condition, 0, len(conditions))
 	for i := range conditions {
-		cond := conditions[i].DeepCopy()
-		cond.LastTransitionTime = v1.Now()
-		cond.ObservedGeneration = observedGeneration
-		if mergeCondition(conditionsToUpdate, cond) {
-			log.Info("updated condition", "type", cond.Type, "status", cond.Status, "reason", cond.Reason, "message", cond.Message)
-		}
-		if cond.Status == v1alpha1.ConditionFalse {
+		newConditions = append(newConditions, (*idpCondition)(conditions[i].DeepCopy()))
+		if conditions[i].Status == v1alpha1.ConditionFalse {
 			hadErrorCondition = true
 		}
 	}
-	sort.SliceStable(*conditionsToUpdate, func(i, j int) bool {
-		return (*conditionsToUpdate)[i].Type < (*conditionsToUpdate)[j].Type
+	mergeConditions(idpConditions{conditionsToUpdate}, newConditions, observedGeneration, v1.Now(), func(c condition) {
+		cond := c.(*idpCondition)
+		log.Info("updated condition", "type", cond.Type, "status", cond.Status, "reason", cond.Reason, "message", cond.Message)
 	})
 	return hadErrorCondition
 }
 
-// mergeCondition merges a new v1alpha1.Condition into a slice of existing conditions. It returns true
-// if the condition has meaningfully changed.
-func mergeCondition(existing *[]v1alpha1.Condition, new *v1alpha1.Condition) bool {
-	// Find any existing condition with a matching type.
-	var old *v1alpha1.Condition
-	for i := range *existing {
-		if (*existing)[i].Type == new.Type {
-			old = &(*existing)[i]
-			continue
+// MergeConfigConditions merges conditions into conditionsToUpdate, using now as the LastTransitionTime of any
+// condition whose status has changed. It returns true if any condition in conditionsToUpdate has changed.
+func MergeConfigConditions(
+	conditions []*configv1alpha1.Condition,
+	observedGeneration int64,
+	conditionsToUpdate *[]configv1alpha1.Condition,
+	now v1.Time,
+) bool {
+	newConditions := make([]condition, 0, len(conditions))
+	for i := range conditions {
+		newConditions = append(newConditions, (*configCondition)(conditions[i].DeepCopy()))
+	}
+	return mergeConditions(configConditions{conditionsToUpdate}, newConditions, observedGeneration, now, func(condition) {})
+}
+
+// condition is a Condition of either the idp or the config API group, so that both can share mergeConditions.
+type condition interface {
+	conditionType() string
+	conditionStatus() string
+	lastTransitionTime() v1.Time
+	setLastTransitionTime(t v1.Time)
+	setObservedGeneration(generation int64)
+	// set overwrites the condition with other, which must be of the same API group.
+	set(other condition)
+}
+
+// conditionList is a slice of the Conditions of either API group, sorted by their type.
+type conditionList interface {
+	sort.Interface
+	// find returns the condition of the given type, or nil when there is none.
+	find(conditionType string) condition
+	// append appends a copy of c, which must be of the same API group.
+	append(c condition)
+}
+
+// mergeConditions merges newConditions into existing and calls changed with each condition which changed. It
+// returns true if any condition has changed.
+func mergeConditions(
+	existing conditionList,
+	newConditions []condition,
+	observedGeneration int64,
+	now v1.Time,
+	changed func(condition),
+) bool {
+	anyChanged := false
+	for _, cond := range newConditions {
+		cond.setLastTransitionTime(now)
+		cond.setObservedGeneration(observedGeneration)
+		if mergeCondition(existing, cond) {
+			changed(cond)
+			anyChanged = true
 		}
 	}
+	sort.Stable(existing)
+	return anyChanged
+}
 
+// mergeCondition merges a new condition into a list of existing conditions. It returns true if the condition has
+// meaningfully changed.
+func mergeCondition(existing conditionList, new condition) bool {
 	// If there is no existing condition of this type, append this one and we're done.
+	old := existing.find(new.conditionType())
 	if old == nil {
-		*existing = append(*existing, *new)
+		existing.append(new)
 		return true
 	}
 
 	// Set the LastTransitionTime depending on whether the status has changed.
-	new = new.DeepCopy()
-	if old.Status == new.Status {
-		new.LastTransitionTime = old.LastTransitionTime
+	if old.conditionStatus() == new.conditionStatus() {
+		new.setLastTransitionTime(old.lastTransitionTime())
 	}
 
 	// If anything has actually changed, update the entry and return true.
 	if !equality.Semantic.DeepEqual(old, new) {
-		*old = *new
+		old.set(new)
 		return true
 	}
 
 	// Otherwise the entry is already up to date.
 	return false
 }
+
+type idpCondition v1alpha1.Condition
+
+func (c *idpCondition) conditionType() string           { return c.Type }
+func (c *idpCondition) conditionStatus() string         { return string(c.Status) }
+func (c *idpCondition) lastTransitionTime() v1.Time     { return c.LastTransitionTime }
+func (c *idpCondition) setLastTransitionTime(t v1.Time) { c.LastTransitionTime = t }
+func (c *idpCondition) setObservedGeneration(g int64)   { c.ObservedGeneration = g }
+func (c *idpCondition) set(other condition)             { *c = *other.(*idpCondition) }
+
+type idpConditions struct{ conditions *[]v1alpha1.Condition }
+
+func (l idpConditions) Len() int           { return len(*l.conditions) }
+func (l idpConditions) Less(i, j int) bool { return (*l.conditions)[i].Type < (*l.conditions)[j].Type }
+func (l idpConditions) Swap(i, j int) {
+	(*l.conditions)[i], (*l.conditions)[j] = (*l.conditions)[j], (*l.conditions)[i]
+}
+
+func (l idpConditions) find(conditionType string) condition {
+	for i := range *l.conditions {
+		if (*l.conditions)[i].Type == conditionType {
+			return (*idpCondition)(&(*l.conditions)[i])
+		}
+	}
+	return nil
+}
+
+func (l idpConditions) append(c condition) {
+	*l.conditions = append(*l.conditions, v1alpha1.Condition(*c.(*idpCondition)))
+}
+
+type configCondition configv1alpha1.Condition
+
+func (c *configCondition) conditionType() string           { return c.Type }
+func (c *configCondition) conditionStatus() string         { return string(c.Status) }
+func (c *configCondition) lastTransitionTime() v1.Time     { return c.LastTransitionTime }
+func (c *configCondition) setLastTransitionTime(t v1.Time) { c.LastTransitionTime = t }
+func (c *configCondition) setObservedGeneration(g int64)   { c.ObservedGeneration = g }
+func (c *configCondition) set(other condition)             { *c = *other.(*configCondition) }
+
+type configConditions struct{ conditions *[]configv1alpha1.Condition }
+
+func (l configConditions) Len() int { return len(*l.conditions) }
+func (l configConditions) Less(i, j int) bool {
+	return (*l.conditions)[i].Type < (*l.conditions)[j].Type
+}
+func (l configConditions) Swap(i, j int) {
+	(*l.conditions)[i], (*l.conditions)[j] = (*l.conditions)[j], (*l.conditions)[i]
+}
+
+func (l configConditions) find(conditionType string) condition {
+	for i := range *l.conditions {
+		if (*l.conditions)[i].Type == conditionType {
+			return (*configCondition)(&(*l.conditions)[i])
+		}
+	}
+	return nil
+}
+
+func (l configConditions) append(c condition) {
+	*l.conditions = append(*l.conditions, configv1alpha1.Condition(*c.(*configCondition)))
+}
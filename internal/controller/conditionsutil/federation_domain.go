@@ -0,0 +1,43 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package conditionsutil
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+)
+
+// UpdateFederationDomainConditions merges conditions into the status of a FederationDomain. The cachedFederationDomain
+// is the copy from an informer, which is used to skip calling the API when the conditions are already up to date.
+func UpdateFederationDomainConditions(
+	ctx context.Context,
+	client pinnipedclientset.Interface,
+	cachedFederationDomain *configv1alpha1.FederationDomain,
+	now v1.Time,
+	conditions ...*configv1alpha1.Condition,
+) error {
+	cachedConditions := append([]configv1alpha1.Condition(nil), cachedFederationDomain.Status.Conditions...)
+	if !MergeConfigConditions(conditions, cachedFederationDomain.Generation, &cachedConditions, now) {
+		return nil
+	}
+
+	federationDomains := client.ConfigV1alpha1().FederationDomains(cachedFederationDomain.Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		federationDomain, err := federationDomains.Get(ctx, cachedFederationDomain.Name, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("cannot get FederationDomain: %w", err)
+		}
+		if !MergeConfigConditions(conditions, federationDomain.Generation, &federationDomain.Status.Conditions, now) {
+			return nil
+		}
+		_, err = federationDomains.UpdateStatus(ctx, federationDomain, v1.UpdateOptions{})
+		return err
+	})
+}
@@ -309,6 +309,18 @@ func (c *agentController) loadSigningKey(agentPod *corev1.Pod) error {
 		return nil
 	}
 
+	err := c.fetchSigningKey(agentPod)
+	observeKeyFetch(err)
+	if err != nil {
+		return err
+	}
+
+	// Remember that we've successfully loaded the key from this pod so we can skip the exec+load if nothing has changed.
+	c.execCache.Set(agentPod.UID, struct{}{}, 15*time.Minute)
+	return nil
+}
+
+func (c *agentController) fetchSigningKey(agentPod *corev1.Pod) error {
 	// Exec into the agent pod and cat out the certificate and the key.
 	outputJSON, err := c.executor.Exec(agentPod.Namespace, agentPod.Name, "pinniped-concierge-kube-cert-agent", "print")
 	if err != nil {
@@ -336,9 +348,6 @@ func (c *agentController) loadSigningKey(agentPod *corev1.Pod) error {
 	if err := c.dynamicCertProvider.SetCertKeyContent(certPEM, keyPEM); err != nil {
 		return fmt.Errorf("failed to set signing cert/key content from agent pod %s/%s: %w", agentPod.Namespace, agentPod.Name, err)
 	}
-
-	// Remember that we've successfully loaded the key from this pod so we can skip the exec+load if nothing has changed.
-	c.execCache.Set(agentPod.UID, struct{}{}, 15*time.Minute)
 	return nil
 }
 
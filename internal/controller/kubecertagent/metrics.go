@@ -0,0 +1,34 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubecertagent
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var keyFetches = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Namespace:      "pinniped",
+		Subsystem:      "concierge",
+		Name:           "kube_cert_agent_key_fetches_total",
+		Help:           "Number of attempts to fetch the cluster signing key from the kube-cert-agent pod, by result.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"result"},
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(keyFetches)
+}
+
+func observeKeyFetch(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	keyFetches.WithLabelValues(result).Inc()
+}
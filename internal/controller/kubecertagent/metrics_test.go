@@ -0,0 +1,29 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubecertagent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestObserveKeyFetch(t *testing.T) {
+	counter := func(result string) float64 {
+		t.Helper()
+		value, err := testutil.GetCounterMetricValue(keyFetches.WithLabelValues(result))
+		require.NoError(t, err)
+		return value
+	}
+	successesBefore, failuresBefore := counter("success"), counter("failure")
+
+	observeKeyFetch(nil)
+	observeKeyFetch(errors.New("some exec error"))
+	observeKeyFetch(errors.New("some other exec error"))
+
+	require.Equal(t, successesBefore+1, counter("success"))
+	require.Equal(t, failuresBefore+2, counter("failure"))
+}
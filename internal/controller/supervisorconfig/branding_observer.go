@@ -0,0 +1,128 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	"go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/plog"
+)
+
+type IssuerToBrandingMapSetter interface {
+	SetIssuerToBrandingMap(issuerToBrandingMap map[string]*branding.Branding)
+}
+
+type brandingObserverController struct {
+	issuerToBrandingSetter   IssuerToBrandingMapSetter
+	client                   pinnipedclientset.Interface
+	clock                    clock.Clock
+	federationDomainInformer v1alpha1.FederationDomainInformer
+	configMapInformer        corev1informers.ConfigMapInformer
+}
+
+// NewBrandingObserverController returns a controller which loads the branding ConfigMap of each FederationDomain,
+// which customizes the look of the Supervisor's HTML pages for that FederationDomain.
+func NewBrandingObserverController(
+	issuerToBrandingSetter IssuerToBrandingMapSetter,
+	client pinnipedclientset.Interface,
+	clock clock.Clock,
+	configMapInformer corev1informers.ConfigMapInformer,
+	federationDomainInformer v1alpha1.FederationDomainInformer,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "branding-observer-controller",
+			Syncer: &brandingObserverController{
+				issuerToBrandingSetter:   issuerToBrandingSetter,
+				client:                   client,
+				clock:                    clock,
+				federationDomainInformer: federationDomainInformer,
+				configMapInformer:        configMapInformer,
+			},
+		},
+		withInformer(
+			configMapInformer,
+			pinnipedcontroller.MatchAnythingFilter(nil),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			federationDomainInformer,
+			pinnipedcontroller.MatchAnythingFilter(nil),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+func (c *brandingObserverController) Sync(ctx controllerlib.Context) error {
+	federationDomains, err := c.federationDomainInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list FederationDomains: %w", err)
+	}
+
+	// Rebuild the whole map on any change to any ConfigMap or FederationDomain. Issuers whose branding cannot be
+	// loaded are left out of the map, so their pages have the default look.
+	issuerToBrandingMap := map[string]*branding.Branding{}
+
+	var errs []error
+	for _, federationDomain := range federationDomains {
+		b, condition := c.loadBranding(federationDomain)
+		if b != nil {
+			issuerToBrandingMap[federationDomain.Spec.Issuer] = b
+		}
+		if err := conditionsutil.UpdateFederationDomainConditions(
+			ctx.Context,
+			c.client,
+			federationDomain,
+			metav1.NewTime(c.clock.Now()),
+			condition,
+		); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status of FederationDomain %s/%s: %w", federationDomain.Namespace, federationDomain.Name, err))
+		}
+	}
+
+	plog.Debug("brandingObserverController Sync updated the branding cache", "issuerCount", len(issuerToBrandingMap))
+	c.issuerToBrandingSetter.SetIssuerToBrandingMap(issuerToBrandingMap)
+
+	return errors.NewAggregate(errs)
+}
+
+func (c *brandingObserverController) loadBranding(federationDomain *configv1alpha1.FederationDomain) (*branding.Branding, *configv1alpha1.Condition) {
+	if federationDomain.Spec.Branding == nil || federationDomain.Spec.Branding.ConfigMap.Name == "" {
+		return nil, &configv1alpha1.Condition{
+			Type:    typeBrandingLoaded,
+			Status:  configv1alpha1.ConditionTrue,
+			Reason:  reasonDefaultBrandingUsed,
+			Message: "no branding ConfigMap is named in the spec, so the default branding is used",
+		}
+	}
+
+	configMapName := federationDomain.Spec.Branding.ConfigMap.Name
+	configMap, err := c.configMapInformer.Lister().ConfigMaps(federationDomain.Namespace).Get(configMapName)
+	if err != nil {
+		return nil, falseCondition(typeBrandingLoaded, reasonConfigMapNotLoaded,
+			fmt.Sprintf("cannot load branding from ConfigMap %q, so the default branding is used: %v", configMapName, err))
+	}
+
+	b, err := branding.FromConfigMap(configMap)
+	if err != nil {
+		return nil, falseCondition(typeBrandingLoaded, reasonInvalidBranding,
+			fmt.Sprintf("the branding in ConfigMap %q is invalid, so the default branding is used: %v", configMapName, err))
+	}
+
+	return b, trueCondition(typeBrandingLoaded, fmt.Sprintf("the branding is loaded from ConfigMap %q", configMapName))
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	kubeinformers "k8s.io/client-go/informers"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/testutil"
+)
+
+type fakeIssuerToBrandingMapSetter struct {
+	mutex               sync.Mutex
+	issuerToBrandingMap map[string]*branding.Branding
+}
+
+func (f *fakeIssuerToBrandingMapSetter) SetIssuerToBrandingMap(issuerToBrandingMap map[string]*branding.Branding) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.issuerToBrandingMap = issuerToBrandingMap
+}
+
+func TestBrandingObserverControllerFilters(t *testing.T) {
+	t.Parallel()
+
+	observableWithInformerOption := testutil.NewObservableWithInformerOption()
+	configMapInformer := kubeinformers.NewSharedInformerFactory(nil, 0).Core().V1().ConfigMaps()
+	federationDomainInformer := pinnipedinformers.NewSharedInformerFactory(nil, 0).Config().V1alpha1().FederationDomains()
+	_ = NewBrandingObserverController(
+		nil,
+		nil,
+		nil,
+		configMapInformer,
+		federationDomainInformer,
+		observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
+	)
+
+	configMapFilter := observableWithInformerOption.GetFilterForInformer(configMapInformer)
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"}}
+	otherConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-name", Namespace: "other-namespace"}}
+	require.True(t, configMapFilter.Add(configMap))
+	require.True(t, configMapFilter.Update(configMap, otherConfigMap))
+	require.True(t, configMapFilter.Delete(configMap))
+
+	federationDomainFilter := observableWithInformerOption.GetFilterForInformer(federationDomainInformer)
+	federationDomain := &v1alpha1.FederationDomain{ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"}}
+	otherFederationDomain := &v1alpha1.FederationDomain{ObjectMeta: metav1.ObjectMeta{Name: "other-name", Namespace: "other-namespace"}}
+	require.True(t, federationDomainFilter.Add(federationDomain))
+	require.True(t, federationDomainFilter.Update(federationDomain, otherFederationDomain))
+	require.True(t, federationDomainFilter.Delete(federationDomain))
+}
+
+func TestBrandingObserverControllerSync(t *testing.T) {
+	t.Parallel()
+
+	const testNamespace = "test-namespace"
+	now := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	federationDomain := func(name, configMapName string) *v1alpha1.FederationDomain {
+		fd := &v1alpha1.FederationDomain{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Spec:       v1alpha1.FederationDomainSpec{Issuer: "https://" + name + ".example.com"},
+		}
+		if configMapName != "" {
+			fd.Spec.Branding = &v1alpha1.FederationDomainBrandingSpec{ConfigMap: corev1.LocalObjectReference{Name: configMapName}}
+		}
+		return fd
+	}
+	configMap := func(name string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace}, Data: data}
+	}
+	condition := func(status v1alpha1.ConditionStatus, reason, message string) []v1alpha1.Condition {
+		return []v1alpha1.Condition{{
+			Type:               "BrandingLoaded",
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now),
+			Reason:             reason,
+			Message:            message,
+		}}
+	}
+
+	tests := []struct {
+		name                    string
+		federationDomains       []runtime.Object
+		configMaps              []runtime.Object
+		failStatusUpdates       bool
+		wantIssuerToBrandingMap map[string]*branding.Branding
+		wantConditions          map[string][]v1alpha1.Condition
+		wantError               string
+	}{
+		{
+			name:                    "no FederationDomains",
+			wantIssuerToBrandingMap: map[string]*branding.Branding{},
+			wantConditions:          map[string][]v1alpha1.Condition{},
+		},
+		{
+			name: "FederationDomains with and without branding",
+			federationDomains: []runtime.Object{
+				federationDomain("default", ""),
+				federationDomain("branded", "good-branding"),
+				federationDomain("missing", "no-such-branding"),
+				federationDomain("invalid", "bad-branding"),
+			},
+			configMaps: []runtime.Object{
+				configMap("good-branding", map[string]string{"productName": "Acme SSO", "primaryColor": "#1b3951"}),
+				configMap("bad-branding", map[string]string{"primaryColor": "blue"}),
+			},
+			wantIssuerToBrandingMap: map[string]*branding.Branding{
+				"https://branded.example.com": {ProductName: "Acme SSO", PrimaryColor: "#1b3951"},
+			},
+			wantConditions: map[string][]v1alpha1.Condition{
+				"default": condition(v1alpha1.ConditionTrue, "DefaultBrandingUsed",
+					"no branding ConfigMap is named in the spec, so the default branding is used"),
+				"branded": condition(v1alpha1.ConditionTrue, "Success",
+					`the branding is loaded from ConfigMap "good-branding"`),
+				"missing": condition(v1alpha1.ConditionFalse, "ConfigMapNotLoaded",
+					`cannot load branding from ConfigMap "no-such-branding", so the default branding is used: configmap "no-such-branding" not found`),
+				"invalid": condition(v1alpha1.ConditionFalse, "InvalidBranding",
+					`the branding in ConfigMap "bad-branding" is invalid, so the default branding is used: primaryColor must be a CSS hex color like #1b3951, not "blue"`),
+			},
+		},
+		{
+			name:              "updating the status fails",
+			federationDomains: []runtime.Object{federationDomain("branded", "good-branding")},
+			configMaps:        []runtime.Object{configMap("good-branding", map[string]string{"productName": "Acme SSO"})},
+			failStatusUpdates: true,
+			wantIssuerToBrandingMap: map[string]*branding.Branding{
+				"https://branded.example.com": {ProductName: "Acme SSO"},
+			},
+			wantConditions: map[string][]v1alpha1.Condition{"branded": nil},
+			wantError:      "could not update status of FederationDomain test-namespace/branded: some update error",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakePinnipedClient := pinnipedfake.NewSimpleClientset(tt.federationDomains...)
+			if tt.failStatusUpdates {
+				fakePinnipedClient.PrependReactor("update", "federationdomains", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some update error")
+				})
+			}
+			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(fakePinnipedClient, 0)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(kubernetesfake.NewSimpleClientset(tt.configMaps...), 0)
+			issuerToBrandingSetter := &fakeIssuerToBrandingMapSetter{}
+
+			controller := NewBrandingObserverController(
+				issuerToBrandingSetter,
+				fakePinnipedClient,
+				clock.NewFakeClock(now),
+				kubeInformers.Core().V1().ConfigMaps(),
+				pinnipedInformers.Config().V1alpha1().FederationDomains(),
+				controllerlib.WithInformer,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			pinnipedInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			err := controllerlib.TestSync(t, controller, controllerlib.Context{Context: ctx, Key: controllerlib.Key{}})
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.wantIssuerToBrandingMap, issuerToBrandingSetter.issuerToBrandingMap)
+
+			actual, err := fakePinnipedClient.ConfigV1alpha1().FederationDomains(testNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			actualConditions := map[string][]v1alpha1.Condition{}
+			for _, fd := range actual.Items {
+				actualConditions[fd.Name] = fd.Status.Conditions
+			}
+			require.Equal(t, tt.wantConditions, actualConditions)
+		})
+	}
+}
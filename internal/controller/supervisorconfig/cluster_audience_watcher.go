@@ -0,0 +1,201 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
+	"go.pinniped.dev/internal/plog"
+)
+
+// ClustersSetter can be notified of all valid clusters which may receive tokens using token exchange.
+// Implementations of this type should be thread-safe to support calls from multiple goroutines.
+type ClustersSetter interface {
+	SetClusters(clusters []*clusteraudience.Cluster)
+}
+
+type clusterAudienceWatcherController struct {
+	clustersSetter          ClustersSetter
+	clock                   clock.Clock
+	client                  pinnipedclientset.Interface
+	clusterAudienceInformer configinformers.ClusterAudienceInformer
+}
+
+// NewClusterAudienceWatcherController creates a controllerlib.Controller that watches ClusterAudience objects and
+// notifies a ClustersSetter of the valid ones.
+func NewClusterAudienceWatcherController(
+	clustersSetter ClustersSetter,
+	clock clock.Clock,
+	client pinnipedclientset.Interface,
+	clusterAudienceInformer configinformers.ClusterAudienceInformer,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "ClusterAudienceWatcherController",
+			Syncer: &clusterAudienceWatcherController{
+				clustersSetter:          clustersSetter,
+				clock:                   clock,
+				client:                  client,
+				clusterAudienceInformer: clusterAudienceInformer,
+			},
+		},
+		withInformer(
+			clusterAudienceInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *clusterAudienceWatcherController) Sync(ctx controllerlib.Context) error {
+	clusterAudiences, err := c.clusterAudienceInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	// Count how many times each audience is used, to complain about duplicates. Neither of the duplicates can be
+	// used, since it would be unclear which of them decides who may receive tokens.
+	audienceCounts := make(map[string]int)
+	for _, clusterAudience := range clusterAudiences {
+		audienceCounts[clusterAudience.Spec.Audience]++
+	}
+
+	var errs []error
+
+	clusters := make([]*clusteraudience.Cluster, 0, len(clusterAudiences))
+	for _, clusterAudience := range clusterAudiences {
+		if audienceCounts[clusterAudience.Spec.Audience] > 1 {
+			if err := c.updateStatus(
+				ctx.Context,
+				clusterAudience.Namespace,
+				clusterAudience.Name,
+				configv1alpha1.DuplicateClusterAudienceStatusCondition,
+				"Duplicate audience: "+clusterAudience.Spec.Audience,
+			); err != nil {
+				errs = append(errs, fmt.Errorf("could not update status: %w", err))
+			}
+			continue
+		}
+
+		cluster, err := clusterFromSpec(&clusterAudience.Spec)
+		if err != nil {
+			if err := c.updateStatus(
+				ctx.Context,
+				clusterAudience.Namespace,
+				clusterAudience.Name,
+				configv1alpha1.InvalidClusterAudienceStatusCondition,
+				"Invalid: "+err.Error(),
+			); err != nil {
+				errs = append(errs, fmt.Errorf("could not update status: %w", err))
+			}
+			continue
+		}
+
+		if err := c.updateStatus(
+			ctx.Context,
+			clusterAudience.Namespace,
+			clusterAudience.Name,
+			configv1alpha1.SuccessClusterAudienceStatusCondition,
+			"Tokens may be issued for this audience",
+		); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status: %w", err))
+			continue
+		}
+
+		clusters = append(clusters, cluster)
+	}
+
+	c.clustersSetter.SetClusters(clusters)
+
+	return errors.NewAggregate(errs)
+}
+
+func clusterFromSpec(spec *configv1alpha1.ClusterAudienceSpec) (*clusteraudience.Cluster, error) {
+	if spec.Audience == "" {
+		return nil, fmt.Errorf("audience must not be empty")
+	}
+	if spec.Audience == clientregistry.PinnipedCLI().GetID() {
+		// Tokens for this audience would be accepted as the Supervisor's own ID tokens.
+		return nil, fmt.Errorf("audience must not be the client ID %q", spec.Audience)
+	}
+
+	for _, pattern := range spec.GroupsFilter {
+		if pattern == "" || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+			return nil, fmt.Errorf("groupsFilter entry %q must be a group name, optionally followed by a single \"*\"", pattern)
+		}
+	}
+
+	cluster := &clusteraudience.Cluster{
+		Audience:      spec.Audience,
+		AllowedGroups:      spec.AllowedGroups,
+		AllowedActorGroups: spec.AllowedActorGroups,
+		GroupsFilter:       spec.GroupsFilter,
+	}
+	if spec.Claims == nil {
+		cluster.IncludeUsername = true
+		cluster.IncludeGroups = true
+	}
+	for _, claim := range spec.Claims {
+		switch claim {
+		case configv1alpha1.UsernameClusterAudienceClaim:
+			cluster.IncludeUsername = true
+		case configv1alpha1.GroupsClusterAudienceClaim:
+			cluster.IncludeGroups = true
+		default:
+			return nil, fmt.Errorf("unknown claim %q", claim)
+		}
+	}
+	return cluster, nil
+}
+
+func (c *clusterAudienceWatcherController) updateStatus(
+	ctx context.Context,
+	namespace, name string,
+	status configv1alpha1.ClusterAudienceStatusCondition,
+	message string,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterAudience, err := c.client.ConfigV1alpha1().ClusterAudiences(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get failed: %w", err)
+		}
+
+		if clusterAudience.Status.Status == status && clusterAudience.Status.Message == message {
+			return nil
+		}
+
+		plog.Debug(
+			"attempting status update",
+			"clusteraudience",
+			klog.KRef(namespace, name),
+			"status",
+			status,
+			"message",
+			message,
+		)
+		clusterAudience.Status.Status = status
+		clusterAudience.Status.Message = message
+		clusterAudience.Status.LastUpdateTime = timePtr(metav1.NewTime(c.clock.Now()))
+		_, err = c.client.ConfigV1alpha1().ClusterAudiences(namespace).UpdateStatus(ctx, clusterAudience, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
+	"go.pinniped.dev/internal/testutil"
+)
+
+type fakeClustersSetter struct {
+	mutex    sync.Mutex
+	clusters []*clusteraudience.Cluster
+}
+
+func (f *fakeClustersSetter) SetClusters(clusters []*clusteraudience.Cluster) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.clusters = clusters
+}
+
+func TestClusterAudienceWatcherControllerFilter(t *testing.T) {
+	t.Parallel()
+
+	observableWithInformerOption := testutil.NewObservableWithInformerOption()
+	clusterAudienceInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).Config().V1alpha1().ClusterAudiences()
+	_ = NewClusterAudienceWatcherController(
+		nil,
+		nil,
+		nil,
+		clusterAudienceInformer,
+		observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
+	)
+	filter := observableWithInformerOption.GetFilterForInformer(clusterAudienceInformer)
+
+	target := &v1alpha1.ClusterAudience{ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"}}
+	other := &v1alpha1.ClusterAudience{ObjectMeta: metav1.ObjectMeta{Name: "other-name", Namespace: "other-namespace"}}
+	require.True(t, filter.Add(target))
+	require.True(t, filter.Update(target, other))
+	require.True(t, filter.Delete(target))
+	require.Equal(t, controllerlib.Key{}, filter.Parent(target))
+}
+
+func TestClusterAudienceWatcherControllerSync(t *testing.T) {
+	t.Parallel()
+
+	const testNamespace = "test-namespace"
+
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	frozenMetav1Now := metav1.NewTime(now)
+
+	newClusterAudience := func(name string, spec v1alpha1.ClusterAudienceSpec) *v1alpha1.ClusterAudience {
+		return &v1alpha1.ClusterAudience{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Spec:       spec,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		inputObjects []runtime.Object
+		wantClusters []*clusteraudience.Cluster
+		wantStatuses map[string]v1alpha1.ClusterAudienceStatus
+	}{
+		{
+			name:         "no ClusterAudiences",
+			wantClusters: []*clusteraudience.Cluster{},
+			wantStatuses: map[string]v1alpha1.ClusterAudienceStatus{},
+		},
+		{
+			name: "valid ClusterAudiences",
+			inputObjects: []runtime.Object{
+				newClusterAudience("a", v1alpha1.ClusterAudienceSpec{Audience: "cluster-a"}),
+				newClusterAudience("b", v1alpha1.ClusterAudienceSpec{
+					Audience:           "cluster-b",
+					AllowedGroups:      []string{"admins"},
+					AllowedActorGroups: []string{"support-portals"},
+					Claims:             []v1alpha1.ClusterAudienceClaim{v1alpha1.GroupsClusterAudienceClaim},
+					GroupsFilter:       []string{"team-*", "admins"},
+				}),
+			},
+			wantClusters: []*clusteraudience.Cluster{
+				{Audience: "cluster-a", IncludeUsername: true, IncludeGroups: true},
+				{Audience: "cluster-b", AllowedGroups: []string{"admins"}, AllowedActorGroups: []string{"support-portals"}, IncludeGroups: true, GroupsFilter: []string{"team-*", "admins"}},
+			},
+			wantStatuses: map[string]v1alpha1.ClusterAudienceStatus{
+				"a": {Status: v1alpha1.SuccessClusterAudienceStatusCondition, Message: "Tokens may be issued for this audience", LastUpdateTime: &frozenMetav1Now},
+				"b": {Status: v1alpha1.SuccessClusterAudienceStatusCondition, Message: "Tokens may be issued for this audience", LastUpdateTime: &frozenMetav1Now},
+			},
+		},
+		{
+			name: "duplicate audiences are not used",
+			inputObjects: []runtime.Object{
+				newClusterAudience("a", v1alpha1.ClusterAudienceSpec{Audience: "cluster-a"}),
+				newClusterAudience("b", v1alpha1.ClusterAudienceSpec{Audience: "cluster-a"}),
+				newClusterAudience("c", v1alpha1.ClusterAudienceSpec{Audience: "cluster-c"}),
+			},
+			wantClusters: []*clusteraudience.Cluster{
+				{Audience: "cluster-c", IncludeUsername: true, IncludeGroups: true},
+			},
+			wantStatuses: map[string]v1alpha1.ClusterAudienceStatus{
+				"a": {Status: v1alpha1.DuplicateClusterAudienceStatusCondition, Message: "Duplicate audience: cluster-a", LastUpdateTime: &frozenMetav1Now},
+				"b": {Status: v1alpha1.DuplicateClusterAudienceStatusCondition, Message: "Duplicate audience: cluster-a", LastUpdateTime: &frozenMetav1Now},
+				"c": {Status: v1alpha1.SuccessClusterAudienceStatusCondition, Message: "Tokens may be issued for this audience", LastUpdateTime: &frozenMetav1Now},
+			},
+		},
+		{
+			name: "invalid ClusterAudiences are not used",
+			inputObjects: []runtime.Object{
+				newClusterAudience("empty", v1alpha1.ClusterAudienceSpec{}),
+				newClusterAudience("cli", v1alpha1.ClusterAudienceSpec{Audience: "pinniped-cli"}),
+				newClusterAudience("filter", v1alpha1.ClusterAudienceSpec{Audience: "cluster-a", GroupsFilter: []string{"a*b"}}),
+				newClusterAudience("claim", v1alpha1.ClusterAudienceSpec{Audience: "cluster-b", Claims: []v1alpha1.ClusterAudienceClaim{"email"}}),
+			},
+			wantClusters: []*clusteraudience.Cluster{},
+			wantStatuses: map[string]v1alpha1.ClusterAudienceStatus{
+				"empty":  {Status: v1alpha1.InvalidClusterAudienceStatusCondition, Message: "Invalid: audience must not be empty", LastUpdateTime: &frozenMetav1Now},
+				"cli":    {Status: v1alpha1.InvalidClusterAudienceStatusCondition, Message: `Invalid: audience must not be the client ID "pinniped-cli"`, LastUpdateTime: &frozenMetav1Now},
+				"filter": {Status: v1alpha1.InvalidClusterAudienceStatusCondition, Message: `Invalid: groupsFilter entry "a*b" must be a group name, optionally followed by a single "*"`, LastUpdateTime: &frozenMetav1Now},
+				"claim":  {Status: v1alpha1.InvalidClusterAudienceStatusCondition, Message: `Invalid: unknown claim "email"`, LastUpdateTime: &frozenMetav1Now},
+			},
+		},
+		{
+			name: "an unchanged status is not updated",
+			inputObjects: []runtime.Object{
+				&v1alpha1.ClusterAudience{
+					ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: testNamespace},
+					Spec:       v1alpha1.ClusterAudienceSpec{Audience: "cluster-a"},
+					Status: v1alpha1.ClusterAudienceStatus{
+						Status:         v1alpha1.SuccessClusterAudienceStatusCondition,
+						Message:        "Tokens may be issued for this audience",
+						LastUpdateTime: &metav1.Time{},
+					},
+				},
+			},
+			wantClusters: []*clusteraudience.Cluster{
+				{Audience: "cluster-a", IncludeUsername: true, IncludeGroups: true},
+			},
+			wantStatuses: map[string]v1alpha1.ClusterAudienceStatus{
+				"a": {Status: v1alpha1.SuccessClusterAudienceStatusCondition, Message: "Tokens may be issued for this audience", LastUpdateTime: &metav1.Time{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakePinnipedClient := pinnipedfake.NewSimpleClientset(tt.inputObjects...)
+			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(fakePinnipedClient, 0)
+			clustersSetter := &fakeClustersSetter{}
+
+			controller := NewClusterAudienceWatcherController(
+				clustersSetter,
+				clock.NewFakeClock(now),
+				fakePinnipedClient,
+				pinnipedInformers.Config().V1alpha1().ClusterAudiences(),
+				controllerlib.WithInformer,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			pinnipedInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+			require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+
+			require.ElementsMatch(t, tt.wantClusters, clustersSetter.clusters)
+
+			actual, err := fakePinnipedClient.ConfigV1alpha1().ClusterAudiences(testNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			actualStatuses := map[string]v1alpha1.ClusterAudienceStatus{}
+			for _, clusterAudience := range actual.Items {
+				actualStatuses[clusterAudience.Name] = clusterAudience.Status
+			}
+			require.Equal(t, tt.wantStatuses, actualStatuses)
+		})
+	}
+}
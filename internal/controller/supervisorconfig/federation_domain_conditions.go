@@ -0,0 +1,63 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+)
+
+// The types of the FederationDomain conditions which are written by the controllers in this package. The conditions
+// about the symmetric keys are written by the generator package.
+const (
+	typeIssuerURLValid                = "IssuerURLValid"
+	typeIssuerIsUnique                = "IssuerIsUnique"
+	typeOneTLSSecretPerIssuerHostname = "OneTLSSecretPerIssuerHostname"
+	typeSettingsValid                 = "SettingsValid"
+	typeIdentityProvidersFound        = "IdentityProvidersFound"
+	typeJWKSPresent                   = "JWKSPresent"
+	typeTLSSecretLoaded               = "TLSSecretLoaded"
+	typeBrandingLoaded                = "BrandingLoaded"
+
+	reasonSuccess                   = "Success"
+	reasonUnableToValidate          = "UnableToValidate"
+	reasonInvalidIssuerURL          = "InvalidIssuerURL"
+	reasonDuplicateIssuer           = "DuplicateIssuer"
+	reasonDifferentSecretRefsFound  = "DifferentSecretRefsFound"
+	reasonInvalidSettings           = "InvalidSettings"
+	reasonIdentityProvidersNotFound = "IdentityProvidersNotFound"
+	reasonSignerConfigured          = "SignerConfigured"
+	reasonSuppliedSecretInvalid     = "SuppliedSecretInvalid"
+	reasonDefaultCertificateUsed    = "DefaultCertificateUsed"
+	reasonSecretNotLoaded           = "SecretNotLoaded"
+	reasonDefaultBrandingUsed       = "DefaultBrandingUsed"
+	reasonConfigMapNotLoaded        = "ConfigMapNotLoaded"
+	reasonInvalidBranding           = "InvalidBranding"
+)
+
+func trueCondition(conditionType, message string) *configv1alpha1.Condition {
+	return &configv1alpha1.Condition{
+		Type:    conditionType,
+		Status:  configv1alpha1.ConditionTrue,
+		Reason:  reasonSuccess,
+		Message: message,
+	}
+}
+
+func falseCondition(conditionType, reason, message string) *configv1alpha1.Condition {
+	return &configv1alpha1.Condition{
+		Type:    conditionType,
+		Status:  configv1alpha1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+func unknownCondition(conditionType, message string) *configv1alpha1.Condition {
+	return &configv1alpha1.Condition{
+		Type:    conditionType,
+		Status:  configv1alpha1.ConditionUnknown,
+		Reason:  reasonUnableToValidate,
+		Message: message,
+	}
+}
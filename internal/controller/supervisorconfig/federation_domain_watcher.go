@@ -8,19 +8,28 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
+	idpinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/idp/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/supervisorconfig/generator"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/jwks"
 	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/plog"
 )
@@ -37,6 +46,11 @@ type federationDomainWatcherController struct {
 	clock                    clock.Clock
 	client                   pinnipedclientset.Interface
 	federationDomainInformer configinformers.FederationDomainInformer
+	secretInformer           corev1informers.SecretInformer
+	signerBackends           jwks.SignerBackends
+
+	oidcIdentityProviderInformer idpinformers.OIDCIdentityProviderInformer
+	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer
 }
 
 // NewFederationDomainWatcherController creates a controllerlib.Controller that watches
@@ -46,6 +60,10 @@ func NewFederationDomainWatcherController(
 	clock clock.Clock,
 	client pinnipedclientset.Interface,
 	federationDomainInformer configinformers.FederationDomainInformer,
+	secretInformer corev1informers.SecretInformer,
+	signerBackends jwks.SignerBackends,
+	oidcIdentityProviderInformer idpinformers.OIDCIdentityProviderInformer,
+	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
 	return controllerlib.New(
@@ -56,6 +74,11 @@ func NewFederationDomainWatcherController(
 				clock:                    clock,
 				client:                   client,
 				federationDomainInformer: federationDomainInformer,
+				secretInformer:           secretInformer,
+				signerBackends:           signerBackends,
+
+				oidcIdentityProviderInformer: oidcIdentityProviderInformer,
+				ldapIdentityProviderInformer: ldapIdentityProviderInformer,
 			},
 		},
 		withInformer(
@@ -63,6 +86,24 @@ func NewFederationDomainWatcherController(
 			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
 			controllerlib.InformerOption{},
 		),
+		// Secrets which were supplied by the administrators of FederationDomains have the same types as the
+		// generated Secrets, so watch for all of those types to validate the supplied Secrets again.
+		withInformer(
+			secretInformer,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(isFederationDomainKeySecret),
+			controllerlib.InformerOption{},
+		),
+		// The identity providers are watched to check again whether the ones named by each FederationDomain exist.
+		withInformer(
+			oidcIdentityProviderInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			ldapIdentityProviderInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
 	)
 }
 
@@ -89,7 +130,15 @@ func (c *federationDomainWatcherController) Sync(ctx controllerlib.Context) erro
 	uniqueSecretNamesPerIssuerAddress := make(map[string]map[string]bool)
 	issuerURLToHostnameKey := lowercaseHostWithoutPort
 
+	// Make a map of Secret names -> count of how many times the Secret was supplied in the spec of a
+	// FederationDomain. This will help us complain when a supplied Secret is used for more than one key.
+	suppliedSecretCounts := make(map[string]int)
+
 	for _, federationDomain := range federationDomains {
+		for _, secretName := range suppliedSecretNames(federationDomain.Spec.Secrets) {
+			suppliedSecretCounts[secretName]++
+		}
+
 		issuerURL, err := url.Parse(federationDomain.Spec.Issuer)
 		if err != nil {
 			continue // Skip url parse errors because they will be validated again below.
@@ -111,64 +160,105 @@ func (c *federationDomainWatcherController) Sync(ctx controllerlib.Context) erro
 
 	federationDomainIssuers := make([]*provider.FederationDomainIssuer, 0)
 	for _, federationDomain := range federationDomains {
+		var conditions []*configv1alpha1.Condition
+		var legacyStatus configv1alpha1.FederationDomainStatusCondition
+		var legacyMessage string
+
 		issuerURL, urlParseErr := url.Parse(federationDomain.Spec.Issuer)
 
 		// Skip url parse errors because they will be validated below.
-		if urlParseErr == nil {
-			if issuerCount := issuerCounts[issuerURLToIssuerKey(issuerURL)]; issuerCount > 1 {
-				if err := c.updateStatus(
-					ctx.Context,
-					federationDomain.Namespace,
-					federationDomain.Name,
-					configv1alpha1.DuplicateFederationDomainStatusCondition,
-					"Duplicate issuer: "+federationDomain.Spec.Issuer,
-				); err != nil {
-					errs = append(errs, fmt.Errorf("could not update status: %w", err))
+		switch {
+		case urlParseErr != nil:
+			conditions = append(conditions,
+				unknownCondition(typeIssuerIsUnique, "unable to check if issuer is unique because URL cannot be parsed"),
+				unknownCondition(typeOneTLSSecretPerIssuerHostname, "unable to check if TLS secrets are consistent because URL cannot be parsed"),
+			)
+		default:
+			if issuerCounts[issuerURLToIssuerKey(issuerURL)] > 1 {
+				conditions = append(conditions, falseCondition(typeIssuerIsUnique, reasonDuplicateIssuer,
+					"multiple FederationDomains have the same issuer URL: "+federationDomain.Spec.Issuer))
+				legacyStatus = configv1alpha1.DuplicateFederationDomainStatusCondition
+				legacyMessage = "Duplicate issuer: " + federationDomain.Spec.Issuer
+			} else {
+				conditions = append(conditions, trueCondition(typeIssuerIsUnique, "there is no other FederationDomain with the same issuer URL"))
+			}
+			if len(uniqueSecretNamesPerIssuerAddress[issuerURLToHostnameKey(issuerURL)]) > 1 {
+				conditions = append(conditions, falseCondition(typeOneTLSSecretPerIssuerHostname, reasonDifferentSecretRefsFound,
+					"other FederationDomains with the same issuer DNS hostname must use the same secretName: "+issuerURLToHostnameKey(issuerURL)))
+				if legacyStatus == "" {
+					legacyStatus = configv1alpha1.SameIssuerHostMustUseSameSecretFederationDomainStatusCondition
+					legacyMessage = "Issuers with the same DNS hostname (address not including port) must use the same secretName: " + issuerURLToHostnameKey(issuerURL)
 				}
-				continue
+			} else {
+				conditions = append(conditions, trueCondition(typeOneTLSSecretPerIssuerHostname, "all FederationDomains with the same issuer DNS hostname use the same secretName"))
 			}
 		}
 
-		// Skip url parse errors because they will be validated below.
-		if urlParseErr == nil && len(uniqueSecretNamesPerIssuerAddress[issuerURLToHostnameKey(issuerURL)]) > 1 {
-			if err := c.updateStatus(
-				ctx.Context,
-				federationDomain.Namespace,
-				federationDomain.Name,
-				configv1alpha1.SameIssuerHostMustUseSameSecretFederationDomainStatusCondition,
-				"Issuers with the same DNS hostname (address not including port) must use the same secretName: "+issuerURLToHostnameKey(issuerURL),
-			); err != nil {
-				errs = append(errs, fmt.Errorf("could not update status: %w", err))
-			}
-			continue
+		tokenLifetimes, settingsErr := tokenLifetimesSettings(federationDomain.Spec.TokenLifetimes)
+		if settingsErr == nil {
+			settingsErr = validateSigningKeyRotation(federationDomain.Spec.SigningKeyRotation, tokenLifetimes)
+		}
+		if settingsErr == nil {
+			settingsErr = c.validateSigner(&federationDomain.Spec)
+		}
+		if settingsErr == nil {
+			settingsErr = c.validateSuppliedSecrets(federationDomain, suppliedSecretCounts)
+		}
+		if settingsErr != nil {
+			conditions = append(conditions, falseCondition(typeSettingsValid, reasonInvalidSettings, settingsErr.Error()))
+		} else {
+			conditions = append(conditions, trueCondition(typeSettingsValid, "the token lifetimes, signing keys and supplied secrets are valid"))
 		}
 
-		federationDomainIssuer, err := provider.NewFederationDomainIssuer(federationDomain.Spec.Issuer) // This validates the Issuer URL.
-		if err != nil {
-			if err := c.updateStatus(
-				ctx.Context,
-				federationDomain.Namespace,
-				federationDomain.Name,
-				configv1alpha1.InvalidFederationDomainStatusCondition,
-				"Invalid: "+err.Error(),
-			); err != nil {
-				errs = append(errs, fmt.Errorf("could not update status: %w", err))
+		identityProviders := identityProvidersSettings(federationDomain.Spec.IdentityProviders)
+		federationDomainIssuer, issuerErr := provider.NewFederationDomainIssuer( // This validates the Issuer URL.
+			federationDomain.Spec.Issuer,
+			identityProviders,
+			tokenLifetimes,
+		)
+		if issuerErr != nil {
+			conditions = append(conditions, falseCondition(typeIssuerURLValid, reasonInvalidIssuerURL, issuerErr.Error()))
+		} else {
+			conditions = append(conditions, trueCondition(typeIssuerURLValid, "spec.issuer is a valid URL"))
+		}
+
+		conditions = append(conditions, c.identityProvidersFoundCondition(federationDomain.Namespace, identityProviders))
+
+		if legacyStatus == "" {
+			// The settings are validated before the issuer, so their errors take precedence in the legacy message.
+			err := settingsErr
+			if err == nil {
+				err = issuerErr
+			}
+			if err != nil {
+				legacyStatus = configv1alpha1.InvalidFederationDomainStatusCondition
+				legacyMessage = "Invalid: " + err.Error()
 			}
-			continue
+		}
+
+		var tokenLifetimesForStatus *configv1alpha1.FederationDomainTokenLifetimesStatus
+		if legacyStatus == "" {
+			legacyStatus = configv1alpha1.SuccessFederationDomainStatusCondition
+			legacyMessage = "Provider successfully created"
+			tokenLifetimesForStatus = tokenLifetimesStatus(tokenLifetimes)
 		}
 
 		if err := c.updateStatus(
 			ctx.Context,
 			federationDomain.Namespace,
 			federationDomain.Name,
-			configv1alpha1.SuccessFederationDomainStatusCondition,
-			"Provider successfully created",
+			legacyStatus,
+			legacyMessage,
+			tokenLifetimesForStatus,
+			conditions,
 		); err != nil {
 			errs = append(errs, fmt.Errorf("could not update status: %w", err))
 			continue
 		}
 
-		federationDomainIssuers = append(federationDomainIssuers, federationDomainIssuer)
+		if legacyStatus == configv1alpha1.SuccessFederationDomainStatusCondition {
+			federationDomainIssuers = append(federationDomainIssuers, federationDomainIssuer)
+		}
 	}
 
 	c.providerSetter.SetProviders(federationDomainIssuers...)
@@ -176,11 +266,42 @@ func (c *federationDomainWatcherController) Sync(ctx controllerlib.Context) erro
 	return errors.NewAggregate(errs)
 }
 
+// identityProvidersFoundCondition checks that each identity provider which is named by the FederationDomain exists.
+// A missing identity provider does not stop the FederationDomain from being served, since it may be created later.
+func (c *federationDomainWatcherController) identityProvidersFoundCondition(
+	namespace string,
+	identityProviders provider.FederationDomainIdentityProviders,
+) *configv1alpha1.Condition {
+	names := identityProviders.HiddenNames
+	if identityProviders.DefaultName != "" {
+		names = append([]string{identityProviders.DefaultName}, names...)
+	}
+	if len(names) == 0 {
+		return trueCondition(typeIdentityProvidersFound, "no identity providers are named in the spec")
+	}
+
+	var missing []string
+	for _, name := range names {
+		_, oidcErr := c.oidcIdentityProviderInformer.Lister().OIDCIdentityProviders(namespace).Get(name)
+		_, ldapErr := c.ldapIdentityProviderInformer.Lister().LDAPIdentityProviders(namespace).Get(name)
+		if oidcErr != nil && ldapErr != nil {
+			missing = append(missing, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(missing) > 0 {
+		return falseCondition(typeIdentityProvidersFound, reasonIdentityProvidersNotFound,
+			"cannot find the identity providers named in the spec: "+strings.Join(missing, ", "))
+	}
+	return trueCondition(typeIdentityProvidersFound, "all of the identity providers named in the spec were found")
+}
+
 func (c *federationDomainWatcherController) updateStatus(
 	ctx context.Context,
 	namespace, name string,
 	status configv1alpha1.FederationDomainStatusCondition,
 	message string,
+	tokenLifetimes *configv1alpha1.FederationDomainTokenLifetimesStatus,
+	conditions []*configv1alpha1.Condition,
 ) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		federationDomain, err := c.client.ConfigV1alpha1().FederationDomains(namespace).Get(ctx, name, metav1.GetOptions{})
@@ -188,7 +309,18 @@ func (c *federationDomainWatcherController) updateStatus(
 			return fmt.Errorf("get failed: %w", err)
 		}
 
-		if federationDomain.Status.Status == status && federationDomain.Status.Message == message {
+		now := metav1.NewTime(c.clock.Now())
+		conditionsChanged := conditionsutil.MergeConfigConditions(
+			conditions,
+			federationDomain.Generation,
+			&federationDomain.Status.Conditions,
+			now,
+		)
+
+		if federationDomain.Status.Status == status &&
+			federationDomain.Status.Message == message &&
+			apiequality.Semantic.DeepEqual(federationDomain.Status.TokenLifetimes, tokenLifetimes) &&
+			!conditionsChanged {
 			return nil
 		}
 
@@ -203,10 +335,175 @@ func (c *federationDomainWatcherController) updateStatus(
 		)
 		federationDomain.Status.Status = status
 		federationDomain.Status.Message = message
-		federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(c.clock.Now()))
+		federationDomain.Status.TokenLifetimes = tokenLifetimes
+		federationDomain.Status.LastUpdateTime = &now
 		_, err = c.client.ConfigV1alpha1().FederationDomains(namespace).UpdateStatus(ctx, federationDomain, metav1.UpdateOptions{})
 		return err
 	})
 }
 
 func timePtr(t metav1.Time) *metav1.Time { return &t }
+
+func identityProvidersSettings(spec *configv1alpha1.FederationDomainIdentityProvidersSpec) provider.FederationDomainIdentityProviders {
+	if spec == nil {
+		return provider.FederationDomainIdentityProviders{}
+	}
+	return provider.FederationDomainIdentityProviders{
+		HiddenNames: spec.Hidden,
+		DefaultName: spec.Default,
+	}
+}
+
+func (c *federationDomainWatcherController) validateSigner(spec *configv1alpha1.FederationDomainSpec) error {
+	if spec.Signer == nil {
+		return nil
+	}
+	if spec.SigningKeyRotation != nil {
+		return fmt.Errorf("signer and signingKeyRotation cannot both be configured")
+	}
+	if _, ok := c.signerBackends[spec.Signer.Name]; !ok {
+		return fmt.Errorf("signer.name %q is not one of the signers in the Supervisor's configuration", spec.Signer.Name)
+	}
+	return nil
+}
+
+// validateSuppliedSecrets returns an error when any of the Secrets which were supplied by the administrator of the
+// FederationDomain cannot be used for its keys.
+func (c *federationDomainWatcherController) validateSuppliedSecrets(
+	federationDomain *configv1alpha1.FederationDomain,
+	suppliedSecretCounts map[string]int,
+) error {
+	secrets := federationDomain.Spec.Secrets
+	if secrets == nil {
+		return nil
+	}
+	if secrets.JWKS.Name != "" && (federationDomain.Spec.Signer != nil || federationDomain.Spec.SigningKeyRotation != nil) {
+		return fmt.Errorf("secrets.jwks cannot be configured together with signer or signingKeyRotation")
+	}
+
+	var errs []error
+	validate := func(field, secretName string, validateSecret func(*corev1.Secret) error) {
+		if secretName == "" {
+			return
+		}
+		if suppliedSecretCounts[secretName] > 1 {
+			errs = append(errs, fmt.Errorf("secrets.%s: secret %q cannot be supplied more than once", field, secretName))
+			return
+		}
+		secret, err := c.secretInformer.Lister().Secrets(federationDomain.Namespace).Get(secretName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("secrets.%s: %w", field, err))
+			return
+		}
+		if err := validateSecret(secret); err != nil {
+			errs = append(errs, fmt.Errorf("secrets.%s: %w", field, err))
+		}
+	}
+	symmetricSecretValidator := func(secretUsage generator.SecretUsage) func(*corev1.Secret) error {
+		return func(secret *corev1.Secret) error {
+			return generator.ValidateSuppliedSymmetricSecret(secret, secretUsage)
+		}
+	}
+
+	validate("jwks", secrets.JWKS.Name, validateSuppliedJWKSSecret)
+	validate("tokenSigningKey", secrets.TokenSigningKey.Name, symmetricSecretValidator(generator.SecretUsageTokenSigningKey))
+	validate("stateSigningKey", secrets.StateSigningKey.Name, symmetricSecretValidator(generator.SecretUsageStateSigningKey))
+	validate("stateEncryptionKey", secrets.StateEncryptionKey.Name, symmetricSecretValidator(generator.SecretUsageStateEncryptionKey))
+	return errors.NewAggregate(errs)
+}
+
+// suppliedSecretNames returns the names of all of the Secrets which are supplied in the spec.
+func suppliedSecretNames(secrets *configv1alpha1.FederationDomainSecretsSpec) []string {
+	if secrets == nil {
+		return nil
+	}
+	var names []string
+	for _, ref := range []corev1.LocalObjectReference{
+		secrets.JWKS,
+		secrets.TokenSigningKey,
+		secrets.StateSigningKey,
+		secrets.StateEncryptionKey,
+	} {
+		if ref.Name != "" {
+			names = append(names, ref.Name)
+		}
+	}
+	return names
+}
+
+// isFederationDomainKeySecret returns whether the object is a Secret which could hold one of the keys of a
+// FederationDomain.
+func isFederationDomainKeySecret(obj metav1.Object) bool {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return false
+	}
+	switch secret.Type {
+	case jwksSecretTypeValue,
+		generator.FederationDomainTokenSigningKeyType,
+		generator.FederationDomainStateSigningKeyType,
+		generator.FederationDomainStateEncryptionKeyType:
+		return true
+	default:
+		return false
+	}
+}
+
+func tokenLifetimesSettings(spec *configv1alpha1.FederationDomainTokenLifetimesSpec) (provider.FederationDomainTokenLifetimes, error) {
+	if spec == nil {
+		return provider.FederationDomainTokenLifetimes{}, nil
+	}
+
+	var errs []error
+	positiveDuration := func(field string, d *metav1.Duration) time.Duration {
+		if d == nil {
+			return 0 // use the default
+		}
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("tokenLifetimes.%s must be a positive duration", field))
+		}
+		return d.Duration
+	}
+
+	lifetimes := provider.FederationDomainTokenLifetimes{
+		IDToken:         positiveDuration("idToken", spec.IDToken),
+		AccessToken:     positiveDuration("accessToken", spec.AccessToken),
+		RefreshToken:    positiveDuration("refreshToken", spec.RefreshToken),
+		AbsoluteSession: positiveDuration("absoluteSession", spec.AbsoluteSession),
+	}
+	if len(errs) > 0 {
+		return provider.FederationDomainTokenLifetimes{}, errors.NewAggregate(errs)
+	}
+
+	// Compare the effective lifetimes, since a lifetime which was left to its default can still conflict with one
+	// which was configured. A refresh token which outlives the session, or an ID or access token which outlives the
+	// refresh token, would let a client keep using a token after the session which issued it has ended.
+	timeouts := oidc.OIDCTimeoutsConfiguration(lifetimes)
+	if timeouts.RefreshTokenLifespan > timeouts.AbsoluteSessionLifespan {
+		errs = append(errs, fmt.Errorf("tokenLifetimes.refreshToken of %s must not be longer than tokenLifetimes.absoluteSession of %s",
+			timeouts.RefreshTokenLifespan, timeouts.AbsoluteSessionLifespan))
+	}
+	if timeouts.IDTokenLifespan > timeouts.RefreshTokenLifespan {
+		errs = append(errs, fmt.Errorf("tokenLifetimes.idToken of %s must not be longer than tokenLifetimes.refreshToken of %s",
+			timeouts.IDTokenLifespan, timeouts.RefreshTokenLifespan))
+	}
+	if timeouts.AccessTokenLifespan > timeouts.RefreshTokenLifespan {
+		errs = append(errs, fmt.Errorf("tokenLifetimes.accessToken of %s must not be longer than tokenLifetimes.refreshToken of %s",
+			timeouts.AccessTokenLifespan, timeouts.RefreshTokenLifespan))
+	}
+	if len(errs) > 0 {
+		return provider.FederationDomainTokenLifetimes{}, errors.NewAggregate(errs)
+	}
+	return lifetimes, nil
+}
+
+// tokenLifetimesStatus returns the effective lifetimes, including the defaults for any which were not configured.
+func tokenLifetimesStatus(lifetimes provider.FederationDomainTokenLifetimes) *configv1alpha1.FederationDomainTokenLifetimesStatus {
+	timeouts := oidc.OIDCTimeoutsConfiguration(lifetimes)
+	return &configv1alpha1.FederationDomainTokenLifetimesStatus{
+		IDToken:         metav1.Duration{Duration: timeouts.IDTokenLifespan},
+		AccessToken:     metav1.Duration{Duration: timeouts.AccessTokenLifespan},
+		RefreshToken:    metav1.Duration{Duration: timeouts.RefreshTokenLifespan},
+		AbsoluteSession: metav1.Duration{Duration: timeouts.AbsoluteSessionLifespan},
+	}
+}
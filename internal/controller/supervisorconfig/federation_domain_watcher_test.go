@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -16,18 +17,23 @@ import (
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/clock"
+	kubeinformers "k8s.io/client-go/informers"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	coretesting "k8s.io/client-go/testing"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/oidc/jwks"
 	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/testutil"
 )
@@ -37,19 +43,32 @@ func TestInformerFilters(t *testing.T) {
 		var r *require.Assertions
 		var observableWithInformerOption *testutil.ObservableWithInformerOption
 		var configMapInformerFilter controllerlib.Filter
+		var secretInformerFilter controllerlib.Filter
+		var oidcIdentityProviderInformerFilter controllerlib.Filter
+		var ldapIdentityProviderInformerFilter controllerlib.Filter
 
 		it.Before(func() {
 			r = require.New(t)
 			observableWithInformerOption = testutil.NewObservableWithInformerOption()
 			federationDomainInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).Config().V1alpha1().FederationDomains()
+			oidcIdentityProviderInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().OIDCIdentityProviders()
+			ldapIdentityProviderInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().LDAPIdentityProviders()
+			secretInformer := kubeinformers.NewSharedInformerFactory(nil, 0).Core().V1().Secrets()
 			_ = NewFederationDomainWatcherController(
 				nil,
 				nil,
 				nil,
 				federationDomainInformer,
+				secretInformer,
+				nil,
+				oidcIdentityProviderInformer,
+				ldapIdentityProviderInformer,
 				observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
 			)
 			configMapInformerFilter = observableWithInformerOption.GetFilterForInformer(federationDomainInformer)
+			secretInformerFilter = observableWithInformerOption.GetFilterForInformer(secretInformer)
+			oidcIdentityProviderInformerFilter = observableWithInformerOption.GetFilterForInformer(oidcIdentityProviderInformer)
+			ldapIdentityProviderInformerFilter = observableWithInformerOption.GetFilterForInformer(ldapIdentityProviderInformer)
 		})
 
 		when("watching FederationDomain objects", func() {
@@ -80,6 +99,76 @@ func TestInformerFilters(t *testing.T) {
 				})
 			})
 		})
+
+		when("watching Secret objects", func() {
+			var subject controllerlib.Filter
+			var jwksSecret, tokenSigningKeySecret, stateSigningKeySecret, stateEncryptionKeySecret, otherSecret *corev1.Secret
+
+			it.Before(func() {
+				subject = secretInformerFilter
+				jwksSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+					Type:       "secrets.pinniped.dev/federation-domain-jwks",
+				}
+				tokenSigningKeySecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+					Type:       "secrets.pinniped.dev/federation-domain-token-signing-key",
+				}
+				stateSigningKeySecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+					Type:       "secrets.pinniped.dev/federation-domain-state-signing-key",
+				}
+				stateEncryptionKeySecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+					Type:       "secrets.pinniped.dev/federation-domain-state-encryption-key",
+				}
+				otherSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+					Type:       corev1.SecretTypeOpaque,
+				}
+			})
+
+			when("a Secret which could hold the keys of a FederationDomain changes", func() {
+				it("returns true to trigger the sync method", func() {
+					for _, secret := range []*corev1.Secret{jwksSecret, tokenSigningKeySecret, stateSigningKeySecret, stateEncryptionKeySecret} {
+						r.True(subject.Add(secret))
+						r.True(subject.Update(secret, otherSecret))
+						r.True(subject.Update(otherSecret, secret))
+						r.True(subject.Delete(secret))
+						r.Equal(controllerlib.Key{}, subject.Parent(secret))
+					}
+				})
+			})
+
+			when("any other Secret changes", func() {
+				it("returns false to skip the sync method", func() {
+					r.False(subject.Add(otherSecret))
+					r.False(subject.Update(otherSecret, otherSecret))
+					r.False(subject.Delete(otherSecret))
+				})
+			})
+		})
+
+		when("watching identity provider objects", func() {
+			when("any OIDCIdentityProvider or LDAPIdentityProvider changes", func() {
+				it("returns true to trigger the sync method", func() {
+					oidcIdentityProvider := &idpv1alpha1.OIDCIdentityProvider{ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"}}
+					ldapIdentityProvider := &idpv1alpha1.LDAPIdentityProvider{ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"}}
+					for _, test := range []struct {
+						filter controllerlib.Filter
+						obj    metav1.Object
+					}{
+						{filter: oidcIdentityProviderInformerFilter, obj: oidcIdentityProvider},
+						{filter: ldapIdentityProviderInformerFilter, obj: ldapIdentityProvider},
+					} {
+						r.True(test.filter.Add(test.obj))
+						r.True(test.filter.Update(test.obj, test.obj))
+						r.True(test.filter.Delete(test.obj))
+						r.Equal(controllerlib.Key{}, test.filter.Parent(test.obj))
+					}
+				})
+			})
+		})
 	}, spec.Parallel(), spec.Report(report.Terminal{}))
 }
 
@@ -102,6 +191,8 @@ func TestSync(t *testing.T) {
 		var subject controllerlib.Controller
 		var federationDomainInformerClient *pinnipedfake.Clientset
 		var federationDomainInformers pinnipedinformers.SharedInformerFactory
+		var kubeInformerClient *kubernetesfake.Clientset
+		var kubeInformers kubeinformers.SharedInformerFactory
 		var pinnipedAPIClient *pinnipedfake.Clientset
 		var cancelContext context.Context
 		var cancelContextCancelFunc context.CancelFunc
@@ -119,6 +210,10 @@ func TestSync(t *testing.T) {
 				clock.NewFakeClock(frozenNow),
 				pinnipedAPIClient,
 				federationDomainInformers.Config().V1alpha1().FederationDomains(),
+				kubeInformers.Core().V1().Secrets(),
+				jwks.SignerBackends{"some-signer": nil},
+				federationDomainInformers.IDP().V1alpha1().OIDCIdentityProviders(),
+				federationDomainInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				controllerlib.WithInformer,
 			)
 
@@ -134,6 +229,7 @@ func TestSync(t *testing.T) {
 
 			// Must start informers before calling TestRunSynchronously()
 			federationDomainInformers.Start(cancelContext.Done())
+			kubeInformers.Start(cancelContext.Done())
 			controllerlib.TestRunSynchronously(t, subject)
 		}
 
@@ -147,6 +243,8 @@ func TestSync(t *testing.T) {
 
 			federationDomainInformerClient = pinnipedfake.NewSimpleClientset()
 			federationDomainInformers = pinnipedinformers.NewSharedInformerFactory(federationDomainInformerClient, 0)
+			kubeInformerClient = kubernetesfake.NewSimpleClientset()
+			kubeInformers = kubeinformers.NewSharedInformerFactory(kubeInformerClient, 0)
 			pinnipedAPIClient = pinnipedfake.NewSimpleClientset()
 
 			federationDomainGVR = schema.GroupVersionResource{
@@ -160,6 +258,575 @@ func TestSync(t *testing.T) {
 			cancelContextCancelFunc()
 		})
 
+		when("there is a FederationDomain with identity provider settings in the informer", func() {
+			var federationDomain *v1alpha1.FederationDomain
+
+			it.Before(func() {
+				federationDomain = &v1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: namespace},
+					Spec: v1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer.com",
+						IdentityProviders: &v1alpha1.FederationDomainIdentityProvidersSpec{
+							Hidden:  []string{"some-hidden-idp"},
+							Default: "some-default-idp",
+						},
+					},
+				}
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomain))
+				r.NoError(federationDomainInformerClient.Tracker().Add(federationDomain))
+			})
+
+			it("calls the ProvidersSetter with the identity provider settings", func() {
+				startInformersAndController()
+				err := controllerlib.TestSync(t, subject, *syncContext)
+				r.NoError(err)
+
+				expectedProvider, err := provider.NewFederationDomainIssuer(federationDomain.Spec.Issuer, provider.FederationDomainIdentityProviders{
+					HiddenNames: []string{"some-hidden-idp"},
+					DefaultName: "some-default-idp",
+				}, provider.FederationDomainTokenLifetimes{})
+				r.NoError(err)
+
+				r.True(providersSetter.SetProvidersWasCalled)
+				r.Equal([]*provider.FederationDomainIssuer{expectedProvider}, providersSetter.FederationDomainsReceived)
+			})
+
+			it("updates the status to say that the identity providers were not found", func() {
+				startInformersAndController()
+				err := controllerlib.TestSync(t, subject, *syncContext)
+				r.NoError(err)
+
+				actualFederationDomain, err := pinnipedAPIClient.ConfigV1alpha1().FederationDomains(namespace).Get(cancelContext, federationDomain.Name, metav1.GetOptions{})
+				r.NoError(err)
+				r.Equal(v1alpha1.SuccessFederationDomainStatusCondition, actualFederationDomain.Status.Status)
+				r.Equal(expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "IdentityProvidersFound",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "IdentityProvidersNotFound",
+					Message: `cannot find the identity providers named in the spec: "some-default-idp", "some-hidden-idp"`,
+				}), actualFederationDomain.Status.Conditions)
+			})
+
+			when("the identity providers exist", func() {
+				it.Before(func() {
+					r.NoError(federationDomainInformerClient.Tracker().Add(&idpv1alpha1.OIDCIdentityProvider{
+						ObjectMeta: metav1.ObjectMeta{Name: "some-default-idp", Namespace: namespace},
+					}))
+					r.NoError(federationDomainInformerClient.Tracker().Add(&idpv1alpha1.LDAPIdentityProvider{
+						ObjectMeta: metav1.ObjectMeta{Name: "some-hidden-idp", Namespace: namespace},
+					}))
+				})
+
+				it("updates the status to say that the identity providers were found", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					actualFederationDomain, err := pinnipedAPIClient.ConfigV1alpha1().FederationDomains(namespace).Get(cancelContext, federationDomain.Name, metav1.GetOptions{})
+					r.NoError(err)
+					r.Equal(expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "IdentityProvidersFound",
+						Status:  v1alpha1.ConditionTrue,
+						Reason:  "Success",
+						Message: "all of the identity providers named in the spec were found",
+					}), actualFederationDomain.Status.Conditions)
+				})
+			})
+		})
+
+		when("there is a FederationDomain with token lifetimes in the informer", func() {
+			var federationDomain *v1alpha1.FederationDomain
+
+			it.Before(func() {
+				federationDomain = &v1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: namespace},
+					Spec: v1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer.com",
+						TokenLifetimes: &v1alpha1.FederationDomainTokenLifetimesSpec{
+							AccessToken:     &metav1.Duration{Duration: 5 * time.Minute},
+							RefreshToken:    &metav1.Duration{Duration: time.Hour},
+							AbsoluteSession: &metav1.Duration{Duration: 4 * time.Hour},
+						},
+					},
+				}
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomain))
+				r.NoError(federationDomainInformerClient.Tracker().Add(federationDomain))
+			})
+
+			it("calls the ProvidersSetter with the token lifetimes", func() {
+				startInformersAndController()
+				err := controllerlib.TestSync(t, subject, *syncContext)
+				r.NoError(err)
+
+				expectedProvider, err := provider.NewFederationDomainIssuer(federationDomain.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{
+					AccessToken:     5 * time.Minute,
+					RefreshToken:    time.Hour,
+					AbsoluteSession: 4 * time.Hour,
+				})
+				r.NoError(err)
+
+				r.True(providersSetter.SetProvidersWasCalled)
+				r.Equal([]*provider.FederationDomainIssuer{expectedProvider}, providersSetter.FederationDomainsReceived)
+			})
+
+			it("updates the status with the effective token lifetimes, including defaults", func() {
+				startInformersAndController()
+				err := controllerlib.TestSync(t, subject, *syncContext)
+				r.NoError(err)
+
+				federationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
+				federationDomain.Status.Message = "Provider successfully created"
+				federationDomain.Status.TokenLifetimes = &v1alpha1.FederationDomainTokenLifetimesStatus{
+					IDToken:         metav1.Duration{Duration: 2 * time.Minute},
+					AccessToken:     metav1.Duration{Duration: 5 * time.Minute},
+					RefreshToken:    metav1.Duration{Duration: time.Hour},
+					AbsoluteSession: metav1.Duration{Duration: 4 * time.Hour},
+				}
+				federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomain.Status.Conditions = expectedConditions(frozenNow)
+
+				expectedActions := []coretesting.Action{
+					coretesting.NewGetAction(
+						federationDomainGVR,
+						federationDomain.Namespace,
+						federationDomain.Name,
+					),
+					coretesting.NewUpdateSubresourceAction(
+						federationDomainGVR,
+						"status",
+						federationDomain.Namespace,
+						federationDomain,
+					),
+				}
+				r.Equal(expectedActions, pinnipedAPIClient.Actions())
+			})
+
+			when("a token lifetime is not positive", func() {
+				it.Before(func() {
+					federationDomain.Spec.TokenLifetimes.RefreshToken = &metav1.Duration{Duration: 0}
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+				})
+
+				it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = "Invalid: tokenLifetimes.refreshToken must be a positive duration"
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: "tokenLifetimes.refreshToken must be a positive duration",
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				})
+			})
+
+			when("the refresh token lifetime is longer than the absolute session lifetime", func() {
+				it.Before(func() {
+					federationDomain.Spec.TokenLifetimes.RefreshToken = nil // the default of 9h is longer than the session
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+				})
+
+				it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = "Invalid: tokenLifetimes.refreshToken of 9h0m0s must not be longer than tokenLifetimes.absoluteSession of 4h0m0s"
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: "tokenLifetimes.refreshToken of 9h0m0s must not be longer than tokenLifetimes.absoluteSession of 4h0m0s",
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				})
+			})
+
+			when("the ID and access token lifetimes are longer than the refresh token lifetime", func() {
+				it.Before(func() {
+					federationDomain.Spec.TokenLifetimes.IDToken = &metav1.Duration{Duration: 2 * time.Hour}
+					federationDomain.Spec.TokenLifetimes.AccessToken = &metav1.Duration{Duration: 90 * time.Minute}
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+				})
+
+				it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = "Invalid: [tokenLifetimes.idToken of 2h0m0s must not be longer than tokenLifetimes.refreshToken of 1h0m0s, tokenLifetimes.accessToken of 1h30m0s must not be longer than tokenLifetimes.refreshToken of 1h0m0s]"
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: "[tokenLifetimes.idToken of 2h0m0s must not be longer than tokenLifetimes.refreshToken of 1h0m0s, tokenLifetimes.accessToken of 1h30m0s must not be longer than tokenLifetimes.refreshToken of 1h0m0s]",
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				})
+			})
+
+			when("the signing key rotation policy is invalid", func() {
+				it.Before(func() {
+					federationDomain.Spec.SigningKeyRotation = &v1alpha1.FederationDomainSigningKeyRotationSpec{
+						KeyLifetime: metav1.Duration{Duration: 24 * time.Hour},
+						PrePublish:  metav1.Duration{Duration: -time.Hour},
+						Retire:      metav1.Duration{Duration: time.Hour},
+					}
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+				})
+
+				it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = "Invalid: signingKeyRotation.prePublish must not be a negative duration"
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: "signingKeyRotation.prePublish must not be a negative duration",
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				})
+			})
+
+			when("the signer is not in the Supervisor's configuration", func() {
+				it.Before(func() {
+					federationDomain.Spec.Signer = &v1alpha1.FederationDomainSignerSpec{Name: "some-other-signer", KeyName: "some-key"}
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+				})
+
+				it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = `Invalid: signer.name "some-other-signer" is not one of the signers in the Supervisor's configuration`
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: `signer.name "some-other-signer" is not one of the signers in the Supervisor's configuration`,
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				})
+			})
+
+			when("both a signer and a signing key rotation policy are configured", func() {
+				it.Before(func() {
+					federationDomain.Spec.Signer = &v1alpha1.FederationDomainSignerSpec{Name: "some-signer", KeyName: "some-key"}
+					federationDomain.Spec.SigningKeyRotation = &v1alpha1.FederationDomainSigningKeyRotationSpec{
+						KeyLifetime: metav1.Duration{Duration: 24 * time.Hour},
+						PrePublish:  metav1.Duration{Duration: time.Hour},
+						Retire:      metav1.Duration{Duration: time.Hour},
+					}
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+				})
+
+				it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = "Invalid: signer and signingKeyRotation cannot both be configured"
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: "signer and signingKeyRotation cannot both be configured",
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				})
+			})
+
+			when("Secrets are supplied for the keys", func() {
+				var symmetricKeySecret = func(name string, secretType corev1.SecretType) *corev1.Secret {
+					return &corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+						Type:       secretType,
+						Data:       map[string][]byte{"key": []byte("0123456789abcdef0123456789abcdef")},
+					}
+				}
+
+				var expectInvalidStatus = func(message string) {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Empty(providersSetter.FederationDomainsReceived)
+
+					federationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
+					federationDomain.Status.Message = message
+					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "SettingsValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidSettings",
+						Message: strings.TrimPrefix(message, "Invalid: "),
+					})
+
+					expectedActions := []coretesting.Action{
+						coretesting.NewGetAction(
+							federationDomainGVR,
+							federationDomain.Namespace,
+							federationDomain.Name,
+						),
+						coretesting.NewUpdateSubresourceAction(
+							federationDomainGVR,
+							"status",
+							federationDomain.Namespace,
+							federationDomain,
+						),
+					}
+					r.Equal(expectedActions, pinnipedAPIClient.Actions())
+				}
+
+				it.Before(func() {
+					federationDomain.Spec.Secrets = &v1alpha1.FederationDomainSecretsSpec{
+						JWKS:               corev1.LocalObjectReference{Name: "some-jwks"},
+						TokenSigningKey:    corev1.LocalObjectReference{Name: "some-token-signing-key"},
+						StateSigningKey:    corev1.LocalObjectReference{Name: "some-state-signing-key"},
+						StateEncryptionKey: corev1.LocalObjectReference{Name: "some-state-encryption-key"},
+					}
+					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+
+					r.NoError(kubeInformerClient.Tracker().Add(&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "some-jwks", Namespace: namespace},
+						Type:       "secrets.pinniped.dev/federation-domain-jwks",
+						Data: map[string][]byte{
+							"activeJWK": readJWKJSON(t, "testdata/good-jwk.json"),
+							"jwks":      readJWKJSON(t, "testdata/good-jwks.json"),
+						},
+					}))
+					r.NoError(kubeInformerClient.Tracker().Add(symmetricKeySecret("some-token-signing-key", "secrets.pinniped.dev/federation-domain-token-signing-key")))
+					r.NoError(kubeInformerClient.Tracker().Add(symmetricKeySecret("some-state-signing-key", "secrets.pinniped.dev/federation-domain-state-signing-key")))
+					r.NoError(kubeInformerClient.Tracker().Add(symmetricKeySecret("some-state-encryption-key", "secrets.pinniped.dev/federation-domain-state-encryption-key")))
+				})
+
+				it("calls the ProvidersSetter with the FederationDomain when the Secrets are valid", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.NoError(err)
+
+					r.True(providersSetter.SetProvidersWasCalled)
+					r.Len(providersSetter.FederationDomainsReceived, 1)
+					r.Equal(federationDomain.Spec.Issuer, providersSetter.FederationDomainsReceived[0].Issuer())
+				})
+
+				when("a supplied Secret does not exist", func() {
+					it.Before(func() {
+						r.NoError(kubeInformerClient.Tracker().Delete(
+							schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, namespace, "some-state-signing-key",
+						))
+					})
+
+					it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+						expectInvalidStatus(`Invalid: secrets.stateSigningKey: secret "some-state-signing-key" not found`)
+					})
+				})
+
+				when("supplied Secrets have the wrong format", func() {
+					it.Before(func() {
+						secretGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+						wrongType := symmetricKeySecret("some-token-signing-key", "secrets.pinniped.dev/federation-domain-state-signing-key")
+						r.NoError(kubeInformerClient.Tracker().Update(secretGVR, wrongType, namespace))
+						shortKey := symmetricKeySecret("some-state-encryption-key", "secrets.pinniped.dev/federation-domain-state-encryption-key")
+						shortKey.Data["key"] = []byte("too-short")
+						r.NoError(kubeInformerClient.Tracker().Update(secretGVR, shortKey, namespace))
+						publicJWK := &corev1.Secret{
+							ObjectMeta: metav1.ObjectMeta{Name: "some-jwks", Namespace: namespace},
+							Type:       "secrets.pinniped.dev/federation-domain-jwks",
+							Data: map[string][]byte{
+								"activeJWK": readJWKJSON(t, "testdata/public-jwk.json"),
+								"jwks":      readJWKJSON(t, "testdata/good-jwks.json"),
+							},
+						}
+						r.NoError(kubeInformerClient.Tracker().Update(secretGVR, publicJWK, namespace))
+					})
+
+					it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+						expectInvalidStatus(`Invalid: [` +
+							`secrets.jwks: secret "some-jwks" must have type "secrets.pinniped.dev/federation-domain-jwks", a private JWK in its "activeJWK" data, and a JWKS which includes that JWK in its "jwks" data, ` +
+							`secrets.tokenSigningKey: secret "some-token-signing-key" must have type "secrets.pinniped.dev/federation-domain-token-signing-key", ` +
+							`secrets.stateEncryptionKey: secret "some-state-encryption-key" must contain a 32-byte key in its "key" data]`)
+					})
+				})
+
+				when("a supplied Secret has an owner", func() {
+					it.Before(func() {
+						owned := symmetricKeySecret("some-token-signing-key", "secrets.pinniped.dev/federation-domain-token-signing-key")
+						owned.OwnerReferences = []metav1.OwnerReference{{
+							APIVersion: v1alpha1.SchemeGroupVersion.String(),
+							Kind:       "FederationDomain",
+							Name:       "some-other-federation-domain",
+							UID:        "some-other-uid",
+						}}
+						r.NoError(kubeInformerClient.Tracker().Update(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, owned, namespace))
+					})
+
+					it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+						expectInvalidStatus(`Invalid: secrets.tokenSigningKey: secret "some-token-signing-key" must not have owner references`)
+					})
+				})
+
+				when("the same Secret is supplied more than once", func() {
+					it.Before(func() {
+						federationDomain.Spec.Secrets.StateSigningKey.Name = "some-token-signing-key"
+						r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+						r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					})
+
+					it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+						expectInvalidStatus(`Invalid: [` +
+							`secrets.tokenSigningKey: secret "some-token-signing-key" cannot be supplied more than once, ` +
+							`secrets.stateSigningKey: secret "some-token-signing-key" cannot be supplied more than once]`)
+					})
+				})
+
+				when("a JWKS Secret is supplied along with a signing key rotation policy", func() {
+					it.Before(func() {
+						federationDomain.Spec.SigningKeyRotation = &v1alpha1.FederationDomainSigningKeyRotationSpec{
+							KeyLifetime: metav1.Duration{Duration: 24 * time.Hour},
+							PrePublish:  metav1.Duration{Duration: time.Hour},
+							Retire:      metav1.Duration{Duration: time.Hour},
+						}
+						r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+						r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain, federationDomain.Namespace))
+					})
+
+					it("sets the status to invalid and does not call the ProvidersSetter with the FederationDomain", func() {
+						expectInvalidStatus("Invalid: secrets.jwks cannot be configured together with signer or signingKeyRotation")
+					})
+				})
+			})
+		})
+
 		when("there are some valid FederationDomains in the informer", func() {
 			var (
 				federationDomain1 *v1alpha1.FederationDomain
@@ -187,10 +854,10 @@ func TestSync(t *testing.T) {
 				err := controllerlib.TestSync(t, subject, *syncContext)
 				r.NoError(err)
 
-				provider1, err := provider.NewFederationDomainIssuer(federationDomain1.Spec.Issuer)
+				provider1, err := provider.NewFederationDomainIssuer(federationDomain1.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 
-				provider2, err := provider.NewFederationDomainIssuer(federationDomain2.Spec.Issuer)
+				provider2, err := provider.NewFederationDomainIssuer(federationDomain2.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 
 				r.True(providersSetter.SetProvidersWasCalled)
@@ -210,11 +877,15 @@ func TestSync(t *testing.T) {
 
 				federationDomain1.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 				federationDomain1.Status.Message = "Provider successfully created"
+				federationDomain1.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 				federationDomain1.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomain1.Status.Conditions = expectedConditions(frozenNow)
 
 				federationDomain2.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 				federationDomain2.Status.Message = "Provider successfully created"
+				federationDomain2.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 				federationDomain2.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomain2.Status.Conditions = expectedConditions(frozenNow)
 
 				expectedActions := []coretesting.Action{
 					coretesting.NewGetAction(
@@ -247,7 +918,9 @@ func TestSync(t *testing.T) {
 				it.Before(func() {
 					federationDomain1.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain1.Status.Message = "Provider successfully created"
+					federationDomain1.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain1.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain1.Status.Conditions = expectedConditions(frozenNow)
 
 					r.NoError(pinnipedAPIClient.Tracker().Update(federationDomainGVR, federationDomain1, federationDomain1.Namespace))
 					r.NoError(federationDomainInformerClient.Tracker().Update(federationDomainGVR, federationDomain1, federationDomain1.Namespace))
@@ -260,7 +933,9 @@ func TestSync(t *testing.T) {
 
 					federationDomain2.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain2.Status.Message = "Provider successfully created"
+					federationDomain2.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain2.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain2.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -288,10 +963,10 @@ func TestSync(t *testing.T) {
 					err := controllerlib.TestSync(t, subject, *syncContext)
 					r.NoError(err)
 
-					provider1, err := provider.NewFederationDomainIssuer(federationDomain1.Spec.Issuer)
+					provider1, err := provider.NewFederationDomainIssuer(federationDomain1.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 					r.NoError(err)
 
-					provider2, err := provider.NewFederationDomainIssuer(federationDomain2.Spec.Issuer)
+					provider2, err := provider.NewFederationDomainIssuer(federationDomain2.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 					r.NoError(err)
 
 					r.True(providersSetter.SetProvidersWasCalled)
@@ -326,10 +1001,10 @@ func TestSync(t *testing.T) {
 					err := controllerlib.TestSync(t, subject, *syncContext)
 					r.EqualError(err, "could not update status: some update error")
 
-					provider1, err := provider.NewFederationDomainIssuer(federationDomain1.Spec.Issuer)
+					provider1, err := provider.NewFederationDomainIssuer(federationDomain1.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 					r.NoError(err)
 
-					provider2, err := provider.NewFederationDomainIssuer(federationDomain2.Spec.Issuer)
+					provider2, err := provider.NewFederationDomainIssuer(federationDomain2.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 					r.NoError(err)
 
 					r.True(providersSetter.SetProvidersWasCalled)
@@ -347,11 +1022,15 @@ func TestSync(t *testing.T) {
 
 					federationDomain1.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain1.Status.Message = "Provider successfully created"
+					federationDomain1.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain1.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain1.Status.Conditions = expectedConditions(frozenNow)
 
 					federationDomain2.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain2.Status.Message = "Provider successfully created"
+					federationDomain2.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain2.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain2.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -419,7 +1098,9 @@ func TestSync(t *testing.T) {
 
 					federationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain.Status.Message = "Provider successfully created"
+					federationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -467,7 +1148,9 @@ func TestSync(t *testing.T) {
 
 					federationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain.Status.Message = "Provider successfully created"
+					federationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -504,7 +1187,9 @@ func TestSync(t *testing.T) {
 
 					federationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain.Status.Message = "Provider successfully created"
+					federationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -545,7 +1230,7 @@ func TestSync(t *testing.T) {
 				err := controllerlib.TestSync(t, subject, *syncContext)
 				r.NoError(err)
 
-				validProvider, err := provider.NewFederationDomainIssuer(validFederationDomain.Spec.Issuer)
+				validProvider, err := provider.NewFederationDomainIssuer(validFederationDomain.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 
 				r.True(providersSetter.SetProvidersWasCalled)
@@ -564,11 +1249,19 @@ func TestSync(t *testing.T) {
 
 				validFederationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 				validFederationDomain.Status.Message = "Provider successfully created"
+				validFederationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 				validFederationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				validFederationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 				invalidFederationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
 				invalidFederationDomain.Status.Message = "Invalid: issuer must not have query"
 				invalidFederationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				invalidFederationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "IssuerURLValid",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "InvalidIssuerURL",
+					Message: "issuer must not have query",
+				})
 
 				expectedActions := []coretesting.Action{
 					coretesting.NewGetAction(
@@ -619,7 +1312,7 @@ func TestSync(t *testing.T) {
 					err := controllerlib.TestSync(t, subject, *syncContext)
 					r.EqualError(err, "could not update status: some update error")
 
-					validProvider, err := provider.NewFederationDomainIssuer(validFederationDomain.Spec.Issuer)
+					validProvider, err := provider.NewFederationDomainIssuer(validFederationDomain.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 					r.NoError(err)
 
 					r.True(providersSetter.SetProvidersWasCalled)
@@ -638,11 +1331,19 @@ func TestSync(t *testing.T) {
 
 					validFederationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					validFederationDomain.Status.Message = "Provider successfully created"
+					validFederationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					validFederationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					validFederationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 					invalidFederationDomain.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
 					invalidFederationDomain.Status.Message = "Invalid: issuer must not have query"
 					invalidFederationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					invalidFederationDomain.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+						Type:    "IssuerURLValid",
+						Status:  v1alpha1.ConditionFalse,
+						Reason:  "InvalidIssuerURL",
+						Message: "issuer must not have query",
+					})
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -709,7 +1410,7 @@ func TestSync(t *testing.T) {
 				err := controllerlib.TestSync(t, subject, *syncContext)
 				r.NoError(err)
 
-				nonDuplicateProvider, err := provider.NewFederationDomainIssuer(federationDomain.Spec.Issuer)
+				nonDuplicateProvider, err := provider.NewFederationDomainIssuer(federationDomain.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 
 				r.True(providersSetter.SetProvidersWasCalled)
@@ -728,15 +1429,29 @@ func TestSync(t *testing.T) {
 
 				federationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 				federationDomain.Status.Message = "Provider successfully created"
+				federationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 				federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 				federationDomainDuplicate1.Status.Status = v1alpha1.DuplicateFederationDomainStatusCondition
 				federationDomainDuplicate1.Status.Message = "Duplicate issuer: https://iSSueR-duPlicAte.cOm/a"
 				federationDomainDuplicate1.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomainDuplicate1.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "IssuerIsUnique",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "DuplicateIssuer",
+					Message: "multiple FederationDomains have the same issuer URL: https://iSSueR-duPlicAte.cOm/a",
+				})
 
 				federationDomainDuplicate2.Status.Status = v1alpha1.DuplicateFederationDomainStatusCondition
 				federationDomainDuplicate2.Status.Message = "Duplicate issuer: https://issuer-duplicate.com/a"
 				federationDomainDuplicate2.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomainDuplicate2.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "IssuerIsUnique",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "DuplicateIssuer",
+					Message: "multiple FederationDomains have the same issuer URL: https://issuer-duplicate.com/a",
+				})
 
 				expectedActions := []coretesting.Action{
 					coretesting.NewGetAction(
@@ -797,7 +1512,9 @@ func TestSync(t *testing.T) {
 
 					federationDomain.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomain.Status.Message = "Provider successfully created"
+					federationDomain.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomain.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomain.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -882,7 +1599,7 @@ func TestSync(t *testing.T) {
 				err := controllerlib.TestSync(t, subject, *syncContext)
 				r.NoError(err)
 
-				nonDuplicateProvider, err := provider.NewFederationDomainIssuer(federationDomainDifferentIssuerAddress.Spec.Issuer)
+				nonDuplicateProvider, err := provider.NewFederationDomainIssuer(federationDomainDifferentIssuerAddress.Spec.Issuer, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 
 				r.True(providersSetter.SetProvidersWasCalled)
@@ -901,19 +1618,49 @@ func TestSync(t *testing.T) {
 
 				federationDomainDifferentIssuerAddress.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 				federationDomainDifferentIssuerAddress.Status.Message = "Provider successfully created"
+				federationDomainDifferentIssuerAddress.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 				federationDomainDifferentIssuerAddress.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomainDifferentIssuerAddress.Status.Conditions = expectedConditions(frozenNow)
 
 				federationDomainSameIssuerAddress1.Status.Status = v1alpha1.SameIssuerHostMustUseSameSecretFederationDomainStatusCondition
 				federationDomainSameIssuerAddress1.Status.Message = "Issuers with the same DNS hostname (address not including port) must use the same secretName: issuer-duplicate-address.com"
 				federationDomainSameIssuerAddress1.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomainSameIssuerAddress1.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "OneTLSSecretPerIssuerHostname",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "DifferentSecretRefsFound",
+					Message: "other FederationDomains with the same issuer DNS hostname must use the same secretName: issuer-duplicate-address.com",
+				})
 
 				federationDomainSameIssuerAddress2.Status.Status = v1alpha1.SameIssuerHostMustUseSameSecretFederationDomainStatusCondition
 				federationDomainSameIssuerAddress2.Status.Message = "Issuers with the same DNS hostname (address not including port) must use the same secretName: issuer-duplicate-address.com"
 				federationDomainSameIssuerAddress2.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomainSameIssuerAddress2.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "OneTLSSecretPerIssuerHostname",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "DifferentSecretRefsFound",
+					Message: "other FederationDomains with the same issuer DNS hostname must use the same secretName: issuer-duplicate-address.com",
+				})
 
 				federationDomainWithInvalidIssuerURL.Status.Status = v1alpha1.InvalidFederationDomainStatusCondition
 				federationDomainWithInvalidIssuerURL.Status.Message = `Invalid: could not parse issuer as URL: parse ":/host//path": missing protocol scheme`
 				federationDomainWithInvalidIssuerURL.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+				federationDomainWithInvalidIssuerURL.Status.Conditions = expectedConditions(frozenNow, v1alpha1.Condition{
+					Type:    "IssuerURLValid",
+					Status:  v1alpha1.ConditionFalse,
+					Reason:  "InvalidIssuerURL",
+					Message: `could not parse issuer as URL: parse ":/host//path": missing protocol scheme`,
+				}, v1alpha1.Condition{
+					Type:    "IssuerIsUnique",
+					Status:  v1alpha1.ConditionUnknown,
+					Reason:  "UnableToValidate",
+					Message: "unable to check if issuer is unique because URL cannot be parsed",
+				}, v1alpha1.Condition{
+					Type:    "OneTLSSecretPerIssuerHostname",
+					Status:  v1alpha1.ConditionUnknown,
+					Reason:  "UnableToValidate",
+					Message: "unable to check if TLS secrets are consistent because URL cannot be parsed",
+				})
 
 				expectedActions := []coretesting.Action{
 					coretesting.NewGetAction(
@@ -985,7 +1732,9 @@ func TestSync(t *testing.T) {
 
 					federationDomainDifferentIssuerAddress.Status.Status = v1alpha1.SuccessFederationDomainStatusCondition
 					federationDomainDifferentIssuerAddress.Status.Message = "Provider successfully created"
+					federationDomainDifferentIssuerAddress.Status.TokenLifetimes = defaultTokenLifetimesStatus()
 					federationDomainDifferentIssuerAddress.Status.LastUpdateTime = timePtr(metav1.NewTime(frozenNow))
+					federationDomainDifferentIssuerAddress.Status.Conditions = expectedConditions(frozenNow)
 
 					expectedActions := []coretesting.Action{
 						coretesting.NewGetAction(
@@ -1026,3 +1775,58 @@ func TestSync(t *testing.T) {
 		})
 	}, spec.Parallel(), spec.Report(report.Terminal{}))
 }
+
+func defaultTokenLifetimesStatus() *v1alpha1.FederationDomainTokenLifetimesStatus {
+	return &v1alpha1.FederationDomainTokenLifetimesStatus{
+		IDToken:         metav1.Duration{Duration: 2 * time.Minute},
+		AccessToken:     metav1.Duration{Duration: 2 * time.Minute},
+		RefreshToken:    metav1.Duration{Duration: 9 * time.Hour},
+		AbsoluteSession: metav1.Duration{Duration: 9 * time.Hour},
+	}
+}
+
+// expectedConditions returns the conditions which the FederationDomain watcher writes for a valid FederationDomain,
+// with any of them replaced by the overrides of the same type.
+func expectedConditions(now time.Time, overrides ...v1alpha1.Condition) []v1alpha1.Condition {
+	conditions := []v1alpha1.Condition{
+		{
+			Type:    "IdentityProvidersFound",
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Success",
+			Message: "no identity providers are named in the spec",
+		},
+		{
+			Type:    "IssuerIsUnique",
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Success",
+			Message: "there is no other FederationDomain with the same issuer URL",
+		},
+		{
+			Type:    "IssuerURLValid",
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Success",
+			Message: "spec.issuer is a valid URL",
+		},
+		{
+			Type:    "OneTLSSecretPerIssuerHostname",
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Success",
+			Message: "all FederationDomains with the same issuer DNS hostname use the same secretName",
+		},
+		{
+			Type:    "SettingsValid",
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Success",
+			Message: "the token lifetimes, signing keys and supplied secrets are valid",
+		},
+	}
+	for i := range conditions {
+		for _, override := range overrides {
+			if override.Type == conditions[i].Type {
+				conditions[i] = override
+			}
+		}
+		conditions[i].LastTransitionTime = metav1.NewTime(now)
+	}
+	return conditions
+}
@@ -11,6 +11,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
@@ -20,6 +22,7 @@ import (
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/plog"
 )
@@ -31,11 +34,14 @@ type federationDomainSecretsController struct {
 	pinnipedClient           pinnipedclientset.Interface
 	federationDomainInformer configinformers.FederationDomainInformer
 	secretInformer           corev1informers.SecretInformer
+	clock                    clock.Clock
 }
 
 // NewFederationDomainSecretsController returns a controllerlib.Controller that ensures a child Secret
 // always exists for a parent FederationDomain. It does this using the provided secretHelper, which
-// provides the parent/child mapping logic.
+// provides the parent/child mapping logic. When the FederationDomain names a Secret which was supplied by
+// its administrator, that Secret is used instead and it is never created or updated by this controller.
+// The controller also writes the condition of the FederationDomain which says whether its Secret is present.
 func NewFederationDomainSecretsController(
 	secretHelper SecretHelper,
 	secretRefFunc func(domain *configv1alpha1.FederationDomainStatus) *corev1.LocalObjectReference,
@@ -43,25 +49,30 @@ func NewFederationDomainSecretsController(
 	pinnipedClient pinnipedclientset.Interface,
 	secretInformer corev1informers.SecretInformer,
 	federationDomainInformer configinformers.FederationDomainInformer,
+	clock clock.Clock,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
+	c := &federationDomainSecretsController{
+		secretHelper:             secretHelper,
+		secretRefFunc:            secretRefFunc,
+		kubeClient:               kubeClient,
+		pinnipedClient:           pinnipedClient,
+		secretInformer:           secretInformer,
+		federationDomainInformer: federationDomainInformer,
+		clock:                    clock,
+	}
 	return controllerlib.New(
 		controllerlib.Config{
-			Name: fmt.Sprintf("%s%s", secretHelper.NamePrefix(), "controller"),
-			Syncer: &federationDomainSecretsController{
-				secretHelper:             secretHelper,
-				secretRefFunc:            secretRefFunc,
-				kubeClient:               kubeClient,
-				pinnipedClient:           pinnipedClient,
-				secretInformer:           secretInformer,
-				federationDomainInformer: federationDomainInformer,
-			},
+			Name:   fmt.Sprintf("%s%s", secretHelper.NamePrefix(), "controller"),
+			Syncer: c,
 		},
 		// We want to be notified when a FederationDomain's secret gets updated or deleted. When this happens, we
 		// should get notified via the corresponding FederationDomain key.
 		withInformer(
 			secretInformer,
-			pinnipedcontroller.SimpleFilter(secretHelper.Handles, pinnipedcontroller.SecretIsControlledByParentFunc(secretHelper.Handles)),
+			pinnipedcontroller.SimpleFilter(func(obj metav1.Object) bool {
+				return c.federationDomainKeyForSecret(obj) != controllerlib.Key{}
+			}, c.federationDomainKeyForSecret),
 			controllerlib.InformerOption{},
 		),
 		// We want to be notified when anything happens to an FederationDomain.
@@ -97,6 +108,10 @@ func (c *federationDomainSecretsController) Sync(ctx controllerlib.Context) erro
 	}
 
 	federationDomain = federationDomain.DeepCopy()
+	if suppliedSecretName := c.secretHelper.SuppliedSecretName(federationDomain); suppliedSecretName != "" {
+		return c.syncSuppliedSecret(ctx.Context, federationDomain, suppliedSecretName)
+	}
+
 	newSecret, err := c.secretHelper.Generate(federationDomain)
 	if err != nil {
 		return fmt.Errorf("failed to generate secret: %w", err)
@@ -116,8 +131,24 @@ func (c *federationDomainSecretsController) Sync(ctx controllerlib.Context) erro
 			klog.KObj(existingSecret),
 		)
 
+		rotatedSecret, requeueAfter, err := c.secretHelper.Rotate(existingSecret)
+		if err != nil {
+			return fmt.Errorf("failed to rotate secret: %w", err)
+		}
+		if rotatedSecret != nil {
+			// The resource version from the cache is kept, so this fails and is retried if the secret was changed since.
+			if _, err := c.kubeClient.CoreV1().Secrets(rotatedSecret.Namespace).Update(ctx.Context, rotatedSecret, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update rotated secret: %w", err)
+			}
+			plog.Debug("rotated secret", "federationdomain", klog.KObj(federationDomain), "secret", klog.KObj(rotatedSecret))
+			existingSecret = rotatedSecret
+		}
+		if requeueAfter > 0 {
+			ctx.Queue.AddAfter(ctx.Key, requeueAfter)
+		}
+
 		federationDomain = c.secretHelper.ObserveActiveSecretAndUpdateParentFederationDomain(federationDomain, existingSecret)
-		if err := c.updateFederationDomainStatus(ctx.Context, federationDomain); err != nil {
+		if err := c.updateFederationDomainStatus(ctx.Context, federationDomain, c.secretPresentCondition(existingSecret.Name)); err != nil {
 			return fmt.Errorf("failed to update federationdomain: %w", err)
 		}
 		plog.Debug("updated federationdomain", "federationdomain", klog.KObj(federationDomain), "secret", klog.KObj(newSecret))
@@ -133,7 +164,7 @@ func (c *federationDomainSecretsController) Sync(ctx controllerlib.Context) erro
 	plog.Debug("created/updated secret", "federationdomain", klog.KObj(federationDomain), "secret", klog.KObj(newSecret))
 
 	federationDomain = c.secretHelper.ObserveActiveSecretAndUpdateParentFederationDomain(federationDomain, newSecret)
-	if err := c.updateFederationDomainStatus(ctx.Context, federationDomain); err != nil {
+	if err := c.updateFederationDomainStatus(ctx.Context, federationDomain, c.secretPresentCondition(newSecret.Name)); err != nil {
 		return fmt.Errorf("failed to update federationdomain: %w", err)
 	}
 	plog.Debug("updated federationdomain", "federationdomain", klog.KObj(federationDomain), "secret", klog.KObj(newSecret))
@@ -141,6 +172,69 @@ func (c *federationDomainSecretsController) Sync(ctx controllerlib.Context) erro
 	return nil
 }
 
+// syncSuppliedSecret uses the Secret which was supplied by the administrator of the federationDomain param.
+// That Secret is never created or updated. When it is missing or invalid, only the condition of the
+// FederationDomain is updated, since the FederationDomain watcher reports the details of the problem.
+func (c *federationDomainSecretsController) syncSuppliedSecret(
+	ctx context.Context,
+	federationDomain *configv1alpha1.FederationDomain,
+	secretName string,
+) error {
+	secret, err := c.secretInformer.Lister().Secrets(federationDomain.Namespace).Get(secretName)
+	notFound := k8serrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return fmt.Errorf("failed to get supplied secret: %w", err)
+	}
+	if notFound || !c.secretHelper.IsValid(federationDomain, secret) {
+		plog.Debug(
+			"supplied secret is missing or invalid",
+			"federationdomain",
+			klog.KObj(federationDomain),
+			"secret",
+			klog.KRef(federationDomain.Namespace, secretName),
+		)
+		if err := c.updateFederationDomainStatus(ctx, federationDomain, &configv1alpha1.Condition{
+			Type:    c.secretHelper.ConditionType(),
+			Status:  configv1alpha1.ConditionFalse,
+			Reason:  "SuppliedSecretInvalid",
+			Message: fmt.Sprintf("the supplied Secret %q is missing or invalid", secretName),
+		}); err != nil {
+			return fmt.Errorf("failed to update federationdomain: %w", err)
+		}
+		return nil
+	}
+
+	federationDomain = c.secretHelper.ObserveActiveSecretAndUpdateParentFederationDomain(federationDomain, secret)
+	if err := c.updateFederationDomainStatus(ctx, federationDomain, c.secretPresentCondition(secret.Name)); err != nil {
+		return fmt.Errorf("failed to update federationdomain: %w", err)
+	}
+	plog.Debug("updated federationdomain", "federationdomain", klog.KObj(federationDomain), "secret", klog.KObj(secret))
+
+	return nil
+}
+
+// federationDomainKeyForSecret returns the key of the FederationDomain which either controls the Secret or names
+// it as a supplied Secret, or an empty key when there is no such FederationDomain.
+func (c *federationDomainSecretsController) federationDomainKeyForSecret(obj metav1.Object) controllerlib.Key {
+	if c.secretHelper.Handles(obj) {
+		return pinnipedcontroller.SecretIsControlledByParentFunc(c.secretHelper.Handles)(obj)
+	}
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return controllerlib.Key{}
+	}
+
+	federationDomains, err := c.federationDomainInformer.Lister().FederationDomains(obj.GetNamespace()).List(k8slabels.Everything())
+	if err != nil {
+		return controllerlib.Key{}
+	}
+	for _, federationDomain := range federationDomains {
+		if suppliedSecretName := c.secretHelper.SuppliedSecretName(federationDomain); suppliedSecretName != "" && suppliedSecretName == obj.GetName() {
+			return controllerlib.Key{Namespace: federationDomain.Namespace, Name: federationDomain.Name}
+		}
+	}
+	return controllerlib.Key{}
+}
+
 // secretNeedsUpdate returns whether or not the Secret, with name secretName, for the federationDomain param
 // needs to be updated. It returns the existing secret as its second argument.
 func (c *federationDomainSecretsController) secretNeedsUpdate(
@@ -205,9 +299,19 @@ func (c *federationDomainSecretsController) createOrUpdateSecret(
 	})
 }
 
+func (c *federationDomainSecretsController) secretPresentCondition(secretName string) *configv1alpha1.Condition {
+	return &configv1alpha1.Condition{
+		Type:    c.secretHelper.ConditionType(),
+		Status:  configv1alpha1.ConditionTrue,
+		Reason:  "Success",
+		Message: fmt.Sprintf("the key is stored in Secret %q", secretName),
+	}
+}
+
 func (c *federationDomainSecretsController) updateFederationDomainStatus(
 	ctx context.Context,
 	newFederationDomain *configv1alpha1.FederationDomain,
+	condition *configv1alpha1.Condition,
 ) error {
 	federationDomainClient := c.pinnipedClient.ConfigV1alpha1().FederationDomains(newFederationDomain.Namespace)
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -216,9 +320,15 @@ func (c *federationDomainSecretsController) updateFederationDomainStatus(
 			return fmt.Errorf("failed to get federationdomain %s/%s: %w", newFederationDomain.Namespace, newFederationDomain.Name, err)
 		}
 
+		conditionChanged := conditionsutil.MergeConfigConditions(
+			[]*configv1alpha1.Condition{condition},
+			oldFederationDomain.Generation,
+			&oldFederationDomain.Status.Conditions,
+			metav1.NewTime(c.clock.Now()),
+		)
 		oldFederationDomainSecretRef := c.secretRefFunc(&oldFederationDomain.Status)
 		newFederationDomainSecretRef := c.secretRefFunc(&newFederationDomain.Status)
-		if reflect.DeepEqual(oldFederationDomainSecretRef, newFederationDomainSecretRef) {
+		if reflect.DeepEqual(oldFederationDomainSecretRef, newFederationDomainSecretRef) && !conditionChanged {
 			return nil
 		}
 
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -18,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
@@ -33,13 +35,23 @@ import (
 func TestFederationDomainControllerFilterSecret(t *testing.T) {
 	t.Parallel()
 
+	federationDomainWithSuppliedSecret := &configv1alpha1.FederationDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+		Spec: configv1alpha1.FederationDomainSpec{
+			Secrets: &configv1alpha1.FederationDomainSecretsSpec{
+				TokenSigningKey: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+			},
+		},
+	}
+
 	tests := []struct {
-		name       string
-		secret     metav1.Object
-		wantAdd    bool
-		wantUpdate bool
-		wantDelete bool
-		wantParent controllerlib.Key
+		name              string
+		secret            metav1.Object
+		federationDomains []*configv1alpha1.FederationDomain
+		wantAdd           bool
+		wantUpdate        bool
+		wantDelete        bool
+		wantParent        controllerlib.Key
 	}{
 		{
 			name: "no owner reference",
@@ -48,6 +60,33 @@ func TestFederationDomainControllerFilterSecret(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{},
 			},
 		},
+		{
+			name: "no owner reference but supplied by a FederationDomain",
+			secret: &corev1.Secret{
+				Type:       "secrets.pinniped.dev/federation-domain-token-signing-key",
+				ObjectMeta: metav1.ObjectMeta{Name: "some-supplied-secret", Namespace: "some-namespace"},
+			},
+			federationDomains: []*configv1alpha1.FederationDomain{federationDomainWithSuppliedSecret},
+			wantAdd:           true,
+			wantUpdate:        true,
+			wantDelete:        true,
+			wantParent:        controllerlib.Key{Namespace: "some-namespace", Name: "some-name"},
+		},
+		{
+			name: "no owner reference and supplied by a FederationDomain for another usage",
+			secret: &corev1.Secret{
+				Type:       "secrets.pinniped.dev/federation-domain-token-signing-key",
+				ObjectMeta: metav1.ObjectMeta{Name: "some-other-supplied-secret", Namespace: "some-namespace"},
+			},
+			federationDomains: []*configv1alpha1.FederationDomain{{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+				Spec: configv1alpha1.FederationDomainSpec{
+					Secrets: &configv1alpha1.FederationDomainSecretsSpec{
+						StateSigningKey: corev1.LocalObjectReference{Name: "some-other-supplied-secret"},
+					},
+				},
+			}},
+		},
 		{
 			name: "owner reference without correct APIVersion",
 			secret: &corev1.Secret{
@@ -177,7 +216,8 @@ func TestFederationDomainControllerFilterSecret(t *testing.T) {
 				map[string]string{},
 				rand.Reader,
 				SecretUsageTokenSigningKey,
-				func(cacheKey string, cacheValue []byte) {},
+				KeyRotation{},
+				func(cacheKey string, cacheValues [][]byte) {},
 			)
 
 			secretInformer := kubeinformers.NewSharedInformerFactory(
@@ -188,6 +228,9 @@ func TestFederationDomainControllerFilterSecret(t *testing.T) {
 				pinnipedfake.NewSimpleClientset(),
 				0,
 			).Config().V1alpha1().FederationDomains()
+			for _, federationDomain := range test.federationDomains {
+				require.NoError(t, federationDomainInformer.Informer().GetIndexer().Add(federationDomain))
+			}
 			withInformer := testutil.NewObservableWithInformerOption()
 			_ = NewFederationDomainSecretsController(
 				secretHelper,
@@ -196,6 +239,7 @@ func TestFederationDomainControllerFilterSecret(t *testing.T) {
 				nil, // pinnipedClient, not needed
 				secretInformer,
 				federationDomainInformer,
+				nil, // clock, not needed
 				withInformer.WithInformer,
 			)
 
@@ -240,7 +284,8 @@ func TestNewFederationDomainSecretsControllerFilterFederationDomain(t *testing.T
 				map[string]string{},
 				rand.Reader,
 				SecretUsageTokenSigningKey,
-				func(cacheKey string, cacheValue []byte) {},
+				KeyRotation{},
+				func(cacheKey string, cacheValues [][]byte) {},
 			)
 
 			secretInformer := kubeinformers.NewSharedInformerFactory(
@@ -259,6 +304,7 @@ func TestNewFederationDomainSecretsControllerFilterFederationDomain(t *testing.T
 				nil, // pinnipedClient, not needed
 				secretInformer,
 				federationDomainInformer,
+				nil, // clock, not needed
 				withInformer.WithInformer,
 			)
 
@@ -286,6 +332,8 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 		secretUID  = "secret-uid"
 	)
 
+	now := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+
 	federationDomainGVR := schema.GroupVersionResource{
 		Group:    configv1alpha1.SchemeGroupVersion.Group,
 		Version:  configv1alpha1.SchemeGroupVersion.Version,
@@ -341,6 +389,32 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 	goodFederationDomainWithJWKSAndTokenSigningKey := goodFederationDomainWithJWKS.DeepCopy()
 	goodFederationDomainWithJWKSAndTokenSigningKey.Status.Secrets.TokenSigningKey = goodFederationDomainWithTokenSigningKey.Status.Secrets.TokenSigningKey
 
+	tokenSigningKeyPresentCondition := func(status configv1alpha1.ConditionStatus, reason, message string) []configv1alpha1.Condition {
+		return []configv1alpha1.Condition{{
+			Type:               "TokenSigningKeyPresent",
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now),
+			Reason:             reason,
+			Message:            message,
+		}}
+	}
+
+	goodFederationDomainWithTokenSigningKeyAndCondition := goodFederationDomainWithTokenSigningKey.DeepCopy()
+	goodFederationDomainWithTokenSigningKeyAndCondition.Status.Conditions = tokenSigningKeyPresentCondition(
+		configv1alpha1.ConditionTrue, "Success", fmt.Sprintf("the key is stored in Secret %q", goodSecret.Name),
+	)
+
+	goodFederationDomainWithJWKSAndTokenSigningKeyAndCondition := goodFederationDomainWithJWKSAndTokenSigningKey.DeepCopy()
+	goodFederationDomainWithJWKSAndTokenSigningKeyAndCondition.Status.Conditions = goodFederationDomainWithTokenSigningKeyAndCondition.Status.Conditions
+
+	federationDomainWithInvalidSuppliedSecretCondition := func(secretName string) *configv1alpha1.FederationDomain {
+		federationDomain := goodFederationDomain.DeepCopy()
+		federationDomain.Status.Conditions = tokenSigningKeyPresentCondition(
+			configv1alpha1.ConditionFalse, "SuppliedSecretInvalid", fmt.Sprintf("the supplied Secret %q is missing or invalid", secretName),
+		)
+		return federationDomain
+	}
+
 	invalidSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      secretName,
@@ -359,6 +433,12 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 		},
 	}
 
+	suppliedSecret := goodSecret.DeepCopy()
+	suppliedSecret.OwnerReferences = nil
+
+	rotatedSecret := goodSecret.DeepCopy()
+	rotatedSecret.Data["some-key"] = []byte("some-rotated-value")
+
 	tests := []struct {
 		name                        string
 		storage                     func(**configv1alpha1.FederationDomain, **corev1.Secret)
@@ -366,6 +446,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 		secretHelper                func(*mocksecrethelper.MockSecretHelper)
 		wantFederationDomainActions []kubetesting.Action
 		wantSecretActions           []kubetesting.Action
+		wantRequeueAfter            time.Duration
 		wantError                   string
 	}{
 		{
@@ -392,7 +473,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
 			},
 			wantSecretActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretGVR, namespace, goodSecret.Name),
@@ -415,7 +496,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithJWKSAndTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithJWKSAndTokenSigningKeyAndCondition),
 			},
 			wantSecretActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretGVR, namespace, goodSecret.Name),
@@ -433,7 +514,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			client: func(c *pinnipedfake.Clientset, _ *kubernetesfake.Clientset) {
 				c.PrependReactor("get", "federationdomains", func(_ kubetesting.Action) (bool, runtime.Object, error) {
-					return true, goodFederationDomainWithJWKSAndTokenSigningKey, nil
+					return true, goodFederationDomainWithJWKSAndTokenSigningKeyAndCondition, nil
 				})
 			},
 			wantFederationDomainActions: []kubetesting.Action{
@@ -456,13 +537,122 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
 			},
 			wantSecretActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretGVR, namespace, goodSecret.Name),
 				kubetesting.NewUpdateAction(secretGVR, namespace, goodSecret),
 			},
 		},
+		{
+			name: "FederationDomain exists and valid secret exists",
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().Generate(goodFederationDomain).Times(1).Return(goodSecret, nil)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, goodSecret).Times(1).Return(true)
+				secretHelper.EXPECT().Rotate(goodSecret).Times(1).Return(nil, time.Duration(0), nil)
+				secretHelper.EXPECT().ObserveActiveSecretAndUpdateParentFederationDomain(goodFederationDomain, goodSecret).Times(1).Return(goodFederationDomainWithTokenSigningKey)
+			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
+			},
+		},
+		{
+			name: "FederationDomain exists and valid secret exists which will need to be rotated later",
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().Generate(goodFederationDomain).Times(1).Return(goodSecret, nil)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, goodSecret).Times(1).Return(true)
+				secretHelper.EXPECT().Rotate(goodSecret).Times(1).Return(nil, time.Hour, nil)
+				secretHelper.EXPECT().ObserveActiveSecretAndUpdateParentFederationDomain(goodFederationDomain, goodSecret).Times(1).Return(goodFederationDomainWithTokenSigningKey)
+			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
+			},
+			wantRequeueAfter: time.Hour,
+		},
+		{
+			name: "FederationDomain exists and valid secret exists which is rotated",
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().Generate(goodFederationDomain).Times(1).Return(goodSecret, nil)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, goodSecret).Times(1).Return(true)
+				secretHelper.EXPECT().Rotate(goodSecret).Times(1).Return(rotatedSecret, time.Hour, nil)
+				secretHelper.EXPECT().ObserveActiveSecretAndUpdateParentFederationDomain(goodFederationDomain, rotatedSecret).Times(1).Return(goodFederationDomainWithTokenSigningKey)
+			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
+			},
+			wantSecretActions: []kubetesting.Action{
+				kubetesting.NewUpdateAction(secretGVR, namespace, rotatedSecret),
+			},
+			wantRequeueAfter: time.Hour,
+		},
+		{
+			name: "FederationDomain exists and valid secret exists and rotating it fails",
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().Generate(goodFederationDomain).Times(1).Return(goodSecret, nil)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, goodSecret).Times(1).Return(true)
+				secretHelper.EXPECT().Rotate(goodSecret).Times(1).Return(nil, time.Duration(0), errors.New("some rotate error"))
+			},
+			wantError: "failed to rotate secret: some rotate error",
+		},
+		{
+			name: "FederationDomain exists and valid secret exists and updating the rotated secret fails",
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().Generate(goodFederationDomain).Times(1).Return(goodSecret, nil)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, goodSecret).Times(1).Return(true)
+				secretHelper.EXPECT().Rotate(goodSecret).Times(1).Return(rotatedSecret, time.Hour, nil)
+			},
+			client: func(_ *pinnipedfake.Clientset, c *kubernetesfake.Clientset) {
+				c.PrependReactor("update", "secrets", func(_ kubetesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some update error")
+				})
+			},
+			wantError: "failed to update rotated secret: some update error",
+		},
+		{
+			name: "FederationDomain exists and supplied secret exists",
+			storage: func(federationDomain **configv1alpha1.FederationDomain, s **corev1.Secret) {
+				*s = suppliedSecret.DeepCopy()
+			},
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().SuppliedSecretName(goodFederationDomain).AnyTimes().Return(suppliedSecret.Name)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, suppliedSecret).Times(1).Return(true)
+				secretHelper.EXPECT().ObserveActiveSecretAndUpdateParentFederationDomain(goodFederationDomain, suppliedSecret).Times(1).Return(goodFederationDomainWithTokenSigningKey)
+			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
+			},
+		},
+		{
+			name: "FederationDomain exists and invalid supplied secret exists",
+			storage: func(federationDomain **configv1alpha1.FederationDomain, s **corev1.Secret) {
+				*s = suppliedSecret.DeepCopy()
+			},
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().SuppliedSecretName(goodFederationDomain).AnyTimes().Return(suppliedSecret.Name)
+				secretHelper.EXPECT().IsValid(goodFederationDomain, suppliedSecret).Times(1).Return(false)
+			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, federationDomainWithInvalidSuppliedSecretCondition(suppliedSecret.Name)),
+			},
+		},
+		{
+			name: "FederationDomain exists and supplied secret does not exist",
+			storage: func(federationDomain **configv1alpha1.FederationDomain, s **corev1.Secret) {
+				*s = nil
+			},
+			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
+				secretHelper.EXPECT().SuppliedSecretName(goodFederationDomain).AnyTimes().Return("some-supplied-secret")
+			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, federationDomainWithInvalidSuppliedSecretCondition("some-supplied-secret")),
+			},
+		},
 		{
 			name: "FederationDomain exists and generating a secret fails",
 			secretHelper: func(secretHelper *mocksecrethelper.MockSecretHelper) {
@@ -483,7 +673,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
 			},
 			wantSecretActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretGVR, namespace, goodSecret.Name),
@@ -561,7 +751,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
 			},
 			wantSecretActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretGVR, namespace, goodSecret.Name),
@@ -614,9 +804,9 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKey),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithTokenSigningKeyAndCondition),
 			},
 			wantSecretActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretGVR, namespace, goodSecret.Name),
@@ -672,7 +862,11 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			if test.secretHelper != nil {
 				test.secretHelper(secretHelper)
 			}
-			secretHelper.EXPECT().Handles(gomock.Any()).AnyTimes().Return(true)
+			secretHelper.EXPECT().SuppliedSecretName(gomock.Any()).AnyTimes().Return("")
+			secretHelper.EXPECT().ConditionType().AnyTimes().Return("TokenSigningKeyPresent")
+			secretHelper.EXPECT().Handles(gomock.Any()).AnyTimes().DoAndReturn(func(obj metav1.Object) bool {
+				return metav1.GetControllerOf(obj) != nil
+			})
 
 			c := NewFederationDomainSecretsController(
 				secretHelper,
@@ -683,6 +877,7 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 				pinnipedAPIClient,
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().FederationDomains(),
+				clock.NewFakeClock(now),
 				controllerlib.WithInformer,
 			)
 
@@ -691,18 +886,21 @@ func TestFederationDomainSecretsControllerSync(t *testing.T) {
 			pinnipedInformers.Start(ctx.Done())
 			controllerlib.TestRunSynchronously(t, c)
 
+			queue := &rotationTestQueue{}
 			err := controllerlib.TestSync(t, c, controllerlib.Context{
 				Context: ctx,
 				Key: controllerlib.Key{
 					Namespace: namespace,
 					Name:      federationDomainName,
 				},
+				Queue: queue,
 			})
 			if test.wantError != "" {
 				require.EqualError(t, err, test.wantError)
 				return
 			}
 			require.NoError(t, err)
+			require.Equal(t, test.wantRequeueAfter, queue.duration)
 
 			if test.wantFederationDomainActions == nil {
 				test.wantFederationDomainActions = []kubetesting.Action{}
@@ -0,0 +1,125 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package generator
+
+import (
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+const (
+	// previousSymmetricSecretDataKey is the corev1.Secret.Data key for the symmetric key which was replaced by the
+	// most recent rotation. It is kept until the values which were encoded with it are no longer valid.
+	previousSymmetricSecretDataKey = "previousKey"
+
+	// symmetricKeyActiveFromDataKey is the corev1.Secret.Data key for the time, in RFC 3339 format, at which the
+	// current symmetric key started being used. It is only present once the key is managed by a KeyRotation.
+	symmetricKeyActiveFromDataKey = "keyActiveFrom"
+
+	// minimumRotationRequeueInterval limits how soon a controller checks on a rotated key again.
+	minimumRotationRequeueInterval = time.Second
+)
+
+// KeyRotation configures the time-based rotation of a generated symmetric key. The zero value never rotates the
+// key.
+type KeyRotation struct {
+	// Interval is how long each key is used before it is replaced. The key is not rotated when it is zero.
+	Interval time.Duration
+
+	// GracePeriod is how long the previous key is kept after a rotation. It should be the lifespan of the values
+	// which were encoded with the previous key, so that they can still be decoded. Since only one previous key is
+	// kept, the next rotation waits for the grace period to end even when the interval is shorter.
+	GracePeriod time.Duration
+
+	// Clock tells the time of the rotation. The real clock is used when it is nil.
+	Clock clock.Clock
+}
+
+func (r KeyRotation) now() time.Time {
+	if r.Clock == nil {
+		return time.Now().UTC()
+	}
+	return r.Clock.Now().UTC()
+}
+
+// symmetricKeys returns the symmetric keys in the secret, newest first.
+func symmetricKeys(secret *corev1.Secret) [][]byte {
+	keys := [][]byte{secret.Data[symmetricSecretDataKey]}
+	if previousKey := secret.Data[previousSymmetricSecretDataKey]; len(previousKey) == symmetricKeySize {
+		keys = append(keys, previousKey)
+	}
+	return keys
+}
+
+// rotateSymmetricKey follows the rotation policy for the symmetric key in the secret, which must be valid. It
+// returns a copy of the secret with its new data, or nil when the data does not need to change, and how long to wait
+// before the data needs to change again, which is zero when it never does.
+func rotateSymmetricKey(
+	secret *corev1.Secret,
+	rotation KeyRotation,
+	generateKey func() ([]byte, error),
+) (*corev1.Secret, time.Duration, error) {
+	now := rotation.now()
+
+	data := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+
+	activeFrom, err := time.Parse(time.RFC3339, string(data[symmetricKeyActiveFromDataKey]))
+	hasActiveFrom := err == nil
+
+	// The previous key is dropped once the grace period after its replacement has passed. Without a time of
+	// replacement, there is no telling how long it has been kept for, so it is dropped right away.
+	_, hasPreviousKey := data[previousSymmetricSecretDataKey]
+	if hasPreviousKey && (!hasActiveFrom || !now.Before(activeFrom.Add(rotation.GracePeriod))) {
+		delete(data, previousSymmetricSecretDataKey)
+		hasPreviousKey = false
+	}
+
+	var nextChange time.Time
+	if rotation.Interval > 0 {
+		if !hasActiveFrom {
+			// The key was generated before it was managed by a rotation policy, so its interval starts now.
+			activeFrom = now
+			data[symmetricKeyActiveFromDataKey] = []byte(now.Format(time.RFC3339))
+		}
+
+		if !hasPreviousKey && !now.Before(activeFrom.Add(rotation.Interval)) {
+			key, err := generateKey()
+			if err != nil {
+				return nil, 0, err
+			}
+			data[previousSymmetricSecretDataKey] = data[symmetricSecretDataKey]
+			data[symmetricSecretDataKey] = key
+			data[symmetricKeyActiveFromDataKey] = []byte(now.Format(time.RFC3339))
+			activeFrom = now
+			hasPreviousKey = true
+		}
+
+		nextChange = activeFrom.Add(rotation.Interval)
+	}
+	if hasPreviousKey {
+		// Either the previous key is dropped first, or the next rotation is waiting for it to be dropped.
+		nextChange = activeFrom.Add(rotation.GracePeriod)
+	}
+
+	var requeueAfter time.Duration
+	if !nextChange.IsZero() {
+		requeueAfter = nextChange.Sub(now)
+		if requeueAfter < minimumRotationRequeueInterval {
+			requeueAfter = minimumRotationRequeueInterval
+		}
+	}
+
+	if reflect.DeepEqual(data, secret.Data) {
+		return nil, requeueAfter, nil
+	}
+	rotatedSecret := secret.DeepCopy()
+	rotatedSecret.Data = data
+	return rotatedSecret, requeueAfter, nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"go.pinniped.dev/internal/controllerlib"
+)
+
+func TestRotateSymmetricKey(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	formatted := func(t time.Time) []byte { return []byte(t.Format(time.RFC3339)) }
+
+	var (
+		currentKey   = []byte("some-current-32-byte-symmetric-k")
+		previousKey  = []byte("some-previous-32-byte-symmetric-")
+		generatedKey = []byte("some-generated-32-byte-symmetric")
+
+		rotation = KeyRotation{
+			Interval:    30 * 24 * time.Hour,
+			GracePeriod: 7 * 24 * time.Hour,
+			Clock:       clock.NewFakeClock(now),
+		}
+		noRotation = KeyRotation{
+			GracePeriod: 7 * 24 * time.Hour,
+			Clock:       clock.NewFakeClock(now),
+		}
+	)
+
+	tests := []struct {
+		name             string
+		rotation         KeyRotation
+		data             map[string][]byte
+		generateKey      func() ([]byte, error)
+		wantData         map[string][]byte
+		wantRequeueAfter time.Duration
+		wantError        string
+	}{
+		{
+			name:     "rotation is turned off and there is no previous key",
+			rotation: noRotation,
+			data:     map[string][]byte{"key": currentKey},
+		},
+		{
+			name:     "rotation is turned off and the previous key is still in its grace period",
+			rotation: noRotation,
+			data: map[string][]byte{
+				"key":           currentKey,
+				"previousKey":   previousKey,
+				"keyActiveFrom": formatted(now.Add(-time.Hour)),
+			},
+			wantRequeueAfter: 7*24*time.Hour - time.Hour,
+		},
+		{
+			name:     "rotation is turned off and the grace period of the previous key has passed",
+			rotation: noRotation,
+			data: map[string][]byte{
+				"key":           currentKey,
+				"previousKey":   previousKey,
+				"keyActiveFrom": formatted(now.Add(-7 * 24 * time.Hour)),
+			},
+			wantData: map[string][]byte{
+				"key":           currentKey,
+				"keyActiveFrom": formatted(now.Add(-7 * 24 * time.Hour)),
+			},
+		},
+		{
+			name:     "the previous key has no time of replacement",
+			rotation: noRotation,
+			data: map[string][]byte{
+				"key":         currentKey,
+				"previousKey": previousKey,
+			},
+			wantData: map[string][]byte{
+				"key": currentKey,
+			},
+		},
+		{
+			name:     "the key was generated before rotation was turned on",
+			rotation: rotation,
+			data:     map[string][]byte{"key": currentKey},
+			wantData: map[string][]byte{
+				"key":           currentKey,
+				"keyActiveFrom": formatted(now),
+			},
+			wantRequeueAfter: 30 * 24 * time.Hour,
+		},
+		{
+			name:     "the key is not due for rotation yet",
+			rotation: rotation,
+			data: map[string][]byte{
+				"key":           currentKey,
+				"keyActiveFrom": formatted(now.Add(-10 * 24 * time.Hour)),
+			},
+			wantRequeueAfter: 20 * 24 * time.Hour,
+		},
+		{
+			name:     "the key is due for rotation",
+			rotation: rotation,
+			data: map[string][]byte{
+				"key":           currentKey,
+				"keyActiveFrom": formatted(now.Add(-30 * 24 * time.Hour)),
+			},
+			wantData: map[string][]byte{
+				"key":           generatedKey,
+				"previousKey":   currentKey,
+				"keyActiveFrom": formatted(now),
+			},
+			wantRequeueAfter: 7 * 24 * time.Hour,
+		},
+		{
+			name:     "the key is overdue for rotation and the grace period of the previous key has passed",
+			rotation: rotation,
+			data: map[string][]byte{
+				"key":           currentKey,
+				"previousKey":   previousKey,
+				"keyActiveFrom": formatted(now.Add(-40 * 24 * time.Hour)),
+			},
+			wantData: map[string][]byte{
+				"key":           generatedKey,
+				"previousKey":   currentKey,
+				"keyActiveFrom": formatted(now),
+			},
+			wantRequeueAfter: 7 * 24 * time.Hour,
+		},
+		{
+			name: "the key is due for rotation but the previous key is still in its grace period",
+			rotation: KeyRotation{
+				Interval:    time.Hour,
+				GracePeriod: 7 * 24 * time.Hour,
+				Clock:       clock.NewFakeClock(now),
+			},
+			data: map[string][]byte{
+				"key":           currentKey,
+				"previousKey":   previousKey,
+				"keyActiveFrom": formatted(now.Add(-2 * time.Hour)),
+			},
+			wantRequeueAfter: 7*24*time.Hour - 2*time.Hour,
+		},
+		{
+			name: "the grace period of the previous key has just passed",
+			rotation: KeyRotation{
+				Interval:    30 * 24 * time.Hour,
+				GracePeriod: 0,
+				Clock:       clock.NewFakeClock(now),
+			},
+			data: map[string][]byte{
+				"key":           currentKey,
+				"previousKey":   previousKey,
+				"keyActiveFrom": formatted(now),
+			},
+			wantData: map[string][]byte{
+				"key":           currentKey,
+				"keyActiveFrom": formatted(now),
+			},
+			wantRequeueAfter: 30 * 24 * time.Hour,
+		},
+		{
+			name:     "generating the key fails",
+			rotation: rotation,
+			data: map[string][]byte{
+				"key":           currentKey,
+				"keyActiveFrom": formatted(now.Add(-30 * 24 * time.Hour)),
+			},
+			generateKey: func() ([]byte, error) {
+				return nil, errors.New("some generate error")
+			},
+			wantError: "some generate error",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace", ResourceVersion: "1"},
+				Type:       FederationDomainStateSigningKeyType,
+				Data:       test.data,
+			}
+			generateKey := test.generateKey
+			if generateKey == nil {
+				generateKey = func() ([]byte, error) { return generatedKey, nil }
+			}
+
+			rotatedSecret, requeueAfter, err := rotateSymmetricKey(secret, test.rotation, generateKey)
+			if test.wantError != "" {
+				require.EqualError(t, err, test.wantError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantRequeueAfter, requeueAfter)
+
+			if test.wantData == nil {
+				require.Nil(t, rotatedSecret)
+				return
+			}
+			wantSecret := secret.DeepCopy()
+			wantSecret.Data = test.wantData
+			require.Equal(t, wantSecret, rotatedSecret)
+		})
+	}
+}
+
+func TestSymmetricKeys(t *testing.T) {
+	var (
+		currentKey  = []byte("some-current-32-byte-symmetric-k")
+		previousKey = []byte("some-previous-32-byte-symmetric-")
+	)
+
+	require.Equal(t, [][]byte{currentKey}, symmetricKeys(&corev1.Secret{
+		Data: map[string][]byte{"key": currentKey},
+	}))
+	require.Equal(t, [][]byte{currentKey, previousKey}, symmetricKeys(&corev1.Secret{
+		Data: map[string][]byte{"key": currentKey, "previousKey": previousKey},
+	}))
+	require.Equal(t, [][]byte{currentKey}, symmetricKeys(&corev1.Secret{
+		Data: map[string][]byte{"key": currentKey, "previousKey": []byte("too short")},
+	}))
+}
+
+// rotationTestQueue records how long a controller waits before it checks on a rotated key again.
+type rotationTestQueue struct {
+	duration time.Duration
+
+	controllerlib.Queue // panic if any other methods called
+}
+
+func (q *rotationTestQueue) AddAfter(_ controllerlib.Key, duration time.Duration) {
+	q.duration = duration
+}
@@ -6,6 +6,7 @@ package generator
 import (
 	"fmt"
 	"io"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,10 +19,22 @@ import (
 // IsValid(). It can also be Notify()'d about a Secret being persisted.
 //
 // A SecretHelper has a NamePrefix() that can be used to identify it from other SecretHelper instances.
+//
+// When SuppliedSecretName() returns a name, the FederationDomain uses a Secret from its administrator instead of
+// a generated Secret, so that Secret must never be updated.
+//
+// Rotate() returns a copy of a valid generated Secret whose key has been rotated, or nil when the Secret does not
+// need to change, along with how long to wait before it needs to change again, which is zero when it never does.
+//
+// ConditionType() is the type of the condition in the parent FederationDomain's status which says whether its
+// Secret is present.
 type SecretHelper interface {
 	NamePrefix() string
+	ConditionType() string
+	SuppliedSecretName(*configv1alpha1.FederationDomain) string
 	Generate(*configv1alpha1.FederationDomain) (*corev1.Secret, error)
 	IsValid(*configv1alpha1.FederationDomain, *corev1.Secret) bool
+	Rotate(*corev1.Secret) (*corev1.Secret, time.Duration, error)
 	ObserveActiveSecretAndUpdateParentFederationDomain(*configv1alpha1.FederationDomain, *corev1.Secret) *configv1alpha1.FederationDomain
 	Handles(metav1.Object) bool
 }
@@ -61,19 +74,21 @@ const (
 )
 
 // New returns a SecretHelper that has been parameterized with common symmetric secret generation
-// knobs.
+// knobs. The updateCacheFunc is called with the keys of the active Secret, newest first.
 func NewSymmetricSecretHelper(
 	namePrefix string,
 	labels map[string]string,
 	rand io.Reader,
 	secretUsage SecretUsage,
-	updateCacheFunc func(cacheKey string, cacheValue []byte),
+	rotation KeyRotation,
+	updateCacheFunc func(cacheKey string, cacheValues [][]byte),
 ) SecretHelper {
 	return &symmetricSecretHelper{
 		namePrefix:      namePrefix,
 		labels:          labels,
 		rand:            rand,
 		secretUsage:     secretUsage,
+		rotation:        rotation,
 		updateCacheFunc: updateCacheFunc,
 	}
 }
@@ -83,15 +98,35 @@ type symmetricSecretHelper struct {
 	labels          map[string]string
 	rand            io.Reader
 	secretUsage     SecretUsage
-	updateCacheFunc func(cacheKey string, cacheValue []byte)
+	rotation        KeyRotation
+	updateCacheFunc func(cacheKey string, cacheValues [][]byte)
 }
 
 func (s *symmetricSecretHelper) NamePrefix() string { return s.namePrefix }
 
+// ConditionType implements SecretHelper.ConditionType().
+func (s *symmetricSecretHelper) ConditionType() string {
+	switch s.secretUsage {
+	case SecretUsageTokenSigningKey:
+		return "TokenSigningKeyPresent"
+	case SecretUsageStateSigningKey:
+		return "StateSigningKeyPresent"
+	case SecretUsageStateEncryptionKey:
+		return "StateEncryptionKeyPresent"
+	default:
+		panic(fmt.Sprintf("unknown secret usage enum value: %d", s.secretUsage))
+	}
+}
+
+// SuppliedSecretName implements SecretHelper.SuppliedSecretName().
+func (s *symmetricSecretHelper) SuppliedSecretName(parent *configv1alpha1.FederationDomain) string {
+	return SuppliedSecretName(parent, s.secretUsage)
+}
+
 // Generate implements SecretHelper.Generate().
 func (s *symmetricSecretHelper) Generate(parent *configv1alpha1.FederationDomain) (*corev1.Secret, error) {
-	key := make([]byte, symmetricKeySize)
-	if _, err := s.rand.Read(key); err != nil {
+	key, err := s.generateKey()
+	if err != nil {
 		return nil, err
 	}
 
@@ -117,23 +152,28 @@ func (s *symmetricSecretHelper) Generate(parent *configv1alpha1.FederationDomain
 
 // IsValid implements SecretHelper.IsValid().
 func (s *symmetricSecretHelper) IsValid(parent *configv1alpha1.FederationDomain, secret *corev1.Secret) bool {
-	if !metav1.IsControlledBy(secret, parent) {
-		return false
+	if suppliedSecretName := s.SuppliedSecretName(parent); suppliedSecretName != "" {
+		return secret.Name == suppliedSecretName && ValidateSuppliedSymmetricSecret(secret, s.secretUsage) == nil
 	}
 
-	if secret.Type != s.secretType() {
+	if !metav1.IsControlledBy(secret, parent) {
 		return false
 	}
 
-	key, ok := secret.Data[symmetricSecretDataKey]
-	if !ok {
-		return false
-	}
-	if len(key) != symmetricKeySize {
-		return false
-	}
+	return validateSymmetricSecretData(secret, s.secretUsage) == nil
+}
 
-	return true
+// Rotate implements SecretHelper.Rotate().
+func (s *symmetricSecretHelper) Rotate(secret *corev1.Secret) (*corev1.Secret, time.Duration, error) {
+	return rotateSymmetricKey(secret, s.rotation, s.generateKey)
+}
+
+func (s *symmetricSecretHelper) generateKey() ([]byte, error) {
+	key := make([]byte, symmetricKeySize)
+	if _, err := s.rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
 }
 
 // ObserveActiveSecretAndUpdateParentFederationDomain implements SecretHelper.ObserveActiveSecretAndUpdateParentFederationDomain().
@@ -141,7 +181,7 @@ func (s *symmetricSecretHelper) ObserveActiveSecretAndUpdateParentFederationDoma
 	federationDomain *configv1alpha1.FederationDomain,
 	secret *corev1.Secret,
 ) *configv1alpha1.FederationDomain {
-	s.updateCacheFunc(federationDomain.Spec.Issuer, secret.Data[symmetricSecretDataKey])
+	s.updateCacheFunc(federationDomain.Spec.Issuer, symmetricKeys(secret))
 
 	switch s.secretUsage {
 	case SecretUsageTokenSigningKey:
@@ -158,7 +198,11 @@ func (s *symmetricSecretHelper) ObserveActiveSecretAndUpdateParentFederationDoma
 }
 
 func (s *symmetricSecretHelper) secretType() corev1.SecretType {
-	switch s.secretUsage {
+	return symmetricSecretType(s.secretUsage)
+}
+
+func symmetricSecretType(secretUsage SecretUsage) corev1.SecretType {
+	switch secretUsage {
 	case SecretUsageTokenSigningKey:
 		return FederationDomainTokenSigningKeyType
 	case SecretUsageStateSigningKey:
@@ -166,8 +210,47 @@ func (s *symmetricSecretHelper) secretType() corev1.SecretType {
 	case SecretUsageStateEncryptionKey:
 		return FederationDomainStateEncryptionKeyType
 	default:
-		panic(fmt.Sprintf("unknown secret usage enum value: %d", s.secretUsage))
+		panic(fmt.Sprintf("unknown secret usage enum value: %d", secretUsage))
+	}
+}
+
+// SuppliedSecretName returns the name of the Secret which the FederationDomain's spec names for the secretUsage,
+// or an empty string when the Secret should be generated.
+func SuppliedSecretName(federationDomain *configv1alpha1.FederationDomain, secretUsage SecretUsage) string {
+	secrets := federationDomain.Spec.Secrets
+	if secrets == nil {
+		return ""
+	}
+	switch secretUsage {
+	case SecretUsageTokenSigningKey:
+		return secrets.TokenSigningKey.Name
+	case SecretUsageStateSigningKey:
+		return secrets.StateSigningKey.Name
+	case SecretUsageStateEncryptionKey:
+		return secrets.StateEncryptionKey.Name
+	default:
+		panic(fmt.Sprintf("unknown secret usage enum value: %d", secretUsage))
+	}
+}
+
+// ValidateSuppliedSymmetricSecret returns an error when a Secret which was supplied by an administrator cannot be
+// used for the secretUsage. Supplied Secrets must not have owners, since they would be garbage collected along
+// with their owners.
+func ValidateSuppliedSymmetricSecret(secret *corev1.Secret, secretUsage SecretUsage) error {
+	if len(secret.OwnerReferences) != 0 {
+		return fmt.Errorf("secret %q must not have owner references", secret.Name)
+	}
+	return validateSymmetricSecretData(secret, secretUsage)
+}
+
+func validateSymmetricSecretData(secret *corev1.Secret, secretUsage SecretUsage) error {
+	if secretType := symmetricSecretType(secretUsage); secret.Type != secretType {
+		return fmt.Errorf("secret %q must have type %q", secret.Name, secretType)
+	}
+	if len(secret.Data[symmetricSecretDataKey]) != symmetricKeySize {
+		return fmt.Errorf("secret %q must contain a %d-byte key in its %q data", secret.Name, symmetricKeySize, symmetricSecretDataKey)
 	}
+	return nil
 }
 
 func (s *symmetricSecretHelper) Handles(obj metav1.Object) bool {
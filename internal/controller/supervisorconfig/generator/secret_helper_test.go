@@ -24,28 +24,32 @@ func TestSymmetricSecretHelper(t *testing.T) {
 		name                         string
 		secretUsage                  SecretUsage
 		wantSecretType               corev1.SecretType
+		wantConditionType            string
 		wantSetFederationDomainField func(*configv1alpha1.FederationDomain) string
 	}{
 		{
-			name:           "token signing key",
-			secretUsage:    SecretUsageTokenSigningKey,
-			wantSecretType: "secrets.pinniped.dev/federation-domain-token-signing-key",
+			name:              "token signing key",
+			secretUsage:       SecretUsageTokenSigningKey,
+			wantSecretType:    "secrets.pinniped.dev/federation-domain-token-signing-key",
+			wantConditionType: "TokenSigningKeyPresent",
 			wantSetFederationDomainField: func(federationDomain *configv1alpha1.FederationDomain) string {
 				return federationDomain.Status.Secrets.TokenSigningKey.Name
 			},
 		},
 		{
-			name:           "state signing key",
-			secretUsage:    SecretUsageStateSigningKey,
-			wantSecretType: "secrets.pinniped.dev/federation-domain-state-signing-key",
+			name:              "state signing key",
+			secretUsage:       SecretUsageStateSigningKey,
+			wantSecretType:    "secrets.pinniped.dev/federation-domain-state-signing-key",
+			wantConditionType: "StateSigningKeyPresent",
 			wantSetFederationDomainField: func(federationDomain *configv1alpha1.FederationDomain) string {
 				return federationDomain.Status.Secrets.StateSigningKey.Name
 			},
 		},
 		{
-			name:           "state encryption key",
-			secretUsage:    SecretUsageStateEncryptionKey,
-			wantSecretType: "secrets.pinniped.dev/federation-domain-state-encryption-key",
+			name:              "state encryption key",
+			secretUsage:       SecretUsageStateEncryptionKey,
+			wantSecretType:    "secrets.pinniped.dev/federation-domain-state-encryption-key",
+			wantConditionType: "StateEncryptionKeyPresent",
 			wantSetFederationDomainField: func(federationDomain *configv1alpha1.FederationDomain) string {
 				return federationDomain.Status.Secrets.StateEncryptionKey.Name
 			},
@@ -62,16 +66,17 @@ func TestSymmetricSecretHelper(t *testing.T) {
 			}
 			randSource := strings.NewReader(keyWith32Bytes)
 			var federationDomainIssuerValue string
-			var symmetricKeyValue []byte
+			var symmetricKeysValue [][]byte
 			h := NewSymmetricSecretHelper(
 				"some-name-prefix-",
 				labels,
 				randSource,
 				test.secretUsage,
-				func(federationDomainIssuer string, symmetricKey []byte) {
-					require.True(t, federationDomainIssuer == "" && symmetricKeyValue == nil, "expected notify func not to have been called yet")
+				KeyRotation{},
+				func(federationDomainIssuer string, symmetricKeys [][]byte) {
+					require.True(t, federationDomainIssuer == "" && symmetricKeysValue == nil, "expected notify func not to have been called yet")
 					federationDomainIssuerValue = federationDomainIssuer
-					symmetricKeyValue = symmetricKey
+					symmetricKeysValue = symmetricKeys
 				},
 			)
 
@@ -103,11 +108,12 @@ func TestSymmetricSecretHelper(t *testing.T) {
 			})
 
 			require.True(t, h.IsValid(parent, child))
+			require.Equal(t, test.wantConditionType, h.ConditionType())
 
 			h.ObserveActiveSecretAndUpdateParentFederationDomain(parent, child)
 			require.Equal(t, parent.Spec.Issuer, federationDomainIssuerValue)
 			require.Equal(t, child.Name, test.wantSetFederationDomainField(parent))
-			require.Equal(t, child.Data["key"], symmetricKeyValue)
+			require.Equal(t, [][]byte{child.Data["key"]}, symmetricKeysValue)
 
 			require.True(t, h.Handles(child))
 			wrongTypedChild := child.DeepCopy()
@@ -180,11 +186,81 @@ func TestSymmetricSecretHelperIsValid(t *testing.T) {
 				s.Type = FederationDomainTokenSigningKeyType
 			}, want: true,
 		},
+		{
+			name:        "supplied secret",
+			secretUsage: SecretUsageTokenSigningKey,
+			child: func(s *corev1.Secret) {
+				s.Name = "some-supplied-secret"
+				s.Type = FederationDomainTokenSigningKeyType
+				s.OwnerReferences = nil
+			},
+			parent: func(federationDomain *configv1alpha1.FederationDomain) {
+				federationDomain.Spec.Secrets = &configv1alpha1.FederationDomainSecretsSpec{
+					TokenSigningKey: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+				}
+			},
+			want: true,
+		},
+		{
+			name:        "supplied secret with an owner",
+			secretUsage: SecretUsageTokenSigningKey,
+			child: func(s *corev1.Secret) {
+				s.Name = "some-supplied-secret"
+				s.Type = FederationDomainTokenSigningKeyType
+			},
+			parent: func(federationDomain *configv1alpha1.FederationDomain) {
+				federationDomain.Spec.Secrets = &configv1alpha1.FederationDomainSecretsSpec{
+					TokenSigningKey: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+				}
+			},
+			want: false,
+		},
+		{
+			name:        "supplied secret with the wrong type",
+			secretUsage: SecretUsageTokenSigningKey,
+			child: func(s *corev1.Secret) {
+				s.Name = "some-supplied-secret"
+				s.Type = FederationDomainStateSigningKeyType
+				s.OwnerReferences = nil
+			},
+			parent: func(federationDomain *configv1alpha1.FederationDomain) {
+				federationDomain.Spec.Secrets = &configv1alpha1.FederationDomainSecretsSpec{
+					TokenSigningKey: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+				}
+			},
+			want: false,
+		},
+		{
+			name:        "generated secret when a secret is supplied for the same usage",
+			secretUsage: SecretUsageTokenSigningKey,
+			child: func(s *corev1.Secret) {
+				s.Type = FederationDomainTokenSigningKeyType
+			},
+			parent: func(federationDomain *configv1alpha1.FederationDomain) {
+				federationDomain.Spec.Secrets = &configv1alpha1.FederationDomainSecretsSpec{
+					TokenSigningKey: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+				}
+			},
+			want: false,
+		},
+		{
+			name:        "generated secret when a secret is supplied for another usage",
+			secretUsage: SecretUsageTokenSigningKey,
+			child: func(s *corev1.Secret) {
+				s.Type = FederationDomainTokenSigningKeyType
+			},
+			parent: func(federationDomain *configv1alpha1.FederationDomain) {
+				federationDomain.Spec.Secrets = &configv1alpha1.FederationDomainSecretsSpec{
+					StateSigningKey: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+				}
+			},
+			want: true,
+		},
 	}
 	for _, test := range tests {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
-			h := NewSymmetricSecretHelper("none of these args matter", nil, nil, test.secretUsage, nil)
+			h := NewSymmetricSecretHelper("none of these args matter", nil, nil, test.secretUsage, KeyRotation{}, nil)
 
 			parent := &configv1alpha1.FederationDomain{
 				ObjectMeta: metav1.ObjectMeta{
@@ -221,3 +297,58 @@ func TestSymmetricSecretHelperIsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSuppliedSymmetricSecret(t *testing.T) {
+	tests := []struct {
+		name        string
+		secretUsage SecretUsage
+		secret      func(*corev1.Secret)
+		wantErr     string
+	}{
+		{
+			name:        "happy path",
+			secretUsage: SecretUsageStateEncryptionKey,
+		},
+		{
+			name:        "owner references",
+			secretUsage: SecretUsageStateEncryptionKey,
+			secret: func(s *corev1.Secret) {
+				s.OwnerReferences = []metav1.OwnerReference{{Kind: "SomeKind", Name: "some-owner"}}
+			},
+			wantErr: `secret "some-secret" must not have owner references`,
+		},
+		{
+			name:        "wrong type",
+			secretUsage: SecretUsageStateSigningKey,
+			wantErr:     `secret "some-secret" must have type "secrets.pinniped.dev/federation-domain-state-signing-key"`,
+		},
+		{
+			name:        "data key is too long",
+			secretUsage: SecretUsageStateEncryptionKey,
+			secret: func(s *corev1.Secret) {
+				s.Data["key"] = []byte(keyWith32Bytes + "more")
+			},
+			wantErr: `secret "some-secret" must contain a 32-byte key in its "key" data`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-secret", Namespace: "some-namespace"},
+				Type:       FederationDomainStateEncryptionKeyType,
+				Data:       map[string][]byte{"key": []byte(keyWith32Bytes)},
+			}
+			if test.secret != nil {
+				test.secret(secret)
+			}
+
+			err := ValidateSuppliedSymmetricSecret(secret, test.secretUsage)
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -31,16 +31,20 @@ type supervisorSecretsController struct {
 	labels         map[string]string
 	kubeClient     kubernetes.Interface
 	secretInformer corev1informers.SecretInformer
-	setCacheFunc   func(secret []byte)
+	rotation       KeyRotation
+	setCacheFunc   func(secrets [][]byte)
 }
 
 // NewSupervisorSecretsController instantiates a new controllerlib.Controller which will ensure existence of a generated secret.
+// The key in the secret is rotated according to the rotation param, and setCacheFunc is called with the keys in the
+// secret, newest first.
 func NewSupervisorSecretsController(
 	owner *appsv1.Deployment,
 	labels map[string]string,
 	kubeClient kubernetes.Interface,
 	secretInformer corev1informers.SecretInformer,
-	setCacheFunc func(secret []byte),
+	rotation KeyRotation,
+	setCacheFunc func(secrets [][]byte),
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 	initialEventFunc pinnipedcontroller.WithInitialEventOptionFunc,
 ) controllerlib.Controller {
@@ -48,6 +52,7 @@ func NewSupervisorSecretsController(
 		labels:         labels,
 		kubeClient:     kubeClient,
 		secretInformer: secretInformer,
+		rotation:       rotation,
 		setCacheFunc:   setCacheFunc,
 	}
 	return controllerlib.New(
@@ -84,7 +89,24 @@ func (c *supervisorSecretsController) Sync(ctx controllerlib.Context) error {
 	secretNeedsUpdate := isNotFound || !isValid(secret, c.labels)
 	if !secretNeedsUpdate {
 		plog.Debug("secret is up to date", "secret", klog.KObj(secret))
-		c.setCacheFunc(secret.Data[symmetricSecretDataKey])
+
+		rotatedSecret, requeueAfter, err := rotateSymmetricKey(secret, c.rotation, generateKey)
+		if err != nil {
+			return fmt.Errorf("failed to rotate secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		if rotatedSecret != nil {
+			// The resource version from the cache is kept, so this fails and is retried if the secret was changed since.
+			if _, err := c.kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx.Context, rotatedSecret, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update rotated secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+			plog.Debug("rotated secret", "secret", klog.KObj(secret))
+			secret = rotatedSecret
+		}
+		if requeueAfter > 0 {
+			ctx.Queue.AddAfter(ctx.Key, requeueAfter)
+		}
+
+		c.setCacheFunc(symmetricKeys(secret))
 		return nil
 	}
 
@@ -102,7 +124,7 @@ func (c *supervisorSecretsController) Sync(ctx controllerlib.Context) error {
 		return fmt.Errorf("failed to create/update secret %s/%s: %w", newSecret.Namespace, newSecret.Name, err)
 	}
 
-	c.setCacheFunc(newSecret.Data[symmetricSecretDataKey])
+	c.setCacheFunc(symmetricKeys(newSecret))
 
 	return nil
 }
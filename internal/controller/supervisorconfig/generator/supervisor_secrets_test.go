@@ -8,6 +8,7 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
@@ -16,6 +17,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
@@ -114,6 +116,7 @@ func TestSupervisorSecretsControllerFilterSecret(t *testing.T) {
 				labels,
 				nil, // kubeClient, not needed
 				secretInformer,
+				KeyRotation{},
 				nil, // setCache, not needed
 				withInformer.WithInformer,
 				testutil.NewObservableWithInitialEventOption().WithInitialEvent,
@@ -140,6 +143,7 @@ func TestSupervisorSecretsControllerInitialEvent(t *testing.T) {
 		nil,
 		nil, // kubeClient, not needed
 		secretInformer,
+		KeyRotation{},
 		nil, // setCache, not needed
 		testutil.NewObservableWithInformerOption().WithInformer,
 		initialEventOption.WithInitialEvent,
@@ -194,16 +198,32 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 	// Add an extra label to make sure we don't overwrite existing labels on a Secret.
 	generatedSecret.Labels["extra-label-key"] = "extra-label-value"
 
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	rotation := KeyRotation{
+		Interval:    30 * 24 * time.Hour,
+		GracePeriod: 7 * 24 * time.Hour,
+		Clock:       clock.NewFakeClock(now),
+	}
+
+	rotatedSecret := generatedSecret.DeepCopy()
+	rotatedSecret.Data = map[string][]byte{
+		"key":           otherGeneratedSymmetricKey,
+		"previousKey":   generatedSymmetricKey,
+		"keyActiveFrom": []byte(now.Format(time.RFC3339)),
+	}
+
 	once := sync.Once{}
 
 	tests := []struct {
-		name               string
-		storedSecret       func(**corev1.Secret)
-		generateKey        func() ([]byte, error)
-		apiClient          func(*testing.T, *kubernetesfake.Clientset)
-		wantError          string
-		wantActions        []kubetesting.Action
-		wantCallbackSecret []byte
+		name                string
+		storedSecret        func(**corev1.Secret)
+		generateKey         func() ([]byte, error)
+		apiClient           func(*testing.T, *kubernetesfake.Clientset)
+		wantError           string
+		rotation            KeyRotation
+		wantActions         []kubetesting.Action
+		wantCallbackSecrets [][]byte
+		wantRequeueAfter    time.Duration
 	}{
 		{
 			name: "when the secrets does not exist, it gets generated",
@@ -213,11 +233,65 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 			wantActions: []kubetesting.Action{
 				kubetesting.NewCreateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
+		},
+		{
+			name:                "when a valid secret exists, nothing happens",
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
+		},
+		{
+			name:     "when a valid secret exists which is not due for rotation, it is checked on again later",
+			rotation: rotation,
+			storedSecret: func(secret **corev1.Secret) {
+				(*secret).Data["keyActiveFrom"] = []byte(now.Add(-24 * time.Hour).Format(time.RFC3339))
+			},
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
+			wantRequeueAfter:    29 * 24 * time.Hour,
+		},
+		{
+			name:     "when a valid secret exists which is due for rotation, its key is rotated",
+			rotation: rotation,
+			storedSecret: func(secret **corev1.Secret) {
+				(*secret).Data["keyActiveFrom"] = []byte(now.Add(-30 * 24 * time.Hour).Format(time.RFC3339))
+			},
+			generateKey: func() ([]byte, error) {
+				return otherGeneratedSymmetricKey, nil
+			},
+			wantActions: []kubetesting.Action{
+				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, rotatedSecret),
+			},
+			wantCallbackSecrets: [][]byte{otherGeneratedSymmetricKey, generatedSymmetricKey},
+			wantRequeueAfter:    7 * 24 * time.Hour,
 		},
 		{
-			name:               "when a valid secret exists, nothing happens",
-			wantCallbackSecret: generatedSymmetricKey,
+			name:     "an error is returned when rotating the key fails",
+			rotation: rotation,
+			storedSecret: func(secret **corev1.Secret) {
+				(*secret).Data["keyActiveFrom"] = []byte(now.Add(-30 * 24 * time.Hour).Format(time.RFC3339))
+			},
+			generateKey: func() ([]byte, error) {
+				return nil, errors.New("some generate error")
+			},
+			wantError: "failed to rotate secret some-namespace/some-name-abc123: some generate error",
+		},
+		{
+			name:     "an error is returned when updating the rotated secret fails",
+			rotation: rotation,
+			storedSecret: func(secret **corev1.Secret) {
+				(*secret).Data["keyActiveFrom"] = []byte(now.Add(-30 * 24 * time.Hour).Format(time.RFC3339))
+			},
+			generateKey: func() ([]byte, error) {
+				return otherGeneratedSymmetricKey, nil
+			},
+			apiClient: func(t *testing.T, client *kubernetesfake.Clientset) {
+				client.PrependReactor("update", "secrets", func(action kubetesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some update error")
+				})
+			},
+			wantActions: []kubetesting.Action{
+				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, rotatedSecret),
+			},
+			wantError: "failed to update rotated secret some-namespace/some-name-abc123: some update error",
 		},
 		{
 			name: "secret gets updated when the type is wrong",
@@ -228,7 +302,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "secret gets updated when the key data does not exist",
@@ -239,7 +313,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "secret gets updated when the key data is too short",
@@ -250,7 +324,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "an error is returned when creating fails",
@@ -318,7 +392,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "upon updating we discover that a valid secret exists",
@@ -333,7 +407,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 			wantActions: []kubetesting.Action{
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 			},
-			wantCallbackSecret: otherGeneratedSymmetricKey,
+			wantCallbackSecrets: [][]byte{otherGeneratedSymmetricKey},
 		},
 		{
 			name: "upon updating we discover that a secret with missing labels exists",
@@ -344,7 +418,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "upon updating we discover that a secret with incorrect labels exists",
@@ -355,7 +429,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewUpdateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "upon updating we discover that the secret has been deleted",
@@ -374,7 +448,7 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 				kubetesting.NewGetAction(secretsGVR, generatedSecretNamespace, generatedSecretName),
 				kubetesting.NewCreateAction(secretsGVR, generatedSecretNamespace, generatedSecret),
 			},
-			wantCallbackSecret: generatedSymmetricKey,
+			wantCallbackSecrets: [][]byte{generatedSymmetricKey},
 		},
 		{
 			name: "upon updating we discover that the secret has been deleted and our create fails",
@@ -440,15 +514,16 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 			informers := kubeinformers.NewSharedInformerFactory(informerClient, 0)
 			secrets := informers.Core().V1().Secrets()
 
-			var callbackSecret []byte
+			var callbackSecrets [][]byte
 			c := NewSupervisorSecretsController(
 				owner,
 				labels,
 				apiClient,
 				secrets,
-				func(secret []byte) {
-					require.Nil(t, callbackSecret, "callback was called twice")
-					callbackSecret = secret
+				test.rotation,
+				func(secrets [][]byte) {
+					require.Nil(t, callbackSecrets, "callback was called twice")
+					callbackSecrets = secrets
 				},
 				testutil.NewObservableWithInformerOption().WithInformer,
 				testutil.NewObservableWithInitialEventOption().WithInitialEvent,
@@ -458,12 +533,14 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 			informers.Start(ctx.Done())
 			controllerlib.TestRunSynchronously(t, c)
 
+			queue := &rotationTestQueue{}
 			err := controllerlib.TestSync(t, c, controllerlib.Context{
 				Context: ctx,
 				Key: controllerlib.Key{
 					Namespace: generatedSecretNamespace,
 					Name:      generatedSecretName,
 				},
+				Queue: queue,
 			})
 			if test.wantError != "" {
 				require.EqualError(t, err, test.wantError)
@@ -476,7 +553,8 @@ func TestSupervisorSecretsControllerSync(t *testing.T) {
 			}
 			require.Equal(t, test.wantActions, apiClient.Actions())
 
-			require.Equal(t, test.wantCallbackSecret, callbackSecret)
+			require.Equal(t, test.wantCallbackSecrets, callbackSecrets)
+			require.Equal(t, test.wantRequeueAfter, queue.duration)
 		})
 	}
 }
@@ -9,16 +9,19 @@ import (
 
 	"gopkg.in/square/go-jose.v2"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/errors"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 
 	"go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/jwks"
 	"go.pinniped.dev/internal/plog"
 )
 
 type jwksObserverController struct {
 	issuerToJWKSSetter       IssuerToJWKSMapSetter
+	signerBackends           jwks.SignerBackends
 	federationDomainInformer v1alpha1.FederationDomainInformer
 	secretInformer           corev1informers.SecretInformer
 }
@@ -27,16 +30,19 @@ type IssuerToJWKSMapSetter interface {
 	SetIssuerToJWKSMap(
 		issuerToJWKSMap map[string]*jose.JSONWebKeySet,
 		issuerToActiveJWKMap map[string]*jose.JSONWebKey,
+		issuerToSignerMap map[string]jwks.Signer,
 	)
 }
 
 // Returns a controller which watches all of the FederationDomains and their corresponding Secrets
-// and fills an in-memory cache of the JWKS info for each currently configured issuer.
+// and fills an in-memory cache of the JWKS info for each currently configured issuer. The JWKS info
+// of a FederationDomain which has a signer comes from its backend in signerBackends instead.
 // This controller assumes that the informers passed to it are already scoped down to the
 // appropriate namespace. It also assumes that the IssuerToJWKSMapSetter passed to it has an
 // underlying implementation which is thread-safe.
 func NewJWKSObserverController(
 	issuerToJWKSSetter IssuerToJWKSMapSetter,
+	signerBackends jwks.SignerBackends,
 	secretInformer corev1informers.SecretInformer,
 	federationDomainInformer v1alpha1.FederationDomainInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
@@ -46,6 +52,7 @@ func NewJWKSObserverController(
 			Name: "jwks-observer-controller",
 			Syncer: &jwksObserverController{
 				issuerToJWKSSetter:       issuerToJWKSSetter,
+				signerBackends:           signerBackends,
 				federationDomainInformer: federationDomainInformer,
 				secretInformer:           secretInformer,
 			},
@@ -74,8 +81,27 @@ func (c *jwksObserverController) Sync(ctx controllerlib.Context) error {
 	// can cause the map to need to be updated.
 	issuerToJWKSMap := map[string]*jose.JSONWebKeySet{}
 	issuerToActiveJWKMap := map[string]*jose.JSONWebKey{}
+	issuerToSignerMap := map[string]jwks.Signer{}
+	var errs []error
 
 	for _, provider := range allProviders {
+		if signerSpec := provider.Spec.Signer; signerSpec != nil {
+			signerBackend, ok := c.signerBackends[signerSpec.Name]
+			if !ok {
+				// The FederationDomain watcher reports this in the FederationDomain's status.
+				plog.Debug("jwksObserverController Sync found a FederationDomain with an unknown signer", "namespace", ns, "federationDomain", provider.Name, "signer", signerSpec.Name)
+				continue
+			}
+			signer, jwksFromSigner, err := signerBackend.Signer(ctx.Context, signerSpec.KeyName)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to get key %q from signer %q for FederationDomain %s/%s: %w", signerSpec.KeyName, signerSpec.Name, ns, provider.Name, err))
+				continue
+			}
+			issuerToJWKSMap[provider.Spec.Issuer] = jwksFromSigner
+			issuerToSignerMap[provider.Spec.Issuer] = signer
+			continue
+		}
+
 		secretRef := provider.Status.Secrets.JWKS
 		jwksSecret, err := c.secretInformer.Lister().Secrets(ns).Get(secretRef.Name)
 		if err != nil {
@@ -107,8 +133,11 @@ func (c *jwksObserverController) Sync(ctx controllerlib.Context) error {
 		len(issuerToJWKSMap),
 		"issuerActiveJWKCount",
 		len(issuerToActiveJWKMap),
+		"issuerSignerCount",
+		len(issuerToSignerMap),
 	)
-	c.issuerToJWKSSetter.SetIssuerToJWKSMap(issuerToJWKSMap, issuerToActiveJWKMap)
+	c.issuerToJWKSSetter.SetIssuerToJWKSMap(issuerToJWKSMap, issuerToActiveJWKMap, issuerToSignerMap)
 
-	return nil
+	// Signers may be temporarily unavailable, so try again later.
+	return errors.NewAggregate(errs)
 }
@@ -5,7 +5,12 @@ package supervisorconfig
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/sclevine/spec"
@@ -14,6 +19,7 @@ import (
 	"gopkg.in/square/go-jose.v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 
@@ -21,6 +27,7 @@ import (
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/jwks"
 	"go.pinniped.dev/internal/testutil"
 )
 
@@ -39,6 +46,7 @@ func TestJWKSObserverControllerInformerFilters(t *testing.T) {
 			secretsInformer := kubeinformers.NewSharedInformerFactory(nil, 0).Core().V1().Secrets()
 			federationDomainInformer := pinnipedinformers.NewSharedInformerFactory(nil, 0).Config().V1alpha1().FederationDomains()
 			_ = NewJWKSObserverController(
+				nil,
 				nil,
 				secretsInformer,
 				federationDomainInformer,
@@ -107,15 +115,35 @@ type fakeIssuerToJWKSMapSetter struct {
 	setIssuerToJWKSMapWasCalled  bool
 	issuerToJWKSMapReceived      map[string]*jose.JSONWebKeySet
 	issuerToActiveJWKMapReceived map[string]*jose.JSONWebKey
+	issuerToSignerMapReceived    map[string]jwks.Signer
 }
 
 func (f *fakeIssuerToJWKSMapSetter) SetIssuerToJWKSMap(
 	issuerToJWKSMap map[string]*jose.JSONWebKeySet,
 	issuerToActiveJWKMap map[string]*jose.JSONWebKey,
+	issuerToSignerMap map[string]jwks.Signer,
 ) {
 	f.setIssuerToJWKSMapWasCalled = true
 	f.issuerToJWKSMapReceived = issuerToJWKSMap
 	f.issuerToActiveJWKMapReceived = issuerToActiveJWKMap
+	f.issuerToSignerMapReceived = issuerToSignerMap
+}
+
+type fakeSignerBackend struct {
+	keyName string
+	signer  jwks.Signer
+	keySet  *jose.JSONWebKeySet
+	err     error
+}
+
+func (f *fakeSignerBackend) Signer(_ context.Context, keyName string) (jwks.Signer, *jose.JSONWebKeySet, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	if keyName != f.keyName {
+		return nil, nil, fmt.Errorf("no key named %s", keyName)
+	}
+	return f.signer, f.keySet, nil
 }
 
 func TestJWKSObserverControllerSync(t *testing.T) {
@@ -133,6 +161,7 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 			cancelContextCancelFunc context.CancelFunc
 			syncContext             *controllerlib.Context
 			issuerToJWKSSetter      *fakeIssuerToJWKSMapSetter
+			signerBackends          jwks.SignerBackends
 		)
 
 		// Defer starting the informers until the last possible moment so that the
@@ -141,6 +170,7 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 			// Set this at the last second to allow for injection of server override.
 			subject = NewJWKSObserverController(
 				issuerToJWKSSetter,
+				signerBackends,
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().FederationDomains(),
 				controllerlib.WithInformer,
@@ -172,6 +202,7 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 			pinnipedInformerClient = pinnipedfake.NewSimpleClientset()
 			pinnipedInformers = pinnipedinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
 			issuerToJWKSSetter = &fakeIssuerToJWKSMapSetter{}
+			signerBackends = nil
 
 			unrelatedSecret := &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
@@ -376,5 +407,75 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 				requireJWKJSON(expectedJWK2, issuerToJWKSSetter.issuerToActiveJWKMapReceived["https://issuer-with-good-secret2.com"])
 			})
 		})
+
+		when("there are FederationDomains which use signers", func() {
+			var (
+				goodSigner jwks.Signer
+				goodKeySet *jose.JSONWebKeySet
+			)
+
+			it.Before(func() {
+				key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				r.NoError(err)
+				goodSigner, err = jwks.NewJWKSigner(&jose.JSONWebKey{Key: key, KeyID: "some-key-id"})
+				r.NoError(err)
+				goodKeySet = &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "some-key-id"}}}
+
+				signerBackends = jwks.SignerBackends{
+					"good-signer":   &fakeSignerBackend{keyName: "some-key", signer: goodSigner, keySet: goodKeySet},
+					"broken-signer": &fakeSignerBackend{err: errors.New("some signer error")},
+				}
+
+				newFederationDomain := func(name string, signer *v1alpha1.FederationDomainSignerSpec) *v1alpha1.FederationDomain {
+					return &v1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: installedInNamespace},
+						Spec: v1alpha1.FederationDomainSpec{
+							Issuer: "https://" + name + ".com",
+							Signer: signer,
+						},
+						Status: v1alpha1.FederationDomainStatus{
+							Secrets: v1alpha1.FederationDomainSecrets{
+								// The secret should not be used.
+								JWKS: corev1.LocalObjectReference{Name: "good-jwks-secret-name"},
+							},
+						},
+					}
+				}
+				r.NoError(pinnipedInformerClient.Tracker().Add(newFederationDomain("good-signer-issuer", &v1alpha1.FederationDomainSignerSpec{Name: "good-signer", KeyName: "some-key"})))
+				r.NoError(pinnipedInformerClient.Tracker().Add(newFederationDomain("missing-key-issuer", &v1alpha1.FederationDomainSignerSpec{Name: "good-signer", KeyName: "some-other-key"})))
+				r.NoError(pinnipedInformerClient.Tracker().Add(newFederationDomain("broken-signer-issuer", &v1alpha1.FederationDomainSignerSpec{Name: "broken-signer", KeyName: "some-key"})))
+				r.NoError(pinnipedInformerClient.Tracker().Add(newFederationDomain("unknown-signer-issuer", &v1alpha1.FederationDomainSignerSpec{Name: "unknown-signer", KeyName: "some-key"})))
+
+				jwk := string(readJWKJSON(t, "testdata/public-jwk.json"))
+				r.NoError(kubeInformerClient.Tracker().Add(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "good-jwks-secret-name", Namespace: installedInNamespace},
+					Data: map[string][]byte{
+						"activeJWK": []byte(jwk),
+						"jwks":      []byte(`{"keys": [` + jwk + `]}`),
+					},
+				}))
+			})
+
+			it("updates the issuerToJWKSSetter's maps to include the signers which are available, and returns an error for the others", func() {
+				startInformersAndController()
+				err := controllerlib.TestSync(t, subject, *syncContext)
+				// The FederationDomains are listed in no particular order, so neither are the errors.
+				var aggregate utilerrors.Aggregate
+				r.True(errors.As(err, &aggregate))
+				errorMessages := make([]string, 0, len(aggregate.Errors()))
+				for _, err := range aggregate.Errors() {
+					errorMessages = append(errorMessages, err.Error())
+				}
+				r.ElementsMatch([]string{
+					`failed to get key "some-key" from signer "broken-signer" for FederationDomain some-namespace/broken-signer-issuer: some signer error`,
+					`failed to get key "some-other-key" from signer "good-signer" for FederationDomain some-namespace/missing-key-issuer: no key named some-other-key`,
+				}, errorMessages)
+
+				r.True(issuerToJWKSSetter.setIssuerToJWKSMapWasCalled)
+				r.Equal(map[string]*jose.JSONWebKeySet{"https://good-signer-issuer.com": goodKeySet}, issuerToJWKSSetter.issuerToJWKSMapReceived)
+				r.Empty(issuerToJWKSSetter.issuerToActiveJWKMapReceived)
+				r.Equal(map[string]jwks.Signer{"https://good-signer-issuer.com": goodSigner}, issuerToJWKSSetter.issuerToSignerMapReceived)
+			})
+		})
 	}, spec.Parallel(), spec.Report(report.Terminal{}))
 }
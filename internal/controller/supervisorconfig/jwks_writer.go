@@ -5,18 +5,26 @@ package supervisorconfig
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"sort"
+	"time"
 
 	"gopkg.in/square/go-jose.v2"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/errors"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
@@ -26,8 +34,11 @@ import (
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
 	"go.pinniped.dev/internal/controller/supervisorconfig/generator"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -41,12 +52,21 @@ const (
 	//
 	// Note! The value for this key will contain only public key material!
 	jwksKey = "jwks"
+	// rotatingJWKsKey points to all of the keys which are managed by the FederationDomain's signing key rotation
+	// policy, including the next key and any retired keys, along with the time at which each became active.
+	// It is only present when the FederationDomain has a signing key rotation policy.
+	//
+	// Note! The value for this key will contain private key material!
+	rotatingJWKsKey = "rotatingJWKs"
 
 	jwksSecretTypeValue corev1.SecretType = "secrets.pinniped.dev/federation-domain-jwks"
 )
 
 const (
 	federationDomainKind = "FederationDomain"
+
+	// minimumRotationRequeueInterval limits how soon the controller checks on the signing keys again.
+	minimumRotationRequeueInterval = time.Second
 )
 
 // generateKey is stubbed out for the purpose of testing. The default behavior is to generate an EC key.
@@ -57,6 +77,13 @@ func generateECKey(r io.Reader) (interface{}, error) {
 	return ecdsa.GenerateKey(elliptic.P256(), r)
 }
 
+// rotatingJWK is a signing key which is managed by a signing key rotation policy. Each key is the active signing
+// key from its ActiveFrom time until the ActiveFrom time of the next key.
+type rotatingJWK struct {
+	JWK        jose.JSONWebKey `json:"jwk"`
+	ActiveFrom time.Time       `json:"activeFrom"`
+}
+
 // jwkController holds the fields necessary for the JWKS controller to communicate with FederationDomains and
 // secrets, both via a cache and via the API.
 type jwksWriterController struct {
@@ -65,6 +92,7 @@ type jwksWriterController struct {
 	kubeClient               kubernetes.Interface
 	federationDomainInformer configinformers.FederationDomainInformer
 	secretInformer           corev1informers.SecretInformer
+	clock                    clock.Clock
 }
 
 // NewJWKSWriterController returns a controllerlib.Controller that ensures a FederationDomain has a corresponding
@@ -75,28 +103,29 @@ func NewJWKSWriterController(
 	pinnipedClient pinnipedclientset.Interface,
 	secretInformer corev1informers.SecretInformer,
 	federationDomainInformer configinformers.FederationDomainInformer,
+	clock clock.Clock,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
-	isSecretToSync := func(obj metav1.Object) bool {
-		return generator.IsFederationDomainSecretOfType(obj, jwksSecretTypeValue)
+	c := &jwksWriterController{
+		jwksSecretLabels:         jwksSecretLabels,
+		kubeClient:               kubeClient,
+		pinnipedClient:           pinnipedClient,
+		secretInformer:           secretInformer,
+		federationDomainInformer: federationDomainInformer,
+		clock:                    clock,
 	}
-
 	return controllerlib.New(
 		controllerlib.Config{
-			Name: "JWKSController",
-			Syncer: &jwksWriterController{
-				jwksSecretLabels:         jwksSecretLabels,
-				kubeClient:               kubeClient,
-				pinnipedClient:           pinnipedClient,
-				secretInformer:           secretInformer,
-				federationDomainInformer: federationDomainInformer,
-			},
+			Name:   "JWKSController",
+			Syncer: c,
 		},
 		// We want to be notified when a FederationDomain's secret gets updated or deleted. When this happens, we
 		// should get notified via the corresponding FederationDomain key.
 		withInformer(
 			secretInformer,
-			pinnipedcontroller.SimpleFilter(isSecretToSync, pinnipedcontroller.SecretIsControlledByParentFunc(isSecretToSync)),
+			pinnipedcontroller.SimpleFilter(func(obj metav1.Object) bool {
+				return c.federationDomainKeyForSecret(obj) != controllerlib.Key{}
+			}, c.federationDomainKeyForSecret),
 			controllerlib.InformerOption{},
 		),
 		// We want to be notified when anything happens to an FederationDomain.
@@ -132,6 +161,44 @@ func (c *jwksWriterController) Sync(ctx controllerlib.Context) error {
 		return nil
 	}
 
+	if federationDomain.Spec.Signer != nil {
+		// The signing keys of this FederationDomain are kept by its signer, so it does not need a Secret.
+		plog.Debug(
+			"FederationDomain uses a signer",
+			"federationdomain",
+			klog.KRef(ctx.Key.Namespace, ctx.Key.Name),
+		)
+		return c.ensureFederationDomainStatus(ctx, federationDomain, federationDomain.Status.Secrets.JWKS.Name, &configv1alpha1.Condition{
+			Type:    typeJWKSPresent,
+			Status:  configv1alpha1.ConditionTrue,
+			Reason:  reasonSignerConfigured,
+			Message: fmt.Sprintf("the signing keys are kept by signer %q", federationDomain.Spec.Signer.Name),
+		})
+	}
+
+	if suppliedSecretName := suppliedJWKSSecretName(federationDomain); suppliedSecretName != "" {
+		return c.syncSuppliedSecret(ctx, federationDomain, suppliedSecretName)
+	}
+
+	if rotation := federationDomain.Spec.SigningKeyRotation; rotation != nil {
+		lifetimes, err := tokenLifetimesSettings(federationDomain.Spec.TokenLifetimes)
+		if err == nil {
+			err = validateSigningKeyRotation(rotation, lifetimes)
+		}
+		if err != nil {
+			// The FederationDomain will not be served, so there is no point in creating keys for it.
+			plog.Debug(
+				"ignoring FederationDomain with invalid signing key rotation",
+				"federationdomain",
+				klog.KRef(ctx.Key.Namespace, ctx.Key.Name),
+				"err",
+				err,
+			)
+			return nil
+		}
+		return c.syncRotatingSecret(ctx, federationDomain, rotation)
+	}
+
 	secretNeedsUpdate, err := c.secretNeedsUpdate(federationDomain)
 	if err != nil {
 		return fmt.Errorf("cannot determine secret status: %w", err)
@@ -143,7 +210,7 @@ func (c *jwksWriterController) Sync(ctx controllerlib.Context) error {
 			"federationdomain",
 			klog.KRef(ctx.Key.Namespace, ctx.Key.Name),
 		)
-		return nil
+		return c.ensureFederationDomainStatus(ctx, federationDomain, federationDomain.Status.Secrets.JWKS.Name, jwksPresentCondition(federationDomain.Status.Secrets.JWKS.Name))
 	}
 
 	// If the FederationDomain does not have a secret associated with it, that secret does not exist, or the secret
@@ -158,10 +225,30 @@ func (c *jwksWriterController) Sync(ctx controllerlib.Context) error {
 	}
 	plog.Debug("created/updated secret", "secret", klog.KObj(secret))
 
-	// Ensure that the FederationDomain points to the secret.
+	return c.ensureFederationDomainStatus(ctx, federationDomain, secret.Name, jwksPresentCondition(secret.Name))
+}
+
+// ensureFederationDomainStatus makes sure that the FederationDomain points to the secret and has the JWKSPresent
+// condition. The FederationDomain is not updated when its status is already up to date.
+func (c *jwksWriterController) ensureFederationDomainStatus(
+	ctx controllerlib.Context,
+	federationDomain *configv1alpha1.FederationDomain,
+	secretName string,
+	condition *configv1alpha1.Condition,
+) error {
 	newFederationDomain := federationDomain.DeepCopy()
-	newFederationDomain.Status.Secrets.JWKS.Name = secret.Name
-	if err := c.updateFederationDomainStatus(ctx.Context, newFederationDomain); err != nil {
+	newFederationDomain.Status.Secrets.JWKS.Name = secretName
+	conditionChanged := conditionsutil.MergeConfigConditions(
+		[]*configv1alpha1.Condition{condition},
+		federationDomain.Generation,
+		&newFederationDomain.Status.Conditions,
+		metav1.NewTime(c.clock.Now()),
+	)
+	if secretName == federationDomain.Status.Secrets.JWKS.Name && !conditionChanged {
+		return nil
+	}
+
+	if err := c.updateFederationDomainStatus(ctx.Context, newFederationDomain, condition); err != nil {
 		return fmt.Errorf("cannot update FederationDomain: %w", err)
 	}
 	plog.Debug("updated FederationDomain", "federationdomain", klog.KObj(newFederationDomain))
@@ -169,6 +256,125 @@ func (c *jwksWriterController) Sync(ctx controllerlib.Context) error {
 	return nil
 }
 
+func jwksPresentCondition(secretName string) *configv1alpha1.Condition {
+	return trueCondition(typeJWKSPresent, fmt.Sprintf("the JWKS is stored in Secret %q", secretName))
+}
+
+// syncSuppliedSecret points the FederationDomain at the JWKS Secret which was supplied by its administrator. That
+// Secret is never created or updated. When it is missing or invalid, nothing is done, since the FederationDomain
+// watcher reports the problem in the FederationDomain's status.
+func (c *jwksWriterController) syncSuppliedSecret(
+	ctx controllerlib.Context,
+	federationDomain *configv1alpha1.FederationDomain,
+	secretName string,
+) error {
+	secret, err := c.secretInformer.Lister().Secrets(federationDomain.Namespace).Get(secretName)
+	notFound := k8serrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return fmt.Errorf("cannot get secret: %w", err)
+	}
+	if notFound || validateSuppliedJWKSSecret(secret) != nil {
+		plog.Debug(
+			"supplied secret is missing or invalid",
+			"federationdomain",
+			klog.KObj(federationDomain),
+			"secret",
+			klog.KRef(federationDomain.Namespace, secretName),
+		)
+		return c.ensureFederationDomainStatus(ctx, federationDomain, federationDomain.Status.Secrets.JWKS.Name, falseCondition(
+			typeJWKSPresent,
+			reasonSuppliedSecretInvalid,
+			fmt.Sprintf("the supplied Secret %q is missing or invalid", secretName),
+		))
+	}
+
+	return c.ensureFederationDomainStatus(ctx, federationDomain, secret.Name, jwksPresentCondition(secret.Name))
+}
+
+// federationDomainKeyForSecret returns the key of the FederationDomain which either controls the JWKS Secret or
+// names it as a supplied Secret, or an empty key when there is no such FederationDomain.
+func (c *jwksWriterController) federationDomainKeyForSecret(obj metav1.Object) controllerlib.Key {
+	isSecretToSync := func(obj metav1.Object) bool {
+		return generator.IsFederationDomainSecretOfType(obj, jwksSecretTypeValue)
+	}
+	if isSecretToSync(obj) {
+		return pinnipedcontroller.SecretIsControlledByParentFunc(isSecretToSync)(obj)
+	}
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return controllerlib.Key{}
+	}
+
+	federationDomains, err := c.federationDomainInformer.Lister().FederationDomains(obj.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return controllerlib.Key{}
+	}
+	for _, federationDomain := range federationDomains {
+		if suppliedSecretName := suppliedJWKSSecretName(federationDomain); suppliedSecretName != "" && suppliedSecretName == obj.GetName() {
+			return controllerlib.Key{Namespace: federationDomain.Namespace, Name: federationDomain.Name}
+		}
+	}
+	return controllerlib.Key{}
+}
+
+// syncRotatingSecret maintains the keys of a FederationDomain which has a signing key rotation policy, and requeues
+// itself for the next time that the keys need to change.
+func (c *jwksWriterController) syncRotatingSecret(
+	ctx controllerlib.Context,
+	federationDomain *configv1alpha1.FederationDomain,
+	rotation *configv1alpha1.FederationDomainSigningKeyRotationSpec,
+) error {
+	now := c.clock.Now().UTC()
+	secretName := federationDomain.Name + "-jwks"
+
+	oldSecret, err := c.secretInformer.Lister().Secrets(federationDomain.Namespace).Get(secretName)
+	notFound := k8serrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return fmt.Errorf("cannot get secret: %w", err)
+	}
+
+	var keys []rotatingJWK
+	if !notFound {
+		keys = rotatingJWKsFromSecret(oldSecret, now)
+	}
+	keys, nextChange, err := rotateJWKs(keys, rotation, now)
+	if err != nil {
+		return fmt.Errorf("cannot rotate keys: %w", err)
+	}
+	data, err := rotatingJWKsSecretData(keys, now)
+	if err != nil {
+		return fmt.Errorf("cannot generate secret: %w", err)
+	}
+
+	secret := c.newJWKSSecret(federationDomain, data)
+	secretClient := c.kubeClient.CoreV1().Secrets(federationDomain.Namespace)
+	switch {
+	case notFound:
+		if _, err := secretClient.Create(ctx.Context, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("cannot create or update secret: cannot create secret: %w", err)
+		}
+		plog.Debug("created secret", "secret", klog.KObj(secret))
+	case oldSecret.Type != jwksSecretTypeValue || !reflect.DeepEqual(oldSecret.Data, data):
+		// The resource version from the cache is kept, so this fails and is retried if the secret was changed since.
+		secret = oldSecret.DeepCopy()
+		secret.Data = data
+		secret.Type = jwksSecretTypeValue
+		if _, err := secretClient.Update(ctx.Context, secret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("cannot create or update secret: %w", err)
+		}
+		plog.Debug("updated secret", "secret", klog.KObj(secret))
+	default:
+		secret = oldSecret
+	}
+
+	requeueAfter := nextChange.Sub(now)
+	if requeueAfter < minimumRotationRequeueInterval {
+		requeueAfter = minimumRotationRequeueInterval
+	}
+	ctx.Queue.AddAfter(ctx.Key, requeueAfter)
+
+	return c.ensureFederationDomainStatus(ctx, federationDomain, secret.Name, jwksPresentCondition(secret.Name))
+}
+
 func (c *jwksWriterController) secretNeedsUpdate(federationDomain *configv1alpha1.FederationDomain) (bool, error) {
 	if federationDomain.Status.Secrets.JWKS.Name == "" {
 		// If the FederationDomain says it doesn't have a secret associated with it, then let's create one.
@@ -225,7 +431,14 @@ func (c *jwksWriterController) generateSecret(federationDomain *configv1alpha1.F
 		return nil, fmt.Errorf("cannot marshal jwks: %w", err)
 	}
 
-	s := corev1.Secret{
+	return c.newJWKSSecret(federationDomain, map[string][]byte{
+		activeJWKKey: jwkData,
+		jwksKey:      jwksData,
+	}), nil
+}
+
+func (c *jwksWriterController) newJWKSSecret(federationDomain *configv1alpha1.FederationDomain, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      federationDomain.Name + "-jwks",
 			Namespace: federationDomain.Namespace,
@@ -238,14 +451,9 @@ func (c *jwksWriterController) generateSecret(federationDomain *configv1alpha1.F
 				}),
 			},
 		},
-		Data: map[string][]byte{
-			activeJWKKey: jwkData,
-			jwksKey:      jwksData,
-		},
+		Data: data,
 		Type: jwksSecretTypeValue,
 	}
-
-	return &s, nil
 }
 
 func (c *jwksWriterController) createOrUpdateSecret(
@@ -286,6 +494,7 @@ func (c *jwksWriterController) createOrUpdateSecret(
 func (c *jwksWriterController) updateFederationDomainStatus(
 	ctx context.Context,
 	newFederationDomain *configv1alpha1.FederationDomain,
+	condition *configv1alpha1.Condition,
 ) error {
 	federationDomainClient := c.pinnipedClient.ConfigV1alpha1().FederationDomains(newFederationDomain.Namespace)
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -294,7 +503,13 @@ func (c *jwksWriterController) updateFederationDomainStatus(
 			return fmt.Errorf("cannot get FederationDomain: %w", err)
 		}
 
-		if newFederationDomain.Status.Secrets.JWKS.Name == oldFederationDomain.Status.Secrets.JWKS.Name {
+		conditionChanged := conditionsutil.MergeConfigConditions(
+			[]*configv1alpha1.Condition{condition},
+			oldFederationDomain.Generation,
+			&oldFederationDomain.Status.Conditions,
+			metav1.NewTime(c.clock.Now()),
+		)
+		if newFederationDomain.Status.Secrets.JWKS.Name == oldFederationDomain.Status.Secrets.JWKS.Name && !conditionChanged {
 			// If the existing FederationDomain is up to date, we don't need to update it.
 			return nil
 		}
@@ -367,3 +582,212 @@ func isValid(secret *corev1.Secret) bool {
 
 	return true
 }
+
+// suppliedJWKSSecretName returns the name of the JWKS Secret which was supplied by the FederationDomain's
+// administrator, or an empty string when the JWKS should be generated.
+func suppliedJWKSSecretName(federationDomain *configv1alpha1.FederationDomain) string {
+	if federationDomain.Spec.Secrets == nil {
+		return ""
+	}
+	return federationDomain.Spec.Secrets.JWKS.Name
+}
+
+// validateSuppliedJWKSSecret returns an error when a JWKS Secret which was supplied by an administrator cannot be
+// used. Supplied Secrets must not have owners, since they would be garbage collected along with their owners.
+func validateSuppliedJWKSSecret(secret *corev1.Secret) error {
+	if len(secret.OwnerReferences) != 0 {
+		return fmt.Errorf("secret %q must not have owner references", secret.Name)
+	}
+	if !isValid(secret) {
+		return fmt.Errorf(
+			"secret %q must have type %q, a private JWK in its %q data, and a JWKS which includes that JWK in its %q data",
+			secret.Name, jwksSecretTypeValue, activeJWKKey, jwksKey,
+		)
+	}
+	return nil
+}
+
+// validateSigningKeyRotation returns an error when the signing key rotation policy cannot be followed, or when it would
+// stop publishing a key while tokens which were signed by it are still valid, given the token lifetimes.
+func validateSigningKeyRotation(
+	rotation *configv1alpha1.FederationDomainSigningKeyRotationSpec,
+	lifetimes provider.FederationDomainTokenLifetimes,
+) error {
+	if rotation == nil {
+		return nil
+	}
+	var errs []error
+	if rotation.KeyLifetime.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("signingKeyRotation.keyLifetime must be a positive duration"))
+	}
+	if rotation.PrePublish.Duration < 0 {
+		errs = append(errs, fmt.Errorf("signingKeyRotation.prePublish must not be a negative duration"))
+	} else if rotation.KeyLifetime.Duration > 0 && rotation.PrePublish.Duration >= rotation.KeyLifetime.Duration {
+		errs = append(errs, fmt.Errorf("signingKeyRotation.prePublish must be shorter than signingKeyRotation.keyLifetime"))
+	}
+	if rotation.Retire.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("signingKeyRotation.retire must be a positive duration"))
+	} else {
+		timeouts := oidc.OIDCTimeoutsConfiguration(lifetimes)
+		if rotation.Retire.Duration < timeouts.IDTokenLifespan {
+			errs = append(errs, fmt.Errorf("signingKeyRotation.retire must not be shorter than the ID token lifetime of %s", timeouts.IDTokenLifespan))
+		}
+		if rotation.Retire.Duration < timeouts.AccessTokenLifespan {
+			errs = append(errs, fmt.Errorf("signingKeyRotation.retire must not be shorter than the access token lifetime of %s", timeouts.AccessTokenLifespan))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+// rotatingJWKsFromSecret returns the rotating keys from the secret, sorted by the time at which they became active.
+// When the secret was created before the FederationDomain had a rotation policy, its active key is returned as
+// the only key, starting now. When the secret has no valid keys, nothing is returned.
+func rotatingJWKsFromSecret(secret *corev1.Secret, now time.Time) []rotatingJWK {
+	if secret.Type != jwksSecretTypeValue {
+		return nil
+	}
+
+	if rotatingJWKsData, ok := secret.Data[rotatingJWKsKey]; ok {
+		var keys []rotatingJWK
+		if err := json.Unmarshal(rotatingJWKsData, &keys); err != nil {
+			plog.Debug("cannot unmarshal rotating jwks", "err", err)
+			return nil
+		}
+		for _, key := range keys {
+			if key.JWK.IsPublic() || !key.JWK.Valid() {
+				plog.Debug("rotating jwk is not a valid private key", "keyid", key.JWK.KeyID)
+				return nil
+			}
+		}
+		sort.SliceStable(keys, func(i, j int) bool { return keys[i].ActiveFrom.Before(keys[j].ActiveFrom) })
+		return keys
+	}
+
+	if !isValid(secret) {
+		return nil
+	}
+	var activeJWK jose.JSONWebKey
+	if err := json.Unmarshal(secret.Data[activeJWKKey], &activeJWK); err != nil {
+		return nil // should not happen, since it is valid
+	}
+	return []rotatingJWK{{JWK: activeJWK, ActiveFrom: now}}
+}
+
+// rotateJWKs follows the rotation policy for the keys, which must be sorted by the time at which they became
+// active. It returns the new keys, in the same order, and the next time at which they need to change.
+func rotateJWKs(
+	keys []rotatingJWK,
+	rotation *configv1alpha1.FederationDomainSigningKeyRotationSpec,
+	now time.Time,
+) ([]rotatingJWK, time.Time, error) {
+	if len(keys) == 0 {
+		key, err := newRotatingJWK(now)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		keys = []rotatingJWK{key}
+	}
+
+	active := activeRotatingJWKIndex(keys, now)
+	nextActiveFrom := keys[active].ActiveFrom.Add(rotation.KeyLifetime.Duration)
+	if active == len(keys)-1 {
+		publishAt := nextActiveFrom.Add(-rotation.PrePublish.Duration)
+		if now.Before(publishAt) {
+			// It is not time to publish the next key yet.
+			nextActiveFrom = publishAt
+		} else {
+			// When the next key is published late, e.g. because the Supervisor was not running, the active key
+			// is used for longer so that the next key is still published for the whole pre-publish window.
+			if earliest := now.Add(rotation.PrePublish.Duration); nextActiveFrom.Before(earliest) {
+				nextActiveFrom = earliest
+			}
+			key, err := newRotatingJWK(nextActiveFrom)
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		nextActiveFrom = keys[active+1].ActiveFrom
+	}
+	nextChange := nextActiveFrom
+
+	// Keys which were replaced are kept until the retire window after they were replaced has passed.
+	rotatedKeys := make([]rotatingJWK, 0, len(keys))
+	for i, key := range keys {
+		if i < active {
+			retireAt := keys[i+1].ActiveFrom.Add(rotation.Retire.Duration)
+			if !now.Before(retireAt) {
+				continue
+			}
+			if retireAt.Before(nextChange) {
+				nextChange = retireAt
+			}
+		}
+		rotatedKeys = append(rotatedKeys, key)
+	}
+
+	return rotatedKeys, nextChange, nil
+}
+
+// activeRotatingJWKIndex returns the index of the key which most recently became active. When no key has become
+// active yet, which could happen after the clock went backwards, the first key is used.
+func activeRotatingJWKIndex(keys []rotatingJWK, now time.Time) int {
+	active := 0
+	for i, key := range keys {
+		if !key.ActiveFrom.After(now) {
+			active = i
+		}
+	}
+	return active
+}
+
+func newRotatingJWK(activeFrom time.Time) (rotatingJWK, error) {
+	key, err := generateKey(rand.Reader)
+	if err != nil {
+		return rotatingJWK{}, fmt.Errorf("cannot generate key: %w", err)
+	}
+
+	jwk := jose.JSONWebKey{
+		Key:       key,
+		Algorithm: "ES256",
+		Use:       "sig",
+	}
+	// The thumbprint of the key gives each key a unique and stable key ID.
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return rotatingJWK{}, fmt.Errorf("cannot compute key thumbprint: %w", err)
+	}
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return rotatingJWK{JWK: jwk, ActiveFrom: activeFrom.UTC()}, nil
+}
+
+// rotatingJWKsSecretData returns the data of the secret for the rotating keys. The JWKS contains every key,
+// including the next key and any retired keys, while only the active key is used to sign tokens.
+func rotatingJWKsSecretData(keys []rotatingJWK, now time.Time) (map[string][]byte, error) {
+	activeJWKData, err := json.Marshal(keys[activeRotatingJWKIndex(keys, now)].JWK)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal jwk: %w", err)
+	}
+
+	jwks := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		jwks.Keys = append(jwks.Keys, key.JWK.Public())
+	}
+	jwksData, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal jwks: %w", err)
+	}
+
+	rotatingJWKsData, err := json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal rotating jwks: %w", err)
+	}
+
+	return map[string][]byte{
+		activeJWKKey:    activeJWKData,
+		jwksKey:         jwksData,
+		rotatingJWKsKey: rotatingJWKsData,
+	}, nil
+}
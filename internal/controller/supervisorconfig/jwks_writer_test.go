@@ -7,17 +7,21 @@ import (
 	"bytes"
 	"context"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"io"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
@@ -26,19 +30,30 @@ import (
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/testutil"
 )
 
 func TestJWKSWriterControllerFilterSecret(t *testing.T) {
 	t.Parallel()
 
+	federationDomainWithSuppliedSecret := &configv1alpha1.FederationDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+		Spec: configv1alpha1.FederationDomainSpec{
+			Secrets: &configv1alpha1.FederationDomainSecretsSpec{
+				JWKS: corev1.LocalObjectReference{Name: "some-supplied-secret"},
+			},
+		},
+	}
+
 	tests := []struct {
-		name       string
-		secret     metav1.Object
-		wantAdd    bool
-		wantUpdate bool
-		wantDelete bool
-		wantParent controllerlib.Key
+		name              string
+		secret            metav1.Object
+		federationDomains []*configv1alpha1.FederationDomain
+		wantAdd           bool
+		wantUpdate        bool
+		wantDelete        bool
+		wantParent        controllerlib.Key
 	}{
 		{
 			name: "no owner reference",
@@ -47,6 +62,26 @@ func TestJWKSWriterControllerFilterSecret(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{},
 			},
 		},
+		{
+			name: "no owner reference but supplied by a FederationDomain",
+			secret: &corev1.Secret{
+				Type:       "secrets.pinniped.dev/federation-domain-jwks",
+				ObjectMeta: metav1.ObjectMeta{Name: "some-supplied-secret", Namespace: "some-namespace"},
+			},
+			federationDomains: []*configv1alpha1.FederationDomain{federationDomainWithSuppliedSecret},
+			wantAdd:           true,
+			wantUpdate:        true,
+			wantDelete:        true,
+			wantParent:        controllerlib.Key{Namespace: "some-namespace", Name: "some-name"},
+		},
+		{
+			name: "no owner reference and supplied by a FederationDomain in another namespace",
+			secret: &corev1.Secret{
+				Type:       "secrets.pinniped.dev/federation-domain-jwks",
+				ObjectMeta: metav1.ObjectMeta{Name: "some-supplied-secret", Namespace: "some-other-namespace"},
+			},
+			federationDomains: []*configv1alpha1.FederationDomain{federationDomainWithSuppliedSecret},
+		},
 		{
 			name: "owner reference without correct APIVersion",
 			secret: &corev1.Secret{
@@ -179,6 +214,9 @@ func TestJWKSWriterControllerFilterSecret(t *testing.T) {
 				pinnipedfake.NewSimpleClientset(),
 				0,
 			).Config().V1alpha1().FederationDomains()
+			for _, federationDomain := range test.federationDomains {
+				require.NoError(t, federationDomainInformer.Informer().GetIndexer().Add(federationDomain))
+			}
 			withInformer := testutil.NewObservableWithInformerOption()
 			_ = NewJWKSWriterController(
 				nil, // labels, not needed
@@ -186,6 +224,7 @@ func TestJWKSWriterControllerFilterSecret(t *testing.T) {
 				nil, // pinnipedClient, not needed
 				secretInformer,
 				federationDomainInformer,
+				nil, // clock, not needed
 				withInformer.WithInformer,
 			)
 
@@ -240,6 +279,7 @@ func TestJWKSWriterControllerFilterFederationDomain(t *testing.T) {
 				nil, // pinnipedClient, not needed
 				secretInformer,
 				federationDomainInformer,
+				nil, // clock, not needed
 				withInformer.WithInformer,
 			)
 
@@ -258,6 +298,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 	// We shouldn't run this test in parallel since it messes with a global function (generateKey).
 
 	const namespace = "tuna-namespace"
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
 
 	goodKeyPEM, err := ioutil.ReadFile("testdata/good-ec-key.pem")
 	require.NoError(t, err)
@@ -282,8 +323,41 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			Issuer: "https://some-issuer.com",
 		},
 	}
+	jwksPresentCondition := func(status configv1alpha1.ConditionStatus, reason, message string) []configv1alpha1.Condition {
+		return []configv1alpha1.Condition{{
+			Type:               "JWKSPresent",
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now),
+			Reason:             reason,
+			Message:            message,
+		}}
+	}
+
 	goodFederationDomainWithStatus := goodFederationDomain.DeepCopy()
 	goodFederationDomainWithStatus.Status.Secrets.JWKS.Name = goodFederationDomainWithStatus.Name + "-jwks"
+	goodFederationDomainWithStatusAndCondition := goodFederationDomainWithStatus.DeepCopy()
+	goodFederationDomainWithStatusAndCondition.Status.Conditions = jwksPresentCondition(
+		configv1alpha1.ConditionTrue, "Success", `the JWKS is stored in Secret "good-federationDomain-jwks"`,
+	)
+	federationDomainWithSigner := goodFederationDomain.DeepCopy()
+	federationDomainWithSigner.Spec.Signer = &configv1alpha1.FederationDomainSignerSpec{Name: "some-signer", KeyName: "some-key"}
+	federationDomainWithSignerAndCondition := federationDomainWithSigner.DeepCopy()
+	federationDomainWithSignerAndCondition.Status.Conditions = jwksPresentCondition(
+		configv1alpha1.ConditionTrue, "SignerConfigured", `the signing keys are kept by signer "some-signer"`,
+	)
+	federationDomainWithSuppliedSecret := goodFederationDomain.DeepCopy()
+	federationDomainWithSuppliedSecret.Spec.Secrets = &configv1alpha1.FederationDomainSecretsSpec{
+		JWKS: corev1.LocalObjectReference{Name: "some-supplied-jwks"},
+	}
+	federationDomainWithSuppliedSecretAndStatusAndCondition := federationDomainWithSuppliedSecret.DeepCopy()
+	federationDomainWithSuppliedSecretAndStatusAndCondition.Status.Secrets.JWKS.Name = "some-supplied-jwks"
+	federationDomainWithSuppliedSecretAndStatusAndCondition.Status.Conditions = jwksPresentCondition(
+		configv1alpha1.ConditionTrue, "Success", `the JWKS is stored in Secret "some-supplied-jwks"`,
+	)
+	federationDomainWithInvalidSuppliedSecretCondition := federationDomainWithSuppliedSecret.DeepCopy()
+	federationDomainWithInvalidSuppliedSecretCondition.Status.Conditions = jwksPresentCondition(
+		configv1alpha1.ConditionFalse, "SuppliedSecretInvalid", `the supplied Secret "some-supplied-jwks" is missing or invalid`,
+	)
 
 	secretGVR := schema.GroupVersionResource{
 		Group:    corev1.SchemeGroupVersion.Group,
@@ -328,6 +402,13 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 	secretWithWrongType := newSecret("testdata/good-jwk.json", "testdata/good-jwks.json")
 	secretWithWrongType.Type = "not-the-right-type"
 
+	suppliedSecret := newSecret("testdata/good-jwk.json", "testdata/good-jwks.json")
+	suppliedSecret.Name = "some-supplied-jwks"
+	suppliedSecret.OwnerReferences = nil
+	invalidSuppliedSecret := newSecret("testdata/public-jwk.json", "testdata/good-jwks.json")
+	invalidSuppliedSecret.Name = "some-supplied-jwks"
+	invalidSuppliedSecret.OwnerReferences = nil
+
 	tests := []struct {
 		name                        string
 		key                         controllerlib.Key
@@ -354,7 +435,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatus),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -372,7 +453,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
-				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatus),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -388,6 +469,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -399,12 +481,85 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			secrets: []*corev1.Secret{
 				goodSecret,
 			},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
+			},
+		},
+		{
+			name: "existing federationDomain with existing secret and condition",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*configv1alpha1.FederationDomain{
+				goodFederationDomainWithStatusAndCondition,
+			},
+			secrets: []*corev1.Secret{
+				goodSecret,
+			},
+			wantSecretActions:           []kubetesting.Action{},
+			wantFederationDomainActions: []kubetesting.Action{},
 		},
 		{
 			name: "deleted federationDomain",
 			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
 			// Nothing to do here since Kube will garbage collect our child secret via its OwnerReference.
 		},
+		{
+			name: "federationDomain with a signer",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*configv1alpha1.FederationDomain{
+				federationDomainWithSigner,
+			},
+			// The signer keeps the keys, so there is no secret to create.
+			wantSecretActions: []kubetesting.Action{},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, federationDomainWithSignerAndCondition),
+			},
+		},
+		{
+			name: "federationDomain with a supplied secret",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*configv1alpha1.FederationDomain{
+				federationDomainWithSuppliedSecret,
+			},
+			secrets: []*corev1.Secret{
+				suppliedSecret,
+			},
+			// The supplied secret is used as it is, and it is never updated.
+			wantSecretActions: []kubetesting.Action{},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, federationDomainWithSuppliedSecretAndStatusAndCondition),
+			},
+		},
+		{
+			name: "federationDomain with an invalid supplied secret",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*configv1alpha1.FederationDomain{
+				federationDomainWithSuppliedSecret,
+			},
+			secrets: []*corev1.Secret{
+				invalidSuppliedSecret,
+			},
+			// The supplied secret is never replaced, so its problem is only reported.
+			wantSecretActions: []kubetesting.Action{},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, federationDomainWithInvalidSuppliedSecretCondition),
+			},
+		},
+		{
+			name: "federationDomain with a missing supplied secret",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*configv1alpha1.FederationDomain{
+				federationDomainWithSuppliedSecret,
+			},
+			wantSecretActions: []kubetesting.Action{},
+			wantFederationDomainActions: []kubetesting.Action{
+				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, federationDomainWithInvalidSuppliedSecretCondition),
+			},
+		},
 		{
 			name: "missing jwk in secret",
 			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
@@ -421,6 +576,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -439,6 +595,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -457,6 +614,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -475,6 +633,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -493,6 +652,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -511,6 +671,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -529,6 +690,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -547,6 +709,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -565,6 +728,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -583,6 +747,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			},
 			wantFederationDomainActions: []kubetesting.Action{
 				kubetesting.NewGetAction(federationDomainGVR, namespace, goodFederationDomain.Name),
+				kubetesting.NewUpdateSubresourceAction(federationDomainGVR, "status", namespace, goodFederationDomainWithStatusAndCondition),
 			},
 		},
 		{
@@ -714,6 +879,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 				pinnipedAPIClient,
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().FederationDomains(),
+				clock.NewFakeClock(now),
 				controllerlib.WithInformer,
 			)
 
@@ -744,6 +910,300 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 	}
 }
 
+func TestJWKSWriterControllerSyncWithSigningKeyRotation(t *testing.T) {
+	// We shouldn't run this test in parallel since it messes with a global function (generateKey).
+	generateKey = generateECKey
+
+	const namespace = "tuna-namespace"
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	federationDomain := &configv1alpha1.FederationDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "good-federationDomain", Namespace: namespace, UID: "good-federationDomain-uid"},
+		Spec: configv1alpha1.FederationDomainSpec{
+			Issuer: "https://some-issuer.com",
+			SigningKeyRotation: &configv1alpha1.FederationDomainSigningKeyRotationSpec{
+				KeyLifetime: metav1.Duration{Duration: 24 * time.Hour},
+				PrePublish:  metav1.Duration{Duration: time.Hour},
+				Retire:      metav1.Duration{Duration: 2 * time.Hour},
+			},
+		},
+	}
+	legacySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "good-federationDomain-jwks", Namespace: namespace, ResourceVersion: "1"},
+		Type:       "secrets.pinniped.dev/federation-domain-jwks",
+		Data: map[string][]byte{
+			"activeJWK": readJWKJSON(t, "testdata/good-jwk.json"),
+			"jwks":      readJWKJSON(t, "testdata/good-jwks.json"),
+		},
+	}
+
+	sync := func(t *testing.T, secret *corev1.Secret, at time.Time) (*kubernetesfake.Clientset, *jwksTestQueue) {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		kubeAPIClient := kubernetesfake.NewSimpleClientset()
+		kubeInformerClient := kubernetesfake.NewSimpleClientset()
+		if secret != nil {
+			require.NoError(t, kubeAPIClient.Tracker().Add(secret))
+			require.NoError(t, kubeInformerClient.Tracker().Add(secret))
+		}
+		pinnipedAPIClient := pinnipedfake.NewSimpleClientset(federationDomain)
+		pinnipedInformerClient := pinnipedfake.NewSimpleClientset(federationDomain)
+		kubeInformers := kubeinformers.NewSharedInformerFactory(kubeInformerClient, 0)
+		pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
+
+		c := NewJWKSWriterController(
+			nil,
+			kubeAPIClient,
+			pinnipedAPIClient,
+			kubeInformers.Core().V1().Secrets(),
+			pinnipedInformers.Config().V1alpha1().FederationDomains(),
+			clock.NewFakeClock(at),
+			controllerlib.WithInformer,
+		)
+		kubeInformers.Start(ctx.Done())
+		pinnipedInformers.Start(ctx.Done())
+		controllerlib.TestRunSynchronously(t, c)
+
+		queue := &jwksTestQueue{}
+		key := controllerlib.Key{Namespace: namespace, Name: federationDomain.Name}
+		require.NoError(t, controllerlib.TestSync(t, c, controllerlib.Context{Context: ctx, Key: key, Queue: queue}))
+		require.Equal(t, key, queue.key)
+
+		fd, err := pinnipedAPIClient.ConfigV1alpha1().FederationDomains(namespace).Get(ctx, federationDomain.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "good-federationDomain-jwks", fd.Status.Secrets.JWKS.Name)
+		return kubeAPIClient, queue
+	}
+
+	writtenSecret := func(t *testing.T, client *kubernetesfake.Clientset) *corev1.Secret {
+		t.Helper()
+		secret, err := client.CoreV1().Secrets(namespace).Get(context.Background(), "good-federationDomain-jwks", metav1.GetOptions{})
+		require.NoError(t, err)
+		return secret
+	}
+
+	requireSecretKeys := func(t *testing.T, secret *corev1.Secret, wantActiveKeyID string, wantKeyIDs []string) {
+		t.Helper()
+		var activeJWK jose.JSONWebKey
+		require.NoError(t, json.Unmarshal(secret.Data["activeJWK"], &activeJWK))
+		require.Equal(t, wantActiveKeyID, activeJWK.KeyID)
+		require.False(t, activeJWK.IsPublic())
+
+		var jwks jose.JSONWebKeySet
+		require.NoError(t, json.Unmarshal(secret.Data["jwks"], &jwks))
+		gotKeyIDs := []string{}
+		for _, key := range jwks.Keys {
+			require.True(t, key.IsPublic())
+			gotKeyIDs = append(gotKeyIDs, key.KeyID)
+		}
+		require.Equal(t, wantKeyIDs, gotKeyIDs)
+		require.True(t, isValid(secret))
+	}
+
+	t.Run("new secret", func(t *testing.T) {
+		client, queue := sync(t, nil, now)
+		secret := writtenSecret(t, client)
+		keys := rotatingJWKsFromSecret(secret, now)
+		require.Len(t, keys, 1)
+		require.Equal(t, now, keys[0].ActiveFrom)
+		requireSecretKeys(t, secret, keys[0].JWK.KeyID, []string{keys[0].JWK.KeyID})
+		require.Equal(t, 23*time.Hour, queue.duration) // when the next key should be published
+	})
+
+	t.Run("secret which was created without a rotation policy keeps its key", func(t *testing.T) {
+		client, queue := sync(t, legacySecret, now)
+		secret := writtenSecret(t, client)
+		keys := rotatingJWKsFromSecret(secret, now)
+		require.Len(t, keys, 1)
+		require.Equal(t, now, keys[0].ActiveFrom)
+		requireSecretKeys(t, secret, "pinniped-supervisor-key", []string{"pinniped-supervisor-key"})
+		require.Equal(t, 23*time.Hour, queue.duration)
+	})
+
+	t.Run("next key is published before it is used, then the old key is retired", func(t *testing.T) {
+		client, _ := sync(t, legacySecret, now)
+		secret := writtenSecret(t, client)
+
+		// Nothing changes before the pre-publish window.
+		client, queue := sync(t, secret, now.Add(22*time.Hour))
+		require.Equal(t, secret.Data, writtenSecret(t, client).Data)
+		require.Equal(t, time.Hour, queue.duration)
+
+		// The next key is published but the old key is still active.
+		client, queue = sync(t, secret, now.Add(23*time.Hour))
+		secret = writtenSecret(t, client)
+		keys := rotatingJWKsFromSecret(secret, now)
+		require.Len(t, keys, 2)
+		nextKeyID := keys[1].JWK.KeyID
+		require.NotEqual(t, "pinniped-supervisor-key", nextKeyID)
+		require.Equal(t, now.Add(24*time.Hour), keys[1].ActiveFrom)
+		requireSecretKeys(t, secret, "pinniped-supervisor-key", []string{"pinniped-supervisor-key", nextKeyID})
+		require.Equal(t, time.Hour, queue.duration) // when the next key becomes active
+
+		// The next key becomes active, and the old key is still served for verification.
+		client, queue = sync(t, secret, now.Add(24*time.Hour))
+		secret = writtenSecret(t, client)
+		requireSecretKeys(t, secret, nextKeyID, []string{"pinniped-supervisor-key", nextKeyID})
+		require.Equal(t, 2*time.Hour, queue.duration) // when the old key is retired
+
+		// The old key is retired.
+		client, queue = sync(t, secret, now.Add(26*time.Hour))
+		secret = writtenSecret(t, client)
+		requireSecretKeys(t, secret, nextKeyID, []string{nextKeyID})
+		require.Equal(t, 21*time.Hour, queue.duration) // when the key after that should be published
+	})
+}
+
+func TestRotateJWKs(t *testing.T) {
+	// We shouldn't run this test in parallel since it messes with a global function (generateKey).
+	generateKey = generateECKey
+
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	rotation := &configv1alpha1.FederationDomainSigningKeyRotationSpec{
+		KeyLifetime: metav1.Duration{Duration: 10 * time.Hour},
+		PrePublish:  metav1.Duration{Duration: time.Hour},
+		Retire:      metav1.Duration{Duration: 30 * time.Minute},
+	}
+
+	tests := []struct {
+		name            string
+		keysActiveFrom  []time.Time
+		now             time.Time
+		wantActiveFrom  []time.Time
+		wantNextChange  time.Time
+		wantActiveIndex int
+	}{
+		{
+			name:           "no keys yet",
+			now:            start,
+			wantActiveFrom: []time.Time{start},
+			wantNextChange: start.Add(9 * time.Hour),
+		},
+		{
+			name:           "before the pre-publish window",
+			keysActiveFrom: []time.Time{start},
+			now:            start.Add(8 * time.Hour),
+			wantActiveFrom: []time.Time{start},
+			wantNextChange: start.Add(9 * time.Hour),
+		},
+		{
+			name:           "in the pre-publish window",
+			keysActiveFrom: []time.Time{start},
+			now:            start.Add(9 * time.Hour),
+			wantActiveFrom: []time.Time{start, start.Add(10 * time.Hour)},
+			wantNextChange: start.Add(10 * time.Hour),
+		},
+		{
+			name:           "the next key is published late",
+			keysActiveFrom: []time.Time{start},
+			now:            start.Add(15 * time.Hour),
+			wantActiveFrom: []time.Time{start, start.Add(16 * time.Hour)},
+			wantNextChange: start.Add(16 * time.Hour),
+		},
+		{
+			name:            "in the retire window",
+			keysActiveFrom:  []time.Time{start, start.Add(10 * time.Hour)},
+			now:             start.Add(10 * time.Hour),
+			wantActiveFrom:  []time.Time{start, start.Add(10 * time.Hour)},
+			wantNextChange:  start.Add(10*time.Hour + 30*time.Minute),
+			wantActiveIndex: 1,
+		},
+		{
+			name:           "after the retire window",
+			keysActiveFrom: []time.Time{start, start.Add(10 * time.Hour)},
+			now:            start.Add(10*time.Hour + 30*time.Minute),
+			wantActiveFrom: []time.Time{start.Add(10 * time.Hour)},
+			wantNextChange: start.Add(19 * time.Hour),
+		},
+		{
+			name:           "several keys were replaced while the Supervisor was not running",
+			keysActiveFrom: []time.Time{start, start.Add(10 * time.Hour), start.Add(20 * time.Hour)},
+			now:            start.Add(40 * time.Hour),
+			wantActiveFrom: []time.Time{start.Add(20 * time.Hour), start.Add(41 * time.Hour)},
+			wantNextChange: start.Add(41 * time.Hour),
+		},
+		{
+			name:           "no key is active yet",
+			keysActiveFrom: []time.Time{start.Add(time.Hour)},
+			now:            start,
+			wantActiveFrom: []time.Time{start.Add(time.Hour)},
+			wantNextChange: start.Add(10 * time.Hour),
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var keys []rotatingJWK
+			for _, activeFrom := range test.keysActiveFrom {
+				key, err := newRotatingJWK(activeFrom)
+				require.NoError(t, err)
+				keys = append(keys, key)
+			}
+
+			rotatedKeys, nextChange, err := rotateJWKs(keys, rotation, test.now)
+			require.NoError(t, err)
+
+			gotActiveFrom := []time.Time{}
+			keyIDs := map[string]bool{}
+			for _, key := range rotatedKeys {
+				gotActiveFrom = append(gotActiveFrom, key.ActiveFrom)
+				keyIDs[key.JWK.KeyID] = true
+			}
+			require.Equal(t, test.wantActiveFrom, gotActiveFrom)
+			require.Len(t, keyIDs, len(rotatedKeys), "key IDs should be unique")
+			require.Equal(t, test.wantNextChange, nextChange)
+			require.Equal(t, test.wantActiveIndex, activeRotatingJWKIndex(rotatedKeys, test.now))
+		})
+	}
+}
+
+func TestValidateSigningKeyRotation(t *testing.T) {
+	defaultLifetimes := provider.FederationDomainTokenLifetimes{}
+	require.NoError(t, validateSigningKeyRotation(nil, defaultLifetimes))
+	require.NoError(t, validateSigningKeyRotation(&configv1alpha1.FederationDomainSigningKeyRotationSpec{
+		KeyLifetime: metav1.Duration{Duration: time.Hour},
+		Retire:      metav1.Duration{Duration: 2 * time.Minute},
+	}, defaultLifetimes))
+	require.EqualError(t, validateSigningKeyRotation(&configv1alpha1.FederationDomainSigningKeyRotationSpec{
+		PrePublish: metav1.Duration{Duration: -time.Hour},
+		Retire:     metav1.Duration{Duration: -time.Hour},
+	}, defaultLifetimes), "[signingKeyRotation.keyLifetime must be a positive duration, "+
+		"signingKeyRotation.prePublish must not be a negative duration, "+
+		"signingKeyRotation.retire must be a positive duration]")
+
+	// Keys must be published before they are used, and after they were used for as long as their tokens are valid.
+	require.EqualError(t, validateSigningKeyRotation(&configv1alpha1.FederationDomainSigningKeyRotationSpec{
+		KeyLifetime: metav1.Duration{Duration: time.Hour},
+		PrePublish:  metav1.Duration{Duration: time.Hour},
+	}, defaultLifetimes), "[signingKeyRotation.prePublish must be shorter than signingKeyRotation.keyLifetime, "+
+		"signingKeyRotation.retire must be a positive duration]")
+	require.EqualError(t, validateSigningKeyRotation(&configv1alpha1.FederationDomainSigningKeyRotationSpec{
+		KeyLifetime: metav1.Duration{Duration: time.Hour},
+		Retire:      metav1.Duration{Duration: time.Minute},
+	}, defaultLifetimes), "[signingKeyRotation.retire must not be shorter than the ID token lifetime of 2m0s, "+
+		"signingKeyRotation.retire must not be shorter than the access token lifetime of 2m0s]")
+	require.EqualError(t, validateSigningKeyRotation(&configv1alpha1.FederationDomainSigningKeyRotationSpec{
+		KeyLifetime: metav1.Duration{Duration: time.Hour},
+		Retire:      metav1.Duration{Duration: 10 * time.Minute},
+	}, provider.FederationDomainTokenLifetimes{IDToken: 15 * time.Minute, AccessToken: 5 * time.Minute}),
+		"signingKeyRotation.retire must not be shorter than the ID token lifetime of 15m0s")
+}
+
+type jwksTestQueue struct {
+	key      controllerlib.Key
+	duration time.Duration
+
+	controllerlib.Queue // panic if any other methods called
+}
+
+func (q *jwksTestQueue) AddAfter(key controllerlib.Key, duration time.Duration) {
+	q.key = key
+	q.duration = duration
+}
+
 func readJWKJSON(t *testing.T, path string) []byte {
 	t.Helper()
 
@@ -44,9 +44,11 @@ const (
 	loadedTLSConfigurationMessage = "loaded TLS configuration"
 )
 
-// UpstreamLDAPIdentityProviderICache is a thread safe cache that holds a list of validated upstream LDAP IDP configurations.
+// UpstreamLDAPIdentityProviderICache is a thread safe cache that holds a list of validated upstream LDAP IDP configurations,
+// along with the names of the upstreams which have a failing condition.
 type UpstreamLDAPIdentityProviderICache interface {
 	SetLDAPIdentityProviders([]provider.UpstreamLDAPIdentityProviderI)
+	SetUnreadyLDAPIdentityProviders(names []string)
 }
 
 type ldapWatcherController struct {
@@ -136,17 +138,21 @@ func (c *ldapWatcherController) Sync(ctx controllerlib.Context) error {
 
 	requeue := false
 	validatedUpstreams := make([]provider.UpstreamLDAPIdentityProviderI, 0, len(actualUpstreams))
+	var unreadyUpstreams []string
 	for _, upstream := range actualUpstreams {
 		valid, requestedRequeue := c.validateUpstream(ctx.Context, upstream)
 		if valid != nil {
 			validatedUpstreams = append(validatedUpstreams, valid)
 		}
 		if requestedRequeue {
+			// Either invalid or unreachable, and the latter are still in the cache in case they recover.
+			unreadyUpstreams = append(unreadyUpstreams, upstream.Name)
 			requeue = true
 		}
 	}
 
 	c.cache.SetLDAPIdentityProviders(validatedUpstreams)
+	c.cache.SetUnreadyLDAPIdentityProviders(unreadyUpstreams)
 
 	if requeue {
 		return controllerlib.ErrSyntheticRequeue
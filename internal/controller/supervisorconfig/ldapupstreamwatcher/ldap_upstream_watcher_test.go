@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -934,6 +935,19 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				require.Equal(t, tt.wantResultingUpstreams[i], normalizedActualUpstreams[i])
 			}
 
+			// The upstreams which failed validation should make the Supervisor unready.
+			var wantUnready []string
+			for _, upstream := range tt.wantResultingUpstreams {
+				if upstream.Status.Phase == v1alpha1.LDAPPhaseError {
+					wantUnready = append(wantUnready, fmt.Sprintf("LDAPIdentityProvider %q", upstream.Name))
+				}
+			}
+			if len(wantUnready) == 0 {
+				require.NoError(t, cache.CheckReady(nil))
+			} else {
+				require.EqualError(t, cache.CheckReady(nil), "upstream identity providers are not ready: "+strings.Join(wantUnready, ", "))
+			}
+
 			// Check that the controller remembered which version of the secret it most recently validated successfully with.
 			if tt.wantValidatedSettings == nil {
 				tt.wantValidatedSettings = map[string]validatedSettings{}
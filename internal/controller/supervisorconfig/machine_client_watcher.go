@@ -0,0 +1,199 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	machineClientSecretType corev1.SecretType = "secrets.pinniped.dev/machine-client"
+	machineClientSecretKey                    = "clientSecret"
+
+	// The same bcrypt cost which fosite uses by default for client secrets.
+	machineClientSecretHashCost = 12
+)
+
+// MachineClientsSetter can be notified of all valid machine clients.
+// Implementations of this type should be thread-safe to support calls from multiple goroutines.
+type MachineClientsSetter interface {
+	SetMachineClients(machineClients []*clientregistry.MachineClient)
+}
+
+// hashedMachineClientSecret remembers the hash of a client secret, because bcrypt is slow on purpose.
+type hashedMachineClientSecret struct {
+	resourceVersion string
+	hash            []byte
+}
+
+type machineClientWatcherController struct {
+	machineClientsSetter  MachineClientsSetter
+	clock                 clock.Clock
+	client                pinnipedclientset.Interface
+	machineClientInformer configinformers.MachineClientInformer
+	secretInformer        corev1informers.SecretInformer
+	hashedSecrets         map[string]hashedMachineClientSecret
+}
+
+// NewMachineClientWatcherController creates a controllerlib.Controller that watches MachineClient objects and their
+// Secrets, and notifies a MachineClientsSetter of the valid clients.
+func NewMachineClientWatcherController(
+	machineClientsSetter MachineClientsSetter,
+	clock clock.Clock,
+	client pinnipedclientset.Interface,
+	machineClientInformer configinformers.MachineClientInformer,
+	secretInformer corev1informers.SecretInformer,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "MachineClientWatcherController",
+			Syncer: &machineClientWatcherController{
+				machineClientsSetter:  machineClientsSetter,
+				clock:                 clock,
+				client:                client,
+				machineClientInformer: machineClientInformer,
+				secretInformer:        secretInformer,
+				hashedSecrets:         map[string]hashedMachineClientSecret{},
+			},
+		},
+		withInformer(
+			machineClientInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			secretInformer,
+			pinnipedcontroller.MatchAnySecretOfTypeFilter(machineClientSecretType, pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *machineClientWatcherController) Sync(ctx controllerlib.Context) error {
+	machineClients, err := c.machineClientInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	validMachineClients := make([]*clientregistry.MachineClient, 0, len(machineClients))
+	hashedSecrets := make(map[string]hashedMachineClientSecret, len(machineClients))
+	for _, machineClient := range machineClients {
+		status, message := configv1alpha1.SuccessMachineClientStatusCondition, "Tokens may be issued to this client"
+
+		var valid *clientregistry.MachineClient
+		if machineClient.Name == clientregistry.PinnipedCLI().GetID() {
+			status, message = configv1alpha1.InvalidMachineClientStatusCondition, fmt.Sprintf("Invalid: the client ID %q is reserved", machineClient.Name)
+		} else if hash, err := c.hashedSecret(machineClient, hashedSecrets); err != nil {
+			status, message = configv1alpha1.InvalidMachineClientStatusCondition, "Invalid: "+err.Error()
+		} else {
+			valid = clientregistry.NewMachineClient(machineClient.Name, hash, machineClient.Spec.Username, machineClient.Spec.Groups)
+			valid.AllowDelegation = machineClient.Spec.AllowDelegation
+		}
+
+		if err := c.updateStatus(ctx.Context, machineClient.Namespace, machineClient.Name, status, message); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status: %w", err))
+			continue
+		}
+
+		if valid != nil {
+			validMachineClients = append(validMachineClients, valid)
+		}
+	}
+
+	// Forget the hashes of the Secrets which are no longer used.
+	c.hashedSecrets = hashedSecrets
+
+	c.machineClientsSetter.SetMachineClients(validMachineClients)
+
+	return errors.NewAggregate(errs)
+}
+
+// hashedSecret returns the bcrypt hash of the client secret of the machine client. It reuses the previous hash
+// while the Secret is unchanged, and records the hash in hashedSecrets.
+func (c *machineClientWatcherController) hashedSecret(machineClient *configv1alpha1.MachineClient, hashedSecrets map[string]hashedMachineClientSecret) ([]byte, error) {
+	secret, err := c.secretInformer.Lister().Secrets(machineClient.Namespace).Get(machineClient.Spec.SecretName)
+	if k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("secret %q not found", machineClient.Spec.SecretName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %q: %w", machineClient.Spec.SecretName, err)
+	}
+	if secret.Type != machineClientSecretType {
+		return nil, fmt.Errorf("secret %q has wrong type %q (should be %q)", secret.Name, secret.Type, machineClientSecretType)
+	}
+	clientSecret := secret.Data[machineClientSecretKey]
+	if len(clientSecret) == 0 {
+		return nil, fmt.Errorf("secret %q is missing required key %q", secret.Name, machineClientSecretKey)
+	}
+
+	key := secret.Namespace + "/" + secret.Name
+	if previous, ok := c.hashedSecrets[key]; ok && previous.resourceVersion == secret.ResourceVersion {
+		hashedSecrets[key] = previous
+		return previous.hash, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword(clientSecret, machineClientSecretHashCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the client secret: %w", err)
+	}
+	hashedSecrets[key] = hashedMachineClientSecret{resourceVersion: secret.ResourceVersion, hash: hash}
+	return hash, nil
+}
+
+func (c *machineClientWatcherController) updateStatus(
+	ctx context.Context,
+	namespace, name string,
+	status configv1alpha1.MachineClientStatusCondition,
+	message string,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		machineClient, err := c.client.ConfigV1alpha1().MachineClients(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get failed: %w", err)
+		}
+
+		if machineClient.Status.Status == status && machineClient.Status.Message == message {
+			return nil
+		}
+
+		plog.Debug(
+			"attempting status update",
+			"machineclient",
+			klog.KRef(namespace, name),
+			"status",
+			status,
+			"message",
+			message,
+		)
+		machineClient.Status.Status = status
+		machineClient.Status.Message = message
+		machineClient.Status.LastUpdateTime = timePtr(metav1.NewTime(c.clock.Now()))
+		_, err = c.client.ConfigV1alpha1().MachineClients(namespace).UpdateStatus(ctx, machineClient, metav1.UpdateOptions{})
+		return err
+	})
+}
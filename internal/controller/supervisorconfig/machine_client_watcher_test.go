@@ -0,0 +1,273 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	kubeinformers "k8s.io/client-go/informers"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/testutil"
+)
+
+type fakeMachineClientsSetter struct {
+	mutex          sync.Mutex
+	machineClients []*clientregistry.MachineClient
+}
+
+func (f *fakeMachineClientsSetter) SetMachineClients(machineClients []*clientregistry.MachineClient) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.machineClients = machineClients
+}
+
+func TestMachineClientWatcherControllerFilter(t *testing.T) {
+	t.Parallel()
+
+	observableWithInformerOption := testutil.NewObservableWithInformerOption()
+	machineClientInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).Config().V1alpha1().MachineClients()
+	secretInformer := kubeinformers.NewSharedInformerFactoryWithOptions(nil, 0).Core().V1().Secrets()
+	_ = NewMachineClientWatcherController(
+		nil,
+		nil,
+		nil,
+		machineClientInformer,
+		secretInformer,
+		observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
+	)
+
+	machineClientFilter := observableWithInformerOption.GetFilterForInformer(machineClientInformer)
+	target := &v1alpha1.MachineClient{ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"}}
+	other := &v1alpha1.MachineClient{ObjectMeta: metav1.ObjectMeta{Name: "other-name", Namespace: "other-namespace"}}
+	require.True(t, machineClientFilter.Add(target))
+	require.True(t, machineClientFilter.Update(target, other))
+	require.True(t, machineClientFilter.Delete(target))
+	require.Equal(t, controllerlib.Key{}, machineClientFilter.Parent(target))
+
+	secretFilter := observableWithInformerOption.GetFilterForInformer(secretInformer)
+	machineClientSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+		Type:       "secrets.pinniped.dev/machine-client",
+	}
+	otherSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-name", Namespace: "some-namespace"},
+		Type:       corev1.SecretTypeOpaque,
+	}
+	require.True(t, secretFilter.Add(machineClientSecret))
+	require.True(t, secretFilter.Update(otherSecret, machineClientSecret))
+	require.True(t, secretFilter.Delete(machineClientSecret))
+	require.False(t, secretFilter.Add(otherSecret))
+	require.False(t, secretFilter.Update(otherSecret, otherSecret))
+	require.False(t, secretFilter.Delete(otherSecret))
+}
+
+func TestMachineClientWatcherControllerSync(t *testing.T) {
+	t.Parallel()
+
+	const testNamespace = "test-namespace"
+
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	frozenMetav1Now := metav1.NewTime(now)
+
+	newMachineClient := func(name, secretName string) *v1alpha1.MachineClient {
+		return &v1alpha1.MachineClient{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Spec: v1alpha1.MachineClientSpec{
+				SecretName:      secretName,
+				Username:        name + "-user",
+				Groups:          []string{name + "-group"},
+				AllowDelegation: name == "b",
+			},
+		}
+	}
+	newSecret := func(name string, secretType corev1.SecretType, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, ResourceVersion: "1"},
+			Type:       secretType,
+			Data:       data,
+		}
+	}
+	status := func(condition v1alpha1.MachineClientStatusCondition, message string) v1alpha1.MachineClientStatus {
+		return v1alpha1.MachineClientStatus{Status: condition, Message: message, LastUpdateTime: &frozenMetav1Now}
+	}
+
+	tests := []struct {
+		name               string
+		inputObjects       []runtime.Object
+		inputSecrets       []runtime.Object
+		wantValidClientIDs []string
+		wantStatuses       map[string]v1alpha1.MachineClientStatus
+	}{
+		{
+			name:               "no MachineClients",
+			wantValidClientIDs: []string{},
+			wantStatuses:       map[string]v1alpha1.MachineClientStatus{},
+		},
+		{
+			name: "valid MachineClients",
+			inputObjects: []runtime.Object{
+				newMachineClient("a", "a-secret"),
+				newMachineClient("b", "b-secret"),
+			},
+			inputSecrets: []runtime.Object{
+				newSecret("a-secret", machineClientSecretType, map[string][]byte{"clientSecret": []byte("a-password")}),
+				newSecret("b-secret", machineClientSecretType, map[string][]byte{"clientSecret": []byte("b-password")}),
+			},
+			wantValidClientIDs: []string{"a", "b"},
+			wantStatuses: map[string]v1alpha1.MachineClientStatus{
+				"a": status(v1alpha1.SuccessMachineClientStatusCondition, "Tokens may be issued to this client"),
+				"b": status(v1alpha1.SuccessMachineClientStatusCondition, "Tokens may be issued to this client"),
+			},
+		},
+		{
+			name: "invalid MachineClients",
+			inputObjects: []runtime.Object{
+				newMachineClient("pinniped-cli", "some-secret"),
+				newMachineClient("missing-secret", "does-not-exist"),
+				newMachineClient("wrong-type", "opaque-secret"),
+				newMachineClient("missing-key", "empty-secret"),
+				newMachineClient("valid", "some-secret"),
+			},
+			inputSecrets: []runtime.Object{
+				newSecret("some-secret", machineClientSecretType, map[string][]byte{"clientSecret": []byte("password")}),
+				newSecret("opaque-secret", corev1.SecretTypeOpaque, map[string][]byte{"clientSecret": []byte("password")}),
+				newSecret("empty-secret", machineClientSecretType, map[string][]byte{"clientSecret": {}}),
+			},
+			wantValidClientIDs: []string{"valid"},
+			wantStatuses: map[string]v1alpha1.MachineClientStatus{
+				"pinniped-cli":   status(v1alpha1.InvalidMachineClientStatusCondition, `Invalid: the client ID "pinniped-cli" is reserved`),
+				"missing-secret": status(v1alpha1.InvalidMachineClientStatusCondition, `Invalid: secret "does-not-exist" not found`),
+				"wrong-type":     status(v1alpha1.InvalidMachineClientStatusCondition, `Invalid: secret "opaque-secret" has wrong type "Opaque" (should be "secrets.pinniped.dev/machine-client")`),
+				"missing-key":    status(v1alpha1.InvalidMachineClientStatusCondition, `Invalid: secret "empty-secret" is missing required key "clientSecret"`),
+				"valid":          status(v1alpha1.SuccessMachineClientStatusCondition, "Tokens may be issued to this client"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakePinnipedClient := pinnipedfake.NewSimpleClientset(tt.inputObjects...)
+			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(fakePinnipedClient, 0)
+			fakeKubeClient := kubernetesfake.NewSimpleClientset(tt.inputSecrets...)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(fakeKubeClient, 0)
+			machineClientsSetter := &fakeMachineClientsSetter{}
+
+			controller := NewMachineClientWatcherController(
+				machineClientsSetter,
+				clock.NewFakeClock(now),
+				fakePinnipedClient,
+				pinnipedInformers.Config().V1alpha1().MachineClients(),
+				kubeInformers.Core().V1().Secrets(),
+				controllerlib.WithInformer,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			pinnipedInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+			require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+
+			actualValidClientIDs := []string{}
+			hashes := map[string][]byte{}
+			for _, machineClient := range machineClientsSetter.machineClients {
+				id := machineClient.Client.GetID()
+				actualValidClientIDs = append(actualValidClientIDs, id)
+				require.Equal(t, id+"-user", machineClient.Username)
+				require.Equal(t, []string{id + "-group"}, machineClient.Groups)
+				require.False(t, machineClient.Client.IsPublic())
+				require.Equal(t, id == "b", machineClient.AllowDelegation)
+				hashes[id] = machineClient.Client.GetHashedSecret()
+			}
+			sort.Strings(actualValidClientIDs)
+			require.Equal(t, tt.wantValidClientIDs, actualValidClientIDs)
+
+			actual, err := fakePinnipedClient.ConfigV1alpha1().MachineClients(testNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			actualStatuses := map[string]v1alpha1.MachineClientStatus{}
+			for _, machineClient := range actual.Items {
+				actualStatuses[machineClient.Name] = machineClient.Status
+			}
+			require.Equal(t, tt.wantStatuses, actualStatuses)
+
+			// A second sync reuses the hashes of the unchanged Secrets.
+			require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+			for _, machineClient := range machineClientsSetter.machineClients {
+				require.Equal(t, hashes[machineClient.Client.GetID()], machineClient.Client.GetHashedSecret())
+			}
+		})
+	}
+}
+
+func TestMachineClientWatcherControllerSyncHashesSecret(t *testing.T) {
+	t.Parallel()
+
+	const testNamespace = "test-namespace"
+
+	fakePinnipedClient := pinnipedfake.NewSimpleClientset(&v1alpha1.MachineClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-client", Namespace: testNamespace},
+		Spec:       v1alpha1.MachineClientSpec{SecretName: "some-secret", Username: "some-user"},
+	})
+	pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(fakePinnipedClient, 0)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-secret", Namespace: testNamespace, ResourceVersion: "1"},
+		Type:       machineClientSecretType,
+		Data:       map[string][]byte{"clientSecret": []byte("first-password")},
+	}
+	fakeKubeClient := kubernetesfake.NewSimpleClientset(secret)
+	kubeInformers := kubeinformers.NewSharedInformerFactory(fakeKubeClient, 0)
+	machineClientsSetter := &fakeMachineClientsSetter{}
+
+	controller := NewMachineClientWatcherController(
+		machineClientsSetter,
+		clock.NewFakeClock(time.Now()),
+		fakePinnipedClient,
+		pinnipedInformers.Config().V1alpha1().MachineClients(),
+		kubeInformers.Core().V1().Secrets(),
+		controllerlib.WithInformer,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pinnipedInformers.Start(ctx.Done())
+	kubeInformers.Start(ctx.Done())
+	controllerlib.TestRunSynchronously(t, controller)
+
+	syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+	require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+	require.Len(t, machineClientsSetter.machineClients, 1)
+	require.NoError(t, bcrypt.CompareHashAndPassword(machineClientsSetter.machineClients[0].Client.GetHashedSecret(), []byte("first-password")))
+
+	// Changing the Secret causes the new client secret to be hashed.
+	secret = secret.DeepCopy()
+	secret.ResourceVersion = "2"
+	secret.Data["clientSecret"] = []byte("second-password")
+	require.NoError(t, kubeInformers.Core().V1().Secrets().Informer().GetIndexer().Update(secret))
+
+	require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+	require.Len(t, machineClientsSetter.machineClients, 1)
+	require.NoError(t, bcrypt.CompareHashAndPassword(machineClientsSetter.machineClients[0].Client.GetHashedSecret(), []byte("second-password")))
+}
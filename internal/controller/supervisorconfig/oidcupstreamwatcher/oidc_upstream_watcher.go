@@ -35,6 +35,8 @@ import (
 	"go.pinniped.dev/internal/controller/supervisorconfig/upstreamwatchers"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/tlsprofile"
+	"go.pinniped.dev/internal/tracing"
 	"go.pinniped.dev/internal/upstreamoidc"
 )
 
@@ -62,9 +64,11 @@ const (
 	errOIDCFailureStatus = constable.Error("OIDCIdentityProvider has a failing condition")
 )
 
-// UpstreamOIDCIdentityProviderICache is a thread safe cache that holds a list of validated upstream OIDC IDP configurations.
+// UpstreamOIDCIdentityProviderICache is a thread safe cache that holds a list of validated upstream OIDC IDP configurations,
+// along with the names of the upstreams which failed validation.
 type UpstreamOIDCIdentityProviderICache interface {
 	SetOIDCIdentityProviders([]provider.UpstreamOIDCIdentityProviderI)
+	SetUnreadyOIDCIdentityProviders(names []string)
 }
 
 // lruValidatorCache caches the *oidc.Provider associated with a particular issuer/TLS configuration.
@@ -149,15 +153,18 @@ func (c *oidcWatcherController) Sync(ctx controllerlib.Context) error {
 
 	requeue := false
 	validatedUpstreams := make([]provider.UpstreamOIDCIdentityProviderI, 0, len(actualUpstreams))
+	var unreadyUpstreams []string
 	for _, upstream := range actualUpstreams {
 		valid := c.validateUpstream(ctx, upstream)
 		if valid == nil {
 			requeue = true
+			unreadyUpstreams = append(unreadyUpstreams, upstream.Name)
 		} else {
 			validatedUpstreams = append(validatedUpstreams, provider.UpstreamOIDCIdentityProviderI(valid))
 		}
 	}
 	c.cache.SetOIDCIdentityProviders(validatedUpstreams)
+	c.cache.SetUnreadyOIDCIdentityProviders(unreadyUpstreams)
 	if requeue {
 		return controllerlib.ErrSyntheticRequeue
 	}
@@ -266,10 +273,10 @@ func (c *oidcWatcherController) validateIssuer(ctx context.Context, upstream *v1
 
 		httpClient = &http.Client{
 			Timeout: time.Minute,
-			Transport: &http.Transport{
+			Transport: tracing.NewTransport("oidc upstream", &http.Transport{
 				Proxy:           http.ProxyFromEnvironment,
 				TLSClientConfig: tlsConfig,
-			},
+			}),
 		}
 
 		discoveredProvider, err = oidc.NewProvider(oidc.ClientContext(ctx, httpClient), upstream.Spec.Issuer)
@@ -348,12 +355,10 @@ func (c *oidcWatcherController) updateStatus(ctx context.Context, upstream *v1al
 }
 
 func getTLSConfig(upstream *v1alpha1.OIDCIdentityProvider) (*tls.Config, error) {
-	result := tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
+	result := tlsprofile.ClientConfig()
 
 	if upstream.Spec.TLS == nil || upstream.Spec.TLS.CertificateAuthorityData == "" {
-		return &result, nil
+		return result, nil
 	}
 
 	bundle, err := base64.StdEncoding.DecodeString(upstream.Spec.TLS.CertificateAuthorityData)
@@ -366,7 +371,7 @@ func getTLSConfig(upstream *v1alpha1.OIDCIdentityProvider) (*tls.Config, error)
 		return nil, fmt.Errorf("spec.certificateAuthorityData is invalid: %w", upstreamwatchers.ErrNoCertificates)
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 func computeScopes(additionalScopes []string) []string {
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -18,6 +19,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 
@@ -801,7 +803,9 @@ oidc: issuer did not match the issuer returned by provider, expected "` + testIs
 
 				// We always want to use the proxy from env on these clients, so although the following assertions
 				// are a little hacky, this is a cheap way to test that we are using it.
-				actualTransport, ok := actualIDP.Client.Transport.(*http.Transport)
+				tracingTransport, ok := actualIDP.Client.Transport.(utilnet.RoundTripperWrapper)
+				require.True(t, ok, "expected cached provider to have client with a tracing Transport")
+				actualTransport, ok := tracingTransport.WrappedRoundTripper().(*http.Transport)
 				require.True(t, ok, "expected cached provider to have client with Transport of type *http.Transport")
 				httpProxyFromEnvFunction := reflect.ValueOf(http.ProxyFromEnvironment).Pointer()
 				actualTransportProxyFunction := reflect.ValueOf(actualTransport.Proxy).Pointer()
@@ -817,6 +821,19 @@ oidc: issuer did not match the issuer returned by provider, expected "` + testIs
 			// Assert on the expected Status of the upstreams. Preprocess the upstreams a bit so that they're easier to assert against.
 			require.ElementsMatch(t, tt.wantResultingUpstreams, normalizeOIDCUpstreams(actualUpstreams.Items, now))
 
+			// The upstreams which failed validation should make the Supervisor unready.
+			var wantUnready []string
+			for _, upstream := range tt.wantResultingUpstreams {
+				if upstream.Status.Phase == v1alpha1.PhaseError {
+					wantUnready = append(wantUnready, fmt.Sprintf("OIDCIdentityProvider %q", upstream.Name))
+				}
+			}
+			if len(wantUnready) == 0 {
+				require.NoError(t, cache.CheckReady(nil))
+			} else {
+				require.EqualError(t, cache.CheckReady(nil), "upstream identity providers are not ready: "+strings.Join(wantUnready, ", "))
+			}
+
 			// Running the sync() a second time should be idempotent except for logs, and should return the same error.
 			// This also helps exercise code paths where the OIDC provider discovery hits cache.
 			if err := controllerlib.TestSync(t, controller, syncCtx); tt.wantErr != "" {
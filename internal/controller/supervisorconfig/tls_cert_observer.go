@@ -10,11 +10,17 @@ import (
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/errors"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	"go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/plog"
 )
@@ -22,6 +28,8 @@ import (
 type tlsCertObserverController struct {
 	issuerTLSCertSetter             IssuerTLSCertSetter
 	defaultTLSCertificateSecretName string
+	client                          pinnipedclientset.Interface
+	clock                           clock.Clock
 	federationDomainInformer        v1alpha1.FederationDomainInformer
 	secretInformer                  corev1informers.SecretInformer
 }
@@ -34,6 +42,8 @@ type IssuerTLSCertSetter interface {
 func NewTLSCertObserverController(
 	issuerTLSCertSetter IssuerTLSCertSetter,
 	defaultTLSCertificateSecretName string,
+	client pinnipedclientset.Interface,
+	clock clock.Clock,
 	secretInformer corev1informers.SecretInformer,
 	federationDomainInformer v1alpha1.FederationDomainInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
@@ -44,6 +54,8 @@ func NewTLSCertObserverController(
 			Syncer: &tlsCertObserverController{
 				issuerTLSCertSetter:             issuerTLSCertSetter,
 				defaultTLSCertificateSecretName: defaultTLSCertificateSecretName,
+				client:                          client,
+				clock:                           clock,
 				federationDomainInformer:        federationDomainInformer,
 				secretInformer:                  secretInformer,
 			},
@@ -72,18 +84,42 @@ func (c *tlsCertObserverController) Sync(ctx controllerlib.Context) error {
 	// can cause the map to need to be updated.
 	issuerHostToTLSCertMap := map[string]*tls.Certificate{}
 
+	var errs []error
 	for _, provider := range allProviders {
 		secretName := ""
 		if provider.Spec.TLS != nil {
 			secretName = provider.Spec.TLS.SecretName
 		}
+		var certFromSecret *tls.Certificate
+		var condition *configv1alpha1.Condition
+		if secretName == "" {
+			condition = &configv1alpha1.Condition{
+				Type:    typeTLSSecretLoaded,
+				Status:  configv1alpha1.ConditionTrue,
+				Reason:  reasonDefaultCertificateUsed,
+				Message: "no TLS secret is named in the spec, so the default TLS certificate is used",
+			}
+		} else if certFromSecret, err = c.certFromSecret(ns, secretName); err != nil {
+			condition = falseCondition(typeTLSSecretLoaded, reasonSecretNotLoaded, fmt.Sprintf("cannot load TLS certificate from Secret %q: %v", secretName, err))
+		} else {
+			condition = trueCondition(typeTLSSecretLoaded, fmt.Sprintf("the TLS certificate is loaded from Secret %q", secretName))
+		}
+		if err := conditionsutil.UpdateFederationDomainConditions(
+			ctx.Context,
+			c.client,
+			provider,
+			metav1.NewTime(c.clock.Now()),
+			condition,
+		); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status of FederationDomain %s/%s: %w", provider.Namespace, provider.Name, err))
+		}
+
 		issuerURL, err := url.Parse(provider.Spec.Issuer)
 		if err != nil {
 			plog.Debug("tlsCertObserverController Sync found an invalid issuer URL", "namespace", ns, "issuer", provider.Spec.Issuer)
 			continue
 		}
-		certFromSecret, err := c.certFromSecret(ns, secretName)
-		if err != nil {
+		if certFromSecret == nil {
 			continue
 		}
 		// Lowercase the host part of the URL because hostnames should be treated as case-insensitive.
@@ -100,7 +136,7 @@ func (c *tlsCertObserverController) Sync(ctx controllerlib.Context) error {
 		c.issuerTLSCertSetter.SetDefaultTLSCert(defaultCert)
 	}
 
-	return nil
+	return errors.NewAggregate(errs)
 }
 
 func (c *tlsCertObserverController) certFromSecret(ns string, secretName string) (*tls.Certificate, error) {
@@ -112,7 +148,7 @@ func (c *tlsCertObserverController) certFromSecret(ns string, secretName string)
 	certFromSecret, err := tls.X509KeyPair(tlsSecret.Data["tls.crt"], tlsSecret.Data["tls.key"])
 	if err != nil {
 		plog.Debug("tlsCertObserverController Sync found a TLS secret with Data in an unexpected format", "namespace", ns, "secretName", secretName)
-		return nil, err
+		return nil, fmt.Errorf("secret does not contain a valid certificate and key: %w", err)
 	}
 	return &certFromSecret, nil
 }
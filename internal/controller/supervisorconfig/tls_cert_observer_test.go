@@ -6,17 +6,22 @@ package supervisorconfig
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"io/ioutil"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
@@ -42,6 +47,8 @@ func TestTLSCertObserverControllerInformerFilters(t *testing.T) {
 			_ = NewTLSCertObserverController(
 				nil,
 				"", // don't care about the secret name for this test
+				nil,
+				nil,
 				secretsInformer,
 				federationDomainInformer,
 				observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
@@ -132,6 +139,7 @@ func TestTLSCertObserverControllerSync(t *testing.T) {
 			r                       *require.Assertions
 			subject                 controllerlib.Controller
 			pinnipedInformerClient  *pinnipedfake.Clientset
+			pinnipedAPIClient       *pinnipedfake.Clientset
 			kubeInformerClient      *kubernetesfake.Clientset
 			pinnipedInformers       pinnipedinformers.SharedInformerFactory
 			kubeInformers           kubeinformers.SharedInformerFactory
@@ -139,6 +147,7 @@ func TestTLSCertObserverControllerSync(t *testing.T) {
 			cancelContextCancelFunc context.CancelFunc
 			syncContext             *controllerlib.Context
 			issuerTLSCertSetter     *fakeIssuerTLSCertSetter
+			frozenNow               time.Time
 		)
 
 		// Defer starting the informers until the last possible moment so that the
@@ -148,6 +157,8 @@ func TestTLSCertObserverControllerSync(t *testing.T) {
 			subject = NewTLSCertObserverController(
 				issuerTLSCertSetter,
 				defaultTLSSecretName,
+				pinnipedAPIClient,
+				clock.NewFakeClock(frozenNow),
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().FederationDomains(),
 				controllerlib.WithInformer,
@@ -184,7 +195,9 @@ func TestTLSCertObserverControllerSync(t *testing.T) {
 			kubeInformers = kubeinformers.NewSharedInformerFactory(kubeInformerClient, 0)
 			pinnipedInformerClient = pinnipedfake.NewSimpleClientset()
 			pinnipedInformers = pinnipedinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
+			pinnipedAPIClient = pinnipedfake.NewSimpleClientset()
 			issuerTLSCertSetter = &fakeIssuerTLSCertSetter{}
+			frozenNow = time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
 
 			unrelatedSecret := &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
@@ -315,12 +328,19 @@ func TestTLSCertObserverControllerSync(t *testing.T) {
 					Data:       map[string][]byte{"junk": nil},
 				}
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithoutSecret1))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithoutSecret1))
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithoutSecret2))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithoutSecret2))
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithBadSecret))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithBadSecret))
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithBadIssuer))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithBadIssuer))
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithGoodSecret1))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithGoodSecret1))
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithGoodSecret2))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithGoodSecret2))
 				r.NoError(pinnipedInformerClient.Tracker().Add(federationDomainWithIPv6Issuer))
+				r.NoError(pinnipedAPIClient.Tracker().Add(federationDomainWithIPv6Issuer))
 				r.NoError(kubeInformerClient.Tracker().Add(goodTLSSecret1))
 				r.NoError(kubeInformerClient.Tracker().Add(goodTLSSecret2))
 				r.NoError(kubeInformerClient.Tracker().Add(badTLSSecret))
@@ -352,6 +372,87 @@ func TestTLSCertObserverControllerSync(t *testing.T) {
 				r.Equal(expectedCertificate1, *actualCertificate3)
 			})
 
+			it("updates the TLSSecretLoaded condition of each FederationDomain", func() {
+				startInformersAndController()
+				r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+				tlsSecretLoadedCondition := func(name string) v1alpha1.Condition {
+					federationDomain, err := pinnipedAPIClient.ConfigV1alpha1().FederationDomains(installedInNamespace).Get(cancelContext, name, metav1.GetOptions{})
+					r.NoError(err)
+					r.Len(federationDomain.Status.Conditions, 1)
+					return federationDomain.Status.Conditions[0]
+				}
+				r.Equal(v1alpha1.Condition{
+					Type:               "TLSSecretLoaded",
+					Status:             v1alpha1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(frozenNow),
+					Reason:             "DefaultCertificateUsed",
+					Message:            "no TLS secret is named in the spec, so the default TLS certificate is used",
+				}, tlsSecretLoadedCondition("no-secret-federationdomain1"))
+				r.Equal(v1alpha1.Condition{
+					Type:               "TLSSecretLoaded",
+					Status:             v1alpha1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(frozenNow),
+					Reason:             "DefaultCertificateUsed",
+					Message:            "no TLS secret is named in the spec, so the default TLS certificate is used",
+				}, tlsSecretLoadedCondition("no-secret-federationdomain2"))
+				r.Equal(v1alpha1.Condition{
+					Type:               "TLSSecretLoaded",
+					Status:             v1alpha1.ConditionFalse,
+					LastTransitionTime: metav1.NewTime(frozenNow),
+					Reason:             "SecretNotLoaded",
+					Message:            `cannot load TLS certificate from Secret "bad-tls-secret-name": secret does not contain a valid certificate and key: tls: failed to find any PEM data in certificate input`,
+				}, tlsSecretLoadedCondition("bad-secret-federationdomain"))
+				r.Equal(v1alpha1.Condition{
+					Type:               "TLSSecretLoaded",
+					Status:             v1alpha1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(frozenNow),
+					Reason:             "Success",
+					Message:            `the TLS certificate is loaded from Secret "good-tls-secret-name2"`,
+				}, tlsSecretLoadedCondition("good-secret-federationdomain2"))
+			})
+
+			when("the TLS Secret of a FederationDomain does not exist", func() {
+				it.Before(func() {
+					r.NoError(kubeInformerClient.Tracker().Delete(
+						corev1.SchemeGroupVersion.WithResource("secrets"), installedInNamespace, "good-tls-secret-name2",
+					))
+				})
+
+				it("updates the TLSSecretLoaded condition to false", func() {
+					startInformersAndController()
+					r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+					federationDomain, err := pinnipedAPIClient.ConfigV1alpha1().FederationDomains(installedInNamespace).Get(cancelContext, "good-secret-federationdomain2", metav1.GetOptions{})
+					r.NoError(err)
+					r.Equal([]v1alpha1.Condition{{
+						Type:               "TLSSecretLoaded",
+						Status:             v1alpha1.ConditionFalse,
+						LastTransitionTime: metav1.NewTime(frozenNow),
+						Reason:             "SecretNotLoaded",
+						Message:            `cannot load TLS certificate from Secret "good-tls-secret-name2": secret "good-tls-secret-name2" not found`,
+					}}, federationDomain.Status.Conditions)
+				})
+			})
+
+			when("updating the status of the FederationDomains fails", func() {
+				it.Before(func() {
+					pinnipedAPIClient.PrependReactor("update", "federationdomains", func(_ coretesting.Action) (bool, runtime.Object, error) {
+						return true, nil, errors.New("some update error")
+					})
+				})
+
+				it("still updates the issuerTLSCertSetter's map and returns an error", func() {
+					startInformersAndController()
+					err := controllerlib.TestSync(t, subject, *syncContext)
+					r.Error(err)
+					r.Contains(err.Error(), "could not update status of FederationDomain some-namespace/good-secret-federationdomain1: some update error")
+
+					r.True(issuerTLSCertSetter.setIssuerHostToTLSCertMapWasCalled)
+					r.Len(issuerTLSCertSetter.issuerHostToTLSCertMapReceived, 3)
+				})
+			})
+
 			when("there is also a default TLS cert secret with the configured default TLS cert secret name", func() {
 				var (
 					expectedDefaultCertificate tls.Certificate
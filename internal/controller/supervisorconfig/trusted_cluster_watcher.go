@@ -0,0 +1,215 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
+)
+
+// Discovery of a cluster's issuer is redone this often, so changes to the issuer's configuration are noticed.
+// This does not affect the cache of the issuer's JWKS, which is refreshed whenever a token has an unknown key.
+const trustedClusterDiscoveryCacheTTL = 15 * time.Minute
+
+// TrustedClustersSetter can be notified of all valid clusters whose ServiceAccount tokens may be exchanged.
+// Implementations of this type should be thread-safe to support calls from multiple goroutines.
+type TrustedClustersSetter interface {
+	SetTrustedClusters(trustedClusters []*trustedcluster.TrustedCluster)
+}
+
+// DiscoverFunc performs OIDC discovery of an issuer using the given HTTP client.
+type DiscoverFunc func(ctx context.Context, httpClient *http.Client, issuer string) (*oidc.Provider, error)
+
+type trustedClusterWatcherController struct {
+	trustedClustersSetter  TrustedClustersSetter
+	clock                  clock.Clock
+	client                 pinnipedclientset.Interface
+	trustedClusterInformer configinformers.TrustedClusterInformer
+	discover               DiscoverFunc
+	discoveryCache         *cache.Expiring
+}
+
+// NewTrustedClusterWatcherController creates a controllerlib.Controller that watches TrustedCluster objects,
+// discovers their issuers, and notifies a TrustedClustersSetter of the valid ones.
+func NewTrustedClusterWatcherController(
+	trustedClustersSetter TrustedClustersSetter,
+	clock clock.Clock,
+	client pinnipedclientset.Interface,
+	trustedClusterInformer configinformers.TrustedClusterInformer,
+	discover DiscoverFunc,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "TrustedClusterWatcherController",
+			Syncer: &trustedClusterWatcherController{
+				trustedClustersSetter:  trustedClustersSetter,
+				clock:                  clock,
+				client:                 client,
+				trustedClusterInformer: trustedClusterInformer,
+				discover:               discover,
+				discoveryCache:         cache.NewExpiring(),
+			},
+		},
+		withInformer(
+			trustedClusterInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// DiscoverIssuer is the DiscoverFunc which is used outside of tests.
+func DiscoverIssuer(ctx context.Context, httpClient *http.Client, issuer string) (*oidc.Provider, error) {
+	return oidc.NewProvider(oidc.ClientContext(ctx, httpClient), issuer)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *trustedClusterWatcherController) Sync(ctx controllerlib.Context) error {
+	trustedClusters, err := c.trustedClusterInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	// Count how many times each issuer is used, to complain about duplicates. The issuer of a token decides which
+	// cluster verifies it, so neither of the duplicates can be used.
+	issuerCounts := make(map[string]int)
+	for _, trustedCluster := range trustedClusters {
+		issuerCounts[trustedCluster.Spec.Issuer]++
+	}
+
+	var errs []error
+
+	validTrustedClusters := make([]*trustedcluster.TrustedCluster, 0, len(trustedClusters))
+	for _, trustedCluster := range trustedClusters {
+		status, message := configv1alpha1.SuccessTrustedClusterStatusCondition, "Verified the issuer configuration"
+
+		var valid *trustedcluster.TrustedCluster
+		if issuerCounts[trustedCluster.Spec.Issuer] > 1 {
+			status, message = configv1alpha1.DuplicateTrustedClusterStatusCondition, "Duplicate issuer: "+trustedCluster.Spec.Issuer
+		} else if httpClient, err := trustedClusterHTTPClient(trustedCluster.Spec.TLS); err != nil {
+			status, message = configv1alpha1.InvalidTrustedClusterStatusCondition, "Invalid: "+err.Error()
+		} else if provider, err := c.discoverIssuer(ctx.Context, httpClient, &trustedCluster.Spec); err != nil {
+			status, message = configv1alpha1.ErrorTrustedClusterStatusCondition, err.Error()
+			// Try the discovery again later.
+			errs = append(errs, fmt.Errorf("could not discover issuer of TrustedCluster %s/%s: %w", trustedCluster.Namespace, trustedCluster.Name, err))
+		} else {
+			valid = &trustedcluster.TrustedCluster{
+				Name:     trustedCluster.Name,
+				Issuer:   trustedCluster.Spec.Issuer,
+				Verifier: provider.Verifier(&oidc.Config{ClientID: trustedCluster.Spec.Audience}),
+			}
+		}
+
+		if err := c.updateStatus(ctx.Context, trustedCluster.Namespace, trustedCluster.Name, status, message); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status: %w", err))
+			continue
+		}
+
+		if valid != nil {
+			validTrustedClusters = append(validTrustedClusters, valid)
+		}
+	}
+
+	c.trustedClustersSetter.SetTrustedClusters(validTrustedClusters)
+
+	return errors.NewAggregate(errs)
+}
+
+func (c *trustedClusterWatcherController) discoverIssuer(ctx context.Context, httpClient *http.Client, spec *configv1alpha1.TrustedClusterSpec) (*oidc.Provider, error) {
+	var key struct{ issuer, caBundle string }
+	key.issuer = spec.Issuer
+	if spec.TLS != nil {
+		key.caBundle = spec.TLS.CertificateAuthorityData
+	}
+
+	if result, ok := c.discoveryCache.Get(key); ok {
+		return result.(*oidc.Provider), nil
+	}
+
+	provider, err := c.discover(ctx, httpClient, spec.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform OIDC discovery against %q: %w", spec.Issuer, err)
+	}
+	c.discoveryCache.Set(key, provider, trustedClusterDiscoveryCacheTTL)
+	return provider, nil
+}
+
+func trustedClusterHTTPClient(tlsSpec *configv1alpha1.TrustedClusterTLSSpec) (*http.Client, error) {
+	tlsConfig := tlsprofile.ClientConfig()
+
+	if tlsSpec != nil && tlsSpec.CertificateAuthorityData != "" {
+		bundle, err := base64.StdEncoding.DecodeString(tlsSpec.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("spec.tls.certificateAuthorityData is invalid: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("spec.tls.certificateAuthorityData is invalid: no certificates found")
+		}
+	}
+
+	return &http.Client{
+		Timeout: time.Minute,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+func (c *trustedClusterWatcherController) updateStatus(
+	ctx context.Context,
+	namespace, name string,
+	status configv1alpha1.TrustedClusterStatusCondition,
+	message string,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		trustedCluster, err := c.client.ConfigV1alpha1().TrustedClusters(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get failed: %w", err)
+		}
+
+		if trustedCluster.Status.Status == status && trustedCluster.Status.Message == message {
+			return nil
+		}
+
+		plog.Debug(
+			"attempting status update",
+			"trustedcluster",
+			klog.KRef(namespace, name),
+			"status",
+			status,
+			"message",
+			message,
+		)
+		trustedCluster.Status.Status = status
+		trustedCluster.Status.Message = message
+		trustedCluster.Status.LastUpdateTime = timePtr(metav1.NewTime(c.clock.Now()))
+		_, err = c.client.ConfigV1alpha1().TrustedClusters(namespace).UpdateStatus(ctx, trustedCluster, metav1.UpdateOptions{})
+		return err
+	})
+}
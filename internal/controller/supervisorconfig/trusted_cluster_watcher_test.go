@@ -0,0 +1,207 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
+)
+
+type fakeTrustedClustersSetter struct {
+	mutex           sync.Mutex
+	trustedClusters []*trustedcluster.TrustedCluster
+}
+
+func (f *fakeTrustedClustersSetter) SetTrustedClusters(trustedClusters []*trustedcluster.TrustedCluster) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.trustedClusters = trustedClusters
+}
+
+func TestTrustedClusterWatcherControllerSync(t *testing.T) {
+	t.Parallel()
+
+	const testNamespace = "test-namespace"
+
+	ca, err := certauthority.New("Test CA", time.Hour)
+	require.NoError(t, err)
+
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	frozenMetav1Now := metav1.NewTime(now)
+
+	newTrustedCluster := func(name string, spec v1alpha1.TrustedClusterSpec) *v1alpha1.TrustedCluster {
+		return &v1alpha1.TrustedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Spec:       spec,
+		}
+	}
+	status := func(condition v1alpha1.TrustedClusterStatusCondition, message string) v1alpha1.TrustedClusterStatus {
+		return v1alpha1.TrustedClusterStatus{Status: condition, Message: message, LastUpdateTime: &frozenMetav1Now}
+	}
+
+	tests := []struct {
+		name               string
+		inputObjects       []runtime.Object
+		discoveryErrors    map[string]error
+		wantTrustedNames   []string
+		wantStatuses       map[string]v1alpha1.TrustedClusterStatus
+		wantErr            string
+		wantDiscoveryCalls int
+	}{
+		{
+			name:             "no TrustedClusters",
+			wantTrustedNames: []string{},
+			wantStatuses:     map[string]v1alpha1.TrustedClusterStatus{},
+		},
+		{
+			name: "valid TrustedClusters",
+			inputObjects: []runtime.Object{
+				newTrustedCluster("a", v1alpha1.TrustedClusterSpec{Issuer: "https://a.example.com", Audience: "pinniped"}),
+				newTrustedCluster("b", v1alpha1.TrustedClusterSpec{
+					Issuer:   "https://b.example.com",
+					Audience: "pinniped",
+					TLS:      &v1alpha1.TrustedClusterTLSSpec{CertificateAuthorityData: base64.StdEncoding.EncodeToString(ca.Bundle())},
+				}),
+			},
+			wantTrustedNames: []string{"a", "b"},
+			wantStatuses: map[string]v1alpha1.TrustedClusterStatus{
+				"a": status(v1alpha1.SuccessTrustedClusterStatusCondition, "Verified the issuer configuration"),
+				"b": status(v1alpha1.SuccessTrustedClusterStatusCondition, "Verified the issuer configuration"),
+			},
+			wantDiscoveryCalls: 2,
+		},
+		{
+			name: "duplicate issuers are not used",
+			inputObjects: []runtime.Object{
+				newTrustedCluster("a", v1alpha1.TrustedClusterSpec{Issuer: "https://a.example.com", Audience: "pinniped"}),
+				newTrustedCluster("b", v1alpha1.TrustedClusterSpec{Issuer: "https://a.example.com", Audience: "other"}),
+				newTrustedCluster("c", v1alpha1.TrustedClusterSpec{Issuer: "https://c.example.com", Audience: "pinniped"}),
+			},
+			wantTrustedNames: []string{"c"},
+			wantStatuses: map[string]v1alpha1.TrustedClusterStatus{
+				"a": status(v1alpha1.DuplicateTrustedClusterStatusCondition, "Duplicate issuer: https://a.example.com"),
+				"b": status(v1alpha1.DuplicateTrustedClusterStatusCondition, "Duplicate issuer: https://a.example.com"),
+				"c": status(v1alpha1.SuccessTrustedClusterStatusCondition, "Verified the issuer configuration"),
+			},
+			wantDiscoveryCalls: 1,
+		},
+		{
+			name: "invalid CA bundles",
+			inputObjects: []runtime.Object{
+				newTrustedCluster("not-base64", v1alpha1.TrustedClusterSpec{
+					Issuer:   "https://a.example.com",
+					Audience: "pinniped",
+					TLS:      &v1alpha1.TrustedClusterTLSSpec{CertificateAuthorityData: "!!!"},
+				}),
+				newTrustedCluster("no-certs", v1alpha1.TrustedClusterSpec{
+					Issuer:   "https://b.example.com",
+					Audience: "pinniped",
+					TLS:      &v1alpha1.TrustedClusterTLSSpec{CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("not a cert"))},
+				}),
+			},
+			wantTrustedNames: []string{},
+			wantStatuses: map[string]v1alpha1.TrustedClusterStatus{
+				"not-base64": status(v1alpha1.InvalidTrustedClusterStatusCondition, "Invalid: spec.tls.certificateAuthorityData is invalid: illegal base64 data at input byte 0"),
+				"no-certs":   status(v1alpha1.InvalidTrustedClusterStatusCondition, "Invalid: spec.tls.certificateAuthorityData is invalid: no certificates found"),
+			},
+		},
+		{
+			name: "discovery failure",
+			inputObjects: []runtime.Object{
+				newTrustedCluster("a", v1alpha1.TrustedClusterSpec{Issuer: "https://a.example.com", Audience: "pinniped"}),
+				newTrustedCluster("b", v1alpha1.TrustedClusterSpec{Issuer: "https://b.example.com", Audience: "pinniped"}),
+			},
+			discoveryErrors:  map[string]error{"https://a.example.com": errors.New("some discovery error")},
+			wantTrustedNames: []string{"b"},
+			wantStatuses: map[string]v1alpha1.TrustedClusterStatus{
+				"a": status(v1alpha1.ErrorTrustedClusterStatusCondition, `failed to perform OIDC discovery against "https://a.example.com": some discovery error`),
+				"b": status(v1alpha1.SuccessTrustedClusterStatusCondition, "Verified the issuer configuration"),
+			},
+			wantErr:            `could not discover issuer of TrustedCluster test-namespace/a: failed to perform OIDC discovery against "https://a.example.com": some discovery error`,
+			wantDiscoveryCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakePinnipedClient := pinnipedfake.NewSimpleClientset(tt.inputObjects...)
+			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(fakePinnipedClient, 0)
+			trustedClustersSetter := &fakeTrustedClustersSetter{}
+
+			discoveryCalls := 0
+			discover := func(ctx context.Context, httpClient *http.Client, issuer string) (*oidc.Provider, error) {
+				discoveryCalls++
+				require.NotNil(t, httpClient)
+				if err := tt.discoveryErrors[issuer]; err != nil {
+					return nil, err
+				}
+				return &oidc.Provider{}, nil
+			}
+
+			controller := NewTrustedClusterWatcherController(
+				trustedClustersSetter,
+				clock.NewFakeClock(now),
+				fakePinnipedClient,
+				pinnipedInformers.Config().V1alpha1().TrustedClusters(),
+				discover,
+				controllerlib.WithInformer,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			pinnipedInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+			if err := controllerlib.TestSync(t, controller, syncCtx); tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantDiscoveryCalls, discoveryCalls)
+
+			actualTrustedNames := []string{}
+			for _, trustedCluster := range trustedClustersSetter.trustedClusters {
+				require.NotNil(t, trustedCluster.Verifier)
+				actualTrustedNames = append(actualTrustedNames, trustedCluster.Name)
+			}
+			sort.Strings(actualTrustedNames)
+			require.Equal(t, tt.wantTrustedNames, actualTrustedNames)
+
+			actual, err := fakePinnipedClient.ConfigV1alpha1().TrustedClusters(testNamespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			actualStatuses := map[string]v1alpha1.TrustedClusterStatus{}
+			for _, trustedCluster := range actual.Items {
+				actualStatuses[trustedCluster.Name] = trustedCluster.Status
+			}
+			require.Equal(t, tt.wantStatuses, actualStatuses)
+
+			// A second sync uses the cached discovery results.
+			_ = controllerlib.TestSync(t, controller, syncCtx)
+			require.Equal(t, tt.wantDiscoveryCalls+len(tt.discoveryErrors), discoveryCalls)
+		})
+	}
+}
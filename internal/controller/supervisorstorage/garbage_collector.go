@@ -4,6 +4,7 @@
 package supervisorstorage
 
 import (
+	"context"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -13,9 +14,12 @@ import (
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 
+	"go.pinniped.dev/internal/auditlog"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/fositestorage/sessions"
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -86,6 +90,7 @@ func (c *garbageCollectorController) Sync(ctx controllerlib.Context) error {
 	if err != nil {
 		return err
 	}
+	countStorageSecrets(listOfSecrets)
 
 	for i := range listOfSecrets {
 		secret := listOfSecrets[i]
@@ -108,12 +113,35 @@ func (c *garbageCollectorController) Sync(ctx controllerlib.Context) error {
 				continue
 			}
 			plog.Info("storage garbage collector deleted resource", logKV(secret))
+			garbageCollectorDeletions.WithLabelValues(storageType(secret)).Inc()
+			recordSessionGarbageCollected(ctx.Context, secret)
 		}
 	}
 
 	return nil
 }
 
+// recordSessionGarbageCollected audits the deletion of the stored tokens of a downstream session.
+func recordSessionGarbageCollected(ctx context.Context, secret *v1.Secret) {
+	requestID := secret.Labels[fositestorage.StorageRequestIDLabelName]
+	if requestID == "" {
+		return // not a Secret of a session
+	}
+	event := auditlog.Event{
+		Type:        auditlog.SessionGarbageCollected,
+		RequestID:   requestID,
+		StorageType: secret.Labels[crud.SecretLabelKey],
+	}
+	if session, err := sessions.FromSecret(secret); err == nil {
+		event.ClientID = session.ClientID
+		event.UpstreamName = session.ProviderName
+		event.UpstreamType = string(session.ProviderType)
+		event.Username = session.Username
+		event.Groups = session.Groups
+	}
+	auditlog.Record(ctx, event)
+}
+
 func logKV(secret *v1.Secret) []interface{} {
 	return []interface{}{
 		"secretName", secret.Name,
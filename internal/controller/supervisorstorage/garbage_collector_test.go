@@ -4,6 +4,7 @@
 package supervisorstorage
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"testing"
@@ -20,7 +21,9 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
+	metricstestutil "k8s.io/component-base/metrics/testutil"
 
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/testutil"
 )
@@ -244,6 +247,61 @@ func TestGarbageCollectorControllerSync(t *testing.T) {
 			})
 		})
 
+		when("an expired secret belongs to a downstream session", func() {
+			var auditLog *bytes.Buffer
+
+			it.Before(func() {
+				auditLog = &bytes.Buffer{}
+				auditLogger, err := auditlog.New(auditLog, nil, func() time.Time { return frozenNow })
+				r.NoError(err)
+				auditlog.SetGlobalLogger(auditLogger)
+
+				sessionSecret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "expired session secret",
+						Namespace: installedInNamespace,
+						Labels: map[string]string{
+							"storage.pinniped.dev/type":       "refresh-token",
+							"storage.pinniped.dev/request-id": "some-request-id",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": frozenNow.Add(-time.Second).Format(time.RFC3339),
+						},
+					},
+				}
+				r.NoError(kubeInformerClient.Tracker().Add(sessionSecret))
+				r.NoError(kubeClient.Tracker().Add(sessionSecret))
+			})
+
+			it.After(func() {
+				auditlog.SetGlobalLogger(nil)
+			})
+
+			it("records the garbage collection of the session in the audit log and the metrics", func() {
+				deletionsBefore, err := metricstestutil.GetCounterMetricValue(garbageCollectorDeletions.WithLabelValues("refresh-token"))
+				r.NoError(err)
+
+				startInformersAndController()
+				r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+				deletionsAfter, err := metricstestutil.GetCounterMetricValue(garbageCollectorDeletions.WithLabelValues("refresh-token"))
+				r.NoError(err)
+				r.Equal(deletionsBefore+1, deletionsAfter)
+				refreshTokenSecrets, err := metricstestutil.GetGaugeMetricValue(storageSecrets.WithLabelValues("refresh-token"))
+				r.NoError(err)
+				r.Equal(float64(1), refreshTokenSecrets)
+				authcodeSecrets, err := metricstestutil.GetGaugeMetricValue(storageSecrets.WithLabelValues("authcode"))
+				r.NoError(err)
+				r.Equal(float64(0), authcodeSecrets)
+
+				r.Equal(
+					[]kubetesting.Action{kubetesting.NewDeleteAction(secretsGVR, installedInNamespace, "expired session secret")},
+					kubeClient.Actions(),
+				)
+				r.Equal(`{"time":"`+frozenNow.Format(time.RFC3339Nano)+`","event":"SessionGarbageCollected","requestID":"some-request-id","storageType":"refresh-token"}`+"\n", auditLog.String())
+			})
+		})
+
 		when("very little time has passed since the previous sync call", func() {
 			it.Before(func() {
 				// Add a secret that will expire in 20 seconds.
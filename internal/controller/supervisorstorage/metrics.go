@@ -0,0 +1,87 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/fositestorage/accesstoken"
+	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/openidconnect"
+	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
+)
+
+// otherStorageType is the storage_type label of Secrets which are not stored by the Supervisor itself.
+const otherStorageType = "other"
+
+//nolint:gochecknoglobals
+var (
+	// knownStorageTypes bounds the values of the storage_type labels, since anyone who may create Secrets in the
+	// Supervisor's namespace could label them with any type.
+	knownStorageTypes = map[string]bool{
+		accesstoken.TypeLabelValue:                true,
+		authorizationcode.TypeLabelValue:          true,
+		openidconnect.TypeLabelValue:              true,
+		pkce.TypeLabelValue:                       true,
+		pushedauthorizationrequest.TypeLabelValue: true,
+		refreshtoken.TypeLabelValue:               true,
+		loginlimiter.TypeLabelValue:               true,
+	}
+
+	storageSecrets = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "storage_secrets",
+			Help:           "Number of Secrets which store sessions and failed logins, by storage type, as of the most recent garbage collection sweep.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"storage_type"},
+	)
+
+	garbageCollectorDeletions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "garbage_collector_deletions_total",
+			Help:           "Number of expired Secrets which the garbage collector deleted, by storage type.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"storage_type"},
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(storageSecrets, garbageCollectorDeletions)
+}
+
+func storageType(secret *v1.Secret) string {
+	if storageType := secret.Labels[crud.SecretLabelKey]; knownStorageTypes[storageType] {
+		return storageType
+	}
+	return otherStorageType
+}
+
+// countStorageSecrets sets the storage_secrets gauge to the number of Secrets of each storage type.
+func countStorageSecrets(secrets []*v1.Secret) {
+	counts := map[string]int{otherStorageType: 0}
+	for storageType := range knownStorageTypes {
+		counts[storageType] = 0
+	}
+	for _, secret := range secrets {
+		if _, ok := secret.Labels[crud.SecretLabelKey]; ok {
+			counts[storageType(secret)]++
+		}
+	}
+	for storageType, count := range counts {
+		storageSecrets.WithLabelValues(storageType).Set(float64(count))
+	}
+}
@@ -194,10 +194,20 @@ func (c *controller) processNextWorkItem(ctx context.Context) {
 		Recorder: c.recorder,
 	}
 
+	start := time.Now()
 	err := c.sync(syncCtx)
+	observeSync(c.Name(), time.Since(start), err)
 	c.handleKey(key, err)
 }
 
+func observeSync(name string, duration time.Duration, err error) {
+	syncs.WithLabelValues(name).Inc()
+	syncDuration.WithLabelValues(name).Observe(duration.Seconds())
+	if err != nil && !errors.Is(err, ErrSyntheticRequeue) {
+		syncErrors.WithLabelValues(name).Inc()
+	}
+}
+
 func (c *controller) handleKey(key Key, err error) {
 	if err == nil {
 		c.queue.Forget(key)
@@ -0,0 +1,51 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllerlib
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var (
+	syncs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "controller",
+			Name:           "syncs_total",
+			Help:           "Number of times that each controller synced a key.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+
+	syncErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "controller",
+			Name:           "sync_errors_total",
+			Help:           "Number of syncs of each controller which returned an error, not counting synthetic requeues.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+
+	syncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "controller",
+			Name:           "sync_duration_seconds",
+			Help:           "How long the syncs of each controller took.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 8),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(syncs, syncErrors, syncDuration)
+}
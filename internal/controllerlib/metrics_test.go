@@ -0,0 +1,35 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllerlib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestObserveSync(t *testing.T) {
+	const name = "test-observe-sync-controller"
+
+	observeSync(name, time.Millisecond, nil)
+	observeSync(name, time.Millisecond, errors.New("some error"))
+	observeSync(name, time.Millisecond, ErrSyntheticRequeue)
+	observeSync(name, time.Millisecond, fmt.Errorf("wrapped: %w", ErrSyntheticRequeue))
+
+	count, err := testutil.GetCounterMetricValue(syncs.WithLabelValues(name))
+	require.NoError(t, err)
+	require.Equal(t, float64(4), count)
+
+	errorCount, err := testutil.GetCounterMetricValue(syncErrors.WithLabelValues(name))
+	require.NoError(t, err)
+	require.Equal(t, float64(1), errorCount)
+
+	durationCount, err := testutil.GetHistogramMetricCount(syncDuration.WithLabelValues(name))
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), durationCount)
+}
@@ -13,12 +13,15 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/tracing"
 )
 
 //nolint:gosec // ignore lint warnings that these are credentials
@@ -45,6 +48,7 @@ type Storage interface {
 	Update(ctx context.Context, signature, resourceVersion string, data JSON) (newResourceVersion string, err error)
 	Delete(ctx context.Context, signature string) error
 	DeleteByLabel(ctx context.Context, labelName string, labelValue string) error
+	UpdateData(ctx context.Context, signature string, data JSON, update func() bool) error
 }
 
 type JSON interface{} // document that we need valid JSON types
@@ -69,7 +73,10 @@ type secretsStorage struct {
 	lifetime      time.Duration
 }
 
-func (s *secretsStorage) Create(ctx context.Context, signature string, data JSON, additionalLabels map[string]string) (string, error) {
+func (s *secretsStorage) Create(ctx context.Context, signature string, data JSON, additionalLabels map[string]string) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "Create")
+	defer func() { tracing.End(span, err) }()
+
 	secret, err := s.toSecret(signature, "", data, additionalLabels)
 	if err != nil {
 		return "", err
@@ -81,7 +88,10 @@ func (s *secretsStorage) Create(ctx context.Context, signature string, data JSON
 	return secret.ResourceVersion, nil
 }
 
-func (s *secretsStorage) Get(ctx context.Context, signature string, data JSON) (string, error) {
+func (s *secretsStorage) Get(ctx context.Context, signature string, data JSON) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "Get")
+	defer func() { tracing.End(span, err) }()
+
 	secret, err := s.secrets.Get(ctx, s.getName(signature), metav1.GetOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to get %s for signature %s: %w", s.resource, signature, err)
@@ -95,6 +105,19 @@ func (s *secretsStorage) Get(ctx context.Context, signature string, data JSON) (
 	return secret.ResourceVersion, nil
 }
 
+// FromSecret is similar to Storage.Get, but it can be used when you already have a copy of the Secret,
+// e.g. from a list call. The resource parameter must match the value used to create the Secret.
+func FromSecret(resource string, secret *corev1.Secret, data JSON) error {
+	s := New(resource, nil, nil, 0).(*secretsStorage)
+	if err := s.validateSecret(secret); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(secret.Data[secretDataKey], data); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
 func (s *secretsStorage) validateSecret(secret *corev1.Secret) error {
 	if secret.Type != s.secretType {
 		return fmt.Errorf("%w: %s must equal %s", ErrSecretTypeMismatch, secret.Type, s.secretType)
@@ -108,7 +131,10 @@ func (s *secretsStorage) validateSecret(secret *corev1.Secret) error {
 	return nil
 }
 
-func (s *secretsStorage) Update(ctx context.Context, signature, resourceVersion string, data JSON) (string, error) {
+func (s *secretsStorage) Update(ctx context.Context, signature, resourceVersion string, data JSON) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "Update")
+	defer func() { tracing.End(span, err) }()
+
 	secret, err := s.toSecret(signature, resourceVersion, data, nil)
 	if err != nil {
 		return "", err
@@ -120,14 +146,20 @@ func (s *secretsStorage) Update(ctx context.Context, signature, resourceVersion
 	return secret.ResourceVersion, nil
 }
 
-func (s *secretsStorage) Delete(ctx context.Context, signature string) error {
+func (s *secretsStorage) Delete(ctx context.Context, signature string) (err error) {
+	ctx, span := s.startSpan(ctx, "Delete")
+	defer func() { tracing.End(span, err) }()
+
 	if err := s.secrets.Delete(ctx, s.getName(signature), metav1.DeleteOptions{}); err != nil {
 		return fmt.Errorf("failed to delete %s for signature %s: %w", s.resource, signature, err)
 	}
 	return nil
 }
 
-func (s *secretsStorage) DeleteByLabel(ctx context.Context, labelName string, labelValue string) error {
+func (s *secretsStorage) DeleteByLabel(ctx context.Context, labelName string, labelValue string) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteByLabel")
+	defer func() { tracing.End(span, err) }()
+
 	list, err := s.secrets.List(ctx, metav1.ListOptions{
 		LabelSelector: labels.Set{
 			SecretLabelKey: s.resource,
@@ -150,6 +182,43 @@ func (s *secretsStorage) DeleteByLabel(ctx context.Context, labelName string, la
 	return nil
 }
 
+// UpdateData decodes the data of the Secret of the signature into data and calls update, which may change data.
+// The Secret is saved when update returns true. Unlike Update, this keeps the existing labels and lifetime of the
+// Secret. The update fails with a conflict error when the Secret was changed in the meantime.
+func (s *secretsStorage) UpdateData(ctx context.Context, signature string, data JSON, update func() bool) (err error) {
+	ctx, span := s.startSpan(ctx, "UpdateData")
+	defer func() { tracing.End(span, err) }()
+
+	secret, err := s.secrets.Get(ctx, s.getName(signature), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s for signature %s: %w", s.resource, signature, err)
+	}
+	if err := s.validateSecret(secret); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(secret.Data[secretDataKey], data); err != nil {
+		return fmt.Errorf("failed to decode %s for signature %s: %w", s.resource, signature, err)
+	}
+	if !update() {
+		return nil
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode secret data for %s: %w", secret.Name, err)
+	}
+	secret.Data[secretDataKey] = buf
+	// The resource version from the get is kept, so this will fail if the Secret was changed in the meantime.
+	if _, err := s.secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update %s for signature %s at resource version %s: %w", s.resource, signature, secret.ResourceVersion, err)
+	}
+	return nil
+}
+
+// startSpan starts the span of an operation on the Secrets of this storage.
+func (s *secretsStorage) startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "crud "+operation, attribute.String("pinniped.storage_type", s.resource))
+}
+
 //nolint: gochecknoglobals
 var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
 
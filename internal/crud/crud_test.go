@@ -12,6 +12,8 @@ import (
 
 	"github.com/ory/fosite/compose"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/validation"
@@ -21,6 +23,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/kubernetes/fake"
 	coretesting "k8s.io/client-go/testing"
+
+	"go.pinniped.dev/internal/testutil/tracingtest"
 )
 
 func TestStorage(t *testing.T) {
@@ -759,6 +763,265 @@ func TestStorage(t *testing.T) {
 			},
 			wantErr: `failed to list secrets for resource "seals" matching label "additionalLabel=matching-value": some listing error`,
 		},
+		{
+			name:     "update data of non-existent",
+			resource: "seals",
+			mocks:    nil,
+			run: func(t *testing.T, storage Storage, fakeClock *clock.FakeClock) error {
+				signature := hmac.AuthorizeCodeSignature(authorizationCode3)
+				return storage.UpdateData(ctx, signature, &testJSON{}, func() bool {
+					t.Fatal("update should not be called when the secret does not exist")
+					return false
+				})
+			},
+			wantActions: []coretesting.Action{
+				coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba"),
+			},
+			wantSecrets: nil,
+			wantErr:     `failed to get seals for signature 5aUhdNmfWLW3yKX8Zfz5ztS5IiiWBgu36Gja-o2xl0I: secrets "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba" not found`,
+		},
+		{
+			name:     "update data",
+			resource: "seals",
+			mocks: func(t *testing.T, mock mocker) {
+				require.NoError(t, mock.Tracker().Add(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+							"additionalLabel":           "some-value",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"happy-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				}))
+				require.NoError(t, mock.Tracker().Add(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-lvzgyywdc2dhjdbgf5jvzfyphosigvhnsh6qlse3blumogoqhqhq",
+						Namespace:       namespace,
+						ResourceVersion: "",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+							"additionalLabel":           "some-value",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"other-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				}))
+			},
+			run: func(t *testing.T, storage Storage, fakeClock *clock.FakeClock) error {
+				fakeClock.Step(time.Hour) // updating should not change the lifetime of the secret
+				signature := hmac.AuthorizeCodeSignature(authorizationCode3)
+				seal := &testJSON{}
+				return storage.UpdateData(ctx, signature, seal, func() bool {
+					require.Equal(t, "happy-seal", seal.Data)
+					seal.Data = "updated-" + seal.Data
+					return true
+				})
+			},
+			wantActions: []coretesting.Action{
+				coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba"),
+				coretesting.NewUpdateAction(secretsGVR, namespace, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35", // update at the RV which was read
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+							"additionalLabel":           "some-value",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"updated-happy-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				}),
+			},
+			wantSecrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+							"additionalLabel":           "some-value",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"updated-happy-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-lvzgyywdc2dhjdbgf5jvzfyphosigvhnsh6qlse3blumogoqhqhq",
+						Namespace:       namespace,
+						ResourceVersion: "",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+							"additionalLabel":           "some-value",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"other-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name:     "update data when update returns false",
+			resource: "seals",
+			mocks: func(t *testing.T, mock mocker) {
+				require.NoError(t, mock.Tracker().Add(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"skip-me"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				}))
+			},
+			run: func(t *testing.T, storage Storage, fakeClock *clock.FakeClock) error {
+				signature := hmac.AuthorizeCodeSignature(authorizationCode3)
+				return storage.UpdateData(ctx, signature, &testJSON{}, func() bool { return false })
+			},
+			wantActions: []coretesting.Action{
+				coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba"),
+			},
+			wantSecrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"skip-me"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name:     "when there is an error performing the update while updating data",
+			resource: "seals",
+			mocks: func(t *testing.T, mock mocker) {
+				require.NoError(t, mock.Tracker().Add(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"sad-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				}))
+				mock.PrependReactor("update", "secrets", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, fmt.Errorf("some update error")
+				})
+			},
+			run: func(t *testing.T, storage Storage, fakeClock *clock.FakeClock) error {
+				signature := hmac.AuthorizeCodeSignature(authorizationCode3)
+				return storage.UpdateData(ctx, signature, &testJSON{}, func() bool { return true })
+			},
+			wantActions: []coretesting.Action{
+				coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba"),
+				coretesting.NewUpdateAction(secretsGVR, namespace, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"sad-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				}),
+			},
+			wantSecrets: []corev1.Secret{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pinniped-storage-seals-4wssc5gzt5mlln6iux6gl7hzz3klsirisydaxn7indnpvdnrs5ba",
+						Namespace:       namespace,
+						ResourceVersion: "35",
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": "seals",
+						},
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"Data":"sad-seal"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/seals",
+				},
+			},
+			wantErr: "failed to update seals for signature 5aUhdNmfWLW3yKX8Zfz5ztS5IiiWBgu36Gja-o2xl0I at resource version 35: some update error",
+		},
 		{
 			name:     "invalid exiting secret type",
 			resource: "candies",
@@ -1037,6 +1300,83 @@ func TestStorage(t *testing.T) {
 	}
 }
 
+func TestFromSecret(t *testing.T) {
+	type testJSON struct {
+		Data string
+	}
+
+	validSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "pinniped-storage-access-token-abc",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type": "access-token",
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"Data":"snorlax"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/access-token",
+		}
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		secret   func(*corev1.Secret)
+		wantData *testJSON
+		wantErr  string
+	}{
+		{
+			name:     "happy path",
+			resource: "access-token",
+			secret:   func(s *corev1.Secret) {},
+			wantData: &testJSON{Data: "snorlax"},
+		},
+		{
+			name:     "wrong resource",
+			resource: "refresh-token",
+			secret:   func(s *corev1.Secret) {},
+			wantErr:  "secret storage data has incorrect type: storage.pinniped.dev/access-token must equal storage.pinniped.dev/refresh-token",
+		},
+		{
+			name:     "wrong label",
+			resource: "access-token",
+			secret:   func(s *corev1.Secret) { s.Labels["storage.pinniped.dev/type"] = "refresh-token" },
+			wantErr:  "secret storage data has incorrect label: refresh-token must equal access-token",
+		},
+		{
+			name:     "wrong version",
+			resource: "access-token",
+			secret:   func(s *corev1.Secret) { s.Data["pinniped-storage-version"] = []byte("2") },
+			wantErr:  "secret storage data has incorrect version",
+		},
+		{
+			name:     "invalid JSON",
+			resource: "access-token",
+			secret:   func(s *corev1.Secret) { s.Data["pinniped-storage-data"] = []byte(`}`) },
+			wantErr:  "failed to decode pinniped-storage-access-token-abc: invalid character '}' looking for beginning of value",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			secret := validSecret()
+			tt.secret(secret)
+
+			data := &testJSON{}
+			err := FromSecret(tt.resource, secret, data)
+			require.Equal(t, tt.wantErr, errString(err))
+			if tt.wantErr == "" {
+				require.Equal(t, tt.wantData, data)
+			}
+		})
+	}
+}
+
 func checkSecretActionNames(t *testing.T, actions []coretesting.Action) {
 	t.Helper()
 
@@ -1095,3 +1435,31 @@ func errString(err error) string {
 
 	return err.Error()
 }
+
+func TestStorageSpans(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	type testJSON struct {
+		Data string
+	}
+	ctx := context.Background()
+	storage := New("access-token", fake.NewSimpleClientset().CoreV1().Secrets("some-namespace"), time.Now, 0)
+
+	rv, err := storage.Create(ctx, "some-signature", &testJSON{Data: "snorlax"}, nil)
+	require.NoError(t, err)
+	_, err = storage.Update(ctx, "some-signature", rv, &testJSON{Data: "pikachu"})
+	require.NoError(t, err)
+	_, err = storage.Get(ctx, "some-signature", &testJSON{})
+	require.NoError(t, err)
+	require.NoError(t, storage.Delete(ctx, "some-signature"))
+	_, err = storage.Get(ctx, "some-signature", &testJSON{})
+	require.Error(t, err)
+
+	require.Equal(t, []string{"crud Create", "crud Update", "crud Get", "crud Delete", "crud Get"}, tracingtest.SpanNames(recorder))
+	spans := recorder.GetSpans()
+	for _, span := range spans {
+		require.Contains(t, span.Attributes, attribute.String("pinniped.storage_type", "access-token"))
+	}
+	require.Equal(t, codes.Unset, spans[2].StatusCode)
+	require.Equal(t, codes.Error, spans[4].StatusCode)
+}
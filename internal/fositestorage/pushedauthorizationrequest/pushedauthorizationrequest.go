@@ -0,0 +1,100 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pushedauthorizationrequest stores the authorization requests which clients push to the Supervisor
+// ahead of time, as described in RFC 9126.
+package pushedauthorizationrequest
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "pushed-auth-request"
+
+	ErrInvalidPushedAuthorizeRequestVersion = constable.Error("pushed authorization request data has wrong version")
+	ErrInvalidPushedAuthorizeRequestData    = constable.Error("pushed authorization request data must be present")
+
+	pushedAuthorizeRequestStorageVersion = "1"
+)
+
+// Request is an authorization request which was pushed by a client.
+type Request struct {
+	// ClientID is the ID of the client which pushed the request.
+	ClientID string `json:"clientID"`
+
+	// Params are the authorization request params, as they would have been sent to the authorization endpoint.
+	Params url.Values `json:"params"`
+
+	// ExpiresAt is when the request_uri which refers to the request stops being valid.
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Storage stores pushed authorization requests by the reference in their request_uri.
+type Storage interface {
+	CreatePushedAuthorizeRequest(ctx context.Context, reference string, request *Request) error
+	GetPushedAuthorizeRequest(ctx context.Context, reference string) (*Request, error)
+	DeletePushedAuthorizeRequest(ctx context.Context, reference string) error
+}
+
+var _ Storage = &pushedAuthorizeRequestStorage{}
+
+type pushedAuthorizeRequestStorage struct {
+	storage crud.Storage
+}
+
+type session struct {
+	Request *Request `json:"request"`
+	Version string   `json:"version"`
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) Storage {
+	return &pushedAuthorizeRequestStorage{storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime)}
+}
+
+func (a *pushedAuthorizeRequestStorage) CreatePushedAuthorizeRequest(ctx context.Context, reference string, request *Request) error {
+	if request == nil || request.ClientID == "" {
+		return ErrInvalidPushedAuthorizeRequestData
+	}
+
+	_, err := a.storage.Create(ctx, reference, &session{Request: request, Version: pushedAuthorizeRequestStorageVersion}, nil)
+	return err
+}
+
+func (a *pushedAuthorizeRequestStorage) GetPushedAuthorizeRequest(ctx context.Context, reference string) (*Request, error) {
+	session := &session{}
+	_, err := a.storage.Get(ctx, reference, session)
+
+	if errors.IsNotFound(err) {
+		return nil, fosite.ErrNotFound.WithWrap(err).WithDebug(err.Error())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pushed authorization request for %s: %w", reference, err)
+	}
+
+	if version := session.Version; version != pushedAuthorizeRequestStorageVersion {
+		return nil, fmt.Errorf("%w: pushed authorization request for %s has version %s instead of %s",
+			ErrInvalidPushedAuthorizeRequestVersion, reference, version, pushedAuthorizeRequestStorageVersion)
+	}
+
+	if session.Request == nil || session.Request.ClientID == "" {
+		return nil, fmt.Errorf("malformed pushed authorization request for %s: %w", reference, ErrInvalidPushedAuthorizeRequestData)
+	}
+
+	return session.Request, nil
+}
+
+func (a *pushedAuthorizeRequestStorage) DeletePushedAuthorizeRequest(ctx context.Context, reference string) error {
+	return a.storage.Delete(ctx, reference)
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package pushedauthorizationrequest
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	coretesting "k8s.io/client-go/testing"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+var lifetime = time.Minute * 2
+var fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+
+func TestPushedAuthorizeRequestStorage(t *testing.T) {
+	secretsGVR := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "secrets",
+	}
+
+	wantActions := []coretesting.Action{
+		coretesting.NewCreateAction(secretsGVR, namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "pinniped-storage-pushed-auth-request-pwu5zs7lekbhnln2w4",
+				ResourceVersion: "",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type": "pushed-auth-request",
+				},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"request":{"clientID":"pinny","params":{"client_id":["pinny"],"scope":["openid"]},"expiresAt":"2030-01-01T00:01:00Z"},"version":"1"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/pushed-auth-request",
+		}),
+		coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-pushed-auth-request-pwu5zs7lekbhnln2w4"),
+		coretesting.NewDeleteAction(secretsGVR, namespace, "pinniped-storage-pushed-auth-request-pwu5zs7lekbhnln2w4"),
+	}
+
+	ctx, client, _, storage := makeTestSubject()
+
+	request := &Request{
+		ClientID:  "pinny",
+		Params:    url.Values{"client_id": []string{"pinny"}, "scope": []string{"openid"}},
+		ExpiresAt: fakeNow.Add(time.Minute),
+	}
+	err := storage.CreatePushedAuthorizeRequest(ctx, "fancy-signature", request)
+	require.NoError(t, err)
+
+	newRequest, err := storage.GetPushedAuthorizeRequest(ctx, "fancy-signature")
+	require.NoError(t, err)
+	require.Equal(t, request, newRequest)
+
+	err = storage.DeletePushedAuthorizeRequest(ctx, "fancy-signature")
+	require.NoError(t, err)
+
+	require.Equal(t, wantActions, client.Actions())
+}
+
+func TestGetNotFound(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	_, notFoundErr := storage.GetPushedAuthorizeRequest(ctx, "non-existent-signature")
+	require.EqualError(t, notFoundErr, "not_found")
+	require.True(t, errors.Is(notFoundErr, fosite.ErrNotFound))
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, _, secrets, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pinniped-storage-pushed-auth-request-pwu5zs7lekbhnln2w4",
+			ResourceVersion: "",
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "pushed-auth-request",
+			},
+			Annotations: map[string]string{
+				"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"request":{"clientID":"pinny","params":{"client_id":["pinny"]},"expiresAt":"2030-01-01T00:01:00Z"},"version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/pushed-auth-request",
+	}
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	_, err = storage.GetPushedAuthorizeRequest(ctx, "fancy-signature")
+
+	require.EqualError(t, err, "pushed authorization request data has wrong version: pushed authorization request for fancy-signature has version not-the-right-version instead of 1")
+}
+
+func TestNilSessionRequest(t *testing.T) {
+	ctx, _, secrets, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pinniped-storage-pushed-auth-request-pwu5zs7lekbhnln2w4",
+			ResourceVersion: "",
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "pushed-auth-request",
+			},
+			Annotations: map[string]string{
+				"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"nonsense-key": "nonsense-value","version":"1"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/pushed-auth-request",
+	}
+
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	_, err = storage.GetPushedAuthorizeRequest(ctx, "fancy-signature")
+	require.EqualError(t, err, "malformed pushed authorization request for fancy-signature: pushed authorization request data must be present")
+}
+
+func TestCreateWithoutClient(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	err := storage.CreatePushedAuthorizeRequest(ctx, "signature-doesnt-matter", nil)
+	require.EqualError(t, err, "pushed authorization request data must be present")
+
+	err = storage.CreatePushedAuthorizeRequest(ctx, "signature-doesnt-matter", &Request{Params: url.Values{}})
+	require.EqualError(t, err, "pushed authorization request data must be present")
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, corev1client.SecretInterface, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, secrets, New(secrets, clock.NewFakeClock(fakeNow).Now, lifetime)
+}
@@ -10,14 +10,16 @@ import (
 
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/oauth2"
-	"github.com/ory/fosite/handler/openid"
 	"k8s.io/apimachinery/pkg/api/errors"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/fositestorage"
 	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
 )
 
 const (
@@ -26,7 +28,15 @@ const (
 	ErrInvalidRefreshTokenRequestVersion = constable.Error("refresh token request data has wrong version")
 	ErrInvalidRefreshTokenRequestData    = constable.Error("refresh token request data must be present")
 
-	refreshTokenStorageVersion = "1"
+	// reuseReason is the reason of the audit event which is recorded when reuse of a refresh token is detected.
+	reuseReason = "refresh_token_reuse"
+
+	refreshTokenStorageVersion = "2"
+
+	// reuseGracePeriod is how long a refresh token which was already used may be used again. Parallel kubectl
+	// processes sharing a session cache may all try to refresh the same expired tokens at the same moment,
+	// and that should not be mistaken for a stolen refresh token.
+	reuseGracePeriod = 30 * time.Second
 )
 
 type RevocationStorage interface {
@@ -38,19 +48,63 @@ var _ RevocationStorage = &refreshTokenStorage{}
 
 type refreshTokenStorage struct {
 	storage crud.Storage
+	clock   func() time.Time
 }
 
 type session struct {
 	Request *fosite.Request `json:"request"`
 	Version string          `json:"version"`
+	// UsedAt is set when the refresh token was exchanged for new tokens. Used refresh tokens are kept around
+	// so that reuse of an old refresh token can be detected.
+	UsedAt *time.Time `json:"usedAt,omitempty"`
 }
 
 func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) RevocationStorage {
-	return &refreshTokenStorage{storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime)}
+	return &refreshTokenStorage{
+		storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime),
+		clock:   clock,
+	}
+}
+
+type rotationContextKey struct{}
+
+// WithRotation returns a context for exchanging the refresh token with the given signature for new tokens. Fosite
+// revokes the old refresh tokens with RevokeRefreshToken during the exchange, and this context causes only the
+// exchanged refresh token to be marked as used instead of being deleted, so that any later reuse of it can be detected.
+// Other refresh tokens of the same session, e.g. one issued by a parallel refresh, are left alone.
+func WithRotation(ctx context.Context, signature string) context.Context {
+	return context.WithValue(ctx, rotationContextKey{}, signature)
+}
+
+// IsRotation returns whether the context was made by WithRotation.
+func IsRotation(ctx context.Context) bool {
+	_, rotation := ctx.Value(rotationContextKey{}).(string)
+	return rotation
 }
 
 func (a *refreshTokenStorage) RevokeRefreshToken(ctx context.Context, requestID string) error {
-	return a.storage.DeleteByLabel(ctx, fositestorage.StorageRequestIDLabelName, requestID)
+	signature, rotation := ctx.Value(rotationContextKey{}).(string)
+	if !rotation {
+		return a.storage.DeleteByLabel(ctx, fositestorage.StorageRequestIDLabelName, requestID)
+	}
+
+	usedAt := a.clock().UTC()
+	session := newValidEmptyRefreshTokenSession()
+	err := a.storage.UpdateData(ctx, signature, session, func() bool {
+		if session.Request.GetID() != requestID || session.UsedAt != nil {
+			return false
+		}
+		session.UsedAt = &usedAt
+		return true
+	})
+	if errors.IsNotFound(err) {
+		return fosite.ErrNotFound.WithWrap(err).WithDebug(err.Error())
+	}
+	if errors.IsConflict(err) {
+		// Another refresh of the same refresh token is happening at the same moment.
+		return fmt.Errorf("%w: %v", fosite.ErrSerializationFailure, err)
+	}
+	return err
 }
 
 func (a *refreshTokenStorage) CreateRefreshTokenSession(ctx context.Context, signature string, requester fosite.Requester) error {
@@ -75,6 +129,24 @@ func (a *refreshTokenStorage) GetRefreshTokenSession(ctx context.Context, signat
 		return nil, err
 	}
 
+	if session.UsedAt != nil && a.clock().Sub(*session.UsedAt) > reuseGracePeriod {
+		// Either the legitimate client or an attacker is holding a stolen refresh token, so fosite will revoke all
+		// refresh tokens and access tokens of the same authorization when this error is returned along with the request.
+		plog.Warning("refresh token reuse detected, revoking all tokens of the session",
+			"requestID", session.Request.GetID(),
+			"clientID", session.Request.GetClient().GetID(),
+			"usedAt", session.UsedAt.Format(time.RFC3339),
+		)
+		auditlog.Record(ctx, auditlog.Event{
+			Type:      auditlog.RefreshTokenReuseDetected,
+			RequestID: session.Request.GetID(),
+			ClientID:  session.Request.GetClient().GetID(),
+			TokenType: "refresh_token",
+			Reason:    reuseReason,
+		})
+		return session.Request, fosite.ErrInactiveToken.WithHint("The refresh token was already used.")
+	}
+
 	return session.Request, err
 }
 
@@ -110,7 +182,7 @@ func newValidEmptyRefreshTokenSession() *session {
 	return &session{
 		Request: &fosite.Request{
 			Client:  &clientregistry.Client{},
-			Session: &openid.DefaultSession{},
+			Session: psession.NewPinnipedSession(),
 		},
 	}
 }
@@ -4,6 +4,7 @@
 package refreshtoken
 
 import (
+	"bytes"
 	"context"
 	"net/url"
 	"testing"
@@ -21,7 +22,9 @@ import (
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	coretesting "k8s.io/client-go/testing"
 
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/psession"
 )
 
 const namespace = "test-ns"
@@ -50,7 +53,7 @@ func TestRefreshTokenStorage(t *testing.T) {
 				},
 			},
 			Data: map[string][]byte{
-				"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"requestedAudience":null,"grantedAudience":null},"version":"1"}`),
+				"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"fosite":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"custom":{"providerName":"fake-upstream-idp","providerType":"oidc"}},"requestedAudience":null,"grantedAudience":null},"version":"2"}`),
 				"pinniped-storage-version": []byte("1"),
 			},
 			Type: "storage.pinniped.dev/refresh-token",
@@ -87,12 +90,18 @@ func TestRefreshTokenStorage(t *testing.T) {
 		RequestedScope: nil,
 		GrantedScope:   nil,
 		Form:           url.Values{"key": []string{"val"}},
-		Session: &openid.DefaultSession{
-			Claims:    nil,
-			Headers:   nil,
-			ExpiresAt: nil,
-			Username:  "snorlax",
-			Subject:   "panda",
+		Session: &psession.PinnipedSession{
+			Fosite: &openid.DefaultSession{
+				Claims:    nil,
+				Headers:   nil,
+				ExpiresAt: nil,
+				Username:  "snorlax",
+				Subject:   "panda",
+			},
+			Custom: &psession.CustomSessionData{
+				ProviderName: "fake-upstream-idp",
+				ProviderType: psession.ProviderTypeOIDC,
+			},
 		},
 		RequestedAudience: nil,
 		GrantedAudience:   nil,
@@ -125,7 +134,7 @@ func TestRefreshTokenStorageRevocation(t *testing.T) {
 				},
 			},
 			Data: map[string][]byte{
-				"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"requestedAudience":null,"grantedAudience":null},"version":"1"}`),
+				"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"fosite":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"custom":{"providerName":"fake-upstream-idp","providerType":"oidc"}},"requestedAudience":null,"grantedAudience":null},"version":"2"}`),
 				"pinniped-storage-version": []byte("1"),
 			},
 			Type: "storage.pinniped.dev/refresh-token",
@@ -152,9 +161,15 @@ func TestRefreshTokenStorageRevocation(t *testing.T) {
 			},
 		},
 		Form: url.Values{"key": []string{"val"}},
-		Session: &openid.DefaultSession{
-			Username: "snorlax",
-			Subject:  "panda",
+		Session: &psession.PinnipedSession{
+			Fosite: &openid.DefaultSession{
+				Username: "snorlax",
+				Subject:  "panda",
+			},
+			Custom: &psession.CustomSessionData{
+				ProviderName: "fake-upstream-idp",
+				ProviderType: psession.ProviderTypeOIDC,
+			},
 		},
 	}
 	err := storage.CreateRefreshTokenSession(ctx, "fancy-signature", request)
@@ -167,6 +182,193 @@ func TestRefreshTokenStorageRevocation(t *testing.T) {
 	require.Equal(t, wantActions, client.Actions())
 }
 
+func TestRefreshTokenStorageRotation(t *testing.T) {
+	wantActions := []coretesting.Action{
+		coretesting.NewCreateAction(secretsGVR, namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "pinniped-storage-refresh-token-pwu5zs7lekbhnln2w4",
+				ResourceVersion: "",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type":       "refresh-token",
+					"storage.pinniped.dev/request-id": "abcd-1",
+				},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"fosite":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"custom":{"providerName":"fake-upstream-idp","providerType":"oidc"}},"requestedAudience":null,"grantedAudience":null},"version":"2"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/refresh-token",
+		}),
+		coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-refresh-token-pwu5zs7lekbhnln2w4"),
+		// Rotation marks the refresh token as used instead of deleting it, and keeps its labels and lifetime.
+		coretesting.NewUpdateAction(secretsGVR, namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "pinniped-storage-refresh-token-pwu5zs7lekbhnln2w4",
+				Namespace:       namespace,
+				ResourceVersion: "",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type":       "refresh-token",
+					"storage.pinniped.dev/request-id": "abcd-1",
+				},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"fosite":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"custom":{"providerName":"fake-upstream-idp","providerType":"oidc"}},"requestedAudience":null,"grantedAudience":null},"version":"2","usedAt":"2030-01-01T00:00:00Z"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/refresh-token",
+		}),
+		// Rotating again does not change a refresh token which was already used.
+		coretesting.NewGetAction(secretsGVR, namespace, "pinniped-storage-refresh-token-pwu5zs7lekbhnln2w4"),
+	}
+
+	ctx, client, _, storage := makeTestSubject()
+
+	request := &fosite.Request{
+		ID:          "abcd-1",
+		RequestedAt: time.Time{},
+		Client: &clientregistry.Client{
+			DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
+				DefaultClient: &fosite.DefaultClient{
+					ID:     "pinny",
+					Public: true,
+				},
+				JSONWebKeysURI:          "where",
+				TokenEndpointAuthMethod: "something",
+			},
+		},
+		Form: url.Values{"key": []string{"val"}},
+		Session: &psession.PinnipedSession{
+			Fosite: &openid.DefaultSession{
+				Username: "snorlax",
+				Subject:  "panda",
+			},
+			Custom: &psession.CustomSessionData{
+				ProviderName: "fake-upstream-idp",
+				ProviderType: psession.ProviderTypeOIDC,
+			},
+		},
+	}
+	err := storage.CreateRefreshTokenSession(ctx, "fancy-signature", request)
+	require.NoError(t, err)
+
+	// Revoke the request ID of the session that we just created, as fosite does when refreshing
+	err = storage.RevokeRefreshToken(WithRotation(ctx, "fancy-signature"), "abcd-1")
+	require.NoError(t, err)
+
+	err = storage.RevokeRefreshToken(WithRotation(ctx, "fancy-signature"), "abcd-1")
+	require.NoError(t, err)
+
+	require.Equal(t, wantActions, client.Actions())
+}
+
+func TestRefreshTokenReuse(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	fakeClock := clock.NewFakeClock(fakeNow)
+	storage := New(secrets, fakeClock.Now, lifetime)
+	ctx := context.Background()
+
+	var auditLog bytes.Buffer
+	auditLogger, err := auditlog.New(&auditLog, nil, fakeClock.Now)
+	require.NoError(t, err)
+	auditlog.SetGlobalLogger(auditLogger)
+	t.Cleanup(func() { auditlog.SetGlobalLogger(nil) })
+
+	request := &fosite.Request{
+		ID:      "abcd-1",
+		Client:  &clientregistry.Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: "pinny"}}},
+		Session: psession.NewPinnipedSession(),
+	}
+	otherRequest := &fosite.Request{
+		ID:      "other-1",
+		Client:  &clientregistry.Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: "pinny"}}},
+		Session: psession.NewPinnipedSession(),
+	}
+	require.NoError(t, storage.CreateRefreshTokenSession(ctx, "first-signature", request))
+	require.NoError(t, storage.CreateRefreshTokenSession(ctx, "other-signature", otherRequest))
+
+	// Refreshing uses up the first refresh token and issues a second one for the same request.
+	require.NoError(t, storage.RevokeRefreshToken(WithRotation(ctx, "first-signature"), "abcd-1"))
+	require.NoError(t, storage.CreateRefreshTokenSession(ctx, "second-signature", request))
+
+	// Using the first refresh token again right away is allowed, in case of parallel refreshes.
+	fakeClock.Step(reuseGracePeriod)
+	gotRequest, err := storage.GetRefreshTokenSession(ctx, "first-signature", nil)
+	require.NoError(t, err)
+	require.Equal(t, "abcd-1", gotRequest.GetID())
+	require.Empty(t, auditLog.String())
+
+	// Using the first refresh token again later is detected as reuse.
+	fakeClock.Step(time.Second)
+	gotRequest, err = storage.GetRefreshTokenSession(ctx, "first-signature", nil)
+	require.True(t, errors.Is(err, fosite.ErrInactiveToken))
+	require.NotNil(t, gotRequest) // fosite needs the request to revoke all tokens of the same authorization
+	require.Equal(t, "abcd-1", gotRequest.GetID())
+	require.JSONEq(t, `{
+		"time": "2030-01-01T00:00:31Z",
+		"event": "RefreshTokenReuseDetected",
+		"requestID": "abcd-1",
+		"clientID": "pinny",
+		"tokenType": "refresh_token",
+		"reason": "refresh_token_reuse"
+	}`, auditLog.String())
+
+	// Fosite then deletes the reused refresh token and revokes all the others.
+	require.NoError(t, storage.DeleteRefreshTokenSession(ctx, "first-signature"))
+	require.NoError(t, storage.RevokeRefreshToken(ctx, "abcd-1"))
+	_, err = storage.GetRefreshTokenSession(ctx, "second-signature", nil)
+	require.True(t, errors.Is(err, fosite.ErrNotFound))
+
+	// Other sessions are not affected.
+	gotRequest, err = storage.GetRefreshTokenSession(ctx, "other-signature", nil)
+	require.NoError(t, err)
+	require.Equal(t, "other-1", gotRequest.GetID())
+}
+
+func TestOverlappingRefreshes(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	fakeClock := clock.NewFakeClock(fakeNow)
+	storage := New(secrets, fakeClock.Now, lifetime)
+	ctx := context.Background()
+
+	request := &fosite.Request{
+		ID:      "abcd-1",
+		Client:  &clientregistry.Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: "pinny"}}},
+		Session: psession.NewPinnipedSession(),
+	}
+	require.NoError(t, storage.CreateRefreshTokenSession(ctx, "first-signature", request))
+
+	// Two parallel refreshes exchange the same refresh token. Each issues its own new refresh token.
+	require.NoError(t, storage.RevokeRefreshToken(WithRotation(ctx, "first-signature"), "abcd-1"))
+	require.NoError(t, storage.CreateRefreshTokenSession(ctx, "second-signature", request))
+	fakeClock.Step(time.Second)
+	require.NoError(t, storage.RevokeRefreshToken(WithRotation(ctx, "first-signature"), "abcd-1"))
+	require.NoError(t, storage.CreateRefreshTokenSession(ctx, "third-signature", request))
+
+	// Either of the new refresh tokens can be used later, since neither of them was exchanged yet.
+	fakeClock.Step(time.Hour)
+	for _, signature := range []string{"second-signature", "third-signature"} {
+		gotRequest, err := storage.GetRefreshTokenSession(ctx, signature, nil)
+		require.NoError(t, err, signature)
+		require.Equal(t, "abcd-1", gotRequest.GetID())
+	}
+
+	// Only the refresh token which was exchanged is detected as reuse.
+	_, err := storage.GetRefreshTokenSession(ctx, "first-signature", nil)
+	require.True(t, errors.Is(err, fosite.ErrInactiveToken))
+
+	// Exchanging a refresh token which no longer exists is reported like fosite's own lookups.
+	err = storage.RevokeRefreshToken(WithRotation(ctx, "missing-signature"), "abcd-1")
+	require.True(t, errors.Is(err, fosite.ErrNotFound))
+}
+
 func TestGetNotFound(t *testing.T) {
 	ctx, _, _, storage := makeTestSubject()
 
@@ -190,7 +392,7 @@ func TestWrongVersion(t *testing.T) {
 			},
 		},
 		Data: map[string][]byte{
-			"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"requestedAudience":null,"grantedAudience":null},"version":"not-the-right-version"}`),
+			"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"pinny","redirect_uris":null,"grant_types":null,"response_types":null,"scopes":null,"audience":null,"public":true,"jwks_uri":"where","jwks":null,"token_endpoint_auth_method":"something","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":""},"scopes":null,"grantedScopes":null,"form":{"key":["val"]},"session":{"fosite":{"Claims":null,"Headers":null,"ExpiresAt":null,"Username":"snorlax","Subject":"panda"},"custom":{"providerName":"fake-upstream-idp","providerType":"oidc"}},"requestedAudience":null,"grantedAudience":null},"version":"not-the-right-version"}`),
 			"pinniped-storage-version": []byte("1"),
 		},
 		Type: "storage.pinniped.dev/refresh-token",
@@ -200,7 +402,7 @@ func TestWrongVersion(t *testing.T) {
 
 	_, err = storage.GetRefreshTokenSession(ctx, "fancy-signature", nil)
 
-	require.EqualError(t, err, "refresh token request data has wrong version: refresh token session for fancy-signature has version not-the-right-version instead of 1")
+	require.EqualError(t, err, "refresh token request data has wrong version: refresh token session for fancy-signature has version not-the-right-version instead of 2")
 }
 
 func TestNilSessionRequest(t *testing.T) {
@@ -218,7 +420,7 @@ func TestNilSessionRequest(t *testing.T) {
 			},
 		},
 		Data: map[string][]byte{
-			"pinniped-storage-data":    []byte(`{"nonsense-key": "nonsense-value","version":"1"}`),
+			"pinniped-storage-data":    []byte(`{"nonsense-key": "nonsense-value","version":"2"}`),
 			"pinniped-storage-version": []byte("1"),
 		},
 		Type: "storage.pinniped.dev/refresh-token",
@@ -246,10 +448,10 @@ func TestCreateWithWrongRequesterDataTypes(t *testing.T) {
 		Client:  &clientregistry.Client{},
 	}
 	err := storage.CreateRefreshTokenSession(ctx, "signature-doesnt-matter", request)
-	require.EqualError(t, err, "requester's session must be of type openid.DefaultSession")
+	require.EqualError(t, err, "requester's session must be of type PinnipedSession")
 
 	request = &fosite.Request{
-		Session: &openid.DefaultSession{},
+		Session: psession.NewPinnipedSession(),
 		Client:  nil,
 	}
 	err = storage.CreateRefreshTokenSession(ctx, "signature-doesnt-matter", request)
@@ -261,7 +463,7 @@ func TestCreateWithoutRequesterID(t *testing.T) {
 
 	request := &fosite.Request{
 		ID:      "", // empty ID
-		Session: &openid.DefaultSession{},
+		Session: psession.NewPinnipedSession(),
 		Client:  &clientregistry.Client{},
 	}
 	err := storage.CreateRefreshTokenSession(ctx, "signature-doesnt-matter", request)
@@ -0,0 +1,248 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sessions provides administrative helpers for listing and revoking downstream Supervisor sessions.
+//
+// A session is the set of stored access tokens and refresh tokens which share the same fosite request ID,
+// i.e. everything which was granted as a result of one end user login.
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ory/fosite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/fositestorage/accesstoken"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+const ErrSessionNotFound = constable.Error("session not found")
+
+// Session describes one downstream session.
+type Session struct {
+	// ID is the fosite request ID which is shared by all tokens belonging to this session.
+	ID string `json:"id"`
+
+	Username     string                `json:"username,omitempty"`
+	Groups       []string              `json:"groups,omitempty"`
+	Subject      string                `json:"subject,omitempty"`
+	ProviderName string                `json:"providerName,omitempty"`
+	ProviderType psession.ProviderType `json:"providerType,omitempty"`
+	ClientID     string                `json:"clientID,omitempty"`
+
+	// IssuedAt is the time at which the end user authenticated.
+	IssuedAt time.Time `json:"issuedAt"`
+
+	// ExpiresAt is the time after which none of the session's stored tokens will be usable anymore.
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Filter selects a subset of sessions. Empty fields match everything.
+type Filter struct {
+	Username string
+	Group    string
+}
+
+func (f Filter) matches(s *Session) bool {
+	if f.Username != "" && f.Username != s.Username {
+		return false
+	}
+	if f.Group != "" && !contains(s.Groups, f.Group) {
+		return false
+	}
+	return true
+}
+
+// storedSession matches the JSON layout used by the accesstoken and refreshtoken storage packages.
+type storedSession struct {
+	Request *fosite.Request `json:"request"`
+	Version string          `json:"version"`
+}
+
+// sessionTypes are the storage types which carry the request ID label and together describe a session.
+//nolint: gochecknoglobals
+var sessionTypes = []string{refreshtoken.TypeLabelValue, accesstoken.TypeLabelValue}
+
+// List returns all sessions found in the provided Secrets client which match the filter, sorted by issue time.
+func List(ctx context.Context, secrets corev1client.SecretInterface, filter Filter) ([]Session, error) {
+	list, err := secrets.List(ctx, metav1.ListOptions{
+		LabelSelector: sessionSelector().String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session storage secrets: %w", err)
+	}
+
+	byID := fromSecrets(list.Items)
+
+	result := make([]Session, 0, len(byID))
+	for _, session := range byID {
+		if filter.matches(session) {
+			result = append(result, *session)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].IssuedAt.Equal(result[j].IssuedAt) {
+			return result[i].IssuedAt.Before(result[j].IssuedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// Get returns the session with the given ID. It returns ErrSessionNotFound when none of its tokens are stored.
+func Get(ctx context.Context, secrets corev1client.SecretInterface, id string) (*Session, error) {
+	idRequirement, err := labels.NewRequirement(fositestorage.StorageRequestIDLabelName, selection.Equals, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id) // not a valid label value, so it cannot be a session ID
+	}
+	list, err := secrets.List(ctx, metav1.ListOptions{
+		LabelSelector: sessionSelector().Add(*idRequirement).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session storage secrets for session %s: %w", id, err)
+	}
+	session, ok := fromSecrets(list.Items)[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return session, nil
+}
+
+// Revoke deletes every stored token belonging to the session with the given ID.
+// It returns ErrSessionNotFound when there is nothing to delete.
+func Revoke(ctx context.Context, secrets corev1client.SecretInterface, id string) error {
+	list, err := secrets.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{fositestorage.StorageRequestIDLabelName: id}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list session storage secrets for session %s: %w", id, err)
+	}
+	if len(list.Items) == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	for _, secret := range list.Items {
+		if err := secrets.Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("failed to delete session storage secret %s for session %s: %w", secret.Name, id, err)
+		}
+	}
+	return nil
+}
+
+// FromSecret decodes the session which a Secret of the access token or refresh token storage belongs to. The ID and
+// ExpiresAt fields of the result are not set.
+func FromSecret(secret *corev1.Secret) (*Session, error) {
+	stored := &storedSession{
+		Request: &fosite.Request{
+			Client:  &clientregistry.Client{},
+			Session: psession.NewPinnipedSession(),
+		},
+	}
+	if err := crud.FromSecret(secret.Labels[crud.SecretLabelKey], secret, stored); err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		ClientID: stored.Request.GetClient().GetID(),
+		IssuedAt: stored.Request.GetRequestedAt(),
+	}
+
+	pinnipedSession, ok := stored.Request.GetSession().(*psession.PinnipedSession)
+	if !ok || pinnipedSession.Fosite == nil {
+		// Sessions stored by older versions of the Supervisor have a different layout, so only the basics are known.
+		return session, nil
+	}
+	if custom := pinnipedSession.Custom; custom != nil {
+		session.ProviderName = custom.ProviderName
+		session.ProviderType = custom.ProviderType
+	}
+	if claims := pinnipedSession.Fosite.Claims; claims != nil {
+		session.Subject = claims.Subject
+		if !claims.AuthTime.IsZero() {
+			session.IssuedAt = claims.AuthTime
+		}
+		if username, ok := claims.Extra[oidc.DownstreamUsernameClaim].(string); ok {
+			session.Username = username
+		}
+		if groups, ok := claims.Extra[oidc.DownstreamGroupsClaim].([]interface{}); ok {
+			for _, group := range groups {
+				if g, ok := group.(string); ok {
+					session.Groups = append(session.Groups, g)
+				}
+			}
+		}
+	}
+	return session, nil
+}
+
+// sessionSelector selects the Secrets which store the access tokens and refresh tokens of sessions.
+func sessionSelector() labels.Selector {
+	typeRequirement, err := labels.NewRequirement(crud.SecretLabelKey, selection.In, sessionTypes)
+	if err != nil {
+		panic(err) // programmer error, the storage types are valid label values
+	}
+	return labels.NewSelector().Add(*typeRequirement)
+}
+
+// fromSecrets groups the access token and refresh token storage Secrets by request ID, and decodes the session
+// which each group belongs to. A session expires when the last of its Secrets does.
+func fromSecrets(secrets []corev1.Secret) map[string]*Session {
+	byID := map[string]*Session{}
+	for i := range secrets {
+		secret := &secrets[i]
+		id := secret.Labels[fositestorage.StorageRequestIDLabelName]
+		if id == "" {
+			continue
+		}
+
+		expiresAt := garbageCollectAfter(secret)
+		if existing, ok := byID[id]; ok {
+			if expiresAt.After(existing.ExpiresAt) {
+				existing.ExpiresAt = expiresAt
+			}
+			continue
+		}
+
+		session, err := FromSecret(secret)
+		if err != nil {
+			// Skip anything that we cannot read, but still allow the rest of the list to be returned.
+			plog.WarningErr("could not decode session storage secret", err, "secretName", secret.Name)
+			continue
+		}
+		session.ID = id
+		session.ExpiresAt = expiresAt
+		byID[id] = session
+	}
+	return byID
+}
+
+func garbageCollectAfter(secret *corev1.Secret) time.Time {
+	t, err := time.Parse(crud.SecretLifetimeAnnotationDateFormat, secret.Annotations[crud.SecretLifetimeAnnotationKey])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, hay := range haystack {
+		if hay == needle {
+			return true
+		}
+	}
+	return false
+}
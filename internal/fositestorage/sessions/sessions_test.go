@@ -0,0 +1,131 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sessions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/fositestorage/accesstoken"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/psession"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestListGetAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+
+	createSession(t, secrets, "request-1", "alice", []string{"admins", "devs"}, fakeNow.Add(time.Minute))
+	createSession(t, secrets, "request-2", "bob", []string{"devs"}, fakeNow)
+
+	// An unrelated Secret should be ignored.
+	_, err := secrets.Create(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	alice := Session{
+		ID:           "request-1",
+		Username:     "alice",
+		Groups:       []string{"admins", "devs"},
+		Subject:      "https://upstream?sub=alice",
+		ProviderName: "some-upstream",
+		ProviderType: psession.ProviderTypeLDAP,
+		ClientID:     "pinniped-cli",
+		IssuedAt:     fakeNow.Add(time.Minute),
+		ExpiresAt:    fakeNow.Add(9 * time.Hour),
+	}
+	bob := Session{
+		ID:           "request-2",
+		Username:     "bob",
+		Groups:       []string{"devs"},
+		Subject:      "https://upstream?sub=bob",
+		ProviderName: "some-upstream",
+		ProviderType: psession.ProviderTypeLDAP,
+		ClientID:     "pinniped-cli",
+		IssuedAt:     fakeNow,
+		ExpiresAt:    fakeNow.Add(9 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []Session
+	}{
+		{name: "no filter", want: []Session{bob, alice}},
+		{name: "filter by username", filter: Filter{Username: "alice"}, want: []Session{alice}},
+		{name: "filter by group", filter: Filter{Group: "devs"}, want: []Session{bob, alice}},
+		{name: "filter by group and username", filter: Filter{Group: "admins", Username: "bob"}, want: []Session{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := List(ctx, secrets, tt.filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	gotSession, err := Get(ctx, secrets, "request-1")
+	require.NoError(t, err)
+	require.Equal(t, &alice, gotSession)
+
+	require.NoError(t, Revoke(ctx, secrets, "request-1"))
+	got, err := List(ctx, secrets, Filter{})
+	require.NoError(t, err)
+	require.Equal(t, []Session{bob}, got)
+
+	err = Revoke(ctx, secrets, "request-1")
+	require.EqualError(t, err, "session not found: request-1")
+	require.ErrorIs(t, err, ErrSessionNotFound)
+
+	_, err = Get(ctx, secrets, "request-1")
+	require.EqualError(t, err, "session not found: request-1")
+	require.ErrorIs(t, err, ErrSessionNotFound)
+
+	_, err = Get(ctx, secrets, "not a valid label value")
+	require.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func createSession(t *testing.T, secrets corev1client.SecretInterface, id, username string, groups []string, authTime time.Time) {
+	t.Helper()
+
+	groupsAsInterfaces := make([]interface{}, 0, len(groups))
+	for _, g := range groups {
+		groupsAsInterfaces = append(groupsAsInterfaces, g)
+	}
+	request := &fosite.Request{
+		ID:          id,
+		RequestedAt: authTime,
+		Client:      &clientregistry.Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: "pinniped-cli"}}},
+		Session: &psession.PinnipedSession{
+			Fosite: &openid.DefaultSession{
+				Claims: &jwt.IDTokenClaims{
+					Subject:  "https://upstream?sub=" + username,
+					AuthTime: authTime,
+					Extra:    map[string]interface{}{"username": username, "groups": groupsAsInterfaces},
+				},
+			},
+			Custom: &psession.CustomSessionData{ProviderName: "some-upstream", ProviderType: psession.ProviderTypeLDAP},
+		},
+	}
+
+	clock := func() time.Time { return fakeNow }
+	require.NoError(t, accesstoken.New(secrets, clock, 2*time.Minute).CreateAccessTokenSession(context.Background(), id+"-access", request))
+	require.NoError(t, refreshtoken.New(secrets, clock, 9*time.Hour).CreateRefreshTokenSession(context.Background(), id+"-refresh", request))
+}
@@ -8,6 +8,7 @@ import (
 
 	identityv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/identity/v1alpha1"
 	loginv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/login/v1alpha1"
+	sessionv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/session/v1alpha1"
 )
 
 type GroupData schema.GroupVersion
@@ -32,3 +33,16 @@ func ConciergeAggregatedGroups(apiGroupSuffix string) (login, identity GroupData
 			Version: identityv1alpha1.SchemeGroupVersion.Version,
 		}
 }
+
+func SupervisorAggregatedGroups(apiGroupSuffix string) (session GroupData) {
+	sessionSupervisorAPIGroup, ok := Replace(sessionv1alpha1.GroupName, apiGroupSuffix)
+
+	if !ok {
+		panic("static group input is invalid")
+	}
+
+	return GroupData{
+		Group:   sessionSupervisorAPIGroup,
+		Version: sessionv1alpha1.SchemeGroupVersion.Version,
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sourceip finds the IP address of the client which made an HTTP request.
+package sourceip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// FromRequest returns the IP address of the client of the request. When the request was wrapped by Wrap and came
+// from a trusted proxy, this is the address of the client which the proxy reported.
+func FromRequest(r *http.Request) string {
+	if clientIP, ok := r.Context().Value(contextKey{}).(string); ok {
+		return clientIP
+	}
+	return hostOf(r.RemoteAddr)
+}
+
+// TrustedProxies are the networks of the proxies in front of the Supervisor, e.g. load balancers and Ingress
+// controllers, which are trusted to report the addresses of their clients.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses CIDRs like "10.0.0.0/8" or "2001:db8::/32".
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	trusted := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		trusted = append(trusted, network)
+	}
+	return trusted, nil
+}
+
+// TrustsPeer returns whether the peer at remoteAddr is a trusted proxy. Peers which connected over a unix socket
+// are trusted, because only the other containers of the Supervisor's pod can reach it.
+func (t TrustedProxies) TrustsPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return true // unix sockets have no host and port
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && t.contains(ip)
+}
+
+func (t TrustedProxies) contains(ip net.IP) bool {
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap the provided http.Handler so that requests from trusted proxies use the Forwarded header, or else the
+// X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers. The client's IP address is the nearest address in
+// the chain of proxies which is not trusted, and the host and scheme of the request become the ones which the nearest
+// proxy reported. The headers of requests from other peers are ignored, since any client could have set them.
+func Wrap(wrapped http.Handler, trusted TrustedProxies) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !trusted.TrustsPeer(r.RemoteAddr) {
+			wrapped.ServeHTTP(w, r)
+			return
+		}
+
+		hops, host, proto := forwardedHeaders(r.Header)
+		clientIP := hostOf(r.RemoteAddr)
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := parseHop(hops[i])
+			if ip == nil {
+				break // e.g. "unknown", so the nearest address is the best we know
+			}
+			clientIP = ip.String()
+			if !trusted.contains(ip) {
+				break
+			}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), contextKey{}, clientIP))
+		if host != "" {
+			r.Host = host
+		}
+		if proto == "http" || proto == "https" {
+			u := *r.URL
+			u.Scheme = proto
+			r.URL = &u
+		}
+		wrapped.ServeHTTP(w, r)
+	})
+}
+
+// forwardedHeaders returns the addresses of the clients of each proxy, from the farthest to the nearest, and the
+// host and proto of the request which the nearest proxy reported.
+func forwardedHeaders(header http.Header) ([]string, string, string) {
+	var hops []string
+	var host, proto string
+
+	if forwarded := header.Values("Forwarded"); len(forwarded) > 0 {
+		// e.g. Forwarded: for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::17]:4711"
+		for _, element := range splitList(forwarded) {
+			for _, pair := range strings.Split(element, ";") {
+				key, value := splitPair(pair)
+				switch key {
+				case "for":
+					hops = append(hops, value)
+				case "host":
+					host = value
+				case "proto":
+					proto = strings.ToLower(value)
+				}
+			}
+		}
+		return hops, host, proto
+	}
+
+	hops = splitList(header.Values("X-Forwarded-For"))
+	if hosts := splitList(header.Values("X-Forwarded-Host")); len(hosts) > 0 {
+		host = hosts[len(hosts)-1]
+	}
+	if protos := splitList(header.Values("X-Forwarded-Proto")); len(protos) > 0 {
+		proto = strings.ToLower(protos[len(protos)-1])
+	}
+	return hops, host, proto
+}
+
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func splitPair(pair string) (string, string) {
+	parts := strings.SplitN(pair, "=", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return strings.ToLower(strings.TrimSpace(parts[0])), strings.Trim(strings.TrimSpace(parts[1]), `"`)
+}
+
+// parseHop parses an address from X-Forwarded-For or the "for" of Forwarded, which may have a port, e.g. "192.0.2.43",
+// "192.0.2.43:47011", "2001:db8::17" or "[2001:db8::17]:4711".
+func parseHop(hop string) net.IP {
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	return net.ParseIP(strings.Trim(hop, "[]"))
+}
+
+func hostOf(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sourceip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRequest(t *testing.T) {
+	for remoteAddr, want := range map[string]string{
+		"10.0.0.1:12345":   "10.0.0.1",
+		"[2001:db8::1]:80": "2001:db8::1",
+		"not-an-address":   "not-an-address",
+	} {
+		require.Equal(t, want, FromRequest(&http.Request{RemoteAddr: remoteAddr}))
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32"})
+	require.NoError(t, err)
+	require.True(t, trusted.TrustsPeer("10.1.2.3:12345"))
+	require.True(t, trusted.TrustsPeer("[2001:db8::1]:443"))
+	require.True(t, trusted.TrustsPeer("@"))
+	require.False(t, trusted.TrustsPeer("192.168.0.1:12345"))
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.1"})
+	require.EqualError(t, err, `invalid CIDR "10.0.0.1": invalid CIDR address: 10.0.0.1`)
+}
+
+func TestWrap(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		headers      map[string][]string
+		wantSourceIP string
+		wantHost     string
+		wantScheme   string
+	}{
+		{
+			name:         "untrusted peer",
+			remoteAddr:   "192.168.0.1:12345",
+			headers:      map[string][]string{"X-Forwarded-For": {"1.2.3.4"}, "X-Forwarded-Host": {"evil.example.com"}},
+			wantSourceIP: "192.168.0.1",
+			wantHost:     "issuer.example.com",
+		},
+		{
+			name:         "trusted peer without headers",
+			remoteAddr:   "10.0.0.1:12345",
+			wantSourceIP: "10.0.0.1",
+			wantHost:     "issuer.example.com",
+		},
+		{
+			name:       "trusted peer with X-Forwarded headers",
+			remoteAddr: "10.0.0.1:12345",
+			headers: map[string][]string{
+				"X-Forwarded-For":   {"1.2.3.4, 5.6.7.8", "10.0.0.2"},
+				"X-Forwarded-Host":  {"external.example.com"},
+				"X-Forwarded-Proto": {"HTTPS"},
+			},
+			wantSourceIP: "5.6.7.8",
+			wantHost:     "external.example.com",
+			wantScheme:   "https",
+		},
+		{
+			name:       "trusted peer with Forwarded header",
+			remoteAddr: "10.0.0.1:12345",
+			headers: map[string][]string{
+				"Forwarded":       {`for="[2001:db8::17]:4711";proto=https;host=external.example.com, for=10.0.0.2`},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			wantSourceIP: "2001:db8::17",
+			wantHost:     "external.example.com",
+			wantScheme:   "https",
+		},
+		{
+			name:         "trusted peer with an unknown client",
+			remoteAddr:   "10.0.0.1:12345",
+			headers:      map[string][]string{"Forwarded": {"for=unknown, for=10.0.0.2:80"}},
+			wantSourceIP: "10.0.0.2",
+			wantHost:     "issuer.example.com",
+		},
+		{
+			name:         "trusted peer with only trusted hops",
+			remoteAddr:   "10.0.0.1:12345",
+			headers:      map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			wantSourceIP: "10.0.0.3",
+			wantHost:     "issuer.example.com",
+		},
+		{
+			name:         "unix socket peer",
+			remoteAddr:   "@",
+			headers:      map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			wantSourceIP: "1.2.3.4",
+			wantHost:     "issuer.example.com",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/some/path", nil)
+			require.NoError(t, err)
+			r.RemoteAddr = test.remoteAddr
+			r.Host = "issuer.example.com"
+			for key, values := range test.headers {
+				r.Header[key] = values
+			}
+
+			var called bool
+			Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				require.Equal(t, test.wantSourceIP, FromRequest(r))
+				require.Equal(t, test.wantHost, r.Host)
+				require.Equal(t, test.wantScheme, r.URL.Scheme)
+			}), trusted).ServeHTTP(httptest.NewRecorder(), r)
+			require.True(t, called)
+		})
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"go.pinniped.dev/internal/dynamiccert"
 	"go.pinniped.dev/internal/kubeclient"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 const (
@@ -56,31 +57,33 @@ const (
 type webhook struct {
 	certProvider   dynamiccert.Private
 	secretInformer corev1informers.SecretInformer
+	tlsProfile     *tlsprofile.Profile
 }
 
 func newWebhook(
 	certProvider dynamiccert.Private,
 	secretInformer corev1informers.SecretInformer,
+	tlsProfile *tlsprofile.Profile,
 ) *webhook {
 	return &webhook{
 		certProvider:   certProvider,
 		secretInformer: secretInformer,
+		tlsProfile:     tlsProfile,
 	}
 }
 
 // start runs the webhook in a separate goroutine and returns whether or not the
 // webhook was started successfully.
 func (w *webhook) start(ctx context.Context, l net.Listener) error {
+	tlsConfig := w.tlsProfile.Config()
+	tlsConfig.GetCertificate = func(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		certPEM, keyPEM := w.certProvider.CurrentCertKeyContent()
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		return &cert, err
+	}
 	server := http.Server{
-		Handler: w,
-		TLSConfig: &tls.Config{
-			MinVersion: tls.VersionTLS13,
-			GetCertificate: func(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
-				certPEM, keyPEM := w.certProvider.CurrentCertKeyContent()
-				cert, err := tls.X509KeyPair(certPEM, keyPEM)
-				return &cert, err
-			},
-		},
+		Handler:   w,
+		TLSConfig: tlsConfig,
 	}
 
 	errCh := make(chan error)
@@ -329,8 +332,9 @@ func startWebhook(
 	l net.Listener,
 	dynamicCertProvider dynamiccert.Private,
 	secretInformer corev1informers.SecretInformer,
+	tlsProfile *tlsprofile.Profile,
 ) error {
-	return newWebhook(dynamicCertProvider, secretInformer).start(ctx, l)
+	return newWebhook(dynamicCertProvider, secretInformer, tlsProfile).start(ctx, l)
 }
 
 func waitForSignal() os.Signal {
@@ -343,6 +347,18 @@ func run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// The TLS_PROFILE environment variable is "modern" or "intermediate". This webhook has always only allowed TLS 1.3,
+	// so it defaults to the modern profile.
+	profileName := os.Getenv("TLS_PROFILE")
+	if profileName == "" {
+		profileName = tlsprofile.Modern
+	}
+	tlsProfile, err := tlsprofile.FromSpec(&tlsprofile.Spec{Profile: profileName})
+	if err != nil {
+		return fmt.Errorf("invalid TLS_PROFILE: %w", err)
+	}
+	tlsprofile.SetDefault(tlsProfile)
+
 	client, err := kubeclient.New()
 	if err != nil {
 		return fmt.Errorf("cannot create k8s client: %w", err)
@@ -366,7 +382,7 @@ func run() error {
 	}
 	defer func() { _ = l.Close() }()
 
-	err = startWebhook(ctx, l, dynamicCertProvider, kubeInformers.Core().V1().Secrets())
+	err = startWebhook(ctx, l, dynamicCertProvider, kubeInformers.Core().V1().Secrets(), tlsProfile)
 	if err != nil {
 		return fmt.Errorf("cannot start webhook: %w", err)
 	}
@@ -32,6 +32,7 @@ import (
 
 	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/dynamiccert"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 func TestWebhook(t *testing.T) {
@@ -95,7 +96,9 @@ func TestWebhook(t *testing.T) {
 	secretInformer := createSecretInformer(ctx, t, kubeClient)
 
 	certProvider, caBundle, serverName := newCertProvider(t)
-	w := newWebhook(certProvider, secretInformer)
+	tlsProfile, err := tlsprofile.FromSpec(&tlsprofile.Spec{Profile: tlsprofile.Modern})
+	require.NoError(t, err)
+	w := newWebhook(certProvider, secretInformer, tlsProfile)
 
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
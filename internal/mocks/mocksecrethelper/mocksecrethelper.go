@@ -10,6 +10,7 @@ package mocksecrethelper
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	v1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
@@ -40,6 +41,20 @@ func (m *MockSecretHelper) EXPECT() *MockSecretHelperMockRecorder {
 	return m.recorder
 }
 
+// ConditionType mocks base method.
+func (m *MockSecretHelper) ConditionType() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConditionType")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ConditionType indicates an expected call of ConditionType.
+func (mr *MockSecretHelperMockRecorder) ConditionType() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConditionType", reflect.TypeOf((*MockSecretHelper)(nil).ConditionType))
+}
+
 // Generate mocks base method.
 func (m *MockSecretHelper) Generate(arg0 *v1alpha1.FederationDomain) (*v1.Secret, error) {
 	m.ctrl.T.Helper()
@@ -110,3 +125,33 @@ func (mr *MockSecretHelperMockRecorder) ObserveActiveSecretAndUpdateParentFedera
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveActiveSecretAndUpdateParentFederationDomain", reflect.TypeOf((*MockSecretHelper)(nil).ObserveActiveSecretAndUpdateParentFederationDomain), arg0, arg1)
 }
+
+// Rotate mocks base method.
+func (m *MockSecretHelper) Rotate(arg0 *v1.Secret) (*v1.Secret, time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", arg0)
+	ret0, _ := ret[0].(*v1.Secret)
+	ret1, _ := ret[1].(time.Duration)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockSecretHelperMockRecorder) Rotate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockSecretHelper)(nil).Rotate), arg0)
+}
+
+// SuppliedSecretName mocks base method.
+func (m *MockSecretHelper) SuppliedSecretName(arg0 *v1alpha1.FederationDomain) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuppliedSecretName", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// SuppliedSecretName indicates an expected call of SuppliedSecretName.
+func (mr *MockSecretHelperMockRecorder) SuppliedSecretName(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuppliedSecretName", reflect.TypeOf((*MockSecretHelper)(nil).SuppliedSecretName), arg0)
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/psession"
+)
+
+// AuditEvent returns an audit event of the type for the request, with its ID and client, and with the user and
+// upstream identity provider of its session when it has one.
+func AuditEvent(eventType auditlog.EventType, requester fosite.Requester) auditlog.Event {
+	if requester == nil {
+		return auditlog.Event{Type: eventType}
+	}
+	return AuditEventForSession(eventType, requester, requester.GetSession())
+}
+
+// AuditEventForSession is like AuditEvent, but takes the user from a session which the request does not have yet.
+func AuditEventForSession(eventType auditlog.EventType, requester fosite.Requester, session fosite.Session) auditlog.Event {
+	event := auditlog.Event{Type: eventType, RequestID: requester.GetID()}
+	if client := requester.GetClient(); client != nil {
+		event.ClientID = client.GetID()
+	}
+
+	if pinnipedSession, ok := session.(*psession.PinnipedSession); ok {
+		if pinnipedSession.Fosite == nil {
+			return event
+		}
+		if pinnipedSession.Custom != nil {
+			event.UpstreamName = pinnipedSession.Custom.ProviderName
+			event.UpstreamType = string(pinnipedSession.Custom.ProviderType)
+		}
+	}
+	if oidcSession, ok := session.(openid.Session); ok && oidcSession.IDTokenClaims() != nil {
+		event.Username, _ = oidcSession.IDTokenClaims().Extra[DownstreamUsernameClaim].(string)
+		event.Groups = sessionGroups(session)
+	}
+	return event
+}
+
+// AuditReason returns the name of the OAuth error, which is safe to record because it never contains user input.
+func AuditReason(err error) string {
+	return fosite.ErrorToRFC6749Error(err).ErrorField
+}
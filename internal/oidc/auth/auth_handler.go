@@ -5,8 +5,12 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
@@ -15,15 +19,20 @@ import (
 	"github.com/ory/fosite/token/jwt"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
-	"k8s.io/apiserver/pkg/authentication/authenticator"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/httputil/httperr"
 	"go.pinniped.dev/internal/httputil/securityheader"
 	"go.pinniped.dev/internal/oidc"
 	"go.pinniped.dev/internal/oidc/csrftoken"
 	"go.pinniped.dev/internal/oidc/downstreamsession"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/requestobject"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
 	"go.pinniped.dev/pkg/oidcclient/pkce"
 )
@@ -33,16 +42,26 @@ const (
 	CustomPasswordHeaderName = "Pinniped-Password" //nolint:gosec // this is not a credential
 )
 
+// AuthorizeRequestStorage finds the clients and the pushed authorization requests which an authorization request
+// may refer to.
+type AuthorizeRequestStorage interface {
+	fosite.ClientManager
+	pushedauthorizationrequest.Storage
+}
+
 func NewHandler(
 	downstreamIssuer string,
 	idpLister oidc.UpstreamIdentityProvidersLister,
+	identityProviders provider.FederationDomainIdentityProviders,
 	oauthHelperWithoutStorage fosite.OAuth2Provider,
 	oauthHelperWithStorage fosite.OAuth2Provider,
+	authorizeRequestStorage AuthorizeRequestStorage,
 	generateCSRF func() (csrftoken.CSRFToken, error),
 	generatePKCE func() (pkce.Code, error),
 	generateNonce func() (nonce.Nonce, error),
-	upstreamStateEncoder oidc.Encoder,
+	upstreamStateEncoder oidc.Codec,
 	cookieCodec oidc.Codec,
+	loginLimiter *loginlimiter.Limiter,
 ) http.Handler {
 	return securityheader.Wrap(httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method != http.MethodPost && r.Method != http.MethodGet {
@@ -52,12 +71,37 @@ func NewHandler(
 			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET or POST)", r.Method)
 		}
 
-		oidcUpstream, ldapUpstream, err := chooseUpstreamIDP(idpLister)
+		if err := resolveAuthorizeParams(r, authorizeRequestStorage, downstreamIssuer, upstreamStateEncoder); err != nil {
+			var rfc6749Error *fosite.RFC6749Error
+			if !errors.As(err, &rfc6749Error) {
+				return err
+			}
+			plog.Info("authorize request error", oidc.FositeErrorForLog(err)...)
+			oauthHelperWithoutStorage.WriteAuthorizeError(w, fosite.NewAuthorizeRequest(), err)
+			return nil
+		}
+
+		oidcUpstream, ldapUpstream, err := chooseUpstreamIDP(r, idpLister, identityProviders)
 		if err != nil {
 			plog.WarningErr("authorize upstream config", err)
 			return err
 		}
 
+		if oidcUpstream == nil && ldapUpstream == nil {
+			if hasCustomCredentialHeaders(r) {
+				// A CLI cannot use the chooser page, so it must choose by itself.
+				return httperr.Newf(http.StatusUnprocessableEntity,
+					"Multiple upstream providers are configured, so the %s param must be specified",
+					oidc.AuthorizeUpstreamIDPNameParamName,
+				)
+			}
+			return handleAuthRequestWithoutUpstreamChoice(r, w,
+				oauthHelperWithoutStorage,
+				downstreamIssuer,
+				upstreamStateEncoder,
+			)
+		}
+
 		if oidcUpstream != nil {
 			return handleAuthRequestForOIDCUpstream(r, w,
 				oauthHelperWithoutStorage,
@@ -68,27 +112,164 @@ func NewHandler(
 				cookieCodec,
 			)
 		}
+		if !hasCustomCredentialHeaders(r) {
+			// The request does not come from a CLI which knows how to send credentials in headers, so it is
+			// probably a web browser. Send the browser to our own login page to collect the credentials.
+			return handleAuthRequestForLDAPUpstreamBrowserFlow(r, w,
+				oauthHelperWithoutStorage,
+				generateCSRF, generateNonce, generatePKCE,
+				ldapUpstream,
+				downstreamIssuer,
+				upstreamStateEncoder,
+				cookieCodec,
+			)
+		}
 		return handleAuthRequestForLDAPUpstream(r, w,
 			oauthHelperWithStorage,
 			ldapUpstream,
+			loginLimiter,
 		)
 	}))
 }
 
+// resolveAuthorizeParams replaces the params of a request which refers to a pushed authorization request, which
+// carries a request object, or which continues a request from the identity provider chooser page, with the
+// authorization request params which they stand for. Nothing else in this handler, including fosite, ever sees the
+// request_uri, request or chooser state params.
+func resolveAuthorizeParams(r *http.Request, storage AuthorizeRequestStorage, downstreamIssuer string, stateDecoder oidc.Decoder) error {
+	if err := r.ParseForm(); err != nil {
+		return errors.WithStack(fosite.ErrInvalidRequest.WithHint(
+			"Unable to parse the request params.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	requestURI := r.Form.Get("request_uri")
+	requestObject := r.Form.Get("request")
+	chooserState := r.Form.Get(oidc.AuthorizeChooseIDPStateParamName)
+	clientID := r.Form.Get("client_id")
+
+	switch {
+	case requestURI != "" && requestObject != "":
+		return errors.WithStack(fosite.ErrInvalidRequest.WithHint(
+			"The request and request_uri params must not both be given."))
+	case chooserState != "" && (requestURI != "" || requestObject != ""):
+		return errors.WithStack(fosite.ErrInvalidRequest.WithHintf(
+			"The %s param must not be given together with the request or request_uri params.", oidc.AuthorizeChooseIDPStateParamName))
+	case chooserState != "":
+		params, err := chooserAuthorizeParams(stateDecoder, chooserState)
+		if err != nil {
+			return err
+		}
+		// The chooser page only adds the choice of the identity provider to the original params.
+		params.Set(oidc.AuthorizeUpstreamIDPNameParamName, r.Form.Get(oidc.AuthorizeUpstreamIDPNameParamName))
+		params.Set(oidc.AuthorizeUpstreamIDPTypeParamName, r.Form.Get(oidc.AuthorizeUpstreamIDPTypeParamName))
+		r.Form = params
+	case requestURI != "":
+		params, err := pushedAuthorizeParams(r.Context(), storage, clientID, requestURI)
+		if err != nil {
+			return err
+		}
+		r.Form = params
+	case requestObject != "":
+		client, err := storage.GetClient(r.Context(), clientID)
+		if err != nil {
+			return errors.WithStack(fosite.ErrInvalidClient.WithHint(
+				"The requested OAuth 2.0 Client does not exist.").WithWrap(err).WithDebug(err.Error()))
+		}
+		params, err := requestobject.Params(client, downstreamIssuer, requestObject, time.Now())
+		if err != nil {
+			return err
+		}
+		r.Form = params
+	}
+
+	return nil
+}
+
+// chooserAuthorizeParams returns the params of the original authorize request which the identity provider chooser
+// page was shown for.
+func chooserAuthorizeParams(stateDecoder oidc.Decoder, encodedState string) (url.Values, error) {
+	var state oidc.ChooseIDPStateParamData
+	if err := stateDecoder.Decode(oidc.ChooseIDPStateParamEncodingName, encodedState, &state); err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithHintf(
+			"The %s param is not valid. It may have expired.", oidc.AuthorizeChooseIDPStateParamName).WithWrap(err).WithDebug(err.Error()))
+	}
+	if state.FormatVersion != oidc.UpstreamStateParamFormatVersion {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithHintf(
+			"The %s param has an unsupported format version.", oidc.AuthorizeChooseIDPStateParamName))
+	}
+	params, err := url.ParseQuery(state.AuthParams)
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithHintf(
+			"The %s param is not valid.", oidc.AuthorizeChooseIDPStateParamName).WithWrap(err).WithDebug(err.Error()))
+	}
+	return params, nil
+}
+
+// pushedAuthorizeParams returns the params of the pushed authorization request which the request_uri refers to.
+// Each request_uri can be used only once.
+func pushedAuthorizeParams(ctx context.Context, storage pushedauthorizationrequest.Storage, clientID, requestURI string) (url.Values, error) {
+	if !strings.HasPrefix(requestURI, oidc.PushedAuthorizationRequestURIPrefix) {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestURI.WithHint(
+			"Only request_uri values from the pushed authorization request endpoint are supported."))
+	}
+	reference := strings.TrimPrefix(requestURI, oidc.PushedAuthorizationRequestURIPrefix)
+
+	pushedRequest, err := storage.GetPushedAuthorizeRequest(ctx, reference)
+	if errors.Is(err, fosite.ErrNotFound) {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestURI.WithHint(
+			"The request_uri is not valid. It may have been used already."))
+	}
+	if err != nil {
+		plog.Error("authorize pushed authorization request storage error", err)
+		return nil, httperr.Wrap(http.StatusInternalServerError, "error getting pushed authorization request", err)
+	}
+
+	if pushedRequest.ClientID != clientID {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestURI.WithHint(
+			"The request_uri was not pushed by the client in the client_id param."))
+	}
+
+	err = storage.DeletePushedAuthorizeRequest(ctx, reference)
+	if apierrors.IsNotFound(err) {
+		// Another request used the same request_uri in the meantime.
+		return nil, errors.WithStack(fosite.ErrInvalidRequestURI.WithHint(
+			"The request_uri is not valid. It may have been used already."))
+	}
+	if err != nil {
+		plog.Error("authorize pushed authorization request storage error", err)
+		return nil, httperr.Wrap(http.StatusInternalServerError, "error deleting pushed authorization request", err)
+	}
+
+	if !time.Now().Before(pushedRequest.ExpiresAt) {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestURI.WithHint(
+			"The request_uri has expired."))
+	}
+
+	params := url.Values{}
+	for name, values := range pushedRequest.Params {
+		params[name] = values
+	}
+	params.Set("client_id", pushedRequest.ClientID)
+	return params, nil
+}
+
 func handleAuthRequestForLDAPUpstream(
 	r *http.Request,
 	w http.ResponseWriter,
 	oauthHelper fosite.OAuth2Provider,
 	ldapUpstream provider.UpstreamLDAPIdentityProviderI,
+	loginLimiter *loginlimiter.Limiter,
 ) error {
 	authorizeRequester, created := newAuthorizeRequest(r, w, oauthHelper)
 	if !created {
 		return nil
 	}
+	recordAuthorizeStarted(r, authorizeRequester, ldapUpstream.GetName(), psession.ProviderTypeLDAP)
 
 	username := r.Header.Get(CustomUsernameHeaderName)
 	password := r.Header.Get(CustomPasswordHeaderName)
 	if username == "" || password == "" {
+		recordLDAPAuthenticationFailed(r, authorizeRequester, ldapUpstream, username, "missing_credentials")
 		// Return an error according to OIDC spec 3.1.2.6 (second paragraph).
 		err := errors.WithStack(fosite.ErrAccessDenied.WithHintf("Missing or blank username or password."))
 		plog.Info("authorize response error", oidc.FositeErrorForLog(err)...)
@@ -96,25 +277,53 @@ func handleAuthRequestForLDAPUpstream(
 		return nil
 	}
 
+	attempt := loginlimiter.NewAttempt(r, ldapUpstream.GetName(), username)
+	if err := loginLimiter.Begin(r.Context(), attempt); err != nil {
+		var limitedErr *loginlimiter.LimitedError
+		if errors.As(err, &limitedErr) {
+			w.Header().Set("Retry-After", strconv.FormatInt(limitedErr.RetryAfterSeconds(), 10))
+			err = errors.WithStack(fosite.ErrTemporarilyUnavailable.WithHintf(
+				"Too many failed login attempts. Try again in %d seconds.", limitedErr.RetryAfterSeconds()))
+		} else {
+			plog.WarningErr("error checking failed login attempts", err, "upstreamName", ldapUpstream.GetName())
+			err = errors.WithStack(fosite.ErrTemporarilyUnavailable.WithHint("Could not check for failed login attempts."))
+		}
+		plog.Info("authorize response error", oidc.FositeErrorForLog(err)...)
+		oauthHelper.WriteAuthorizeError(w, authorizeRequester, err)
+		return nil
+	}
+
 	authenticateResponse, authenticated, err := ldapUpstream.AuthenticateUser(r.Context(), username, password)
 	if err != nil {
 		plog.WarningErr("unexpected error during upstream LDAP authentication", err, "upstreamName", ldapUpstream.GetName())
+		loginLimiter.RecordError(r.Context(), attempt)
+		recordLDAPAuthenticationFailed(r, authorizeRequester, ldapUpstream, username, "upstream_error")
 		return httperr.New(http.StatusBadGateway, "unexpected error during upstream authentication")
 	}
 	if !authenticated {
 		plog.Debug("failed upstream LDAP authentication", "upstreamName", ldapUpstream.GetName())
+		recordLDAPAuthenticationFailed(r, authorizeRequester, ldapUpstream, username, "invalid_credentials")
+		loginLimiter.RecordFailure(attempt)
 		// Return an error according to OIDC spec 3.1.2.6 (second paragraph).
 		err = errors.WithStack(fosite.ErrAccessDenied.WithHintf("Username/password not accepted by LDAP provider."))
 		plog.Info("authorize response error", oidc.FositeErrorForLog(err)...)
 		oauthHelper.WriteAuthorizeError(w, authorizeRequester, err)
 		return nil
 	}
+	if err := loginLimiter.RecordSuccess(r.Context(), attempt); err != nil {
+		plog.WarningErr("error forgetting failed login attempts", err, "upstreamName", ldapUpstream.GetName())
+	}
 
 	openIDSession := downstreamsession.MakeDownstreamSession(
-		downstreamSubjectFromUpstreamLDAP(ldapUpstream, authenticateResponse),
+		downstreamsession.DownstreamLDAPSubject(authenticateResponse.User.GetUID(), *ldapUpstream.GetURL()),
 		authenticateResponse.User.GetName(),
 		authenticateResponse.User.GetGroups(),
+		&psession.CustomSessionData{
+			ProviderName: ldapUpstream.GetName(),
+			ProviderType: psession.ProviderTypeLDAP,
+		},
 	)
+	auditlog.Record(r.Context(), oidc.AuditEventForSession(auditlog.UpstreamAuthenticationSucceeded, authorizeRequester, openIDSession))
 
 	authorizeResponder, err := oauthHelper.NewAuthorizeResponse(r.Context(), authorizeRequester, openIDSession)
 	if err != nil {
@@ -128,14 +337,48 @@ func handleAuthRequestForLDAPUpstream(
 	return nil
 }
 
-func handleAuthRequestForOIDCUpstream(
+func handleAuthRequestWithoutUpstreamChoice(
+	r *http.Request,
+	w http.ResponseWriter,
+	oauthHelper fosite.OAuth2Provider,
+	downstreamIssuer string,
+	stateEncoder oidc.Encoder,
+) error {
+	authorizeRequester, created := newAuthorizeRequest(r, w, oauthHelper)
+	if !created {
+		return nil
+	}
+
+	if !validateAuthorizeRequest(r, w, oauthHelper, authorizeRequester) {
+		return nil
+	}
+
+	encodedStateParamValue, err := stateEncoder.Encode(oidc.ChooseIDPStateParamEncodingName, oidc.ChooseIDPStateParamData{
+		AuthParams:    authorizeRequester.GetRequestForm().Encode(),
+		FormatVersion: oidc.UpstreamStateParamFormatVersion,
+	})
+	if err != nil {
+		plog.Error("authorize chooser state param error", err)
+		return httperr.Wrap(http.StatusInternalServerError, "error encoding chooser state param", err)
+	}
+
+	chooserQuery := url.Values{"state": []string{encodedStateParamValue}}
+	http.Redirect(w, r,
+		fmt.Sprintf("%s%s?%s", downstreamIssuer, oidc.ChooseIDPEndpointPath, chooserQuery.Encode()),
+		http.StatusFound,
+	)
+
+	return nil
+}
+
+func handleAuthRequestForLDAPUpstreamBrowserFlow(
 	r *http.Request,
 	w http.ResponseWriter,
 	oauthHelper fosite.OAuth2Provider,
 	generateCSRF func() (csrftoken.CSRFToken, error),
 	generateNonce func() (nonce.Nonce, error),
 	generatePKCE func() (pkce.Code, error),
-	oidcUpstream provider.UpstreamOIDCIdentityProviderI,
+	ldapUpstream provider.UpstreamLDAPIdentityProviderI,
 	downstreamIssuer string,
 	upstreamStateEncoder oidc.Encoder,
 	cookieCodec oidc.Codec,
@@ -145,60 +388,75 @@ func handleAuthRequestForOIDCUpstream(
 		return nil
 	}
 
-	now := time.Now()
-	_, err := oauthHelper.NewAuthorizeResponse(r.Context(), authorizeRequester, &openid.DefaultSession{
-		Claims: &jwt.IDTokenClaims{
-			// Temporary claim values to allow `NewAuthorizeResponse` to perform other OIDC validations.
-			Subject:     "none",
-			AuthTime:    now,
-			RequestedAt: now,
-		},
-	})
-	if err != nil {
-		plog.Info("authorize response error", oidc.FositeErrorForLog(err)...)
-		oauthHelper.WriteAuthorizeError(w, authorizeRequester, err)
+	if !validateAuthorizeRequest(r, w, oauthHelper, authorizeRequester) {
 		return nil
 	}
+	recordAuthorizeStarted(r, authorizeRequester, ldapUpstream.GetName(), psession.ProviderTypeLDAP)
 
-	csrfValue, nonceValue, pkceValue, err := generateValues(generateCSRF, generateNonce, generatePKCE)
+	_, _, encodedStateParamValue, err := upstreamStateParamAndCSRFCookie(
+		r, w,
+		authorizeRequester,
+		generateCSRF, generateNonce, generatePKCE,
+		ldapUpstream.GetName(),
+		psession.ProviderTypeLDAP,
+		upstreamStateEncoder,
+		cookieCodec,
+	)
 	if err != nil {
-		plog.Error("authorize generate error", err)
 		return err
 	}
-	csrfFromCookie := readCSRFCookie(r, cookieCodec)
-	if csrfFromCookie != "" {
-		csrfValue = csrfFromCookie
+
+	loginQuery := url.Values{"state": []string{encodedStateParamValue}}
+	http.Redirect(w, r,
+		fmt.Sprintf("%s%s?%s", downstreamIssuer, oidc.LoginEndpointPath, loginQuery.Encode()),
+		http.StatusFound,
+	)
+
+	return nil
+}
+
+func handleAuthRequestForOIDCUpstream(
+	r *http.Request,
+	w http.ResponseWriter,
+	oauthHelper fosite.OAuth2Provider,
+	generateCSRF func() (csrftoken.CSRFToken, error),
+	generateNonce func() (nonce.Nonce, error),
+	generatePKCE func() (pkce.Code, error),
+	oidcUpstream provider.UpstreamOIDCIdentityProviderI,
+	downstreamIssuer string,
+	upstreamStateEncoder oidc.Encoder,
+	cookieCodec oidc.Codec,
+) error {
+	authorizeRequester, created := newAuthorizeRequest(r, w, oauthHelper)
+	if !created {
+		return nil
 	}
 
-	upstreamOAuthConfig := oauth2.Config{
-		ClientID: oidcUpstream.GetClientID(),
-		Endpoint: oauth2.Endpoint{
-			AuthURL: oidcUpstream.GetAuthorizationURL().String(),
-		},
-		RedirectURL: fmt.Sprintf("%s/callback", downstreamIssuer),
-		Scopes:      oidcUpstream.GetScopes(),
+	if !validateAuthorizeRequest(r, w, oauthHelper, authorizeRequester) {
+		return nil
 	}
+	recordAuthorizeStarted(r, authorizeRequester, oidcUpstream.GetName(), psession.ProviderTypeOIDC)
 
-	encodedStateParamValue, err := upstreamStateParam(
+	nonceValue, pkceValue, encodedStateParamValue, err := upstreamStateParamAndCSRFCookie(
+		r, w,
 		authorizeRequester,
+		generateCSRF, generateNonce, generatePKCE,
 		oidcUpstream.GetName(),
-		nonceValue,
-		csrfValue,
-		pkceValue,
+		psession.ProviderTypeOIDC,
 		upstreamStateEncoder,
+		cookieCodec,
 	)
 	if err != nil {
-		plog.Error("authorize upstream state param error", err)
 		return err
 	}
 
-	if csrfFromCookie == "" {
-		// We did not receive an incoming CSRF cookie, so write a new one.
-		err := addCSRFSetCookieHeader(w, csrfValue, cookieCodec)
-		if err != nil {
-			plog.Error("error setting CSRF cookie", err)
-			return err
-		}
+	upstreamOAuthConfig := oauth2.Config{
+		ClientID: oidcUpstream.GetClientID(),
+		Endpoint: oauth2.Endpoint{
+			AuthURL: oidcUpstream.GetAuthorizationURL().String(),
+		},
+		RedirectURL: fmt.Sprintf("%s/callback", downstreamIssuer),
+		Scopes:      oidcUpstream.GetScopes(),
 	}
 
 	authCodeOptions := []oauth2.AuthCodeOption{
@@ -224,6 +482,102 @@ func handleAuthRequestForOIDCUpstream(
 	return nil
 }
 
+func recordAuthorizeStarted(r *http.Request, authorizeRequester fosite.AuthorizeRequester, upstreamName string, upstreamType psession.ProviderType) {
+	event := oidc.AuditEvent(auditlog.AuthorizeStarted, authorizeRequester)
+	event.UpstreamName = upstreamName
+	event.UpstreamType = string(upstreamType)
+	auditlog.Record(r.Context(), event)
+}
+
+func recordLDAPAuthenticationFailed(r *http.Request, authorizeRequester fosite.AuthorizeRequester, ldapUpstream provider.UpstreamLDAPIdentityProviderI, username string, reason string) {
+	event := oidc.AuditEvent(auditlog.UpstreamAuthenticationFailed, authorizeRequester)
+	event.UpstreamName = ldapUpstream.GetName()
+	event.UpstreamType = string(psession.ProviderTypeLDAP)
+	event.Username = username
+	event.Reason = reason
+	auditlog.Record(r.Context(), event)
+}
+
+// validateAuthorizeRequest performs the OIDC validations inside NewAuthorizeResponse without storing anything,
+// so that invalid requests are rejected before the end user is asked to log in.
+func validateAuthorizeRequest(r *http.Request, w http.ResponseWriter, oauthHelper fosite.OAuth2Provider, authorizeRequester fosite.AuthorizeRequester) bool {
+	now := time.Now()
+	_, err := oauthHelper.NewAuthorizeResponse(r.Context(), authorizeRequester, &psession.PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims: &jwt.IDTokenClaims{
+				// Temporary claim values to allow `NewAuthorizeResponse` to perform other OIDC validations.
+				Subject:     "none",
+				AuthTime:    now,
+				RequestedAt: now,
+			},
+		},
+	})
+	if err != nil {
+		plog.Info("authorize response error", oidc.FositeErrorForLog(err)...)
+		oauthHelper.WriteAuthorizeError(w, authorizeRequester, err)
+		return false
+	}
+	return true
+}
+
+// upstreamStateParamAndCSRFCookie generates the values needed to continue the login in a web browser, encodes them
+// into a state param, and sets the CSRF cookie on the response when the browser did not already have one.
+func upstreamStateParamAndCSRFCookie(
+	r *http.Request,
+	w http.ResponseWriter,
+	authorizeRequester fosite.AuthorizeRequester,
+	generateCSRF func() (csrftoken.CSRFToken, error),
+	generateNonce func() (nonce.Nonce, error),
+	generatePKCE func() (pkce.Code, error),
+	upstreamName string,
+	upstreamType psession.ProviderType,
+	upstreamStateEncoder oidc.Encoder,
+	cookieCodec oidc.Codec,
+) (nonce.Nonce, pkce.Code, string, error) {
+	csrfValue, nonceValue, pkceValue, err := generateValues(generateCSRF, generateNonce, generatePKCE)
+	if err != nil {
+		plog.Error("authorize generate error", err)
+		return "", "", "", err
+	}
+	csrfFromCookie := readCSRFCookie(r, cookieCodec)
+	if csrfFromCookie != "" {
+		csrfValue = csrfFromCookie
+	}
+
+	encodedStateParamValue, err := upstreamStateParam(
+		authorizeRequester,
+		upstreamName,
+		upstreamType,
+		nonceValue,
+		csrfValue,
+		pkceValue,
+		upstreamStateEncoder,
+	)
+	if err != nil {
+		plog.Error("authorize upstream state param error", err)
+		return "", "", "", err
+	}
+
+	if csrfFromCookie == "" {
+		// We did not receive an incoming CSRF cookie, so write a new one.
+		err := addCSRFSetCookieHeader(w, csrfValue, cookieCodec)
+		if err != nil {
+			plog.Error("error setting CSRF cookie", err)
+			return "", "", "", err
+		}
+	}
+
+	return nonceValue, pkceValue, encodedStateParamValue, nil
+}
+
+// hasCustomCredentialHeaders returns true when the client sent either of the custom username and password headers,
+// which only non-browser clients such as the Pinniped CLI will do.
+func hasCustomCredentialHeaders(r *http.Request) bool {
+	_, hasUsername := r.Header[http.CanonicalHeaderKey(CustomUsernameHeaderName)]
+	_, hasPassword := r.Header[http.CanonicalHeaderKey(CustomPasswordHeaderName)]
+	return hasUsername || hasPassword
+}
+
 func newAuthorizeRequest(r *http.Request, w http.ResponseWriter, oauthHelper fosite.OAuth2Provider) (fosite.AuthorizeRequester, bool) {
 	authorizeRequester, err := oauthHelper.NewAuthorizeRequest(r.Context(), r)
 	if err != nil {
@@ -260,33 +614,78 @@ func readCSRFCookie(r *http.Request, codec oidc.Decoder) csrftoken.CSRFToken {
 	return csrfFromCookie
 }
 
-// Select either an OIDC or an LDAP IDP, or return an error.
-func chooseUpstreamIDP(idpLister oidc.UpstreamIdentityProvidersLister) (provider.UpstreamOIDCIdentityProviderI, provider.UpstreamLDAPIdentityProviderI, error) {
+// Select either an OIDC or an LDAP IDP, or return an error. When the end user needs to choose, both are nil.
+func chooseUpstreamIDP(
+	r *http.Request,
+	idpLister oidc.UpstreamIdentityProvidersLister,
+	identityProviders provider.FederationDomainIdentityProviders,
+) (provider.UpstreamOIDCIdentityProviderI, provider.UpstreamLDAPIdentityProviderI, error) {
 	oidcUpstreams := idpLister.GetOIDCIdentityProviders()
 	ldapUpstreams := idpLister.GetLDAPIdentityProviders()
+
+	if requestedName := r.FormValue(oidc.AuthorizeUpstreamIDPNameParamName); requestedName != "" {
+		return findUpstreamIDP(requestedName, r.FormValue(oidc.AuthorizeUpstreamIDPTypeParamName), oidcUpstreams, ldapUpstreams)
+	}
+
 	switch {
 	case len(oidcUpstreams)+len(ldapUpstreams) == 0:
 		return nil, nil, httperr.New(
 			http.StatusUnprocessableEntity,
 			"No upstream providers are configured",
 		)
-	case len(oidcUpstreams)+len(ldapUpstreams) > 1:
-		var upstreamIDPNames []string
-		for _, idp := range oidcUpstreams {
-			upstreamIDPNames = append(upstreamIDPNames, idp.GetName())
+	case len(oidcUpstreams) == 1 && len(ldapUpstreams) == 0:
+		return oidcUpstreams[0], nil, nil
+	case len(oidcUpstreams) == 0 && len(ldapUpstreams) == 1:
+		return nil, ldapUpstreams[0], nil
+	}
+
+	if identityProviders.DefaultName != "" {
+		oidcUpstream, ldapUpstream, err := findUpstreamIDP(identityProviders.DefaultName, "", oidcUpstreams, ldapUpstreams)
+		if err == nil {
+			return oidcUpstream, ldapUpstream, nil
 		}
-		for _, idp := range ldapUpstreams {
-			upstreamIDPNames = append(upstreamIDPNames, idp.GetName())
+		plog.Warning("default upstream provider could not be used", "defaultName", identityProviders.DefaultName, "reason", err.Error())
+	}
+
+	return nil, nil, nil
+}
+
+// findUpstreamIDP finds the upstream IDP with the given name. The type is optional, unless several upstream IDPs
+// of different types have the same name.
+func findUpstreamIDP(
+	name string,
+	idpType string,
+	oidcUpstreams []provider.UpstreamOIDCIdentityProviderI,
+	ldapUpstreams []provider.UpstreamLDAPIdentityProviderI,
+) (provider.UpstreamOIDCIdentityProviderI, provider.UpstreamLDAPIdentityProviderI, error) {
+	var foundOIDC provider.UpstreamOIDCIdentityProviderI
+	var foundLDAP provider.UpstreamLDAPIdentityProviderI
+	if idpType == "" || idpType == string(psession.ProviderTypeOIDC) {
+		for _, p := range oidcUpstreams {
+			if p.GetName() == name {
+				foundOIDC = p
+			}
 		}
-		plog.Warning("Too many upstream providers are configured (found: %s)", upstreamIDPNames)
-		return nil, nil, httperr.New(
+	}
+	if idpType == "" || idpType == string(psession.ProviderTypeLDAP) {
+		for _, p := range ldapUpstreams {
+			if p.GetName() == name {
+				foundLDAP = p
+			}
+		}
+	}
+
+	switch {
+	case foundOIDC != nil && foundLDAP != nil:
+		return nil, nil, httperr.Newf(
 			http.StatusUnprocessableEntity,
-			"Too many upstream providers are configured (support for multiple upstreams is not yet implemented)",
+			"Multiple upstream providers are named %q, so the %s param must be specified",
+			name, oidc.AuthorizeUpstreamIDPTypeParamName,
 		)
-	case len(oidcUpstreams) == 1:
-		return oidcUpstreams[0], nil, nil
+	case foundOIDC == nil && foundLDAP == nil:
+		return nil, nil, httperr.Newf(http.StatusUnprocessableEntity, "Upstream provider not found: %q", name)
 	default:
-		return nil, ldapUpstreams[0], nil
+		return foundOIDC, foundLDAP, nil
 	}
 }
 
@@ -313,6 +712,7 @@ func generateValues(
 func upstreamStateParam(
 	authorizeRequester fosite.AuthorizeRequester,
 	upstreamName string,
+	upstreamType psession.ProviderType,
 	nonceValue nonce.Nonce,
 	csrfValue csrftoken.CSRFToken,
 	pkceValue pkce.Code,
@@ -321,6 +721,7 @@ func upstreamStateParam(
 	stateParamData := oidc.UpstreamStateParamData{
 		AuthParams:    authorizeRequester.GetRequestForm().Encode(),
 		UpstreamName:  upstreamName,
+		UpstreamType:  string(upstreamType),
 		Nonce:         nonceValue,
 		CSRFToken:     csrfValue,
 		PKCECode:      pkceValue,
@@ -350,11 +751,3 @@ func addCSRFSetCookieHeader(w http.ResponseWriter, csrfValue csrftoken.CSRFToken
 
 	return nil
 }
-
-func downstreamSubjectFromUpstreamLDAP(ldapUpstream provider.UpstreamLDAPIdentityProviderI, authenticateResponse *authenticator.Response) string {
-	ldapURL := *ldapUpstream.GetURL()
-	q := ldapURL.Query()
-	q.Set(oidc.IDTokenSubjectClaim, authenticateResponse.User.GetUID())
-	ldapURL.RawQuery = q.Encode()
-	return ldapURL.String()
-}
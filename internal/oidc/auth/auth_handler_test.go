@@ -5,6 +5,9 @@ package auth
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
 	"html"
 	"net/http"
@@ -13,21 +16,30 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/securecookie"
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes/fake"
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	coretesting "k8s.io/client-go/testing"
 	"k8s.io/utils/pointer"
 
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/clientregistry"
 	"go.pinniped.dev/internal/oidc/csrftoken"
 	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/oidctestutil"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
@@ -119,6 +131,18 @@ func TestAuthorizationEndpoint(t *testing.T) {
 			"state":             happyState,
 		}
 
+		fositeTemporarilyUnavailableWithTooManyFailuresHintErrorQuery = map[string]string{
+			"error":             "temporarily_unavailable",
+			"error_description": "The authorization server is currently unable to handle the request due to a temporary overloading or maintenance of the server. Too many failed login attempts. Try again in 30 seconds.",
+			"state":             happyState,
+		}
+
+		fositeTemporarilyUnavailableWithUncheckedFailuresHintErrorQuery = map[string]string{
+			"error":             "temporarily_unavailable",
+			"error_description": "The authorization server is currently unable to handle the request due to a temporary overloading or maintenance of the server. Could not check for failed login attempts.",
+			"state":             happyState,
+		}
+
 		fositeAccessDeniedWithMissingUsernamePasswordHintErrorQuery = map[string]string{
 			"error":             "access_denied",
 			"error_description": "The resource owner or authorization server denied the request. Missing or blank username or password.",
@@ -134,13 +158,13 @@ func TestAuthorizationEndpoint(t *testing.T) {
 	createOauthHelperWithRealStorage := func(secretsClient v1.SecretInterface) (fosite.OAuth2Provider, *oidc.KubeStorage) {
 		// Configure fosite the same way that the production code would when using Kube storage.
 		// Inject this into our test subject at the last second so we get a fresh storage for every test.
-		kubeOauthStore := oidc.NewKubeStorage(secretsClient, timeoutsConfiguration)
-		return oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration), kubeOauthStore
+		kubeOauthStore := oidc.NewKubeStorage(secretsClient, nil, timeoutsConfiguration)
+		return oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, nil, nil, nil, timeoutsConfiguration), kubeOauthStore
 	}
 
 	// Configure fosite the same way that the production code would, using NullStorage to turn off storage.
 	nullOauthStore := oidc.NullStorage{}
-	oauthHelperWithNullStorage := oidc.FositeOauth2Helper(nullOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration)
+	oauthHelperWithNullStorage := oidc.FositeOauth2Helper(nullOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, nil, nil, nil, timeoutsConfiguration)
 
 	upstreamAuthURL, err := url.Parse("https://some-upstream-idp:8443/auth")
 	require.NoError(t, err)
@@ -181,6 +205,19 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		},
 	}
 
+	otherUpstreamOIDCIdentityProvider := oidctestutil.TestUpstreamOIDCIdentityProvider{
+		Name:             "some-other-oidc-idp",
+		ClientID:         "some-other-client-id",
+		AuthorizationURL: *upstreamAuthURL,
+		Scopes:           []string{"other-scope1", "other-scope2"},
+	}
+
+	otherUpstreamLDAPIdentityProvider := upstreamLDAPIdentityProvider
+	otherUpstreamLDAPIdentityProvider.Name = "some-other-ldap-idp"
+
+	sameNameAsOIDCUpstreamLDAPIdentityProvider := upstreamLDAPIdentityProvider
+	sameNameAsOIDCUpstreamLDAPIdentityProvider.Name = upstreamOIDCIdentityProvider.Name
+
 	erroringUpstreamLDAPIdentityProvider := oidctestutil.TestUpstreamLDAPIdentityProvider{
 		Name: "some-ldap-idp",
 		AuthenticateFunc: func(ctx context.Context, username, password string) (*authenticator.Response, bool, error) {
@@ -271,19 +308,16 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		return pathWithQuery("/some/path", modifiedHappyGetRequestQueryMap(queryOverrides))
 	}
 
-	expectedUpstreamStateParam := func(queryOverrides map[string]string, csrfValueOverride, upstreamNameOverride string) string {
+	expectedUpstreamStateParamForType := func(queryOverrides map[string]string, csrfValueOverride, upstreamName, upstreamType string) string {
 		csrf := happyCSRF
 		if csrfValueOverride != "" {
 			csrf = csrfValueOverride
 		}
-		upstreamName := upstreamOIDCIdentityProvider.Name
-		if upstreamNameOverride != "" {
-			upstreamName = upstreamNameOverride
-		}
 		encoded, err := happyStateEncoder.Encode("s",
 			oidctestutil.ExpectedUpstreamStateParamFormat{
 				P: encodeQuery(modifiedHappyGetRequestQueryMap(queryOverrides)),
 				U: upstreamName,
+				T: upstreamType,
 				N: happyNonce,
 				C: csrf,
 				K: happyPKCE,
@@ -294,6 +328,31 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		return encoded
 	}
 
+	expectedUpstreamStateParam := func(queryOverrides map[string]string, csrfValueOverride, upstreamNameOverride string) string {
+		upstreamName := upstreamOIDCIdentityProvider.Name
+		if upstreamNameOverride != "" {
+			upstreamName = upstreamNameOverride
+		}
+		return expectedUpstreamStateParamForType(queryOverrides, csrfValueOverride, upstreamName, "oidc")
+	}
+
+	expectedRedirectLocationForLDAPLoginPage := func(csrfValueOverride string) string {
+		return urlWithQuery(downstreamIssuer+"/login", map[string]string{
+			"state": expectedUpstreamStateParamForType(nil, csrfValueOverride, upstreamLDAPIdentityProvider.Name, "ldap"),
+		})
+	}
+
+	expectedRedirectLocationForChooserPage := func(queryOverrides map[string]string) string {
+		encoded, err := happyStateEncoder.Encode("i",
+			oidctestutil.ExpectedChooseIDPStateParamFormat{
+				P: encodeQuery(modifiedHappyGetRequestQueryMap(queryOverrides)),
+				V: "1",
+			},
+		)
+		require.NoError(t, err)
+		return urlWithQuery(downstreamIssuer+"/choose_identity_provider", map[string]string{"state": encoded})
+	}
+
 	expectedRedirectLocationForUpstreamOIDC := func(expectedUpstreamState string, expectedPrompt string) string {
 		query := map[string]string{
 			"response_type":         "code",
@@ -315,6 +374,52 @@ func TestAuthorizationEndpoint(t *testing.T) {
 	// Note that fosite puts the granted scopes as a param in the redirect URI even though the spec doesn't seem to require it
 	happyAuthcodeDownstreamRedirectLocationRegexp := downstreamRedirectURI + `\?code=([^&]+)&scope=openid&state=` + happyState
 
+	happyPushedAuthorizeRequest := func(expiresAt time.Time) *pushedauthorizationrequest.Request {
+		params := url.Values{}
+		for k, v := range happyGetRequestQueryMap {
+			params.Set(k, v)
+		}
+		return &pushedauthorizationrequest.Request{ClientID: downstreamClientID, Params: params, ExpiresAt: expiresAt}
+	}
+	happyPushedAuthorizeRequests := map[string]*pushedauthorizationrequest.Request{
+		"happy-reference": happyPushedAuthorizeRequest(time.Now().Add(time.Minute)),
+	}
+	happyRequestURIPath := pathWithQuery("/some/path", map[string]string{
+		"client_id":   downstreamClientID,
+		"request_uri": "urn:ietf:params:oauth:request_uri:happy-reference",
+	})
+
+	requestObjectSigningKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientJWKS := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: requestObjectSigningKey.Public(), KeyID: "client-key"}}}
+	// chooserState is the state param which the chooser page puts into its links back to the authorize endpoint.
+	chooserState := func(params map[string]string) string {
+		encoded, err := happyStateEncoder.Encode("i",
+			oidctestutil.ExpectedChooseIDPStateParamFormat{P: encodeQuery(params), V: "1"},
+		)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	signedRequestObject := func(params map[string]string) string {
+		claims := map[string]interface{}{}
+		for k, v := range params {
+			claims[k] = v
+		}
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: requestObjectSigningKey},
+			(&jose.SignerOptions{}).WithHeader("kid", "client-key"),
+		)
+		require.NoError(t, err)
+		requestObject, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:   downstreamClientID,
+			Audience: jwt.Audience{downstreamIssuer},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return requestObject
+	}
+
 	incomingCookieCSRFValue := "csrf-value-from-cookie"
 	encodedIncomingCookieCSRFValue, err := happyCookieEncoder.Encode("csrf", incomingCookieCSRFValue)
 	require.NoError(t, err)
@@ -323,6 +428,7 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		name string
 
 		idpLister            provider.DynamicUpstreamIDPProvider
+		identityProviders    provider.FederationDomainIdentityProviders
 		generateCSRF         func() (csrftoken.CSRFToken, error)
 		generatePKCE         func() (pkce.Code, error)
 		generateNonce        func() (nonce.Nonce, error)
@@ -336,6 +442,12 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		customUsernameHeader *string // nil means do not send header, empty means send header with empty value
 		customPasswordHeader *string // nil means do not send header, empty means send header with empty value
 
+		pushedAuthorizeRequests map[string]*pushedauthorizationrequest.Request // stored before the request, by reference
+		clientJWKS              *jose.JSONWebKeySet                            // registered for the client, for request objects
+
+		failedLoginsBeforeRequest   int  // recorded for the happy LDAP username before the request
+		failedLoginsCannotBeChecked bool // the storage of the login limiter fails
+
 		wantStatus                             int
 		wantContentType                        string
 		wantBodyString                         string
@@ -344,6 +456,8 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		wantBodyStringWithLocationInHref       bool
 		wantLocationHeader                     string
 		wantUpstreamStateParamInLocationHeader bool
+		wantChooserStateParamInLocationHeader  bool
+		wantRetryAfterHeader                   string
 
 		// For when the request was authenticated by an upstream LDAP provider and an authcode is being returned.
 		wantRedirectLocationRegexp        string
@@ -357,6 +471,9 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		wantDownstreamPKCEChallengeMethod string
 		wantDownstreamNonce               string
 		wantUnnecessaryStoredRecords      int
+
+		// Getting and deleting the pushed authorization request which the request refers to.
+		wantPushedAuthorizeRequestActions int
 	}
 	tests := []testCase{
 		{
@@ -397,6 +514,55 @@ func TestAuthorizationEndpoint(t *testing.T) {
 			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
 			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
 		},
+		{
+			name:                                   "LDAP upstream happy path using GET from a web browser without a CSRF cookie",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   happyGetRequestPath,
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForLDAPLoginPage(""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:                                   "LDAP upstream happy path using GET from a web browser with a CSRF cookie",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   happyGetRequestPath,
+			csrfCookie:                             "__Host-pinniped-csrf=" + encodedIncomingCookieCSRFValue + " ",
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantLocationHeader:                     expectedRedirectLocationForLDAPLoginPage(incomingCookieCSRFValue),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:               "LDAP upstream from a web browser with an invalid downstream request does not redirect to the login page",
+			idpLister:          oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			generateCSRF:       happyCSRFGenerator,
+			generatePKCE:       happyPKCEGenerator,
+			generateNonce:      happyNonceGenerator,
+			stateEncoder:       happyStateEncoder,
+			cookieEncoder:      happyCookieEncoder,
+			method:             http.MethodGet,
+			path:               modifiedHappyGetRequestPath(map[string]string{"code_challenge": ""}),
+			wantStatus:         http.StatusFound,
+			wantContentType:    "application/json; charset=utf-8",
+			wantLocationHeader: urlWithQuery(downstreamRedirectURI, fositeMissingCodeChallengeErrorQuery),
+			wantBodyString:     "",
+		},
 		{
 			name:                                   "OIDC upstream happy path using GET with a CSRF cookie",
 			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
@@ -580,6 +746,33 @@ func TestAuthorizationEndpoint(t *testing.T) {
 			wantLocationHeader:   urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithBadUsernamePasswordHintErrorQuery),
 			wantBodyString:       "",
 		},
+		{
+			name:                      "too many failed logins for the username for LDAP authentication",
+			idpLister:                 oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			method:                    http.MethodGet,
+			path:                      happyGetRequestPath,
+			customUsernameHeader:      pointer.StringPtr(happyLDAPUsername),
+			customPasswordHeader:      pointer.StringPtr(happyLDAPPassword),
+			failedLoginsBeforeRequest: 3,
+			wantStatus:                http.StatusFound,
+			wantContentType:           "application/json; charset=utf-8",
+			wantLocationHeader:        urlWithQuery(downstreamRedirectURI, fositeTemporarilyUnavailableWithTooManyFailuresHintErrorQuery),
+			wantRetryAfterHeader:      "30",
+			wantBodyString:            "",
+		},
+		{
+			name:                        "failed logins cannot be checked for LDAP authentication",
+			idpLister:                   oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			method:                      http.MethodGet,
+			path:                        happyGetRequestPath,
+			customUsernameHeader:        pointer.StringPtr(happyLDAPUsername),
+			customPasswordHeader:        pointer.StringPtr(happyLDAPPassword),
+			failedLoginsCannotBeChecked: true,
+			wantStatus:                  http.StatusFound,
+			wantContentType:             "application/json; charset=utf-8",
+			wantLocationHeader:          urlWithQuery(downstreamRedirectURI, fositeTemporarilyUnavailableWithUncheckedFailuresHintErrorQuery),
+			wantBodyString:              "",
+		},
 		{
 			name:                 "wrong upstream username for LDAP authentication",
 			idpLister:            oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
@@ -1061,31 +1254,184 @@ func TestAuthorizationEndpoint(t *testing.T) {
 			wantBodyString:  "Unprocessable Entity: No upstream providers are configured\n",
 		},
 		{
-			name:            "too many upstream providers are configured: multiple OIDC",
-			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider, &upstreamOIDCIdentityProvider).Build(), // more than one not allowed
-			method:          http.MethodGet,
-			path:            happyGetRequestPath,
-			wantStatus:      http.StatusUnprocessableEntity,
-			wantContentType: "text/plain; charset=utf-8",
-			wantBodyString:  "Unprocessable Entity: Too many upstream providers are configured (support for multiple upstreams is not yet implemented)\n",
+			name:                                   "multiple upstream providers are configured, choosing OIDC by name",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&otherUpstreamOIDCIdentityProvider, &upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   modifiedHappyGetRequestPath(map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name}),
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name}, "", ""), ""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:                                   "multiple upstream providers are configured with the same name, choosing OIDC by name and type",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&sameNameAsOIDCUpstreamLDAPIdentityProvider).Build(),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   modifiedHappyGetRequestPath(map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name, "pinniped_idp_type": "oidc"}),
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name, "pinniped_idp_type": "oidc"}, "", ""), ""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
 		},
 		{
-			name:            "too many upstream providers are configured: multiple LDAP",
-			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider, &upstreamLDAPIdentityProvider).Build(), // more than one not allowed
+			name:                                   "multiple upstream providers are configured, using the default OIDC upstream",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			identityProviders:                      provider.FederationDomainIdentityProviders{DefaultName: upstreamOIDCIdentityProvider.Name},
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   happyGetRequestPath,
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(nil, "", ""), ""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:                              "multiple upstream providers are configured, choosing LDAP by name using custom credential headers",
+			idpLister:                         oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&otherUpstreamLDAPIdentityProvider, &upstreamLDAPIdentityProvider).Build(),
+			method:                            http.MethodGet,
+			path:                              modifiedHappyGetRequestPath(map[string]string{"pinniped_idp_name": upstreamLDAPIdentityProvider.Name, "pinniped_idp_type": "ldap"}),
+			customUsernameHeader:              pointer.StringPtr(happyLDAPUsername),
+			customPasswordHeader:              pointer.StringPtr(happyLDAPPassword),
+			wantStatus:                        http.StatusFound,
+			wantContentType:                   htmlContentType,
+			wantRedirectLocationRegexp:        happyAuthcodeDownstreamRedirectLocationRegexp,
+			wantBodyStringWithLocationInHref:  false,
+			wantDownstreamIDTokenSubject:      upstreamLDAPURL + "&sub=" + happyLDAPUID,
+			wantDownstreamIDTokenUsername:     happyLDAPUsernameFromAuthenticator,
+			wantDownstreamIDTokenGroups:       happyLDAPGroups,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamRedirectURI:         downstreamRedirectURI,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+		},
+		{
+			name:                                   "multiple upstream providers are configured, using the default LDAP upstream from a web browser",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			identityProviders:                      provider.FederationDomainIdentityProviders{DefaultName: upstreamLDAPIdentityProvider.Name},
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   happyGetRequestPath,
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForLDAPLoginPage(""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:                                  "multiple upstream providers are configured: multiple OIDC, without an IDP choice",
+			idpLister:                             oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider, &otherUpstreamOIDCIdentityProvider).Build(),
+			stateEncoder:                          happyStateEncoder,
+			method:                                http.MethodGet,
+			path:                                  happyGetRequestPath,
+			wantStatus:                            http.StatusFound,
+			wantContentType:                       htmlContentType,
+			wantLocationHeader:                    expectedRedirectLocationForChooserPage(nil),
+			wantChooserStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:      true,
+		},
+		{
+			name:                                  "multiple upstream providers are configured: multiple LDAP, without an IDP choice",
+			idpLister:                             oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider, &otherUpstreamLDAPIdentityProvider).Build(),
+			stateEncoder:                          happyStateEncoder,
+			method:                                http.MethodGet,
+			path:                                  happyGetRequestPath,
+			wantStatus:                            http.StatusFound,
+			wantContentType:                       htmlContentType,
+			wantLocationHeader:                    expectedRedirectLocationForChooserPage(nil),
+			wantChooserStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:      true,
+		},
+		{
+			name:                                  "multiple upstream providers are configured: both OIDC and LDAP, without an IDP choice",
+			idpLister:                             oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			stateEncoder:                          happyStateEncoder,
+			method:                                http.MethodGet,
+			path:                                  happyGetRequestPath,
+			wantStatus:                            http.StatusFound,
+			wantContentType:                       htmlContentType,
+			wantLocationHeader:                    expectedRedirectLocationForChooserPage(nil),
+			wantChooserStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:      true,
+		},
+		{
+			name:                                  "multiple upstream providers are configured and the default is not found, without an IDP choice",
+			idpLister:                             oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			identityProviders:                     provider.FederationDomainIdentityProviders{DefaultName: "this-idp-does-not-exist"},
+			stateEncoder:                          happyStateEncoder,
+			method:                                http.MethodGet,
+			path:                                  happyGetRequestPath,
+			wantStatus:                            http.StatusFound,
+			wantContentType:                       htmlContentType,
+			wantLocationHeader:                    expectedRedirectLocationForChooserPage(nil),
+			wantChooserStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:      true,
+		},
+		{
+			name:                 "multiple upstream providers are configured, without an IDP choice, using custom credential headers",
+			idpLister:            oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			method:               http.MethodGet,
+			path:                 happyGetRequestPath,
+			customUsernameHeader: pointer.StringPtr(happyLDAPUsername),
+			customPasswordHeader: pointer.StringPtr(happyLDAPPassword),
+			wantStatus:           http.StatusUnprocessableEntity,
+			wantContentType:      "text/plain; charset=utf-8",
+			wantBodyString:       "Unprocessable Entity: Multiple upstream providers are configured, so the pinniped_idp_name param must be specified\n",
+		},
+		{
+			name:                 "multiple upstream providers are configured with the same name, choosing by name without a type",
+			idpLister:            oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&sameNameAsOIDCUpstreamLDAPIdentityProvider).Build(),
+			method:               http.MethodGet,
+			path:                 modifiedHappyGetRequestPath(map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name}),
+			customUsernameHeader: pointer.StringPtr(happyLDAPUsername),
+			customPasswordHeader: pointer.StringPtr(happyLDAPPassword),
+			wantStatus:           http.StatusUnprocessableEntity,
+			wantContentType:      "text/plain; charset=utf-8",
+			wantBodyString:       "Unprocessable Entity: Multiple upstream providers are named \"some-oidc-idp\", so the pinniped_idp_type param must be specified\n",
+		},
+		{
+			name:            "choosing an upstream provider which does not exist",
+			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
 			method:          http.MethodGet,
-			path:            happyGetRequestPath,
+			path:            modifiedHappyGetRequestPath(map[string]string{"pinniped_idp_name": "this-idp-does-not-exist"}),
 			wantStatus:      http.StatusUnprocessableEntity,
 			wantContentType: "text/plain; charset=utf-8",
-			wantBodyString:  "Unprocessable Entity: Too many upstream providers are configured (support for multiple upstreams is not yet implemented)\n",
+			wantBodyString:  "Unprocessable Entity: Upstream provider not found: \"this-idp-does-not-exist\"\n",
 		},
 		{
-			name:            "too many upstream providers are configured: both OIDC and LDAP",
-			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(), // more than one not allowed
+			name:            "choosing an upstream provider by name with the wrong type",
+			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
 			method:          http.MethodGet,
-			path:            happyGetRequestPath,
+			path:            modifiedHappyGetRequestPath(map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name, "pinniped_idp_type": "ldap"}),
 			wantStatus:      http.StatusUnprocessableEntity,
 			wantContentType: "text/plain; charset=utf-8",
-			wantBodyString:  "Unprocessable Entity: Too many upstream providers are configured (support for multiple upstreams is not yet implemented)\n",
+			wantBodyString:  "Unprocessable Entity: Upstream provider not found: \"some-oidc-idp\"\n",
 		},
 		{
 			name:            "PUT is a bad method",
@@ -1114,6 +1460,254 @@ func TestAuthorizationEndpoint(t *testing.T) {
 			wantContentType: "text/plain; charset=utf-8",
 			wantBodyString:  "Method Not Allowed: DELETE (try GET or POST)\n",
 		},
+		{
+			name:                                   "OIDC upstream happy path using a pushed authorization request",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   happyRequestURIPath,
+			pushedAuthorizeRequests:                happyPushedAuthorizeRequests,
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(nil, "", ""), ""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+			wantPushedAuthorizeRequestActions:      2,
+		},
+		{
+			name:                              "LDAP upstream happy path using a pushed authorization request",
+			idpLister:                         oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			method:                            http.MethodGet,
+			path:                              happyRequestURIPath,
+			pushedAuthorizeRequests:           happyPushedAuthorizeRequests,
+			customUsernameHeader:              pointer.StringPtr(happyLDAPUsername),
+			customPasswordHeader:              pointer.StringPtr(happyLDAPPassword),
+			wantStatus:                        http.StatusFound,
+			wantContentType:                   htmlContentType,
+			wantRedirectLocationRegexp:        happyAuthcodeDownstreamRedirectLocationRegexp,
+			wantBodyStringWithLocationInHref:  false,
+			wantDownstreamIDTokenSubject:      upstreamLDAPURL + "&sub=" + happyLDAPUID,
+			wantDownstreamIDTokenUsername:     happyLDAPUsernameFromAuthenticator,
+			wantDownstreamIDTokenGroups:       happyLDAPGroups,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamRedirectURI:         downstreamRedirectURI,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantPushedAuthorizeRequestActions: 2,
+		},
+		{
+			name:            "request_uri which was not returned by the pushed authorization request endpoint",
+			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:          http.MethodGet,
+			path:            pathWithQuery("/some/path", map[string]string{"client_id": downstreamClientID, "request_uri": "https://client.example.com/request.jwt"}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request_uri",
+					"error_description": "The request_uri in the Authorization Request returns an error or contains invalid data. Only request_uri values from the pushed authorization request endpoint are supported."
+				}
+			`),
+		},
+		{
+			name:            "request_uri which refers to an unknown pushed authorization request",
+			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:          http.MethodGet,
+			path:            happyRequestURIPath,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request_uri",
+					"error_description": "The request_uri in the Authorization Request returns an error or contains invalid data. The request_uri is not valid. It may have been used already."
+				}
+			`),
+			wantPushedAuthorizeRequestActions: 1,
+		},
+		{
+			name:                    "request_uri which was pushed by another client",
+			idpLister:               oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:                  http.MethodGet,
+			path:                    pathWithQuery("/some/path", map[string]string{"client_id": "other-client", "request_uri": "urn:ietf:params:oauth:request_uri:happy-reference"}),
+			pushedAuthorizeRequests: happyPushedAuthorizeRequests,
+			wantStatus:              http.StatusBadRequest,
+			wantContentType:         "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request_uri",
+					"error_description": "The request_uri in the Authorization Request returns an error or contains invalid data. The request_uri was not pushed by the client in the client_id param."
+				}
+			`),
+			wantPushedAuthorizeRequestActions: 1,
+		},
+		{
+			name:      "request_uri which has expired",
+			idpLister: oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:    http.MethodGet,
+			path:      happyRequestURIPath,
+			pushedAuthorizeRequests: map[string]*pushedauthorizationrequest.Request{
+				"happy-reference": happyPushedAuthorizeRequest(time.Now().Add(-time.Second)),
+			},
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request_uri",
+					"error_description": "The request_uri in the Authorization Request returns an error or contains invalid data. The request_uri has expired."
+				}
+			`),
+			wantPushedAuthorizeRequestActions: 2,
+		},
+		{
+			name:      "both request and request_uri",
+			idpLister: oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:    http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"client_id":   downstreamClientID,
+				"request_uri": "urn:ietf:params:oauth:request_uri:happy-reference",
+				"request":     signedRequestObject(happyGetRequestQueryMap),
+			}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The request and request_uri params must not both be given."
+				}
+			`),
+		},
+		{
+			name:                                   "OIDC upstream happy path using a request object",
+			idpLister:                              oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   pathWithQuery("/some/path", map[string]string{"client_id": downstreamClientID, "request": signedRequestObject(happyGetRequestQueryMap)}),
+			clientJWKS:                             clientJWKS,
+			wantStatus:                             http.StatusFound,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(nil, "", ""), ""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:            "request object for a client without JSON Web Keys",
+			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:          http.MethodGet,
+			path:            pathWithQuery("/some/path", map[string]string{"client_id": downstreamClientID, "request": signedRequestObject(happyGetRequestQueryMap)}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "request_not_supported",
+					"error_description": "The OP does not support use of the request parameter. The client has no JSON Web Keys registered, so it cannot use request objects."
+				}
+			`),
+		},
+		{
+			name:            "request object for a client which does not exist",
+			idpLister:       oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			method:          http.MethodGet,
+			path:            pathWithQuery("/some/path", map[string]string{"client_id": "invalid-client", "request": signedRequestObject(happyGetRequestQueryMap)}),
+			wantStatus:      http.StatusUnauthorized,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON:    fositeInvalidClientErrorBody,
+		},
+		{
+			name:          "OIDC upstream chosen on the chooser page",
+			idpLister:     oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&otherUpstreamOIDCIdentityProvider, &upstreamOIDCIdentityProvider).WithLDAP(&upstreamLDAPIdentityProvider).Build(),
+			generateCSRF:  happyCSRFGenerator,
+			generatePKCE:  happyPKCEGenerator,
+			generateNonce: happyNonceGenerator,
+			stateEncoder:  happyStateEncoder,
+			cookieEncoder: happyCookieEncoder,
+			method:        http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"pinniped_choose_idp_state": chooserState(happyGetRequestQueryMap),
+				"pinniped_idp_name":         upstreamOIDCIdentityProvider.Name,
+				"pinniped_idp_type":         "oidc",
+			}),
+			wantStatus:                  http.StatusFound,
+			wantContentType:             htmlContentType,
+			wantCSRFValueInCookieHeader: happyCSRF,
+			wantLocationHeader: expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(
+				map[string]string{"pinniped_idp_name": upstreamOIDCIdentityProvider.Name, "pinniped_idp_type": "oidc"}, "", "",
+			), ""),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name:         "chooser state param which cannot be decoded",
+			idpLister:    oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			stateEncoder: happyStateEncoder,
+			method:       http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"pinniped_choose_idp_state": "this-will-not-decode",
+				"pinniped_idp_name":         upstreamOIDCIdentityProvider.Name,
+			}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The pinniped_choose_idp_state param is not valid. It may have expired."
+				}
+			`),
+		},
+		{
+			name:         "chooser state param which has the wrong format version",
+			idpLister:    oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			stateEncoder: happyStateEncoder,
+			method:       http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"pinniped_choose_idp_state": func() string {
+					encoded, err := happyStateEncoder.Encode("i",
+						oidctestutil.ExpectedChooseIDPStateParamFormat{P: encodeQuery(happyGetRequestQueryMap), V: "wrong-state-version"},
+					)
+					require.NoError(t, err)
+					return encoded
+				}(),
+				"pinniped_idp_name": upstreamOIDCIdentityProvider.Name,
+			}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The pinniped_choose_idp_state param has an unsupported format version."
+				}
+			`),
+		},
+		{
+			name:         "both chooser state and request_uri",
+			idpLister:    oidctestutil.NewUpstreamIDPListerBuilder().WithOIDC(&upstreamOIDCIdentityProvider).Build(),
+			stateEncoder: happyStateEncoder,
+			method:       http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"client_id":                 downstreamClientID,
+				"request_uri":               "urn:ietf:params:oauth:request_uri:happy-reference",
+				"pinniped_choose_idp_state": chooserState(happyGetRequestQueryMap),
+			}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The pinniped_choose_idp_state param must not be given together with the request or request_uri params."
+				}
+			`),
+		},
 	}
 
 	runOneTestCase := func(t *testing.T, test testCase, subject http.Handler, kubeOauthStore *oidc.KubeStorage, kubeClient *fake.Clientset, secretsClient v1.SecretInterface) {
@@ -1136,6 +1730,7 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		require.Equal(t, test.wantStatus, rsp.Code)
 		testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), test.wantContentType)
 		testutil.RequireSecurityHeaders(t, rsp)
+		require.Equal(t, test.wantRetryAfterHeader, rsp.Header().Get("Retry-After"))
 
 		actualLocation := rsp.Header().Get("Location")
 		switch {
@@ -1143,10 +1738,14 @@ func TestAuthorizationEndpoint(t *testing.T) {
 			if test.wantUpstreamStateParamInLocationHeader {
 				requireEqualDecodedStateParams(t, actualLocation, test.wantLocationHeader, test.stateEncoder)
 			}
+			if test.wantChooserStateParamInLocationHeader {
+				requireEqualDecodedChooserStateParams(t, actualLocation, test.wantLocationHeader, test.stateEncoder)
+			}
 			// The upstream state param is encoded using a timestamp at the beginning so we don't want to
 			// compare those states since they may be different, but we do want to compare the downstream
 			// state param that should be exactly the same.
-			requireEqualURLs(t, actualLocation, test.wantLocationHeader, test.wantUpstreamStateParamInLocationHeader)
+			requireEqualURLs(t, actualLocation, test.wantLocationHeader,
+				test.wantUpstreamStateParamInLocationHeader || test.wantChooserStateParamInLocationHeader)
 
 			// Authorization requests for either a successful OIDC upstream or for an error with any upstream
 			// should never use Kube storage. There is only one exception to this rule, which is that certain
@@ -1173,6 +1772,10 @@ func TestAuthorizationEndpoint(t *testing.T) {
 				test.wantDownstreamNonce,
 				downstreamClientID,
 				test.wantDownstreamRedirectURI,
+				&psession.CustomSessionData{
+					ProviderName: upstreamLDAPIdentityProvider.Name,
+					ProviderType: psession.ProviderTypeLDAP,
+				},
 			)
 		default:
 			require.Empty(t, rsp.Header().Values("Location"))
@@ -1204,20 +1807,60 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		}
 	}
 
+	// The login limiter has its own client, so that its Secrets do not get in the way of the assertions about
+	// the stored records.
+	newLoginLimiter := func(t *testing.T, test testCase) *loginlimiter.Limiter {
+		loginLimiterKubeClient := fake.NewSimpleClientset()
+		if test.failedLoginsCannotBeChecked {
+			loginLimiterKubeClient.PrependReactor("get", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("some get error")
+			})
+		}
+		loginLimiter := loginlimiter.New(loginLimiterKubeClient.CoreV1().Secrets("some-namespace"), time.Now, loginlimiter.Config{
+			UsernameFailures: 3,
+			SourceIPFailures: 50,
+			InitialBackoff:   30 * time.Second,
+			MaxBackoff:       30 * time.Second,
+			ResetAfter:       time.Minute,
+		})
+		for i := 0; i < test.failedLoginsBeforeRequest; i++ {
+			// The source IP of requests from httptest.NewRequest.
+			attempt := loginlimiter.Attempt{UpstreamName: upstreamLDAPIdentityProvider.Name, Username: happyLDAPUsername, SourceIP: "192.0.2.1"}
+			require.NoError(t, loginLimiter.Begin(context.Background(), attempt))
+			loginLimiter.RecordFailure(attempt)
+		}
+		return loginLimiter
+	}
+
 	for _, test := range tests {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
 			kubeClient := fake.NewSimpleClientset()
 			secretsClient := kubeClient.CoreV1().Secrets("some-namespace")
 			oauthHelperWithRealStorage, kubeOauthStore := createOauthHelperWithRealStorage(secretsClient)
+			// Keep the pushed authorization requests apart, so the assertions about other stored records are unaffected.
+			pushedAuthorizeRequestKubeClient := fake.NewSimpleClientset()
+			pushedAuthorizeRequestStore := oidc.NewKubeStorage(pushedAuthorizeRequestKubeClient.CoreV1().Secrets("some-namespace"), nil, timeoutsConfiguration)
+			for reference, pushedAuthorizeRequest := range test.pushedAuthorizeRequests {
+				require.NoError(t, pushedAuthorizeRequestStore.CreatePushedAuthorizeRequest(context.Background(), reference, pushedAuthorizeRequest))
+			}
+			pushedAuthorizeRequestKubeClient.ClearActions()
+			var authorizeRequestStorage AuthorizeRequestStorage = pushedAuthorizeRequestStore
+			if test.clientJWKS != nil {
+				authorizeRequestStorage = &clientJWKSStorage{KubeStorage: pushedAuthorizeRequestStore, jwks: test.clientJWKS}
+			}
 			subject := NewHandler(
 				downstreamIssuer,
 				test.idpLister,
+				test.identityProviders,
 				oauthHelperWithNullStorage, oauthHelperWithRealStorage,
+				authorizeRequestStorage,
 				test.generateCSRF, test.generatePKCE, test.generateNonce,
 				test.stateEncoder, test.cookieEncoder,
+				newLoginLimiter(t, test),
 			)
 			runOneTestCase(t, test, subject, kubeOauthStore, kubeClient, secretsClient)
+			require.Len(t, pushedAuthorizeRequestKubeClient.Actions(), test.wantPushedAuthorizeRequestActions)
 		})
 	}
 
@@ -1231,9 +1874,12 @@ func TestAuthorizationEndpoint(t *testing.T) {
 		subject := NewHandler(
 			downstreamIssuer,
 			test.idpLister,
+			test.identityProviders,
 			oauthHelperWithNullStorage, oauthHelperWithRealStorage,
+			kubeOauthStore,
 			test.generateCSRF, test.generatePKCE, test.generateNonce,
 			test.stateEncoder, test.cookieEncoder,
+			newLoginLimiter(t, test),
 		)
 
 		runOneTestCase(t, test, subject, kubeOauthStore, kubeClient, secretsClient)
@@ -1274,6 +1920,21 @@ func TestAuthorizationEndpoint(t *testing.T) {
 	})
 }
 
+// clientJWKSStorage registers JSON Web Keys for the clients of the KubeStorage, which has none of its own.
+type clientJWKSStorage struct {
+	*oidc.KubeStorage
+	jwks *jose.JSONWebKeySet
+}
+
+func (s *clientJWKSStorage) GetClient(ctx context.Context, id string) (fosite.Client, error) {
+	client, err := s.KubeStorage.GetClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	client.(*clientregistry.Client).JSONWebKeys = s.jwks
+	return client, nil
+}
+
 type errorReturningEncoder struct {
 	oidc.Codec
 }
@@ -1304,6 +1965,28 @@ func requireEqualDecodedStateParams(t *testing.T, actualURL string, expectedURL
 	require.Equal(t, expectedDecodedStateParam, actualDecodedStateParam)
 }
 
+func requireEqualDecodedChooserStateParams(t *testing.T, actualURL string, expectedURL string, stateParamDecoder oidc.Codec) {
+	t.Helper()
+	actualLocationURL, err := url.Parse(actualURL)
+	require.NoError(t, err)
+	expectedLocationURL, err := url.Parse(expectedURL)
+	require.NoError(t, err)
+
+	expectedQueryStateParam := expectedLocationURL.Query().Get("state")
+	require.NotEmpty(t, expectedQueryStateParam)
+	var expectedDecodedStateParam oidctestutil.ExpectedChooseIDPStateParamFormat
+	err = stateParamDecoder.Decode("i", expectedQueryStateParam, &expectedDecodedStateParam)
+	require.NoError(t, err)
+
+	actualQueryStateParam := actualLocationURL.Query().Get("state")
+	require.NotEmpty(t, actualQueryStateParam)
+	var actualDecodedStateParam oidctestutil.ExpectedChooseIDPStateParamFormat
+	err = stateParamDecoder.Decode("i", actualQueryStateParam, &actualDecodedStateParam)
+	require.NoError(t, err)
+
+	require.Equal(t, expectedDecodedStateParam, actualDecodedStateParam)
+}
+
 func requireEqualURLs(t *testing.T, actualURL string, expectedURL string, ignoreState bool) {
 	t.Helper()
 	actualLocationURL, err := url.Parse(actualURL)
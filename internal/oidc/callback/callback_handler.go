@@ -9,17 +9,22 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/ory/fosite"
 
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/httputil/httperr"
 	"go.pinniped.dev/internal/httputil/securityheader"
 	"go.pinniped.dev/internal/oidc"
 	"go.pinniped.dev/internal/oidc/csrftoken"
 	"go.pinniped.dev/internal/oidc/downstreamsession"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
 	"go.pinniped.dev/internal/oidc/provider/formposthtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
 )
 
 const (
@@ -35,6 +40,7 @@ func NewHandler(
 	oauthHelper fosite.OAuth2Provider,
 	stateDecoder, cookieDecoder oidc.Decoder,
 	redirectURI string,
+	getBranding func() *branding.Branding,
 ) http.Handler {
 	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
 		state, err := validateRequest(r, stateDecoder, cookieDecoder)
@@ -65,6 +71,7 @@ func NewHandler(
 		// Automatically grant the openid, offline_access, and pinniped:request-audience scopes, but only if they were requested.
 		downstreamsession.GrantScopesIfRequested(authorizeRequester)
 
+		exchangeStart := time.Now()
 		token, err := upstreamIDPConfig.ExchangeAuthcodeAndValidateTokens(
 			r.Context(),
 			authcode(r),
@@ -72,22 +79,30 @@ func NewHandler(
 			state.Nonce,
 			redirectURI,
 		)
+		observeCodeExchange(upstreamIDPConfig.GetName(), exchangeStart, err)
 		if err != nil {
 			plog.WarningErr("error exchanging and validating upstream tokens", err, "upstreamName", upstreamIDPConfig.GetName())
+			recordUpstreamAuthenticationFailed(r, authorizeRequester, upstreamIDPConfig, "upstream_error")
 			return httperr.New(http.StatusBadGateway, "error exchanging and validating upstream tokens")
 		}
 
 		subject, username, err := getSubjectAndUsernameFromUpstreamIDToken(upstreamIDPConfig, token.IDToken.Claims)
 		if err != nil {
+			recordUpstreamAuthenticationFailed(r, authorizeRequester, upstreamIDPConfig, "invalid_claims")
 			return err
 		}
 
 		groups, err := getGroupsFromUpstreamIDToken(upstreamIDPConfig, token.IDToken.Claims)
 		if err != nil {
+			recordUpstreamAuthenticationFailed(r, authorizeRequester, upstreamIDPConfig, "invalid_claims")
 			return err
 		}
 
-		openIDSession := downstreamsession.MakeDownstreamSession(subject, username, groups)
+		openIDSession := downstreamsession.MakeDownstreamSession(subject, username, groups, &psession.CustomSessionData{
+			ProviderName: upstreamIDPConfig.GetName(),
+			ProviderType: psession.ProviderTypeOIDC,
+		})
+		auditlog.Record(r.Context(), oidc.AuditEventForSession(auditlog.UpstreamAuthenticationSucceeded, authorizeRequester, openIDSession))
 
 		authorizeResponder, err := oauthHelper.NewAuthorizeResponse(r.Context(), authorizeRequester, openIDSession)
 		if err != nil {
@@ -95,11 +110,23 @@ func NewHandler(
 			return httperr.Wrap(http.StatusInternalServerError, "error while generating and saving authcode", err)
 		}
 
-		oauthHelper.WriteAuthorizeResponse(w, authorizeRequester, authorizeResponder)
+		// The response_mode=form_post page is shown with the FederationDomain's branding and in the browser's language.
+		b := getBranding()
+		w.Header().Set("Content-Security-Policy", formposthtml.ContentSecurityPolicy(b))
+		oidc.WithFormPostHTMLTemplate(oauthHelper, formposthtml.Template(b, locale.FromRequest(r))).
+			WriteAuthorizeResponse(w, authorizeRequester, authorizeResponder)
 
 		return nil
 	})
-	return securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy())
+	return securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy(nil))
+}
+
+func recordUpstreamAuthenticationFailed(r *http.Request, authorizeRequester fosite.AuthorizeRequester, upstreamIDPConfig provider.UpstreamOIDCIdentityProviderI, reason string) {
+	event := oidc.AuditEvent(auditlog.UpstreamAuthenticationFailed, authorizeRequester)
+	event.UpstreamName = upstreamIDPConfig.GetName()
+	event.UpstreamType = string(psession.ProviderTypeOIDC)
+	event.Reason = reason
+	auditlog.Record(r.Context(), event)
 }
 
 func authcode(r *http.Request) string {
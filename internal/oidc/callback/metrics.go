@@ -0,0 +1,49 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package callback
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var (
+	codeExchangeDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "oidc_code_exchange_duration_seconds",
+			Help:           "How long it took to exchange authcodes with each upstream OIDC identity provider and to validate the tokens which it returned.",
+			Buckets:        metrics.ExponentialBuckets(0.005, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"upstream"},
+	)
+
+	codeExchangeErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "oidc_code_exchange_errors_total",
+			Help:           "Number of authcode exchanges with each upstream OIDC identity provider which failed or returned invalid tokens.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"upstream"},
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(codeExchangeDuration, codeExchangeErrors)
+}
+
+func observeCodeExchange(upstreamName string, start time.Time, err error) {
+	codeExchangeDuration.WithLabelValues(upstreamName).Observe(time.Since(start).Seconds())
+	if err != nil {
+		codeExchangeErrors.WithLabelValues(upstreamName).Inc()
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package chooseidp provides a handler for the page where end users choose among several upstream identity providers.
+package chooseidp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/httputil/securityheader"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/idpdiscovery"
+	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/chooseidphtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+	"go.pinniped.dev/internal/plog"
+)
+
+func NewHandler(
+	downstreamIssuer string,
+	upstreamIDPs oidc.UpstreamIdentityProvidersLister,
+	identityProviders provider.FederationDomainIdentityProviders,
+	stateDecoder oidc.Decoder,
+	getBranding func() *branding.Branding,
+) http.Handler {
+	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET)", r.Method)
+		}
+
+		encodedState := r.FormValue("state")
+		state, err := readState(encodedState, stateDecoder)
+		if err != nil {
+			plog.InfoErr("error reading state", err)
+			return err
+		}
+
+		if _, err := url.ParseQuery(state.AuthParams); err != nil {
+			plog.Error("error reading state downstream auth params", err)
+			return httperr.New(http.StatusBadRequest, "error reading state downstream auth params")
+		}
+
+		// Each choice continues the original authorize request, with the addition of the chosen identity provider.
+		// The links carry the state param rather than the original params, so that the params of a pushed
+		// authorization request never appear in a URL.
+		pageData := &chooseidphtml.PageData{IdentityProviders: []chooseidphtml.IdentityProvider{}}
+		for _, idp := range idpdiscovery.List(upstreamIDPs, identityProviders) {
+			authParams := url.Values{}
+			authParams.Set(oidc.AuthorizeChooseIDPStateParamName, encodedState)
+			authParams.Set(oidc.AuthorizeUpstreamIDPNameParamName, idp.Name)
+			authParams.Set(oidc.AuthorizeUpstreamIDPTypeParamName, idp.Type)
+			pageData.IdentityProviders = append(pageData.IdentityProviders, chooseidphtml.IdentityProvider{
+				Name: idp.Name,
+				Type: idp.Type,
+				URL:  fmt.Sprintf("%s%s?%s", downstreamIssuer, oidc.AuthorizationEndpointPath, authParams.Encode()),
+			})
+		}
+
+		b := getBranding()
+		w.Header().Set("Content-Security-Policy", chooseidphtml.ContentSecurityPolicy(b))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := chooseidphtml.Template(b, locale.FromRequest(r)).Execute(w, pageData); err != nil {
+			// The status code was already written, so all that we can do is log the error.
+			plog.Error("error rendering identity provider chooser page", err)
+		}
+		return nil
+	})
+	return securityheader.WrapWithCustomCSP(handler, chooseidphtml.ContentSecurityPolicy(nil))
+}
+
+func readState(encodedState string, stateDecoder oidc.Decoder) (*oidc.ChooseIDPStateParamData, error) {
+	if encodedState == "" {
+		return nil, httperr.New(http.StatusBadRequest, "state param not found")
+	}
+
+	var state oidc.ChooseIDPStateParamData
+	if err := stateDecoder.Decode(oidc.ChooseIDPStateParamEncodingName, encodedState, &state); err != nil {
+		return nil, httperr.New(http.StatusBadRequest, "error reading state")
+	}
+
+	if state.FormatVersion != oidc.UpstreamStateParamFormatVersion {
+		return nil, httperr.New(http.StatusUnprocessableEntity, "state format version is invalid")
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package chooseidp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/chooseidphtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+)
+
+const (
+	downstreamIssuer = "https://my-downstream-issuer.com/path"
+	chooserPath      = "/downstream-provider-name/choose_identity_provider"
+	htmlContentType  = "text/html; charset=utf-8"
+)
+
+var (
+	happyDownstreamRequestParamsQuery = url.Values{
+		"response_type":         []string{"code"},
+		"scope":                 []string{"openid"},
+		"client_id":             []string{"pinniped-cli"},
+		"state":                 []string{"8b-state"},
+		"nonce":                 []string{"some-nonce-value"},
+		"code_challenge":        []string{"some-challenge"},
+		"code_challenge_method": []string{"S256"},
+		"redirect_uri":          []string{"http://127.0.0.1/callback"},
+	}
+)
+
+func TestChooseIDPEndpoint(t *testing.T) {
+	var stateEncoderHashKey = []byte("fake-hash-secret")
+	var stateEncoderBlockKey = []byte("0123456789ABCDEF") // block encryption requires 16/24/32 bytes for AES
+
+	var happyStateCodec = securecookie.New(stateEncoderHashKey, stateEncoderBlockKey)
+	happyStateCodec.SetSerializer(securecookie.JSONEncoder{})
+
+	encodeState := func(state *oidctestutil.ExpectedChooseIDPStateParamFormat) string {
+		encoded, err := happyStateCodec.Encode("i", state)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	happyState := encodeState(&oidctestutil.ExpectedChooseIDPStateParamFormat{
+		P: happyDownstreamRequestParamsQuery.Encode(),
+		V: "1",
+	})
+
+	wantAuthorizeURL := func(idpName, idpType string) string {
+		// The original params stay hidden in the state param.
+		query := url.Values{}
+		query.Set("pinniped_choose_idp_state", happyState)
+		query.Set("pinniped_idp_name", idpName)
+		query.Set("pinniped_idp_type", idpType)
+		return downstreamIssuer + "/oauth2/authorize?" + query.Encode()
+	}
+
+	idpLister := oidctestutil.NewUpstreamIDPListerBuilder().
+		WithOIDC(
+			&oidctestutil.TestUpstreamOIDCIdentityProvider{Name: "z-oidc-idp"},
+			&oidctestutil.TestUpstreamOIDCIdentityProvider{Name: "hidden-oidc-idp"},
+		).
+		WithLDAP(
+			&oidctestutil.TestUpstreamLDAPIdentityProvider{Name: "a-ldap-idp"},
+		).
+		Build()
+
+	tests := []struct {
+		name string
+
+		identityProviders provider.FederationDomainIdentityProviders
+		branding          *branding.Branding
+		method            string
+		path              string
+		acceptLanguage    string
+
+		wantStatus      int
+		wantContentType string
+		wantBodyString  string
+		wantChooserPage *chooseidphtml.PageData
+	}{
+		{
+			name:            "GET shows all identity providers, sorted by name",
+			method:          http.MethodGet,
+			path:            chooserPath + "?state=" + url.QueryEscape(happyState),
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantChooserPage: &chooseidphtml.PageData{
+				IdentityProviders: []chooseidphtml.IdentityProvider{
+					{Name: "a-ldap-idp", Type: "ldap", URL: wantAuthorizeURL("a-ldap-idp", "ldap")},
+					{Name: "hidden-oidc-idp", Type: "oidc", URL: wantAuthorizeURL("hidden-oidc-idp", "oidc")},
+					{Name: "z-oidc-idp", Type: "oidc", URL: wantAuthorizeURL("z-oidc-idp", "oidc")},
+				},
+			},
+		},
+		{
+			name:              "GET does not show hidden identity providers",
+			identityProviders: provider.FederationDomainIdentityProviders{HiddenNames: []string{"hidden-oidc-idp"}},
+			method:            http.MethodGet,
+			path:              chooserPath + "?state=" + url.QueryEscape(happyState),
+			wantStatus:        http.StatusOK,
+			wantContentType:   htmlContentType,
+			wantChooserPage: &chooseidphtml.PageData{
+				IdentityProviders: []chooseidphtml.IdentityProvider{
+					{Name: "a-ldap-idp", Type: "ldap", URL: wantAuthorizeURL("a-ldap-idp", "ldap")},
+					{Name: "z-oidc-idp", Type: "oidc", URL: wantAuthorizeURL("z-oidc-idp", "oidc")},
+				},
+			},
+		},
+		{
+			name:              "GET when all identity providers are hidden",
+			identityProviders: provider.FederationDomainIdentityProviders{HiddenNames: []string{"hidden-oidc-idp", "z-oidc-idp", "a-ldap-idp"}},
+			method:            http.MethodGet,
+			path:              chooserPath + "?state=" + url.QueryEscape(happyState),
+			wantStatus:        http.StatusOK,
+			wantContentType:   htmlContentType,
+			wantChooserPage:   &chooseidphtml.PageData{IdentityProviders: []chooseidphtml.IdentityProvider{}},
+		},
+		{
+			name:              "GET shows the branding and the preferred language",
+			identityProviders: provider.FederationDomainIdentityProviders{HiddenNames: []string{"hidden-oidc-idp", "z-oidc-idp"}},
+			branding: &branding.Branding{
+				ProductName: "Acme SSO",
+				Logo:        "data:image/png;base64,iVBORw0KGgo=",
+				SupportURL:  "https://help.example.com",
+			},
+			method:          http.MethodGet,
+			path:            chooserPath + "?state=" + url.QueryEscape(happyState),
+			acceptLanguage:  "ja",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantChooserPage: &chooseidphtml.PageData{
+				IdentityProviders: []chooseidphtml.IdentityProvider{
+					{Name: "a-ldap-idp", Type: "ldap", URL: wantAuthorizeURL("a-ldap-idp", "ldap")},
+				},
+			},
+		},
+		{
+			name:            "POST is a bad method",
+			method:          http.MethodPost,
+			path:            chooserPath + "?state=" + url.QueryEscape(happyState),
+			wantStatus:      http.StatusMethodNotAllowed,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Method Not Allowed: POST (try GET)\n",
+		},
+		{
+			name:            "state param was not included",
+			method:          http.MethodGet,
+			path:            chooserPath,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Bad Request: state param not found\n",
+		},
+		{
+			name:            "state param was not signed correctly",
+			method:          http.MethodGet,
+			path:            chooserPath + "?state=this-will-not-decode",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Bad Request: error reading state\n",
+		},
+		{
+			name:   "state param has the wrong format version",
+			method: http.MethodGet,
+			path: chooserPath + "?state=" + url.QueryEscape(encodeState(&oidctestutil.ExpectedChooseIDPStateParamFormat{
+				P: happyDownstreamRequestParamsQuery.Encode(),
+				V: "wrong-state-version",
+			})),
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Unprocessable Entity: state format version is invalid\n",
+		},
+		{
+			name:   "state param has invalid downstream auth params",
+			method: http.MethodGet,
+			path: chooserPath + "?state=" + url.QueryEscape(encodeState(&oidctestutil.ExpectedChooseIDPStateParamFormat{
+				P: "%z",
+				V: "1",
+			})),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Bad Request: error reading state downstream auth params\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			subject := NewHandler(downstreamIssuer, idpLister, test.identityProviders, happyStateCodec,
+				func() *branding.Branding { return test.branding })
+
+			req := httptest.NewRequest(test.method, test.path, nil)
+			if test.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", test.acceptLanguage)
+			}
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), test.wantContentType)
+			testutil.RequireSecurityHeaders(t, rsp)
+			if test.wantChooserPage != nil {
+				require.Equal(t, chooseidphtml.ContentSecurityPolicy(test.branding), rsp.Header().Get("Content-Security-Policy"))
+				var wantBody bytes.Buffer
+				require.NoError(t, chooseidphtml.Template(test.branding, locale.FromRequest(req)).Execute(&wantBody, test.wantChooserPage))
+				require.Equal(t, wantBody.String(), rsp.Body.String())
+			} else {
+				require.Equal(t, chooseidphtml.ContentSecurityPolicy(nil), rsp.Header().Get("Content-Security-Policy"))
+				require.Equal(t, test.wantBodyString, rsp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/fosite/handler/oauth2"
+	"github.com/pkg/errors"
+
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/psession"
+)
+
+// ClientCredentialsFactory returns a compose.Factory for a ClientCredentialsHandler which only issues tokens to the
+// given machine clients.
+func ClientCredentialsFactory(machineClients clientregistry.DynamicMachineClientProvider) compose.Factory {
+	return func(config *compose.Config, storage interface{}, strategy interface{}) interface{} {
+		return &ClientCredentialsHandler{
+			ClientCredentialsGrantHandler: compose.OAuth2ClientCredentialsGrantFactory(config, storage, strategy).(*oauth2.ClientCredentialsGrantHandler),
+			issuer:                        config.IDTokenIssuer,
+			machineClients:                machineClients,
+		}
+	}
+}
+
+// ClientCredentialsHandler handles the client credentials grant like fosite does, and also gives the session the
+// identity of the machine client, so the access tokens can be exchanged for cluster tokens.
+type ClientCredentialsHandler struct {
+	*oauth2.ClientCredentialsGrantHandler
+	issuer         string
+	machineClients clientregistry.DynamicMachineClientProvider
+}
+
+var _ fosite.TokenEndpointHandler = (*ClientCredentialsHandler)(nil)
+
+func (c *ClientCredentialsHandler) HandleTokenEndpointRequest(ctx context.Context, requester fosite.AccessRequester) error {
+	if err := c.ClientCredentialsGrantHandler.HandleTokenEndpointRequest(ctx, requester); err != nil {
+		return err
+	}
+
+	var machineClient *clientregistry.MachineClient
+	if c.machineClients != nil {
+		machineClient = c.machineClients.GetMachineClient(requester.GetClient().GetID())
+	}
+	if machineClient == nil {
+		return errors.WithStack(fosite.ErrUnauthorizedClient.WithHint("The client is not allowed to use the client credentials grant."))
+	}
+
+	// There is no end user to approve the scopes, and the client was already checked to be allowed all of them.
+	for _, scope := range requester.GetRequestedScopes() {
+		requester.GrantScope(scope)
+	}
+
+	session, ok := requester.GetSession().(*psession.PinnipedSession)
+	if !ok {
+		return errors.WithStack(fosite.ErrServerError.WithHint("Unexpected session type."))
+	}
+	now := time.Now().UTC()
+	claims := session.Fosite.Claims
+	claims.Subject = c.subject(machineClient.Client.GetID())
+	claims.RequestedAt = now
+	claims.AuthTime = now
+	groups := machineClient.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+	claims.Extra = map[string]interface{}{
+		DownstreamUsernameClaim: machineClient.Username,
+		DownstreamGroupsClaim:   groups,
+	}
+	return nil
+}
+
+// subject returns the downstream subject of a machine client. Client IDs are only unique within one Supervisor, so
+// the issuer is used to make the subject globally unique.
+func (c *ClientCredentialsHandler) subject(clientID string) string {
+	return c.issuer + "?" + url.Values{"client_id": {clientID}}.Encode()
+}
@@ -94,3 +94,38 @@ func TestPinnipedCLI(t *testing.T) {
 		  "token_endpoint_auth_signing_alg": "RS256"
 		}`, string(marshaled))
 }
+
+func TestDynamicClientManager(t *testing.T) {
+	ctx := context.Background()
+
+	machineClients := NewDynamicMachineClientProvider()
+	registry := NewDynamicClientManager(machineClients)
+
+	got, err := registry.GetClient(ctx, "some-machine-client")
+	require.EqualError(t, err, "not_found")
+	require.Nil(t, got)
+
+	machineClient := NewMachineClient("some-machine-client", []byte("some-hash"), "some-username", []string{"some-group"})
+	machineClients.SetMachineClients([]*MachineClient{machineClient})
+	require.Same(t, machineClient, machineClients.GetMachineClient("some-machine-client"))
+
+	got, err = registry.GetClient(ctx, "some-machine-client")
+	require.NoError(t, err)
+	require.Same(t, machineClient.Client, got)
+	require.False(t, got.IsPublic())
+	require.Equal(t, []byte("some-hash"), got.GetHashedSecret())
+	require.Equal(t, fosite.Arguments{"client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"}, got.GetGrantTypes())
+	require.Equal(t, fosite.Arguments{"openid", "pinniped:request-audience"}, got.GetScopes())
+	require.Equal(t, "client_secret_basic", got.(fosite.OpenIDConnectClient).GetTokenEndpointAuthMethod())
+
+	got, err = registry.GetClient(ctx, "pinniped-cli")
+	require.NoError(t, err)
+	require.Equal(t, PinnipedCLI(), got)
+
+	machineClients.SetMachineClients(nil)
+	require.Nil(t, machineClients.GetMachineClient("some-machine-client"))
+
+	got, err = NewDynamicClientManager(nil).GetClient(ctx, "pinniped-cli")
+	require.NoError(t, err)
+	require.Equal(t, PinnipedCLI(), got)
+}
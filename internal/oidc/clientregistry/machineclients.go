@@ -0,0 +1,112 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clientregistry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/ory/fosite"
+)
+
+// MachineClient is a confidential client which may get tokens for itself using the client credentials grant, as
+// configured by a MachineClient resource.
+type MachineClient struct {
+	// Client is the fosite client, which has the client's ID and its bcrypt hashed client secret.
+	Client *Client
+
+	// Username and Groups are the identity of the tokens which are issued to the client.
+	Username string
+	Groups   []string
+
+	// AllowDelegation allows the client to act on behalf of users in token exchange requests.
+	AllowDelegation bool
+}
+
+// NewMachineClient returns a MachineClient which may use the client credentials and token exchange grants.
+func NewMachineClient(id string, hashedSecret []byte, username string, groups []string) *MachineClient {
+	return &MachineClient{
+		Client: &Client{
+			DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
+				DefaultClient: &fosite.DefaultClient{
+					ID:     id,
+					Secret: hashedSecret,
+					GrantTypes: fosite.Arguments{
+						"client_credentials",
+						"urn:ietf:params:oauth:grant-type:token-exchange",
+					},
+					Scopes: fosite.Arguments{
+						oidc.ScopeOpenID,
+						"pinniped:request-audience",
+					},
+					Public: false,
+				},
+				TokenEndpointAuthMethod: "client_secret_basic",
+			},
+		},
+		Username: username,
+		Groups:   groups,
+	}
+}
+
+// DynamicMachineClientProvider is a thread-safe cache of the configured machine clients.
+type DynamicMachineClientProvider interface {
+	SetMachineClients(machineClients []*MachineClient)
+	// GetMachineClient returns the machine client with the ID, or nil when there is no such client.
+	GetMachineClient(id string) *MachineClient
+}
+
+type dynamicMachineClientProvider struct {
+	idToMachineClient map[string]*MachineClient
+	mutex             sync.RWMutex
+}
+
+func NewDynamicMachineClientProvider() DynamicMachineClientProvider {
+	return &dynamicMachineClientProvider{
+		idToMachineClient: map[string]*MachineClient{},
+	}
+}
+
+func (p *dynamicMachineClientProvider) SetMachineClients(machineClients []*MachineClient) {
+	idToMachineClient := make(map[string]*MachineClient, len(machineClients))
+	for _, machineClient := range machineClients {
+		idToMachineClient[machineClient.Client.GetID()] = machineClient
+	}
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.idToMachineClient = idToMachineClient
+}
+
+func (p *dynamicMachineClientProvider) GetMachineClient(id string) *MachineClient {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	return p.idToMachineClient[id]
+}
+
+// DynamicClientManager is a fosite.ClientManager which has the static clients and the configured machine clients.
+type DynamicClientManager struct {
+	StaticClientManager
+	machineClients DynamicMachineClientProvider
+}
+
+var _ fosite.ClientManager = (*DynamicClientManager)(nil)
+
+// NewDynamicClientManager returns a DynamicClientManager. The machineClients may be nil, in which case it only has
+// the static clients.
+func NewDynamicClientManager(machineClients DynamicMachineClientProvider) *DynamicClientManager {
+	return &DynamicClientManager{machineClients: machineClients}
+}
+
+// GetClient returns the static client or the machine client specified by the given ID.
+//
+// It returns a fosite.ErrNotFound if an unknown client is specified.
+func (m *DynamicClientManager) GetClient(ctx context.Context, id string) (fosite.Client, error) {
+	if m.machineClients != nil {
+		if machineClient := m.machineClients.GetMachineClient(id); machineClient != nil {
+			return machineClient.Client, nil
+		}
+	}
+	return m.StaticClientManager.GetClient(ctx, id)
+}
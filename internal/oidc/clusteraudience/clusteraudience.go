@@ -0,0 +1,116 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clusteraudience holds the clusters which may receive tokens from the Supervisor using RFC 8693 token
+// exchange, as configured by ClusterAudiences.
+package clusteraudience
+
+import (
+	"strings"
+	"sync"
+)
+
+// Cluster is the validated configuration of one audience.
+type Cluster struct {
+	// Audience of the tokens for this cluster.
+	Audience string
+
+	// AllowedGroups are the groups whose members may receive tokens. When empty, every user may receive tokens.
+	AllowedGroups []string
+
+	// AllowedActorGroups are the groups whose members may act on behalf of users. When empty, nobody may.
+	AllowedActorGroups []string
+
+	// IncludeUsername and IncludeGroups choose the identity claims of the tokens.
+	IncludeUsername bool
+	IncludeGroups   bool
+
+	// GroupsFilter limits the groups claim when it is not empty. An entry which ends with "*" is a prefix.
+	GroupsFilter []string
+}
+
+// IsAllowed returns true when a user who belongs to the given groups may receive tokens for the cluster.
+func (c *Cluster) IsAllowed(groups []string) bool {
+	if len(c.AllowedGroups) == 0 {
+		return true
+	}
+	return memberOfAny(groups, c.AllowedGroups)
+}
+
+// IsActorAllowed returns true when an actor who belongs to the given groups may receive tokens for the cluster on
+// behalf of users.
+func (c *Cluster) IsActorAllowed(groups []string) bool {
+	return memberOfAny(groups, c.AllowedActorGroups)
+}
+
+func memberOfAny(groups []string, allowed []string) bool {
+	for _, group := range groups {
+		for _, a := range allowed {
+			if group == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterGroups returns the groups which should be in the groups claim of the tokens for the cluster.
+func (c *Cluster) FilterGroups(groups []string) []string {
+	if len(c.GroupsFilter) == 0 {
+		return groups
+	}
+	filtered := []string{}
+	for _, group := range groups {
+		if matchesAny(group, c.GroupsFilter) {
+			filtered = append(filtered, group)
+		}
+	}
+	return filtered
+}
+
+func matchesAny(group string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(group, prefix) {
+				return true
+			}
+		} else if group == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// DynamicClusterAudienceProvider is a thread-safe cache of the configured clusters.
+type DynamicClusterAudienceProvider interface {
+	SetClusters(clusters []*Cluster)
+	// GetCluster returns the cluster of the audience, or nil when no cluster has that audience.
+	GetCluster(audience string) *Cluster
+}
+
+type dynamicClusterAudienceProvider struct {
+	audienceToCluster map[string]*Cluster
+	mutex             sync.RWMutex
+}
+
+func NewDynamicClusterAudienceProvider() DynamicClusterAudienceProvider {
+	return &dynamicClusterAudienceProvider{
+		audienceToCluster: map[string]*Cluster{},
+	}
+}
+
+func (p *dynamicClusterAudienceProvider) SetClusters(clusters []*Cluster) {
+	audienceToCluster := make(map[string]*Cluster, len(clusters))
+	for _, cluster := range clusters {
+		audienceToCluster[cluster.Audience] = cluster
+	}
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.audienceToCluster = audienceToCluster
+}
+
+func (p *dynamicClusterAudienceProvider) GetCluster(audience string) *Cluster {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	return p.audienceToCluster[audience]
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clusteraudience
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAllowed(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowedGroups []string
+		groups        []string
+		want          bool
+	}{
+		{name: "no allowed groups allows everyone", groups: nil, want: true},
+		{name: "member of an allowed group", allowedGroups: []string{"a", "b"}, groups: []string{"c", "b"}, want: true},
+		{name: "not a member of any allowed group", allowedGroups: []string{"a", "b"}, groups: []string{"c"}, want: false},
+		{name: "no groups at all", allowedGroups: []string{"a"}, groups: nil, want: false},
+		{name: "allowed groups are not prefixes", allowedGroups: []string{"a*"}, groups: []string{"ab"}, want: false},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &Cluster{AllowedGroups: test.allowedGroups}
+			require.Equal(t, test.want, cluster.IsAllowed(test.groups))
+		})
+	}
+}
+
+func TestIsActorAllowed(t *testing.T) {
+	tests := []struct {
+		name               string
+		allowedActorGroups []string
+		groups             []string
+		want               bool
+	}{
+		{name: "no allowed actor groups allows nobody", groups: []string{"a"}, want: false},
+		{name: "member of an allowed actor group", allowedActorGroups: []string{"a", "b"}, groups: []string{"c", "b"}, want: true},
+		{name: "not a member of any allowed actor group", allowedActorGroups: []string{"a", "b"}, groups: []string{"c"}, want: false},
+		{name: "no groups at all", allowedActorGroups: []string{"a"}, groups: nil, want: false},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &Cluster{AllowedActorGroups: test.allowedActorGroups}
+			require.Equal(t, test.want, cluster.IsActorAllowed(test.groups))
+		})
+	}
+}
+
+func TestFilterGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter []string
+		groups []string
+		want   []string
+	}{
+		{name: "no filter", groups: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "exact names", filter: []string{"b", "d"}, groups: []string{"a", "b", "c", "d"}, want: []string{"b", "d"}},
+		{name: "prefixes", filter: []string{"team-*"}, groups: []string{"team-a", "other", "team-b", "team"}, want: []string{"team-a", "team-b"}},
+		{name: "star alone matches everything", filter: []string{"*"}, groups: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "nothing matches", filter: []string{"x"}, groups: []string{"a"}, want: []string{}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cluster := &Cluster{GroupsFilter: test.filter}
+			require.Equal(t, test.want, cluster.FilterGroups(test.groups))
+		})
+	}
+}
+
+func TestDynamicClusterAudienceProvider(t *testing.T) {
+	p := NewDynamicClusterAudienceProvider()
+	require.Nil(t, p.GetCluster("some-audience"))
+
+	cluster := &Cluster{Audience: "some-audience"}
+	p.SetClusters([]*Cluster{cluster, {Audience: "other-audience"}})
+	require.Same(t, cluster, p.GetCluster("some-audience"))
+	require.Nil(t, p.GetCluster("unknown-audience"))
+
+	p.SetClusters(nil)
+	require.Nil(t, p.GetCluster("some-audience"))
+}
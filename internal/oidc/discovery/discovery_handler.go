@@ -37,6 +37,13 @@ type Metadata struct {
 	ScopesSupported                   []string `json:"scopes_supported"`
 	ClaimsSupported                   []string `json:"claims_supported"`
 
+	RequestParameterSupported              bool     `json:"request_parameter_supported"`
+	RequestURIParameterSupported           bool     `json:"request_uri_parameter_supported"`
+	RequestObjectSigningAlgValuesSupported []string `json:"request_object_signing_alg_values_supported,omitempty"`
+
+	// PushedAuthorizationRequestEndpoint is defined in RFC 9126 section 5.
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+
 	// ^^^ Optional ^^^
 
 	// vvv Custom vvv
@@ -70,6 +77,12 @@ func NewHandler(issuerURL string) http.Handler {
 		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic"},
 		ScopesSupported:                   []string{"openid", "offline"},
 		ClaimsSupported:                   []string{"groups"},
+
+		// None of the clients which may use the authorization endpoint has JSON Web Keys registered, so none of them
+		// can sign request objects yet. Pushed authorization requests are accepted from all of them.
+		RequestParameterSupported:          false,
+		RequestURIParameterSupported:       true,
+		PushedAuthorizationRequestEndpoint: issuerURL + oidc.PushedAuthorizationRequestEndpointPath,
 	}
 
 	var b bytes.Buffer
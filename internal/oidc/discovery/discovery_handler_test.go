@@ -49,6 +49,10 @@ func TestDiscovery(t *testing.T) {
 				TokenEndpointAuthMethodsSupported: []string{"client_secret_basic"},
 				ScopesSupported:                   []string{"openid", "offline"},
 				ClaimsSupported:                   []string{"groups"},
+
+				RequestParameterSupported:          false,
+				RequestURIParameterSupported:       true,
+				PushedAuthorizationRequestEndpoint: "https://some-issuer.com/some/path/oauth2/par",
 			},
 		},
 		{
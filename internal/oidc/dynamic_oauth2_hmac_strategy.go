@@ -96,3 +96,9 @@ func (s *dynamicOauth2HMACStrategy) ValidateAuthorizeCode(
 func (s *dynamicOauth2HMACStrategy) delegate() *oauth2.HMACSHAStrategy {
 	return compose.NewOAuth2HMACStrategy(s.fositeConfig, s.keyFunc(), nil)
 }
+
+// RefreshTokenSignature returns the signature under which a refresh token issued by FositeOauth2Helper is stored.
+// Unlike issuing a refresh token, this does not need the HMAC key.
+func RefreshTokenSignature(token string) string {
+	return compose.NewOAuth2HMACStrategy(&compose.Config{}, nil, nil).RefreshTokenSignature(token)
+}
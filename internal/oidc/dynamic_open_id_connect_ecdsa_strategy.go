@@ -5,12 +5,15 @@ package oidc
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"reflect"
+	"crypto"
+	"errors"
+	"strings"
 
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/compose"
 	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"gopkg.in/square/go-jose.v2"
 
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/oidc/jwks"
@@ -25,6 +28,8 @@ import (
 // If we ever update FederationDomain's to hold their signing key, we might not need this type, since we
 // could have an invariant that routes to an FederationDomain's endpoints are only wired up if an
 // FederationDomain has a valid signing key.
+//
+// The signing key is only used through a jwks.Signer, so it does not need to be in the memory of the Supervisor.
 type dynamicOpenIDConnectECDSAStrategy struct {
 	fositeConfig *compose.Config
 	jwksProvider jwks.DynamicJWKSProvider
@@ -46,26 +51,120 @@ func (s *dynamicOpenIDConnectECDSAStrategy) GenerateIDToken(
 	ctx context.Context,
 	requester fosite.Requester,
 ) (string, error) {
-	_, activeJwk := s.jwksProvider.GetJWKS(s.fositeConfig.IDTokenIssuer)
-	if activeJwk == nil {
+	signer, err := s.jwksProvider.GetSigner(s.fositeConfig.IDTokenIssuer)
+	if err != nil {
+		plog.Debug(
+			"JWK cannot be used for signing",
+			"issuer",
+			s.fositeConfig.IDTokenIssuer,
+			"err",
+			err,
+		)
+		return "", fosite.ErrServerError.WithWrap(err)
+	}
+	if signer == nil {
 		plog.Debug("no JWK found for issuer", "issuer", s.fositeConfig.IDTokenIssuer)
 		return "", fosite.ErrTemporarilyUnavailable.WithWrap(constable.Error("no JWK found for issuer"))
 	}
-	key, ok := activeJwk.Key.(*ecdsa.PrivateKey)
-	if !ok {
-		actualType := "nil"
-		if t := reflect.TypeOf(activeJwk.Key); t != nil {
-			actualType = t.String()
-		}
+
+	jwtStrategy, err := newSignerJWTStrategy(signer)
+	if err != nil {
 		plog.Debug(
-			"JWK must be of type ecdsa",
+			"JWK cannot be used for signing",
 			"issuer",
 			s.fositeConfig.IDTokenIssuer,
-			"actualType",
-			actualType,
+			"err",
+			err,
 		)
-		return "", fosite.ErrServerError.WithWrap(constable.Error("JWK must be of type ecdsa"))
+		return "", fosite.ErrServerError.WithWrap(err)
+	}
+
+	return (&openid.DefaultStrategy{
+		JWTStrategy:         jwtStrategy,
+		Expiry:              s.fositeConfig.GetIDTokenLifespan(),
+		Issuer:              s.fositeConfig.IDTokenIssuer,
+		MinParameterEntropy: s.fositeConfig.GetMinParameterEntropy(),
+	}).GenerateIDToken(ctx, requester)
+}
+
+// signerJWTStrategy is a jwt.JWTStrategy like those of fosite, except that it signs with a jwks.Signer instead of
+// with a private key.
+type signerJWTStrategy struct {
+	signer    jose.OpaqueSigner
+	algorithm jose.SignatureAlgorithm
+	publicKey crypto.PublicKey
+}
+
+var _ jwt.JWTStrategy = &signerJWTStrategy{}
+
+func newSignerJWTStrategy(signer jwks.Signer) (*signerJWTStrategy, error) {
+	opaqueSigner, algorithm, err := jwks.NewOpaqueSigner(signer)
+	if err != nil {
+		return nil, err
+	}
+	return &signerJWTStrategy{signer: opaqueSigner, algorithm: algorithm, publicKey: signer.Public()}, nil
+}
+
+func (s *signerJWTStrategy) Generate(_ context.Context, claims jwt.MapClaims, header jwt.Mapper) (string, string, error) {
+	if header == nil || claims == nil {
+		return "", "", errors.New("either claims or header is nil")
+	}
+
+	token := jwt.NewWithClaims(s.algorithm, claims)
+	for k, v := range header.ToMap() {
+		token.Header[k] = v
+	}
+
+	rawToken, err := token.SignedString(s.signer)
+	if err != nil {
+		return "", "", err
+	}
+	sig, err := s.GetSignature(context.Background(), rawToken)
+	if err != nil {
+		return "", "", err
+	}
+	return rawToken, sig, nil
+}
+
+func (s *signerJWTStrategy) Validate(ctx context.Context, token string) (string, error) {
+	if _, err := s.Decode(ctx, token); err != nil {
+		return "", err
+	}
+	return s.GetSignature(ctx, token)
+}
+
+func (s *signerJWTStrategy) Decode(_ context.Context, token string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(token, jwt.MapClaims{}, func(*jwt.Token) (interface{}, error) {
+		return s.publicKey, nil
+	})
+}
+
+func (s *signerJWTStrategy) GetSignature(_ context.Context, token string) (string, error) {
+	split := strings.Split(token, ".")
+	if len(split) != 3 {
+		return "", errors.New("header, body and signature must all be set")
 	}
+	return split[2], nil
+}
 
-	return compose.NewOpenIDConnectECDSAStrategy(s.fositeConfig, key).GenerateIDToken(ctx, requester)
+// Hash is used for the at_hash and c_hash claims, which use the hash algorithm of the JWS algorithm.
+func (s *signerJWTStrategy) Hash(_ context.Context, in []byte) ([]byte, error) {
+	hash := s.hash().New()
+	_, _ = hash.Write(in)
+	return hash.Sum(nil), nil
+}
+
+func (s *signerJWTStrategy) GetSigningMethodLength() int {
+	return s.hash().Size()
+}
+
+func (s *signerJWTStrategy) hash() crypto.Hash {
+	switch s.algorithm { //nolint:exhaustive // all other algorithms use SHA-256
+	case jose.ES384, jose.RS384, jose.PS384:
+		return crypto.SHA384
+	case jose.ES512, jose.RS512, jose.PS512:
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
 }
@@ -5,6 +5,7 @@ package oidc
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -19,9 +20,9 @@ import (
 	"github.com/ory/fosite/token/jwt"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/square/go-jose.v2"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
 
 	"go.pinniped.dev/internal/oidc/jwks"
-	"go.pinniped.dev/internal/testutil/oidctestutil"
 )
 
 func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
@@ -36,6 +37,9 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	require.NoError(t, err)
 
+	ec384PrivateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
 	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	require.NoError(t, err)
 
@@ -45,7 +49,9 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 		jwksProvider   func(jwks.DynamicJWKSProvider)
 		wantErrorType  *fosite.RFC6749Error
 		wantErrorCause string
-		wantSigningJWK *jose.JSONWebKey
+		wantSigningKey crypto.PublicKey
+		wantAlgorithm  jose.SignatureAlgorithm
+		wantKeyID      string
 	}{
 		{
 			name:   "jwks provider does contain signing key for issuer",
@@ -58,11 +64,53 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 							Key: ecPrivateKey,
 						},
 					},
+					nil,
 				)
 			},
-			wantSigningJWK: &jose.JSONWebKey{
-				Key: ecPrivateKey,
+			wantSigningKey: ecPrivateKey.Public(),
+			wantAlgorithm:  jose.ES256,
+		},
+		{
+			name:   "jwks provider contains RSA signing key with key ID for issuer",
+			issuer: goodIssuer,
+			jwksProvider: func(provider jwks.DynamicJWKSProvider) {
+				provider.SetIssuerToJWKSMap(
+					nil,
+					map[string]*jose.JSONWebKey{
+						goodIssuer: {
+							Key:   rsaPrivateKey,
+							KeyID: "some-rsa-key",
+						},
+					},
+					nil,
+				)
 			},
+			wantSigningKey: rsaPrivateKey.Public(),
+			wantAlgorithm:  jose.RS256,
+			wantKeyID:      "some-rsa-key",
+		},
+		{
+			name:   "jwks provider contains signer for issuer",
+			issuer: goodIssuer,
+			jwksProvider: func(provider jwks.DynamicJWKSProvider) {
+				signer, err := jwks.NewJWKSigner(&jose.JSONWebKey{Key: ec384PrivateKey, KeyID: "some-hsm-key"})
+				require.NoError(t, err)
+				provider.SetIssuerToJWKSMap(
+					nil,
+					map[string]*jose.JSONWebKey{
+						goodIssuer: {
+							Key:   rsaPrivateKey,
+							KeyID: "the-signer-should-be-used-instead",
+						},
+					},
+					map[string]jwks.Signer{
+						goodIssuer: signer,
+					},
+				)
+			},
+			wantSigningKey: ec384PrivateKey.Public(),
+			wantAlgorithm:  jose.ES384,
+			wantKeyID:      "some-hsm-key",
 		},
 		{
 			name:           "jwks provider does not contain signing key for issuer",
@@ -78,13 +126,31 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 					nil,
 					map[string]*jose.JSONWebKey{
 						goodIssuer: {
-							Key: rsaPrivateKey,
+							Key: []byte("some-symmetric-key"),
 						},
 					},
+					nil,
 				)
 			},
 			wantErrorType:  fosite.ErrServerError,
-			wantErrorCause: "JWK must be of type ecdsa",
+			wantErrorCause: "JWK must be a private key, but was []uint8",
+		},
+		{
+			name:   "jwks provider contains public key for issuer",
+			issuer: goodIssuer,
+			jwksProvider: func(provider jwks.DynamicJWKSProvider) {
+				provider.SetIssuerToJWKSMap(
+					nil,
+					map[string]*jose.JSONWebKey{
+						goodIssuer: {
+							Key: ecPrivateKey.Public(),
+						},
+					},
+					nil,
+				)
+			},
+			wantErrorType:  fosite.ErrServerError,
+			wantErrorCause: "JWK must be a private key, but was *ecdsa.PublicKey",
 		},
 	}
 	for _, test := range tests {
@@ -121,15 +187,23 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 
-				privateKey, ok := test.wantSigningJWK.Key.(*ecdsa.PrivateKey)
-				require.True(t, ok, "wanted private key to be *ecdsa.PrivateKey, but was %T", test.wantSigningJWK)
-
 				// Perform a light validation on the token to make sure 1) we passed through the correct
 				// signing key and 2) we forwarded the fosite.Requester correctly. Token generation is
 				// tested more expansively in the token endpoint.
-				token := oidctestutil.VerifyECDSAIDToken(t, goodIssuer, clientID, privateKey, idToken)
-				require.Equal(t, goodSubject, token.Subject)
-				require.Equal(t, goodNonce, token.Nonce)
+				token, err := josejwt.ParseSigned(idToken)
+				require.NoError(t, err)
+				require.Len(t, token.Headers, 1)
+				require.Equal(t, string(test.wantAlgorithm), token.Headers[0].Algorithm)
+				require.Equal(t, test.wantKeyID, token.Headers[0].KeyID)
+
+				var claims struct {
+					josejwt.Claims
+					Nonce string `json:"nonce"`
+				}
+				require.NoError(t, token.Claims(test.wantSigningKey, &claims))
+				require.Equal(t, goodIssuer, claims.Issuer)
+				require.Equal(t, goodSubject, claims.Subject)
+				require.Equal(t, goodNonce, claims.Nonce)
 			}
 		})
 	}
@@ -1,4 +1,4 @@
-// Copyright 2020 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package dynamiccodec provides a type that can encode information using a just-in-time signing and
@@ -15,44 +15,72 @@ import (
 
 var _ oidc.Codec = &Codec{}
 
-// KeyFunc returns a single key: a symmetric key.
-type KeyFunc func() []byte
+// KeysFunc returns an ordered list of symmetric keys, newest first. The newest key is used to encode
+// information, and the older keys are still accepted when decoding information so that a key can be
+// rotated without invalidating the values that were encoded just before the rotation.
+type KeysFunc func() [][]byte
 
-// Codec can dynamically encode and decode information by using a KeyFunc to get its keys
+// Codec can dynamically encode and decode information by using a KeysFunc to get its keys
 // just-in-time.
 type Codec struct {
-	lifespan          time.Duration
-	signingKeyFunc    KeyFunc
-	encryptionKeyFunc KeyFunc
+	lifespan           time.Duration
+	signingKeysFunc    KeysFunc
+	encryptionKeysFunc KeysFunc
 }
 
-// New creates a new Codec that will use the provided keyFuncs for its key source, and
+// New creates a new Codec that will use the provided keysFuncs for its key source, and
 // use the securecookie.JSONEncoder. The securecookie.JSONEncoder is used because the default
 // securecookie.GobEncoder is less compact and more difficult to make forward compatible.
 //
 // The returned Codec will make ensure that the encoded values will only be valid for the provided
 // lifespan.
-func New(lifespan time.Duration, signingKeyFunc, encryptionKeyFunc KeyFunc) *Codec {
+func New(lifespan time.Duration, signingKeysFunc, encryptionKeysFunc KeysFunc) *Codec {
 	return &Codec{
-		lifespan:          lifespan,
-		signingKeyFunc:    signingKeyFunc,
-		encryptionKeyFunc: encryptionKeyFunc,
+		lifespan:           lifespan,
+		signingKeysFunc:    signingKeysFunc,
+		encryptionKeysFunc: encryptionKeysFunc,
 	}
 }
 
 // Encode implements oidc.Encode().
 func (c *Codec) Encode(name string, value interface{}) (string, error) {
-	return c.delegate().Encode(name, value)
+	return c.codec(newestKey(c.signingKeysFunc()), newestKey(c.encryptionKeysFunc())).Encode(name, value)
 }
 
 // Decode implements oidc.Decode().
 func (c *Codec) Decode(name string, value string, into interface{}) error {
-	return c.delegate().Decode(name, value, into)
+	// The signing and encryption keys are rotated independently, so any pair of them may have been
+	// used to encode the value.
+	signingKeys := keysOrNil(c.signingKeysFunc())
+	encryptionKeys := keysOrNil(c.encryptionKeysFunc())
+	codecs := make([]securecookie.Codec, 0, len(signingKeys)*len(encryptionKeys))
+	for _, signingKey := range signingKeys {
+		for _, encryptionKey := range encryptionKeys {
+			codecs = append(codecs, c.codec(signingKey, encryptionKey))
+		}
+	}
+	return securecookie.DecodeMulti(name, value, into, codecs...)
 }
 
-func (c *Codec) delegate() *securecookie.SecureCookie {
-	codec := securecookie.New(c.signingKeyFunc(), c.encryptionKeyFunc())
+func (c *Codec) codec(signingKey, encryptionKey []byte) *securecookie.SecureCookie {
+	codec := securecookie.New(signingKey, encryptionKey)
 	codec.MaxAge(int(c.lifespan.Seconds()))
 	codec.SetSerializer(securecookie.JSONEncoder{})
 	return codec
 }
+
+func newestKey(keys [][]byte) []byte {
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[0]
+}
+
+// keysOrNil returns a list holding only a nil key when there are no keys, so that a securecookie.SecureCookie
+// can still be created to report why there are no keys.
+func keysOrNil(keys [][]byte) [][]byte {
+	if len(keys) == 0 {
+		return [][]byte{nil}
+	}
+	return keys
+}
@@ -1,4 +1,4 @@
-// Copyright 2020 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package dynamiccodec
@@ -95,8 +95,8 @@ func TestCodec(t *testing.T) {
 				lifespan = time.Hour
 			}
 
-			encoder := New(lifespan, func() []byte { return encoderSigningKey },
-				func() []byte { return encoderEncryptionKey })
+			encoder := New(lifespan, func() [][]byte { return [][]byte{encoderSigningKey} },
+				func() [][]byte { return [][]byte{encoderEncryptionKey} })
 
 			encoded, err := encoder.Encode("some-name", "some-message")
 			if test.wantEncoderErrorPrefix != "" {
@@ -109,8 +109,8 @@ func TestCodec(t *testing.T) {
 				time.Sleep(test.lifespan + time.Second)
 			}
 
-			decoder := New(lifespan, func() []byte { return decoderSigningKey },
-				func() []byte { return decoderEncryptionKey })
+			decoder := New(lifespan, func() [][]byte { return [][]byte{decoderSigningKey} },
+				func() [][]byte { return [][]byte{decoderEncryptionKey} })
 
 			var decoded string
 			err = decoder.Decode("some-name", encoded, &decoded)
@@ -125,3 +125,91 @@ func TestCodec(t *testing.T) {
 		})
 	}
 }
+
+func TestCodecKeyRotation(t *testing.T) {
+	var (
+		oldSigningKey    = []byte("old-signing-key")
+		newSigningKey    = []byte("new-signing-key")
+		oldEncryptionKey = []byte("16-byte-old-encr")
+		newEncryptionKey = []byte("16-byte-new-encr")
+	)
+
+	tests := []struct {
+		name               string
+		encoderSigningKeys [][]byte
+		encoderEncryption  [][]byte
+		decoderSigningKeys [][]byte
+		decoderEncryption  [][]byte
+		wantDecoderError   string
+	}{
+		{
+			name:               "value encoded before the signing key was rotated",
+			encoderSigningKeys: [][]byte{oldSigningKey},
+			encoderEncryption:  [][]byte{oldEncryptionKey},
+			decoderSigningKeys: [][]byte{newSigningKey, oldSigningKey},
+			decoderEncryption:  [][]byte{oldEncryptionKey},
+		},
+		{
+			name:               "value encoded before the encryption key was rotated",
+			encoderSigningKeys: [][]byte{oldSigningKey},
+			encoderEncryption:  [][]byte{oldEncryptionKey},
+			decoderSigningKeys: [][]byte{oldSigningKey},
+			decoderEncryption:  [][]byte{newEncryptionKey, oldEncryptionKey},
+		},
+		{
+			name:               "value encoded before both keys were rotated",
+			encoderSigningKeys: [][]byte{oldSigningKey},
+			encoderEncryption:  [][]byte{oldEncryptionKey},
+			decoderSigningKeys: [][]byte{newSigningKey, oldSigningKey},
+			decoderEncryption:  [][]byte{newEncryptionKey, oldEncryptionKey},
+		},
+		{
+			name:               "value encoded with the newest keys",
+			encoderSigningKeys: [][]byte{newSigningKey, oldSigningKey},
+			encoderEncryption:  [][]byte{newEncryptionKey, oldEncryptionKey},
+			decoderSigningKeys: [][]byte{newSigningKey},
+			decoderEncryption:  [][]byte{newEncryptionKey},
+		},
+		{
+			name:               "value encoded with the newest signing key and no encryption key",
+			encoderSigningKeys: [][]byte{newSigningKey, oldSigningKey},
+			decoderSigningKeys: [][]byte{newSigningKey, oldSigningKey},
+		},
+		{
+			name:               "value encoded before the previous key was dropped",
+			encoderSigningKeys: [][]byte{oldSigningKey},
+			encoderEncryption:  [][]byte{oldEncryptionKey},
+			decoderSigningKeys: [][]byte{newSigningKey},
+			decoderEncryption:  [][]byte{oldEncryptionKey},
+			wantDecoderError:   "securecookie: the value is not valid",
+		},
+		{
+			name:               "value encoded with the newest keys is not accepted by the previous keys",
+			encoderSigningKeys: [][]byte{newSigningKey, oldSigningKey},
+			encoderEncryption:  [][]byte{newEncryptionKey, oldEncryptionKey},
+			decoderSigningKeys: [][]byte{oldSigningKey},
+			decoderEncryption:  [][]byte{oldEncryptionKey},
+			wantDecoderError:   "securecookie: the value is not valid",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			encoder := New(time.Hour, func() [][]byte { return test.encoderSigningKeys },
+				func() [][]byte { return test.encoderEncryption })
+			encoded, err := encoder.Encode("some-name", "some-message")
+			require.NoError(t, err)
+
+			decoder := New(time.Hour, func() [][]byte { return test.decoderSigningKeys },
+				func() [][]byte { return test.decoderEncryption })
+			var decoded string
+			err = decoder.Decode("some-name", encoded, &decoded)
+			if test.wantDecoderError != "" {
+				require.EqualError(t, err, test.wantDecoderError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "some-message", decoded)
+		})
+	}
+}
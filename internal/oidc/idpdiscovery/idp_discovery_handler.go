@@ -11,6 +11,7 @@ import (
 	"sort"
 
 	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/provider"
 )
 
 const (
@@ -19,23 +20,24 @@ const (
 )
 
 type response struct {
-	IDPs []identityProviderResponse `json:"pinniped_identity_providers"`
+	IDPs []IdentityProvider `json:"pinniped_identity_providers"`
 }
 
-type identityProviderResponse struct {
+// IdentityProvider describes an upstream identity provider which is listed for end users.
+type IdentityProvider struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
 }
 
 // NewHandler returns an http.Handler that serves the upstream IDP discovery endpoint.
-func NewHandler(upstreamIDPs oidc.UpstreamIdentityProvidersLister) http.Handler {
+func NewHandler(upstreamIDPs oidc.UpstreamIdentityProvidersLister, settings provider.FederationDomainIdentityProviders) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, `Method not allowed (try GET)`, http.StatusMethodNotAllowed)
 			return
 		}
 
-		encodedMetadata, encodeErr := responseAsJSON(upstreamIDPs)
+		encodedMetadata, encodeErr := responseAsJSON(upstreamIDPs, settings)
 		if encodeErr != nil {
 			http.Error(w, encodeErr.Error(), http.StatusInternalServerError)
 			return
@@ -49,27 +51,38 @@ func NewHandler(upstreamIDPs oidc.UpstreamIdentityProvidersLister) http.Handler
 	})
 }
 
-func responseAsJSON(upstreamIDPs oidc.UpstreamIdentityProvidersLister) ([]byte, error) {
+func responseAsJSON(upstreamIDPs oidc.UpstreamIdentityProvidersLister, settings provider.FederationDomainIdentityProviders) ([]byte, error) {
 	r := response{
-		IDPs: []identityProviderResponse{},
+		IDPs: List(upstreamIDPs, settings),
 	}
 
+	var b bytes.Buffer
+	encodeErr := json.NewEncoder(&b).Encode(&r)
+	encodedMetadata := b.Bytes()
+
+	return encodedMetadata, encodeErr
+}
+
+// List returns the upstream identity providers which should be listed for end users, sorted by name.
+func List(upstreamIDPs oidc.UpstreamIdentityProvidersLister, settings provider.FederationDomainIdentityProviders) []IdentityProvider {
+	idps := []IdentityProvider{}
+
 	// The cache of IDPs could change at any time, so always recalculate the list.
-	for _, provider := range upstreamIDPs.GetLDAPIdentityProviders() {
-		r.IDPs = append(r.IDPs, identityProviderResponse{Name: provider.GetName(), Type: idpDiscoveryTypeLDAP})
+	for _, p := range upstreamIDPs.GetLDAPIdentityProviders() {
+		if !settings.IsHidden(p.GetName()) {
+			idps = append(idps, IdentityProvider{Name: p.GetName(), Type: idpDiscoveryTypeLDAP})
+		}
 	}
-	for _, provider := range upstreamIDPs.GetOIDCIdentityProviders() {
-		r.IDPs = append(r.IDPs, identityProviderResponse{Name: provider.GetName(), Type: idpDiscoveryTypeOIDC})
+	for _, p := range upstreamIDPs.GetOIDCIdentityProviders() {
+		if !settings.IsHidden(p.GetName()) {
+			idps = append(idps, IdentityProvider{Name: p.GetName(), Type: idpDiscoveryTypeOIDC})
+		}
 	}
 
 	// Nobody like an API that changes the results unnecessarily. :)
-	sort.SliceStable(r.IDPs, func(i, j int) bool {
-		return r.IDPs[i].Name < r.IDPs[j].Name
+	sort.SliceStable(idps, func(i, j int) bool {
+		return idps[i].Name < idps[j].Name
 	})
 
-	var b bytes.Buffer
-	encodeErr := json.NewEncoder(&b).Encode(&r)
-	encodedMetadata := b.Bytes()
-
-	return encodedMetadata, encodeErr
+	return idps
 }
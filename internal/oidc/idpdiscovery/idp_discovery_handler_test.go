@@ -20,8 +20,9 @@ func TestIDPDiscovery(t *testing.T) {
 	tests := []struct {
 		name string
 
-		method string
-		path   string
+		method   string
+		path     string
+		settings provider.FederationDomainIdentityProviders
 
 		wantStatus                 int
 		wantContentType            string
@@ -36,7 +37,7 @@ func TestIDPDiscovery(t *testing.T) {
 			wantStatus:      http.StatusOK,
 			wantContentType: "application/json",
 			wantFirstResponseBodyJSON: &response{
-				IDPs: []identityProviderResponse{
+				IDPs: []IdentityProvider{
 					{Name: "a-some-ldap-idp", Type: "ldap"},
 					{Name: "a-some-oidc-idp", Type: "oidc"},
 					{Name: "x-some-idp", Type: "ldap"},
@@ -46,7 +47,7 @@ func TestIDPDiscovery(t *testing.T) {
 				},
 			},
 			wantSecondResponseBodyJSON: &response{
-				IDPs: []identityProviderResponse{
+				IDPs: []IdentityProvider{
 					{Name: "some-other-ldap-idp-1", Type: "ldap"},
 					{Name: "some-other-ldap-idp-2", Type: "ldap"},
 					{Name: "some-other-oidc-idp-1", Type: "oidc"},
@@ -54,6 +55,29 @@ func TestIDPDiscovery(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:            "hidden IDPs are not listed",
+			method:          http.MethodGet,
+			path:            "/some/path" + oidc.WellKnownEndpointPath,
+			settings:        provider.FederationDomainIdentityProviders{HiddenNames: []string{"x-some-idp", "some-other-oidc-idp-1"}},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantFirstResponseBodyJSON: &response{
+				IDPs: []IdentityProvider{
+					{Name: "a-some-ldap-idp", Type: "ldap"},
+					{Name: "a-some-oidc-idp", Type: "oidc"},
+					{Name: "z-some-ldap-idp", Type: "ldap"},
+					{Name: "z-some-oidc-idp", Type: "oidc"},
+				},
+			},
+			wantSecondResponseBodyJSON: &response{
+				IDPs: []IdentityProvider{
+					{Name: "some-other-ldap-idp-1", Type: "ldap"},
+					{Name: "some-other-ldap-idp-2", Type: "ldap"},
+					{Name: "some-other-oidc-idp-2", Type: "oidc"},
+				},
+			},
+		},
 		{
 			name:            "bad method",
 			method:          http.MethodPost,
@@ -75,7 +99,7 @@ func TestIDPDiscovery(t *testing.T) {
 				WithLDAP(&oidctestutil.TestUpstreamLDAPIdentityProvider{Name: "x-some-idp"}).
 				Build()
 
-			handler := NewHandler(idpLister)
+			handler := NewHandler(idpLister, test.settings)
 			req := httptest.NewRequest(test.method, test.path, nil)
 			rsp := httptest.NewRecorder()
 			handler.ServeHTTP(rsp, req)
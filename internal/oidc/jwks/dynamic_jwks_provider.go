@@ -1,4 +1,4 @@
-// Copyright 2020 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package jwks
@@ -13,13 +13,18 @@ type DynamicJWKSProvider interface {
 	SetIssuerToJWKSMap(
 		issuerToJWKSMap map[string]*jose.JSONWebKeySet,
 		issuerToActiveJWKMap map[string]*jose.JSONWebKey,
+		issuerToSignerMap map[string]Signer,
 	)
 	GetJWKS(issuerName string) (jwks *jose.JSONWebKeySet, activeJWK *jose.JSONWebKey)
+	// GetSigner returns the Signer for the issuer, or nil when the issuer does not have a signing key yet.
+	// An issuer's Signer is either from a SignerBackend or made from its active JWK.
+	GetSigner(issuerName string) (Signer, error)
 }
 
 type dynamicJWKSProvider struct {
 	issuerToJWKSMap      map[string]*jose.JSONWebKeySet
 	issuerToActiveJWKMap map[string]*jose.JSONWebKey
+	issuerToSignerMap    map[string]Signer
 	mutex                sync.RWMutex
 }
 
@@ -27,17 +32,20 @@ func NewDynamicJWKSProvider() DynamicJWKSProvider {
 	return &dynamicJWKSProvider{
 		issuerToJWKSMap:      map[string]*jose.JSONWebKeySet{},
 		issuerToActiveJWKMap: map[string]*jose.JSONWebKey{},
+		issuerToSignerMap:    map[string]Signer{},
 	}
 }
 
 func (p *dynamicJWKSProvider) SetIssuerToJWKSMap(
 	issuerToJWKSMap map[string]*jose.JSONWebKeySet,
 	issuerToActiveJWKMap map[string]*jose.JSONWebKey,
+	issuerToSignerMap map[string]Signer,
 ) {
 	p.mutex.Lock() // acquire a write lock
 	defer p.mutex.Unlock()
 	p.issuerToJWKSMap = issuerToJWKSMap
 	p.issuerToActiveJWKMap = issuerToActiveJWKMap
+	p.issuerToSignerMap = issuerToSignerMap
 }
 
 func (p *dynamicJWKSProvider) GetJWKS(issuerName string) (*jose.JSONWebKeySet, *jose.JSONWebKey) {
@@ -45,3 +53,16 @@ func (p *dynamicJWKSProvider) GetJWKS(issuerName string) (*jose.JSONWebKeySet, *
 	defer p.mutex.RUnlock()
 	return p.issuerToJWKSMap[issuerName], p.issuerToActiveJWKMap[issuerName]
 }
+
+func (p *dynamicJWKSProvider) GetSigner(issuerName string) (Signer, error) {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	if signer, ok := p.issuerToSignerMap[issuerName]; ok {
+		return signer, nil
+	}
+	activeJWK := p.issuerToActiveJWKMap[issuerName]
+	if activeJWK == nil {
+		return nil, nil
+	}
+	return NewJWKSigner(activeJWK)
+}
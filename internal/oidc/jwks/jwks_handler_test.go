@@ -111,6 +111,6 @@ func newDynamicJWKSProvider(t *testing.T, issuer string, jwksJSON string) Dynami
 	issuerToActiveJWKMap := map[string]*jose.JSONWebKey{
 		issuer: &keySet.Keys[0],
 	}
-	jwksProvider.SetIssuerToJWKSMap(issuerToJWKSMap, issuerToActiveJWKMap)
+	jwksProvider.SetIssuerToJWKSMap(issuerToJWKSMap, issuerToActiveJWKMap, nil)
 	return jwksProvider
 }
@@ -0,0 +1,444 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build cgo
+// +build cgo
+
+// Package pkcs11signer provides a jwks.SignerBackend for signing keys which are kept in a PKCS#11 token, e.g. in a
+// hardware security module. The private keys never leave the token.
+//
+// Loading a PKCS#11 module requires cgo, so this backend is only available in Supervisor binaries which were
+// built with cgo.
+package pkcs11signer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	"gopkg.in/square/go-jose.v2"
+
+	"go.pinniped.dev/internal/oidc/jwks"
+)
+
+//nolint:gochecknoglobals
+var (
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveP384 = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidNamedCurveP521 = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+
+	// digestInfoPrefixes are the DER prefixes of the DigestInfo structures which are signed by RSA PKCS #1 v1.5,
+	// see RFC 8017 section 9.2.
+	digestInfoPrefixes = map[crypto.Hash][]byte{
+		crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+		crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+		crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	}
+)
+
+// maxSessions is the number of PKCS#11 sessions which each backend opens at most, so that this many signatures can be
+// made at the same time. Modules often limit the number of sessions, so this is kept small.
+const maxSessions = 4
+
+type backend struct {
+	module     *pkcs11.Ctx
+	tokenLabel string
+	pin        string
+
+	// A PKCS#11 session must not be used by more than one goroutine at a time, so each operation takes a session
+	// from idle, or opens a new one while fewer than maxSessions are open. The number of open sessions is the number
+	// of values in open.
+	idle chan pkcs11.SessionHandle
+	open chan struct{}
+}
+
+var _ jwks.SignerBackend = &backend{}
+
+// New loads the PKCS#11 module from modulePath and logs in to the token with the label tokenLabel as its user.
+func New(modulePath, tokenLabel, pin string) (jwks.SignerBackend, error) {
+	module := pkcs11.New(modulePath)
+	if module == nil {
+		return nil, fmt.Errorf("could not load PKCS#11 module %q", modulePath)
+	}
+	// Several signers may use the same module, e.g. for different tokens, but the module is only initialized once.
+	initialized := true
+	if err := module.Initialize(); errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		initialized = false
+	} else if err != nil {
+		module.Destroy()
+		return nil, fmt.Errorf("could not initialize PKCS#11 module %q: %w", modulePath, err)
+	}
+
+	b := &backend{
+		module:     module,
+		tokenLabel: tokenLabel,
+		pin:        pin,
+		idle:       make(chan pkcs11.SessionHandle, maxSessions),
+		open:       make(chan struct{}, maxSessions),
+	}
+	// Log in right away, so that a wrong configuration is reported at startup.
+	if err := b.withSession(func(pkcs11.SessionHandle, bool) error { return nil }); err != nil {
+		if initialized {
+			_ = module.Finalize()
+			module.Destroy()
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// withSession calls f with a session which is logged in to the token. When the session turns out to be unusable,
+// e.g. because the token was reset or the module logged out, f is called once more with a new session, and with
+// recovered set to true, since the object handles from before may be invalid too.
+func (b *backend) withSession(f func(session pkcs11.SessionHandle, recovered bool) error) error {
+	session, err := b.acquire(false)
+	if err != nil {
+		return err
+	}
+	err = f(session, false)
+	if !isSessionLost(err) {
+		b.release(session)
+		return err
+	}
+
+	// Other idle sessions may be unusable for the same reason, so a new one is opened.
+	b.discard(session)
+	session, err = b.acquire(true)
+	if err != nil {
+		return err
+	}
+	err = f(session, true)
+	if isSessionLost(err) {
+		b.discard(session)
+		return err
+	}
+	b.release(session)
+	return err
+}
+
+// acquire returns an idle session, or a new one if fresh is true or there is no idle session. It waits while
+// maxSessions sessions are in use.
+func (b *backend) acquire(fresh bool) (pkcs11.SessionHandle, error) {
+	if !fresh {
+		select {
+		case session := <-b.idle:
+			return session, nil
+		default:
+		}
+	}
+
+	for {
+		select {
+		case session := <-b.idle:
+			if !fresh {
+				return session, nil
+			}
+			// Close an idle session to make room for the new one.
+			b.discard(session)
+		case b.open <- struct{}{}:
+			session, err := b.login()
+			if err != nil {
+				<-b.open
+				return 0, err
+			}
+			return session, nil
+		}
+	}
+}
+
+func (b *backend) release(session pkcs11.SessionHandle) {
+	b.idle <- session
+}
+
+func (b *backend) discard(session pkcs11.SessionHandle) {
+	_ = b.module.CloseSession(session)
+	<-b.open
+}
+
+// isSessionLost returns true for the errors which mean that a session, or the object handles which were found in it,
+// can no longer be used, but a new session might work.
+func isSessionLost(err error) bool {
+	for _, lost := range []pkcs11.Error{
+		pkcs11.CKR_SESSION_HANDLE_INVALID,
+		pkcs11.CKR_SESSION_CLOSED,
+		pkcs11.CKR_USER_NOT_LOGGED_IN,
+		pkcs11.CKR_OBJECT_HANDLE_INVALID,
+		pkcs11.CKR_KEY_HANDLE_INVALID,
+		pkcs11.CKR_DEVICE_REMOVED,
+		pkcs11.CKR_TOKEN_NOT_PRESENT,
+	} {
+		if errors.Is(err, lost) {
+			return true
+		}
+	}
+	return false
+}
+
+// login opens a new session with the token and logs in to it. The token is looked up each time, since it may have
+// moved to another slot, e.g. after it was reset.
+func (b *backend) login() (pkcs11.SessionHandle, error) {
+	slots, err := b.module.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("could not list PKCS#11 slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		tokenInfo, err := b.module.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("could not get PKCS#11 token info of slot %d: %w", slot, err)
+		}
+		if tokenInfo.Label != b.tokenLabel {
+			continue
+		}
+
+		session, err := b.module.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+		if err != nil {
+			return 0, fmt.Errorf("could not open PKCS#11 session with token %q: %w", b.tokenLabel, err)
+		}
+		// All sessions of this process share one login, so only the first session usually needs to log in.
+		if err := b.module.Login(session, pkcs11.CKU_USER, b.pin); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+			_ = b.module.CloseSession(session)
+			return 0, fmt.Errorf("could not log in to PKCS#11 token %q: %w", b.tokenLabel, err)
+		}
+		return session, nil
+	}
+
+	return 0, fmt.Errorf("could not find PKCS#11 token %q", b.tokenLabel)
+}
+
+// Signer returns a Signer for the private key which is labeled keyName. The token must also have a public key with
+// the same label, since a PKCS#11 private key does not need to expose its public key.
+func (b *backend) Signer(_ context.Context, keyName string) (jwks.Signer, *jose.JSONWebKeySet, error) {
+	var privateKey pkcs11.ObjectHandle
+	var publicKey crypto.PublicKey
+	err := b.withSession(func(session pkcs11.SessionHandle, _ bool) error {
+		var err error
+		privateKey, err = b.findObject(session, pkcs11.CKO_PRIVATE_KEY, keyName)
+		if err != nil {
+			return err
+		}
+		publicKeyObject, err := b.findObject(session, pkcs11.CKO_PUBLIC_KEY, keyName)
+		if err != nil {
+			return err
+		}
+		publicKey, err = b.publicKey(session, publicKeyObject)
+		if err != nil {
+			return fmt.Errorf("could not read public key %q: %w", keyName, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: publicKey}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not compute thumbprint of public key %q: %w", keyName, err)
+	}
+	signer := &keySigner{
+		backend:    b,
+		keyName:    keyName,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		keyID:      base64.RawURLEncoding.EncodeToString(thumbprint),
+	}
+
+	publicJWK, err := jwks.PublicJWK(signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not use key %q: %w", keyName, err)
+	}
+	return signer, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*publicJWK}}, nil
+}
+
+func (b *backend) findObject(session pkcs11.SessionHandle, class uint, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := b.module.FindObjectsInit(session, template); err != nil {
+		return 0, fmt.Errorf("could not search for key %q: %w", label, err)
+	}
+	objects, _, err := b.module.FindObjects(session, 2)
+	if finalErr := b.module.FindObjectsFinal(session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not search for key %q: %w", label, err)
+	}
+
+	kind := "public"
+	if class == pkcs11.CKO_PRIVATE_KEY {
+		kind = "private"
+	}
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("could not find %s key %q", kind, label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("found more than one %s key %q", kind, label)
+	}
+}
+
+func (b *backend) publicKey(session pkcs11.SessionHandle, object pkcs11.ObjectHandle) (crypto.PublicKey, error) {
+	attributes, err := b.module.GetAttributeValue(session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyType := attributes[0].Value; {
+	case bytes.Equal(keyType, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC).Value):
+		attributes, err := b.module.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ecPublicKey(attributes[0].Value, attributes[1].Value)
+	case bytes.Equal(keyType, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_RSA).Value):
+		attributes, err := b.module.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(attributes[1].Value)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA public exponent is too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(attributes[0].Value), E: int(exponent.Int64())}, nil
+	default:
+		return nil, errors.New("only EC and RSA keys are supported")
+	}
+}
+
+func ecPublicKey(params, point []byte) (*ecdsa.PublicKey, error) {
+	var curveOID asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &curveOID); err != nil {
+		return nil, fmt.Errorf("could not parse EC parameters: %w", err)
+	}
+	var curve elliptic.Curve
+	switch {
+	case curveOID.Equal(oidNamedCurveP256):
+		curve = elliptic.P256()
+	case curveOID.Equal(oidNamedCurveP384):
+		curve = elliptic.P384()
+	case curveOID.Equal(oidNamedCurveP521):
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported elliptic curve %s", curveOID)
+	}
+
+	// The point should be DER encoded, but some modules return the raw point instead.
+	var derPoint []byte
+	if rest, err := asn1.Unmarshal(point, &derPoint); err == nil && len(rest) == 0 {
+		point = derPoint
+	}
+	x, y := elliptic.Unmarshal(curve, point)
+	if x == nil {
+		return nil, errors.New("could not parse EC point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+type keySigner struct {
+	backend   *backend
+	keyName   string
+	publicKey crypto.PublicKey
+	keyID     string
+
+	// privateKey is looked up again when the backend had to open a new session.
+	privateKeyMutex sync.Mutex
+	privateKey      pkcs11.ObjectHandle
+}
+
+var _ jwks.Signer = &keySigner{}
+
+func (s *keySigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *keySigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign implements crypto.Signer. ECDSA signatures are returned in ASN.1 form and RSA signatures use PKCS #1 v1.5,
+// as with the private keys of the standard library.
+func (s *keySigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, errors.New("RSA PSS signatures are not supported")
+	}
+	if len(digest) != opts.HashFunc().Size() {
+		return nil, errors.New("digest has the wrong length for its hash function")
+	}
+
+	switch s.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		signature, err := s.sign(pkcs11.CKM_ECDSA, digest)
+		if err != nil {
+			return nil, err
+		}
+		half := len(signature) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(signature[:half]),
+			S: new(big.Int).SetBytes(signature[half:]),
+		})
+	case *rsa.PublicKey:
+		prefix, ok := digestInfoPrefixes[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash function %s", opts.HashFunc())
+		}
+		return s.sign(pkcs11.CKM_RSA_PKCS, append(append([]byte{}, prefix...), digest...))
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", s.publicKey)
+	}
+}
+
+func (s *keySigner) sign(mechanism uint, data []byte) ([]byte, error) {
+	var signature []byte
+	err := s.backend.withSession(func(session pkcs11.SessionHandle, recovered bool) error {
+		privateKey, err := s.privateKeyHandle(session, recovered)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.module.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, privateKey); err != nil {
+			return fmt.Errorf("could not sign with PKCS#11 key: %w", err)
+		}
+		signature, err = s.backend.module.Sign(session, data)
+		if err != nil {
+			return fmt.Errorf("could not sign with PKCS#11 key: %w", err)
+		}
+		return nil
+	})
+	return signature, err
+}
+
+func (s *keySigner) privateKeyHandle(session pkcs11.SessionHandle, lookUp bool) (pkcs11.ObjectHandle, error) {
+	s.privateKeyMutex.Lock()
+	defer s.privateKeyMutex.Unlock()
+
+	if lookUp {
+		privateKey, err := s.backend.findObject(session, pkcs11.CKO_PRIVATE_KEY, s.keyName)
+		if err != nil {
+			return 0, err
+		}
+		s.privateKey = privateKey
+	}
+	return s.privateKey, nil
+}
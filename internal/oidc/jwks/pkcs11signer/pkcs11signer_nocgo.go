@@ -0,0 +1,18 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !cgo
+// +build !cgo
+
+package pkcs11signer
+
+import (
+	"errors"
+
+	"go.pinniped.dev/internal/oidc/jwks"
+)
+
+// New always fails, since loading a PKCS#11 module requires cgo.
+func New(_, _, _ string) (jwks.SignerBackend, error) {
+	return nil, errors.New("PKCS#11 signers are not supported by this build of the Supervisor, since it was built without cgo")
+}
@@ -0,0 +1,326 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build cgo
+// +build cgo
+
+package pkcs11signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	testTokenLabel = "pinniped-test"
+	testSOPIN      = "5678"
+	testPIN        = "1234"
+)
+
+// softHSMModulePaths are the places where the common Linux distributions install SoftHSM.
+var softHSMModulePaths = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib64/pkcs11/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+}
+
+// newTestBackend returns a backend for a freshly initialized SoftHSM token, along with the path of the SoftHSM
+// module. The token is stored in a temporary directory, so each test starts with an empty token. To run these tests
+// against another PKCS#11 module instead, set PINNIPED_TEST_PKCS11_MODULE, PINNIPED_TEST_PKCS11_TOKEN_LABEL and
+// PINNIPED_TEST_PKCS11_PIN. The keys which the tests generate are session objects, so they do not stay in that token.
+//
+// The tests are skipped when SoftHSM is not installed, unless PINNIPED_TEST_PKCS11_REQUIRED is set.
+func newTestBackend(t *testing.T) (*backend, string) {
+	t.Helper()
+
+	modulePath, tokenLabel, pin := os.Getenv("PINNIPED_TEST_PKCS11_MODULE"), os.Getenv("PINNIPED_TEST_PKCS11_TOKEN_LABEL"), os.Getenv("PINNIPED_TEST_PKCS11_PIN")
+	if modulePath == "" {
+		modulePath, tokenLabel, pin = findSoftHSM(t), testTokenLabel, testPIN
+		initSoftHSMToken(t, modulePath)
+	}
+
+	signerBackend, err := New(modulePath, tokenLabel, pin)
+	require.NoError(t, err)
+	b := signerBackend.(*backend)
+	t.Cleanup(func() {
+		for len(b.idle) > 0 {
+			b.discard(<-b.idle)
+		}
+		_ = b.module.Finalize()
+		b.module.Destroy()
+	})
+	return b, modulePath
+}
+
+// usingSoftHSM returns true unless the tests run against another PKCS#11 module.
+func usingSoftHSM() bool {
+	return os.Getenv("PINNIPED_TEST_PKCS11_MODULE") == ""
+}
+
+func findSoftHSM(t *testing.T) string {
+	t.Helper()
+
+	for _, path := range softHSMModulePaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if os.Getenv("PINNIPED_TEST_PKCS11_REQUIRED") != "" {
+		t.Fatalf("could not find SoftHSM in any of %v", softHSMModulePaths)
+	}
+	t.Skip("SoftHSM is not installed and PINNIPED_TEST_PKCS11_MODULE is not set")
+	return ""
+}
+
+// initSoftHSMToken points SoftHSM at a new token directory and initializes a token in it, like
+// softhsm2-util --init-token would.
+func initSoftHSMToken(t *testing.T, modulePath string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	tokenDir := filepath.Join(dir, "tokens")
+	require.NoError(t, os.Mkdir(tokenDir, 0700))
+	configPath := filepath.Join(dir, "softhsm2.conf")
+	config := fmt.Sprintf("directories.tokendir = %s\nobjectstore.backend = file\nlog.level = ERROR\n", tokenDir)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0600))
+	setEnv(t, "SOFTHSM2_CONF", configPath)
+
+	module := pkcs11.New(modulePath)
+	require.NotNil(t, module)
+	defer module.Destroy()
+	require.NoError(t, module.Initialize())
+	defer func() { require.NoError(t, module.Finalize()) }()
+
+	slots, err := module.GetSlotList(false)
+	require.NoError(t, err)
+	require.NotEmpty(t, slots)
+	require.NoError(t, module.InitToken(slots[0], testSOPIN, testTokenLabel))
+
+	// SoftHSM moves the token to a new slot once it is initialized, so look for it again.
+	slots, err = module.GetSlotList(true)
+	require.NoError(t, err)
+	for _, slot := range slots {
+		tokenInfo, err := module.GetTokenInfo(slot)
+		require.NoError(t, err)
+		if tokenInfo.Label != testTokenLabel {
+			continue
+		}
+		session, err := module.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		require.NoError(t, err)
+		require.NoError(t, module.Login(session, pkcs11.CKU_SO, testSOPIN))
+		require.NoError(t, module.InitPIN(session, testPIN))
+		require.NoError(t, module.Logout(session))
+		require.NoError(t, module.CloseSession(session))
+		return
+	}
+	t.Fatalf("could not find the %q token after initializing it", testTokenLabel)
+}
+
+// setEnv sets an environment variable for the rest of the test.
+func setEnv(t *testing.T, key, value string) {
+	t.Helper()
+
+	previous, wasSet := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if wasSet {
+			require.NoError(t, os.Setenv(key, previous))
+		} else {
+			require.NoError(t, os.Unsetenv(key))
+		}
+	})
+}
+
+// generateKeyPair generates a key pair in its own session, which stays open until the end of the test, since session
+// objects are destroyed with the session which created them. Token objects are only generated in SoftHSM tokens,
+// since they would stay in other tokens.
+func generateKeyPair(t *testing.T, b *backend, label string, onToken bool, mechanism uint, publicKeyAttributes ...*pkcs11.Attribute) {
+	t.Helper()
+
+	session, err := b.login()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.module.CloseSession(session) })
+
+	publicKeyTemplate := append([]*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, onToken),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}, publicKeyAttributes...)
+	privateKeyTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, onToken),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	_, _, err = b.module.GenerateKeyPair(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, publicKeyTemplate, privateKeyTemplate)
+	require.NoError(t, err)
+}
+
+func generateECKeyPair(t *testing.T, b *backend, label string, onToken bool) {
+	t.Helper()
+
+	p256, err := asn1.Marshal(oidNamedCurveP256)
+	require.NoError(t, err)
+	generateKeyPair(t, b, label, onToken, pkcs11.CKM_EC_KEY_PAIR_GEN, pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, p256))
+}
+
+// requireSigns signs with the signer and verifies the signature with the public key of the key set.
+func requireSigns(t *testing.T, signer crypto.Signer, keySet *jose.JSONWebKeySet) {
+	t.Helper()
+
+	digest := sha256.Sum256([]byte("some-payload"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.True(t, ecdsa.VerifyASN1(keySet.Keys[0].Key.(*ecdsa.PublicKey), digest[:], signature))
+}
+
+func TestECSigner(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	generateECKeyPair(t, b, "some-ec-key", false)
+
+	signer, keySet, err := b.Signer(context.Background(), "some-ec-key")
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 1)
+	require.Equal(t, signer.KeyID(), keySet.Keys[0].KeyID)
+	require.Equal(t, "ES256", keySet.Keys[0].Algorithm)
+	require.True(t, keySet.Keys[0].IsPublic())
+
+	requireSigns(t, signer, keySet)
+}
+
+func TestRSASigner(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	generateKeyPair(t, b, "some-rsa-key", false, pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN,
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, 2048),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}),
+	)
+
+	signer, keySet, err := b.Signer(context.Background(), "some-rsa-key")
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 1)
+	require.Equal(t, "RS256", keySet.Keys[0].Algorithm)
+	publicKey := keySet.Keys[0].Key.(*rsa.PublicKey)
+
+	digest := sha256.Sum256([]byte("some-payload"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.NoError(t, rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature))
+
+	digest384 := sha512.Sum384([]byte("some-payload"))
+	signature, err = signer.Sign(rand.Reader, digest384[:], crypto.SHA384)
+	require.NoError(t, err)
+	require.NoError(t, rsa.VerifyPKCS1v15(publicKey, crypto.SHA384, digest384[:], signature))
+
+	_, err = signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{Hash: crypto.SHA256})
+	require.EqualError(t, err, "RSA PSS signatures are not supported")
+}
+
+func TestConcurrentSigning(t *testing.T) {
+	b, _ := newTestBackend(t)
+	generateECKeyPair(t, b, "some-ec-key", false)
+	signer, keySet, err := b.Signer(context.Background(), "some-ec-key")
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("some-payload"))
+	signatures := make(chan []byte, 3*maxSessions)
+	errs := make(chan error, 3*maxSessions)
+	var wg sync.WaitGroup
+	for i := 0; i < 3*maxSessions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+			signatures <- signature
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(signatures)
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	for signature := range signatures {
+		require.True(t, ecdsa.VerifyASN1(keySet.Keys[0].Key.(*ecdsa.PublicKey), digest[:], signature))
+	}
+
+	require.LessOrEqual(t, len(b.open), maxSessions)
+	require.Equal(t, len(b.open), len(b.idle))
+}
+
+func TestSignerRecoversFromClosedSessions(t *testing.T) {
+	b, _ := newTestBackend(t)
+	generateECKeyPair(t, b, "some-ec-key", false)
+	signer, keySet, err := b.Signer(context.Background(), "some-ec-key")
+	require.NoError(t, err)
+	requireSigns(t, signer, keySet)
+
+	// Close the idle sessions behind the backend's back, like a module does when its token is reset.
+	var closed []pkcs11.SessionHandle
+	for len(b.idle) > 0 {
+		session := <-b.idle
+		require.NoError(t, b.module.CloseSession(session))
+		closed = append(closed, session)
+	}
+	for _, session := range closed {
+		b.idle <- session
+	}
+
+	requireSigns(t, signer, keySet)
+	requireSigns(t, signer, keySet)
+}
+
+func TestSignerRecoversFromLogout(t *testing.T) {
+	if !usingSoftHSM() {
+		t.Skip("logging out destroys the session objects, so this test needs token objects, which it only creates in SoftHSM")
+	}
+	b, _ := newTestBackend(t)
+	generateECKeyPair(t, b, "some-ec-key", true)
+	signer, keySet, err := b.Signer(context.Background(), "some-ec-key")
+	require.NoError(t, err)
+	requireSigns(t, signer, keySet)
+
+	// Logging out invalidates the login of every session, and the handles of all private objects.
+	session := <-b.idle
+	require.NoError(t, b.module.Logout(session))
+	b.idle <- session
+
+	requireSigns(t, signer, keySet)
+	requireSigns(t, signer, keySet)
+}
+
+func TestSignerErrors(t *testing.T) {
+	b, modulePath := newTestBackend(t)
+
+	_, _, err := b.Signer(context.Background(), "some-key-which-does-not-exist")
+	require.EqualError(t, err, `could not find private key "some-key-which-does-not-exist"`)
+
+	_, err = New(modulePath, "some-token-which-does-not-exist", "1234")
+	require.EqualError(t, err, `could not find PKCS#11 token "some-token-which-does-not-exist"`)
+}
+
+func TestNewWithBadModule(t *testing.T) {
+	_, err := New("/some/path/which/does/not/exist.so", "some-token", "1234")
+	require.EqualError(t, err, `could not load PKCS#11 module "/some/path/which/does/not/exist.so"`)
+}
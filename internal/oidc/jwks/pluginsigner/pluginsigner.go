@@ -0,0 +1,177 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pluginsigner provides a jwks.SignerBackend which runs an external program to get public keys and to sign,
+// e.g. to use a key management service for which the Supervisor has no built-in support.
+//
+// The program is run once for each operation. It reads a JSON Request from its standard input and writes a JSON
+// Response to its standard output. When it cannot perform the operation, it exits with a non-zero status and may
+// write an explanation to its standard error.
+package pluginsigner
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"go.pinniped.dev/internal/oidc/jwks"
+)
+
+const (
+	// OperationGetPublicKey asks the plugin for the public key of a key, which is returned in Response.PublicKey.
+	OperationGetPublicKey = "GetPublicKey"
+	// OperationSign asks the plugin to sign Request.Digest with a key, which is returned in Response.Signature.
+	OperationSign = "Sign"
+
+	// operationTimeout is how long the plugin may take for each operation.
+	operationTimeout = 30 * time.Second
+)
+
+// Request is written to the standard input of the plugin.
+type Request struct {
+	Operation string `json:"operation"`
+	KeyName   string `json:"keyName"`
+
+	// Digest is the digest to sign. It is only set for OperationSign.
+	Digest []byte `json:"digest,omitempty"`
+	// Hash is the name of the hash function which was used for the digest, e.g. "SHA-256".
+	// It is only set for OperationSign.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Response is read from the standard output of the plugin.
+type Response struct {
+	// PublicKey is the public key, for OperationGetPublicKey. When it does not have a key ID, its thumbprint is used.
+	PublicKey *jose.JSONWebKey `json:"publicKey,omitempty"`
+	// Signature is the signature, for OperationSign. ECDSA signatures must be ASN.1 encoded and RSA signatures must
+	// use PKCS #1 v1.5, as with the private keys of the Go standard library.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+type backend struct {
+	command string
+	args    []string
+}
+
+var _ jwks.SignerBackend = &backend{}
+
+// New returns a jwks.SignerBackend which runs command with args for each of its operations.
+func New(command string, args []string) jwks.SignerBackend {
+	return &backend{command: command, args: args}
+}
+
+func (b *backend) Signer(ctx context.Context, keyName string) (jwks.Signer, *jose.JSONWebKeySet, error) {
+	response, err := b.run(ctx, &Request{Operation: OperationGetPublicKey, KeyName: keyName})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey := response.PublicKey
+	if publicKey == nil || !publicKey.Valid() || !publicKey.IsPublic() {
+		return nil, nil, fmt.Errorf("signer plugin %q did not return a valid public key for key %q", b.command, keyName)
+	}
+	keyID := publicKey.KeyID
+	if keyID == "" {
+		thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not compute thumbprint of public key %q: %w", keyName, err)
+		}
+		keyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	}
+
+	signer := &pluginSigner{backend: b, keyName: keyName, publicKey: publicKey.Key, keyID: keyID}
+	publicJWK, err := jwks.PublicJWK(signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not use key %q: %w", keyName, err)
+	}
+	return signer, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*publicJWK}}, nil
+}
+
+func (b *backend) run(ctx context.Context, request *Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.command, b.args...) //nolint:gosec // the command is from the static configuration
+	cmd.Stdin = bytes.NewReader(requestJSON)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("signer plugin %q failed to %s key %q: %w: %s", b.command, request.Operation, request.KeyName, err, message)
+		}
+		return nil, fmt.Errorf("signer plugin %q failed to %s key %q: %w", b.command, request.Operation, request.KeyName, err)
+	}
+
+	var response Response
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("signer plugin %q returned an invalid response: %w", b.command, err)
+	}
+	return &response, nil
+}
+
+type pluginSigner struct {
+	backend   *backend
+	keyName   string
+	publicKey crypto.PublicKey
+	keyID     string
+}
+
+var _ jwks.Signer = &pluginSigner{}
+
+func (s *pluginSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *pluginSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign implements crypto.Signer. The signature is checked before it is returned, since an invalid signature would
+// only be noticed by the clients which try to verify it.
+func (s *pluginSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, errors.New("RSA PSS signatures are not supported")
+	}
+
+	response, err := s.backend.run(context.Background(), &Request{
+		Operation: OperationSign,
+		KeyName:   s.keyName,
+		Digest:    digest,
+		Hash:      opts.HashFunc().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch publicKey := s.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(publicKey, digest, response.Signature) {
+			return nil, fmt.Errorf("signer plugin %q returned an invalid signature", s.backend.command)
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(publicKey, opts.HashFunc(), digest, response.Signature); err != nil {
+			return nil, fmt.Errorf("signer plugin %q returned an invalid signature: %w", s.backend.command, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", s.publicKey)
+	}
+	return response.Signature, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginsigner
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	pluginEnvVar        = "PINNIPED_TEST_SIGNER_PLUGIN_KEY_FILE"
+	missingKeyName      = "some-missing-key"
+	keyNameWithKeyID    = "some-key-with-a-key-id"
+	badSignatureKeyName = "some-key-with-bad-signatures"
+)
+
+// TestPluginProcess is not a real test. It is run as the signer plugin by the other tests, using the private JWK in
+// the file named by pluginEnvVar.
+func TestPluginProcess(t *testing.T) {
+	keyFile := os.Getenv(pluginEnvVar)
+	if keyFile == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	fail := func(err error) {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var request Request
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fail(err)
+	}
+	keyJSON, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		fail(err)
+	}
+	var key jose.JSONWebKey
+	if err := json.Unmarshal(keyJSON, &key); err != nil {
+		fail(err)
+	}
+
+	var response Response
+	switch {
+	case request.KeyName == missingKeyName:
+		fail(fmt.Errorf("no key named %s", request.KeyName))
+	case request.Operation == OperationGetPublicKey:
+		publicKey := key.Public()
+		if request.KeyName == keyNameWithKeyID {
+			publicKey.KeyID = "some-key-id"
+		}
+		response.PublicKey = &publicKey
+	case request.Operation == OperationSign && request.KeyName == badSignatureKeyName:
+		response.Signature = []byte("some-bad-signature")
+	case request.Operation == OperationSign && request.Hash == crypto.SHA256.String():
+		response.Signature, err = key.Key.(crypto.Signer).Sign(rand.Reader, request.Digest, crypto.SHA256)
+		if err != nil {
+			fail(err)
+		}
+	default:
+		fail(fmt.Errorf("unexpected request %#v", request))
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(&response); err != nil {
+		fail(err)
+	}
+}
+
+func newTestBackend(t *testing.T, privateKey crypto.Signer) *backend {
+	t.Helper()
+
+	keyJSON, err := json.Marshal(jose.JSONWebKey{Key: privateKey})
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, ioutil.WriteFile(keyFile, keyJSON, 0600))
+
+	require.NoError(t, os.Setenv(pluginEnvVar, keyFile))
+	t.Cleanup(func() { require.NoError(t, os.Unsetenv(pluginEnvVar)) })
+
+	return New(os.Args[0], []string{"-test.run=^TestPluginProcess$"}).(*backend)
+}
+
+func TestSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("some-payload"))
+
+	tests := []struct {
+		name          string
+		privateKey    crypto.Signer
+		keyName       string
+		wantAlgorithm string
+		wantKeyID     string
+		wantErr       string
+		wantSignErr   string
+	}{
+		{
+			name:          "EC key",
+			privateKey:    ecKey,
+			keyName:       "some-key",
+			wantAlgorithm: "ES256",
+		},
+		{
+			name:          "RSA key",
+			privateKey:    rsaKey,
+			keyName:       "some-key",
+			wantAlgorithm: "RS256",
+		},
+		{
+			name:          "key with a key ID",
+			privateKey:    ecKey,
+			keyName:       keyNameWithKeyID,
+			wantAlgorithm: "ES256",
+			wantKeyID:     "some-key-id",
+		},
+		{
+			name:       "missing key",
+			privateKey: ecKey,
+			keyName:    missingKeyName,
+			wantErr:    `signer plugin "` + os.Args[0] + `" failed to GetPublicKey key "some-missing-key": exit status 1: no key named some-missing-key`,
+		},
+		{
+			name:          "bad signature",
+			privateKey:    ecKey,
+			keyName:       badSignatureKeyName,
+			wantAlgorithm: "ES256",
+			wantSignErr:   `signer plugin "` + os.Args[0] + `" returned an invalid signature`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			b := newTestBackend(t, test.privateKey)
+
+			signer, keySet, err := b.Signer(context.Background(), test.keyName)
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, keySet.Keys, 1)
+			publicJWK := keySet.Keys[0]
+			require.True(t, publicJWK.IsPublic())
+			require.Equal(t, test.wantAlgorithm, publicJWK.Algorithm)
+			require.Equal(t, signer.KeyID(), publicJWK.KeyID)
+			if test.wantKeyID != "" {
+				require.Equal(t, test.wantKeyID, publicJWK.KeyID)
+			} else {
+				thumbprint, err := publicJWK.Thumbprint(crypto.SHA256)
+				require.NoError(t, err)
+				require.Equal(t, base64.RawURLEncoding.EncodeToString(thumbprint), publicJWK.KeyID)
+			}
+
+			signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+			if test.wantSignErr != "" {
+				require.EqualError(t, err, test.wantSignErr)
+				return
+			}
+			require.NoError(t, err)
+			switch publicKey := publicJWK.Key.(type) {
+			case *ecdsa.PublicKey:
+				require.True(t, ecdsa.VerifyASN1(publicKey, digest[:], signature))
+			case *rsa.PublicKey:
+				require.NoError(t, rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature))
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/cryptosigner"
+)
+
+// Signer signs tokens on behalf of an issuer. The private key might not be in the memory of the Supervisor at all,
+// e.g. when it is kept in a hardware security module, so it is only ever used through the crypto.Signer interface.
+type Signer interface {
+	crypto.Signer
+
+	// KeyID returns the ID of the signing key, which is also the ID of its public key in the issuer's JWKS.
+	KeyID() string
+}
+
+// SignerBackend provides signing keys which are kept outside of Kubernetes. Its implementations must be thread-safe.
+type SignerBackend interface {
+	// Signer returns a Signer for the named key, along with the public JWKS which should be served for that key.
+	Signer(ctx context.Context, keyName string) (Signer, *jose.JSONWebKeySet, error)
+}
+
+// SignerBackends is a collection of SignerBackends by name, as configured in the Supervisor's static configuration.
+type SignerBackends map[string]SignerBackend
+
+type jwkSigner struct {
+	crypto.Signer
+	keyID string
+}
+
+// NewJWKSigner returns a Signer for a private key which is stored in a JWK.
+func NewJWKSigner(jwk *jose.JSONWebKey) (Signer, error) {
+	signer, ok := jwk.Key.(crypto.Signer)
+	if !ok || jwk.IsPublic() {
+		return nil, fmt.Errorf("JWK must be a private key, but was %T", jwk.Key)
+	}
+	if _, err := SignatureAlgorithm(signer.Public()); err != nil {
+		return nil, err
+	}
+	return &jwkSigner{Signer: signer, keyID: jwk.KeyID}, nil
+}
+
+func (s *jwkSigner) KeyID() string {
+	return s.keyID
+}
+
+// SignatureAlgorithm returns the JWS algorithm which is used to sign tokens with the private key of the given public
+// key.
+func SignatureAlgorithm(publicKey crypto.PublicKey) (jose.SignatureAlgorithm, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+		return "", fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return jose.RS256, nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+// PublicJWK returns the public JWK which can be used to verify the tokens signed by the Signer.
+func PublicJWK(signer Signer) (*jose.JSONWebKey, error) {
+	algorithm, err := SignatureAlgorithm(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &jose.JSONWebKey{
+		Key:       signer.Public(),
+		KeyID:     signer.KeyID(),
+		Algorithm: string(algorithm),
+		Use:       "sig",
+	}, nil
+}
+
+type opaqueSigner struct {
+	jose.OpaqueSigner
+	publicJWK *jose.JSONWebKey
+}
+
+// NewOpaqueSigner adapts a Signer to sign JWTs with go-jose, which also sets the kid header of the JWTs.
+func NewOpaqueSigner(signer Signer) (jose.OpaqueSigner, jose.SignatureAlgorithm, error) {
+	publicJWK, err := PublicJWK(signer)
+	if err != nil {
+		return nil, "", err
+	}
+	return &opaqueSigner{
+		OpaqueSigner: cryptosigner.Opaque(signer),
+		publicJWK:    publicJWK,
+	}, jose.SignatureAlgorithm(publicJWK.Algorithm), nil
+}
+
+func (s *opaqueSigner) Public() *jose.JSONWebKey {
+	return s.publicJWK
+}
+
+func (s *opaqueSigner) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{jose.SignatureAlgorithm(s.publicJWK.Algorithm)}
+}
@@ -13,38 +13,72 @@ import (
 	fositepkce "github.com/ory/fosite/handler/pkce"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
 	"go.pinniped.dev/internal/fositestorage/openidconnect"
 	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/fositestoragei"
 	"go.pinniped.dev/internal/oidc/clientregistry"
 )
 
 type KubeStorage struct {
-	clientManager            fosite.ClientManager
-	authorizationCodeStorage oauth2.AuthorizeCodeStorage
-	pkceStorage              fositepkce.PKCERequestStorage
-	oidcStorage              openid.OpenIDConnectRequestStorage
-	accessTokenStorage       accesstoken.RevocationStorage
-	refreshTokenStorage      refreshtoken.RevocationStorage
+	clientManager                 fosite.ClientManager
+	pushedAuthorizeRequestStorage pushedauthorizationrequest.Storage
+	authorizationCodeStorage      oauth2.AuthorizeCodeStorage
+	pkceStorage                   fositepkce.PKCERequestStorage
+	oidcStorage                   openid.OpenIDConnectRequestStorage
+	accessTokenStorage            accesstoken.RevocationStorage
+	refreshTokenStorage           refreshtoken.RevocationStorage
 }
 
 var _ fositestoragei.AllFositeStorage = &KubeStorage{}
-
-func NewKubeStorage(secrets corev1client.SecretInterface, timeoutsConfiguration TimeoutsConfiguration) *KubeStorage {
+var _ pushedauthorizationrequest.Storage = &KubeStorage{}
+
+// NewKubeStorage returns a KubeStorage which stores sessions in the given Secrets. The machineClients may be nil, in
+// which case only the static clients are known.
+func NewKubeStorage(
+	secrets corev1client.SecretInterface,
+	machineClients clientregistry.DynamicMachineClientProvider,
+	timeoutsConfiguration TimeoutsConfiguration,
+) *KubeStorage {
 	nowFunc := time.Now
 	return &KubeStorage{
-		clientManager:            &clientregistry.StaticClientManager{},
-		authorizationCodeStorage: authorizationcode.New(secrets, nowFunc, timeoutsConfiguration.AuthorizationCodeSessionStorageLifetime),
-		pkceStorage:              pkce.New(secrets, nowFunc, timeoutsConfiguration.PKCESessionStorageLifetime),
-		oidcStorage:              openidconnect.New(secrets, nowFunc, timeoutsConfiguration.OIDCSessionStorageLifetime),
-		accessTokenStorage:       accesstoken.New(secrets, nowFunc, timeoutsConfiguration.AccessTokenSessionStorageLifetime),
-		refreshTokenStorage:      refreshtoken.New(secrets, nowFunc, timeoutsConfiguration.RefreshTokenSessionStorageLifetime),
+		clientManager:                 clientregistry.NewDynamicClientManager(machineClients),
+		pushedAuthorizeRequestStorage: pushedauthorizationrequest.New(secrets, nowFunc, timeoutsConfiguration.PushedAuthorizationRequestStorageLifetime),
+		authorizationCodeStorage:      authorizationcode.New(secrets, nowFunc, timeoutsConfiguration.AuthorizationCodeSessionStorageLifetime),
+		pkceStorage:                   pkce.New(secrets, nowFunc, timeoutsConfiguration.PKCESessionStorageLifetime),
+		oidcStorage:                   openidconnect.New(secrets, nowFunc, timeoutsConfiguration.OIDCSessionStorageLifetime),
+		accessTokenStorage:            accesstoken.New(secrets, nowFunc, timeoutsConfiguration.AccessTokenSessionStorageLifetime),
+		refreshTokenStorage:           refreshtoken.New(secrets, nowFunc, timeoutsConfiguration.RefreshTokenSessionStorageLifetime),
 	}
 }
 
+//
+// Pushed authorization requests:
+//
+// These are keyed by the random reference in the request_uri which is returned to the client.
+//
+// The pushed authorization request endpoint will create these. Fosite does not know about them.
+//
+// The authorize endpoint will delete these when they are used, since each request_uri may only be used once.
+// If the client never sends the end user to the authorize endpoint, then they will be garbage collected.
+//
+
+func (k KubeStorage) CreatePushedAuthorizeRequest(ctx context.Context, reference string, request *pushedauthorizationrequest.Request) error {
+	return k.pushedAuthorizeRequestStorage.CreatePushedAuthorizeRequest(ctx, reference, request)
+}
+
+func (k KubeStorage) GetPushedAuthorizeRequest(ctx context.Context, reference string) (*pushedauthorizationrequest.Request, error) {
+	return k.pushedAuthorizeRequestStorage.GetPushedAuthorizeRequest(ctx, reference)
+}
+
+func (k KubeStorage) DeletePushedAuthorizeRequest(ctx context.Context, reference string) error {
+	return k.pushedAuthorizeRequestStorage.DeletePushedAuthorizeRequest(ctx, reference)
+}
+
 //
 // Authorization Code sessions:
 //
@@ -145,7 +179,11 @@ func (k KubeStorage) DeleteAccessTokenSession(ctx context.Context, signatureOfAc
 }
 
 func (k KubeStorage) RevokeAccessToken(ctx context.Context, requestID string) error {
-	return k.accessTokenStorage.RevokeAccessToken(ctx, requestID)
+	if err := k.accessTokenStorage.RevokeAccessToken(ctx, requestID); err != nil {
+		return err
+	}
+	recordTokenRevoked(ctx, requestID, "access_token")
+	return nil
 }
 
 //
@@ -177,7 +215,19 @@ func (k KubeStorage) DeleteRefreshTokenSession(ctx context.Context, signatureOfR
 }
 
 func (k KubeStorage) RevokeRefreshToken(ctx context.Context, requestID string) error {
-	return k.refreshTokenStorage.RevokeRefreshToken(ctx, requestID)
+	if err := k.refreshTokenStorage.RevokeRefreshToken(ctx, requestID); err != nil {
+		return err
+	}
+	recordTokenRevoked(ctx, requestID, "refresh_token")
+	return nil
+}
+
+// recordTokenRevoked audits a revocation, unless it only replaced the old tokens of a session during a refresh.
+func recordTokenRevoked(ctx context.Context, requestID string, tokenType string) {
+	if refreshtoken.IsRotation(ctx) {
+		return
+	}
+	auditlog.Record(ctx, auditlog.Event{Type: auditlog.TokenRevoked, RequestID: requestID, TokenType: tokenType})
 }
 
 //
@@ -0,0 +1,277 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package login provides a handler for the Supervisor's own login page, which is used by web browsers to
+// log in with upstream identity providers that do not have a login page of their own, e.g. LDAP.
+package login
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ory/fosite"
+
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/httputil/securityheader"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/csrftoken"
+	"go.pinniped.dev/internal/oidc/downstreamsession"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
+	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/formposthtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+	"go.pinniped.dev/internal/oidc/provider/loginhtml"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+// The keys of the messages which may be shown above the login form, which are translated by the locale package.
+const (
+	alertMissingCredentials = "login.missingCredentials"
+	alertBadCredentials     = "login.badCredentials"
+	alertTooManyFailures    = "login.tooManyFailures"
+	alertUpstreamError      = "login.upstreamError"
+)
+
+// loginPage renders the login page with the branding of the FederationDomain and in the language of the request.
+type loginPage struct {
+	data     *loginhtml.PageData
+	branding *branding.Branding
+	printer  *locale.Printer
+}
+
+func NewHandler(
+	upstreamIDPs oidc.UpstreamLDAPIdentityProvidersLister,
+	oauthHelper fosite.OAuth2Provider,
+	stateDecoder, cookieDecoder oidc.Decoder,
+	loginLimiter *loginlimiter.Limiter,
+	getBranding func() *branding.Branding,
+) http.Handler {
+	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET or POST)", r.Method)
+		}
+
+		state, err := validateRequest(r, stateDecoder, cookieDecoder)
+		if err != nil {
+			return err
+		}
+
+		ldapUpstream := findUpstreamIDPConfig(state.UpstreamName, upstreamIDPs)
+		if ldapUpstream == nil {
+			plog.Warning("upstream provider not found")
+			return httperr.New(http.StatusUnprocessableEntity, "upstream provider not found")
+		}
+
+		page := &loginPage{
+			data: &loginhtml.PageData{
+				State:    r.FormValue("state"),
+				IDPName:  ldapUpstream.GetName(),
+				PostPath: r.URL.Path,
+			},
+			branding: getBranding(),
+			printer:  locale.FromRequest(r),
+		}
+		w.Header().Set("Content-Security-Policy", loginhtml.ContentSecurityPolicy(page.branding))
+
+		if r.Method == http.MethodGet {
+			return page.render(w, http.StatusOK, "")
+		}
+
+		return handleLogin(w, r, oauthHelper, ldapUpstream, state, loginLimiter, page)
+	})
+	return securityheader.WrapWithCustomCSP(handler, loginhtml.ContentSecurityPolicy(nil))
+}
+
+func handleLogin(
+	w http.ResponseWriter,
+	r *http.Request,
+	oauthHelper fosite.OAuth2Provider,
+	ldapUpstream provider.UpstreamLDAPIdentityProviderI,
+	state *oidc.UpstreamStateParamData,
+	loginLimiter *loginlimiter.Limiter,
+	page *loginPage,
+) error {
+	username := r.PostFormValue("username")
+	password := r.PostFormValue("password")
+	page.data.Username = username
+
+	if username == "" || password == "" {
+		return page.render(w, http.StatusOK, alertMissingCredentials)
+	}
+
+	attempt := loginlimiter.NewAttempt(r, ldapUpstream.GetName(), username)
+	if err := loginLimiter.Begin(r.Context(), attempt); err != nil {
+		var limitedErr *loginlimiter.LimitedError
+		if errors.As(err, &limitedErr) {
+			w.Header().Set("Retry-After", strconv.FormatInt(limitedErr.RetryAfterSeconds(), 10))
+			return page.render(w, http.StatusTooManyRequests, alertTooManyFailures)
+		}
+		plog.WarningErr("error checking failed login attempts", err, "upstreamName", ldapUpstream.GetName())
+		return page.render(w, http.StatusServiceUnavailable, alertUpstreamError)
+	}
+
+	authenticateResponse, authenticated, err := ldapUpstream.AuthenticateUser(r.Context(), username, password)
+	if err != nil {
+		plog.WarningErr("unexpected error during upstream LDAP authentication", err, "upstreamName", ldapUpstream.GetName())
+		loginLimiter.RecordError(r.Context(), attempt)
+		recordLoginFailed(r, state, ldapUpstream, username, "upstream_error")
+		return page.render(w, http.StatusBadGateway, alertUpstreamError)
+	}
+	if !authenticated {
+		plog.Debug("failed upstream LDAP authentication", "upstreamName", ldapUpstream.GetName())
+		recordLoginFailed(r, state, ldapUpstream, username, "invalid_credentials")
+		loginLimiter.RecordFailure(attempt)
+		return page.render(w, http.StatusOK, alertBadCredentials)
+	}
+	if err := loginLimiter.RecordSuccess(r.Context(), attempt); err != nil {
+		plog.WarningErr("error forgetting failed login attempts", err, "upstreamName", ldapUpstream.GetName())
+	}
+
+	downstreamAuthParams, err := url.ParseQuery(state.AuthParams)
+	if err != nil {
+		plog.Error("error reading state downstream auth params", err)
+		return httperr.New(http.StatusBadRequest, "error reading state downstream auth params")
+	}
+
+	// Recreate enough of the original authorize request so we can pass it to NewAuthorizeRequest().
+	reconstitutedAuthRequest := &http.Request{Form: downstreamAuthParams}
+	authorizeRequester, err := oauthHelper.NewAuthorizeRequest(r.Context(), reconstitutedAuthRequest)
+	if err != nil {
+		plog.Error("error using state downstream auth params", err)
+		return httperr.New(http.StatusBadRequest, "error using state downstream auth params")
+	}
+
+	// Automatically grant the openid, offline_access, and pinniped:request-audience scopes, but only if they were requested.
+	downstreamsession.GrantScopesIfRequested(authorizeRequester)
+
+	openIDSession := downstreamsession.MakeDownstreamSession(
+		downstreamsession.DownstreamLDAPSubject(authenticateResponse.User.GetUID(), *ldapUpstream.GetURL()),
+		authenticateResponse.User.GetName(),
+		authenticateResponse.User.GetGroups(),
+		&psession.CustomSessionData{
+			ProviderName: ldapUpstream.GetName(),
+			ProviderType: psession.ProviderTypeLDAP,
+		},
+	)
+	auditlog.Record(r.Context(), oidc.AuditEventForSession(auditlog.UpstreamAuthenticationSucceeded, authorizeRequester, openIDSession))
+
+	// From here on the response is written by fosite, which may render the response_mode=form_post page.
+	w.Header().Set("Content-Security-Policy", formposthtml.ContentSecurityPolicy(page.branding))
+	oauthHelper = oidc.WithFormPostHTMLTemplate(oauthHelper, formposthtml.Template(page.branding, page.printer))
+
+	authorizeResponder, err := oauthHelper.NewAuthorizeResponse(r.Context(), authorizeRequester, openIDSession)
+	if err != nil {
+		plog.Info("authorize response error", oidc.FositeErrorForLog(err)...)
+		oauthHelper.WriteAuthorizeError(w, authorizeRequester, err)
+		return nil
+	}
+
+	oauthHelper.WriteAuthorizeResponse(w, authorizeRequester, authorizeResponder)
+
+	return nil
+}
+
+func recordLoginFailed(r *http.Request, state *oidc.UpstreamStateParamData, ldapUpstream provider.UpstreamLDAPIdentityProviderI, username string, reason string) {
+	// There is no authorize request yet, but the client is known from the params which will be used to make one.
+	downstreamAuthParams, _ := url.ParseQuery(state.AuthParams)
+	auditlog.Record(r.Context(), auditlog.Event{
+		Type:         auditlog.UpstreamAuthenticationFailed,
+		ClientID:     downstreamAuthParams.Get("client_id"),
+		UpstreamName: ldapUpstream.GetName(),
+		UpstreamType: string(psession.ProviderTypeLDAP),
+		Username:     username,
+		Reason:       reason,
+	})
+}
+
+// render writes the login page, with the alert message of the given key above the form unless the key is empty.
+func (p *loginPage) render(w http.ResponseWriter, status int, alert string) error {
+	if alert != "" {
+		p.data.AlertMessage = p.printer.Sprintf(alert)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := loginhtml.Template(p.branding, p.printer).Execute(w, p.data); err != nil {
+		// The status code was already written, so all that we can do is log the error.
+		plog.Error("error rendering login page", err)
+	}
+	return nil
+}
+
+func validateRequest(r *http.Request, stateDecoder, cookieDecoder oidc.Decoder) (*oidc.UpstreamStateParamData, error) {
+	csrfValue, err := readCSRFCookie(r, cookieDecoder)
+	if err != nil {
+		plog.InfoErr("error reading CSRF cookie", err)
+		return nil, err
+	}
+
+	if r.FormValue("state") == "" {
+		plog.Info("state param not found")
+		return nil, httperr.New(http.StatusBadRequest, "state param not found")
+	}
+
+	state, err := readState(r, stateDecoder)
+	if err != nil {
+		plog.InfoErr("error reading state", err)
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(state.CSRFToken), []byte(csrfValue)) != 1 {
+		plog.Info("CSRF value does not match")
+		return nil, httperr.New(http.StatusForbidden, "CSRF value does not match")
+	}
+
+	return state, nil
+}
+
+func findUpstreamIDPConfig(upstreamName string, upstreamIDPs oidc.UpstreamLDAPIdentityProvidersLister) provider.UpstreamLDAPIdentityProviderI {
+	for _, p := range upstreamIDPs.GetLDAPIdentityProviders() {
+		if p.GetName() == upstreamName {
+			return p
+		}
+	}
+	return nil
+}
+
+func readCSRFCookie(r *http.Request, cookieDecoder oidc.Decoder) (csrftoken.CSRFToken, error) {
+	receivedCSRFCookie, err := r.Cookie(oidc.CSRFCookieName)
+	if err != nil {
+		// Error means that the cookie was not found
+		return "", httperr.Wrap(http.StatusForbidden, "CSRF cookie is missing", err)
+	}
+
+	var csrfFromCookie csrftoken.CSRFToken
+	err = cookieDecoder.Decode(oidc.CSRFCookieEncodingName, receivedCSRFCookie.Value, &csrfFromCookie)
+	if err != nil {
+		return "", httperr.Wrap(http.StatusForbidden, "error reading CSRF cookie", err)
+	}
+
+	return csrfFromCookie, nil
+}
+
+func readState(r *http.Request, stateDecoder oidc.Decoder) (*oidc.UpstreamStateParamData, error) {
+	var state oidc.UpstreamStateParamData
+	if err := stateDecoder.Decode(
+		oidc.UpstreamStateParamEncodingName,
+		r.FormValue("state"),
+		&state,
+	); err != nil {
+		return nil, httperr.New(http.StatusBadRequest, "error reading state")
+	}
+
+	if state.FormatVersion != oidc.UpstreamStateParamFormatVersion {
+		return nil, httperr.New(http.StatusUnprocessableEntity, "state format version is invalid")
+	}
+
+	if state.UpstreamType != string(psession.ProviderTypeLDAP) {
+		return nil, httperr.New(http.StatusUnprocessableEntity, "state is not for an LDAP upstream provider")
+	}
+
+	return &state, nil
+}
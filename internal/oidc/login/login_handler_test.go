@@ -0,0 +1,568 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/formposthtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+	"go.pinniped.dev/internal/oidc/provider/loginhtml"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+)
+
+const (
+	happyUpstreamIDPName = "upstream-ldap-idp-name"
+	upstreamLDAPURL      = "ldaps://some-ldap-host:123?base=ou%3Dusers%2Cdc%3Dpinniped%2Cdc%3Ddev"
+
+	happyLDAPUsername                  = "some-ldap-user"
+	happyLDAPUsernameFromAuthenticator = "some-mapped-ldap-username"
+	happyLDAPPassword                  = "some-ldap-password" //nolint:gosec
+	happyLDAPUID                       = "some-ldap-uid"
+
+	happyDownstreamState        = "8b-state"
+	happyDownstreamCSRF         = "test-csrf"
+	happyDownstreamPKCE         = "test-pkce"
+	happyDownstreamNonce        = "test-nonce"
+	happyDownstreamStateVersion = "1"
+
+	downstreamIssuer              = "https://my-downstream-issuer.com/path"
+	downstreamRedirectURI         = "http://127.0.0.1/callback"
+	downstreamClientID            = "pinniped-cli"
+	downstreamNonce               = "some-nonce-value"
+	downstreamPKCEChallenge       = "some-challenge"
+	downstreamPKCEChallengeMethod = "S256"
+
+	loginPath       = "/downstream-provider-name/login"
+	htmlContentType = "text/html; charset=utf-8"
+)
+
+var (
+	happyLDAPGroups                = []string{"group1", "group2", "group3"}
+	happyDownstreamScopesRequested = []string{"openid"}
+	happyDownstreamScopesGranted   = []string{"openid"}
+
+	happyDownstreamRequestParamsQuery = url.Values{
+		"response_type":         []string{"code"},
+		"scope":                 []string{strings.Join(happyDownstreamScopesRequested, " ")},
+		"client_id":             []string{downstreamClientID},
+		"state":                 []string{happyDownstreamState},
+		"nonce":                 []string{downstreamNonce},
+		"code_challenge":        []string{downstreamPKCEChallenge},
+		"code_challenge_method": []string{downstreamPKCEChallengeMethod},
+		"redirect_uri":          []string{downstreamRedirectURI},
+	}
+	happyDownstreamRequestParams = happyDownstreamRequestParamsQuery.Encode()
+)
+
+func TestLoginEndpoint(t *testing.T) {
+	parsedUpstreamLDAPURL, err := url.Parse(upstreamLDAPURL)
+	require.NoError(t, err)
+
+	happyLDAPUpstream := &oidctestutil.TestUpstreamLDAPIdentityProvider{
+		Name: happyUpstreamIDPName,
+		URL:  parsedUpstreamLDAPURL,
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*authenticator.Response, bool, error) {
+			if username == "" || password == "" {
+				return nil, false, fmt.Errorf("should not have passed empty username or password to the authenticator")
+			}
+			if username == happyLDAPUsername && password == happyLDAPPassword {
+				return &authenticator.Response{
+					User: &user.DefaultInfo{
+						Name:   happyLDAPUsernameFromAuthenticator,
+						UID:    happyLDAPUID,
+						Groups: happyLDAPGroups,
+					},
+				}, true, nil
+			}
+			return nil, false, nil
+		},
+	}
+
+	erroringLDAPUpstream := &oidctestutil.TestUpstreamLDAPIdentityProvider{
+		Name: happyUpstreamIDPName,
+		URL:  parsedUpstreamLDAPURL,
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*authenticator.Response, bool, error) {
+			return nil, false, fmt.Errorf("some ldap upstream auth error")
+		},
+	}
+
+	var stateEncoderHashKey = []byte("fake-hash-secret")
+	var stateEncoderBlockKey = []byte("0123456789ABCDEF") // block encryption requires 16/24/32 bytes for AES
+	var cookieEncoderHashKey = []byte("fake-hash-secret2")
+	var cookieEncoderBlockKey = []byte("0123456789ABCDE2") // block encryption requires 16/24/32 bytes for AES
+
+	var happyStateCodec = securecookie.New(stateEncoderHashKey, stateEncoderBlockKey)
+	happyStateCodec.SetSerializer(securecookie.JSONEncoder{})
+	var happyCookieCodec = securecookie.New(cookieEncoderHashKey, cookieEncoderBlockKey)
+	happyCookieCodec.SetSerializer(securecookie.JSONEncoder{})
+
+	happyStateParam := func() *oidctestutil.ExpectedUpstreamStateParamFormat {
+		return &oidctestutil.ExpectedUpstreamStateParamFormat{
+			P: happyDownstreamRequestParams,
+			U: happyUpstreamIDPName,
+			T: "ldap",
+			N: happyDownstreamNonce,
+			C: happyDownstreamCSRF,
+			K: happyDownstreamPKCE,
+			V: happyDownstreamStateVersion,
+		}
+	}
+	encodeState := func(state *oidctestutil.ExpectedUpstreamStateParamFormat) string {
+		encoded, err := happyStateCodec.Encode("s", state)
+		require.NoError(t, err)
+		return encoded
+	}
+	happyState := encodeState(happyStateParam())
+	modifiedHappyState := func(modify func(s *oidctestutil.ExpectedUpstreamStateParamFormat)) string {
+		state := happyStateParam()
+		modify(state)
+		return encodeState(state)
+	}
+
+	encodedIncomingCookieCSRFValue, err := happyCookieCodec.Encode("csrf", happyDownstreamCSRF)
+	require.NoError(t, err)
+	happyCSRFCookie := "__Host-pinniped-csrf=" + encodedIncomingCookieCSRFValue
+
+	getPath := func(state string) string {
+		return loginPath + "?" + url.Values{"state": []string{state}}.Encode()
+	}
+	postBody := func(state, username, password string) string {
+		return url.Values{"state": []string{state}, "username": []string{username}, "password": []string{password}}.Encode()
+	}
+
+	// Note that fosite puts the granted scopes as a param in the redirect URI even though the spec doesn't seem to require it
+	happyDownstreamRedirectLocationRegexp := downstreamRedirectURI + `\?code=([^&]+)&scope=openid&state=` + happyDownstreamState
+
+	tests := []struct {
+		name string
+
+		idp        *oidctestutil.TestUpstreamLDAPIdentityProvider
+		method     string
+		path       string
+		body       string
+		csrfCookie string
+
+		branding       *branding.Branding
+		acceptLanguage string
+
+		wantStatus                 int
+		wantContentType            string
+		wantBody                   string
+		wantLoginPage              *loginhtml.PageData
+		wantRedirectLocationRegexp string
+		wantBodyFormResponseRegexp string
+	}{
+		{
+			name:            "GET renders the login page",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            getPath(happyState),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantLoginPage:   &loginhtml.PageData{State: happyState, IDPName: happyUpstreamIDPName, PostPath: loginPath},
+		},
+		{
+			name:                       "POST with good credentials redirects back to the client with an authcode",
+			idp:                        happyLDAPUpstream,
+			method:                     http.MethodPost,
+			path:                       loginPath,
+			body:                       postBody(happyState, happyLDAPUsername, happyLDAPPassword),
+			csrfCookie:                 happyCSRFCookie,
+			wantStatus:                 http.StatusFound,
+			wantContentType:            htmlContentType,
+			wantRedirectLocationRegexp: happyDownstreamRedirectLocationRegexp,
+		},
+		{
+			name:   "POST with good credentials using response_mode=form_post renders the form post page",
+			idp:    happyLDAPUpstream,
+			method: http.MethodPost,
+			path:   loginPath,
+			body: postBody(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) {
+				s.P = happyDownstreamRequestParams + "&response_mode=form_post"
+			}), happyLDAPUsername, happyLDAPPassword),
+			csrfCookie:                 happyCSRFCookie,
+			wantStatus:                 http.StatusOK,
+			wantContentType:            htmlContentType,
+			wantBodyFormResponseRegexp: `<code id="manual-auth-code">(.+)</code>`,
+		},
+		{
+			name:   "POST with good credentials using response_mode=form_post renders the form post page with the branding and the preferred language",
+			idp:    happyLDAPUpstream,
+			method: http.MethodPost,
+			path:   loginPath,
+			body: postBody(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) {
+				s.P = happyDownstreamRequestParams + "&response_mode=form_post"
+			}), happyLDAPUsername, happyLDAPPassword),
+			csrfCookie:                 happyCSRFCookie,
+			branding:                   &branding.Branding{ProductName: "Acme SSO", PrimaryColor: "#1b3951"},
+			acceptLanguage:             "de-DE,de;q=0.9",
+			wantStatus:                 http.StatusOK,
+			wantContentType:            htmlContentType,
+			wantBodyFormResponseRegexp: `<html lang="de">(?s:.*)<span>Acme SSO</span>(?s:.*)<h1>Anmeldung erfolgreich</h1>(?s:.*)<code id="manual-auth-code">(.+)</code>`,
+		},
+		{
+			name:            "POST with bad credentials re-renders the login page with an error",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodPost,
+			path:            loginPath,
+			body:            postBody(happyState, happyLDAPUsername, "wrong-password"),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantLoginPage: &loginhtml.PageData{
+				State:        happyState,
+				IDPName:      happyUpstreamIDPName,
+				PostPath:     loginPath,
+				Username:     happyLDAPUsername,
+				AlertMessage: "Incorrect username or password.",
+			},
+		},
+		{
+			name:            "POST with bad credentials re-renders the login page with the branding and an error in the preferred language",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodPost,
+			path:            loginPath,
+			body:            postBody(happyState, happyLDAPUsername, "wrong-password"),
+			csrfCookie:      happyCSRFCookie,
+			branding:        &branding.Branding{ProductName: "Acme SSO", Logo: "data:image/png;base64,iVBORw0KGgo="},
+			acceptLanguage:  "ja",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantLoginPage: &loginhtml.PageData{
+				State:        happyState,
+				IDPName:      happyUpstreamIDPName,
+				PostPath:     loginPath,
+				Username:     happyLDAPUsername,
+				AlertMessage: "ユーザー名またはパスワードが正しくありません。",
+			},
+		},
+		{
+			name:            "POST with a blank password re-renders the login page with an error",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodPost,
+			path:            loginPath,
+			body:            postBody(happyState, happyLDAPUsername, ""),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantLoginPage: &loginhtml.PageData{
+				State:        happyState,
+				IDPName:      happyUpstreamIDPName,
+				PostPath:     loginPath,
+				Username:     happyLDAPUsername,
+				AlertMessage: "Please enter a username and password.",
+			},
+		},
+		{
+			name:            "POST when the upstream returns an error re-renders the login page with an error",
+			idp:             erroringLDAPUpstream,
+			method:          http.MethodPost,
+			path:            loginPath,
+			body:            postBody(happyState, happyLDAPUsername, happyLDAPPassword),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusBadGateway,
+			wantContentType: htmlContentType,
+			wantLoginPage: &loginhtml.PageData{
+				State:        happyState,
+				IDPName:      happyUpstreamIDPName,
+				PostPath:     loginPath,
+				Username:     happyLDAPUsername,
+				AlertMessage: "An error occurred while checking your username and password. Please try again later.",
+			},
+		},
+		{
+			name:            "PUT is a bad method",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodPut,
+			path:            getPath(happyState),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusMethodNotAllowed,
+			wantContentType: htmlContentType,
+			wantBody:        "Method Not Allowed: PUT (try GET or POST)\n",
+		},
+		{
+			name:            "missing CSRF cookie",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            getPath(happyState),
+			wantStatus:      http.StatusForbidden,
+			wantContentType: htmlContentType,
+			wantBody:        "Forbidden: CSRF cookie is missing\n",
+		},
+		{
+			name:            "CSRF cookie does not match the state",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodPost,
+			path:            loginPath,
+			body:            postBody(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) { s.C = "other-csrf" }), happyLDAPUsername, happyLDAPPassword),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusForbidden,
+			wantContentType: htmlContentType,
+			wantBody:        "Forbidden: CSRF value does not match\n",
+		},
+		{
+			name:            "missing state param",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            loginPath,
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: htmlContentType,
+			wantBody:        "Bad Request: state param not found\n",
+		},
+		{
+			name:            "state param which cannot be decoded",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            getPath("this-will-not-decode"),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: htmlContentType,
+			wantBody:        "Bad Request: error reading state\n",
+		},
+		{
+			name:            "state param with the wrong format version",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            getPath(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) { s.V = "wrong" })),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: htmlContentType,
+			wantBody:        "Unprocessable Entity: state format version is invalid\n",
+		},
+		{
+			name:            "state param for an OIDC upstream",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            getPath(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) { s.T = "oidc" })),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: htmlContentType,
+			wantBody:        "Unprocessable Entity: state is not for an LDAP upstream provider\n",
+		},
+		{
+			name:            "state param for an upstream which no longer exists",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodGet,
+			path:            getPath(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) { s.U = "other-idp" })),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: htmlContentType,
+			wantBody:        "Unprocessable Entity: upstream provider not found\n",
+		},
+		{
+			name:            "state param with downstream auth params which are no longer valid",
+			idp:             happyLDAPUpstream,
+			method:          http.MethodPost,
+			path:            loginPath,
+			body:            postBody(modifiedHappyState(func(s *oidctestutil.ExpectedUpstreamStateParamFormat) { s.P = "client_id=bogus" }), happyLDAPUsername, happyLDAPPassword),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: htmlContentType,
+			wantBody:        "Bad Request: error using state downstream auth params\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			secrets := client.CoreV1().Secrets("some-namespace")
+
+			// Configure fosite the same way that the production code would.
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			oauthStore := oidc.NewKubeStorage(secrets, nil, timeoutsConfiguration)
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+			jwksProviderIsUnused := jwks.NewDynamicJWKSProvider()
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, nil, nil, nil, timeoutsConfiguration)
+
+			idpLister := oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(test.idp).Build()
+			// The limiter has its own client, so that its Secrets do not get in the way of the assertions below.
+			loginLimiter := loginlimiter.New(fake.NewSimpleClientset().CoreV1().Secrets("some-namespace"), time.Now, testLoginLimiterConfig())
+			subject := NewHandler(idpLister, oauthHelper, happyStateCodec, happyCookieCodec, loginLimiter,
+				func() *branding.Branding { return test.branding })
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if test.body != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if test.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", test.acceptLanguage)
+			}
+			if test.csrfCookie != "" {
+				req.Header.Set("Cookie", test.csrfCookie)
+			}
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), test.wantContentType)
+
+			wantCustomSessionData := &psession.CustomSessionData{
+				ProviderName: happyUpstreamIDPName,
+				ProviderType: psession.ProviderTypeLDAP,
+			}
+
+			switch {
+			case test.wantLoginPage != nil:
+				require.Equal(t, loginhtml.ContentSecurityPolicy(test.branding), rsp.Header().Get("Content-Security-Policy"))
+				var wantBody bytes.Buffer
+				require.NoError(t, loginhtml.Template(test.branding, locale.FromRequest(req)).Execute(&wantBody, test.wantLoginPage))
+				require.Equal(t, wantBody.String(), rsp.Body.String())
+				require.Empty(t, client.Actions())
+			case test.wantRedirectLocationRegexp != "":
+				require.Len(t, rsp.Header().Values("Location"), 1)
+				oidctestutil.RequireAuthCodeRegexpMatch(
+					t,
+					rsp.Header().Get("Location"),
+					test.wantRedirectLocationRegexp,
+					client,
+					secrets,
+					oauthStore,
+					happyDownstreamScopesGranted,
+					upstreamLDAPURL+"&sub="+happyLDAPUID,
+					happyLDAPUsernameFromAuthenticator,
+					happyLDAPGroups,
+					happyDownstreamScopesRequested,
+					downstreamPKCEChallenge,
+					downstreamPKCEChallengeMethod,
+					downstreamNonce,
+					downstreamClientID,
+					downstreamRedirectURI,
+					wantCustomSessionData,
+				)
+			case test.wantBodyFormResponseRegexp != "":
+				require.Equal(t, formposthtml.ContentSecurityPolicy(test.branding), rsp.Header().Get("Content-Security-Policy"))
+				oidctestutil.RequireAuthCodeRegexpMatch(
+					t,
+					rsp.Body.String(),
+					test.wantBodyFormResponseRegexp,
+					client,
+					secrets,
+					oauthStore,
+					happyDownstreamScopesGranted,
+					upstreamLDAPURL+"&sub="+happyLDAPUID,
+					happyLDAPUsernameFromAuthenticator,
+					happyLDAPGroups,
+					happyDownstreamScopesRequested,
+					downstreamPKCEChallenge,
+					downstreamPKCEChallengeMethod,
+					downstreamNonce,
+					downstreamClientID,
+					downstreamRedirectURI,
+					wantCustomSessionData,
+				)
+			default:
+				testutil.RequireSecurityHeaders(t, rsp)
+				require.Equal(t, test.wantBody, rsp.Body.String())
+				require.Empty(t, client.Actions())
+			}
+		})
+	}
+
+	newCountingSubject := func(loginLimiter *loginlimiter.Limiter) (http.Handler, *int) {
+		authenticateCalls := 0
+		countingLDAPUpstream := &oidctestutil.TestUpstreamLDAPIdentityProvider{
+			Name: happyUpstreamIDPName,
+			URL:  parsedUpstreamLDAPURL,
+			AuthenticateFunc: func(ctx context.Context, username, password string) (*authenticator.Response, bool, error) {
+				authenticateCalls++
+				return happyLDAPUpstream.AuthenticateFunc(ctx, username, password)
+			},
+		}
+		idpLister := oidctestutil.NewUpstreamIDPListerBuilder().WithLDAP(countingLDAPUpstream).Build()
+		return NewHandler(idpLister, nil, happyStateCodec, happyCookieCodec, loginLimiter,
+			func() *branding.Branding { return nil }), &authenticateCalls
+	}
+
+	post := func(subject http.Handler, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, loginPath, strings.NewReader(postBody(happyState, username, password)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Cookie", happyCSRFCookie)
+		rsp := httptest.NewRecorder()
+		subject.ServeHTTP(rsp, req)
+		return rsp
+	}
+
+	t.Run("too many failed logins for the same username are rejected until the backoff has passed", func(t *testing.T) {
+		now := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+		config := testLoginLimiterConfig()
+		config.UsernameFailures = 2
+		subject, authenticateCalls := newCountingSubject(
+			loginlimiter.New(fake.NewSimpleClientset().CoreV1().Secrets("some-namespace"), func() time.Time { return now }, config),
+		)
+
+		require.Equal(t, http.StatusOK, post(subject, happyLDAPUsername, "wrong-password").Code)
+		require.Equal(t, http.StatusOK, post(subject, happyLDAPUsername, "wrong-password").Code)
+		require.Equal(t, 2, *authenticateCalls)
+
+		// Now even the correct password is rejected without asking the upstream.
+		rsp := post(subject, happyLDAPUsername, happyLDAPPassword)
+		require.Equal(t, http.StatusTooManyRequests, rsp.Code)
+		require.Equal(t, "60", rsp.Header().Get("Retry-After"))
+		require.Contains(t, rsp.Body.String(), "Too many failed login attempts. Please wait a few minutes and try again.")
+		require.Equal(t, 2, *authenticateCalls)
+
+		// Other usernames are not affected.
+		require.Equal(t, http.StatusOK, post(subject, "other-user", "wrong-password").Code)
+		require.Equal(t, 3, *authenticateCalls)
+
+		// After the backoff passes, the user may try again.
+		now = now.Add(time.Minute)
+		require.Equal(t, http.StatusOK, post(subject, happyLDAPUsername, "wrong-password").Code)
+		require.Equal(t, 4, *authenticateCalls)
+	})
+
+	t.Run("logins are rejected when the failed logins cannot be checked", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("get", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("some get error")
+		})
+		subject, authenticateCalls := newCountingSubject(
+			loginlimiter.New(client.CoreV1().Secrets("some-namespace"), time.Now, testLoginLimiterConfig()),
+		)
+
+		rsp := post(subject, happyLDAPUsername, happyLDAPPassword)
+		require.Equal(t, http.StatusServiceUnavailable, rsp.Code)
+		require.Contains(t, rsp.Body.String(), "An error occurred while checking your username and password. Please try again later.")
+		require.Equal(t, 0, *authenticateCalls)
+	})
+}
+
+func testLoginLimiterConfig() loginlimiter.Config {
+	return loginlimiter.Config{
+		UsernameFailures: 5,
+		SourceIPFailures: 50,
+		InitialBackoff:   time.Minute,
+		MaxBackoff:       time.Minute,
+		ResetAfter:       5 * time.Minute,
+	}
+}
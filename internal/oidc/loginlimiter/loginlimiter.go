@@ -0,0 +1,312 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package loginlimiter slows down password guessing against upstream identity providers. It counts the failed
+// password logins for each username and for each source IP address, and rejects further attempts with an
+// exponentially growing backoff once there have been too many of them. Each attempt is counted as failed before the
+// upstream identity provider is asked, and taken back when it succeeds.
+//
+// The counts are kept in Secrets, so that all Supervisor pods share them. The Secrets are deleted by the
+// Supervisor's garbage collector after the failures have been forgotten.
+package loginlimiter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/httputil/sourceip"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	TypeLabelValue = "failed-logins"
+
+	// LimitUsername and LimitSourceIP name the limit which rejected an attempt.
+	LimitUsername = "username"
+	LimitSourceIP = "source_ip"
+)
+
+// Config configures a Limiter.
+type Config struct {
+	// UsernameFailures is how many failed attempts for a username of an upstream identity provider are allowed
+	// before further attempts for that username are rejected. Zero turns off this limit.
+	UsernameFailures int
+	// SourceIPFailures is how many failed attempts from a source IP address are allowed before further attempts
+	// from that address are rejected. Zero turns off this limit.
+	SourceIPFailures int
+	// InitialBackoff is how long attempts are rejected after the limit is first reached. It doubles with each
+	// further failure, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// ResetAfter is how long after the most recent failure all failures are forgotten. It must not be shorter
+	// than MaxBackoff.
+	ResetAfter time.Duration
+}
+
+// Attempt describes a password login attempt.
+type Attempt struct {
+	UpstreamName string
+	Username     string
+	SourceIP     string
+}
+
+// NewAttempt describes a password login attempt for the username at the upstream identity provider, made by the
+// client of the request.
+func NewAttempt(r *http.Request, upstreamName, username string) Attempt {
+	return Attempt{UpstreamName: upstreamName, Username: username, SourceIP: sourceip.FromRequest(r)}
+}
+
+// LimitedError is returned by Limiter.Check when an attempt is rejected.
+type LimitedError struct {
+	// Limit is either LimitUsername or LimitSourceIP.
+	Limit string
+	// RetryAfter is how long until attempts will be allowed again.
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts for this %s, retry after %s", strings.ReplaceAll(e.Limit, "_", " "), e.RetryAfter)
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, e.g. for a Retry-After header.
+func (e *LimitedError) RetryAfterSeconds() int64 {
+	return int64((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// Limiter decides whether password login attempts are allowed.
+type Limiter struct {
+	storage crud.Storage
+	clock   func() time.Time
+	config  Config
+}
+
+// failures is the data which is stored for each username and source IP address.
+type failures struct {
+	Count       int       `json:"count"`
+	LastFailure time.Time `json:"lastFailure"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// ipv6PrefixMask masks the part of an IPv6 address which identifies the network of a client.
+//nolint: gochecknoglobals
+var ipv6PrefixMask = net.CIDRMask(64, 128)
+
+type limitKey struct {
+	limit       string
+	key         string
+	maxFailures int
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time, config Config) *Limiter {
+	// Keep the Secrets until the failures would have been forgotten anyway, which is never before any backoff ended.
+	return &Limiter{storage: crud.New(TypeLabelValue, secrets, clock, config.ResetAfter), clock: clock, config: config}
+}
+
+// Begin counts the attempt as failed for its username and its source IP address before the upstream identity
+// provider is asked, so that parallel attempts cannot get around the limits. It returns a *LimitedError without
+// counting the attempt when the attempt must be rejected without asking the upstream identity provider. Any other
+// error means that it could not be decided whether the attempt is allowed.
+//
+// Once the upstream identity provider has answered, exactly one of RecordFailure, RecordSuccess or RecordError must be
+// called for an attempt which was allowed.
+func (l *Limiter) Begin(ctx context.Context, attempt Attempt) error {
+	keys := l.keys(attempt)
+	for i, k := range keys {
+		if err := l.count(ctx, attempt, k); err != nil {
+			// An attempt which is not allowed by one limit does not count against the others.
+			l.uncountAll(ctx, attempt, keys[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordFailure records that the upstream identity provider did not accept an attempt. Begin already counted it.
+func (l *Limiter) RecordFailure(attempt Attempt) {
+	loginFailures.WithLabelValues(attempt.UpstreamName).Inc()
+}
+
+// RecordSuccess forgets the failed attempts for the username of a successful attempt, and stops counting the attempt
+// for its source IP address. The earlier failed attempts from its source IP address are not forgotten, so that one
+// known password does not help to guess the passwords of others.
+func (l *Limiter) RecordSuccess(ctx context.Context, attempt Attempt) error {
+	if l.config.UsernameFailures > 0 {
+		err := l.storage.Delete(ctx, signature(l.usernameKey(attempt)))
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to forget failed login attempts: %w", err)
+		}
+	}
+	if l.config.SourceIPFailures > 0 {
+		if err := l.uncount(ctx, l.sourceIPKey(attempt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordError stops counting an attempt which the upstream identity provider could not decide, e.g. because it
+// could not be reached.
+func (l *Limiter) RecordError(ctx context.Context, attempt Attempt) {
+	l.uncountAll(ctx, attempt, l.keys(attempt))
+}
+
+// count adds a failed attempt to the failures of the key, unless the key is locked.
+func (l *Limiter) count(ctx context.Context, attempt Attempt, k limitKey) error {
+	var limitedErr *LimitedError
+	err := retry.OnError(retry.DefaultRetry, isConflictOrAlreadyExists, func() error {
+		f, rv, err := l.get(ctx, k)
+		if err != nil {
+			return err
+		}
+
+		stored := f != nil
+		now := l.clock()
+		if stored && now.Before(f.LockedUntil) {
+			limitedErr = &LimitedError{Limit: k.limit, RetryAfter: f.LockedUntil.Sub(now)}
+			return nil
+		}
+		if !stored || now.Sub(f.LastFailure) >= l.config.ResetAfter {
+			f = &failures{}
+		}
+		f.Count++
+		f.LastFailure = now
+		if f.Count >= k.maxFailures {
+			f.LockedUntil = now.Add(l.backoff(f.Count - k.maxFailures))
+			plog.Info("too many failed password login attempts",
+				"upstreamName", attempt.UpstreamName,
+				"sourceIP", attempt.SourceIP,
+				"limit", k.limit,
+				"failures", f.Count,
+				"lockedUntil", f.LockedUntil,
+			)
+		}
+
+		// Both are conflict-checked, so parallel attempts cannot overwrite each other's counts.
+		if !stored {
+			_, err = l.storage.Create(ctx, signature(k), f, nil)
+		} else {
+			_, err = l.storage.Update(ctx, signature(k), rv, f)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count login attempt: %w", err)
+	}
+	if limitedErr != nil {
+		loginsRateLimited.WithLabelValues(k.limit).Inc()
+		auditlog.Record(ctx, auditlog.Event{
+			Type:         auditlog.LoginRateLimited,
+			SourceIP:     attempt.SourceIP,
+			UpstreamName: attempt.UpstreamName,
+			Username:     attempt.Username,
+			Reason:       k.limit,
+		})
+		return limitedErr
+	}
+	return nil
+}
+
+// uncount takes back an attempt which count added to the failures of the key.
+func (l *Limiter) uncount(ctx context.Context, k limitKey) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		f, rv, err := l.get(ctx, k)
+		if err != nil || f == nil || f.Count == 0 {
+			return err
+		}
+		f.Count--
+		if f.Count < k.maxFailures {
+			f.LockedUntil = time.Time{}
+		}
+		_, err = l.storage.Update(ctx, signature(k), rv, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to uncount login attempt: %w", err)
+	}
+	return nil
+}
+
+func (l *Limiter) uncountAll(ctx context.Context, attempt Attempt, keys []limitKey) {
+	for _, k := range keys {
+		if err := l.uncount(ctx, k); err != nil {
+			plog.WarningErr("error uncounting login attempt", err, "upstreamName", attempt.UpstreamName, "limit", k.limit)
+		}
+	}
+}
+
+// backoff returns InitialBackoff doubled n times, but not more than MaxBackoff.
+func (l *Limiter) backoff(n int) time.Duration {
+	backoff := l.config.InitialBackoff
+	for i := 0; i < n && backoff < l.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > l.config.MaxBackoff {
+		return l.config.MaxBackoff
+	}
+	return backoff
+}
+
+func (l *Limiter) keys(attempt Attempt) []limitKey {
+	keys := make([]limitKey, 0, 2)
+	if l.config.UsernameFailures > 0 {
+		keys = append(keys, l.usernameKey(attempt))
+	}
+	if l.config.SourceIPFailures > 0 {
+		keys = append(keys, l.sourceIPKey(attempt))
+	}
+	return keys
+}
+
+func (l *Limiter) sourceIPKey(attempt Attempt) limitKey {
+	// IPv6 clients usually get a whole /64 network, so they must not get more attempts by changing their address
+	// within it.
+	key := attempt.SourceIP
+	if ip := net.ParseIP(attempt.SourceIP); ip != nil && ip.To4() == nil {
+		key = (&net.IPNet{IP: ip.Mask(ipv6PrefixMask), Mask: ipv6PrefixMask}).String()
+	}
+	return limitKey{limit: LimitSourceIP, key: key, maxFailures: l.config.SourceIPFailures}
+}
+
+func (l *Limiter) usernameKey(attempt Attempt) limitKey {
+	// Directories usually ignore the case of usernames, so an attacker must not get more attempts by changing it.
+	return limitKey{
+		limit:       LimitUsername,
+		key:         attempt.UpstreamName + "/" + strings.ToLower(attempt.Username),
+		maxFailures: l.config.UsernameFailures,
+	}
+}
+
+// get returns the stored failures and their resource version, or nil when none are stored.
+func (l *Limiter) get(ctx context.Context, k limitKey) (*failures, string, error) {
+	f := &failures{}
+	rv, err := l.storage.Get(ctx, signature(k), f)
+	if k8serrors.IsNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get failed login attempts: %w", err)
+	}
+	return f, rv, nil
+}
+
+// signature hashes the key, so that the names of the Secrets do not reveal usernames or addresses.
+func signature(k limitKey) string {
+	sum := sha256.Sum256([]byte(k.limit + "\x00" + k.key))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func isConflictOrAlreadyExists(err error) bool {
+	return k8serrors.IsConflict(err) || k8serrors.IsAlreadyExists(err)
+}
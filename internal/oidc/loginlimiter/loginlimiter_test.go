@@ -0,0 +1,252 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package loginlimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+const namespace = "test-ns"
+
+func testConfig() Config {
+	return Config{
+		UsernameFailures: 3,
+		SourceIPFailures: 5,
+		InitialBackoff:   30 * time.Second,
+		MaxBackoff:       2 * time.Minute,
+		ResetAfter:       10 * time.Minute,
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	alice := Attempt{UpstreamName: "some-ldap", Username: "alice", SourceIP: "10.0.0.1"}
+	aliceElsewhere := Attempt{UpstreamName: "some-ldap", Username: "alice", SourceIP: "10.0.0.2"}
+	aliceShouting := Attempt{UpstreamName: "some-ldap", Username: "ALICE", SourceIP: "10.0.0.2"}
+	aliceOtherUpstream := Attempt{UpstreamName: "other-ldap", Username: "alice", SourceIP: "10.0.0.2"}
+
+	requireLimited := func(t *testing.T, err error, wantLimit string, wantRetryAfter time.Duration) {
+		t.Helper()
+		var limitedErr *LimitedError
+		require.True(t, errors.As(err, &limitedErr), "wanted a *LimitedError but got %v", err)
+		require.Equal(t, wantLimit, limitedErr.Limit)
+		require.Equal(t, wantRetryAfter, limitedErr.RetryAfter)
+	}
+
+	// fail is a password login attempt which the upstream identity provider does not accept.
+	fail := func(t *testing.T, subject *Limiter, attempt Attempt) {
+		t.Helper()
+		require.NoError(t, subject.Begin(ctx, attempt))
+		subject.RecordFailure(attempt)
+	}
+
+	t.Run("username limit with exponential backoff", func(t *testing.T) {
+		now := start
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, testConfig())
+
+		for i := 0; i < 3; i++ {
+			fail(t, subject, alice)
+		}
+
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, 30*time.Second)
+		// The username is limited from everywhere, regardless of its case, but only for its upstream.
+		requireLimited(t, subject.Begin(ctx, aliceElsewhere), LimitUsername, 30*time.Second)
+		requireLimited(t, subject.Begin(ctx, aliceShouting), LimitUsername, 30*time.Second)
+		fail(t, subject, aliceOtherUpstream)
+
+		now = now.Add(10 * time.Second)
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, 20*time.Second)
+
+		now = now.Add(20 * time.Second)
+		fail(t, subject, alice)
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, time.Minute)
+
+		now = now.Add(time.Minute)
+		fail(t, subject, alice)
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, 2*time.Minute)
+
+		now = now.Add(2 * time.Minute)
+		fail(t, subject, alice)
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, 2*time.Minute)
+
+		// All failures are forgotten a while after the last one.
+		now = now.Add(10 * time.Minute)
+		fail(t, subject, alice)
+		require.NoError(t, subject.Begin(ctx, alice))
+	})
+
+	t.Run("parallel attempts are counted before the upstream is asked", func(t *testing.T) {
+		now := start
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, testConfig())
+
+		// None of the upstream identity providers has answered yet, but only as many attempts as the limit allows
+		// may ask them.
+		for i := 0; i < 3; i++ {
+			require.NoError(t, subject.Begin(ctx, alice))
+		}
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, 30*time.Second)
+	})
+
+	t.Run("conflicting updates of a count are retried", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		conflicts := 0
+		client.PrependReactor("update", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+			if conflicts < 2 {
+				conflicts++
+				return true, nil, k8serrors.NewConflict(schema.GroupResource{Resource: "secrets"}, "some-secret", errors.New("some conflict"))
+			}
+			return false, nil, nil
+		})
+		subject := New(client.CoreV1().Secrets(namespace), func() time.Time { return start }, testConfig())
+
+		for i := 0; i < 3; i++ {
+			fail(t, subject, alice)
+		}
+		require.Equal(t, 2, conflicts)
+		requireLimited(t, subject.Begin(ctx, alice), LimitUsername, 30*time.Second)
+	})
+
+	t.Run("source IP limit across usernames", func(t *testing.T) {
+		now := start
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, testConfig())
+
+		for i := 0; i < 5; i++ {
+			fail(t, subject, Attempt{UpstreamName: "some-ldap", Username: "user" + string(rune('a'+i)), SourceIP: "10.0.0.1"})
+		}
+
+		requireLimited(t, subject.Begin(ctx, alice), LimitSourceIP, 30*time.Second)
+		require.NoError(t, subject.Begin(ctx, aliceElsewhere))
+	})
+
+	t.Run("source IP limit across the /64 network of an IPv6 address", func(t *testing.T) {
+		now := start
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, testConfig())
+
+		for i := 0; i < 5; i++ {
+			fail(t, subject, Attempt{UpstreamName: "some-ldap", Username: "user" + string(rune('a'+i)), SourceIP: fmt.Sprintf("2001:db8:1:2::%d", i+1)})
+		}
+
+		requireLimited(t, subject.Begin(ctx, Attempt{UpstreamName: "some-ldap", Username: "bob", SourceIP: "2001:db8:1:2:ffff:ffff:ffff:ffff"}), LimitSourceIP, 30*time.Second)
+		require.NoError(t, subject.Begin(ctx, Attempt{UpstreamName: "some-ldap", Username: "bob", SourceIP: "2001:db8:1:3::1"}))
+	})
+
+	t.Run("an attempt which is rejected by one limit does not count against the others", func(t *testing.T) {
+		now := start
+		config := testConfig()
+		config.SourceIPFailures = 1
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, config)
+
+		fail(t, subject, aliceOtherUpstream)
+		for i := 0; i < 5; i++ {
+			requireLimited(t, subject.Begin(ctx, aliceElsewhere), LimitSourceIP, 30*time.Second)
+		}
+
+		// The username was not counted, so it is allowed from elsewhere.
+		require.NoError(t, subject.Begin(ctx, alice))
+	})
+
+	t.Run("success forgets the failures of the username but not of the source IP", func(t *testing.T) {
+		now := start
+		config := testConfig()
+		config.SourceIPFailures = 3
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, config)
+
+		fail(t, subject, alice)
+		fail(t, subject, alice)
+		// The successful attempt itself does not count against the source IP.
+		require.NoError(t, subject.Begin(ctx, alice))
+		require.NoError(t, subject.RecordSuccess(ctx, alice))
+		fail(t, subject, alice)
+		require.NoError(t, subject.Begin(ctx, aliceElsewhere))
+		requireLimited(t, subject.Begin(ctx, alice), LimitSourceIP, 30*time.Second)
+
+		// Forgetting a username without failures is fine.
+		require.NoError(t, subject.RecordSuccess(ctx, aliceOtherUpstream))
+	})
+
+	t.Run("attempts which the upstream could not decide are not counted", func(t *testing.T) {
+		now := start
+		subject := New(fake.NewSimpleClientset().CoreV1().Secrets(namespace), func() time.Time { return now }, testConfig())
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, subject.Begin(ctx, alice))
+			subject.RecordError(ctx, alice)
+		}
+		fail(t, subject, alice)
+		fail(t, subject, alice)
+		require.NoError(t, subject.Begin(ctx, alice))
+	})
+
+	t.Run("limits can be turned off", func(t *testing.T) {
+		now := start
+		client := fake.NewSimpleClientset()
+		subject := New(client.CoreV1().Secrets(namespace), func() time.Time { return now }, Config{})
+
+		for i := 0; i < 10; i++ {
+			fail(t, subject, alice)
+		}
+		require.NoError(t, subject.Begin(ctx, alice))
+		require.NoError(t, subject.RecordSuccess(ctx, alice))
+		subject.RecordError(ctx, alice)
+		require.Empty(t, client.Actions())
+	})
+
+	t.Run("failures are stored in Secrets which are garbage collected", func(t *testing.T) {
+		now := start
+		client := fake.NewSimpleClientset()
+		subject := New(client.CoreV1().Secrets(namespace), func() time.Time { return now }, testConfig())
+
+		fail(t, subject, alice)
+
+		secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, secrets.Items, 2)
+		for _, secret := range secrets.Items {
+			require.True(t, strings.HasPrefix(secret.Name, "pinniped-storage-failed-logins-"))
+			require.NotContains(t, secret.Name, "alice")
+			require.Equal(t, "storage.pinniped.dev/failed-logins", string(secret.Type))
+			require.Equal(t, "failed-logins", secret.Labels["storage.pinniped.dev/type"])
+			require.Equal(t, "2030-01-01T00:10:00Z", secret.Annotations["storage.pinniped.dev/garbage-collect-after"])
+			require.NotContains(t, string(secret.Data["pinniped-storage-data"]), "alice")
+		}
+	})
+
+	t.Run("storage errors are returned", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("get", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("some get error")
+		})
+		subject := New(client.CoreV1().Secrets(namespace), func() time.Time { return start }, testConfig())
+
+		err := subject.Begin(ctx, alice)
+		require.EqualError(t, err, "failed to count login attempt: failed to get failed login attempts: failed to get failed-logins for signature "+
+			signature(subject.usernameKey(alice))+": some get error")
+		var limitedErr *LimitedError
+		require.False(t, errors.As(err, &limitedErr))
+
+		require.EqualError(t, subject.RecordSuccess(ctx, alice), "failed to uncount login attempt: failed to get failed login attempts: failed to get failed-logins for signature "+
+			signature(subject.sourceIPKey(alice))+": some get error")
+	})
+}
+
+func TestLimitedError(t *testing.T) {
+	err := &LimitedError{Limit: LimitSourceIP, RetryAfter: 1500 * time.Millisecond}
+	require.EqualError(t, err, "too many failed login attempts for this source ip, retry after 1.5s")
+	require.Equal(t, int64(2), err.RetryAfterSeconds())
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package loginlimiter
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var (
+	loginFailures = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "password_login_failures_total",
+			Help:           "Number of password logins which were not accepted by the upstream identity provider.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"upstream"},
+	)
+
+	loginsRateLimited = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "password_logins_rate_limited_total",
+			Help:           "Number of password logins which were rejected after too many failed attempts, by the limit which rejected them.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"limit"},
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(loginFailures, loginsRateLimited)
+}
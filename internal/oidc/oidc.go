@@ -5,16 +5,21 @@
 package oidc
 
 import (
+	"html/template"
 	"time"
 
 	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/compose"
 
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
 	"go.pinniped.dev/internal/oidc/csrftoken"
 	"go.pinniped.dev/internal/oidc/jwks"
 	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/oidc/provider/formposthtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
 	"go.pinniped.dev/pkg/oidcclient/pkce"
 )
@@ -24,8 +29,12 @@ const (
 	AuthorizationEndpointPath = "/oauth2/authorize"
 	TokenEndpointPath         = "/oauth2/token" //nolint:gosec // ignore lint warning that this is a credential
 	CallbackEndpointPath      = "/callback"
+	LoginEndpointPath         = "/login"
+	ChooseIDPEndpointPath     = "/choose_identity_provider"
 	JWKSEndpointPath          = "/jwks.json"
 	PinnipedIDPsPathV1Alpha1  = "/v1alpha1/pinniped_identity_providers"
+
+	PushedAuthorizationRequestEndpointPath = "/oauth2/par"
 )
 
 const (
@@ -38,6 +47,19 @@ const (
 	// because it will be encoded into the upstream state param value and we're trying to keep that small.
 	UpstreamStateParamEncodingName = "s"
 
+	// The `name` passed to the encoder for encoding the state param of the identity provider chooser page.
+	ChooseIDPStateParamEncodingName = "i"
+
+	// AuthorizeUpstreamIDPNameParamName and AuthorizeUpstreamIDPTypeParamName are the custom authorize request params
+	// which clients may use to choose an upstream identity provider by name, and optionally by type.
+	AuthorizeUpstreamIDPNameParamName = "pinniped_idp_name"
+	AuthorizeUpstreamIDPTypeParamName = "pinniped_idp_type"
+
+	// AuthorizeChooseIDPStateParamName is the custom authorize request param with which the links of the identity
+	// provider chooser page continue the original authorize request. It carries the encrypted chooser state param
+	// instead of the original params, which may have come from a pushed authorization request.
+	AuthorizeChooseIDPStateParamName = "pinniped_choose_idp_state"
+
 	// CSRFCookieName is the name of the browser cookie which shall hold our CSRF value.
 	// The `__Host` prefix has a special meaning. See:
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Cookies#Cookie_prefixes.
@@ -66,6 +88,14 @@ const (
 	// Supervisor's authorization endpoint should give the browser a new CSRF cookie. We set it to
 	// a week so that it is unlikely to expire during a login.
 	CSRFCookieLifespan = time.Hour * 24 * 7
+
+	// PushedAuthorizationRequestURIPrefix is the prefix of the request_uri values which the pushed authorization
+	// request endpoint returns, as defined in RFC 9126. The rest of the value refers to the stored request.
+	PushedAuthorizationRequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+	// UpstreamStateParamLifespan is the length of time that the state param which the Supervisor sends to an
+	// upstream identity provider is valid, i.e. how long a user has to log in to the upstream identity provider.
+	UpstreamStateParamLifespan = 90 * time.Minute
 )
 
 // Encoder is the encoding side of the securecookie.Codec interface.
@@ -86,19 +116,28 @@ type Codec interface {
 }
 
 // UpstreamStateParamData is the format of the state parameter that we use when we communicate to an
-// upstream OIDC provider.
+// upstream OIDC provider, or when we send the browser to the Supervisor's own login page for an upstream
+// LDAP provider.
 //
 // Keep the JSON to a minimal size because the upstream provider could impose size limitations on
 // the state param.
 type UpstreamStateParamData struct {
 	AuthParams    string              `json:"p"`
 	UpstreamName  string              `json:"u"`
+	UpstreamType  string              `json:"t"`
 	Nonce         nonce.Nonce         `json:"n"`
 	CSRFToken     csrftoken.CSRFToken `json:"c"`
 	PKCECode      pkce.Code           `json:"k"`
 	FormatVersion string              `json:"v"`
 }
 
+// ChooseIDPStateParamData is the format of the state parameter that the authorize endpoint passes to the
+// identity provider chooser page, so the original authorize request can continue after the end user has chosen.
+type ChooseIDPStateParamData struct {
+	AuthParams    string `json:"p"`
+	FormatVersion string `json:"v"`
+}
+
 type TimeoutsConfiguration struct {
 	// The length of time that our state param that we encrypt and pass to the upstream OIDC IDP should be considered
 	// valid. If a state param generated by the authorize endpoint is sent to the callback endpoint after this much
@@ -108,6 +147,10 @@ type TimeoutsConfiguration struct {
 	// the browser is sitting at the upstream IDP's login page.
 	UpstreamStateParamLifespan time.Duration
 
+	// How long a request_uri issued by the pushed authorization request endpoint is valid. The client is expected
+	// to send the end user to the authorization endpoint right after pushing the request, so this can be short.
+	PushedAuthorizationRequestLifespan time.Duration
+
 	// How long an authcode issued by the callback endpoint is valid. This determines how much time the end user
 	// has to come back to exchange the authcode for tokens at the token endpoint.
 	AuthorizeCodeLifespan time.Duration
@@ -128,12 +171,23 @@ type TimeoutsConfiguration struct {
 	// in their web browser.
 	RefreshTokenLifespan time.Duration
 
+	// The maximum length of a downstream session, starting from when the user logged in with the upstream IDP.
+	// Each refresh issues a new refresh token, so without this limit a session could be refreshed forever.
+	// Refresh tokens never outlive the end of their session.
+	AbsoluteSessionLifespan time.Duration
+
+	// PushedAuthorizationRequestStorageLifetime is the length of time after which a pushed authorization request is
+	// allowed to be garbage collected from storage. The authorization endpoint deletes each pushed authorization
+	// request when it is used, and rejects expired ones by itself, so this only needs to be slightly longer than the
+	// PushedAuthorizationRequestLifespan to clean up the requests which were never used.
+	PushedAuthorizationRequestStorageLifetime time.Duration
+
 	// AuthorizationCodeSessionStorageLifetime is the length of time after which an authcode is allowed to be garbage
 	// collected from storage. Authcodes are kept in storage after they are redeemed to allow the system to mark the
 	// authcode as already used, so it can reject any future uses of the same authcode with special case handling which
 	// include revoking the access and refresh tokens associated with the session. Therefore, this should be
 	// significantly longer than the AuthorizeCodeLifespan, and there is probably no reason to make it longer than
-	// the sum of the AuthorizeCodeLifespan and the RefreshTokenLifespan.
+	// the sum of the AuthorizeCodeLifespan and the AbsoluteSessionLifespan.
 	AuthorizationCodeSessionStorageLifetime time.Duration
 
 	// PKCESessionStorageLifetime is the length of time after which PKCE data is allowed to be garbage collected from
@@ -167,31 +221,60 @@ type TimeoutsConfiguration struct {
 	RefreshTokenSessionStorageLifetime time.Duration
 }
 
+const (
+	defaultAccessTokenLifespan     = 2 * time.Minute
+	defaultIDTokenLifespan         = defaultAccessTokenLifespan
+	defaultRefreshTokenLifespan    = 9 * time.Hour
+	defaultAbsoluteSessionLifespan = 9 * time.Hour
+)
+
 // Get the defaults for the Supervisor server.
 func DefaultOIDCTimeoutsConfiguration() TimeoutsConfiguration {
-	accessTokenLifespan := 2 * time.Minute
+	return OIDCTimeoutsConfiguration(provider.FederationDomainTokenLifetimes{})
+}
+
+// OIDCTimeoutsConfiguration returns the timeouts for a FederationDomain with the given token lifetimes.
+// Any lifetime which is zero uses the default.
+func OIDCTimeoutsConfiguration(tokenLifetimes provider.FederationDomainTokenLifetimes) TimeoutsConfiguration {
+	accessTokenLifespan := durationOrDefault(tokenLifetimes.AccessToken, defaultAccessTokenLifespan)
+	idTokenLifespan := durationOrDefault(tokenLifetimes.IDToken, defaultIDTokenLifespan)
+	refreshTokenLifespan := durationOrDefault(tokenLifetimes.RefreshToken, defaultRefreshTokenLifespan)
+	absoluteSessionLifespan := durationOrDefault(tokenLifetimes.AbsoluteSession, defaultAbsoluteSessionLifespan)
 	authorizationCodeLifespan := 10 * time.Minute
-	refreshTokenLifespan := 9 * time.Hour
+	pushedAuthorizationRequestLifespan := 1 * time.Minute
 
 	return TimeoutsConfiguration{
-		UpstreamStateParamLifespan:              90 * time.Minute,
-		AuthorizeCodeLifespan:                   authorizationCodeLifespan,
-		AccessTokenLifespan:                     accessTokenLifespan,
-		IDTokenLifespan:                         accessTokenLifespan,
-		RefreshTokenLifespan:                    refreshTokenLifespan,
-		AuthorizationCodeSessionStorageLifetime: authorizationCodeLifespan + refreshTokenLifespan,
-		PKCESessionStorageLifetime:              authorizationCodeLifespan + (1 * time.Minute),
-		OIDCSessionStorageLifetime:              authorizationCodeLifespan + (1 * time.Minute),
-		AccessTokenSessionStorageLifetime:       refreshTokenLifespan + accessTokenLifespan,
-		RefreshTokenSessionStorageLifetime:      refreshTokenLifespan + accessTokenLifespan,
+		UpstreamStateParamLifespan:                UpstreamStateParamLifespan,
+		PushedAuthorizationRequestLifespan:        pushedAuthorizationRequestLifespan,
+		AuthorizeCodeLifespan:                     authorizationCodeLifespan,
+		AccessTokenLifespan:                       accessTokenLifespan,
+		IDTokenLifespan:                           idTokenLifespan,
+		RefreshTokenLifespan:                      refreshTokenLifespan,
+		AbsoluteSessionLifespan:                   absoluteSessionLifespan,
+		PushedAuthorizationRequestStorageLifetime: pushedAuthorizationRequestLifespan + (1 * time.Minute),
+		AuthorizationCodeSessionStorageLifetime:   authorizationCodeLifespan + absoluteSessionLifespan,
+		PKCESessionStorageLifetime:                authorizationCodeLifespan + (1 * time.Minute),
+		OIDCSessionStorageLifetime:                authorizationCodeLifespan + (1 * time.Minute),
+		AccessTokenSessionStorageLifetime:         refreshTokenLifespan + accessTokenLifespan,
+		RefreshTokenSessionStorageLifetime:        refreshTokenLifespan + accessTokenLifespan,
 	}
 }
 
+func durationOrDefault(d time.Duration, defaultDuration time.Duration) time.Duration {
+	if d == 0 {
+		return defaultDuration
+	}
+	return d
+}
+
 func FositeOauth2Helper(
 	oauthStore interface{},
 	issuer string,
 	hmacSecretOfLengthAtLeast32Func func() []byte,
 	jwksProvider jwks.DynamicJWKSProvider,
+	clusters clusteraudience.DynamicClusterAudienceProvider,
+	trustedClusters trustedcluster.DynamicTrustedClusterProvider,
+	machineClients clientregistry.DynamicMachineClientProvider,
 	timeoutsConfiguration TimeoutsConfiguration,
 ) fosite.OAuth2Provider {
 	oauthConfig := &compose.Config{
@@ -232,10 +315,29 @@ func FositeOauth2Helper(
 		compose.OpenIDConnectExplicitFactory,
 		compose.OpenIDConnectRefreshFactory,
 		compose.OAuth2PKCEFactory,
-		TokenExchangeFactory,
+		TokenExchangeFactory(clusters, trustedClusters, machineClients),
+		ClientCredentialsFactory(machineClients),
 	)
-	provider.(*fosite.Fosite).FormPostHTMLTemplate = formposthtml.Template()
-	return provider
+	provider.(*fosite.Fosite).FormPostHTMLTemplate = formposthtml.Template(nil, locale.Default())
+	return &tracedOAuth2Provider{OAuth2Provider: provider}
+}
+
+// WithFormPostHTMLTemplate returns a copy of an OAuth2Provider from FositeOauth2Helper which renders the
+// response_mode=form_post page with the given template, e.g. one with the branding of the FederationDomain and in the
+// language of the request. Any other OAuth2Provider, e.g. a mock in a unit test, is returned unchanged.
+func WithFormPostHTMLTemplate(oauthHelper fosite.OAuth2Provider, t *template.Template) fosite.OAuth2Provider {
+	traced, ok := oauthHelper.(*tracedOAuth2Provider)
+	if !ok {
+		return oauthHelper
+	}
+	f, ok := traced.OAuth2Provider.(*fosite.Fosite)
+	if !ok {
+		return oauthHelper
+	}
+	// The copy shares the handlers and the storage of the original, so it is cheap enough to make for each request.
+	withTemplate := *f
+	withTemplate.FormPostHTMLTemplate = t
+	return &tracedOAuth2Provider{OAuth2Provider: &withTemplate}
 }
 
 // FositeErrorForLog generates a list of information about the provided Fosite error that can be
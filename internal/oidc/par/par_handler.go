@@ -0,0 +1,151 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package par provides a handler for the pushed authorization request endpoint, as described in RFC 9126.
+package par
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/pkg/errors"
+
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/httputil/securityheader"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/requestobject"
+	"go.pinniped.dev/internal/plog"
+)
+
+// OAuthHelper is a fosite.OAuth2Provider which can also authenticate clients, such as *fosite.Fosite.
+type OAuthHelper interface {
+	fosite.OAuth2Provider
+	AuthenticateClient(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error)
+}
+
+type response struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int64  `json:"expires_in"`
+}
+
+func NewHandler(
+	downstreamIssuer string,
+	oauthHelper OAuthHelper,
+	storage pushedauthorizationrequest.Storage,
+	lifespan time.Duration,
+	generateReference func() (string, error),
+) http.Handler {
+	return securityheader.Wrap(httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try POST)", r.Method)
+		}
+
+		if err := r.ParseForm(); err != nil {
+			writeError(w, oauthHelper, errors.WithStack(fosite.ErrInvalidRequest.WithHint(
+				"Unable to parse the HTTP body, make sure to send a properly formatted form request body.").WithWrap(err).WithDebug(err.Error())))
+			return nil
+		}
+
+		client, err := oauthHelper.AuthenticateClient(r.Context(), r, r.PostForm)
+		if err != nil {
+			writeError(w, oauthHelper, err)
+			return nil
+		}
+
+		params, err := pushedParams(client, downstreamIssuer, r.PostForm, time.Now())
+		if err != nil {
+			writeError(w, oauthHelper, err)
+			return nil
+		}
+
+		// Check the request like the authorization endpoint will, so the client finds out about an invalid request
+		// before it sends the end user to the authorization endpoint.
+		authorizeRequest, err := http.NewRequestWithContext(r.Context(), http.MethodGet,
+			downstreamIssuer+oidc.AuthorizationEndpointPath+"?"+params.Encode(), nil)
+		if err != nil {
+			return httperr.Wrap(http.StatusInternalServerError, "error checking pushed authorization request", err)
+		}
+		if _, err := oauthHelper.NewAuthorizeRequest(r.Context(), authorizeRequest); err != nil {
+			writeError(w, oauthHelper, err)
+			return nil
+		}
+
+		reference, err := generateReference()
+		if err != nil {
+			plog.Error("pushed authorization request generate error", err)
+			return httperr.Wrap(http.StatusInternalServerError, "error generating request_uri", err)
+		}
+
+		err = storage.CreatePushedAuthorizeRequest(r.Context(), reference, &pushedauthorizationrequest.Request{
+			ClientID:  client.GetID(),
+			Params:    params,
+			ExpiresAt: time.Now().UTC().Add(lifespan),
+		})
+		if err != nil {
+			plog.Error("pushed authorization request storage error", err)
+			return httperr.Wrap(http.StatusInternalServerError, "error storing pushed authorization request", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		return json.NewEncoder(w).Encode(&response{
+			RequestURI: oidc.PushedAuthorizationRequestURIPrefix + reference,
+			ExpiresIn:  int64(lifespan.Seconds()),
+		})
+	}))
+}
+
+// GenerateReference returns a random value which refers to a pushed authorization request in its request_uri.
+func GenerateReference() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", errors.WithMessage(err, "could not generate random reference")
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// pushedParams returns the authorization request params which the authenticated client pushed, either in the form
+// or in a request object.
+func pushedParams(client fosite.Client, downstreamIssuer string, form url.Values, now time.Time) (url.Values, error) {
+	if form.Get("request_uri") != "" {
+		// RFC 9126 section 2.1 does not allow a request_uri which refers to yet another request.
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithHint(
+			"The request_uri param must not be sent to the pushed authorization request endpoint."))
+	}
+
+	if clientID := form.Get("client_id"); clientID != "" && clientID != client.GetID() {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithHint(
+			"The client_id param does not match the authenticated client."))
+	}
+
+	if requestObject := form.Get("request"); requestObject != "" {
+		return requestobject.Params(client, downstreamIssuer, requestObject, now)
+	}
+
+	params := url.Values{}
+	for name, values := range form {
+		switch name {
+		case "client_secret", "client_assertion", "client_assertion_type":
+			// These authenticate the client to this endpoint and are not part of the authorization request.
+			continue
+		}
+		params[name] = values
+	}
+	params.Set("client_id", client.GetID())
+	return params, nil
+}
+
+func writeError(w http.ResponseWriter, oauthHelper fosite.OAuth2Provider, err error) {
+	plog.Info("pushed authorization request error", oidc.FositeErrorForLog(err)...)
+	// Like the token endpoint, this endpoint is called by the client itself, so the error is written as JSON.
+	oauthHelper.WriteAccessError(w, nil, err)
+}
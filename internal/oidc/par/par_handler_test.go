@@ -0,0 +1,235 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package par
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	downstreamIssuer = "https://my-downstream-issuer.com/path"
+	parPath          = "/downstream-provider-name/oauth2/par"
+	jsonContentType  = "application/json; charset=utf-8"
+)
+
+var (
+	happyDownstreamRequestParams = url.Values{
+		"response_type":         []string{"code"},
+		"scope":                 []string{"openid offline_access pinniped:request-audience"},
+		"client_id":             []string{"pinniped-cli"},
+		"state":                 []string{"8b-state"},
+		"nonce":                 []string{"some-nonce-value"},
+		"code_challenge":        []string{"some-challenge"},
+		"code_challenge_method": []string{"S256"},
+		"redirect_uri":          []string{"http://127.0.0.1/callback"},
+	}
+)
+
+func TestPushedAuthorizationRequestEndpoint(t *testing.T) {
+	hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+	timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+
+	modifiedHappyParams := func(overrides map[string]string) url.Values {
+		params := url.Values{}
+		for k, v := range happyDownstreamRequestParams {
+			params[k] = v
+		}
+		for k, v := range overrides {
+			if v == "" {
+				params.Del(k)
+			} else {
+				params.Set(k, v)
+			}
+		}
+		return params
+	}
+
+	happyReferenceGenerator := func() (string, error) { return "some-reference", nil }
+
+	tests := []struct {
+		name string
+
+		method            string
+		body              string
+		generateReference func() (string, error)
+		createError       error
+
+		wantStatus       int
+		wantContentType  string
+		wantBodyJSON     string
+		wantBodyString   string
+		wantStoredParams url.Values
+	}{
+		{
+			name:             "happy path for a public client",
+			method:           http.MethodPost,
+			body:             happyDownstreamRequestParams.Encode(),
+			wantStatus:       http.StatusCreated,
+			wantContentType:  "application/json; charset=utf-8",
+			wantBodyJSON:     `{"request_uri": "urn:ietf:params:oauth:request_uri:some-reference", "expires_in": 60}`,
+			wantStoredParams: happyDownstreamRequestParams,
+		},
+		{
+			name:            "GET is a bad method",
+			method:          http.MethodGet,
+			wantStatus:      http.StatusMethodNotAllowed,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Method Not Allowed: GET (try POST)\n",
+		},
+		{
+			name:            "client does not exist",
+			method:          http.MethodPost,
+			body:            modifiedHappyParams(map[string]string{"client_id": "invalid-client"}).Encode(),
+			wantStatus:      http.StatusUnauthorized,
+			wantContentType: jsonContentType,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_client",
+					"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method)."
+				}
+			`),
+		},
+		{
+			name:            "request_uri param is not allowed",
+			method:          http.MethodPost,
+			body:            modifiedHappyParams(map[string]string{"request_uri": "urn:ietf:params:oauth:request_uri:other"}).Encode(),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: jsonContentType,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The request_uri param must not be sent to the pushed authorization request endpoint."
+				}
+			`),
+		},
+		{
+			name:            "request object from a client without JSON Web Keys",
+			method:          http.MethodPost,
+			body:            url.Values{"client_id": []string{"pinniped-cli"}, "request": []string{"some.request.object"}}.Encode(),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: jsonContentType,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "request_not_supported",
+					"error_description": "The OP does not support use of the request parameter. The client has no JSON Web Keys registered, so it cannot use request objects."
+				}
+			`),
+		},
+		{
+			name:            "invalid authorization request",
+			method:          http.MethodPost,
+			body:            modifiedHappyParams(map[string]string{"redirect_uri": "http://127.0.0.1/wrong"}).Encode(),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: jsonContentType,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The 'redirect_uri' parameter does not match any of the OAuth 2.0 Client's pre-registered redirect urls."
+				}
+			`),
+		},
+		{
+			name:              "error while generating the reference",
+			method:            http.MethodPost,
+			body:              happyDownstreamRequestParams.Encode(),
+			generateReference: func() (string, error) { return "", errors.New("some generate error") },
+			wantStatus:        http.StatusInternalServerError,
+			wantContentType:   "text/plain; charset=utf-8",
+			wantBodyString:    "Internal Server Error: error generating request_uri\n",
+		},
+		{
+			name:            "error while storing the request",
+			method:          http.MethodPost,
+			body:            happyDownstreamRequestParams.Encode(),
+			createError:     errors.New("some create error"),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBodyString:  "Internal Server Error: error storing pushed authorization request\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			if test.createError != nil {
+				kubeClient.PrependReactor("create", "secrets", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, test.createError
+				})
+			}
+			kubeStorage := oidc.NewKubeStorage(kubeClient.CoreV1().Secrets("some-namespace"), nil, timeoutsConfiguration)
+			oauthHelper := oidc.FositeOauth2Helper(kubeStorage, downstreamIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), nil, nil, nil, timeoutsConfiguration)
+
+			generateReference := test.generateReference
+			if generateReference == nil {
+				generateReference = happyReferenceGenerator
+			}
+			subject := NewHandler(downstreamIssuer, oauthHelper.(OAuthHelper), kubeStorage, timeoutsConfiguration.PushedAuthorizationRequestLifespan, generateReference)
+
+			req := httptest.NewRequest(test.method, parPath, strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), test.wantContentType)
+			testutil.RequireSecurityHeaders(t, rsp)
+
+			if test.wantBodyJSON != "" {
+				require.JSONEq(t, test.wantBodyJSON, rsp.Body.String())
+			} else {
+				require.Equal(t, test.wantBodyString, rsp.Body.String())
+			}
+
+			if test.wantStoredParams == nil {
+				if test.createError == nil {
+					require.Empty(t, kubeClient.Actions())
+				}
+				return
+			}
+			stored, err := kubeStorage.GetPushedAuthorizeRequest(context.Background(), "some-reference")
+			require.NoError(t, err)
+			require.Equal(t, "pinniped-cli", stored.ClientID)
+			require.Equal(t, test.wantStoredParams, stored.Params)
+			testutil.RequireTimeInDelta(t, time.Now().Add(time.Minute), stored.ExpiresAt, 10*time.Second)
+		})
+	}
+}
+
+func TestPushedParams(t *testing.T) {
+	client := clientregistry.PinnipedCLI()
+	now := time.Now()
+
+	params, err := pushedParams(client, downstreamIssuer, url.Values{
+		"scope":         []string{"openid"},
+		"client_secret": []string{"some-secret"},
+	}, now)
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"scope": []string{"openid"}, "client_id": []string{"pinniped-cli"}}, params)
+
+	_, err = pushedParams(client, downstreamIssuer, url.Values{"client_id": []string{"other-client"}}, now)
+	require.EqualError(t, err, "invalid_request")
+	require.Equal(t, "The client_id param does not match the authenticated client.", fosite.ErrorToRFC6749Error(err).HintField)
+}
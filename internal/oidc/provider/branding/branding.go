@@ -0,0 +1,238 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package branding customizes the look of the Supervisor's HTML pages for each FederationDomain, e.g. with a
+// company's logo and colors, and provides the template functions which the pages share.
+//nolint: gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
+package branding
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed" // Needed to trigger //go:embed directives below.
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tdewolff/minify/v2/minify"
+	corev1 "k8s.io/api/core/v1"
+
+	"go.pinniped.dev/internal/oidc/provider/locale"
+)
+
+// The keys of a branding ConfigMap, as documented on the FederationDomainBrandingSpec API type.
+const (
+	ProductNameKey     = "productName"
+	LogoKey            = "logo"
+	PrimaryColorKey    = "primaryColor"
+	BackgroundColorKey = "backgroundColor"
+	TextColorKey       = "textColor"
+	SupportURLKey      = "supportURL"
+
+	defaultProductName = "Pinniped"
+	maxProductNameLen  = 64
+	maxLogoSize        = 256 * 1024
+)
+
+var (
+	//go:embed branding.css
+	rawCSS      string
+	minifiedCSS = MustMinify(minify.CSS(rawCSS))
+
+	//go:embed branding.gohtml
+	rawPartials string
+
+	hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+	// logoContentTypes are the types of images which browsers can show, as detected by http.DetectContentType.
+	// SVG images are text, so they are detected separately.
+	logoContentTypes = map[string]bool{
+		"image/png":  true,
+		"image/jpeg": true,
+		"image/gif":  true,
+		"image/webp": true,
+	}
+)
+
+// Branding is the validated content of a FederationDomain's branding ConfigMap. All fields are optional. A nil
+// *Branding means that the default look is used.
+type Branding struct {
+	// ProductName is shown next to the logo and as the title of the pages.
+	ProductName string
+
+	// Logo is a data URL of the logo image.
+	Logo template.URL
+
+	// PrimaryColor, BackgroundColor and TextColor are CSS hex colors.
+	PrimaryColor    string
+	BackgroundColor string
+	TextColor       string
+
+	// SupportURL is an https or mailto URL which is linked at the bottom of the pages.
+	SupportURL string
+}
+
+// MustMinify returns the output of a minify function, and panics on its error. It is used when the pages' style
+// sheets and scripts are minified at init.
+func MustMinify(s string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// CSPHash returns the hash source of an inline style sheet or script, which allows it in a Content-Security-Policy.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy/default-src#:~:text=%27%3Chash-algorithm%3E-%3Cbase64-value%3E%27.
+func CSPHash(s string) string {
+	hashBytes := sha256.Sum256([]byte(s))
+	return "sha256-" + base64.StdEncoding.EncodeToString(hashBytes[:])
+}
+
+// FromConfigMap validates the content of a branding ConfigMap. Each value may be in either data or binaryData.
+func FromConfigMap(cm *corev1.ConfigMap) (*Branding, error) {
+	var b Branding
+
+	if name := strings.TrimSpace(configMapValue(cm, ProductNameKey)); name != "" {
+		if utf8.RuneCountInString(name) > maxProductNameLen {
+			return nil, fmt.Errorf("%s must be at most %d characters", ProductNameKey, maxProductNameLen)
+		}
+		b.ProductName = name
+	}
+
+	if logo := configMapValue(cm, LogoKey); logo != "" {
+		logoURL, err := logoDataURL([]byte(logo))
+		if err != nil {
+			return nil, err
+		}
+		b.Logo = logoURL
+	}
+
+	for _, color := range []struct {
+		key   string
+		field *string
+	}{
+		{PrimaryColorKey, &b.PrimaryColor},
+		{BackgroundColorKey, &b.BackgroundColor},
+		{TextColorKey, &b.TextColor},
+	} {
+		value := strings.TrimSpace(configMapValue(cm, color.key))
+		if value == "" {
+			continue
+		}
+		if !hexColor.MatchString(value) {
+			return nil, fmt.Errorf("%s must be a CSS hex color like #1b3951, not %q", color.key, value)
+		}
+		*color.field = value
+	}
+
+	if supportURL := strings.TrimSpace(configMapValue(cm, SupportURLKey)); supportURL != "" {
+		parsed, err := url.Parse(supportURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "mailto") || (parsed.Opaque == "" && parsed.Host == "") {
+			return nil, fmt.Errorf("%s must be an https or mailto URL, not %q", SupportURLKey, supportURL)
+		}
+		b.SupportURL = parsed.String()
+	}
+
+	return &b, nil
+}
+
+func configMapValue(cm *corev1.ConfigMap, key string) string {
+	if value, ok := cm.BinaryData[key]; ok {
+		return string(value)
+	}
+	return cm.Data[key]
+}
+
+func logoDataURL(logo []byte) (template.URL, error) {
+	if len(logo) > maxLogoSize {
+		return "", fmt.Errorf("%s must be at most %d KiB", LogoKey, maxLogoSize/1024)
+	}
+	contentType := http.DetectContentType(logo)
+	if !logoContentTypes[contentType] {
+		if !bytes.Contains(bytes.ToLower(logo), []byte("<svg")) {
+			return "", fmt.Errorf("%s must be a PNG, JPEG, GIF, WebP or SVG image", LogoKey)
+		}
+		contentType = "image/svg+xml"
+	}
+	// An image in a data URL cannot run scripts, even when it is an SVG image, because browsers treat it as an image.
+	return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(logo)), nil //nolint:gosec // See above.
+}
+
+// Name returns the product name, or "Pinniped" when the branding does not have one.
+func (b *Branding) Name() string {
+	if b == nil || b.ProductName == "" {
+		return defaultProductName
+	}
+	return b.ProductName
+}
+
+// CSS returns the style sheet which shows the branding, or "" when there is no branding. It is rendered after the
+// style sheet of each page, so its colors take precedence. Pages must allow its hash in their Content-Security-Policy.
+func (b *Branding) CSS() string {
+	if b == nil {
+		return ""
+	}
+	var css strings.Builder
+	css.WriteString(minifiedCSS)
+	if b.BackgroundColor != "" {
+		css.WriteString("body{background-color:" + b.BackgroundColor + "}")
+	}
+	if b.TextColor != "" {
+		css.WriteString("body,code{color:" + b.TextColor + "}")
+	}
+	if c := b.PrimaryColor; c != "" {
+		css.WriteString("h1,a,li a{color:" + c + "}")
+		css.WriteString("button[type=submit]{border:1px solid " + c + ";background-color:" + c + ";color:#fff}")
+		css.WriteString("#loading{border-top-color:" + c + "}")
+	}
+	return css.String()
+}
+
+// HasLogo returns whether the pages show an image from a data URL, which their Content-Security-Policy must allow.
+func (b *Branding) HasLogo() bool {
+	return b != nil && b.Logo != ""
+}
+
+// ParseTemplate parses the HTML template of a page together with the shared templates which render the branding, i.e.
+// "brandingStyle", "brandingHeader" and "brandingFooter". The page's own functions are added to placeholders for the
+// functions of FuncMap. The result must not be executed, only given to Template.
+func ParseTemplate(name, text string, funcs template.FuncMap) *template.Template {
+	parsed := template.Must(template.New(name).Funcs(FuncMap(nil, locale.Default())).Funcs(funcs).Parse(text))
+	template.Must(parsed.New("branding.gohtml").Parse(rawPartials))
+	return parsed
+}
+
+// Template returns a copy of a template from ParseTemplate which renders the page with the given branding and in the
+// given language.
+func Template(parsed *template.Template, b *Branding, p *locale.Printer) *template.Template {
+	return template.Must(parsed.Clone()).Funcs(FuncMap(b, p))
+}
+
+// FuncMap returns the template functions which render the branding and the translated messages of a page:
+//
+//   - lang returns the BCP 47 tag of the language, for the lang attribute of the html element.
+//   - msg formats the message with the given key, like locale.Printer.Sprintf.
+//   - productName returns the product name, which is "Pinniped" by default.
+//   - branding returns the *Branding, which is nil when there is no branding.
+//   - brandingCSS returns the style sheet of the branding.
+//   - supportURL returns the support URL, or "" when there is none.
+func FuncMap(b *Branding, p *locale.Printer) template.FuncMap {
+	var supportURL string
+	if b != nil {
+		supportURL = b.SupportURL
+	}
+	return template.FuncMap{
+		"lang":        p.Lang,
+		"msg":         p.Sprintf,
+		"productName": b.Name,
+		"branding":    func() *Branding { return b },
+		"brandingCSS": func() template.CSS { return template.CSS(b.CSS()) }, //nolint:gosec // The colors are validated.
+		"supportURL":  func() string { return supportURL },
+	}
+}
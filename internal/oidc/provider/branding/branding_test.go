@@ -0,0 +1,175 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package branding
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"go.pinniped.dev/internal/oidc/provider/locale"
+)
+
+func TestFromConfigMap(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nsome-image-data")
+
+	tests := []struct {
+		name         string
+		configMap    *corev1.ConfigMap
+		wantBranding *Branding
+		wantError    string
+	}{
+		{
+			name:         "empty",
+			configMap:    &corev1.ConfigMap{},
+			wantBranding: &Branding{},
+		},
+		{
+			name: "everything",
+			configMap: &corev1.ConfigMap{
+				Data: map[string]string{
+					"productName":     " Acme SSO ",
+					"primaryColor":    "#1b3951",
+					"backgroundColor": "#FFF",
+					"textColor":       "#333333",
+					"supportURL":      "https://help.example.com/sso",
+				},
+				BinaryData: map[string][]byte{"logo": png},
+			},
+			wantBranding: &Branding{
+				ProductName:     "Acme SSO",
+				Logo:            "data:image/png;base64,iVBORw0KGgpzb21lLWltYWdlLWRhdGE=",
+				PrimaryColor:    "#1b3951",
+				BackgroundColor: "#FFF",
+				TextColor:       "#333333",
+				SupportURL:      "https://help.example.com/sso",
+			},
+		},
+		{
+			name: "SVG logo in data and mailto support URL",
+			configMap: &corev1.ConfigMap{Data: map[string]string{
+				"logo":       `<svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+				"supportURL": "mailto:help@example.com",
+			}},
+			wantBranding: &Branding{
+				Logo:       "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciPjwvc3ZnPg==",
+				SupportURL: "mailto:help@example.com",
+			},
+		},
+		{
+			name:      "product name which is too long",
+			configMap: &corev1.ConfigMap{Data: map[string]string{"productName": strings.Repeat("x", 65)}},
+			wantError: "productName must be at most 64 characters",
+		},
+		{
+			name:      "logo which is not an image",
+			configMap: &corev1.ConfigMap{Data: map[string]string{"logo": "not an image"}},
+			wantError: "logo must be a PNG, JPEG, GIF, WebP or SVG image",
+		},
+		{
+			name:      "logo which is too large",
+			configMap: &corev1.ConfigMap{BinaryData: map[string][]byte{"logo": append(png, make([]byte, 256*1024)...)}},
+			wantError: "logo must be at most 256 KiB",
+		},
+		{
+			name:      "color which is not a hex color",
+			configMap: &corev1.ConfigMap{Data: map[string]string{"textColor": "red;}body{display:none"}},
+			wantError: `textColor must be a CSS hex color like #1b3951, not "red;}body{display:none"`,
+		},
+		{
+			name:      "support URL which is not https",
+			configMap: &corev1.ConfigMap{Data: map[string]string{"supportURL": "javascript:alert(1)"}},
+			wantError: `supportURL must be an https or mailto URL, not "javascript:alert(1)"`,
+		},
+		{
+			name:      "support URL without a host",
+			configMap: &corev1.ConfigMap{Data: map[string]string{"supportURL": "https:///path"}},
+			wantError: `supportURL must be an https or mailto URL, not "https:///path"`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			b, err := FromConfigMap(test.configMap)
+			if test.wantError != "" {
+				require.EqualError(t, err, test.wantError)
+				require.Nil(t, b)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantBranding, b)
+		})
+	}
+}
+
+func TestBrandingMethods(t *testing.T) {
+	var noBranding *Branding
+	require.Equal(t, "Pinniped", noBranding.Name())
+	require.Empty(t, noBranding.CSS())
+	require.False(t, noBranding.HasLogo())
+
+	require.Equal(t, "Pinniped", (&Branding{}).Name())
+	require.Equal(t, minifiedCSS, (&Branding{}).CSS())
+
+	b := &Branding{ProductName: "Acme SSO", Logo: "data:image/png;base64,AA==", PrimaryColor: "#123", BackgroundColor: "#456", TextColor: "#789"}
+	require.Equal(t, "Acme SSO", b.Name())
+	require.True(t, b.HasLogo())
+	require.Equal(t, minifiedCSS+
+		"body{background-color:#456}"+
+		"body,code{color:#789}"+
+		"h1,a,li a{color:#123}"+
+		"button[type=submit]{border:1px solid #123;background-color:#123;color:#fff}"+
+		"#loading{border-top-color:#123}", b.CSS())
+}
+
+func TestTemplate(t *testing.T) {
+	parsed := ParseTemplate("page", `<html lang="{{ lang }}"><title>{{ productName }}</title>{{ template "brandingStyle" }}`+
+		`<body>{{ template "brandingHeader" }}<p>{{ msg "chooseIDP.title" }} {{ extra }}</p>{{ template "brandingFooter" }}</body>`,
+		template.FuncMap{"extra": func() string { return "extra" }})
+
+	render := func(b *Branding, p *locale.Printer) string {
+		var buf bytes.Buffer
+		require.NoError(t, Template(parsed, b, p).Execute(&buf, nil))
+		return buf.String()
+	}
+
+	require.Equal(t, `<html lang="en"><title>Pinniped</title><body><p>Choose an identity provider extra</p></body>`,
+		render(nil, locale.Default()))
+
+	b := &Branding{ProductName: "Acme <SSO>", SupportURL: "https://help.example.com"}
+	require.Equal(t, `<html lang="en"><title>Acme &lt;SSO&gt;</title>`+
+		"\n    <style>"+b.CSS()+"</style>"+
+		"<body>\n<header class=\"branding-header\">\n    <span>Acme &lt;SSO&gt;</span>\n</header>"+
+		"<p>Choose an identity provider extra</p>"+
+		"\n<footer class=\"branding-footer\"><a href=\"https://help.example.com\">Need help? Contact support.</a></footer>"+
+		"</body>", render(b, locale.Default()))
+
+	// Rendering with branding must not change the parsed template.
+	require.Equal(t, `<html lang="en"><title>Pinniped</title><body><p>Choose an identity provider extra</p></body>`,
+		render(nil, locale.Default()))
+}
+
+func TestDynamicBrandingProvider(t *testing.T) {
+	provider := NewDynamicBrandingProvider()
+	require.Nil(t, provider.GetBranding("https://issuer.example.com"))
+
+	b := &Branding{ProductName: "Acme SSO"}
+	provider.SetIssuerToBrandingMap(map[string]*Branding{"https://issuer.example.com": b})
+	require.Same(t, b, provider.GetBranding("https://issuer.example.com"))
+	require.Nil(t, provider.GetBranding("https://other.example.com"))
+}
+
+func TestHelpers(t *testing.T) {
+	// These are silly tests but it's easy to we might as well have them.
+	require.Equal(t, "test", MustMinify("test", nil))
+	require.PanicsWithError(t, "some error", func() { MustMinify("", fmt.Errorf("some error")) })
+
+	// Example test vector from https://content-security-policy.com/hash/.
+	require.Equal(t, "sha256-RFWPLDbv2BY+rCkDzsE+0fr8ylGr2R2faWMhq4lfEQc=", CSPHash("doSomething();"))
+}
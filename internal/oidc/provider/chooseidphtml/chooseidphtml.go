@@ -0,0 +1,71 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package chooseidphtml defines HTML templates used by the Supervisor.
+//nolint: gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
+package chooseidphtml
+
+import (
+	_ "embed" // Needed to trigger //go:embed directives below.
+	"html/template"
+	"strings"
+
+	"github.com/tdewolff/minify/v2/minify"
+
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+)
+
+var (
+	//go:embed choose_idp.css
+	rawCSS      string
+	minifiedCSS = branding.MustMinify(minify.CSS(rawCSS))
+
+	//go:embed choose_idp.gohtml
+	rawHTMLTemplate string
+)
+
+// Parse the Go templated HTML and inject functions providing the minified inline CSS.
+var parsedHTMLTemplate = branding.ParseTemplate("choose_idp.gohtml", rawHTMLTemplate, template.FuncMap{
+	"minifiedCSS": func() template.CSS { return template.CSS(minifiedCSS) },
+})
+
+// Generate the hash of the page's own CSS once since it's effectively constant.
+var styleHash = `'` + branding.CSPHash(minifiedCSS) + `'`
+
+// PageData is the data used to render the identity provider chooser page.
+type PageData struct {
+	IdentityProviders []IdentityProvider
+}
+
+// IdentityProvider is one of the choices on the identity provider chooser page.
+type IdentityProvider struct {
+	Name string
+	Type string
+
+	// URL is where the browser goes when the end user chooses this identity provider.
+	URL string
+}
+
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly with
+// the given branding. The style sheet of the branding is allowed by its hash, and its logo by allowing data URLs. The
+// chooser page does not need any JavaScript.
+func ContentSecurityPolicy(b *branding.Branding) string {
+	directives := []string{`default-src 'none'`}
+	styleSrc := `style-src ` + styleHash
+	if css := b.CSS(); css != "" {
+		styleSrc += ` '` + branding.CSPHash(css) + `'`
+	}
+	directives = append(directives, styleSrc)
+	if b.HasLogo() {
+		directives = append(directives, `img-src data:`)
+	}
+	directives = append(directives, `frame-ancestors 'none'`)
+	return strings.Join(directives, "; ")
+}
+
+// Template returns the html/template.Template for rendering the identity provider chooser page with the given branding and in the
+// language of the given Printer.
+func Template(b *branding.Branding, p *locale.Printer) *template.Template {
+	return branding.Template(parsedHTMLTemplate, b, p)
+}
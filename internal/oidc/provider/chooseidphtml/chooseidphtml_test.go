@@ -0,0 +1,157 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package chooseidphtml
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+)
+
+var (
+	testExpectedCSS = `body{font-family:metropolis-light,Helvetica,sans-serif}h1{font-size:20px}.box{position:absolute;top:100px;left:50%;width:400px;margin-left:-200px;font-size:14px;line-height:24px}ul{padding:0;list-style:none}li a{display:block;margin-bottom:8px;padding:10px;border:1px solid #ccc;color:#1b3951;text-decoration:none;transition:all .1s}li a:hover{background-color:#eee;transform:scale(1.01)}.idp-type{float:right;color:#666}`
+
+	testExpectedChooserPage = here.Docf(`
+        <!DOCTYPE html>
+        <html lang="en">
+        <head>
+            <title>Pinniped</title>
+            <meta charset="UTF-8">
+            <style>%s</style>
+        </head>
+        <body>
+        <div class="box">
+            <h1>Choose an identity provider</h1>
+            <p>Log in with one of the following identity providers:</p>
+            <ul>
+                <li><a href="https://example.com/oauth2/authorize?client_id=pinniped-cli&amp;pinniped_idp_name=some-ldap-idp&amp;pinniped_idp_type=ldap">some-ldap-idp<span class="idp-type">ldap</span></a></li>
+                <li><a href="https://example.com/oauth2/authorize?client_id=pinniped-cli&amp;pinniped_idp_name=some-oidc-idp&amp;pinniped_idp_type=oidc">some-oidc-idp<span class="idp-type">oidc</span></a></li>
+            </ul>
+        </div>
+        </body>
+        </html>
+		`, testExpectedCSS)
+
+	testExpectedEmptyChooserPage = here.Docf(`
+        <!DOCTYPE html>
+        <html lang="en">
+        <head>
+            <title>Pinniped</title>
+            <meta charset="UTF-8">
+            <style>%s</style>
+        </head>
+        <body>
+        <div class="box">
+            <h1>Choose an identity provider</h1>
+            <p>No identity providers are available. Please contact your administrator.</p>
+        </div>
+        </body>
+        </html>
+		`, testExpectedCSS)
+
+	testBranding = &branding.Branding{
+		ProductName:  "Acme SSO",
+		Logo:         "data:image/png;base64,iVBORw0KGgo=",
+		PrimaryColor: "#ff0000",
+		SupportURL:   "mailto:help@example.com",
+	}
+
+	testExpectedBrandedJapaneseChooserPage = here.Docf(`
+        <!DOCTYPE html>
+        <html lang="ja">
+        <head>
+            <title>Acme SSO</title>
+            <meta charset="UTF-8">
+            <style>%s</style>
+            <style>%s</style>
+        </head>
+        <body>
+        <header class="branding-header">
+            <img src="data:image/png;base64,iVBORw0KGgo=" alt=""/>
+            <span>Acme SSO</span>
+        </header>
+        <div class="box">
+            <h1>IDプロバイダーの選択</h1>
+            <p>利用できるIDプロバイダーがありません。管理者にお問い合わせください。</p>
+        </div>
+        <footer class="branding-footer"><a href="mailto:help@example.com">お困りの場合は、サポートにお問い合わせください。</a></footer>
+        </body>
+        </html>
+		`, testExpectedCSS, testBranding.CSS())
+
+	// It's okay if this changes in the future, but this gives us a chance to eyeball the formatting.
+	testExpectedCSP = `default-src 'none'; ` +
+		`style-src 'sha256-KSgmYHs6zpX0B554jnpMzad47e//wjte2k50wsvsnik='; ` +
+		`frame-ancestors 'none'`
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageData *PageData
+		branding *branding.Branding
+		lang     string
+		want     string
+	}{
+		{
+			name: "with identity providers",
+			pageData: &PageData{
+				IdentityProviders: []IdentityProvider{
+					{
+						Name: "some-ldap-idp",
+						Type: "ldap",
+						URL:  "https://example.com/oauth2/authorize?client_id=pinniped-cli&pinniped_idp_name=some-ldap-idp&pinniped_idp_type=ldap",
+					},
+					{
+						Name: "some-oidc-idp",
+						Type: "oidc",
+						URL:  "https://example.com/oauth2/authorize?client_id=pinniped-cli&pinniped_idp_name=some-oidc-idp&pinniped_idp_type=oidc",
+					},
+				},
+			},
+			want: testExpectedChooserPage,
+		},
+		{
+			name:     "without identity providers",
+			pageData: &PageData{},
+			want:     testExpectedEmptyChooserPage,
+		},
+		{
+			name:     "with branding in Japanese",
+			pageData: &PageData{},
+			branding: testBranding,
+			lang:     "ja-JP",
+			want:     testExpectedBrandedJapaneseChooserPage,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			printer := locale.Default()
+			if tt.lang != "" {
+				r, err := http.NewRequest(http.MethodGet, "/", nil)
+				require.NoError(t, err)
+				r.Header.Set("Accept-Language", tt.lang)
+				printer = locale.FromRequest(r)
+			}
+			var buf bytes.Buffer
+			require.NoError(t, Template(tt.branding, printer).Execute(&buf, tt.pageData))
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestContentSecurityPolicyHashes(t *testing.T) {
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy(nil))
+	require.Equal(t, `default-src 'none'; `+
+		`style-src 'sha256-KSgmYHs6zpX0B554jnpMzad47e//wjte2k50wsvsnik=' '`+branding.CSPHash(testBranding.CSS())+`'; `+
+		`img-src data:; `+
+		`frame-ancestors 'none'`, ContentSecurityPolicy(testBranding))
+}
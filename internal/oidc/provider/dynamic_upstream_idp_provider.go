@@ -5,7 +5,10 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 
 	"golang.org/x/oauth2"
@@ -67,11 +70,21 @@ type DynamicUpstreamIDPProvider interface {
 	GetOIDCIdentityProviders() []UpstreamOIDCIdentityProviderI
 	SetLDAPIdentityProviders(ldapIDPs []UpstreamLDAPIdentityProviderI)
 	GetLDAPIdentityProviders() []UpstreamLDAPIdentityProviderI
+
+	// The names of the upstream providers which had a failing condition in the most recent validation by their
+	// watcher, i.e. whose phase is Error.
+	SetUnreadyOIDCIdentityProviders(names []string)
+	SetUnreadyLDAPIdentityProviders(names []string)
+
+	// CheckReady returns an error which names the unready upstream providers, if there are any.
+	CheckReady(r *http.Request) error
 }
 
 type dynamicUpstreamIDPProvider struct {
 	oidcUpstreams []UpstreamOIDCIdentityProviderI
 	ldapUpstreams []UpstreamLDAPIdentityProviderI
+	unreadyOIDC   []string
+	unreadyLDAP   []string
 	mutex         sync.RWMutex
 }
 
@@ -105,3 +118,31 @@ func (p *dynamicUpstreamIDPProvider) GetLDAPIdentityProviders() []UpstreamLDAPId
 	defer p.mutex.RUnlock()
 	return p.ldapUpstreams
 }
+
+func (p *dynamicUpstreamIDPProvider) SetUnreadyOIDCIdentityProviders(names []string) {
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.unreadyOIDC = names
+}
+
+func (p *dynamicUpstreamIDPProvider) SetUnreadyLDAPIdentityProviders(names []string) {
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.unreadyLDAP = names
+}
+
+func (p *dynamicUpstreamIDPProvider) CheckReady(_ *http.Request) error {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	var unready []string
+	for _, name := range p.unreadyOIDC {
+		unready = append(unready, fmt.Sprintf("OIDCIdentityProvider %q", name))
+	}
+	for _, name := range p.unreadyLDAP {
+		unready = append(unready, fmt.Sprintf("LDAPIdentityProvider %q", name))
+	}
+	if len(unready) > 0 {
+		return fmt.Errorf("upstream identity providers are not ready: %s", strings.Join(unready, ", "))
+	}
+	return nil
+}
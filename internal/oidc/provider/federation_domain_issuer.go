@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"go.pinniped.dev/internal/constable"
 )
@@ -17,10 +18,46 @@ type FederationDomainIssuer struct {
 	issuer     string
 	issuerHost string
 	issuerPath string
+
+	identityProviders FederationDomainIdentityProviders
+	tokenLifetimes    FederationDomainTokenLifetimes
+}
+
+// FederationDomainIdentityProviders describes how the upstream identity providers are offered to the end users
+// of a FederationDomain.
+type FederationDomainIdentityProviders struct {
+	// HiddenNames are the names of the upstream identity providers which should not be listed for end users.
+	HiddenNames []string
+
+	// DefaultName is the name of the upstream identity provider to use when the end user did not choose one.
+	DefaultName string
+}
+
+// IsHidden returns true when the upstream identity provider with the given name should not be listed for end users.
+func (p FederationDomainIdentityProviders) IsHidden(name string) bool {
+	for _, hidden := range p.HiddenNames {
+		if hidden == name {
+			return true
+		}
+	}
+	return false
 }
 
-func NewFederationDomainIssuer(issuer string) (*FederationDomainIssuer, error) {
-	p := FederationDomainIssuer{issuer: issuer}
+// FederationDomainTokenLifetimes are the lifetimes of the tokens and sessions issued by a FederationDomain.
+// A zero value means that the default lifetime should be used.
+type FederationDomainTokenLifetimes struct {
+	IDToken         time.Duration
+	AccessToken     time.Duration
+	RefreshToken    time.Duration
+	AbsoluteSession time.Duration
+}
+
+func NewFederationDomainIssuer(
+	issuer string,
+	identityProviders FederationDomainIdentityProviders,
+	tokenLifetimes FederationDomainTokenLifetimes,
+) (*FederationDomainIssuer, error) {
+	p := FederationDomainIssuer{issuer: issuer, identityProviders: identityProviders, tokenLifetimes: tokenLifetimes}
 	err := p.validate()
 	if err != nil {
 		return nil, err
@@ -75,3 +112,11 @@ func (p *FederationDomainIssuer) IssuerHost() string {
 func (p *FederationDomainIssuer) IssuerPath() string {
 	return p.issuerPath
 }
+
+func (p *FederationDomainIssuer) IdentityProviders() FederationDomainIdentityProviders {
+	return p.identityProviders
+}
+
+func (p *FederationDomainIssuer) TokenLifetimes() FederationDomainTokenLifetimes {
+	return p.tokenLifetimes
+}
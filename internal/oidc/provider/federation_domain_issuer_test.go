@@ -5,6 +5,7 @@ package provider
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -72,7 +73,7 @@ func TestFederationDomainIssuerValidations(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewFederationDomainIssuer(tt.issuer)
+			_, err := NewFederationDomainIssuer(tt.issuer, FederationDomainIdentityProviders{}, FederationDomainTokenLifetimes{})
 			if tt.wantError != "" {
 				require.EqualError(t, err, tt.wantError)
 			} else {
@@ -81,3 +82,29 @@ func TestFederationDomainIssuerValidations(t *testing.T) {
 		})
 	}
 }
+
+func TestFederationDomainIdentityProviders(t *testing.T) {
+	p, err := NewFederationDomainIssuer("https://tuna.com", FederationDomainIdentityProviders{
+		HiddenNames: []string{"hidden-idp"},
+		DefaultName: "default-idp",
+	}, FederationDomainTokenLifetimes{})
+	require.NoError(t, err)
+
+	idps := p.IdentityProviders()
+	require.Equal(t, "default-idp", idps.DefaultName)
+	require.True(t, idps.IsHidden("hidden-idp"))
+	require.False(t, idps.IsHidden("default-idp"))
+	require.False(t, FederationDomainIdentityProviders{}.IsHidden("hidden-idp"))
+}
+
+func TestFederationDomainTokenLifetimes(t *testing.T) {
+	lifetimes := FederationDomainTokenLifetimes{
+		IDToken:         time.Minute,
+		AccessToken:     2 * time.Minute,
+		RefreshToken:    time.Hour,
+		AbsoluteSession: 4 * time.Hour,
+	}
+	p, err := NewFederationDomainIssuer("https://tuna.com", FederationDomainIdentityProviders{}, lifetimes)
+	require.NoError(t, err)
+	require.Equal(t, lifetimes, p.TokenLifetimes())
+}
@@ -6,60 +6,62 @@
 package formposthtml
 
 import (
-	"crypto/sha256"
 	_ "embed" // Needed to trigger //go:embed directives below.
-	"encoding/base64"
 	"html/template"
 	"strings"
 
 	"github.com/tdewolff/minify/v2/minify"
+
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/locale"
 )
 
 var (
 	//go:embed form_post.css
 	rawCSS      string
-	minifiedCSS = mustMinify(minify.CSS(rawCSS))
+	minifiedCSS = branding.MustMinify(minify.CSS(rawCSS))
 
 	//go:embed form_post.js
 	rawJS      string
-	minifiedJS = mustMinify(minify.JS(rawJS))
+	minifiedJS = branding.MustMinify(minify.JS(rawJS))
 
 	//go:embed form_post.gohtml
 	rawHTMLTemplate string
 )
 
 // Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
-var parsedHTMLTemplate = template.Must(template.New("form_post.gohtml").Funcs(template.FuncMap{
+var parsedHTMLTemplate = branding.ParseTemplate("form_post.gohtml", rawHTMLTemplate, template.FuncMap{
 	"minifiedCSS": func() template.CSS { return template.CSS(minifiedCSS) },
 	"minifiedJS":  func() template.JS { return template.JS(minifiedJS) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
-}).Parse(rawHTMLTemplate))
+})
 
-// Generate the CSP header value once since it's effectively constant:
-var cspValue = strings.Join([]string{
-	`default-src 'none'`,
-	`script-src '` + cspHash(minifiedJS) + `'`,
-	`style-src '` + cspHash(minifiedCSS) + `'`,
-	`img-src data:`,
-	`connect-src *`,
-	`frame-ancestors 'none'`,
-}, "; ")
+// Generate the hashes once since they're effectively constant.
+var (
+	scriptSrc = `script-src '` + branding.CSPHash(minifiedJS) + `'`
+	styleHash = `'` + branding.CSPHash(minifiedCSS) + `'`
+)
 
-func mustMinify(s string, err error) string {
-	if err != nil {
-		panic(err)
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly with
+// the given branding, whose style sheet is allowed by its hash.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy/default-src#:~:text=%27%3Chash-algorithm%3E-%3Cbase64-value%3E%27.
+func ContentSecurityPolicy(b *branding.Branding) string {
+	styleSrc := `style-src ` + styleHash
+	if css := b.CSS(); css != "" {
+		styleSrc += ` '` + branding.CSPHash(css) + `'`
 	}
-	return s
+	return strings.Join([]string{
+		`default-src 'none'`,
+		scriptSrc,
+		styleSrc,
+		`img-src data:`,
+		`connect-src *`,
+		`frame-ancestors 'none'`,
+	}, "; ")
 }
 
-func cspHash(s string) string {
-	hashBytes := sha256.Sum256([]byte(s))
-	return "sha256-" + base64.StdEncoding.EncodeToString(hashBytes[:])
+// Template returns the html/template.Template for rendering the response_type=form_post response page with the given
+// branding and in the language of the given Printer.
+func Template(b *branding.Branding, p *locale.Printer) *template.Template {
+	return branding.Template(parsedHTMLTemplate, b, p)
 }
-
-// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
-//
-// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy/default-src#:~:text=%27%3Chash-algorithm%3E-%3Cbase64-value%3E%27.
-func ContentSecurityPolicy() string { return cspValue }
-
-// Template returns the html/template.Template for rendering the response_type=form_post response page.
-func Template() *template.Template { return parsedHTMLTemplate }
@@ -5,14 +5,17 @@ package formposthtml
 
 import (
 	"bytes"
-	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
 
 	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/locale"
 )
 
 var (
@@ -71,11 +74,11 @@ var (
 func TestTemplate(t *testing.T) {
 	// Use the Fosite helper to render the form, ensuring that the parameters all have the same names + types.
 	var buf bytes.Buffer
-	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(), &buf)
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(nil, locale.Default()), &buf)
 
 	// Render again so we can confirm that there is no error returned (Fosite ignores any error).
 	var buf2 bytes.Buffer
-	require.NoError(t, Template().Execute(&buf2, struct {
+	require.NoError(t, Template(nil, locale.Default()).Execute(&buf2, struct {
 		RedirURL   string
 		Parameters url.Values
 	}{
@@ -87,15 +90,35 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, testExpectedFormPostOutput, buf.String())
 }
 
-func TestContentSecurityPolicyHashes(t *testing.T) {
-	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
+func TestTemplateWithBrandingAndLanguage(t *testing.T) {
+	b := &branding.Branding{ProductName: "Acme SSO", PrimaryColor: "#ff0000", SupportURL: "https://help.example.com"}
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	r.Header.Set("Accept-Language", "de-DE")
+
+	var buf bytes.Buffer
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(b, locale.FromRequest(r)), &buf)
+	page := buf.String()
+
+	require.Contains(t, page, `<html lang="de">`)
+	require.Contains(t, page, "\n    <style>"+b.CSS()+"</style>\n")
+	require.Contains(t, page, "<body>\n<header class=\"branding-header\">\n    <span>Acme SSO</span>\n</header>\n<noscript>")
+	require.Contains(t, page, `<h1>Anmeldung erfolgreich</h1>`)
+	require.Contains(t, page, `<footer class="branding-footer"><a href="https://help.example.com">`)
+	require.NotContains(t, page, "Login succeeded")
+
+	// The default template must not be changed by rendering with branding.
+	var buf2 bytes.Buffer
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(nil, locale.Default()), &buf2)
+	require.Equal(t, testExpectedFormPostOutput, buf2.String())
 }
 
-func TestHelpers(t *testing.T) {
-	// These are silly tests but it's easy to we might as well have them.
-	require.Equal(t, "test", mustMinify("test", nil))
-	require.PanicsWithError(t, "some error", func() { mustMinify("", fmt.Errorf("some error")) })
+func TestContentSecurityPolicyHashes(t *testing.T) {
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy(nil))
 
-	// Example test vector from https://content-security-policy.com/hash/.
-	require.Equal(t, "sha256-RFWPLDbv2BY+rCkDzsE+0fr8ylGr2R2faWMhq4lfEQc=", cspHash("doSomething();"))
+	b := &branding.Branding{PrimaryColor: "#ff0000"}
+	require.Equal(t, strings.Replace(testExpectedCSP,
+		`style-src 'sha256-CtfkX7m8x2UdGYvGgDq+6b6yIAQsASW9pbQK+sG8fNA='`,
+		`style-src 'sha256-CtfkX7m8x2UdGYvGgDq+6b6yIAQsASW9pbQK+sG8fNA=' '`+branding.CSPHash(b.CSS())+`'`, 1),
+		ContentSecurityPolicy(b))
 }
@@ -0,0 +1,124 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package locale translates the text of the Supervisor's HTML pages into the language which each browser prefers.
+//
+// Each language has a message catalog in the messages directory, named by its BCP 47 tag, which maps message keys to
+// fmt format strings. Every catalog must have exactly the keys of the English catalog, which is checked at init.
+//nolint: gochecknoglobals // This package uses globals to ensure that all catalogs are loaded and checked at init.
+package locale
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const defaultLanguage = "en"
+
+var (
+	//go:embed messages/*.json
+	messageFiles embed.FS
+
+	// printers has a Printer for each catalog, with the English one first, so that it is what matcher chooses when
+	// none of the languages of a request are supported.
+	printers = mustLoadPrinters()
+
+	matcher = newMatcher(printers)
+)
+
+// Printer formats the messages of one language.
+type Printer struct {
+	tag      language.Tag
+	messages map[string]string
+}
+
+func mustLoadPrinters() []*Printer {
+	entries, err := messageFiles.ReadDir("messages")
+	if err != nil {
+		panic(err)
+	}
+
+	var result []*Printer
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		raw, err := messageFiles.ReadFile(path.Join("messages", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		p := &Printer{tag: language.MustParse(name)}
+		if err := json.Unmarshal(raw, &p.messages); err != nil {
+			panic(fmt.Errorf("could not parse message catalog %q: %w", entry.Name(), err))
+		}
+		result = append(result, p)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].tag.String() == defaultLanguage && result[j].tag.String() != defaultLanguage
+	})
+	if len(result) == 0 || result[0].tag.String() != defaultLanguage {
+		panic("the message catalog for " + defaultLanguage + " is missing")
+	}
+	for _, p := range result[1:] {
+		if err := sameKeys(result[0].messages, p.messages); err != nil {
+			panic(fmt.Errorf("message catalog %q: %w", p.tag, err))
+		}
+	}
+	return result
+}
+
+func sameKeys(want, got map[string]string) error {
+	for key := range want {
+		if _, ok := got[key]; !ok {
+			return fmt.Errorf("missing message %q", key)
+		}
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			return fmt.Errorf("unknown message %q", key)
+		}
+	}
+	return nil
+}
+
+func newMatcher(printers []*Printer) language.Matcher {
+	tags := make([]language.Tag, 0, len(printers))
+	for _, p := range printers {
+		tags = append(tags, p.tag)
+	}
+	return language.NewMatcher(tags)
+}
+
+// Default returns the Printer for English, which is used when a browser does not prefer any supported language.
+func Default() *Printer { return printers[0] }
+
+// FromRequest returns the Printer for the most preferred of the supported languages in the Accept-Language header
+// of the request.
+func FromRequest(r *http.Request) *Printer {
+	// Invalid headers are treated like missing headers, so the default language is used.
+	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	_, index, _ := matcher.Match(tags...)
+	return printers[index]
+}
+
+// Lang returns the BCP 47 tag of the language, e.g. for the lang attribute of an HTML page.
+func (p *Printer) Lang() string { return p.tag.String() }
+
+// Sprintf formats the message with the given key. It returns the key itself when there is no such message, so that
+// a mistyped key is easy to see on a page.
+func (p *Printer) Sprintf(key string, args ...interface{}) string {
+	format, ok := p.messages[key]
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package locale
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		wantLang       string
+	}{
+		{name: "no header", wantLang: "en"},
+		{name: "invalid header", acceptLanguage: "not a;;language", wantLang: "en"},
+		{name: "unsupported language", acceptLanguage: "fr-FR, fr;q=0.9", wantLang: "en"},
+		{name: "German", acceptLanguage: "de", wantLang: "de"},
+		{name: "regional German", acceptLanguage: "de-AT", wantLang: "de"},
+		{name: "Japanese", acceptLanguage: "ja-JP,ja;q=0.9,en-US;q=0.8,en;q=0.7", wantLang: "ja"},
+		{name: "unsupported language before a supported one", acceptLanguage: "fr, ja;q=0.5", wantLang: "ja"},
+		{name: "weights", acceptLanguage: "de;q=0.2, ja;q=0.8", wantLang: "ja"},
+		{name: "English before German", acceptLanguage: "en-GB, de;q=0.9", wantLang: "en"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, err)
+			if test.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", test.acceptLanguage)
+			}
+			require.Equal(t, test.wantLang, FromRequest(r).Lang())
+		})
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	require.Equal(t, "Log in to some-idp", Default().Sprintf("login.title", "some-idp"))
+	require.Equal(t, "Username", Default().Sprintf("login.username"))
+	require.Equal(t, "no.such.message", Default().Sprintf("no.such.message"))
+
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	r.Header.Set("Accept-Language", "de")
+	require.Equal(t, "Bei some-idp anmelden", FromRequest(r).Sprintf("login.title", "some-idp"))
+}
+
+func TestCatalogs(t *testing.T) {
+	langs := make([]string, 0, len(printers))
+	for _, p := range printers {
+		langs = append(langs, p.Lang())
+		require.Len(t, p.messages, len(Default().messages), "catalog %s", p.Lang())
+	}
+	require.Equal(t, []string{"en", "de", "ja"}, langs)
+
+	require.NoError(t, sameKeys(map[string]string{"a": "", "b": ""}, map[string]string{"b": "", "a": ""}))
+	require.EqualError(t, sameKeys(map[string]string{"a": "", "b": ""}, map[string]string{"a": ""}), `missing message "b"`)
+	require.EqualError(t, sameKeys(map[string]string{"a": ""}, map[string]string{"a": "", "c": ""}), `unknown message "c"`)
+}
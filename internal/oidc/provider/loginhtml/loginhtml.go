@@ -0,0 +1,77 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package loginhtml defines HTML templates used by the Supervisor.
+//nolint: gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
+package loginhtml
+
+import (
+	_ "embed" // Needed to trigger //go:embed directives below.
+	"html/template"
+	"strings"
+
+	"github.com/tdewolff/minify/v2/minify"
+
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+)
+
+var (
+	//go:embed login_form.css
+	rawCSS      string
+	minifiedCSS = branding.MustMinify(minify.CSS(rawCSS))
+
+	//go:embed login_form.gohtml
+	rawHTMLTemplate string
+)
+
+// Parse the Go templated HTML and inject functions providing the minified inline CSS.
+var parsedHTMLTemplate = branding.ParseTemplate("login_form.gohtml", rawHTMLTemplate, template.FuncMap{
+	"minifiedCSS": func() template.CSS { return template.CSS(minifiedCSS) },
+})
+
+// Generate the hash of the page's own CSS once since it's effectively constant.
+var styleHash = `'` + branding.CSPHash(minifiedCSS) + `'`
+
+// PageData is the data used to render the login page.
+type PageData struct {
+	// State is the encoded upstream state param, which is posted back along with the credentials.
+	State string
+
+	// IDPName is the name of the upstream identity provider which will check the credentials.
+	IDPName string
+
+	// PostPath is the form's action, i.e. where the browser sends the credentials.
+	PostPath string
+
+	// Username is used to pre-fill the username field, e.g. after a failed login attempt.
+	Username string
+
+	// AlertMessage is an optional end user friendly error message to show above the form.
+	AlertMessage string
+}
+
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly with
+// the given branding. The style sheet of the branding is allowed by its hash, and its logo by allowing data URLs. The
+// login page does not need any JavaScript. Note that form-action is intentionally not restricted, because browsers
+// also apply it to the redirect back to the client's redirect URI which happens after the form is successfully
+// submitted.
+func ContentSecurityPolicy(b *branding.Branding) string {
+	directives := []string{`default-src 'none'`}
+	styleSrc := `style-src ` + styleHash
+	if css := b.CSS(); css != "" {
+		styleSrc += ` '` + branding.CSPHash(css) + `'`
+	}
+	directives = append(directives, styleSrc)
+	if b.HasLogo() {
+		directives = append(directives, `img-src data:`)
+	}
+	directives = append(directives, `frame-ancestors 'none'`)
+	return strings.Join(directives, "; ")
+}
+
+// Template returns the html/template.Template for rendering the login page with the given branding and in the
+// language of the given Printer.
+func Template(b *branding.Branding, p *locale.Printer) *template.Template {
+	return branding.Template(parsedHTMLTemplate, b, p)
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package loginhtml
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/provider/locale"
+)
+
+var (
+	testExpectedCSS = `body{font-family:metropolis-light,Helvetica,sans-serif}h1{font-size:20px}.box{position:absolute;top:100px;left:50%;width:400px;margin-left:-200px;font-size:14px;line-height:24px}.form-field{margin-bottom:16px}label{display:block}input[type=text],input[type=password]{box-sizing:border-box;width:100%;padding:8px;font-size:14px}button{padding:8px 16px;font-size:14px;cursor:pointer}.alert{margin-bottom:16px;padding:8px;border:1px solid #c92100;background-color:#f5dbd9;color:#c92100}`
+
+	testExpectedLoginPage = here.Docf(`
+        <!DOCTYPE html>
+        <html lang="en">
+        <head>
+            <title>Pinniped</title>
+            <meta charset="UTF-8">
+            <style>%s</style>
+        </head>
+        <body>
+        <div class="box">
+            <h1>Log in to some-ldap-idp</h1>
+            <form action="/some/path/login" method="post">
+                <input type="hidden" name="state" value="some-state&lt;&gt;"/>
+                <div class="form-field">
+                    <label for="username">Username</label>
+                    <input type="text" name="username" id="username" value="" autocomplete="username" required autofocus/>
+                </div>
+                <div class="form-field">
+                    <label for="password">Password</label>
+                    <input type="password" name="password" id="password" autocomplete="current-password" required/>
+                </div>
+                <button type="submit">Log in</button>
+            </form>
+        </div>
+        </body>
+        </html>
+		`, testExpectedCSS)
+
+	testExpectedLoginPageWithAlert = here.Docf(`
+        <!DOCTYPE html>
+        <html lang="en">
+        <head>
+            <title>Pinniped</title>
+            <meta charset="UTF-8">
+            <style>%s</style>
+        </head>
+        <body>
+        <div class="box">
+            <h1>Log in to some-ldap-idp</h1>
+            <div class="alert" role="alert">Incorrect username or password.</div>
+            <form action="/some/path/login" method="post">
+                <input type="hidden" name="state" value="some-state&lt;&gt;"/>
+                <div class="form-field">
+                    <label for="username">Username</label>
+                    <input type="text" name="username" id="username" value="&lt;script&gt;" autocomplete="username" required/>
+                </div>
+                <div class="form-field">
+                    <label for="password">Password</label>
+                    <input type="password" name="password" id="password" autocomplete="current-password" required autofocus/>
+                </div>
+                <button type="submit">Log in</button>
+            </form>
+        </div>
+        </body>
+        </html>
+		`, testExpectedCSS)
+
+	testBranding = &branding.Branding{BackgroundColor: "#f8f8f8", TextColor: "#333"}
+
+	testExpectedBrandedGermanLoginPageWithAlert = here.Docf(`
+        <!DOCTYPE html>
+        <html lang="de">
+        <head>
+            <title>Pinniped</title>
+            <meta charset="UTF-8">
+            <style>%s</style>
+            <style>%s</style>
+        </head>
+        <body>
+        <div class="box">
+            <h1>Bei some-ldap-idp anmelden</h1>
+            <div class="alert" role="alert">Benutzername oder Passwort ist falsch.</div>
+            <form action="/some/path/login" method="post">
+                <input type="hidden" name="state" value="some-state"/>
+                <div class="form-field">
+                    <label for="username">Benutzername</label>
+                    <input type="text" name="username" id="username" value="some-user" autocomplete="username" required/>
+                </div>
+                <div class="form-field">
+                    <label for="password">Passwort</label>
+                    <input type="password" name="password" id="password" autocomplete="current-password" required autofocus/>
+                </div>
+                <button type="submit">Anmelden</button>
+            </form>
+        </div>
+        </body>
+        </html>
+		`, testExpectedCSS, testBranding.CSS())
+
+	// It's okay if this changes in the future, but this gives us a chance to eyeball the formatting.
+	// Our browser-based integration tests should find any incompatibilities.
+	testExpectedCSP = `default-src 'none'; ` +
+		`style-src 'sha256-yZqiAOkVzPvdmNXtwyWN9sRn8GUljKh9AwkG+kybcN8='; ` +
+		`frame-ancestors 'none'`
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageData *PageData
+		branding *branding.Branding
+		lang     string
+		want     string
+	}{
+		{
+			name: "without an alert",
+			pageData: &PageData{
+				State:    "some-state<>",
+				IDPName:  "some-ldap-idp",
+				PostPath: "/some/path/login",
+			},
+			want: testExpectedLoginPage,
+		},
+		{
+			name: "with an alert and a pre-filled username",
+			pageData: &PageData{
+				State:        "some-state<>",
+				IDPName:      "some-ldap-idp",
+				PostPath:     "/some/path/login",
+				Username:     "<script>",
+				AlertMessage: "Incorrect username or password.",
+			},
+			want: testExpectedLoginPageWithAlert,
+		},
+		{
+			name: "with branding in German",
+			pageData: &PageData{
+				State:        "some-state",
+				IDPName:      "some-ldap-idp",
+				PostPath:     "/some/path/login",
+				Username:     "some-user",
+				AlertMessage: "Benutzername oder Passwort ist falsch.",
+			},
+			branding: testBranding,
+			lang:     "de",
+			want:     testExpectedBrandedGermanLoginPageWithAlert,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			printer := locale.Default()
+			if tt.lang != "" {
+				r, err := http.NewRequest(http.MethodGet, "/", nil)
+				require.NoError(t, err)
+				r.Header.Set("Accept-Language", tt.lang)
+				printer = locale.FromRequest(r)
+			}
+			var buf bytes.Buffer
+			require.NoError(t, Template(tt.branding, printer).Execute(&buf, tt.pageData))
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestContentSecurityPolicyHashes(t *testing.T) {
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy(nil))
+	require.Equal(t, `default-src 'none'; `+
+		`style-src 'sha256-yZqiAOkVzPvdmNXtwyWN9sRn8GUljKh9AwkG+kybcN8=' '`+branding.CSPHash(testBranding.CSS())+`'; `+
+		`frame-ancestors 'none'`, ContentSecurityPolicy(testBranding))
+}
@@ -8,20 +8,32 @@ import (
 	"strings"
 	"sync"
 
+	"go.opentelemetry.io/otel/attribute"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/httputil/sourceip"
 	"go.pinniped.dev/internal/oidc"
 	"go.pinniped.dev/internal/oidc/auth"
 	"go.pinniped.dev/internal/oidc/callback"
+	"go.pinniped.dev/internal/oidc/chooseidp"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
 	"go.pinniped.dev/internal/oidc/csrftoken"
 	"go.pinniped.dev/internal/oidc/discovery"
 	"go.pinniped.dev/internal/oidc/dynamiccodec"
 	"go.pinniped.dev/internal/oidc/idpdiscovery"
 	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/oidc/login"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
+	"go.pinniped.dev/internal/oidc/par"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
 	"go.pinniped.dev/internal/oidc/token"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/secret"
+	"go.pinniped.dev/internal/tracing"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
 	"go.pinniped.dev/pkg/oidcclient/pkce"
 )
@@ -32,32 +44,52 @@ import (
 type Manager struct {
 	mu                  sync.RWMutex
 	providers           []*provider.FederationDomainIssuer
-	providerHandlers    map[string]http.Handler              // map of all routes for all providers
-	nextHandler         http.Handler                         // the next handler in a chain, called when this manager didn't know how to handle a request
-	dynamicJWKSProvider jwks.DynamicJWKSProvider             // in-memory cache of per-issuer JWKS data
-	upstreamIDPs        oidc.UpstreamIdentityProvidersLister // in-memory cache of upstream IDPs
-	secretCache         *secret.Cache                        // in-memory cache of cryptographic material
+	providerHandlers    map[string]http.Handler                        // map of all routes for all providers
+	nextHandler         http.Handler                                   // the next handler in a chain, called when this manager didn't know how to handle a request
+	dynamicJWKSProvider jwks.DynamicJWKSProvider                       // in-memory cache of per-issuer JWKS data
+	upstreamIDPs        oidc.UpstreamIdentityProvidersLister           // in-memory cache of upstream IDPs
+	clusters            clusteraudience.DynamicClusterAudienceProvider // in-memory cache of clusters which may receive tokens
+	trustedClusters     trustedcluster.DynamicTrustedClusterProvider   // in-memory cache of clusters whose ServiceAccount tokens are trusted
+	machineClients      clientregistry.DynamicMachineClientProvider    // in-memory cache of confidential clients which get tokens for themselves
+	brandings           branding.DynamicBrandingProvider               // in-memory cache of per-issuer branding of the HTML pages
+	secretCache         *secret.Cache                                  // in-memory cache of cryptographic material
 	secretsClient       corev1client.SecretInterface
+	loginLimiter        *loginlimiter.Limiter // shared state of failed password logins
 }
 
 // NewManager returns an empty Manager.
 // nextHandler will be invoked for any requests that could not be handled by this manager's providers.
 // dynamicJWKSProvider will be used as an in-memory cache for per-issuer JWKS data.
 // upstreamIDPs will be used as an in-memory cache of currently configured upstream IDPs.
+// clusters will be used as an in-memory cache of the clusters which may receive tokens using token exchange.
+// trustedClusters will be used as an in-memory cache of the clusters whose ServiceAccount tokens may be exchanged.
+// machineClients will be used as an in-memory cache of the clients which may use the client credentials grant.
+// brandings will be used as an in-memory cache of the per-issuer branding of the login and other HTML pages.
+// loginLimiter will be used to slow down password guessing at all providers.
 func NewManager(
 	nextHandler http.Handler,
 	dynamicJWKSProvider jwks.DynamicJWKSProvider,
 	upstreamIDPs oidc.UpstreamIdentityProvidersLister,
+	clusters clusteraudience.DynamicClusterAudienceProvider,
+	trustedClusters trustedcluster.DynamicTrustedClusterProvider,
+	machineClients clientregistry.DynamicMachineClientProvider,
+	brandings branding.DynamicBrandingProvider,
 	secretCache *secret.Cache,
 	secretsClient corev1client.SecretInterface,
+	loginLimiter *loginlimiter.Limiter,
 ) *Manager {
 	return &Manager{
 		providerHandlers:    make(map[string]http.Handler),
 		nextHandler:         nextHandler,
 		dynamicJWKSProvider: dynamicJWKSProvider,
 		upstreamIDPs:        upstreamIDPs,
+		clusters:            clusters,
+		trustedClusters:     trustedClusters,
+		machineClients:      machineClients,
+		brandings:           brandings,
 		secretCache:         secretCache,
 		secretsClient:       secretsClient,
+		loginLimiter:        loginLimiter,
 	}
 }
 
@@ -78,8 +110,8 @@ func (m *Manager) SetProviders(federationDomains ...*provider.FederationDomainIs
 
 	var csrfCookieEncoder = dynamiccodec.New(
 		oidc.CSRFCookieLifespan,
-		m.secretCache.GetCSRFCookieEncoderHashKey,
-		func() []byte { return nil },
+		m.secretCache.GetCSRFCookieEncoderHashKeys,
+		func() [][]byte { return nil },
 	)
 
 	for _, incomingProvider := range federationDomains {
@@ -88,37 +120,59 @@ func (m *Manager) SetProviders(federationDomains ...*provider.FederationDomainIs
 
 		tokenHMACKeyGetter := wrapGetter(incomingProvider.Issuer(), m.secretCache.GetTokenHMACKey)
 
-		timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+		timeoutsConfiguration := oidc.OIDCTimeoutsConfiguration(incomingProvider.TokenLifetimes())
 
 		// Use NullStorage for the authorize endpoint because we do not actually want to store anything until
 		// the upstream callback endpoint is called later.
-		oauthHelperWithNullStorage := oidc.FositeOauth2Helper(oidc.NullStorage{}, issuer, tokenHMACKeyGetter, nil, timeoutsConfiguration)
+		oauthHelperWithNullStorage := oidc.FositeOauth2Helper(oidc.NullStorage{}, issuer, tokenHMACKeyGetter, nil, nil, nil, nil, timeoutsConfiguration)
 
 		// For all the other endpoints, make another oauth helper with exactly the same settings except use real storage.
-		oauthHelperWithKubeStorage := oidc.FositeOauth2Helper(oidc.NewKubeStorage(m.secretsClient, timeoutsConfiguration), issuer, tokenHMACKeyGetter, m.dynamicJWKSProvider, timeoutsConfiguration)
+		kubeStorage := oidc.NewKubeStorage(m.secretsClient, m.machineClients, timeoutsConfiguration)
+		oauthHelperWithKubeStorage := oidc.FositeOauth2Helper(kubeStorage, issuer, tokenHMACKeyGetter, m.dynamicJWKSProvider, m.clusters, m.trustedClusters, m.machineClients, timeoutsConfiguration)
 
 		var upstreamStateEncoder = dynamiccodec.New(
 			timeoutsConfiguration.UpstreamStateParamLifespan,
-			wrapGetter(incomingProvider.Issuer(), m.secretCache.GetStateEncoderHashKey),
-			wrapGetter(incomingProvider.Issuer(), m.secretCache.GetStateEncoderBlockKey),
+			wrapKeysGetter(incomingProvider.Issuer(), m.secretCache.GetStateEncoderHashKeys),
+			wrapKeysGetter(incomingProvider.Issuer(), m.secretCache.GetStateEncoderBlockKeys),
 		)
 
+		getBranding := func() *branding.Branding { return m.brandings.GetBranding(issuer) }
+
 		m.providerHandlers[(issuerHostWithPath + oidc.WellKnownEndpointPath)] = discovery.NewHandler(issuer)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.JWKSEndpointPath)] = jwks.NewHandler(issuer, m.dynamicJWKSProvider)
 
-		m.providerHandlers[(issuerHostWithPath + oidc.PinnipedIDPsPathV1Alpha1)] = idpdiscovery.NewHandler(m.upstreamIDPs)
+		m.providerHandlers[(issuerHostWithPath + oidc.PinnipedIDPsPathV1Alpha1)] = idpdiscovery.NewHandler(m.upstreamIDPs, incomingProvider.IdentityProviders())
 
 		m.providerHandlers[(issuerHostWithPath + oidc.AuthorizationEndpointPath)] = auth.NewHandler(
 			issuer,
 			m.upstreamIDPs,
+			incomingProvider.IdentityProviders(),
 			oauthHelperWithNullStorage,
 			oauthHelperWithKubeStorage,
+			kubeStorage,
 			csrftoken.Generate,
 			pkce.Generate,
 			nonce.Generate,
 			upstreamStateEncoder,
 			csrfCookieEncoder,
+			m.loginLimiter,
+		)
+
+		m.providerHandlers[(issuerHostWithPath + oidc.PushedAuthorizationRequestEndpointPath)] = par.NewHandler(
+			issuer,
+			oauthHelperWithKubeStorage.(par.OAuthHelper),
+			kubeStorage,
+			timeoutsConfiguration.PushedAuthorizationRequestLifespan,
+			par.GenerateReference,
+		)
+
+		m.providerHandlers[(issuerHostWithPath + oidc.ChooseIDPEndpointPath)] = chooseidp.NewHandler(
+			issuer,
+			m.upstreamIDPs,
+			incomingProvider.IdentityProviders(),
+			upstreamStateEncoder,
+			getBranding,
 		)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.CallbackEndpointPath)] = callback.NewHandler(
@@ -127,10 +181,21 @@ func (m *Manager) SetProviders(federationDomains ...*provider.FederationDomainIs
 			upstreamStateEncoder,
 			csrfCookieEncoder,
 			issuer+oidc.CallbackEndpointPath,
+			getBranding,
+		)
+
+		m.providerHandlers[(issuerHostWithPath + oidc.LoginEndpointPath)] = login.NewHandler(
+			m.upstreamIDPs,
+			oauthHelperWithKubeStorage,
+			upstreamStateEncoder,
+			csrfCookieEncoder,
+			m.loginLimiter,
+			getBranding,
 		)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.TokenEndpointPath)] = token.NewHandler(
 			oauthHelperWithKubeStorage,
+			timeoutsConfiguration.AbsoluteSessionLifespan,
 		)
 
 		plog.Debug("oidc provider manager added or updated issuer", "issuer", issuer)
@@ -139,27 +204,50 @@ func (m *Manager) SetProviders(federationDomains ...*provider.FederationDomainIs
 
 // ServeHTTP implements the http.Handler interface.
 func (m *Manager) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
-	requestHandler := m.findHandler(req)
+	requestHandler, issuer, endpoint := m.findHandler(req)
 
 	plog.Debug(
 		"oidc provider manager examining request",
 		"method", req.Method,
 		"host", req.Host,
 		"path", req.URL.Path,
+		"sourceIP", sourceip.FromRequest(req),
 		"foundMatchingIssuer", requestHandler != nil,
 	)
 
 	if requestHandler == nil {
-		requestHandler = m.nextHandler // couldn't find an issuer to handle the request
+		m.nextHandler.ServeHTTP(resp, req) // couldn't find an issuer to handle the request
+		return
 	}
-	requestHandler.ServeHTTP(resp, req)
+	req, span := tracing.StartHTTPServerSpan(req, endpoint, attribute.String("pinniped.federation_domain", issuer))
+	code := serveWithMetrics(requestHandler, issuer, endpoint, resp, auditlog.WithRequest(req, issuer))
+	tracing.EndHTTPSpan(span, code)
 }
 
-func (m *Manager) findHandler(req *http.Request) http.Handler {
+// findHandler returns the handler for the request, the issuer of the FederationDomain which it belongs to, and the
+// path of the endpoint relative to that issuer.
+func (m *Manager) findHandler(req *http.Request) (http.Handler, string, string) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.providerHandlers[strings.ToLower(req.Host)+"/"+req.URL.Path]
+	key := strings.ToLower(req.Host) + "/" + req.URL.Path
+	handler := m.providerHandlers[key]
+	if handler == nil {
+		return nil, "", ""
+	}
+
+	// Issuer paths may be nested, so the longest issuer which is a prefix of the request is the one which handles it.
+	issuer, longest := "", -1
+	for _, p := range m.providers {
+		prefix := strings.ToLower(p.IssuerHost()) + "/" + p.IssuerPath()
+		if strings.HasPrefix(key, prefix+"/") && len(prefix) > longest {
+			issuer, longest = p.Issuer(), len(prefix)
+		}
+	}
+	if longest < 0 {
+		return handler, "", key // should not happen, since the handlers were added for the providers
+	}
+	return handler, issuer, key[longest:]
 }
 
 func wrapGetter(issuer string, getter func(string) []byte) func() []byte {
@@ -167,3 +255,9 @@ func wrapGetter(issuer string, getter func(string) []byte) func() []byte {
 		return getter(issuer)
 	}
 }
+
+func wrapKeysGetter(issuer string, getter func(string) [][]byte) func() [][]byte {
+	return func() [][]byte {
+		return getter(issuer)
+	}
+}
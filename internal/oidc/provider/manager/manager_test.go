@@ -14,6 +14,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"go.pinniped.dev/internal/secret"
 
@@ -21,12 +22,18 @@ import (
 	"github.com/stretchr/testify/require"
 	"gopkg.in/square/go-jose.v2"
 	"k8s.io/client-go/kubernetes/fake"
+	metricstestutil "k8s.io/component-base/metrics/testutil"
 
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
 	"go.pinniped.dev/internal/oidc/discovery"
 	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/oidctestutil"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
@@ -137,6 +144,22 @@ func TestManager(t *testing.T) {
 			return csrfCookie.Value, redirectStateParam
 		}
 
+		requirePushedAuthorizationRequestToBeHandled := func(requestIssuer, requestBody string) {
+			recorder := httptest.NewRecorder()
+
+			subject.ServeHTTP(recorder, newPostRequest(requestIssuer+oidc.PushedAuthorizationRequestEndpointPath, requestBody))
+
+			r.False(fallbackHandlerWasCalled)
+
+			// Minimal check to ensure that the right endpoint was called
+			r.Equal(http.StatusCreated, recorder.Code)
+			var response struct {
+				RequestURI string `json:"request_uri"`
+			}
+			r.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+			r.True(strings.HasPrefix(response.RequestURI, oidc.PushedAuthorizationRequestURIPrefix), "unexpected request_uri %s", response.RequestURI)
+		}
+
 		requireCallbackRequestToBeHandled := func(requestIssuer, requestURLSuffix, csrfCookieValue string) string {
 			recorder := httptest.NewRecorder()
 
@@ -265,17 +288,17 @@ func TestManager(t *testing.T) {
 			secretsClient := kubeClient.CoreV1().Secrets("some-namespace")
 
 			cache := secret.Cache{}
-			cache.SetCSRFCookieEncoderHashKey([]byte("fake-csrf-hash-secret"))
+			cache.SetCSRFCookieEncoderHashKeys([][]byte{[]byte("fake-csrf-hash-secret")})
 
 			cache.SetTokenHMACKey(issuer1, []byte("some secret 1 - must have at least 32 bytes"))
-			cache.SetStateEncoderHashKey(issuer1, []byte("some-state-encoder-hash-key-1"))
-			cache.SetStateEncoderBlockKey(issuer1, []byte("16-bytes-STATE01"))
+			cache.SetStateEncoderHashKeys(issuer1, [][]byte{[]byte("some-state-encoder-hash-key-1")})
+			cache.SetStateEncoderBlockKeys(issuer1, [][]byte{[]byte("16-bytes-STATE01")})
 
 			cache.SetTokenHMACKey(issuer2, []byte("some secret 2 - must have at least 32 bytes"))
-			cache.SetStateEncoderHashKey(issuer2, []byte("some-state-encoder-hash-key-2"))
-			cache.SetStateEncoderBlockKey(issuer2, []byte("16-bytes-STATE02"))
+			cache.SetStateEncoderHashKeys(issuer2, [][]byte{[]byte("some-state-encoder-hash-key-2")})
+			cache.SetStateEncoderBlockKeys(issuer2, [][]byte{[]byte("16-bytes-STATE02")})
 
-			subject = NewManager(nextHandler, dynamicJWKSProvider, idpLister, &cache, secretsClient)
+			subject = NewManager(nextHandler, dynamicJWKSProvider, idpLister, clusteraudience.NewDynamicClusterAudienceProvider(), trustedcluster.NewDynamicTrustedClusterProvider(), clientregistry.NewDynamicMachineClientProvider(), branding.NewDynamicBrandingProvider(), &cache, secretsClient, loginlimiter.New(secretsClient, time.Now, loginlimiter.Config{}))
 		})
 
 		when("given no providers via SetProviders()", func() {
@@ -284,6 +307,10 @@ func TestManager(t *testing.T) {
 				subject.ServeHTTP(httptest.NewRecorder(), newGetRequest("/anything"))
 				r.True(fallbackHandlerWasCalled)
 			})
+
+			it("is ready because there are no keys to wait for", func() {
+				r.NoError(subject.CheckKeys(nil))
+			})
 		})
 
 		newTestJWK := func(keyID string) *jose.JSONWebKey {
@@ -343,6 +370,9 @@ func TestManager(t *testing.T) {
 				"redirect_uri":          []string{downstreamRedirectURL},
 			}.Encode()
 
+			requirePushedAuthorizationRequestToBeHandled(issuer1, strings.TrimPrefix(authRequestParams, "?"))
+			requirePushedAuthorizationRequestToBeHandled(issuer2, strings.TrimPrefix(authRequestParams, "?"))
+
 			requireAuthorizationRequestToBeHandled(issuer1, authRequestParams, upstreamIDPAuthorizationURL)
 			requireAuthorizationRequestToBeHandled(issuer2, authRequestParams, upstreamIDPAuthorizationURL)
 
@@ -378,9 +408,9 @@ func TestManager(t *testing.T) {
 
 		when("given some valid providers via SetProviders()", func() {
 			it.Before(func() {
-				p1, err := provider.NewFederationDomainIssuer(issuer1)
+				p1, err := provider.NewFederationDomainIssuer(issuer1, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
-				p2, err := provider.NewFederationDomainIssuer(issuer2)
+				p2, err := provider.NewFederationDomainIssuer(issuer2, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 				subject.SetProviders(p1, p2)
 
@@ -392,7 +422,7 @@ func TestManager(t *testing.T) {
 					issuer1: newTestJWK(issuer1KeyID),
 					issuer2: newTestJWK(issuer2KeyID),
 				}
-				dynamicJWKSProvider.SetIssuerToJWKSMap(jwksMap, activeJWK)
+				dynamicJWKSProvider.SetIssuerToJWKSMap(jwksMap, activeJWK, nil)
 			})
 
 			it("sends all non-matching host requests to the nextHandler", func() {
@@ -417,13 +447,46 @@ func TestManager(t *testing.T) {
 			it("routes matching requests to the appropriate provider", func() {
 				requireRoutesMatchingRequestsToAppropriateProvider()
 			})
+
+			it("is ready when the keys of all providers are loaded", func() {
+				r.NoError(subject.CheckKeys(nil))
+			})
+
+			it("is not ready when the keys of a provider are not loaded yet", func() {
+				dynamicJWKSProvider.SetIssuerToJWKSMap(
+					map[string]*jose.JSONWebKeySet{issuer1: {Keys: []jose.JSONWebKey{*newTestJWK(issuer1KeyID)}}},
+					map[string]*jose.JSONWebKey{issuer1: newTestJWK(issuer1KeyID)},
+					nil,
+				)
+				r.EqualError(subject.CheckKeys(nil), `FederationDomain "`+issuer2+`" is missing its JWKS, signing key`)
+			})
+
+			it("counts the requests for each endpoint of each provider, but not the requests for the nextHandler", func() {
+				countRequests := func(issuer, endpoint, code string) float64 {
+					count, err := metricstestutil.GetCounterMetricValue(requests.WithLabelValues(issuer, endpoint, code))
+					r.NoError(err)
+					return count
+				}
+				issuer1Before := countRequests(issuer1, oidc.WellKnownEndpointPath, "200")
+				issuer2Before := countRequests(issuer2, oidc.WellKnownEndpointPath, "200")
+				issuer2JWKSBefore := countRequests(issuer2, oidc.JWKSEndpointPath, "200")
+
+				subject.ServeHTTP(httptest.NewRecorder(), newGetRequest(issuer2+oidc.WellKnownEndpointPath))
+				subject.ServeHTTP(httptest.NewRecorder(), newGetRequest(issuer2DifferentCaseHostname+oidc.WellKnownEndpointPath))
+				subject.ServeHTTP(httptest.NewRecorder(), newGetRequest(issuer2+oidc.JWKSEndpointPath))
+				subject.ServeHTTP(httptest.NewRecorder(), newGetRequest(issuer1+"/unhandled-sub-path"))
+
+				r.Equal(issuer1Before, countRequests(issuer1, oidc.WellKnownEndpointPath, "200"))
+				r.Equal(issuer2Before+2, countRequests(issuer2, oidc.WellKnownEndpointPath, "200"))
+				r.Equal(issuer2JWKSBefore+1, countRequests(issuer2, oidc.JWKSEndpointPath, "200"))
+			})
 		})
 
 		when("given the same valid providers as arguments to SetProviders() in reverse order", func() {
 			it.Before(func() {
-				p1, err := provider.NewFederationDomainIssuer(issuer1)
+				p1, err := provider.NewFederationDomainIssuer(issuer1, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
-				p2, err := provider.NewFederationDomainIssuer(issuer2)
+				p2, err := provider.NewFederationDomainIssuer(issuer2, provider.FederationDomainIdentityProviders{}, provider.FederationDomainTokenLifetimes{})
 				r.NoError(err)
 				subject.SetProviders(p2, p1)
 
@@ -435,7 +498,7 @@ func TestManager(t *testing.T) {
 					issuer1: newTestJWK(issuer1KeyID),
 					issuer2: newTestJWK(issuer2KeyID),
 				}
-				dynamicJWKSProvider.SetIssuerToJWKSMap(jwksMap, activeJWK)
+				dynamicJWKSProvider.SetIssuerToJWKSMap(jwksMap, activeJWK, nil)
 			})
 
 			it("still routes matching requests to the appropriate provider", func() {
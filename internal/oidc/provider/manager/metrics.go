@@ -0,0 +1,82 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+//nolint:gochecknoglobals
+var (
+	// The label values are limited to the issuers of the FederationDomains, the paths of their endpoints, and the
+	// HTTP status codes which the handlers write, so that requests for arbitrary paths cannot create new series.
+	requests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "http_requests_total",
+			Help:           "Number of requests to the endpoints of each FederationDomain, by HTTP status code.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"federation_domain", "endpoint", "code"},
+	)
+
+	requestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "http_request_duration_seconds",
+			Help:           "How long the endpoints of each FederationDomain took to handle requests.",
+			Buckets:        metrics.ExponentialBuckets(0.005, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"federation_domain", "endpoint"},
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(requests, requestDuration)
+}
+
+// statusRecorder remembers the status code which a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// serveWithMetrics calls the handler of an endpoint of a FederationDomain, counts the request, and returns the status
+// code of the response.
+func serveWithMetrics(handler http.Handler, issuer, endpoint string, resp http.ResponseWriter, req *http.Request) int {
+	recorder := &statusRecorder{ResponseWriter: resp}
+	start := time.Now()
+	handler.ServeHTTP(recorder, req)
+
+	code := recorder.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	requests.WithLabelValues(issuer, endpoint, strconv.Itoa(code)).Inc()
+	requestDuration.WithLabelValues(issuer, endpoint).Observe(time.Since(start).Seconds())
+	return code
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CheckKeys returns an error which names each FederationDomain that cannot serve logins yet, because the keys which
+// its endpoints need have not been loaded by the controllers.
+func (m *Manager) CheckKeys(_ *http.Request) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.providers) == 0 {
+		return nil
+	}
+
+	var problems []string
+	if len(m.secretCache.GetCSRFCookieEncoderHashKeys()) == 0 {
+		problems = append(problems, "CSRF cookie keys are not loaded")
+	}
+	for _, federationDomain := range m.providers {
+		issuer := federationDomain.Issuer()
+		var missing []string
+		if jwks, _ := m.dynamicJWKSProvider.GetJWKS(issuer); jwks == nil {
+			missing = append(missing, "JWKS")
+		}
+		if signer, err := m.dynamicJWKSProvider.GetSigner(issuer); signer == nil || err != nil {
+			missing = append(missing, "signing key")
+		}
+		if len(m.secretCache.GetTokenHMACKey(issuer)) == 0 {
+			missing = append(missing, "token HMAC key")
+		}
+		if len(m.secretCache.GetStateEncoderHashKeys(issuer)) == 0 || len(m.secretCache.GetStateEncoderBlockKeys(issuer)) == 0 {
+			missing = append(missing, "state keys")
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("FederationDomain %q is missing its %s", issuer, strings.Join(missing, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
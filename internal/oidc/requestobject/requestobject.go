@@ -0,0 +1,152 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package requestobject verifies the signed request objects which clients may use to send their authorization
+// request params, as described in RFC 9101.
+package requestobject
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// SigningAlgorithms returns the JWS algorithms which request objects may be signed with. Unsigned request objects
+// are never accepted.
+func SigningAlgorithms() []string {
+	return []string{
+		string(jose.RS256), string(jose.RS384), string(jose.RS512),
+		string(jose.PS256), string(jose.PS384), string(jose.PS512),
+		string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	}
+}
+
+// Params verifies a request object which the client sent to the authorization server of the given issuer and
+// returns the authorization request params in it. Any params which the client sent next to the request object are
+// ignored, as required by RFC 9101, except for the client_id.
+func Params(client fosite.Client, issuer string, requestObject string, now time.Time) (url.Values, error) {
+	clientID := client.GetID()
+
+	oidcClient, ok := client.(fosite.OpenIDConnectClient)
+	if !ok || oidcClient.GetJSONWebKeys() == nil || len(oidcClient.GetJSONWebKeys().Keys) == 0 {
+		return nil, errors.WithStack(fosite.ErrRequestNotSupported.WithHint(
+			"The client has no JSON Web Keys registered, so it cannot use request objects."))
+	}
+
+	token, err := jwt.ParseSigned(requestObject)
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHint(
+			"The request object is not a signed JWT.").WithWrap(err).WithDebug(err.Error()))
+	}
+	if len(token.Headers) != 1 {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHint(
+			"The request object must have exactly one signature."))
+	}
+
+	header := token.Headers[0]
+	if !isSupportedAlgorithm(header.Algorithm) {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHintf(
+			"The request object uses unsupported signing algorithm '%s'.", header.Algorithm))
+	}
+	if wantAlgorithm := oidcClient.GetRequestObjectSigningAlgorithm(); wantAlgorithm != "" && wantAlgorithm != header.Algorithm {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHintf(
+			"The request object uses signing algorithm '%s', but the client must use '%s'.", header.Algorithm, wantAlgorithm))
+	}
+
+	claims, rawClaims, err := verify(token, oidcClient.GetJSONWebKeys(), header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Expiry == nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHint(
+			"The request object must have an expiration time."))
+	}
+	err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   clientID,
+		Audience: jwt.Audience{issuer},
+		Time:     now,
+	}, jwt.DefaultLeeway)
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHintf(
+			"The claims of the request object are not valid: %s.", err).WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return paramsFromClaims(rawClaims, clientID)
+}
+
+func isSupportedAlgorithm(algorithm string) bool {
+	for _, supported := range SigningAlgorithms() {
+		if algorithm == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks the signature of the token with the client's keys. The key ID in the header chooses the key, or all
+// keys are tried when there is none.
+func verify(token *jwt.JSONWebToken, keySet *jose.JSONWebKeySet, keyID string) (*jwt.Claims, map[string]interface{}, error) {
+	keys := keySet.Keys
+	if keyID != "" {
+		keys = keySet.Key(keyID)
+	}
+
+	for _, key := range keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		var claims jwt.Claims
+		var rawClaims map[string]interface{}
+		if err := token.Claims(key.Public().Key, &claims, &rawClaims); err == nil {
+			return &claims, rawClaims, nil
+		}
+	}
+
+	return nil, nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHint(
+		"The signature of the request object could not be verified with any of the client's JSON Web Keys."))
+}
+
+func paramsFromClaims(rawClaims map[string]interface{}, clientID string) (url.Values, error) {
+	params := url.Values{}
+	for name, value := range rawClaims {
+		switch name {
+		case "iss", "aud", "exp", "nbf", "iat", "jti":
+			// These claims are about the request object itself, not about the authorization request.
+			continue
+		case "request", "request_uri":
+			return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHintf(
+				"The request object must not contain the '%s' param.", name))
+		case "client_id":
+			if value != clientID {
+				return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHint(
+					"The client_id in the request object does not match the client."))
+			}
+		}
+
+		switch v := value.(type) {
+		case string:
+			params.Set(name, v)
+		case float64:
+			params.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			params.Set(name, strconv.FormatBool(v))
+		default:
+			// Params with structured values, such as the OIDC claims param, are sent as JSON.
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.WithStack(fosite.ErrInvalidRequestObject.WithHintf(
+					"The '%s' param in the request object could not be encoded.", name).WithWrap(err).WithDebug(err.Error()))
+			}
+			params.Set(name, string(encoded))
+		}
+	}
+	params.Set("client_id", clientID)
+	return params, nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package requestobject
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"go.pinniped.dev/internal/oidc/clientregistry"
+)
+
+func TestParams(t *testing.T) {
+	const (
+		issuer   = "https://issuer.example.com/some/path"
+		clientID = "some-client"
+	)
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	clientWithKeys := func(keys ...jose.JSONWebKey) fosite.Client {
+		client := &clientregistry.Client{}
+		client.DefaultClient = &fosite.DefaultClient{ID: clientID}
+		client.JSONWebKeys = &jose.JSONWebKeySet{Keys: keys}
+		return client
+	}
+	clientJWK := jose.JSONWebKey{Key: clientKey.Public(), KeyID: "client-key", Algorithm: "ES256", Use: "sig"}
+
+	sign := func(t *testing.T, key *ecdsa.PrivateKey, keyID string, claims ...interface{}) string {
+		t.Helper()
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: key},
+			(&jose.SignerOptions{}).WithType("oauth-authz-req+jwt").WithHeader("kid", keyID),
+		)
+		require.NoError(t, err)
+		builder := jwt.Signed(signer)
+		for _, c := range claims {
+			builder = builder.Claims(c)
+		}
+		requestObject, err := builder.CompactSerialize()
+		require.NoError(t, err)
+		return requestObject
+	}
+	validClaims := jwt.Claims{
+		Issuer:   clientID,
+		Audience: jwt.Audience{issuer},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Minute)),
+		IssuedAt: jwt.NewNumericDate(now),
+	}
+
+	tests := []struct {
+		name          string
+		client        fosite.Client
+		requestObject func(t *testing.T) string
+		wantParams    url.Values
+		wantErr       string
+		wantHint      string
+	}{
+		{
+			name:   "valid request object",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "client-key", validClaims, map[string]interface{}{
+					"client_id":     clientID,
+					"response_type": "code",
+					"scope":         "openid offline_access",
+					"max_age":       300,
+					"claims":        map[string]interface{}{"id_token": map[string]interface{}{"groups": nil}},
+				})
+			},
+			wantParams: url.Values{
+				"client_id":     []string{clientID},
+				"response_type": []string{"code"},
+				"scope":         []string{"openid offline_access"},
+				"max_age":       []string{"300"},
+				"claims":        []string{`{"id_token":{"groups":null}}`},
+			},
+		},
+		{
+			name:   "the key is found without a key ID",
+			client: clientWithKeys(jose.JSONWebKey{Key: otherKey.Public(), KeyID: "other-key"}, clientJWK),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "", validClaims, map[string]interface{}{"scope": "openid"})
+			},
+			wantParams: url.Values{"client_id": []string{clientID}, "scope": []string{"openid"}},
+		},
+		{
+			name:   "client without keys",
+			client: clientWithKeys(),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "client-key", validClaims)
+			},
+			wantErr:  "request_not_supported",
+			wantHint: "The client has no JSON Web Keys registered, so it cannot use request objects.",
+		},
+		{
+			name:          "not a JWT",
+			client:        clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string { return "not-a-jwt" },
+			wantErr:       "invalid_request_object",
+			wantHint:      "The request object is not a signed JWT.",
+		},
+		{
+			name:   "unsigned request object",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				return "eyJhbGciOiJub25lIn0.eyJpc3MiOiJzb21lLWNsaWVudCJ9."
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The request object uses unsupported signing algorithm 'none'.",
+		},
+		{
+			name: "signed with a different algorithm than the client requires",
+			client: func() fosite.Client {
+				client := clientWithKeys(clientJWK).(*clientregistry.Client)
+				client.RequestObjectSigningAlgorithm = "RS256"
+				return client
+			}(),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "client-key", validClaims)
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The request object uses signing algorithm 'ES256', but the client must use 'RS256'.",
+		},
+		{
+			name:   "signed with another key",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				return sign(t, otherKey, "client-key", validClaims)
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The signature of the request object could not be verified with any of the client's JSON Web Keys.",
+		},
+		{
+			name:   "no expiration time",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "client-key", jwt.Claims{Issuer: clientID, Audience: jwt.Audience{issuer}})
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The request object must have an expiration time.",
+		},
+		{
+			name:   "expired",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				claims := validClaims
+				claims.Expiry = jwt.NewNumericDate(now.Add(-2 * time.Minute))
+				return sign(t, clientKey, "client-key", claims)
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The claims of the request object are not valid: square/go-jose/jwt: validation failed, token is expired (exp).",
+		},
+		{
+			name:   "issued by another client",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				claims := validClaims
+				claims.Issuer = "other-client"
+				return sign(t, clientKey, "client-key", claims)
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The claims of the request object are not valid: square/go-jose/jwt: validation failed, invalid issuer claim (iss).",
+		},
+		{
+			name:   "meant for another issuer",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				claims := validClaims
+				claims.Audience = jwt.Audience{"https://other-issuer.example.com"}
+				return sign(t, clientKey, "client-key", claims)
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The claims of the request object are not valid: square/go-jose/jwt: validation failed, invalid audience claim (aud).",
+		},
+		{
+			name:   "another client_id",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "client-key", validClaims, map[string]interface{}{"client_id": "other-client"})
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The client_id in the request object does not match the client.",
+		},
+		{
+			name:   "nested request_uri",
+			client: clientWithKeys(clientJWK),
+			requestObject: func(t *testing.T) string {
+				return sign(t, clientKey, "client-key", validClaims, map[string]interface{}{"request_uri": "https://example.com"})
+			},
+			wantErr:  "invalid_request_object",
+			wantHint: "The request object must not contain the 'request_uri' param.",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			params, err := Params(test.client, issuer, test.requestObject(t), now)
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Equal(t, test.wantHint, fosite.ErrorToRFC6749Error(err).HintField)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantParams, params)
+		})
+	}
+}
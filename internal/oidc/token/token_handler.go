@@ -6,36 +6,104 @@ package token
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/ory/fosite"
-	"github.com/ory/fosite/handler/openid"
 
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/httputil/httperr"
 	"go.pinniped.dev/internal/oidc"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
 )
 
 func NewHandler(
 	oauthHelper fosite.OAuth2Provider,
+	absoluteSessionLifespan time.Duration,
 ) http.Handler {
 	return httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
-		var session openid.DefaultSession
-		accessRequest, err := oauthHelper.NewAccessRequest(r.Context(), r, &session)
+		session := psession.NewPinnipedSession()
+		accessRequest, err := oauthHelper.NewAccessRequest(r.Context(), r, session)
 		if err != nil {
 			plog.Info("token request error", oidc.FositeErrorForLog(err)...)
+			recordTokenRequestFailed(r, accessRequest, err)
 			oauthHelper.WriteAccessError(w, accessRequest, err)
 			return nil
 		}
 
-		accessResponse, err := oauthHelper.NewAccessResponse(r.Context(), accessRequest)
+		if err := limitToAbsoluteSessionLifespan(accessRequest, absoluteSessionLifespan, time.Now().UTC()); err != nil {
+			plog.Info("token request error", oidc.FositeErrorForLog(err)...)
+			recordTokenRequestFailed(r, accessRequest, err)
+			oauthHelper.WriteAccessError(w, accessRequest, err)
+			return nil
+		}
+
+		ctx := r.Context()
+		if accessRequest.GetGrantTypes().ExactOne("refresh_token") {
+			// Keep the refresh token which is being exchanged, so that reuse of it can be detected later.
+			ctx = refreshtoken.WithRotation(ctx, oidc.RefreshTokenSignature(accessRequest.GetRequestForm().Get("refresh_token")))
+		}
+
+		accessResponse, err := oauthHelper.NewAccessResponse(ctx, accessRequest)
 		if err != nil {
 			plog.Info("token response error", oidc.FositeErrorForLog(err)...)
+			recordTokenRequestFailed(r, accessRequest, err)
 			oauthHelper.WriteAccessError(w, accessRequest, err)
 			return nil
 		}
+		recordTokenIssued(r, accessRequest)
 
 		oauthHelper.WriteAccessResponse(w, accessRequest, accessResponse)
 
 		return nil
 	})
 }
+
+func recordTokenRequestFailed(r *http.Request, accessRequest fosite.AccessRequester, err error) {
+	event := oidc.AuditEvent(auditlog.TokenRequestFailed, accessRequest)
+	if accessRequest != nil {
+		event.GrantType = strings.Join(accessRequest.GetGrantTypes(), " ")
+	}
+	event.Reason = oidc.AuditReason(err)
+	auditlog.Record(r.Context(), event)
+}
+
+func recordTokenIssued(r *http.Request, accessRequest fosite.AccessRequester) {
+	grantTypes := accessRequest.GetGrantTypes()
+	eventType := auditlog.TokenIssued
+	switch {
+	case grantTypes.ExactOne(oidc.GrantTypeTokenExchange):
+		// The token exchange handler records the audience and the user of the subject token by itself.
+		return
+	case grantTypes.ExactOne("refresh_token"):
+		eventType = auditlog.TokenRefreshed
+	}
+	event := oidc.AuditEvent(eventType, accessRequest)
+	event.GrantType = strings.Join(grantTypes, " ")
+	auditlog.Record(r.Context(), event)
+}
+
+// limitToAbsoluteSessionLifespan makes sure that a session cannot be extended past its absolute lifespan, which
+// starts when the end user logged in with the upstream IDP. Fosite gives every refreshed refresh token a new
+// lifetime, so it would otherwise be possible to keep refreshing a session forever.
+func limitToAbsoluteSessionLifespan(accessRequest fosite.AccessRequester, absoluteSessionLifespan time.Duration, now time.Time) error {
+	session, ok := accessRequest.GetSession().(*psession.PinnipedSession)
+	if !ok || session.Fosite == nil || session.Fosite.Claims == nil || session.Fosite.Claims.AuthTime.IsZero() {
+		// Without an auth time there is nothing to limit, e.g. for sessions which were not started by an end user.
+		return nil
+	}
+
+	sessionEnd := session.Fosite.Claims.AuthTime.Add(absoluteSessionLifespan)
+	if !now.Before(sessionEnd) {
+		return fosite.ErrInvalidGrant.WithHint("The session has reached its maximum lifetime, so the end user must log in again.")
+	}
+
+	refreshTokenExpiresAt := session.GetExpiresAt(fosite.RefreshToken)
+	if !refreshTokenExpiresAt.IsZero() && refreshTokenExpiresAt.After(sessionEnd) {
+		session.SetExpiresAt(fosite.RefreshToken, sessionEnd)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ package token
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -22,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/handler/openid"
@@ -29,6 +31,7 @@ import (
 	"github.com/ory/fosite/token/jwt"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/square/go-jose.v2"
 	josejwt "gopkg.in/square/go-jose.v2/jwt"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,7 +48,11 @@ import (
 	"go.pinniped.dev/internal/fositestoragei"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
 	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
+	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/oidctestutil"
 )
@@ -58,6 +65,7 @@ const (
 	goodNonce            = "some-nonce-value-with-enough-bytes-to-exceed-min-allowed"
 	goodSubject          = "https://issuer?sub=some-subject"
 	goodUsername         = "some-username"
+	goodUpstreamName     = "some-upstream-idp"
 	goodGroups           = "group1,groups2"
 
 	hmacSecret = "this needs to be at least 32 characters to meet entropy requirements"
@@ -70,7 +78,7 @@ const (
 )
 
 var (
-	goodAuthTime        = time.Date(1, 2, 3, 4, 5, 6, 7, time.UTC)
+	goodAuthTime        = time.Now().UTC().Add(-1 * time.Hour).Truncate(time.Second) // must be recent, or else the session would have reached its maximum lifetime
 	goodRequestedAtTime = time.Date(7, 6, 5, 4, 3, 2, 1, time.UTC)
 
 	hmacSecretFunc = func() []byte {
@@ -142,6 +150,13 @@ var (
 		}
 	`)
 
+	fositeInactiveRefreshTokenErrorBody = here.Doc(`
+		{
+			"error":             "token_inactive",
+			"error_description": "Token is inactive because it is malformed, expired or otherwise invalid. Token validation failed."
+		}
+	`)
+
 	fositeReusedAuthCodeErrorBody = here.Doc(`
 		{
 			"error":             "invalid_grant",
@@ -610,6 +625,7 @@ func TestTokenExchange(t *testing.T) {
 
 		wantStatus               int
 		wantResponseBodyContains string
+		wantNoGroupsClaim        bool
 	}{
 		{
 			name:              "happy path",
@@ -617,6 +633,27 @@ func TestTokenExchange(t *testing.T) {
 			requestedAudience: "some-workload-cluster",
 			wantStatus:        http.StatusOK,
 		},
+		{
+			name:              "happy path for a cluster which does not get the groups claim",
+			authcodeExchange:  doValidAuthCodeExchange,
+			requestedAudience: "some-username-only-workload-cluster",
+			wantStatus:        http.StatusOK,
+			wantNoGroupsClaim: true,
+		},
+		{
+			name:                     "audience which is not configured for any cluster",
+			authcodeExchange:         doValidAuthCodeExchange,
+			requestedAudience:        "some-unknown-cluster",
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `"error":"invalid_target"`,
+		},
+		{
+			name:                     "user is not in any of the groups which the cluster allows",
+			authcodeExchange:         doValidAuthCodeExchange,
+			requestedAudience:        "some-restricted-workload-cluster",
+			wantStatus:               http.StatusForbidden,
+			wantResponseBodyContains: `the user is not a member of any group which may receive tokens for the audience 'some-restricted-workload-cluster'`,
+		},
 		{
 			name:                     "missing audience",
 			authcodeExchange:         doValidAuthCodeExchange,
@@ -799,7 +836,10 @@ func TestTokenExchange(t *testing.T) {
 			require.NoError(t, json.Unmarshal(parsedJWT.UnsafePayloadWithoutVerification(), &tokenClaims))
 
 			// Make sure that these are the only fields in the token.
-			idTokenFields := []string{"sub", "aud", "iss", "jti", "auth_time", "exp", "iat", "rat", "groups", "username"}
+			idTokenFields := []string{"sub", "aud", "iss", "jti", "auth_time", "exp", "iat", "rat", "username"}
+			if !test.wantNoGroupsClaim {
+				idTokenFields = append(idTokenFields, "groups")
+			}
 			require.ElementsMatch(t, idTokenFields, getMapKeys(tokenClaims))
 
 			// Assert that the returned token has expected claims values.
@@ -813,7 +853,9 @@ func TestTokenExchange(t *testing.T) {
 			require.Equal(t, goodSubject, tokenClaims["sub"])
 			require.Equal(t, goodIssuer, tokenClaims["iss"])
 			require.Equal(t, goodUsername, tokenClaims["username"])
-			require.Equal(t, goodGroups, tokenClaims["groups"])
+			if !test.wantNoGroupsClaim {
+				require.Equal(t, goodGroups, tokenClaims["groups"])
+			}
 
 			// Also assert that some are the same as the original downstream ID token.
 			requireClaimsAreEqual(t, "iss", claimsOfFirstIDToken, tokenClaims)       // issuer
@@ -837,6 +879,516 @@ func TestTokenExchange(t *testing.T) {
 	}
 }
 
+func TestServiceAccountTokenExchange(t *testing.T) {
+	serviceAccountSigningKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherSigningKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	const (
+		trustedIssuer      = "https://some-trusted-cluster.example.com"
+		serviceAccountName = "system:serviceaccount:some-namespace:some-name"
+	)
+
+	trustedClusters := trustedcluster.NewDynamicTrustedClusterProvider()
+	trustedClusters.SetTrustedClusters([]*trustedcluster.TrustedCluster{{
+		Name:   "some-trusted-cluster",
+		Issuer: trustedIssuer,
+		Verifier: coreosoidc.NewVerifier(
+			trustedIssuer,
+			&staticKeySet{publicKey: serviceAccountSigningKey.Public()},
+			&coreosoidc.Config{ClientID: "pinniped-supervisor", SupportedSigningAlgs: []string{coreosoidc.ES256}},
+		),
+	}})
+
+	serviceAccountToken := func(t *testing.T, key *ecdsa.PrivateKey, claims josejwt.Claims) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+		require.NoError(t, err)
+		token, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	happyClaims := func() josejwt.Claims {
+		return josejwt.Claims{
+			Issuer:   trustedIssuer,
+			Subject:  serviceAccountName,
+			Audience: josejwt.Audience{"pinniped-supervisor"},
+			Expiry:   josejwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+	}
+
+	tests := []struct {
+		name              string
+		subjectToken      func(t *testing.T) string
+		requestedAudience string
+
+		wantStatus               int
+		wantResponseBodyContains string
+	}{
+		{
+			name:              "happy path",
+			subjectToken:      func(t *testing.T) string { return serviceAccountToken(t, serviceAccountSigningKey, happyClaims()) },
+			requestedAudience: "some-workload-cluster",
+			wantStatus:        http.StatusOK,
+		},
+		{
+			name:                     "audience which is not configured for any cluster",
+			subjectToken:             func(t *testing.T) string { return serviceAccountToken(t, serviceAccountSigningKey, happyClaims()) },
+			requestedAudience:        "some-unknown-cluster",
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `"error":"invalid_target"`,
+		},
+		{
+			name: "token from an issuer which is not trusted",
+			subjectToken: func(t *testing.T) string {
+				claims := happyClaims()
+				claims.Issuer = "https://some-other-cluster.example.com"
+				return serviceAccountToken(t, serviceAccountSigningKey, claims)
+			},
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `the issuer 'https://some-other-cluster.example.com' of the subject_token is not a trusted cluster`,
+		},
+		{
+			name:                     "token signed by the wrong key",
+			subjectToken:             func(t *testing.T) string { return serviceAccountToken(t, otherSigningKey, happyClaims()) },
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `invalid subject_token`,
+		},
+		{
+			name: "token for the wrong audience",
+			subjectToken: func(t *testing.T) string {
+				claims := happyClaims()
+				claims.Audience = josejwt.Audience{"kubernetes"}
+				return serviceAccountToken(t, serviceAccountSigningKey, claims)
+			},
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `invalid subject_token`,
+		},
+		{
+			name: "expired token",
+			subjectToken: func(t *testing.T) string {
+				claims := happyClaims()
+				claims.Expiry = josejwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return serviceAccountToken(t, serviceAccountSigningKey, claims)
+			},
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `invalid subject_token`,
+		},
+		{
+			name: "token which is not for a ServiceAccount",
+			subjectToken: func(t *testing.T) string {
+				claims := happyClaims()
+				claims.Subject = "some-user"
+				return serviceAccountToken(t, serviceAccountSigningKey, claims)
+			},
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `invalid subject_token`,
+		},
+		{
+			name:                     "token which is not a JWT",
+			subjectToken:             func(t *testing.T) string { return "some-bogus-value" },
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `invalid subject_token`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secrets := fake.NewSimpleClientset().CoreV1().Secrets("some-namespace")
+			oauthStore := oidc.NewKubeStorage(secrets, nil, oidc.DefaultOIDCTimeoutsConfiguration())
+			_, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, goodIssuer, hmacSecretFunc, jwkProvider, workloadClusters(), trustedClusters, nil, oidc.DefaultOIDCTimeoutsConfiguration())
+			subject := NewHandler(oauthHelper, oidc.DefaultOIDCTimeoutsConfiguration().AbsoluteSessionLifespan)
+
+			request := happyTokenExchangeRequest(test.requestedAudience, test.subjectToken(t))
+			request.Form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:jwt")
+			req := httptest.NewRequest("POST", "/path/shouldn't/matter", body(request.Form).ReadCloser())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rsp := httptest.NewRecorder()
+
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+			if test.wantResponseBodyContains != "" {
+				require.Contains(t, rsp.Body.String(), test.wantResponseBodyContains)
+			}
+
+			// The remaining assertions apply only the the happy path.
+			if rsp.Code != http.StatusOK {
+				return
+			}
+
+			var responseBody map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &responseBody))
+			require.Equal(t, "N_A", responseBody["token_type"])
+			require.Equal(t, "urn:ietf:params:oauth:token-type:jwt", responseBody["issued_token_type"])
+
+			parsedJWT, err := jose.ParseSigned(responseBody["access_token"].(string))
+			require.NoError(t, err)
+			var tokenClaims map[string]interface{}
+			require.NoError(t, json.Unmarshal(parsedJWT.UnsafePayloadWithoutVerification(), &tokenClaims))
+
+			require.Equal(t, goodIssuer, tokenClaims["iss"])
+			require.Equal(t, []interface{}{test.requestedAudience}, tokenClaims["aud"])
+			require.Equal(t, trustedIssuer+"?sub=system%3Aserviceaccount%3Asome-namespace%3Asome-name", tokenClaims["sub"])
+			require.Equal(t, serviceAccountName+"@some-trusted-cluster", tokenClaims["username"])
+			require.Equal(t, []interface{}{
+				"system:serviceaccounts@some-trusted-cluster",
+				"system:serviceaccounts:some-namespace@some-trusted-cluster",
+			}, tokenClaims["groups"])
+
+			// The exchange does not store anything.
+			storedSecrets, err := secrets.List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Empty(t, storedSecrets.Items)
+		})
+	}
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	const (
+		machineClientID     = "some-machine-client"
+		machineClientSecret = "some-machine-client-secret"
+	)
+
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(machineClientSecret), bcrypt.MinCost)
+	require.NoError(t, err)
+	machineClients := clientregistry.NewDynamicMachineClientProvider()
+	machineClients.SetMachineClients([]*clientregistry.MachineClient{
+		clientregistry.NewMachineClient(machineClientID, hashedSecret, "some-machine-user", []string{"some-machine-group"}),
+	})
+
+	happyClientCredentialsForm := func() url.Values {
+		return url.Values{
+			"grant_type": {"client_credentials"},
+			"scope":      {"openid pinniped:request-audience"},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		form         func() url.Values
+		clientID     string
+		clientSecret string
+
+		wantStatus               int
+		wantResponseBodyContains string
+	}{
+		{
+			name:         "happy path",
+			form:         happyClientCredentialsForm,
+			clientID:     machineClientID,
+			clientSecret: machineClientSecret,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:                     "wrong client secret",
+			form:                     happyClientCredentialsForm,
+			clientID:                 machineClientID,
+			clientSecret:             "wrong-secret",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `"error":"invalid_client"`,
+		},
+		{
+			name:                     "unknown client",
+			form:                     happyClientCredentialsForm,
+			clientID:                 "some-unknown-client",
+			clientSecret:             machineClientSecret,
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `"error":"invalid_client"`,
+		},
+		{
+			name: "scope which the client is not allowed",
+			form: func() url.Values {
+				form := happyClientCredentialsForm()
+				form.Set("scope", "openid offline_access")
+				return form
+			},
+			clientID:                 machineClientID,
+			clientSecret:             machineClientSecret,
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `"error":"invalid_scope"`,
+		},
+		{
+			name: "the public pinniped-cli client",
+			form: func() url.Values {
+				form := happyClientCredentialsForm()
+				form.Set("client_id", goodClient)
+				return form
+			},
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `The OAuth 2.0 Client is marked as public and is thus not allowed to use authorization grant 'client_credentials'.`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secrets := fake.NewSimpleClientset().CoreV1().Secrets("some-namespace")
+			oauthStore := oidc.NewKubeStorage(secrets, machineClients, oidc.DefaultOIDCTimeoutsConfiguration())
+			_, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, goodIssuer, hmacSecretFunc, jwkProvider, workloadClusters(), nil, machineClients, oidc.DefaultOIDCTimeoutsConfiguration())
+			subject := NewHandler(oauthHelper, oidc.DefaultOIDCTimeoutsConfiguration().AbsoluteSessionLifespan)
+
+			postForm := func(form url.Values) *httptest.ResponseRecorder {
+				req := httptest.NewRequest("POST", "/path/shouldn't/matter", body(form).ReadCloser())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				if test.clientID != "" {
+					req.SetBasicAuth(test.clientID, test.clientSecret)
+				}
+				rsp := httptest.NewRecorder()
+				subject.ServeHTTP(rsp, req)
+				t.Logf("response body: %q", rsp.Body.String())
+				return rsp
+			}
+
+			rsp := postForm(test.form())
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+			if test.wantResponseBodyContains != "" {
+				require.Contains(t, rsp.Body.String(), test.wantResponseBodyContains)
+			}
+
+			// The remaining assertions apply only the the happy path.
+			if rsp.Code != http.StatusOK {
+				return
+			}
+
+			var responseBody map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &responseBody))
+			require.Equal(t, "bearer", responseBody["token_type"])
+			require.Equal(t, "openid pinniped:request-audience", responseBody["scope"])
+			require.NotContains(t, responseBody, "refresh_token")
+			require.NotContains(t, responseBody, "id_token")
+
+			// The access token can be exchanged for a cluster token which has the identity of the machine client.
+			exchangeForm := happyTokenExchangeRequest("some-workload-cluster", responseBody["access_token"].(string)).Form
+			exchangeForm.Del("client_id")
+			rsp = postForm(exchangeForm)
+			require.Equal(t, http.StatusOK, rsp.Code)
+
+			var exchangeResponseBody map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &exchangeResponseBody))
+			parsedJWT, err := jose.ParseSigned(exchangeResponseBody["access_token"].(string))
+			require.NoError(t, err)
+			var tokenClaims map[string]interface{}
+			require.NoError(t, json.Unmarshal(parsedJWT.UnsafePayloadWithoutVerification(), &tokenClaims))
+
+			require.Equal(t, goodIssuer, tokenClaims["iss"])
+			require.Equal(t, []interface{}{"some-workload-cluster"}, tokenClaims["aud"])
+			require.Equal(t, goodIssuer+"?client_id=some-machine-client", tokenClaims["sub"])
+			require.Equal(t, "some-machine-user", tokenClaims["username"])
+			require.Equal(t, []interface{}{"some-machine-group"}, tokenClaims["groups"])
+		})
+	}
+}
+
+func TestDelegatedTokenExchange(t *testing.T) {
+	const (
+		portalClientID    = "some-support-portal"
+		otherPortalID     = "some-untrusted-portal"
+		nonDelegatingID   = "some-machine-client"
+		machineClientPass = "some-machine-client-secret"
+	)
+
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(machineClientPass), bcrypt.MinCost)
+	require.NoError(t, err)
+	newMachineClient := func(id string, groups []string, allowDelegation bool) *clientregistry.MachineClient {
+		machineClient := clientregistry.NewMachineClient(id, hashedSecret, id+"-user", groups)
+		machineClient.AllowDelegation = allowDelegation
+		return machineClient
+	}
+	machineClients := clientregistry.NewDynamicMachineClientProvider()
+	machineClients.SetMachineClients([]*clientregistry.MachineClient{
+		newMachineClient(portalClientID, []string{"support-portals"}, true),
+		newMachineClient(otherPortalID, []string{"other-group"}, true),
+		newMachineClient(nonDelegatingID, []string{"support-portals"}, false),
+	})
+
+	tests := []struct {
+		name              string
+		clientID          string
+		actorTokenOf      string
+		requestedAudience string
+		modifyParams      func(params url.Values)
+
+		wantStatus               int
+		wantResponseBodyContains string
+	}{
+		{
+			name:              "happy path",
+			clientID:          portalClientID,
+			actorTokenOf:      portalClientID,
+			requestedAudience: "some-delegation-workload-cluster",
+			wantStatus:        http.StatusOK,
+		},
+		{
+			name:                     "client which is not allowed to act on behalf of users",
+			clientID:                 nonDelegatingID,
+			actorTokenOf:             nonDelegatingID,
+			requestedAudience:        "some-delegation-workload-cluster",
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `The client is not allowed to act on behalf of users.`,
+		},
+		{
+			name:                     "actor token of another client",
+			clientID:                 portalClientID,
+			actorTokenOf:             nonDelegatingID,
+			requestedAudience:        "some-delegation-workload-cluster",
+			wantStatus:               http.StatusUnauthorized,
+			wantResponseBodyContains: `the actor_token was not issued to the client`,
+		},
+		{
+			name:                     "actor which is not in the allowed actor groups",
+			clientID:                 otherPortalID,
+			actorTokenOf:             otherPortalID,
+			requestedAudience:        "some-delegation-workload-cluster",
+			wantStatus:               http.StatusForbidden,
+			wantResponseBodyContains: `the actor is not a member of any group which may act on behalf of users for the audience 'some-delegation-workload-cluster'`,
+		},
+		{
+			name:                     "cluster which does not allow any actors",
+			clientID:                 portalClientID,
+			actorTokenOf:             portalClientID,
+			requestedAudience:        "some-workload-cluster",
+			wantStatus:               http.StatusForbidden,
+			wantResponseBodyContains: `the actor is not a member of any group which may act on behalf of users for the audience 'some-workload-cluster'`,
+		},
+		{
+			name:              "invalid actor token",
+			clientID:          portalClientID,
+			actorTokenOf:      portalClientID,
+			requestedAudience: "some-delegation-workload-cluster",
+			modifyParams: func(params url.Values) {
+				params.Set("actor_token", "some-bogus-value")
+			},
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `"error":"invalid_token"`,
+		},
+		{
+			name:              "unsupported actor_token_type",
+			clientID:          portalClientID,
+			actorTokenOf:      portalClientID,
+			requestedAudience: "some-delegation-workload-cluster",
+			modifyParams: func(params url.Values) {
+				params.Set("actor_token_type", "urn:ietf:params:oauth:token-type:jwt")
+			},
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `unsupported actor_token_type parameter value, must be 'urn:ietf:params:oauth:token-type:access_token'`,
+		},
+		{
+			name:              "actor_token_type without actor_token",
+			clientID:          portalClientID,
+			actorTokenOf:      portalClientID,
+			requestedAudience: "some-delegation-workload-cluster",
+			modifyParams: func(params url.Values) {
+				params.Del("actor_token")
+			},
+			wantStatus:               http.StatusBadRequest,
+			wantResponseBodyContains: `missing actor_token parameter`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secrets := fake.NewSimpleClientset().CoreV1().Secrets("some-namespace")
+			oauthStore := oidc.NewKubeStorage(secrets, machineClients, oidc.DefaultOIDCTimeoutsConfiguration())
+			_, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, goodIssuer, hmacSecretFunc, jwkProvider, workloadClusters(), nil, machineClients, oidc.DefaultOIDCTimeoutsConfiguration())
+			subject := NewHandler(oauthHelper, oidc.DefaultOIDCTimeoutsConfiguration().AbsoluteSessionLifespan)
+
+			post := func(form url.Values, clientID string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest("POST", "/path/shouldn't/matter", body(form).ReadCloser())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				if clientID != "" {
+					req.SetBasicAuth(clientID, machineClientPass)
+				}
+				rsp := httptest.NewRecorder()
+				subject.ServeHTTP(rsp, req)
+				t.Logf("response body: %q", rsp.Body.String())
+				return rsp
+			}
+			accessToken := func(rsp *httptest.ResponseRecorder) string {
+				require.Equal(t, http.StatusOK, rsp.Code)
+				var responseBody map[string]interface{}
+				require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &responseBody))
+				return responseBody["access_token"].(string)
+			}
+
+			// The user logs in with the CLI.
+			authRequest := deepCopyRequestForm(happyAuthRequest)
+			authRequest.Form.Set("scope", "openid pinniped:request-audience")
+			authResponder := simulateAuthEndpointHavingAlreadyRun(t, authRequest, oauthHelper)
+			userAccessToken := accessToken(post(url.Values(happyAuthcodeRequestBody(authResponder.GetCode())), ""))
+
+			// The actor gets a token for itself.
+			actorAccessToken := accessToken(post(url.Values{
+				"grant_type": {"client_credentials"},
+				"scope":      {"openid pinniped:request-audience"},
+			}, test.actorTokenOf))
+
+			request := happyTokenExchangeRequest(test.requestedAudience, userAccessToken)
+			request.Form.Del("client_id")
+			request.Form.Set("actor_token", actorAccessToken)
+			request.Form.Set("actor_token_type", "urn:ietf:params:oauth:token-type:access_token")
+			if test.modifyParams != nil {
+				test.modifyParams(request.Form)
+			}
+			rsp := post(request.Form, test.clientID)
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+			if test.wantResponseBodyContains != "" {
+				require.Contains(t, rsp.Body.String(), test.wantResponseBodyContains)
+			}
+
+			// The remaining assertions apply only the the happy path.
+			if rsp.Code != http.StatusOK {
+				return
+			}
+
+			parsedJWT, err := jose.ParseSigned(accessToken(rsp))
+			require.NoError(t, err)
+			var tokenClaims map[string]interface{}
+			require.NoError(t, json.Unmarshal(parsedJWT.UnsafePayloadWithoutVerification(), &tokenClaims))
+
+			require.Equal(t, []interface{}{test.requestedAudience}, tokenClaims["aud"])
+			require.Equal(t, goodSubject, tokenClaims["sub"])
+			require.Equal(t, goodUsername, tokenClaims["username"])
+			require.Equal(t, map[string]interface{}{
+				"sub":      goodIssuer + "?client_id=some-support-portal",
+				"username": "some-support-portal-user",
+			}, tokenClaims["act"])
+		})
+	}
+}
+
+// staticKeySet is a coreosoidc.KeySet which only has one key.
+type staticKeySet struct {
+	publicKey crypto.PublicKey
+}
+
+func (s *staticKeySet) VerifySignature(_ context.Context, token string) ([]byte, error) {
+	parsed, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Verify(s.publicKey)
+}
+
 type refreshRequestInputs struct {
 	modifyTokenRequest func(tokenRequest *http.Request, refreshToken string, accessToken string)
 	want               tokenEndpointResponseExpectedValues
@@ -1047,7 +1599,9 @@ func TestRefreshGrant(t *testing.T) {
 			wantAtHashClaimInIDToken := true
 			// Refreshed ID tokens do not include the nonce from the original auth request
 			wantNonceValueInIDToken := false
-			requireTokenEndpointBehavior(t, test.refreshRequest.want, wantAtHashClaimInIDToken, wantNonceValueInIDToken, refreshResponse, authCode, oauthStore, jwtSigningKey, secrets)
+			// The refresh token from the authcode exchange was used up by the refresh, but is kept to detect reuse.
+			wantUsedRefreshTokensStored := 1
+			requireTokenEndpointBehavior(t, test.refreshRequest.want, wantAtHashClaimInIDToken, wantNonceValueInIDToken, wantUsedRefreshTokensStored, refreshResponse, authCode, oauthStore, jwtSigningKey, secrets)
 
 			if test.refreshRequest.want.wantStatus == http.StatusOK {
 				wantIDToken := contains(test.refreshRequest.want.wantSuccessBodyFields, "id_token")
@@ -1095,6 +1649,183 @@ func TestRefreshGrant(t *testing.T) {
 	}
 }
 
+func TestRefreshGrantWhenRefreshTokenIsReused(t *testing.T) {
+	subject, rsp, _, _, secrets, _ := exchangeAuthcodeForTokens(t, authcodeExchangeInputs{
+		modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access") },
+		want: tokenEndpointResponseExpectedValues{
+			wantStatus:            http.StatusOK,
+			wantSuccessBodyFields: []string{"id_token", "refresh_token", "access_token", "token_type", "expires_in", "scope"},
+			wantRequestedScopes:   []string{"openid", "offline_access"},
+			wantGrantedScopes:     []string{"openid", "offline_access"},
+		},
+	})
+	var parsedAuthcodeExchangeResponseBody map[string]interface{}
+	require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedAuthcodeExchangeResponseBody))
+	firstRefreshToken := parsedAuthcodeExchangeResponseBody["refresh_token"].(string)
+
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/path/shouldn't/matter", happyRefreshRequestBody(refreshToken).ReadCloser())
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		refreshResponse := httptest.NewRecorder()
+		subject.ServeHTTP(refreshResponse, req)
+		t.Logf("refresh response: %#v", refreshResponse)
+		t.Logf("refresh response body: %q", refreshResponse.Body.String())
+		return refreshResponse
+	}
+
+	// The first refresh uses up the first refresh token.
+	refreshResponse := refresh(firstRefreshToken)
+	require.Equal(t, http.StatusOK, refreshResponse.Code)
+	var parsedRefreshResponseBody map[string]interface{}
+	require.NoError(t, json.Unmarshal(refreshResponse.Body.Bytes(), &parsedRefreshResponseBody))
+	secondRefreshToken := parsedRefreshResponseBody["refresh_token"].(string)
+
+	// Using the first refresh token again right away is allowed, as if another kubectl process also refreshed.
+	refreshResponse = refresh(firstRefreshToken)
+	require.Equal(t, http.StatusOK, refreshResponse.Code)
+	testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}, 3)
+	testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 1)
+
+	// Using the first refresh token again after the grace period revokes all refresh tokens and access tokens of the session.
+	backdateUsedRefreshTokens(t, secrets, time.Hour)
+	refreshResponse = refresh(firstRefreshToken)
+	require.Equal(t, http.StatusUnauthorized, refreshResponse.Code)
+	require.JSONEq(t, fositeInactiveRefreshTokenErrorBody, refreshResponse.Body.String())
+	testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}, 0)
+	testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 0)
+
+	// So the refresh token from the first refresh no longer works either.
+	refreshResponse = refresh(secondRefreshToken)
+	require.Equal(t, http.StatusBadRequest, refreshResponse.Code)
+	require.JSONEq(t, fositeInvalidAuthCodeErrorBody, refreshResponse.Body.String())
+}
+
+func TestRefreshGrantWhenRefreshesOverlap(t *testing.T) {
+	subject, rsp, _, _, secrets, _ := exchangeAuthcodeForTokens(t, authcodeExchangeInputs{
+		modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access") },
+		want: tokenEndpointResponseExpectedValues{
+			wantStatus:            http.StatusOK,
+			wantSuccessBodyFields: []string{"id_token", "refresh_token", "access_token", "token_type", "expires_in", "scope"},
+			wantRequestedScopes:   []string{"openid", "offline_access"},
+			wantGrantedScopes:     []string{"openid", "offline_access"},
+		},
+	})
+	var parsedAuthcodeExchangeResponseBody map[string]interface{}
+	require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedAuthcodeExchangeResponseBody))
+	firstRefreshToken := parsedAuthcodeExchangeResponseBody["refresh_token"].(string)
+
+	refresh := func(refreshToken string) string {
+		req := httptest.NewRequest("POST", "/path/shouldn't/matter", happyRefreshRequestBody(refreshToken).ReadCloser())
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		refreshResponse := httptest.NewRecorder()
+		subject.ServeHTTP(refreshResponse, req)
+		t.Logf("refresh response: %#v", refreshResponse)
+		t.Logf("refresh response body: %q", refreshResponse.Body.String())
+		require.Equal(t, http.StatusOK, refreshResponse.Code)
+		var parsedRefreshResponseBody map[string]interface{}
+		require.NoError(t, json.Unmarshal(refreshResponse.Body.Bytes(), &parsedRefreshResponseBody))
+		return parsedRefreshResponseBody["refresh_token"].(string)
+	}
+
+	// Two kubectl processes which share a session cache both refresh the first refresh token within the grace period.
+	secondRefreshToken := refresh(firstRefreshToken)
+	thirdRefreshToken := refresh(firstRefreshToken)
+
+	// Whichever of their refresh tokens ends up in the session cache can still be used long after the grace period,
+	// because only the first refresh token was used.
+	backdateUsedRefreshTokens(t, secrets, time.Hour)
+	refresh(secondRefreshToken)
+	refresh(thirdRefreshToken)
+	testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}, 5)
+}
+
+// backdateUsedRefreshTokens moves the time at which every used refresh token was used into the past.
+func backdateUsedRefreshTokens(t *testing.T, secrets v1.SecretInterface, by time.Duration) {
+	t.Helper()
+
+	list, err := secrets.List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}.String(),
+	})
+	require.NoError(t, err)
+	for i := range list.Items {
+		secret := &list.Items[i]
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(secret.Data["pinniped-storage-data"], &data))
+		usedAt, ok := data["usedAt"].(string)
+		if !ok {
+			continue
+		}
+		parsedUsedAt, err := time.Parse(time.RFC3339Nano, usedAt)
+		require.NoError(t, err)
+		data["usedAt"] = parsedUsedAt.Add(-by).Format(time.RFC3339Nano)
+		secret.Data["pinniped-storage-data"], err = json.Marshal(data)
+		require.NoError(t, err)
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+}
+
+func TestLimitToAbsoluteSessionLifespan(t *testing.T) {
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	absoluteSessionLifespan := 4 * time.Hour
+
+	tests := []struct {
+		name                      string
+		authTime                  time.Time
+		refreshTokenExpiresAt     time.Time
+		wantErr                   string
+		wantRefreshTokenExpiresAt time.Time
+	}{
+		{
+			name:                      "refresh token expires before the end of the session",
+			authTime:                  now.Add(-1 * time.Hour),
+			refreshTokenExpiresAt:     now.Add(2 * time.Hour),
+			wantRefreshTokenExpiresAt: now.Add(2 * time.Hour),
+		},
+		{
+			name:                      "refresh token would expire after the end of the session",
+			authTime:                  now.Add(-1 * time.Hour),
+			refreshTokenExpiresAt:     now.Add(9 * time.Hour),
+			wantRefreshTokenExpiresAt: now.Add(3 * time.Hour),
+		},
+		{
+			name:     "no refresh token",
+			authTime: now.Add(-1 * time.Hour),
+		},
+		{
+			name:                      "no auth time",
+			refreshTokenExpiresAt:     now.Add(9 * time.Hour),
+			wantRefreshTokenExpiresAt: now.Add(9 * time.Hour),
+		},
+		{
+			name:                  "session has reached its maximum lifetime",
+			authTime:              now.Add(-4 * time.Hour),
+			refreshTokenExpiresAt: now.Add(9 * time.Hour),
+			wantErr:               "The provided authorization grant (e.g., authorization code, resource owner credentials) or refresh token is invalid, expired, revoked, does not match the redirection URI used in the authorization request, or was issued to another client. The session has reached its maximum lifetime, so the end user must log in again.",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			session := psession.NewPinnipedSession()
+			session.Fosite.Claims.AuthTime = test.authTime
+			if !test.refreshTokenExpiresAt.IsZero() {
+				session.SetExpiresAt(fosite.RefreshToken, test.refreshTokenExpiresAt)
+			}
+			accessRequest := fosite.NewAccessRequest(session)
+
+			err := limitToAbsoluteSessionLifespan(accessRequest, absoluteSessionLifespan, now)
+			if test.wantErr != "" {
+				require.EqualError(t, err, "invalid_grant")
+				require.Equal(t, test.wantErr, fosite.ErrorToRFC6749Error(err).GetDescription())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantRefreshTokenExpiresAt, session.GetExpiresAt(fosite.RefreshToken))
+		})
+	}
+}
+
 func requireClaimsAreNotEqual(t *testing.T, claimName string, claimsOfTokenA map[string]interface{}, claimsOfTokenB map[string]interface{}) {
 	require.NotEmpty(t, claimsOfTokenA[claimName])
 	require.NotEmpty(t, claimsOfTokenB[claimName])
@@ -1125,7 +1856,7 @@ func exchangeAuthcodeForTokens(t *testing.T, test authcodeExchangeInputs) (
 
 	var oauthHelper fosite.OAuth2Provider
 
-	oauthStore = oidc.NewKubeStorage(secrets, oidc.DefaultOIDCTimeoutsConfiguration())
+	oauthStore = oidc.NewKubeStorage(secrets, nil, oidc.DefaultOIDCTimeoutsConfiguration())
 	if test.makeOathHelper != nil {
 		oauthHelper, authCode, jwtSigningKey = test.makeOathHelper(t, authRequest, oauthStore)
 	} else {
@@ -1135,7 +1866,7 @@ func exchangeAuthcodeForTokens(t *testing.T, test authcodeExchangeInputs) (
 	if test.modifyStorage != nil {
 		test.modifyStorage(t, oauthStore, authCode)
 	}
-	subject = NewHandler(oauthHelper)
+	subject = NewHandler(oauthHelper, oidc.DefaultOIDCTimeoutsConfiguration().AbsoluteSessionLifespan)
 
 	authorizeEndpointGrantedOpenIDScope := strings.Contains(authRequest.Form.Get("scope"), "openid")
 	expectedNumberOfIDSessionsStored := 0
@@ -1161,7 +1892,7 @@ func exchangeAuthcodeForTokens(t *testing.T, test authcodeExchangeInputs) (
 
 	wantAtHashClaimInIDToken := false // due to a bug in fosite, the at_hash claim is not filled in during authcode exchange
 	wantNonceValueInIDToken := true   // ID tokens returned by the authcode exchange must include the nonce from the auth request (unliked refreshed ID tokens)
-	requireTokenEndpointBehavior(t, test.want, wantAtHashClaimInIDToken, wantNonceValueInIDToken, rsp, authCode, oauthStore, jwtSigningKey, secrets)
+	requireTokenEndpointBehavior(t, test.want, wantAtHashClaimInIDToken, wantNonceValueInIDToken, 0, rsp, authCode, oauthStore, jwtSigningKey, secrets)
 
 	return subject, rsp, authCode, jwtSigningKey, secrets, oauthStore
 }
@@ -1171,6 +1902,7 @@ func requireTokenEndpointBehavior(
 	test tokenEndpointResponseExpectedValues,
 	wantAtHashClaimInIDToken bool,
 	wantNonceValueInIDToken bool,
+	wantUsedRefreshTokensStored int,
 	tokenEndpointResponse *httptest.ResponseRecorder,
 	authCode string,
 	oauthStore *oidc.KubeStorage,
@@ -1211,9 +1943,9 @@ func requireTokenEndpointBehavior(
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: authorizationcode.TypeLabelValue}, 1)
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 1)
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: storagepkce.TypeLabelValue}, 0)
-		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}, expectedNumberOfRefreshTokenSessionsStored)
+		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}, expectedNumberOfRefreshTokenSessionsStored+wantUsedRefreshTokensStored)
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: openidconnect.TypeLabelValue}, expectedNumberOfIDSessionsStored)
-		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{}, 2+expectedNumberOfRefreshTokenSessionsStored+expectedNumberOfIDSessionsStored)
+		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{}, 2+expectedNumberOfRefreshTokenSessionsStored+wantUsedRefreshTokensStored+expectedNumberOfIDSessionsStored)
 	} else {
 		require.NotNil(t, test.wantErrorResponseBody, "problem with test table setup: wanted failure but did not specify failure response body")
 
@@ -1310,22 +2042,34 @@ func makeHappyOauthHelper(
 	t.Helper()
 
 	jwtSigningKey, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
-	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, jwkProvider, oidc.DefaultOIDCTimeoutsConfiguration())
+	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, jwkProvider, workloadClusters(), nil, nil, oidc.DefaultOIDCTimeoutsConfiguration())
 	authResponder := simulateAuthEndpointHavingAlreadyRun(t, authRequest, oauthHelper)
 	return oauthHelper, authResponder.GetCode(), jwtSigningKey
 }
 
+// workloadClusters returns the clusters which may receive tokens using token exchange in these tests.
+func workloadClusters() clusteraudience.DynamicClusterAudienceProvider {
+	clusters := clusteraudience.NewDynamicClusterAudienceProvider()
+	clusters.SetClusters([]*clusteraudience.Cluster{
+		{Audience: "some-workload-cluster", IncludeUsername: true, IncludeGroups: true},
+		{Audience: "some-restricted-workload-cluster", AllowedGroups: []string{"some-other-group"}, IncludeUsername: true, IncludeGroups: true},
+		{Audience: "some-username-only-workload-cluster", IncludeUsername: true},
+		{Audience: "some-delegation-workload-cluster", AllowedActorGroups: []string{"support-portals"}, IncludeUsername: true, IncludeGroups: true},
+	})
+	return clusters
+}
+
 type singleUseJWKProvider struct {
 	jwks.DynamicJWKSProvider
 	calls int
 }
 
-func (s *singleUseJWKProvider) GetJWKS(issuerName string) (jwks *jose.JSONWebKeySet, activeJWK *jose.JSONWebKey) {
+func (s *singleUseJWKProvider) GetSigner(issuerName string) (jwks.Signer, error) {
 	s.calls++
 	if s.calls > 1 {
 		return nil, nil
 	}
-	return s.DynamicJWKSProvider.GetJWKS(issuerName)
+	return s.DynamicJWKSProvider.GetSigner(issuerName)
 }
 
 func makeOauthHelperWithJWTKeyThatWorksOnlyOnce(
@@ -1336,7 +2080,7 @@ func makeOauthHelperWithJWTKeyThatWorksOnlyOnce(
 	t.Helper()
 
 	jwtSigningKey, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
-	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, &singleUseJWKProvider{DynamicJWKSProvider: jwkProvider}, oidc.DefaultOIDCTimeoutsConfiguration())
+	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, &singleUseJWKProvider{DynamicJWKSProvider: jwkProvider}, workloadClusters(), nil, nil, oidc.DefaultOIDCTimeoutsConfiguration())
 	authResponder := simulateAuthEndpointHavingAlreadyRun(t, authRequest, oauthHelper)
 	return oauthHelper, authResponder.GetCode(), jwtSigningKey
 }
@@ -1349,7 +2093,7 @@ func makeOauthHelperWithNilPrivateJWTSigningKey(
 	t.Helper()
 
 	jwkProvider := jwks.NewDynamicJWKSProvider() // empty provider which contains no signing key for this issuer
-	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, jwkProvider, oidc.DefaultOIDCTimeoutsConfiguration())
+	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, jwkProvider, workloadClusters(), nil, nil, oidc.DefaultOIDCTimeoutsConfiguration())
 	authResponder := simulateAuthEndpointHavingAlreadyRun(t, authRequest, oauthHelper)
 	return oauthHelper, authResponder.GetCode(), nil
 }
@@ -1358,18 +2102,24 @@ func makeOauthHelperWithNilPrivateJWTSigningKey(
 func simulateAuthEndpointHavingAlreadyRun(t *testing.T, authRequest *http.Request, oauthHelper fosite.OAuth2Provider) fosite.AuthorizeResponder {
 	// We only set the fields in the session that Fosite wants us to set.
 	ctx := context.Background()
-	session := &openid.DefaultSession{
-		Claims: &jwt.IDTokenClaims{
-			Subject:     goodSubject,
-			RequestedAt: goodRequestedAtTime,
-			AuthTime:    goodAuthTime,
-			Extra: map[string]interface{}{
-				oidc.DownstreamUsernameClaim: goodUsername,
-				oidc.DownstreamGroupsClaim:   goodGroups,
+	session := &psession.PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims: &jwt.IDTokenClaims{
+				Subject:     goodSubject,
+				RequestedAt: goodRequestedAtTime,
+				AuthTime:    goodAuthTime,
+				Extra: map[string]interface{}{
+					oidc.DownstreamUsernameClaim: goodUsername,
+					oidc.DownstreamGroupsClaim:   goodGroups,
+				},
 			},
+			Subject:  "", // not used, note that callback_handler.go does not set this
+			Username: "", // not used, note that callback_handler.go does not set this
+		},
+		Custom: &psession.CustomSessionData{
+			ProviderName: goodUpstreamName,
+			ProviderType: psession.ProviderTypeOIDC,
 		},
-		Subject:  "", // not used, note that callback_handler.go does not set this
-		Username: "", // not used, note that callback_handler.go does not set this
 	}
 	authRequester, err := oauthHelper.NewAuthorizeRequest(ctx, authRequest)
 	require.NoError(t, err)
@@ -1399,6 +2149,7 @@ func generateJWTSigningKeyAndJWKSProvider(t *testing.T, issuer string) (*ecdsa.P
 		map[string]*jose.JSONWebKey{
 			issuer: {Key: key},
 		},
+		nil,
 	)
 
 	return key, jwksProvider
@@ -1588,12 +2339,18 @@ func requireValidStoredRequest(
 	require.Equal(t, wantRequestForm, request.GetRequestForm()) // Fosite stores access token request without form
 
 	// Cast session to the type we think it should be.
-	session, ok := request.GetSession().(*openid.DefaultSession)
-	require.Truef(t, ok, "could not cast %T to %T", request.GetSession(), &openid.DefaultSession{})
+	session, ok := request.GetSession().(*psession.PinnipedSession)
+	require.Truef(t, ok, "could not cast %T to %T", request.GetSession(), &psession.PinnipedSession{})
+
+	// Assert that the custom session data was carried through from the authorize endpoint.
+	require.Equal(t, &psession.CustomSessionData{
+		ProviderName: goodUpstreamName,
+		ProviderType: psession.ProviderTypeOIDC,
+	}, session.Custom)
 
 	// Assert that the session claims are what we think they should be, but only if we are doing OIDC.
 	if contains(wantGrantedScopes, "openid") {
-		claims := session.Claims
+		claims := session.Fosite.Claims
 		require.Empty(t, claims.JTI) // When claims.JTI is empty, Fosite will generate a UUID for this field.
 		require.Equal(t, goodSubject, claims.Subject)
 
@@ -1630,11 +2387,11 @@ func requireValidStoredRequest(
 	}
 
 	// Assert that the session headers are what we think they should be.
-	headers := session.Headers
+	headers := session.Fosite.Headers
 	require.Empty(t, headers)
 
 	// Assert that the token expirations are what we think they should be.
-	authCodeExpiresAt, ok := session.ExpiresAt[fosite.AuthorizeCode]
+	authCodeExpiresAt, ok := session.Fosite.ExpiresAt[fosite.AuthorizeCode]
 	require.True(t, ok, "expected session to hold expiration time for auth code")
 	testutil.RequireTimeInDelta(
 		t,
@@ -1644,7 +2401,7 @@ func requireValidStoredRequest(
 	)
 
 	// OpenID Connect sessions do not store access token expiration information.
-	accessTokenExpiresAt, ok := session.ExpiresAt[fosite.AccessToken]
+	accessTokenExpiresAt, ok := session.Fosite.ExpiresAt[fosite.AccessToken]
 	if wantAccessTokenExpiresAt {
 		require.True(t, ok, "expected session to hold expiration time for access token")
 		testutil.RequireTimeInDelta(
@@ -1658,8 +2415,8 @@ func requireValidStoredRequest(
 	}
 
 	// We don't use these, so they should be empty.
-	require.Empty(t, session.Username)
-	require.Empty(t, session.Subject)
+	require.Empty(t, session.Fosite.Username)
+	require.Empty(t, session.Fosite.Subject)
 }
 
 func requireGarbageCollectTimeInDelta(t *testing.T, tokenString string, typeLabel string, secrets v1.SecretInterface, wantExpirationTime time.Time, deltaTime time.Duration) {
@@ -5,32 +5,66 @@ package oidc
 
 import (
 	"context"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/compose"
 	"github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/handler/openid"
+	fositejwt "github.com/ory/fosite/token/jwt"
 	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"go.pinniped.dev/internal/auditlog"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
+	"go.pinniped.dev/internal/psession"
 )
 
 const (
 	tokenTypeAccessToken       = "urn:ietf:params:oauth:token-type:access_token" //nolint: gosec
 	tokenTypeJWT               = "urn:ietf:params:oauth:token-type:jwt"          //nolint: gosec
 	pinnipedTokenExchangeScope = "pinniped:request-audience"                     //nolint: gosec
+
+	// GrantTypeTokenExchange is the grant type of RFC8693 token exchange.
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
 )
 
+// errInvalidTarget is the error from RFC8693 section 2.2.2 for a requested audience which is not acceptable.
+var errInvalidTarget = &fosite.RFC6749Error{
+	ErrorField:       "invalid_target",
+	DescriptionField: "The requested audience is invalid, unknown, or malformed.",
+	CodeField:        http.StatusBadRequest,
+}
+
 type stsParams struct {
-	subjectAccessToken string
-	requestedAudience  string
+	subjectToken      string
+	subjectTokenType  string
+	actorToken        string
+	requestedAudience string
 }
 
-func TokenExchangeFactory(config *compose.Config, storage interface{}, strategy interface{}) interface{} {
-	return &TokenExchangeHandler{
-		idTokenStrategy:     strategy.(openid.OpenIDConnectTokenStrategy),
-		accessTokenStrategy: strategy.(oauth2.AccessTokenStrategy),
-		accessTokenStorage:  storage.(oauth2.AccessTokenStorage),
+// TokenExchangeFactory returns a compose.Factory for a TokenExchangeHandler which only issues tokens for the given
+// clusters, and which also accepts the ServiceAccount tokens of the given trusted clusters as subject tokens. Only
+// the machine clients which allow delegation may send actor tokens.
+func TokenExchangeFactory(
+	clusters clusteraudience.DynamicClusterAudienceProvider,
+	trustedClusters trustedcluster.DynamicTrustedClusterProvider,
+	machineClients clientregistry.DynamicMachineClientProvider,
+) compose.Factory {
+	return func(config *compose.Config, storage interface{}, strategy interface{}) interface{} {
+		return &TokenExchangeHandler{
+			idTokenStrategy:     strategy.(openid.OpenIDConnectTokenStrategy),
+			accessTokenStrategy: strategy.(oauth2.AccessTokenStrategy),
+			accessTokenStorage:  storage.(oauth2.AccessTokenStorage),
+			clusters:            clusters,
+			trustedClusters:     trustedClusters,
+			machineClients:      machineClients,
+		}
 	}
 }
 
@@ -38,6 +72,9 @@ type TokenExchangeHandler struct {
 	idTokenStrategy     openid.OpenIDConnectTokenStrategy
 	accessTokenStrategy oauth2.AccessTokenStrategy
 	accessTokenStorage  oauth2.AccessTokenStorage
+	clusters            clusteraudience.DynamicClusterAudienceProvider
+	trustedClusters     trustedcluster.DynamicTrustedClusterProvider
+	machineClients      clientregistry.DynamicMachineClientProvider
 }
 
 var _ fosite.TokenEndpointHandler = (*TokenExchangeHandler)(nil)
@@ -61,22 +98,46 @@ func (t *TokenExchangeHandler) PopulateTokenEndpointResponse(ctx context.Context
 		return errors.WithStack(err)
 	}
 
-	// Validate the incoming access token and lookup the information about the original authorize request.
-	originalRequester, err := t.validateAccessToken(ctx, requester, params.subjectAccessToken)
-	if err != nil {
-		return errors.WithStack(err)
+	var originalRequester fosite.Requester
+	if params.subjectTokenType == tokenTypeJWT {
+		// Validate the incoming ServiceAccount token and make a session for the ServiceAccount.
+		originalRequester, err = t.validateServiceAccountToken(ctx, params.subjectToken)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		// Validate the incoming access token and lookup the information about the original authorize request.
+		originalRequester, err = t.validateAccessToken(ctx, requester, params.subjectToken, "subject_token")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		// Require that the incoming access token has the pinniped:request-audience and OpenID scopes.
+		if !originalRequester.GetGrantedScopes().Has(pinnipedTokenExchangeScope) {
+			return errors.WithStack(fosite.ErrAccessDenied.WithHintf("missing the %q scope", pinnipedTokenExchangeScope))
+		}
+		if !originalRequester.GetGrantedScopes().Has(oidc.ScopeOpenID) {
+			return errors.WithStack(fosite.ErrAccessDenied.WithHintf("missing the %q scope", oidc.ScopeOpenID))
+		}
 	}
 
-	// Require that the incoming access token has the pinniped:request-audience and OpenID scopes.
-	if !originalRequester.GetGrantedScopes().Has(pinnipedTokenExchangeScope) {
-		return errors.WithStack(fosite.ErrAccessDenied.WithHintf("missing the %q scope", pinnipedTokenExchangeScope))
+	// When the client acts on behalf of the user, validate the actor token, which must belong to the client itself.
+	var actorRequester fosite.Requester
+	if params.actorToken != "" {
+		actorRequester, err = t.validateActorToken(ctx, requester, params.actorToken)
+		if err != nil {
+			return errors.WithStack(err)
+		}
 	}
-	if !originalRequester.GetGrantedScopes().Has(oidc.ScopeOpenID) {
-		return errors.WithStack(fosite.ErrAccessDenied.WithHintf("missing the %q scope", oidc.ScopeOpenID))
+
+	// Require that the requested audience belongs to a registered cluster which allows this user, and this actor.
+	cluster, err := t.authorizeCluster(originalRequester, actorRequester, params.requestedAudience)
+	if err != nil {
+		return errors.WithStack(err)
 	}
 
 	// Use the original authorize request information, along with the requested audience, to mint a new JWT.
-	responseToken, err := t.mintJWT(ctx, originalRequester, params.requestedAudience)
+	responseToken, err := t.mintJWT(ctx, originalRequester, actorRequester, cluster)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -85,15 +146,116 @@ func (t *TokenExchangeHandler) PopulateTokenEndpointResponse(ctx context.Context
 	responder.SetAccessToken(responseToken)
 	responder.SetTokenType("N_A")
 	responder.SetExtra("issued_token_type", tokenTypeJWT)
+
+	auditlog.Record(ctx, tokenExchangedAuditEvent(requester, originalRequester, actorRequester, cluster))
 	return nil
 }
 
-func (t *TokenExchangeHandler) mintJWT(ctx context.Context, requester fosite.Requester, audience string) (string, error) {
-	downscoped := fosite.NewAccessRequest(requester.GetSession())
-	downscoped.Client.(*fosite.DefaultClient).ID = audience
+// tokenExchangedAuditEvent describes the exchange as part of the session of the subject token, even though the
+// token was issued to the client which made the request.
+func tokenExchangedAuditEvent(requester fosite.Requester, original fosite.Requester, actor fosite.Requester, cluster *clusteraudience.Cluster) auditlog.Event {
+	event := AuditEvent(auditlog.TokenExchanged, original)
+	event.ClientID = requester.GetClient().GetID()
+	event.GrantType = GrantTypeTokenExchange
+	event.Audience = cluster.Audience
+	if actor != nil {
+		event.Actor = AuditEvent(auditlog.TokenExchanged, actor).Username
+	}
+	return event
+}
+
+func (t *TokenExchangeHandler) authorizeCluster(requester fosite.Requester, actor fosite.Requester, audience string) (*clusteraudience.Cluster, error) {
+	var cluster *clusteraudience.Cluster
+	if t.clusters != nil {
+		cluster = t.clusters.GetCluster(audience)
+	}
+	if cluster == nil {
+		return nil, errInvalidTarget.WithHintf("the audience %q is not configured for any cluster", audience)
+	}
+	if !cluster.IsAllowed(sessionGroups(requester.GetSession())) {
+		return nil, fosite.ErrAccessDenied.WithHintf("the user is not a member of any group which may receive tokens for the audience %q", audience)
+	}
+	if actor != nil && !cluster.IsActorAllowed(sessionGroups(actor.GetSession())) {
+		return nil, fosite.ErrAccessDenied.WithHintf("the actor is not a member of any group which may act on behalf of users for the audience %q", audience)
+	}
+	return cluster, nil
+}
+
+func (t *TokenExchangeHandler) mintJWT(ctx context.Context, requester fosite.Requester, actor fosite.Requester, cluster *clusteraudience.Cluster) (string, error) {
+	session := clusterSession(requester.GetSession(), cluster)
+	if actor != nil {
+		addActorClaim(session, actor.GetSession())
+	}
+	downscoped := fosite.NewAccessRequest(session)
+	downscoped.Client.(*fosite.DefaultClient).ID = cluster.Audience
 	return t.idTokenStrategy.GenerateIDToken(ctx, downscoped)
 }
 
+// clusterSession returns a copy of the session with only the identity claims which the cluster should get, so the
+// original session stays as it is.
+func clusterSession(original fosite.Session, cluster *clusteraudience.Cluster) fosite.Session {
+	session := original.Clone()
+	oidcSession, ok := session.(openid.Session)
+	if !ok {
+		return session
+	}
+
+	claims := oidcSession.IDTokenClaims()
+	if claims.Extra == nil {
+		claims.Extra = map[string]interface{}{}
+	}
+	if !cluster.IncludeUsername {
+		delete(claims.Extra, DownstreamUsernameClaim)
+	}
+	switch {
+	case !cluster.IncludeGroups:
+		delete(claims.Extra, DownstreamGroupsClaim)
+	case len(cluster.GroupsFilter) > 0:
+		claims.Extra[DownstreamGroupsClaim] = cluster.FilterGroups(sessionGroups(session))
+	}
+	return session
+}
+
+// addActorClaim adds the RFC 8693 "act" claim, which names the actor, to the session of the user.
+func addActorClaim(session fosite.Session, actorSession fosite.Session) {
+	oidcSession, ok := session.(openid.Session)
+	if !ok {
+		return
+	}
+	actorOIDCSession, ok := actorSession.(openid.Session)
+	if !ok || actorOIDCSession.IDTokenClaims() == nil {
+		return
+	}
+	actorClaims := actorOIDCSession.IDTokenClaims()
+	act := map[string]interface{}{"sub": actorClaims.Subject}
+	if username, ok := actorClaims.Extra[DownstreamUsernameClaim].(string); ok {
+		act[DownstreamUsernameClaim] = username
+	}
+	oidcSession.IDTokenClaims().Add("act", act)
+}
+
+// sessionGroups returns the downstream groups of the user, which are a []interface{} after the session was stored.
+func sessionGroups(session fosite.Session) []string {
+	oidcSession, ok := session.(openid.Session)
+	if !ok || oidcSession.IDTokenClaims() == nil {
+		return nil
+	}
+	switch groups := oidcSession.IDTokenClaims().Extra[DownstreamGroupsClaim].(type) {
+	case []string:
+		return groups
+	case []interface{}:
+		result := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if g, ok := group.(string); ok {
+				result = append(result, g)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
 func (t *TokenExchangeHandler) validateParams(params url.Values) (*stsParams, error) {
 	var result stsParams
 
@@ -102,25 +264,34 @@ func (t *TokenExchangeHandler) validateParams(params url.Values) (*stsParams, er
 	if result.requestedAudience == "" {
 		return nil, fosite.ErrInvalidRequest.WithHint("missing audience parameter")
 	}
-	result.subjectAccessToken = params.Get("subject_token")
-	if result.subjectAccessToken == "" {
+	result.subjectToken = params.Get("subject_token")
+	if result.subjectToken == "" {
 		return nil, fosite.ErrInvalidRequest.WithHint("missing subject_token parameter")
 	}
 
 	// Validate some parameters with hardcoded values we support.
-	if params.Get("subject_token_type") != tokenTypeAccessToken {
-		return nil, fosite.ErrInvalidRequest.WithHintf("unsupported subject_token_type parameter value, must be %q", tokenTypeAccessToken)
+	result.subjectTokenType = params.Get("subject_token_type")
+	if result.subjectTokenType != tokenTypeAccessToken && result.subjectTokenType != tokenTypeJWT {
+		return nil, fosite.ErrInvalidRequest.WithHintf("unsupported subject_token_type parameter value, must be %q or %q", tokenTypeAccessToken, tokenTypeJWT)
 	}
 	if params.Get("requested_token_type") != tokenTypeJWT {
 		return nil, fosite.ErrInvalidRequest.WithHintf("unsupported requested_token_type parameter value, must be %q", tokenTypeJWT)
 	}
 
+	// Validate the optional actor token, which must be a Supervisor access token.
+	result.actorToken = params.Get("actor_token")
+	actorTokenType := params.Get("actor_token_type")
+	if result.actorToken == "" && actorTokenType != "" {
+		return nil, fosite.ErrInvalidRequest.WithHint("missing actor_token parameter")
+	}
+	if result.actorToken != "" && actorTokenType != tokenTypeAccessToken {
+		return nil, fosite.ErrInvalidRequest.WithHintf("unsupported actor_token_type parameter value, must be %q", tokenTypeAccessToken)
+	}
+
 	// Validate that none of these unsupported parameters were sent. These are optional and we do not currently support them.
 	for _, param := range []string{
 		"resource",
 		"scope",
-		"actor_token",
-		"actor_token_type",
 	} {
 		if params.Get(param) != "" {
 			return nil, fosite.ErrInvalidRequest.WithHintf("unsupported parameter %s", param)
@@ -130,22 +301,90 @@ func (t *TokenExchangeHandler) validateParams(params url.Values) (*stsParams, er
 	return &result, nil
 }
 
-func (t *TokenExchangeHandler) validateAccessToken(ctx context.Context, requester fosite.AccessRequester, accessToken string) (fosite.Requester, error) {
+func (t *TokenExchangeHandler) validateAccessToken(ctx context.Context, requester fosite.AccessRequester, accessToken string, param string) (fosite.Requester, error) {
 	if err := t.accessTokenStrategy.ValidateAccessToken(ctx, requester, accessToken); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	signature := t.accessTokenStrategy.AccessTokenSignature(accessToken)
 	originalRequester, err := t.accessTokenStorage.GetAccessTokenSession(ctx, signature, requester.GetSession())
 	if err != nil {
-		return nil, fosite.ErrRequestUnauthorized.WithWrap(err).WithHint("invalid subject_token")
+		return nil, fosite.ErrRequestUnauthorized.WithWrap(err).WithHintf("invalid %s", param)
 	}
 	return originalRequester, nil
 }
 
+// validateActorToken checks that the client may act on behalf of users, and that the actor token is a valid access
+// token which was issued to the client.
+func (t *TokenExchangeHandler) validateActorToken(ctx context.Context, requester fosite.AccessRequester, actorToken string) (fosite.Requester, error) {
+	clientID := requester.GetClient().GetID()
+	var machineClient *clientregistry.MachineClient
+	if t.machineClients != nil {
+		machineClient = t.machineClients.GetMachineClient(clientID)
+	}
+	if machineClient == nil || !machineClient.AllowDelegation {
+		return nil, fosite.ErrUnauthorizedClient.WithHint("The client is not allowed to act on behalf of users.")
+	}
+
+	actorRequester, err := t.validateAccessToken(ctx, requester, actorToken, "actor_token")
+	if err != nil {
+		return nil, err
+	}
+	if actorRequester.GetClient().GetID() != clientID {
+		return nil, fosite.ErrRequestUnauthorized.WithHint("the actor_token was not issued to the client")
+	}
+	return actorRequester, nil
+}
+
+// validateServiceAccountToken verifies a ServiceAccount token of a trusted cluster, which is chosen by the issuer of
+// the token, and returns a request whose session has the identity of the ServiceAccount.
+func (t *TokenExchangeHandler) validateServiceAccountToken(ctx context.Context, rawToken string) (fosite.Requester, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, fosite.ErrRequestUnauthorized.WithWrap(err).WithHint("invalid subject_token")
+	}
+	var claims jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, fosite.ErrRequestUnauthorized.WithWrap(err).WithHint("invalid subject_token")
+	}
+
+	var trustedCluster *trustedcluster.TrustedCluster
+	if t.trustedClusters != nil {
+		trustedCluster = t.trustedClusters.FindTrustedCluster(claims.Issuer)
+	}
+	if trustedCluster == nil {
+		return nil, fosite.ErrRequestUnauthorized.WithHintf("the issuer %q of the subject_token is not a trusted cluster", claims.Issuer)
+	}
+
+	identity, err := trustedCluster.Authenticate(ctx, rawToken)
+	if err != nil {
+		return nil, fosite.ErrRequestUnauthorized.WithWrap(err).WithHint("invalid subject_token")
+	}
+
+	now := time.Now().UTC()
+	session := &psession.PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims: &fositejwt.IDTokenClaims{
+				Subject:     identity.Subject,
+				RequestedAt: now,
+				AuthTime:    now,
+				Extra: map[string]interface{}{
+					DownstreamUsernameClaim: identity.Username,
+					DownstreamGroupsClaim:   identity.Groups,
+				},
+			},
+			Headers: &fositejwt.Headers{},
+		},
+		Custom: &psession.CustomSessionData{ProviderName: trustedCluster.Name},
+	}
+	requester := fosite.NewRequest()
+	requester.SetSession(session)
+	return requester, nil
+}
+
 func (t *TokenExchangeHandler) CanSkipClientAuth(_ fosite.AccessRequester) bool {
 	return false
 }
 
 func (t *TokenExchangeHandler) CanHandleTokenEndpointRequest(requester fosite.AccessRequester) bool {
-	return requester.GetGrantTypes().ExactOne("urn:ietf:params:oauth:grant-type:token-exchange")
+	return requester.GetGrantTypes().ExactOne(GrantTypeTokenExchange)
 }
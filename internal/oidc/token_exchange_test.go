@@ -0,0 +1,91 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"testing"
+
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/oidc/clusteraudience"
+	"go.pinniped.dev/internal/psession"
+)
+
+func TestClusterSession(t *testing.T) {
+	newSession := func(groups interface{}) *psession.PinnipedSession {
+		return &psession.PinnipedSession{
+			Fosite: &openid.DefaultSession{
+				Claims: &jwt.IDTokenClaims{
+					Subject: "some-subject",
+					Extra: map[string]interface{}{
+						DownstreamUsernameClaim: "some-username",
+						DownstreamGroupsClaim:   groups,
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		groups    interface{}
+		cluster   *clusteraudience.Cluster
+		wantExtra map[string]interface{}
+	}{
+		{
+			name:    "all claims",
+			groups:  []string{"a", "b"},
+			cluster: &clusteraudience.Cluster{IncludeUsername: true, IncludeGroups: true},
+			wantExtra: map[string]interface{}{
+				"username": "some-username",
+				"groups":   []string{"a", "b"},
+			},
+		},
+		{
+			name:      "no claims",
+			groups:    []string{"a", "b"},
+			cluster:   &clusteraudience.Cluster{},
+			wantExtra: map[string]interface{}{},
+		},
+		{
+			name:      "only the groups",
+			groups:    []string{"a", "b"},
+			cluster:   &clusteraudience.Cluster{IncludeGroups: true},
+			wantExtra: map[string]interface{}{"groups": []string{"a", "b"}},
+		},
+		{
+			name:    "filtered groups from a stored session",
+			groups:  []interface{}{"team-a", "other", "team-b"},
+			cluster: &clusteraudience.Cluster{IncludeUsername: true, IncludeGroups: true, GroupsFilter: []string{"team-*"}},
+			wantExtra: map[string]interface{}{
+				"username": "some-username",
+				"groups":   []string{"team-a", "team-b"},
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			original := newSession(test.groups)
+			session := clusterSession(original, test.cluster).(*psession.PinnipedSession)
+
+			require.Equal(t, test.wantExtra, session.Fosite.Claims.Extra)
+			require.Equal(t, "some-subject", session.Fosite.Claims.Subject)
+			// The original session must not change.
+			require.Equal(t, newSession(test.groups), original)
+		})
+	}
+}
+
+func TestSessionGroups(t *testing.T) {
+	require.Equal(t, []string{"a", "b"}, sessionGroups(&psession.PinnipedSession{Fosite: &openid.DefaultSession{Claims: &jwt.IDTokenClaims{
+		Extra: map[string]interface{}{DownstreamGroupsClaim: []interface{}{"a", 42, "b"}},
+	}}}))
+	require.Equal(t, []string{"a"}, sessionGroups(&psession.PinnipedSession{Fosite: &openid.DefaultSession{Claims: &jwt.IDTokenClaims{
+		Extra: map[string]interface{}{DownstreamGroupsClaim: []string{"a"}},
+	}}}))
+	require.Nil(t, sessionGroups(&psession.PinnipedSession{Fosite: &openid.DefaultSession{Claims: &jwt.IDTokenClaims{}}}))
+}
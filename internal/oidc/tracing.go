@@ -0,0 +1,56 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/ory/fosite"
+
+	"go.pinniped.dev/internal/tracing"
+)
+
+// tracedOAuth2Provider creates a span for each of the fosite operations which the Supervisor's endpoints use.
+// Writing the responses is not traced, since it does not call any other services.
+type tracedOAuth2Provider struct {
+	fosite.OAuth2Provider
+}
+
+// clientAuthenticator is implemented by *fosite.Fosite, which the pushed authorization request endpoint uses directly
+// to authenticate clients.
+type clientAuthenticator interface {
+	AuthenticateClient(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error)
+}
+
+func (p *tracedOAuth2Provider) NewAuthorizeRequest(ctx context.Context, req *http.Request) (_ fosite.AuthorizeRequester, err error) {
+	ctx, span := tracing.Start(ctx, "fosite NewAuthorizeRequest")
+	defer func() { tracing.End(span, err) }()
+	return p.OAuth2Provider.NewAuthorizeRequest(ctx, req)
+}
+
+func (p *tracedOAuth2Provider) NewAuthorizeResponse(ctx context.Context, requester fosite.AuthorizeRequester, session fosite.Session) (_ fosite.AuthorizeResponder, err error) {
+	ctx, span := tracing.Start(ctx, "fosite NewAuthorizeResponse")
+	defer func() { tracing.End(span, err) }()
+	return p.OAuth2Provider.NewAuthorizeResponse(ctx, requester, session)
+}
+
+func (p *tracedOAuth2Provider) NewAccessRequest(ctx context.Context, req *http.Request, session fosite.Session) (_ fosite.AccessRequester, err error) {
+	ctx, span := tracing.Start(ctx, "fosite NewAccessRequest")
+	defer func() { tracing.End(span, err) }()
+	return p.OAuth2Provider.NewAccessRequest(ctx, req, session)
+}
+
+func (p *tracedOAuth2Provider) NewAccessResponse(ctx context.Context, requester fosite.AccessRequester) (_ fosite.AccessResponder, err error) {
+	ctx, span := tracing.Start(ctx, "fosite NewAccessResponse")
+	defer func() { tracing.End(span, err) }()
+	return p.OAuth2Provider.NewAccessResponse(ctx, requester)
+}
+
+func (p *tracedOAuth2Provider) AuthenticateClient(ctx context.Context, r *http.Request, form url.Values) (_ fosite.Client, err error) {
+	ctx, span := tracing.Start(ctx, "fosite AuthenticateClient")
+	defer func() { tracing.End(span, err) }()
+	return p.OAuth2Provider.(clientAuthenticator).AuthenticateClient(ctx, r, form)
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.pinniped.dev/internal/testutil/tracingtest"
+)
+
+type fakeOAuth2Provider struct {
+	fosite.OAuth2Provider // unimplemented methods panic
+	t                     *testing.T
+}
+
+func (f *fakeOAuth2Provider) NewAuthorizeRequest(ctx context.Context, _ *http.Request) (fosite.AuthorizeRequester, error) {
+	require.True(f.t, trace.SpanFromContext(ctx).IsRecording())
+	return fosite.NewAuthorizeRequest(), nil
+}
+
+func (f *fakeOAuth2Provider) NewAuthorizeResponse(context.Context, fosite.AuthorizeRequester, fosite.Session) (fosite.AuthorizeResponder, error) {
+	return fosite.NewAuthorizeResponse(), nil
+}
+
+func (f *fakeOAuth2Provider) NewAccessRequest(context.Context, *http.Request, fosite.Session) (fosite.AccessRequester, error) {
+	return nil, fosite.ErrInvalidGrant
+}
+
+func (f *fakeOAuth2Provider) NewAccessResponse(context.Context, fosite.AccessRequester) (fosite.AccessResponder, error) {
+	return fosite.NewAccessResponse(), nil
+}
+
+func (f *fakeOAuth2Provider) AuthenticateClient(context.Context, *http.Request, url.Values) (fosite.Client, error) {
+	return &fosite.DefaultClient{ID: "some-client"}, nil
+}
+
+func TestTracedOAuth2Provider(t *testing.T) {
+	recorder := tracingtest.Record(t)
+	subject := &tracedOAuth2Provider{OAuth2Provider: &fakeOAuth2Provider{t: t}}
+	ctx := context.Background()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	authorizeRequest, err := subject.NewAuthorizeRequest(ctx, req)
+	require.NoError(t, err)
+	_, err = subject.NewAuthorizeResponse(ctx, authorizeRequest, nil)
+	require.NoError(t, err)
+	_, err = subject.NewAccessRequest(ctx, req, nil)
+	require.ErrorIs(t, err, fosite.ErrInvalidGrant)
+	_, err = subject.NewAccessResponse(ctx, nil)
+	require.NoError(t, err)
+	client, err := subject.AuthenticateClient(ctx, req, url.Values{})
+	require.NoError(t, err)
+	require.Equal(t, "some-client", client.GetID())
+
+	require.Equal(t, []string{
+		"fosite NewAuthorizeRequest",
+		"fosite NewAuthorizeResponse",
+		"fosite NewAccessRequest",
+		"fosite NewAccessResponse",
+		"fosite AuthenticateClient",
+	}, tracingtest.SpanNames(recorder))
+	spans := recorder.GetSpans()
+	require.Equal(t, codes.Unset, spans[1].StatusCode)
+	require.Equal(t, codes.Error, spans[2].StatusCode)
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package trustedcluster holds the clusters whose ServiceAccount tokens may be exchanged for Supervisor-issued tokens
+// using RFC 8693 token exchange, as configured by TrustedClusters.
+package trustedcluster
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+const serviceAccountUsernamePrefix = "system:serviceaccount:"
+
+// TokenVerifier verifies the signature, issuer, audience and expiry of a token.
+type TokenVerifier interface {
+	Verify(ctx context.Context, rawIDToken string) (*oidc.IDToken, error)
+}
+
+// TrustedCluster is the validated configuration of one cluster.
+type TrustedCluster struct {
+	// Name of the cluster, which becomes part of the usernames and groups of its ServiceAccounts.
+	Name string
+
+	// Issuer of the cluster's ServiceAccount tokens.
+	Issuer string
+
+	// Verifier of the cluster's ServiceAccount tokens.
+	Verifier TokenVerifier
+}
+
+// Identity is the downstream identity of a ServiceAccount.
+type Identity struct {
+	Subject  string
+	Username string
+	Groups   []string
+}
+
+// Authenticate verifies a ServiceAccount token of the cluster and returns the identity of the ServiceAccount.
+func (c *TrustedCluster) Authenticate(ctx context.Context, rawToken string) (*Identity, error) {
+	token, err := c.Verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subject of a ServiceAccount token is the username of the ServiceAccount, e.g. system:serviceaccount:ns:name.
+	namespaceAndName := strings.Split(strings.TrimPrefix(token.Subject, serviceAccountUsernamePrefix), ":")
+	if !strings.HasPrefix(token.Subject, serviceAccountUsernamePrefix) ||
+		len(namespaceAndName) != 2 || !validNamePart(namespaceAndName[0]) || !validNamePart(namespaceAndName[1]) {
+		return nil, fmt.Errorf("subject %q is not a ServiceAccount", token.Subject)
+	}
+	namespace := namespaceAndName[0]
+
+	subject, err := url.Parse(c.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer: %w", err)
+	}
+	q := subject.Query()
+	q.Set("sub", token.Subject)
+	subject.RawQuery = q.Encode()
+
+	return &Identity{
+		Subject:  subject.String(),
+		Username: token.Subject + "@" + c.Name,
+		Groups: []string{
+			"system:serviceaccounts@" + c.Name,
+			"system:serviceaccounts:" + namespace + "@" + c.Name,
+		},
+	}, nil
+}
+
+// validNamePart rejects the characters which would make a username ambiguous. Kubernetes does not allow them in
+// the names of namespaces or ServiceAccounts anyway.
+func validNamePart(s string) bool {
+	return s != "" && !strings.ContainsAny(s, "@:")
+}
+
+// DynamicTrustedClusterProvider is a thread-safe cache of the configured clusters.
+type DynamicTrustedClusterProvider interface {
+	SetTrustedClusters(trustedClusters []*TrustedCluster)
+	// FindTrustedCluster returns the cluster with the issuer, or nil when no cluster has that issuer.
+	FindTrustedCluster(issuer string) *TrustedCluster
+}
+
+type dynamicTrustedClusterProvider struct {
+	issuerToTrustedCluster map[string]*TrustedCluster
+	mutex                  sync.RWMutex
+}
+
+func NewDynamicTrustedClusterProvider() DynamicTrustedClusterProvider {
+	return &dynamicTrustedClusterProvider{
+		issuerToTrustedCluster: map[string]*TrustedCluster{},
+	}
+}
+
+func (p *dynamicTrustedClusterProvider) SetTrustedClusters(trustedClusters []*TrustedCluster) {
+	issuerToTrustedCluster := make(map[string]*TrustedCluster, len(trustedClusters))
+	for _, trustedCluster := range trustedClusters {
+		issuerToTrustedCluster[trustedCluster.Issuer] = trustedCluster
+	}
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.issuerToTrustedCluster = issuerToTrustedCluster
+}
+
+func (p *dynamicTrustedClusterProvider) FindTrustedCluster(issuer string) *TrustedCluster {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	return p.issuerToTrustedCluster[issuer]
+}
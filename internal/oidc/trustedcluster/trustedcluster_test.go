@@ -0,0 +1,95 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package trustedcluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVerifier struct {
+	subject string
+	err     error
+}
+
+func (f *fakeVerifier) Verify(_ context.Context, _ string) (*oidc.IDToken, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &oidc.IDToken{Subject: f.subject}, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name         string
+		verifier     *fakeVerifier
+		wantIdentity *Identity
+		wantErr      string
+	}{
+		{
+			name:     "ServiceAccount token",
+			verifier: &fakeVerifier{subject: "system:serviceaccount:some-namespace:some-name"},
+			wantIdentity: &Identity{
+				Subject:  "https://issuer.example.com/path?sub=system%3Aserviceaccount%3Asome-namespace%3Asome-name",
+				Username: "system:serviceaccount:some-namespace:some-name@some-cluster",
+				Groups:   []string{"system:serviceaccounts@some-cluster", "system:serviceaccounts:some-namespace@some-cluster"},
+			},
+		},
+		{
+			name:     "invalid token",
+			verifier: &fakeVerifier{err: errors.New("some verify error")},
+			wantErr:  "some verify error",
+		},
+		{
+			name:     "not a ServiceAccount",
+			verifier: &fakeVerifier{subject: "some-user"},
+			wantErr:  `subject "some-user" is not a ServiceAccount`,
+		},
+		{
+			name:     "missing name",
+			verifier: &fakeVerifier{subject: "system:serviceaccount:some-namespace"},
+			wantErr:  `subject "system:serviceaccount:some-namespace" is not a ServiceAccount`,
+		},
+		{
+			name:     "name which would make the username ambiguous",
+			verifier: &fakeVerifier{subject: "system:serviceaccount:some-namespace:some-name@other-cluster"},
+			wantErr:  `subject "system:serviceaccount:some-namespace:some-name@other-cluster" is not a ServiceAccount`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			trustedCluster := &TrustedCluster{
+				Name:     "some-cluster",
+				Issuer:   "https://issuer.example.com/path",
+				Verifier: test.verifier,
+			}
+			identity, err := trustedCluster.Authenticate(context.Background(), "some-token")
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, identity)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantIdentity, identity)
+		})
+	}
+}
+
+func TestDynamicTrustedClusterProvider(t *testing.T) {
+	p := NewDynamicTrustedClusterProvider()
+	require.Nil(t, p.FindTrustedCluster("https://issuer.example.com"))
+
+	trustedCluster := &TrustedCluster{Name: "some-cluster", Issuer: "https://issuer.example.com"}
+	p.SetTrustedClusters([]*TrustedCluster{trustedCluster, {Name: "other-cluster", Issuer: "https://other.example.com"}})
+	require.Same(t, trustedCluster, p.FindTrustedCluster("https://issuer.example.com"))
+	require.Nil(t, p.FindTrustedCluster("https://unknown.example.com"))
+
+	p.SetTrustedClusters(nil)
+	require.Nil(t, p.FindTrustedCluster("https://issuer.example.com"))
+}
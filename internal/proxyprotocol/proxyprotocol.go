@@ -0,0 +1,196 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package proxyprotocol implements a net.Listener for the PROXY protocol versions 1 and 2, with which layer 4 load
+// balancers like HAProxy or AWS NLBs send the address of their client at the start of each connection.
+//
+// See https://www.haproxy.org/download/2.4/doc/proxy-protocol.txt.
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	// headerTimeout is how long a trusted peer has to send its header.
+	headerTimeout = 10 * time.Second
+
+	// maxV1HeaderLength is the longest possible version 1 header, including its CRLF.
+	maxV1HeaderLength = 107
+
+	v1Prefix = "PROXY "
+
+	errInvalidHeader = constable.Error("invalid PROXY protocol header")
+)
+
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n") //nolint:gochecknoglobals
+
+// NewListener wraps l so that the connections from trusted peers may start with a PROXY protocol header, whose source
+// address becomes the RemoteAddr of the connection. The connections of other peers are not changed, so they cannot
+// pretend to be from another address.
+func NewListener(l net.Listener, trustsPeer func(remoteAddr string) bool) net.Listener {
+	return &listener{Listener: l, trustsPeer: trustsPeer}
+}
+
+type listener struct {
+	net.Listener
+	trustsPeer func(remoteAddr string) bool
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.trustsPeer(c.RemoteAddr().String()) {
+		return c, nil
+	}
+	// The header is read when the connection is first used, so that a slow peer does not block Accept.
+	return &conn{Conn: c, reader: bufio.NewReader(c), remoteAddr: c.RemoteAddr()}, nil
+}
+
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	return c.remoteAddr
+}
+
+func (c *conn) readHeader() {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(headerTimeout)); err != nil {
+		c.err = err
+		return
+	}
+	defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+
+	addr, err := readHeader(c.reader)
+	if err != nil {
+		plog.Debug("could not read PROXY protocol header", "remoteAddr", c.remoteAddr.String(), "err", err)
+		c.err = err
+		return
+	}
+	if addr != nil {
+		c.remoteAddr = addr
+	}
+}
+
+// readHeader reads the header from the start of r, if there is one. It returns the source address of the header, or
+// nil when there is no header or the header does not have an address, e.g. for the health checks of a load balancer.
+func readHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case v1Prefix[0]:
+		prefix, err := r.Peek(len(v1Prefix))
+		if err != nil || string(prefix) != v1Prefix {
+			return nil, nil // e.g. an HTTP request with the PUT method
+		}
+		return readV1Header(r)
+	case v2Signature[0]:
+		signature, err := r.Peek(len(v2Signature))
+		if err != nil || !bytes.Equal(signature, v2Signature) {
+			return nil, nil
+		}
+		return readV2Header(r)
+	default:
+		return nil, nil
+	}
+}
+
+// readV1Header reads a header like "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readV1Header(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == maxV1HeaderLength {
+			return nil, fmt.Errorf("%w: version 1 header is too long", errInvalidHeader)
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: %q", errInvalidHeader, strings.TrimSpace(string(line)))
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("%w: %q", errInvalidHeader, strings.TrimSpace(string(line)))
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2Header reads a binary header, which is the signature, a version and command byte, an address family and
+// protocol byte, the length of the rest of the header, and then the addresses and any TLVs.
+func readV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(v2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	versionAndCommand, familyAndProtocol := header[12], header[13]
+	rest := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+
+	if versionAndCommand>>4 != 2 {
+		return nil, fmt.Errorf("%w: unknown version %d", errInvalidHeader, versionAndCommand>>4)
+	}
+	switch versionAndCommand & 0xf {
+	case 0: // LOCAL, e.g. a health check of the load balancer itself
+		return nil, nil
+	case 1: // PROXY
+	default:
+		return nil, fmt.Errorf("%w: unknown command %d", errInvalidHeader, versionAndCommand&0xf)
+	}
+
+	switch familyAndProtocol {
+	case 0x11, 0x12: // TCP or UDP over IPv4
+		if len(rest) < 12 {
+			return nil, fmt.Errorf("%w: IPv4 addresses are too short", errInvalidHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(rest[0:4]), Port: int(binary.BigEndian.Uint16(rest[8:10]))}, nil
+	case 0x21, 0x22: // TCP or UDP over IPv6
+		if len(rest) < 36 {
+			return nil, fmt.Errorf("%w: IPv6 addresses are too short", errInvalidHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(rest[0:16]), Port: int(binary.BigEndian.Uint16(rest[32:34]))}, nil
+	default: // e.g. unix sockets, which have no IP address
+		return nil, nil
+	}
+}
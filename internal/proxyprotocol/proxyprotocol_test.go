@@ -0,0 +1,167 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package proxyprotocol
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func v2Header(versionAndCommand, familyAndProtocol byte, addresses ...byte) string {
+	header := append([]byte{}, v2Signature...)
+	header = append(header, versionAndCommand, familyAndProtocol, byte(len(addresses)>>8), byte(len(addresses)))
+	return string(append(header, addresses...))
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantAddr  string
+		wantError string
+		wantRest  string
+	}{
+		{
+			name:     "no header",
+			input:    "GET / HTTP/1.1\r\n",
+			wantRest: "GET / HTTP/1.1\r\n",
+		},
+		{
+			name:     "no header with a method which starts like the v1 header",
+			input:    "PUT / HTTP/1.1\r\n",
+			wantRest: "PUT / HTTP/1.1\r\n",
+		},
+		{
+			name:     "v1 TCP4",
+			input:    "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nhello",
+			wantAddr: "192.0.2.1:56324",
+			wantRest: "hello",
+		},
+		{
+			name:     "v1 TCP6",
+			input:    "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\nhello",
+			wantAddr: "[2001:db8::1]:56324",
+			wantRest: "hello",
+		},
+		{
+			name:     "v1 UNKNOWN",
+			input:    "PROXY UNKNOWN\r\nhello",
+			wantRest: "hello",
+		},
+		{
+			name:      "v1 with a bad address",
+			input:     "PROXY TCP4 not-an-ip 198.51.100.1 56324 443\r\nhello",
+			wantError: `invalid PROXY protocol header: "PROXY TCP4 not-an-ip 198.51.100.1 56324 443"`,
+		},
+		{
+			name:      "v1 which is too long",
+			input:     "PROXY TCP4 " + strings.Repeat("1", 200),
+			wantError: "invalid PROXY protocol header: version 1 header is too long",
+		},
+		{
+			name:     "v2 TCP over IPv4",
+			input:    v2Header(0x21, 0x11, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb) + "hello",
+			wantAddr: "192.0.2.1:56324",
+			wantRest: "hello",
+		},
+		{
+			name: "v2 TCP over IPv6 with a TLV",
+			input: v2Header(0x21, 0x21,
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2,
+				0xdc, 0x04, 0x01, 0xbb,
+				0x04, 0x00, 0x01, 0x00,
+			) + "hello",
+			wantAddr: "[2001:db8::1]:56324",
+			wantRest: "hello",
+		},
+		{
+			name:     "v2 LOCAL",
+			input:    v2Header(0x20, 0x00) + "hello",
+			wantRest: "hello",
+		},
+		{
+			name:      "v2 with an unknown version",
+			input:     v2Header(0x11, 0x11, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb),
+			wantError: "invalid PROXY protocol header: unknown version 1",
+		},
+		{
+			name:      "v2 with short addresses",
+			input:     v2Header(0x21, 0x11, 192, 0, 2, 1),
+			wantError: "invalid PROXY protocol header: IPv4 addresses are too short",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(test.input))
+			addr, err := readHeader(r)
+			if test.wantError != "" {
+				require.EqualError(t, err, test.wantError)
+				return
+			}
+			require.NoError(t, err)
+			if test.wantAddr == "" {
+				require.Nil(t, addr)
+			} else {
+				require.Equal(t, test.wantAddr, addr.String())
+			}
+			rest, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, test.wantRest, string(rest))
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	tests := []struct {
+		name           string
+		trusted        bool
+		wantRemoteAddr string
+		wantData       string
+	}{
+		{
+			name:           "trusted peer",
+			trusted:        true,
+			wantRemoteAddr: "192.0.2.1:56324",
+			wantData:       "hello",
+		},
+		{
+			name:     "untrusted peer",
+			trusted:  false,
+			wantData: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nhello",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			rawListener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			l := NewListener(rawListener, func(string) bool { return test.trusted })
+			defer func() { _ = l.Close() }()
+
+			client, err := net.Dial("tcp", l.Addr().String())
+			require.NoError(t, err)
+			_, err = client.Write([]byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nhello"))
+			require.NoError(t, err)
+			require.NoError(t, client.Close())
+
+			c, err := l.Accept()
+			require.NoError(t, err)
+			wantRemoteAddr := test.wantRemoteAddr
+			if wantRemoteAddr == "" {
+				wantRemoteAddr = client.LocalAddr().String()
+			}
+			require.Equal(t, wantRemoteAddr, c.RemoteAddr().String())
+			data, err := ioutil.ReadAll(c)
+			require.NoError(t, err)
+			require.Equal(t, test.wantData, string(data))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package psession provides the session type which the Supervisor stores alongside each downstream request.
+package psession
+
+import (
+	"time"
+
+	"github.com/mohae/deepcopy"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+)
+
+// PinnipedSession is a session container which can be used with fosite.
+type PinnipedSession struct {
+	// Delegate most things to the embedded fosite session.
+	Fosite *openid.DefaultSession `json:"fosite,omitempty"`
+
+	// Custom Pinniped extensions to the session data.
+	Custom *CustomSessionData `json:"custom,omitempty"`
+}
+
+var _ openid.Session = &PinnipedSession{}
+
+// CustomSessionData is the custom session data needed by Pinniped. It should be treated as a union type,
+// where the value of ProviderType decides which other fields to use.
+type CustomSessionData struct {
+	// The name of the upstream identity provider which was used to start this session.
+	// This may be empty for sessions which were not started by an end user, e.g. client credentials grants.
+	ProviderName string `json:"providerName,omitempty"`
+
+	// The type of the upstream identity provider which was used to start this session.
+	ProviderType ProviderType `json:"providerType,omitempty"`
+}
+
+// ProviderType is the type of an upstream identity provider.
+type ProviderType string
+
+const (
+	ProviderTypeOIDC ProviderType = "oidc"
+	ProviderTypeLDAP ProviderType = "ldap"
+)
+
+// NewPinnipedSession returns a new empty session.
+func NewPinnipedSession() *PinnipedSession {
+	return &PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims:  &jwt.IDTokenClaims{},
+			Headers: &jwt.Headers{},
+		},
+		Custom: &CustomSessionData{},
+	}
+}
+
+func (s *PinnipedSession) Clone() fosite.Session {
+	// Implementation copied from openid.DefaultSession's clone method, which makes a deep copy.
+	if s == nil {
+		return nil
+	}
+	return deepcopy.Copy(s).(fosite.Session)
+}
+
+func (s *PinnipedSession) SetExpiresAt(key fosite.TokenType, exp time.Time) {
+	s.Fosite.SetExpiresAt(key, exp)
+}
+
+func (s *PinnipedSession) GetExpiresAt(key fosite.TokenType) time.Time {
+	return s.Fosite.GetExpiresAt(key)
+}
+
+func (s *PinnipedSession) GetUsername() string {
+	return s.Fosite.GetUsername()
+}
+
+func (s *PinnipedSession) SetSubject(subject string) {
+	s.Fosite.SetSubject(subject)
+}
+
+func (s *PinnipedSession) GetSubject() string {
+	return s.Fosite.GetSubject()
+}
+
+func (s *PinnipedSession) IDTokenHeaders() *jwt.Headers {
+	return s.Fosite.IDTokenHeaders()
+}
+
+func (s *PinnipedSession) IDTokenClaims() *jwt.IDTokenClaims {
+	return s.Fosite.IDTokenClaims()
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialrequest
+
+import (
+	"errors"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
+)
+
+const (
+	resultSuccess              = "success"
+	resultUnauthenticated      = "unauthenticated"
+	resultUnknownAuthenticator = "unknown_authenticator"
+	resultError                = "error"
+)
+
+//nolint:gochecknoglobals
+var tokenCredentialRequests = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Namespace:      "pinniped",
+		Subsystem:      "concierge",
+		Name:           "token_credential_requests_total",
+		Help:           "Number of TokenCredentialRequests by authenticator and result.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"authenticator_type", "authenticator_name", "result"},
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(tokenCredentialRequests)
+}
+
+// observeResult counts a TokenCredentialRequest which passed validation. The authenticator labels are left empty
+// when the requested authenticator does not exist, since the client could otherwise put anything into them.
+func observeResult(req *loginapi.TokenCredentialRequest, result string, err error) {
+	authenticatorType, authenticatorName := req.Spec.Authenticator.Kind, req.Spec.Authenticator.Name
+	if errors.Is(err, authncache.ErrNoSuchAuthenticator) {
+		authenticatorType, authenticatorName, result = "", "", resultUnknownAuthenticator
+	}
+	tokenCredentialRequests.WithLabelValues(authenticatorType, authenticatorName, result).Inc()
+}
@@ -99,10 +99,12 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 	userInfo, err := r.authenticator.AuthenticateTokenCredentialRequest(ctx, credentialRequest)
 	if err != nil {
 		traceFailureWithError(t, "token authentication", err)
+		observeResult(credentialRequest, resultError, err)
 		return failureResponse(), nil
 	}
 	if ok := isUserInfoValid(userInfo); !ok {
 		traceSuccess(t, userInfo, false)
+		observeResult(credentialRequest, resultUnauthenticated, nil)
 		return failureResponse(), nil
 	}
 
@@ -111,10 +113,12 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 	certPEM, keyPEM, err := r.issuer.IssueClientCertPEM(userInfo.GetName(), userInfo.GetGroups(), clientCertificateTTL)
 	if err != nil {
 		traceFailureWithError(t, "cert issuer", err)
+		observeResult(credentialRequest, resultError, err)
 		return failureResponse(), nil
 	}
 
 	traceSuccess(t, userInfo, true)
+	observeResult(credentialRequest, resultSuccess, nil)
 
 	return &loginapi.TokenCredentialRequest{
 		Status: loginapi.TokenCredentialRequestStatus{
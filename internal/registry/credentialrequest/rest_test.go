@@ -13,6 +13,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,10 +21,12 @@ import (
 	"k8s.io/apiserver/pkg/authentication/user"
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+	metricstestutil "k8s.io/component-base/metrics/testutil"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 
 	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
 	"go.pinniped.dev/internal/issuer"
 	"go.pinniped.dev/internal/mocks/credentialrequestmocks"
 	"go.pinniped.dev/internal/mocks/issuermocks"
@@ -332,6 +335,51 @@ func TestCreate(t *testing.T) {
 			requireAPIError(t, response, err, apierrors.IsBadRequest, `namespace is not allowed on TokenCredentialRequest: some-ns`)
 			requireOneLogStatement(r, logger, `"failure" failureType:request validation,msg:namespace is not allowed`)
 		})
+
+		it("CreateCountsTheResultsByAuthenticator", func() {
+			req := credentialRequest(loginapi.TokenCredentialRequestSpec{
+				Token:         "some token",
+				Authenticator: corev1.TypedLocalObjectReference{Kind: "WebhookAuthenticator", Name: "counted-webhook"},
+			})
+			unknownReq := credentialRequest(loginapi.TokenCredentialRequestSpec{
+				Token:         "some token",
+				Authenticator: corev1.TypedLocalObjectReference{Kind: "anything-the-client-wants", Name: "counted-unknown"},
+			})
+
+			requestAuthenticator := credentialrequestmocks.NewMockTokenCredentialRequestAuthenticator(ctrl)
+			gomock.InOrder(
+				requestAuthenticator.EXPECT().AuthenticateTokenCredentialRequest(gomock.Any(), req).
+					Return(&user.DefaultInfo{Name: "test-user"}, nil),
+				requestAuthenticator.EXPECT().AuthenticateTokenCredentialRequest(gomock.Any(), req).
+					Return(nil, nil),
+				requestAuthenticator.EXPECT().AuthenticateTokenCredentialRequest(gomock.Any(), req).
+					Return(nil, errors.New("some webhook error")),
+				requestAuthenticator.EXPECT().AuthenticateTokenCredentialRequest(gomock.Any(), unknownReq).
+					Return(nil, fmt.Errorf("wrapped: %w", authncache.ErrNoSuchAuthenticator)),
+			)
+
+			counter := func(labels ...string) float64 {
+				value, err := metricstestutil.GetCounterMetricValue(tokenCredentialRequests.WithLabelValues(labels...))
+				r.NoError(err)
+				return value
+			}
+			successesBefore := counter("WebhookAuthenticator", "counted-webhook", "success")
+			unauthenticatedBefore := counter("WebhookAuthenticator", "counted-webhook", "unauthenticated")
+			errorsBefore := counter("WebhookAuthenticator", "counted-webhook", "error")
+			unknownBefore := counter("", "", "unknown_authenticator")
+
+			storage := NewREST(requestAuthenticator, successfulIssuer(ctrl), schema.GroupResource{})
+			for _, obj := range []runtime.Object{req, req, req, unknownReq} {
+				_, err := callCreate(context.Background(), storage, obj)
+				r.NoError(err)
+			}
+
+			r.Equal(successesBefore+1, counter("WebhookAuthenticator", "counted-webhook", "success"))
+			r.Equal(unauthenticatedBefore+1, counter("WebhookAuthenticator", "counted-webhook", "unauthenticated"))
+			r.Equal(errorsBefore+1, counter("WebhookAuthenticator", "counted-webhook", "error"))
+			r.Equal(unknownBefore+1, counter("", "", "unknown_authenticator"))
+			r.Zero(counter("anything-the-client-wants", "counted-unknown", "unknown_authenticator"))
+		})
 	}, spec.Sequential())
 }
 
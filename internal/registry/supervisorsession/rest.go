@@ -0,0 +1,177 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package supervisorsession serves the SupervisorSessions of the Supervisor's aggregated API from the Secrets in
+// which the Supervisor stores the access tokens and refresh tokens of each session.
+package supervisorsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/registry/rest"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	sessionapi "go.pinniped.dev/generated/latest/apis/supervisor/session"
+	sessionv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/session/v1alpha1"
+	"go.pinniped.dev/internal/fositestorage/sessions"
+)
+
+func NewREST(secrets corev1client.SecretInterface, resource schema.GroupResource) *REST {
+	return &REST{
+		secrets:        secrets,
+		resource:       resource,
+		tableConvertor: rest.NewDefaultTableConvertor(resource),
+	}
+}
+
+type REST struct {
+	secrets        corev1client.SecretInterface
+	resource       schema.GroupResource
+	tableConvertor rest.TableConvertor
+}
+
+// Assert that our *REST implements all the optional interfaces that we expect it to implement.
+var _ interface {
+	rest.Getter
+	rest.Lister
+	rest.GracefulDeleter
+	rest.Scoper
+	rest.Storage
+	rest.CategoriesProvider
+} = (*REST)(nil)
+
+func (*REST) New() runtime.Object {
+	return &sessionapi.SupervisorSession{}
+}
+
+func (*REST) NewList() runtime.Object {
+	return &sessionapi.SupervisorSessionList{}
+}
+
+func (r *REST) ConvertToTable(ctx context.Context, obj runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	return r.tableConvertor.ConvertToTable(ctx, obj, tableOptions)
+}
+
+func (*REST) NamespaceScoped() bool {
+	return false
+}
+
+func (*REST) Categories() []string {
+	return []string{"pinniped"}
+}
+
+func (r *REST) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	out := &sessionapi.SupervisorSessionList{
+		Items: []sessionapi.SupervisorSession{}, // avoid sending nil items list
+	}
+
+	var filter sessions.Filter
+	name := ""
+	if options != nil {
+		// sessions do not have labels, so only an empty label selector can match them
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(labels.Set(nil)) {
+			return out, nil
+		}
+		if options.FieldSelector != nil {
+			for _, requirement := range options.FieldSelector.Requirements() {
+				if requirement.Operator != selection.Equals && requirement.Operator != selection.DoubleEquals {
+					return nil, apierrors.NewBadRequest(fmt.Sprintf("unsupported operator %q in field selector %q, only equality is supported", requirement.Operator, options.FieldSelector))
+				}
+				switch requirement.Field {
+				case "metadata.name":
+					name = requirement.Value
+				case sessionv1alpha1.UsernameFieldLabel:
+					filter.Username = requirement.Value
+				case sessionv1alpha1.GroupsFieldLabel:
+					filter.Group = requirement.Value
+				default:
+					return nil, apierrors.NewBadRequest(fmt.Sprintf("field label not supported: %s", requirement.Field))
+				}
+			}
+		}
+	}
+
+	found, err := sessions.List(ctx, r.secrets, filter)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+
+	for i := range found {
+		if name != "" && found[i].ID != name {
+			continue
+		}
+		out.Items = append(out.Items, *toSupervisorSession(&found[i]))
+	}
+	return out, nil
+}
+
+func (r *REST) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	session, err := sessions.Get(ctx, r.secrets, name)
+	if err != nil {
+		return nil, r.toAPIError(name, err)
+	}
+	return toSupervisorSession(session), nil
+}
+
+func (r *REST) Delete(ctx context.Context, name string, deleteValidation rest.ValidateObjectFunc, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
+	session, err := sessions.Get(ctx, r.secrets, name)
+	if err != nil {
+		return nil, false, r.toAPIError(name, err)
+	}
+	out := toSupervisorSession(session)
+
+	// just a sanity check, the sessions are not stored in a way which would allow a dry run
+	if options != nil && len(options.DryRun) != 0 {
+		errs := field.ErrorList{field.NotSupported(field.NewPath("dryRun"), options.DryRun, nil)}
+		return nil, false, apierrors.NewInvalid(sessionapi.Kind("SupervisorSession"), name, errs)
+	}
+
+	if deleteValidation != nil {
+		if err := deleteValidation(ctx, out.DeepCopyObject()); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := sessions.Revoke(ctx, r.secrets, name); err != nil {
+		return nil, false, r.toAPIError(name, err)
+	}
+	return out, true, nil
+}
+
+func (r *REST) toAPIError(name string, err error) error {
+	if errors.Is(err, sessions.ErrSessionNotFound) {
+		return apierrors.NewNotFound(r.resource, name)
+	}
+	return apierrors.NewInternalError(err)
+}
+
+func toSupervisorSession(session *sessions.Session) *sessionapi.SupervisorSession {
+	return &sessionapi.SupervisorSession{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              session.ID,
+			CreationTimestamp: metav1.NewTime(session.IssuedAt),
+		},
+		Status: sessionapi.SupervisorSessionStatus{
+			Username: session.Username,
+			Groups:   session.Groups,
+			Subject:  session.Subject,
+			IdentityProvider: sessionapi.SupervisorSessionIdentityProvider{
+				Name: session.ProviderName,
+				Type: string(session.ProviderType),
+			},
+			ClientID:  session.ClientID,
+			IssuedAt:  metav1.NewTime(session.IssuedAt),
+			ExpiresAt: metav1.NewTime(session.ExpiresAt),
+		},
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorsession
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	sessionapi "go.pinniped.dev/generated/latest/apis/supervisor/session"
+	"go.pinniped.dev/internal/fositestorage/accesstoken"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/psession"
+)
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var resource = schema.GroupResource{Group: "session.supervisor.pinniped.dev", Resource: "supervisorsessions"}
+
+func TestNew(t *testing.T) {
+	r := NewREST(fake.NewSimpleClientset().CoreV1().Secrets("some-namespace"), schema.GroupResource{Group: "bears", Resource: "panda"})
+	require.NotNil(t, r)
+	require.False(t, r.NamespaceScoped())
+	require.Equal(t, []string{"pinniped"}, r.Categories())
+	require.IsType(t, &sessionapi.SupervisorSession{}, r.New())
+	require.IsType(t, &sessionapi.SupervisorSessionList{}, r.NewList())
+
+	ctx := context.Background()
+
+	list, err := r.List(ctx, nil)
+	require.NoError(t, err)
+	require.IsType(t, &sessionapi.SupervisorSessionList{}, list)
+	require.NotNil(t, list.(*sessionapi.SupervisorSessionList).Items)
+	require.Len(t, list.(*sessionapi.SupervisorSessionList).Items, 0)
+
+	// make sure we can turn lists into tables if needed
+	table, err := r.ConvertToTable(ctx, list, nil)
+	require.NoError(t, err)
+	require.NotNil(t, table)
+	require.Nil(t, table.Rows)
+
+	// exercise group resource - force error by passing a runtime.Object that does not have an embedded object meta
+	_, err = r.ConvertToTable(ctx, &metav1.APIGroup{}, nil)
+	require.Error(t, err, "the resource panda.bears does not support being converted to a Table")
+}
+
+func TestList(t *testing.T) {
+	secrets := fake.NewSimpleClientset().CoreV1().Secrets("some-namespace")
+	createSession(t, secrets, "request-1", "alice", []string{"admins", "devs"}, fakeNow.Add(time.Minute))
+	createSession(t, secrets, "request-2", "bob", []string{"devs"}, fakeNow)
+	alice, bob := wantSession("request-1", "alice", []string{"admins", "devs"}, fakeNow.Add(time.Minute)), wantSession("request-2", "bob", []string{"devs"}, fakeNow)
+
+	tests := []struct {
+		name    string
+		options *metainternalversion.ListOptions
+		want    []sessionapi.SupervisorSession
+		wantErr string
+	}{
+		{
+			name: "no selectors",
+			want: []sessionapi.SupervisorSession{*bob, *alice},
+		},
+		{
+			name:    "by username",
+			options: &metainternalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.username", "alice")},
+			want:    []sessionapi.SupervisorSession{*alice},
+		},
+		{
+			name:    "by group",
+			options: &metainternalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.groups", "devs")},
+			want:    []sessionapi.SupervisorSession{*bob, *alice},
+		},
+		{
+			name:    "by username and group",
+			options: &metainternalversion.ListOptions{FieldSelector: fields.ParseSelectorOrDie("status.username=bob,status.groups=admins")},
+			want:    []sessionapi.SupervisorSession{},
+		},
+		{
+			name:    "by name",
+			options: &metainternalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", "request-2")},
+			want:    []sessionapi.SupervisorSession{*bob},
+		},
+		{
+			name:    "with a label selector",
+			options: &metainternalversion.ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set{"some-label": "some-value"})},
+			want:    []sessionapi.SupervisorSession{},
+		},
+		{
+			name:    "unsupported field",
+			options: &metainternalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.subject", "some-subject")},
+			wantErr: "field label not supported: status.subject",
+		},
+		{
+			name:    "unsupported operator",
+			options: &metainternalversion.ListOptions{FieldSelector: fields.OneTermNotEqualSelector("status.username", "alice")},
+			wantErr: `unsupported operator "!=" in field selector "status.username!=alice", only equality is supported`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewREST(secrets, resource)
+			got, err := r.List(context.Background(), tt.options)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.True(t, apierrors.IsBadRequest(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, &sessionapi.SupervisorSessionList{Items: tt.want}, got)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	secrets := fake.NewSimpleClientset().CoreV1().Secrets("some-namespace")
+	createSession(t, secrets, "request-1", "alice", []string{"admins"}, fakeNow)
+	r := NewREST(secrets, resource)
+
+	got, err := r.Get(context.Background(), "request-1", &metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, wantSession("request-1", "alice", []string{"admins"}, fakeNow), got)
+
+	_, err = r.Get(context.Background(), "request-2", &metav1.GetOptions{})
+	require.EqualError(t, err, `supervisorsessions.session.supervisor.pinniped.dev "request-2" not found`)
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	secrets := fake.NewSimpleClientset().CoreV1().Secrets("some-namespace")
+	createSession(t, secrets, "request-1", "alice", []string{"admins"}, fakeNow)
+	createSession(t, secrets, "request-2", "bob", []string{"devs"}, fakeNow)
+	r := NewREST(secrets, resource)
+
+	_, _, err := r.Delete(ctx, "request-1", nil, &metav1.DeleteOptions{DryRun: []string{metav1.DryRunAll}})
+	require.EqualError(t, err, `SupervisorSession.session.supervisor.pinniped.dev "request-1" is invalid: dryRun: Unsupported value: []string{"All"}`)
+
+	_, _, err = r.Delete(ctx, "request-1", func(context.Context, runtime.Object) error { return errors.New("some validation error") }, nil)
+	require.EqualError(t, err, "some validation error")
+
+	// none of the failed deletes should have revoked anything
+	list, err := r.List(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, list.(*sessionapi.SupervisorSessionList).Items, 2)
+
+	deleted, immediate, err := r.Delete(ctx, "request-1", func(_ context.Context, obj runtime.Object) error {
+		require.Equal(t, "request-1", obj.(*sessionapi.SupervisorSession).Name)
+		return nil
+	}, &metav1.DeleteOptions{})
+	require.NoError(t, err)
+	require.True(t, immediate)
+	require.Equal(t, wantSession("request-1", "alice", []string{"admins"}, fakeNow), deleted)
+
+	list, err = r.List(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, []sessionapi.SupervisorSession{*wantSession("request-2", "bob", []string{"devs"}, fakeNow)}, list.(*sessionapi.SupervisorSessionList).Items)
+
+	_, _, err = r.Delete(ctx, "request-1", nil, &metav1.DeleteOptions{})
+	require.EqualError(t, err, `supervisorsessions.session.supervisor.pinniped.dev "request-1" not found`)
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func wantSession(id, username string, groups []string, authTime time.Time) *sessionapi.SupervisorSession {
+	return &sessionapi.SupervisorSession{
+		ObjectMeta: metav1.ObjectMeta{Name: id, CreationTimestamp: metav1.NewTime(authTime)},
+		Status: sessionapi.SupervisorSessionStatus{
+			Username:         username,
+			Groups:           groups,
+			Subject:          "https://upstream?sub=" + username,
+			IdentityProvider: sessionapi.SupervisorSessionIdentityProvider{Name: "some-upstream", Type: "ldap"},
+			ClientID:         "pinniped-cli",
+			IssuedAt:         metav1.NewTime(authTime),
+			ExpiresAt:        metav1.NewTime(fakeNow.Add(9 * time.Hour)),
+		},
+	}
+}
+
+func createSession(t *testing.T, secrets corev1client.SecretInterface, id, username string, groups []string, authTime time.Time) {
+	t.Helper()
+
+	groupsAsInterfaces := make([]interface{}, 0, len(groups))
+	for _, g := range groups {
+		groupsAsInterfaces = append(groupsAsInterfaces, g)
+	}
+	request := &fosite.Request{
+		ID:          id,
+		RequestedAt: authTime,
+		Client:      &clientregistry.Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: "pinniped-cli"}}},
+		Session: &psession.PinnipedSession{
+			Fosite: &openid.DefaultSession{
+				Claims: &jwt.IDTokenClaims{
+					Subject:  "https://upstream?sub=" + username,
+					AuthTime: authTime,
+					Extra:    map[string]interface{}{"username": username, "groups": groupsAsInterfaces},
+				},
+			},
+			Custom: &psession.CustomSessionData{ProviderName: "some-upstream", ProviderType: psession.ProviderTypeLDAP},
+		},
+	}
+
+	clock := func() time.Time { return fakeNow }
+	require.NoError(t, accesstoken.New(secrets, clock, 2*time.Minute).CreateAccessTokenSession(context.Background(), id+"-access", request))
+	require.NoError(t, refreshtoken.New(secrets, clock, 9*time.Hour).CreateRefreshTokenSession(context.Background(), id+"-refresh", request))
+}
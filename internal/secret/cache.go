@@ -1,4 +1,4 @@
-// Copyright 2020 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package secret
@@ -8,26 +8,28 @@ import (
 	"sync/atomic"
 )
 
+// Cache holds the keys used by the Supervisor. The CSRF cookie and state keys are ordered lists, newest first,
+// since those keys can be rotated while values encoded with the previous keys are still in flight.
 type Cache struct {
-	csrfCookieEncoderHashKey atomic.Value
-	federationDomainCacheMap sync.Map
+	csrfCookieEncoderHashKeys atomic.Value
+	federationDomainCacheMap  sync.Map
 }
 
 // New returns an empty Cache.
 func New() *Cache { return &Cache{} }
 
 type federationDomainCache struct {
-	tokenHMACKey         atomic.Value
-	stateEncoderHashKey  atomic.Value
-	stateEncoderBlockKey atomic.Value
+	tokenHMACKey          atomic.Value
+	stateEncoderHashKeys  atomic.Value
+	stateEncoderBlockKeys atomic.Value
 }
 
-func (c *Cache) GetCSRFCookieEncoderHashKey() []byte {
-	return bytesOrNil(c.csrfCookieEncoderHashKey.Load())
+func (c *Cache) GetCSRFCookieEncoderHashKeys() [][]byte {
+	return keysOrNil(c.csrfCookieEncoderHashKeys.Load())
 }
 
-func (c *Cache) SetCSRFCookieEncoderHashKey(key []byte) {
-	c.csrfCookieEncoderHashKey.Store(key)
+func (c *Cache) SetCSRFCookieEncoderHashKeys(keys [][]byte) {
+	c.csrfCookieEncoderHashKeys.Store(keys)
 }
 
 func (c *Cache) GetTokenHMACKey(oidcIssuer string) []byte {
@@ -38,20 +40,20 @@ func (c *Cache) SetTokenHMACKey(oidcIssuer string, key []byte) {
 	c.getFederationDomainCache(oidcIssuer).tokenHMACKey.Store(key)
 }
 
-func (c *Cache) GetStateEncoderHashKey(oidcIssuer string) []byte {
-	return bytesOrNil(c.getFederationDomainCache(oidcIssuer).stateEncoderHashKey.Load())
+func (c *Cache) GetStateEncoderHashKeys(oidcIssuer string) [][]byte {
+	return keysOrNil(c.getFederationDomainCache(oidcIssuer).stateEncoderHashKeys.Load())
 }
 
-func (c *Cache) SetStateEncoderHashKey(oidcIssuer string, key []byte) {
-	c.getFederationDomainCache(oidcIssuer).stateEncoderHashKey.Store(key)
+func (c *Cache) SetStateEncoderHashKeys(oidcIssuer string, keys [][]byte) {
+	c.getFederationDomainCache(oidcIssuer).stateEncoderHashKeys.Store(keys)
 }
 
-func (c *Cache) GetStateEncoderBlockKey(oidcIssuer string) []byte {
-	return bytesOrNil(c.getFederationDomainCache(oidcIssuer).stateEncoderBlockKey.Load())
+func (c *Cache) GetStateEncoderBlockKeys(oidcIssuer string) [][]byte {
+	return keysOrNil(c.getFederationDomainCache(oidcIssuer).stateEncoderBlockKeys.Load())
 }
 
-func (c *Cache) SetStateEncoderBlockKey(oidcIssuer string, key []byte) {
-	c.getFederationDomainCache(oidcIssuer).stateEncoderBlockKey.Store(key)
+func (c *Cache) SetStateEncoderBlockKeys(oidcIssuer string, keys [][]byte) {
+	c.getFederationDomainCache(oidcIssuer).stateEncoderBlockKeys.Store(keys)
 }
 
 func (c *Cache) getFederationDomainCache(oidcIssuer string) *federationDomainCache {
@@ -69,3 +71,10 @@ func bytesOrNil(b interface{}) []byte {
 	}
 	return b.([]byte)
 }
+
+func keysOrNil(keys interface{}) [][]byte {
+	if keys == nil {
+		return nil
+	}
+	return keys.([][]byte)
+}
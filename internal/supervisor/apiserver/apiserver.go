@@ -0,0 +1,88 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package apiserver contains the Supervisor's aggregated API server, which serves the SupervisorSession API.
+package apiserver
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/version"
+
+	"go.pinniped.dev/internal/registry/supervisorsession"
+)
+
+type Config struct {
+	GenericConfig *genericapiserver.RecommendedConfig
+	ExtraConfig   ExtraConfig
+}
+
+type ExtraConfig struct {
+	// Secrets is a client for the Supervisor's namespace, in which the tokens of the sessions are stored.
+	Secrets                       corev1client.SecretInterface
+	Scheme                        *runtime.Scheme
+	NegotiatedSerializer          runtime.NegotiatedSerializer
+	SessionSupervisorGroupVersion schema.GroupVersion
+}
+
+type PinnipedServer struct {
+	GenericAPIServer *genericapiserver.GenericAPIServer
+}
+
+type completedConfig struct {
+	GenericConfig genericapiserver.CompletedConfig
+	ExtraConfig   *ExtraConfig
+}
+
+type CompletedConfig struct {
+	// Embed a private pointer that cannot be instantiated outside of this package.
+	*completedConfig
+}
+
+// Complete fills in any fields not set that are required to have valid data. It's mutating the receiver.
+func (c *Config) Complete() CompletedConfig {
+	completedCfg := completedConfig{
+		c.GenericConfig.Complete(),
+		&c.ExtraConfig,
+	}
+
+	versionInfo := version.Get()
+	completedCfg.GenericConfig.Version = &versionInfo
+
+	return CompletedConfig{completedConfig: &completedCfg}
+}
+
+// New returns a new instance of PinnipedServer from the given config.
+func (c completedConfig) New() (*PinnipedServer, error) {
+	genericServer, err := c.GenericConfig.New("pinniped-supervisor", genericapiserver.NewEmptyDelegate()) // completion is done in Complete, no need for a second time
+	if err != nil {
+		return nil, fmt.Errorf("completion error: %w", err)
+	}
+
+	s := &PinnipedServer{
+		GenericAPIServer: genericServer,
+	}
+
+	sessionGVR := c.ExtraConfig.SessionSupervisorGroupVersion.WithResource("supervisorsessions")
+	sessionStorage := supervisorsession.NewREST(c.ExtraConfig.Secrets, sessionGVR.GroupResource())
+	if err := s.GenericAPIServer.InstallAPIGroup(
+		&genericapiserver.APIGroupInfo{
+			PrioritizedVersions:          []schema.GroupVersion{sessionGVR.GroupVersion()},
+			VersionedResourcesStorageMap: map[string]map[string]rest.Storage{sessionGVR.Version: {sessionGVR.Resource: sessionStorage}},
+			OptionsExternalVersion:       &schema.GroupVersion{Version: "v1"},
+			Scheme:                       c.ExtraConfig.Scheme,
+			ParameterCodec:               metav1.ParameterCodec,
+			NegotiatedSerializer:         c.ExtraConfig.NegotiatedSerializer,
+		},
+	); err != nil {
+		return nil, fmt.Errorf("could not install API groups: %w", err)
+	}
+
+	return s, nil
+}
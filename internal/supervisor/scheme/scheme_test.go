@@ -0,0 +1,123 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package scheme
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	sessionapi "go.pinniped.dev/generated/latest/apis/supervisor/session"
+	sessionv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/session/v1alpha1"
+)
+
+func TestNew(t *testing.T) {
+	// the standard group
+	regularSessionGV := schema.GroupVersion{
+		Group:   "session.supervisor.pinniped.dev",
+		Version: "v1alpha1",
+	}
+	regularSessionGVInternal := schema.GroupVersion{
+		Group:   "session.supervisor.pinniped.dev",
+		Version: runtime.APIVersionInternal,
+	}
+
+	// the canonical other group
+	otherSessionGV := schema.GroupVersion{
+		Group:   "session.supervisor.walrus.tld",
+		Version: "v1alpha1",
+	}
+	otherSessionGVInternal := schema.GroupVersion{
+		Group:   "session.supervisor.walrus.tld",
+		Version: runtime.APIVersionInternal,
+	}
+
+	// kube's core internal
+	internalGV := schema.GroupVersion{
+		Group:   "",
+		Version: runtime.APIVersionInternal,
+	}
+
+	wantTypes := func(sessionGV, sessionGVInternal schema.GroupVersion) map[schema.GroupVersionKind]reflect.Type {
+		return map[schema.GroupVersionKind]reflect.Type{
+			// all the types that are in the aggregated API group
+
+			sessionGV.WithKind("SupervisorSession"):     reflect.TypeOf(&sessionv1alpha1.SupervisorSession{}).Elem(),
+			sessionGV.WithKind("SupervisorSessionList"): reflect.TypeOf(&sessionv1alpha1.SupervisorSessionList{}).Elem(),
+
+			sessionGVInternal.WithKind("SupervisorSession"):     reflect.TypeOf(&sessionapi.SupervisorSession{}).Elem(),
+			sessionGVInternal.WithKind("SupervisorSessionList"): reflect.TypeOf(&sessionapi.SupervisorSessionList{}).Elem(),
+
+			sessionGV.WithKind("CreateOptions"): reflect.TypeOf(&metav1.CreateOptions{}).Elem(),
+			sessionGV.WithKind("DeleteOptions"): reflect.TypeOf(&metav1.DeleteOptions{}).Elem(),
+			sessionGV.WithKind("GetOptions"):    reflect.TypeOf(&metav1.GetOptions{}).Elem(),
+			sessionGV.WithKind("ListOptions"):   reflect.TypeOf(&metav1.ListOptions{}).Elem(),
+			sessionGV.WithKind("PatchOptions"):  reflect.TypeOf(&metav1.PatchOptions{}).Elem(),
+			sessionGV.WithKind("UpdateOptions"): reflect.TypeOf(&metav1.UpdateOptions{}).Elem(),
+			sessionGV.WithKind("WatchEvent"):    reflect.TypeOf(&metav1.WatchEvent{}).Elem(),
+
+			sessionGVInternal.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
+
+			// the types below this line do not really matter to us because they are in the core group
+
+			internalGV.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
+
+			metav1.Unversioned.WithKind("APIGroup"):        reflect.TypeOf(&metav1.APIGroup{}).Elem(),
+			metav1.Unversioned.WithKind("APIGroupList"):    reflect.TypeOf(&metav1.APIGroupList{}).Elem(),
+			metav1.Unversioned.WithKind("APIResourceList"): reflect.TypeOf(&metav1.APIResourceList{}).Elem(),
+			metav1.Unversioned.WithKind("APIVersions"):     reflect.TypeOf(&metav1.APIVersions{}).Elem(),
+			metav1.Unversioned.WithKind("CreateOptions"):   reflect.TypeOf(&metav1.CreateOptions{}).Elem(),
+			metav1.Unversioned.WithKind("DeleteOptions"):   reflect.TypeOf(&metav1.DeleteOptions{}).Elem(),
+			metav1.Unversioned.WithKind("GetOptions"):      reflect.TypeOf(&metav1.GetOptions{}).Elem(),
+			metav1.Unversioned.WithKind("ListOptions"):     reflect.TypeOf(&metav1.ListOptions{}).Elem(),
+			metav1.Unversioned.WithKind("PatchOptions"):    reflect.TypeOf(&metav1.PatchOptions{}).Elem(),
+			metav1.Unversioned.WithKind("Status"):          reflect.TypeOf(&metav1.Status{}).Elem(),
+			metav1.Unversioned.WithKind("UpdateOptions"):   reflect.TypeOf(&metav1.UpdateOptions{}).Elem(),
+			metav1.Unversioned.WithKind("WatchEvent"):      reflect.TypeOf(&metav1.WatchEvent{}).Elem(),
+		}
+	}
+
+	tests := []struct {
+		name                    string
+		apiGroupSuffix          string
+		want                    map[schema.GroupVersionKind]reflect.Type
+		wantSessionGroupVersion schema.GroupVersion
+	}{
+		{
+			name:                    "regular api group",
+			apiGroupSuffix:          "pinniped.dev",
+			want:                    wantTypes(regularSessionGV, regularSessionGVInternal),
+			wantSessionGroupVersion: regularSessionGV,
+		},
+		{
+			name:                    "other api group",
+			apiGroupSuffix:          "walrus.tld",
+			want:                    wantTypes(otherSessionGV, otherSessionGVInternal),
+			wantSessionGroupVersion: otherSessionGV,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, sessionGV := New(tt.apiGroupSuffix)
+			require.Equal(t, tt.want, scheme.AllKnownTypes())
+			require.Equal(t, tt.wantSessionGroupVersion, sessionGV)
+
+			// the field selectors for listing sessions must work at the group which is actually served
+			gvk := sessionGV.WithKind("SupervisorSession")
+			for _, label := range []string{"metadata.name", "status.username", "status.groups"} {
+				gotLabel, gotValue, err := scheme.ConvertFieldLabel(gvk, label, "some-value")
+				require.NoError(t, err)
+				require.Equal(t, label, gotLabel)
+				require.Equal(t, "some-value", gotValue)
+			}
+			_, _, err := scheme.ConvertFieldLabel(gvk, "status.subject", "some-value")
+			require.EqualError(t, err, "field label not supported: status.subject")
+		})
+	}
+}
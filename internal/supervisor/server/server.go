@@ -8,29 +8,46 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"strings"
+	"syscall"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/util/clock"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/healthz"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/pkg/version"
 	"k8s.io/client-go/rest"
 	"k8s.io/component-base/logs"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
+	aggregatorclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
 
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/auditlog"
 	"go.pinniped.dev/internal/config/supervisor"
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/controller/apicerts"
 	"go.pinniped.dev/internal/controller/supervisorconfig"
 	"go.pinniped.dev/internal/controller/supervisorconfig/generator"
 	"go.pinniped.dev/internal/controller/supervisorconfig/ldapupstreamwatcher"
@@ -39,13 +56,28 @@ import (
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/deploymentref"
 	"go.pinniped.dev/internal/downward"
+	"go.pinniped.dev/internal/dynamiccert"
 	"go.pinniped.dev/internal/groupsuffix"
+	"go.pinniped.dev/internal/httputil/sourceip"
 	"go.pinniped.dev/internal/kubeclient"
+	"go.pinniped.dev/internal/oidc"
+	"go.pinniped.dev/internal/oidc/clientregistry"
+	"go.pinniped.dev/internal/oidc/clusteraudience"
 	"go.pinniped.dev/internal/oidc/jwks"
+	"go.pinniped.dev/internal/oidc/jwks/pkcs11signer"
+	"go.pinniped.dev/internal/oidc/jwks/pluginsigner"
+	"go.pinniped.dev/internal/oidc/loginlimiter"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/oidc/provider/branding"
 	"go.pinniped.dev/internal/oidc/provider/manager"
+	"go.pinniped.dev/internal/oidc/trustedcluster"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/proxyprotocol"
 	"go.pinniped.dev/internal/secret"
+	"go.pinniped.dev/internal/supervisor/apiserver"
+	supervisorscheme "go.pinniped.dev/internal/supervisor/scheme"
+	"go.pinniped.dev/internal/tlsprofile"
+	"go.pinniped.dev/internal/tracing"
 )
 
 const (
@@ -53,30 +85,38 @@ const (
 	defaultResyncInterval = 3 * time.Minute
 )
 
-func start(ctx context.Context, l net.Listener, handler http.Handler) {
+// start serves handler on l until ctx is cancelled. Then it stops accepting new connections and gives in-flight
+// requests up to gracePeriod to finish. The returned channel is closed once the server has stopped.
+func start(ctx context.Context, l net.Listener, handler http.Handler, gracePeriod time.Duration) <-chan struct{} {
 	server := http.Server{Handler: handler}
 
-	errCh := make(chan error)
+	errCh := make(chan error, 1)
 	go func() {
 		errCh <- server.Serve(l)
 	}()
 
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		select {
 		case err := <-errCh:
 			plog.Debug("server exited", "err", err)
 		case <-ctx.Done():
 			plog.Debug("server context cancelled", "err", ctx.Err())
-			if err := server.Shutdown(context.Background()); err != nil {
-				plog.Debug("server shutdown failed", "err", err)
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), gracePeriod)
+			defer shutdownCancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				plog.Debug("server shutdown failed, closing remaining connections", "err", err)
+				_ = server.Close()
 			}
 		}
 	}()
+	return stopped
 }
 
 func waitForSignal() os.Signal {
 	signalCh := make(chan os.Signal, 1)
-	signal.Notify(signalCh, os.Interrupt)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
 	return <-signalCh
 }
 
@@ -86,12 +126,19 @@ func startControllers(
 	cfg *supervisor.Config,
 	issuerManager *manager.Manager,
 	dynamicJWKSProvider jwks.DynamicJWKSProvider,
+	signerBackends jwks.SignerBackends,
 	dynamicTLSCertProvider provider.DynamicTLSCertProvider,
 	dynamicUpstreamIDPProvider provider.DynamicUpstreamIDPProvider,
+	dynamicClusterAudienceProvider clusteraudience.DynamicClusterAudienceProvider,
+	dynamicTrustedClusterProvider trustedcluster.DynamicTrustedClusterProvider,
+	dynamicMachineClientProvider clientregistry.DynamicMachineClientProvider,
+	dynamicBrandingProvider branding.DynamicBrandingProvider,
+	dynamicServingCertProvider dynamiccert.Private,
 	secretCache *secret.Cache,
 	supervisorDeployment *appsv1.Deployment,
 	kubeClient kubernetes.Interface,
 	pinnipedClient pinnipedclientset.Interface,
+	aggregatorClient aggregatorclient.Interface,
 	kubeInformers kubeinformers.SharedInformerFactory,
 	pinnipedInformers pinnipedinformers.SharedInformerFactory,
 ) {
@@ -116,6 +163,10 @@ func startControllers(
 				clock.RealClock{},
 				pinnipedClient,
 				federationDomainInformer,
+				secretInformer,
+				signerBackends,
+				pinnipedInformers.IDP().V1alpha1().OIDCIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -127,6 +178,7 @@ func startControllers(
 				pinnipedClient,
 				secretInformer,
 				federationDomainInformer,
+				clock.RealClock{},
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -134,6 +186,7 @@ func startControllers(
 		WithController(
 			supervisorconfig.NewJWKSObserverController(
 				dynamicJWKSProvider,
+				signerBackends,
 				secretInformer,
 				federationDomainInformer,
 				controllerlib.WithInformer,
@@ -144,21 +197,35 @@ func startControllers(
 			supervisorconfig.NewTLSCertObserverController(
 				dynamicTLSCertProvider,
 				cfg.NamesConfig.DefaultTLSCertificateSecret,
+				pinnipedClient,
+				clock.RealClock{},
 				secretInformer,
 				federationDomainInformer,
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
 		).
+		WithController(
+			supervisorconfig.NewBrandingObserverController(
+				dynamicBrandingProvider,
+				pinnipedClient,
+				clock.RealClock{},
+				kubeInformers.Core().V1().ConfigMaps(),
+				federationDomainInformer,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
 		WithController(
 			generator.NewSupervisorSecretsController(
 				supervisorDeployment,
 				cfg.Labels,
 				kubeClient,
 				secretInformer,
-				func(secret []byte) {
-					plog.Debug("setting csrf cookie secret")
-					secretCache.SetCSRFCookieEncoderHashKey(secret)
+				symmetricKeyRotation(cfg, oidc.CSRFCookieLifespan),
+				func(secrets [][]byte) {
+					plog.Debug("setting csrf cookie secrets")
+					secretCache.SetCSRFCookieEncoderHashKeys(secrets)
 				},
 				controllerlib.WithInformer,
 				controllerlib.WithInitialEvent,
@@ -172,9 +239,11 @@ func startControllers(
 					cfg.Labels,
 					rand.Reader,
 					generator.SecretUsageTokenSigningKey,
-					func(federationDomainIssuer string, symmetricKey []byte) {
+					// The hmac key is never rotated, since that would invalidate every token which was issued before.
+					generator.KeyRotation{},
+					func(federationDomainIssuer string, symmetricKeys [][]byte) {
 						plog.Debug("setting hmac secret", "issuer", federationDomainIssuer)
-						secretCache.SetTokenHMACKey(federationDomainIssuer, symmetricKey)
+						secretCache.SetTokenHMACKey(federationDomainIssuer, symmetricKeys[0])
 					},
 				),
 				func(fd *configv1alpha1.FederationDomainStatus) *corev1.LocalObjectReference {
@@ -184,6 +253,7 @@ func startControllers(
 				pinnipedClient,
 				secretInformer,
 				federationDomainInformer,
+				clock.RealClock{},
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -195,9 +265,10 @@ func startControllers(
 					cfg.Labels,
 					rand.Reader,
 					generator.SecretUsageStateSigningKey,
-					func(federationDomainIssuer string, symmetricKey []byte) {
-						plog.Debug("setting state signature key", "issuer", federationDomainIssuer)
-						secretCache.SetStateEncoderHashKey(federationDomainIssuer, symmetricKey)
+					symmetricKeyRotation(cfg, oidc.UpstreamStateParamLifespan),
+					func(federationDomainIssuer string, symmetricKeys [][]byte) {
+						plog.Debug("setting state signature keys", "issuer", federationDomainIssuer)
+						secretCache.SetStateEncoderHashKeys(federationDomainIssuer, symmetricKeys)
 					},
 				),
 				func(fd *configv1alpha1.FederationDomainStatus) *corev1.LocalObjectReference {
@@ -207,6 +278,7 @@ func startControllers(
 				pinnipedClient,
 				secretInformer,
 				federationDomainInformer,
+				clock.RealClock{},
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -218,9 +290,10 @@ func startControllers(
 					cfg.Labels,
 					rand.Reader,
 					generator.SecretUsageStateEncryptionKey,
-					func(federationDomainIssuer string, symmetricKey []byte) {
-						plog.Debug("setting state encryption key", "issuer", federationDomainIssuer)
-						secretCache.SetStateEncoderBlockKey(federationDomainIssuer, symmetricKey)
+					symmetricKeyRotation(cfg, oidc.UpstreamStateParamLifespan),
+					func(federationDomainIssuer string, symmetricKeys [][]byte) {
+						plog.Debug("setting state encryption keys", "issuer", federationDomainIssuer)
+						secretCache.SetStateEncoderBlockKeys(federationDomainIssuer, symmetricKeys)
 					},
 				),
 				func(fd *configv1alpha1.FederationDomainStatus) *corev1.LocalObjectReference {
@@ -230,6 +303,39 @@ func startControllers(
 				pinnipedClient,
 				secretInformer,
 				federationDomainInformer,
+				clock.RealClock{},
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
+		WithController(
+			supervisorconfig.NewClusterAudienceWatcherController(
+				dynamicClusterAudienceProvider,
+				clock.RealClock{},
+				pinnipedClient,
+				pinnipedInformers.Config().V1alpha1().ClusterAudiences(),
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
+		WithController(
+			supervisorconfig.NewTrustedClusterWatcherController(
+				dynamicTrustedClusterProvider,
+				clock.RealClock{},
+				pinnipedClient,
+				pinnipedInformers.Config().V1alpha1().TrustedClusters(),
+				supervisorconfig.DiscoverIssuer,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
+		WithController(
+			supervisorconfig.NewMachineClientWatcherController(
+				dynamicMachineClientProvider,
+				clock.RealClock{},
+				pinnipedClient,
+				pinnipedInformers.Config().V1alpha1().MachineClients(),
+				secretInformer,
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -252,7 +358,57 @@ func startControllers(
 				secretInformer,
 				controllerlib.WithInformer,
 			),
-			singletonWorker)
+			singletonWorker).
+		// The certs controllers generate the CA and serving certificate of the aggregated API and keep them
+		// up to date, the same way that the Concierge does for its aggregated APIs.
+		WithController(
+			apicerts.NewCertsManagerController(
+				supervisorDeployment.Namespace,
+				cfg.NamesConfig.ServingCertificateSecret,
+				cfg.Labels,
+				kubeClient,
+				secretInformer,
+				controllerlib.WithInformer,
+				controllerlib.WithInitialEvent,
+				time.Duration(*cfg.APIConfig.ServingCertificateConfig.DurationSeconds)*time.Second,
+				"Pinniped Supervisor CA",
+				cfg.NamesConfig.APIService,
+			),
+			singletonWorker,
+		).
+		WithController(
+			apicerts.NewAPIServiceUpdaterController(
+				supervisorDeployment.Namespace,
+				cfg.NamesConfig.ServingCertificateSecret,
+				groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix).APIServiceName(),
+				aggregatorClient,
+				secretInformer,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
+		WithController(
+			apicerts.NewCertsObserverController(
+				supervisorDeployment.Namespace,
+				cfg.NamesConfig.ServingCertificateSecret,
+				dynamicServingCertProvider,
+				secretInformer,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
+		WithController(
+			apicerts.NewCertsExpirerController(
+				supervisorDeployment.Namespace,
+				cfg.NamesConfig.ServingCertificateSecret,
+				kubeClient,
+				secretInformer,
+				controllerlib.WithInformer,
+				time.Duration(*cfg.APIConfig.ServingCertificateConfig.RenewBeforeSeconds)*time.Second,
+				apicerts.TLSCertificateChainSecretKey,
+			),
+			singletonWorker,
+		)
 
 	kubeInformers.Start(ctx.Done())
 	pinnipedInformers.Start(ctx.Done())
@@ -264,6 +420,231 @@ func startControllers(
 	go controllerManager.Start(ctx)
 }
 
+// symmetricKeyRotation returns the rotation policy of a generated symmetric key which encodes values that are valid
+// for the lifespan param.
+func symmetricKeyRotation(cfg *supervisor.Config, lifespan time.Duration) generator.KeyRotation {
+	return generator.KeyRotation{
+		Interval:    time.Duration(*cfg.SymmetricKeyRotation.IntervalSeconds) * time.Second,
+		GracePeriod: lifespan,
+		Clock:       clock.RealClock{},
+	}
+}
+
+// newSignerBackends creates the signers which are configured in the Supervisor's static configuration.
+func newSignerBackends(signers []supervisor.SignerSpec) (jwks.SignerBackends, error) {
+	signerBackends := jwks.SignerBackends{}
+	for _, signer := range signers {
+		switch {
+		case signer.PKCS11 != nil:
+			pin, err := ioutil.ReadFile(signer.PKCS11.PINFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read PIN of signer %q: %w", signer.Name, err)
+			}
+			signerBackend, err := pkcs11signer.New(signer.PKCS11.ModulePath, signer.PKCS11.TokenLabel, strings.TrimSpace(string(pin)))
+			if err != nil {
+				return nil, fmt.Errorf("cannot create signer %q: %w", signer.Name, err)
+			}
+			signerBackends[signer.Name] = signerBackend
+		case signer.Plugin != nil:
+			signerBackends[signer.Name] = pluginsigner.New(signer.Plugin.Command, signer.Plugin.Args)
+		}
+	}
+	return signerBackends, nil
+}
+
+func loginLimiterConfig(limits *supervisor.LoginRateLimitsSpec) loginlimiter.Config {
+	return loginlimiter.Config{
+		UsernameFailures: int(*limits.UsernameFailures),
+		SourceIPFailures: int(*limits.SourceIPFailures),
+		InitialBackoff:   time.Duration(*limits.InitialBackoffSeconds) * time.Second,
+		MaxBackoff:       time.Duration(*limits.MaxBackoffSeconds) * time.Second,
+		ResetAfter:       time.Duration(*limits.ResetAfterSeconds) * time.Second,
+	}
+}
+
+// metricsHandler serves the metrics of all of the Supervisor's packages at /metrics and makes all other paths
+// result in 404.
+func metricsHandler() http.Handler {
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", legacyregistry.Handler())
+	return metricsMux
+}
+
+// newAuditLogger creates the audit log which is configured in the Supervisor's static configuration.
+func newAuditLogger(audit *supervisor.AuditSpec) (*auditlog.Logger, error) {
+	out := io.Writer(os.Stdout)
+	if audit.File != "" {
+		file, err := os.OpenFile(audit.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		out = file
+	}
+	return auditlog.New(out, audit.RedactedFields, time.Now)
+}
+
+// informerFactories are the informer factories of the Supervisor, so that their informers can be checked together.
+type informerFactories []interface {
+	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
+}
+
+func (f informerFactories) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	synced := map[reflect.Type]bool{}
+	for _, factory := range f {
+		for informerType, informerSynced := range factory.WaitForCacheSync(stopCh) {
+			synced[informerType] = informerSynced
+		}
+	}
+	return synced
+}
+
+// startTracing starts exporting the Supervisor's traces to the collector which is configured in its static
+// configuration. The returned func flushes the remaining spans and stops the export.
+func startTracing(ctx context.Context, spec *supervisor.TracingSpec) (func(context.Context) error, error) {
+	tracingConfig := tracing.Config{
+		ServiceName: "pinniped-supervisor",
+		Endpoint:    spec.Endpoint,
+		Insecure:    spec.Insecure,
+		SampleRatio: *spec.SampleRatio,
+	}
+	if !spec.Insecure {
+		tracingConfig.TLSConfig = tlsprofile.ClientConfig()
+	}
+	if spec.CAFile != "" {
+		caPEM, err := ioutil.ReadFile(spec.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read caFile: %w", err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("caFile %q does not contain any PEM certificates", spec.CAFile)
+		}
+		tracingConfig.TLSConfig.RootCAs = rootCAs
+	}
+	return tracing.Setup(ctx, tracingConfig)
+}
+
+// listen creates the listener of an endpoint, which reads the PROXY protocol headers of trusted proxies when the
+// endpoint is configured to.
+func listen(endpoint *supervisor.Endpoint, trustedProxies sourceip.TrustedProxies) (net.Listener, error) {
+	if endpoint.Network == supervisor.NetworkUnix {
+		if err := removeStaleUnixSocket(endpoint.Address); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen(endpoint.Network, endpoint.Address)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.ProxyProtocol {
+		l = proxyprotocol.NewListener(l, trustedProxies.TrustsPeer)
+	}
+	return l, nil
+}
+
+// removeStaleUnixSocket removes the socket which a previous Supervisor process left behind at path, since listening
+// on a path which already exists fails. A socket on which some process is still listening is not removed, and
+// neither is a file which is not a socket.
+func removeStaleUnixSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not check for a stale unix socket: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("could not listen on %s: the file exists and is not a unix socket", path)
+	}
+	if conn, err := net.DialTimeout(supervisor.NetworkUnix, path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("could not listen on %s: another process is listening on the unix socket", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove stale unix socket: %w", err)
+	}
+	return nil
+}
+
+// newHTTPSTLSConfig creates the TLS config of the HTTPS listener, which serves the certificate of the FederationDomain
+// whose issuer's hostname is the SNI of the request, or else the default certificate.
+func newHTTPSTLSConfig(spec *supervisor.TLSSpec, dynamicTLSCertProvider provider.DynamicTLSCertProvider) (*tls.Config, error) {
+	tlsConfig := tlsprofile.Default().Config()
+	tlsConfig.GetCertificate = func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert := dynamicTLSCertProvider.GetTLSCert(strings.ToLower(info.ServerName))
+		defaultCert := dynamicTLSCertProvider.GetDefaultTLSCert()
+		plog.Debug("GetCertificate called for https listener",
+			"info.ServerName", info.ServerName,
+			"foundSNICert", cert != nil,
+			"foundDefaultCert", defaultCert != nil,
+		)
+		if cert == nil {
+			cert = defaultCert
+		}
+		return cert, nil
+	}
+
+	if spec.ClientCAFile != "" {
+		caPEM, err := ioutil.ReadFile(spec.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read clientCAFile: %w", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("clientCAFile %q does not contain any PEM certificates", spec.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// getAggregatedAPIServerConfig creates the config of the aggregated API server, which serves SupervisorSessions
+// from the Secrets in which the Supervisor stores its sessions. Like the Concierge's, it has no storage of its own.
+func getAggregatedAPIServerConfig(
+	dynamicCertProvider dynamiccert.Private,
+	secrets corev1client.SecretInterface,
+	apiGroupSuffix string,
+	scheme *runtime.Scheme,
+	sessionSupervisorGroupVersion schema.GroupVersion,
+) (*apiserver.Config, error) {
+	codecs := serializer.NewCodecFactory(scheme)
+
+	// this is unused for now but it is a safe value that we could use in the future
+	defaultEtcdPathPrefix := fmt.Sprintf("/pinniped-supervisor-registry/%s", apiGroupSuffix)
+
+	recommendedOptions := genericoptions.NewRecommendedOptions(
+		defaultEtcdPathPrefix,
+		codecs.LegacyCodec(sessionSupervisorGroupVersion),
+	)
+	recommendedOptions.Etcd = nil // turn off etcd storage because we don't need it
+	recommendedOptions.SecureServing.ServerCert.GeneratedCert = dynamicCertProvider
+	// Don't run on default 443 because that requires root. This port is usually allowed from the control plane,
+	// since it is also the port of the kubelet.
+	recommendedOptions.SecureServing.BindPort = 10250
+
+	serverConfig := genericapiserver.NewRecommendedConfig(codecs)
+	// This copies the dynamicCertProvider into the config as the cert provider of the running server, so it serves
+	// "the server is currently unable to handle the request" errors until the certs controllers have created its
+	// serving certificate. See the Concierge's getAggregatedAPIServerConfig.
+	if err := recommendedOptions.ApplyTo(serverConfig); err != nil {
+		return nil, err
+	}
+	tlsprofile.Default().ApplyToSecureServing(serverConfig.SecureServing)
+
+	apiServerConfig := &apiserver.Config{
+		GenericConfig: serverConfig,
+		ExtraConfig: apiserver.ExtraConfig{
+			Secrets:                       secrets,
+			Scheme:                        scheme,
+			NegotiatedSerializer:          codecs,
+			SessionSupervisorGroupVersion: sessionSupervisorGroupVersion,
+		},
+	}
+	return apiServerConfig, nil
+}
+
 func run(podInfo *downward.PodInfo, cfg *supervisor.Config) error {
 	serverInstallationNamespace := podInfo.Namespace
 
@@ -296,23 +677,101 @@ func run(podInfo *downward.PodInfo, cfg *supervisor.Config) error {
 	)
 
 	// Serve the /healthz endpoint and make all other paths result in 404.
+	// The /readyz endpoint is added below, once its checks exist.
 	healthMux := http.NewServeMux()
 	healthMux.Handle("/healthz", http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		_, _ = writer.Write([]byte("ok"))
 	}))
 
+	auditLogger, err := newAuditLogger(&cfg.Audit)
+	if err != nil {
+		return fmt.Errorf("cannot create audit log: %w", err)
+	}
+	auditlog.SetGlobalLogger(auditLogger)
+
+	// Every server of the Supervisor uses these TLS settings, so they must be set before any of them start. Its clients
+	// only use them when they are configured explicitly, so that upgrading does not break their connections to upstream
+	// identity providers which only offer older cipher suites.
+	tlsProfile, err := tlsprofile.FromSpec(&cfg.TLS.Spec)
+	if err != nil {
+		return fmt.Errorf("cannot parse tls profile: %w", err)
+	}
+	tlsprofile.SetDefault(tlsProfile)
+	if cfg.TLS.IsSet() {
+		tlsprofile.SetClientDefault(tlsProfile)
+	}
+	plog.Debug("using tls profile", "profile", tlsProfile.String(), "usedByClients", cfg.TLS.IsSet())
+
+	if cfg.Tracing.Endpoint != "" {
+		stopTracing, err := startTracing(ctx, &cfg.Tracing)
+		if err != nil {
+			return fmt.Errorf("cannot start tracing: %w", err)
+		}
+		defer func() {
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer stopCancel()
+			if err := stopTracing(stopCtx); err != nil {
+				plog.Error("could not export the remaining trace spans", err)
+			}
+		}()
+	}
+
+	signerBackends, err := newSignerBackends(cfg.Signers)
+	if err != nil {
+		return fmt.Errorf("cannot create signers: %w", err)
+	}
+
 	dynamicJWKSProvider := jwks.NewDynamicJWKSProvider()
 	dynamicTLSCertProvider := provider.NewDynamicTLSCertProvider()
 	dynamicUpstreamIDPProvider := provider.NewDynamicUpstreamIDPProvider()
+	dynamicClusterAudienceProvider := clusteraudience.NewDynamicClusterAudienceProvider()
+	dynamicTrustedClusterProvider := trustedcluster.NewDynamicTrustedClusterProvider()
+	dynamicMachineClientProvider := clientregistry.NewDynamicMachineClientProvider()
+	dynamicBrandingProvider := branding.NewDynamicBrandingProvider()
+	dynamicServingCertProvider := dynamiccert.NewServingCert("supervisor-serving-cert")
 	secretCache := secret.Cache{}
+	loginLimiter := loginlimiter.New(
+		client.Kubernetes.CoreV1().Secrets(serverInstallationNamespace),
+		time.Now,
+		loginLimiterConfig(&cfg.LoginRateLimits),
+	)
 
 	// OIDC endpoints will be served by the oidProvidersManager, and any non-OIDC paths will fallback to the healthMux.
 	oidProvidersManager := manager.NewManager(
 		healthMux,
 		dynamicJWKSProvider,
 		dynamicUpstreamIDPProvider,
+		dynamicClusterAudienceProvider,
+		dynamicTrustedClusterProvider,
+		dynamicMachineClientProvider,
+		dynamicBrandingProvider,
 		&secretCache,
 		client.Kubernetes.CoreV1().Secrets(serverInstallationNamespace),
+		loginLimiter,
+	)
+
+	// This is closed when the Supervisor receives a signal to stop, so that /readyz fails while it drains.
+	shuttingDown := make(chan struct{})
+
+	// Serve the /readyz endpoint, which fails while the Supervisor is not able to serve logins, so that its pod does
+	// not receive any traffic. Like the kube-apiserver's, it supports ?verbose, ?exclude=<check> and /readyz/<check>.
+	healthz.InstallReadyzHandler(healthMux,
+		healthz.NamedCheck("shutdown", func(_ *http.Request) error {
+			select {
+			case <-shuttingDown:
+				return constable.Error("the supervisor is shutting down")
+			default:
+				return nil
+			}
+		}),
+		healthz.NewInformerSyncHealthz(informerFactories{kubeInformers, pinnipedInformers}),
+		healthz.NamedCheck("federation-domain-keys", oidProvidersManager.CheckKeys),
+		healthz.NamedCheck("upstream-identity-providers", dynamicUpstreamIDPProvider.CheckReady),
+		healthz.NamedCheck("kube-api", func(r *http.Request) error {
+			checkCtx, checkCancel := context.WithTimeout(r.Context(), 2*time.Second)
+			defer checkCancel()
+			return client.Kubernetes.Discovery().RESTClient().Get().AbsPath("/version").Do(checkCtx).Error()
+		}),
 	)
 
 	startControllers(
@@ -320,54 +779,113 @@ func run(podInfo *downward.PodInfo, cfg *supervisor.Config) error {
 		cfg,
 		oidProvidersManager,
 		dynamicJWKSProvider,
+		signerBackends,
 		dynamicTLSCertProvider,
 		dynamicUpstreamIDPProvider,
+		dynamicClusterAudienceProvider,
+		dynamicTrustedClusterProvider,
+		dynamicMachineClientProvider,
+		dynamicBrandingProvider,
+		dynamicServingCertProvider,
 		&secretCache,
 		supervisorDeployment,
 		client.Kubernetes,
 		client.PinnipedSupervisor,
+		client.Aggregation,
 		kubeInformers,
 		pinnipedInformers,
 	)
 
-	//nolint: gosec // Intentionally binding to all network interfaces.
-	httpListener, err := net.Listen("tcp", ":8080")
+	trustedProxies, err := sourceip.ParseTrustedProxies(cfg.TrustedProxies.CIDRs)
 	if err != nil {
-		return fmt.Errorf("cannot create listener: %w", err)
-	}
-	defer func() { _ = httpListener.Close() }()
-	start(ctx, httpListener, oidProvidersManager)
-
-	//nolint: gosec // Intentionally binding to all network interfaces.
-	httpsListener, err := tls.Listen("tcp", ":8443", &tls.Config{
-		MinVersion: tls.VersionTLS12, // Allow v1.2 because clients like the default `curl` on MacOS don't support 1.3 yet.
-		GetCertificate: func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
-			cert := dynamicTLSCertProvider.GetTLSCert(strings.ToLower(info.ServerName))
-			defaultCert := dynamicTLSCertProvider.GetDefaultTLSCert()
-			plog.Debug("GetCertificate called for port 8443",
-				"info.ServerName", info.ServerName,
-				"foundSNICert", cert != nil,
-				"foundDefaultCert", defaultCert != nil,
-			)
-			if cert == nil {
-				cert = defaultCert
-			}
-			return cert, nil
-		},
-	})
+		return fmt.Errorf("cannot parse trusted proxies: %w", err)
+	}
+	// Use the addresses of the clients of trusted proxies for logging, rate limiting and audit events.
+	handler := sourceip.Wrap(oidProvidersManager, trustedProxies)
+
+	gracePeriod := time.Duration(*cfg.Shutdown.GracePeriodSeconds) * time.Second
+	var stoppedServers []<-chan struct{}
+
+	httpAddress := supervisor.NetworkDisabled
+	if cfg.Endpoints.HTTP.Network != supervisor.NetworkDisabled {
+		httpListener, err := listen(cfg.Endpoints.HTTP, trustedProxies)
+		if err != nil {
+			return fmt.Errorf("cannot create http listener: %w", err)
+		}
+		defer func() { _ = httpListener.Close() }()
+		stoppedServers = append(stoppedServers, start(ctx, httpListener, handler, gracePeriod))
+		httpAddress = httpListener.Addr().String()
+	}
+
+	httpsAddress := supervisor.NetworkDisabled
+	if cfg.Endpoints.HTTPS.Network != supervisor.NetworkDisabled {
+		tlsConfig, err := newHTTPSTLSConfig(&cfg.TLS, dynamicTLSCertProvider)
+		if err != nil {
+			return fmt.Errorf("cannot create https tls config: %w", err)
+		}
+		httpsListener, err := listen(cfg.Endpoints.HTTPS, trustedProxies)
+		if err != nil {
+			return fmt.Errorf("cannot create https listener: %w", err)
+		}
+		defer func() { _ = httpsListener.Close() }()
+		stoppedServers = append(stoppedServers, start(ctx, tls.NewListener(httpsListener, tlsConfig), handler, gracePeriod))
+		httpsAddress = httpsListener.Addr().String()
+	}
+
+	if cfg.Metrics.Address != "" {
+		metricsListener, err := net.Listen("tcp", cfg.Metrics.Address)
+		if err != nil {
+			return fmt.Errorf("cannot create metrics listener: %w", err)
+		}
+		defer func() { _ = metricsListener.Close() }()
+		stoppedServers = append(stoppedServers, start(ctx, metricsListener, metricsHandler(), gracePeriod))
+		plog.Debug("serving metrics", "metricsAddress", metricsListener.Addr().String())
+	}
+
+	// Serve the SupervisorSession API, which the Kubernetes API server aggregates, so that admins can list and revoke
+	// sessions without having access to the Secrets in which they are stored.
+	scheme, sessionSupervisorGroupVersion := supervisorscheme.New(*cfg.APIGroupSuffix)
+	apiServerConfig, err := getAggregatedAPIServerConfig(
+		dynamicServingCertProvider,
+		client.Kubernetes.CoreV1().Secrets(serverInstallationNamespace),
+		*cfg.APIGroupSuffix,
+		scheme,
+		sessionSupervisorGroupVersion,
+	)
 	if err != nil {
-		return fmt.Errorf("cannot create listener: %w", err)
+		return fmt.Errorf("could not configure aggregated API server: %w", err)
 	}
-	defer func() { _ = httpsListener.Close() }()
-	start(ctx, httpsListener, oidProvidersManager)
+	apiServer, err := apiServerConfig.Complete().New()
+	if err != nil {
+		return fmt.Errorf("could not create aggregated API server: %w", err)
+	}
+	// Give its in-flight requests the same time to finish as those of the other servers.
+	apiServer.GenericAPIServer.ShutdownTimeout = gracePeriod
+	apiServerStopped, _, err := apiServer.GenericAPIServer.PrepareRun().NonBlockingRun(ctx.Done())
+	if err != nil {
+		return fmt.Errorf("could not start aggregated API server: %w", err)
+	}
+	stoppedServers = append(stoppedServers, apiServerStopped)
 
 	plog.Debug("supervisor is ready",
-		"httpAddress", httpListener.Addr().String(),
-		"httpsAddress", httpsListener.Addr().String(),
+		"httpAddress", httpAddress,
+		"httpsAddress", httpsAddress,
 	)
 
 	gotSignal := waitForSignal()
-	plog.Debug("supervisor exiting", "signal", gotSignal)
+	delay := time.Duration(*cfg.Shutdown.DelaySeconds) * time.Second
+	plog.Debug("supervisor exiting", "signal", gotSignal, "delay", delay.String(), "gracePeriod", gracePeriod.String())
+
+	// Keep accepting new connections while /readyz fails, until the pod has been removed from the endpoints of its
+	// Services and from load balancers, so that new logins are not refused during a rollout.
+	close(shuttingDown)
+	time.Sleep(delay)
+
+	// Stop the controllers and the servers, and wait for the in-flight requests to finish before exiting.
+	cancel()
+	for _, stopped := range stoppedServers {
+		<-stopped
+	}
 
 	return nil
 }
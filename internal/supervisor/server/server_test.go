@@ -0,0 +1,68 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/config/supervisor"
+)
+
+func TestListenOnUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supervisor-server-test-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(dir)) })
+
+	endpoint := func(path string) *supervisor.Endpoint {
+		return &supervisor.Endpoint{Network: supervisor.NetworkUnix, Address: path}
+	}
+
+	t.Run("when no file exists at the path", func(t *testing.T) {
+		path := filepath.Join(dir, "new.sock")
+		l, err := listen(endpoint(path), nil)
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+	})
+
+	t.Run("when a previous process left a stale socket at the path", func(t *testing.T) {
+		path := filepath.Join(dir, "stale.sock")
+		stale, err := net.Listen(supervisor.NetworkUnix, path)
+		require.NoError(t, err)
+		// Leave the socket file behind, like a process which was killed before it could clean up.
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, stale.Close())
+		_, err = os.Lstat(path)
+		require.NoError(t, err)
+
+		l, err := listen(endpoint(path), nil)
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+	})
+
+	t.Run("when another process is listening on the socket", func(t *testing.T) {
+		path := filepath.Join(dir, "live.sock")
+		live, err := net.Listen(supervisor.NetworkUnix, path)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, live.Close()) })
+
+		_, err = listen(endpoint(path), nil)
+		require.EqualError(t, err, "could not listen on "+path+": another process is listening on the unix socket")
+	})
+
+	t.Run("when a file which is not a socket exists at the path", func(t *testing.T) {
+		path := filepath.Join(dir, "not-a-socket")
+		require.NoError(t, ioutil.WriteFile(path, []byte("some data"), 0600))
+
+		_, err := listen(endpoint(path), nil)
+		require.EqualError(t, err, "could not listen on "+path+": the file exists and is not a unix socket")
+		_, err = os.Lstat(path)
+		require.NoError(t, err)
+	})
+}
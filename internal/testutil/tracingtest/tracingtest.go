@@ -0,0 +1,92 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracingtest provides an in-process OTLP collector and an in-memory span recorder for tests.
+package tracingtest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// Collector is an OTLP collector which accepts spans over plaintext gRPC and keeps them in memory.
+type Collector struct {
+	collectortrace.UnimplementedTraceServiceServer
+
+	endpoint string
+
+	mu    sync.Mutex
+	spans []*tracepb.ResourceSpans
+}
+
+// NewCollector starts a collector which is stopped at the end of the test.
+func NewCollector(t *testing.T) *Collector {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	c := &Collector{endpoint: listener.Addr().String()}
+	server := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(server, c)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return c
+}
+
+// Endpoint is the host and port on which the collector listens.
+func (c *Collector) Endpoint() string {
+	return c.endpoint
+}
+
+func (c *Collector) Export(_ context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = append(c.spans, req.ResourceSpans...)
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+// ResourceSpans returns the spans which the collector received so far.
+func (c *Collector) ResourceSpans() []*tracepb.ResourceSpans {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*tracepb.ResourceSpans(nil), c.spans...)
+}
+
+// Record makes the global tracer provider record all spans in memory until the end of the test. Tests which use it
+// must not run in parallel with other tests which create spans.
+func Record(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	// Every span is recorded, including those of remote parents which were not sampled.
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		require.NoError(t, provider.Shutdown(context.Background()))
+	})
+
+	return exporter
+}
+
+// SpanNames returns the names of the recorded spans in the order in which they ended.
+func SpanNames(exporter *tracetest.InMemoryExporter) []string {
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	return names
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tlsprofile defines the TLS settings which are shared by the servers and the outbound clients of a Pinniped
+// component, so that they can all be changed by one setting in its static configuration. Servers always use a profile,
+// while outbound clients keep Go's defaults unless a profile is configured explicitly.
+package tlsprofile
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"go.pinniped.dev/internal/constable"
+)
+
+const (
+	// Modern only allows TLS 1.3, for environments where all clients are known to support it.
+	Modern = "modern"
+	// Intermediate allows TLS 1.2 with the forward secret AEAD cipher suites, and TLS 1.3.
+	Intermediate = "intermediate"
+)
+
+// Spec is how a profile is configured in the static configuration of a Pinniped component.
+type Spec struct {
+	// Profile is "modern" or "intermediate". Defaults to "intermediate".
+	Profile string `json:"profile,omitempty"`
+	// MinVersion is "1.2" or "1.3". It overrides the minimum version of the profile.
+	MinVersion string `json:"minVersion,omitempty"`
+	// CipherSuites are the names of the TLS 1.2 cipher suites which are allowed, e.g.
+	// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". They override the cipher suites of the profile. The cipher suites of
+	// TLS 1.3 cannot be configured.
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+	// CurvePreferences are the names of the elliptic curves which are allowed for key exchange, in order of preference,
+	// i.e. "X25519", "P256", "P384" or "P521". They override the curves of the profile.
+	CurvePreferences []string `json:"curvePreferences,omitempty"`
+}
+
+// Profile is a parsed Spec.
+type Profile struct {
+	MinVersion       uint16
+	CipherSuites     []uint16
+	CurvePreferences []tls.CurveID
+}
+
+//nolint:gochecknoglobals
+var (
+	// intermediateCipherSuites are the TLS 1.2 cipher suites of Mozilla's intermediate configuration. They are also
+	// set for TLS 1.3 profiles, for when their minimum version is overridden.
+	intermediateCipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	}
+
+	defaultCurvePreferences = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}
+
+	curves = map[string]tls.CurveID{
+		"X25519": tls.X25519,
+		"P256":   tls.CurveP256,
+		"P384":   tls.CurveP384,
+		"P521":   tls.CurveP521,
+	}
+
+	defaultProfile       atomic.Value
+	clientDefaultProfile atomic.Value
+)
+
+// IsSet returns whether the Spec configures any setting, i.e. whether the operator chose the TLS settings instead of
+// leaving them to their defaults.
+func (s *Spec) IsSet() bool {
+	return s.Profile != "" || s.MinVersion != "" || len(s.CipherSuites) > 0 || len(s.CurvePreferences) > 0
+}
+
+// FromSpec parses and validates a Spec.
+func FromSpec(spec *Spec) (*Profile, error) {
+	var profile Profile
+	switch spec.Profile {
+	case Intermediate, "":
+		profile = Profile{MinVersion: tls.VersionTLS12}
+	case Modern:
+		profile = Profile{MinVersion: tls.VersionTLS13}
+	default:
+		return nil, fmt.Errorf("unknown profile %q, must be %s or %s", spec.Profile, Modern, Intermediate)
+	}
+	profile.CipherSuites = intermediateCipherSuites
+	profile.CurvePreferences = defaultCurvePreferences
+
+	if spec.MinVersion != "" {
+		switch spec.MinVersion {
+		case "1.2":
+			profile.MinVersion = tls.VersionTLS12
+		case "1.3":
+			profile.MinVersion = tls.VersionTLS13
+		default:
+			return nil, fmt.Errorf("unknown minVersion %q, must be 1.2 or 1.3", spec.MinVersion)
+		}
+	}
+
+	if len(spec.CipherSuites) > 0 {
+		if profile.MinVersion == tls.VersionTLS13 {
+			return nil, constable.Error("cipherSuites must not be set when the minimum version is 1.3")
+		}
+		cipherSuites, err := tls12CipherSuites(spec.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		profile.CipherSuites = cipherSuites
+	}
+
+	if len(spec.CurvePreferences) > 0 {
+		profile.CurvePreferences = make([]tls.CurveID, 0, len(spec.CurvePreferences))
+		for _, name := range spec.CurvePreferences {
+			curve, ok := curves[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown curve %q, must be one of X25519, P256, P384 or P521", name)
+			}
+			profile.CurvePreferences = append(profile.CurvePreferences, curve)
+		}
+	}
+
+	return &profile, nil
+}
+
+// tls12CipherSuites returns the IDs of the cipher suites with the given names. Only the secure cipher suites of
+// TLS 1.2 which are implemented by crypto/tls are allowed, and one of the cipher suites which HTTP/2 requires must
+// be included.
+func tls12CipherSuites(names []string) ([]uint16, error) {
+	known := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		for _, version := range suite.SupportedVersions {
+			if version == tls.VersionTLS12 {
+				known[suite.Name] = suite.ID
+			}
+		}
+	}
+
+	ids := make([]uint16, 0, len(names))
+	hasHTTP2CipherSuite := false
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure TLS 1.2 cipher suite %q", name)
+		}
+		if id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 || id == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+			hasHTTP2CipherSuite = true
+		}
+		ids = append(ids, id)
+	}
+	if !hasHTTP2CipherSuite {
+		return nil, constable.Error("cipherSuites must include TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 or " +
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, which HTTP/2 requires")
+	}
+	return ids, nil
+}
+
+// Config returns a new tls.Config with the settings of the profile, for a server or a client.
+func (p *Profile) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion:       p.MinVersion,
+		CipherSuites:     append([]uint16(nil), p.CipherSuites...),
+		CurvePreferences: append([]tls.CurveID(nil), p.CurvePreferences...),
+	}
+}
+
+// ApplyToSecureServing sets the minimum version and the cipher suites of a generic API server. Generic API servers
+// do not allow their curve preferences to be configured, so they use Go's defaults.
+func (p *Profile) ApplyToSecureServing(info *genericapiserver.SecureServingInfo) {
+	info.MinTLSVersion = p.MinVersion
+	info.CipherSuites = append([]uint16(nil), p.CipherSuites...)
+}
+
+// String describes the profile for logging.
+func (p *Profile) String() string {
+	cipherSuites := make([]string, 0, len(p.CipherSuites))
+	for _, id := range p.CipherSuites {
+		cipherSuites = append(cipherSuites, tls.CipherSuiteName(id))
+	}
+	curveNames := make([]string, 0, len(p.CurvePreferences))
+	for _, curve := range p.CurvePreferences {
+		for name, id := range curves {
+			if id == curve {
+				curveNames = append(curveNames, name)
+			}
+		}
+	}
+	return fmt.Sprintf("minVersion=%s cipherSuites=%s curvePreferences=%s",
+		versionName(p.MinVersion), strings.Join(cipherSuites, ","), strings.Join(curveNames, ","))
+}
+
+func versionName(version uint16) string {
+	if version == tls.VersionTLS13 {
+		return "1.3"
+	}
+	return "1.2"
+}
+
+// SetDefault sets the profile which Default returns. Each component sets it once while it starts, before its
+// controllers create any clients.
+func SetDefault(p *Profile) {
+	defaultProfile.Store(p)
+}
+
+// Default returns the profile of the running component, which its servers use. It is the intermediate profile until
+// SetDefault is called.
+func Default() *Profile {
+	if p, ok := defaultProfile.Load().(*Profile); ok {
+		return p
+	}
+	p, _ := FromSpec(&Spec{Profile: Intermediate})
+	return p
+}
+
+// SetClientDefault sets the profile which ClientDefault returns. Each component sets it once while it starts, before
+// its controllers create any clients, and only when its static configuration explicitly sets its TLS settings.
+func SetClientDefault(p *Profile) {
+	clientDefaultProfile.Store(p)
+}
+
+// ClientDefault returns the profile which the outbound clients of the running component use, e.g. to connect to
+// upstream identity providers and webhooks, or nil when SetClientDefault has not been called. Without a profile the
+// clients keep Go's default cipher suites and curves, because the servers which they connect to are not under
+// Pinniped's control, and some of them only offer cipher suites which no profile allows, e.g. TLS_RSA_* or CBC suites.
+func ClientDefault() *Profile {
+	p, _ := clientDefaultProfile.Load().(*Profile)
+	return p
+}
+
+// ClientConfig returns a new tls.Config for an outbound client, with the settings of ClientDefault, or else with Go's
+// defaults and a minimum version of TLS 1.2.
+func ClientConfig() *tls.Config {
+	if p := ClientDefault(); p != nil {
+		return p.Config()
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tlsprofile
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+)
+
+func TestFromSpec(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        Spec
+		wantProfile *Profile
+		wantString  string
+		wantError   string
+	}{
+		{
+			name: "default",
+			spec: Spec{},
+			wantProfile: &Profile{
+				MinVersion:       tls.VersionTLS12,
+				CipherSuites:     intermediateCipherSuites,
+				CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+			},
+			wantString: "minVersion=1.2 cipherSuites=TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256," +
+				"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384," +
+				"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 curvePreferences=X25519,P256,P384",
+		},
+		{
+			name: "modern",
+			spec: Spec{Profile: "modern"},
+			wantProfile: &Profile{
+				MinVersion:       tls.VersionTLS13,
+				CipherSuites:     intermediateCipherSuites,
+				CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+			},
+		},
+		{
+			name: "intermediate with overrides",
+			spec: Spec{
+				Profile:          "intermediate",
+				CipherSuites:     []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+				CurvePreferences: []string{"P384", "P256"},
+			},
+			wantProfile: &Profile{
+				MinVersion:       tls.VersionTLS12,
+				CipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+				CurvePreferences: []tls.CurveID{tls.CurveP384, tls.CurveP256},
+			},
+			wantString: "minVersion=1.2 cipherSuites=TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 curvePreferences=P384,P256",
+		},
+		{
+			name: "modern with an overridden minimum version",
+			spec: Spec{Profile: "modern", MinVersion: "1.2"},
+			wantProfile: &Profile{
+				MinVersion:       tls.VersionTLS12,
+				CipherSuites:     intermediateCipherSuites,
+				CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+			},
+		},
+		{
+			name:      "unknown profile",
+			spec:      Spec{Profile: "old"},
+			wantError: `unknown profile "old", must be modern or intermediate`,
+		},
+		{
+			name:      "unknown version",
+			spec:      Spec{MinVersion: "1.1"},
+			wantError: `unknown minVersion "1.1", must be 1.2 or 1.3`,
+		},
+		{
+			name:      "cipher suites with TLS 1.3",
+			spec:      Spec{Profile: "modern", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}},
+			wantError: "cipherSuites must not be set when the minimum version is 1.3",
+		},
+		{
+			name:      "insecure cipher suite",
+			spec:      Spec{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			wantError: `unknown or insecure TLS 1.2 cipher suite "TLS_RSA_WITH_RC4_128_SHA"`,
+		},
+		{
+			name:      "TLS 1.3 cipher suite",
+			spec:      Spec{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}},
+			wantError: `unknown or insecure TLS 1.2 cipher suite "TLS_AES_128_GCM_SHA256"`,
+		},
+		{
+			name:      "cipher suites without one which HTTP/2 requires",
+			spec:      Spec{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}},
+			wantError: "cipherSuites must include TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 or TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, which HTTP/2 requires",
+		},
+		{
+			name:      "unknown curve",
+			spec:      Spec{CurvePreferences: []string{"P224"}},
+			wantError: `unknown curve "P224", must be one of X25519, P256, P384 or P521`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := FromSpec(&test.spec)
+			if test.wantError != "" {
+				require.EqualError(t, err, test.wantError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantProfile, profile)
+			if test.wantString != "" {
+				require.Equal(t, test.wantString, profile.String())
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	profile := &Profile{
+		MinVersion:       tls.VersionTLS12,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		CurvePreferences: []tls.CurveID{tls.CurveP256},
+	}
+
+	config := profile.Config()
+	require.Equal(t, &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		CurvePreferences: []tls.CurveID{tls.CurveP256},
+	}, config)
+	config.CipherSuites[0] = tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384
+	require.Equal(t, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, profile.CipherSuites[0], "profile should not be changed")
+
+	var info genericapiserver.SecureServingInfo
+	profile.ApplyToSecureServing(&info)
+	require.Equal(t, uint16(tls.VersionTLS12), info.MinTLSVersion)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, info.CipherSuites)
+}
+
+func TestDefault(t *testing.T) {
+	intermediate, err := FromSpec(&Spec{Profile: Intermediate})
+	require.NoError(t, err)
+	require.Equal(t, intermediate, Default())
+
+	modern, err := FromSpec(&Spec{Profile: Modern})
+	require.NoError(t, err)
+	SetDefault(modern)
+	t.Cleanup(func() { SetDefault(intermediate) })
+	require.Equal(t, modern, Default())
+}
+
+func TestIsSet(t *testing.T) {
+	require.False(t, (&Spec{}).IsSet())
+	require.True(t, (&Spec{Profile: Intermediate}).IsSet())
+	require.True(t, (&Spec{MinVersion: "1.2"}).IsSet())
+	require.True(t, (&Spec{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}).IsSet())
+	require.True(t, (&Spec{CurvePreferences: []string{"P256"}}).IsSet())
+}
+
+func TestClientDefault(t *testing.T) {
+	// A server which only offers a CBC cipher suite, like some existing LDAPS servers, and which no profile allows.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	get := func(config *tls.Config) error {
+		config.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// By default, clients keep Go's defaults, even when the servers of the component use a profile.
+	modern, err := FromSpec(&Spec{Profile: Modern})
+	require.NoError(t, err)
+	previous := Default()
+	SetDefault(modern)
+	t.Cleanup(func() { SetDefault(previous) })
+	require.Nil(t, ClientDefault())
+	require.Equal(t, &tls.Config{MinVersion: tls.VersionTLS12}, ClientConfig())
+	require.NoError(t, get(ClientConfig()))
+
+	// Once a profile is set for clients, they only use its settings.
+	intermediate, err := FromSpec(&Spec{Profile: Intermediate})
+	require.NoError(t, err)
+	SetClientDefault(intermediate)
+	t.Cleanup(func() { SetClientDefault(nil) })
+	require.Equal(t, intermediate, ClientDefault())
+	require.Equal(t, intermediate.Config(), ClientConfig())
+	require.Error(t, get(ClientConfig()))
+}
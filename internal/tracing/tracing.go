@@ -0,0 +1,220 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing creates OpenTelemetry spans and exports them to an OTLP collector.
+//
+// Spans are created with the global tracer provider, which discards them until Setup is called. The W3C trace
+// context headers are used to continue the traces of clients and to send the trace of a client to its servers.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
+
+	"go.pinniped.dev/internal/httputil/sourceip"
+	"go.pinniped.dev/internal/tlsprofile"
+)
+
+const instrumentationName = "go.pinniped.dev"
+
+//nolint:gochecknoglobals
+var propagator = propagation.TraceContext{}
+
+// Config configures the export of spans.
+type Config struct {
+	// ServiceName is the service.name of the exported spans.
+	ServiceName string
+	// Endpoint is the host and port of an OTLP collector which accepts gRPC.
+	Endpoint string
+	// Insecure disables TLS for the connections to the collector.
+	Insecure bool
+	// TLSConfig configures TLS for the connections to the collector. The system's trusted CAs are used when it is nil.
+	TLSConfig *tls.Config
+	// SampleRatio is the fraction of traces which are sampled. The decision only depends on the trace ID, so all
+	// servers decide the same for a trace, and clients cannot force their traces to be sampled.
+	SampleRatio float64
+}
+
+// Setup starts exporting spans to the collector. The returned function flushes the remaining spans and stops the
+// export.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	options := []otlpgrpc.Option{otlpgrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlpgrpc.WithInsecure())
+	} else {
+		tlsConfig := config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = tlsprofile.ClientConfig()
+		}
+		options = append(options, otlpgrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	// The exporter connects in the background, so this does not fail when the collector is not reachable yet.
+	exporter, err := otlp.NewExporter(ctx, otlpgrpc.NewDriver(options...))
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.ServiceNameKey.String(config.ServiceName))),
+		sdktrace.WithSampler(newSampler(config.SampleRatio)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// newSampler samples a fraction of the traces. The spans of remote parents are sampled by the same ratio, regardless
+// of whether the remote parent was sampled, so that the sampled flag of a client's trace context cannot make every
+// request of the client be recorded.
+func newSampler(ratio float64) sdktrace.Sampler {
+	ratioBased := sdktrace.TraceIDRatioBased(ratio)
+	return sdktrace.ParentBased(ratioBased,
+		sdktrace.WithRemoteParentSampled(ratioBased),
+		sdktrace.WithRemoteParentNotSampled(ratioBased),
+	)
+}
+
+// Start starts a span as a child of the span in the context, if any.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends the span and marks it as failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartHTTPServerSpan starts a server span for the request, which continues the trace of the client when the request
+// has W3C trace context headers. The returned request carries the span.
+func StartHTTPServerSpan(r *http.Request, name string, attributes ...attribute.KeyValue) (*http.Request, trace.Span) {
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	attributes = append(serverAttributes(name, r), attributes...)
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attributes...),
+	)
+	return r.WithContext(ctx), span
+}
+
+// serverAttributes are the semantic conventions for HTTP servers, but without the query of the request, which can
+// contain credentials like authorization codes. The client IP is the one which sourceip resolved, rather than any
+// X-Forwarded-For header, which could have been set by the client itself.
+func serverAttributes(name string, r *http.Request) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	for _, kv := range semconv.HTTPServerAttributesFromHTTPRequest("", name, r) {
+		switch kv.Key {
+		case semconv.HTTPTargetKey:
+			kv = semconv.HTTPTargetKey.String(r.URL.EscapedPath())
+		case semconv.HTTPClientIPKey:
+			continue
+		}
+		attributes = append(attributes, kv)
+	}
+	return append(attributes, semconv.HTTPClientIPKey.String(sourceip.FromRequest(r)))
+}
+
+// EndHTTPSpan records the status code of the response and ends the span.
+func EndHTTPSpan(span trace.Span, code int) {
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(code)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(code))
+	span.End()
+}
+
+// NewTransport returns a RoundTripper which creates a client span for each request. It does not send the trace
+// context to the server, since the servers which the Supervisor calls are not part of its deployment.
+func NewTransport(name string, delegate http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{name: name, delegate: delegate}
+}
+
+type tracingTransport struct {
+	name     string
+	delegate http.RoundTripper
+}
+
+// WrappedRoundTripper implements k8s.io/apimachinery/pkg/util/net.RoundTripperWrapper.
+func (t *tracingTransport) WrappedRoundTripper() http.RoundTripper {
+	return t.delegate
+}
+
+func (t *tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(r.Context(), t.name+" "+r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+	)
+	resp, err := t.delegate.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	EndHTTPSpan(span, resp.StatusCode)
+	return resp, nil
+}
+
+// NewPropagatingTransport returns a RoundTripper which sends the W3C trace context of the context of each request for
+// which propagate returns true. A nil delegate means http.DefaultTransport.
+func NewPropagatingTransport(delegate http.RoundTripper, propagate func(*http.Request) bool) http.RoundTripper {
+	if delegate == nil {
+		delegate = http.DefaultTransport
+	}
+	return &propagatingTransport{delegate: delegate, propagate: propagate}
+}
+
+type propagatingTransport struct {
+	delegate  http.RoundTripper
+	propagate func(*http.Request) bool
+}
+
+func (t *propagatingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !trace.SpanContextFromContext(r.Context()).IsValid() || !t.propagate(r) {
+		return t.delegate.RoundTrip(r)
+	}
+	r = r.Clone(r.Context()) // RoundTrippers must not modify the request
+	propagator.Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+	return t.delegate.RoundTrip(r)
+}
+
+// WithNewTrace returns a context with a new trace, unless the context already has one, along with the ID of the
+// trace. Clients without a tracer provider use it to make the requests of one operation share a trace ID. The trace is
+// not marked as sampled, since the servers decide which traces they sample.
+func WithNewTrace(ctx context.Context) (context.Context, string, error) {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		return ctx, spanContext.TraceID().String(), nil
+	}
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	if _, err := rand.Read(traceID[:]); err != nil {
+		return nil, "", fmt.Errorf("could not generate trace ID: %w", err)
+	}
+	if _, err := rand.Read(spanID[:]); err != nil {
+		return nil, "", fmt.Errorf("could not generate span ID: %w", err)
+	}
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	})
+	return trace.ContextWithSpanContext(ctx, spanContext), traceID.String(), nil
+}
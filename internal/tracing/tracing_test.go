@@ -0,0 +1,155 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.pinniped.dev/internal/testutil/tracingtest"
+)
+
+func TestSetup(t *testing.T) {
+	collector := tracingtest.NewCollector(t)
+
+	shutdown, err := Setup(context.Background(), Config{
+		ServiceName: "test-service",
+		Endpoint:    collector.Endpoint(),
+		Insecure:    true,
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+
+	ctx, parent := Start(context.Background(), "parent", attribute.String("some-key", "some-value"))
+	_, child := Start(ctx, "child")
+	End(child, errors.New("some error"))
+	End(parent, nil)
+
+	// Shutting down flushes the spans to the collector.
+	require.NoError(t, shutdown(context.Background()))
+
+	resourceSpans := collector.ResourceSpans()
+	require.Len(t, resourceSpans, 1)
+	require.Equal(t, "service.name", resourceSpans[0].Resource.Attributes[0].Key)
+	require.Equal(t, "test-service", resourceSpans[0].Resource.Attributes[0].Value.GetStringValue())
+	require.Len(t, resourceSpans[0].InstrumentationLibrarySpans, 1)
+	spans := resourceSpans[0].InstrumentationLibrarySpans[0].Spans
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].Name)
+	require.Equal(t, "some error", spans[0].Status.Message)
+	require.Equal(t, "parent", spans[1].Name)
+	require.Equal(t, spans[1].SpanId, spans[0].ParentSpanId)
+	require.Equal(t, spans[1].TraceId, spans[0].TraceId)
+	require.Equal(t, "some-key", spans[1].Attributes[0].Key)
+
+	// Spans are discarded after the shutdown.
+	_, span := Start(context.Background(), "discarded")
+	require.False(t, span.IsRecording())
+}
+
+func TestHTTPSpans(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The trace context is not sent to upstream servers.
+		require.Empty(t, r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	t.Cleanup(upstream.Close)
+	client := &http.Client{Transport: NewTransport("some upstream", http.DefaultTransport)}
+
+	clientCtx, traceID, err := WithNewTrace(context.Background())
+	require.NoError(t, err)
+	require.Len(t, traceID, 32)
+	sameCtx, sameTraceID, err := WithNewTrace(clientCtx)
+	require.NoError(t, err)
+	require.Equal(t, clientCtx, sameCtx)
+	require.Equal(t, traceID, sameTraceID)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Contains(t, r.Header.Get("traceparent"), traceID)
+		r, span := StartHTTPServerSpan(r, "/some/endpoint")
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		EndHTTPSpan(span, http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequestWithContext(clientCtx, http.MethodGet, server.URL+"/some/endpoint?code=some-secret-code", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Forwarded-For", "192.0.2.1") // not from a trusted proxy
+	propagate := func(r *http.Request) bool { return r.URL.Host == strings.TrimPrefix(server.URL, "http://") }
+	resp, err := (&http.Client{Transport: NewPropagatingTransport(http.DefaultTransport, propagate)}).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Empty(t, req.Header.Get("traceparent"), "request should not have been modified")
+
+	// The trace context is only sent to the servers for which propagate returns true.
+	req, err = http.NewRequestWithContext(clientCtx, http.MethodGet, upstream.URL, nil)
+	require.NoError(t, err)
+	resp, err = (&http.Client{Transport: NewPropagatingTransport(http.DefaultTransport, propagate)}).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	spans := recorder.GetSpans()
+	require.Equal(t, []string{"some upstream GET", "/some/endpoint"}, tracingtest.SpanNames(recorder))
+
+	upstreamSpan, serverSpan := spans[0], spans[1]
+	require.Equal(t, trace.SpanKindClient, upstreamSpan.SpanKind)
+	require.Equal(t, codes.Error, upstreamSpan.StatusCode) // 418 is a client error of the Supervisor
+	require.Equal(t, serverSpan.SpanContext.SpanID(), upstreamSpan.Parent.SpanID())
+
+	require.Equal(t, trace.SpanKindServer, serverSpan.SpanKind)
+	require.Equal(t, codes.Error, serverSpan.StatusCode)
+	require.Equal(t, traceID, serverSpan.SpanContext.TraceID().String())
+	require.True(t, serverSpan.Parent.IsRemote())
+	require.False(t, serverSpan.Parent.IsSampled(), "clients leave the sampling decision to the server")
+	require.Contains(t, serverSpan.Attributes, semconv.HTTPTargetKey.String("/some/endpoint"))
+	require.Contains(t, serverSpan.Attributes, semconv.HTTPMethodKey.String("GET"))
+	require.Contains(t, serverSpan.Attributes, semconv.HTTPClientIPKey.String("127.0.0.1"))
+	require.NotContains(t, serverSpan.Attributes, semconv.HTTPClientIPKey.String("192.0.2.1"))
+}
+
+func TestSampler(t *testing.T) {
+	traceID := trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	parent := func(remote bool, flags trace.TraceFlags) context.Context {
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1},
+			TraceFlags: flags,
+			Remote:     remote,
+		})
+		return trace.ContextWithSpanContext(context.Background(), spanContext)
+	}
+	sampled := func(ratio float64, ctx context.Context) bool {
+		result := newSampler(ratio).ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx, TraceID: traceID, Name: "some-span"})
+		return result.Decision == sdktrace.RecordAndSample
+	}
+
+	// This trace ID is not within any ratio below 1.
+	require.True(t, sampled(1, context.Background()))
+	require.False(t, sampled(0.5, context.Background()))
+
+	// Remote parents cannot force or prevent sampling.
+	require.False(t, sampled(0.5, parent(true, trace.FlagsSampled)))
+	require.True(t, sampled(1, parent(true, 0)))
+
+	// The spans of the same process follow their parents.
+	require.True(t, sampled(0.5, parent(false, trace.FlagsSampled)))
+	require.False(t, sampled(1, parent(false, 0)))
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"go.pinniped.dev/internal/tracing"
+)
+
+// The operations which are measured.
+const (
+	operationDial   = "dial"
+	operationBind   = "bind"
+	operationSearch = "search"
+)
+
+//nolint:gochecknoglobals
+var (
+	operationDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "ldap_operation_duration_seconds",
+			Help:           "How long the dials, binds and searches of each upstream LDAP identity provider took.",
+			Buckets:        metrics.ExponentialBuckets(0.005, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"upstream", "operation"},
+	)
+
+	operationErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "pinniped",
+			Subsystem:      "supervisor",
+			Name:           "ldap_operation_errors_total",
+			Help:           "Number of dials, binds and searches of each upstream LDAP identity provider which failed, not counting binds with invalid credentials.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"upstream", "operation"},
+	)
+)
+
+//nolint:gochecknoinits
+func init() {
+	legacyregistry.MustRegister(operationDuration, operationErrors)
+}
+
+// measure measures an operation with an LDAP server and traces it as a child of the span of the context.
+func measure(ctx context.Context, upstreamName, operation string, f func() error) error {
+	_, span := tracing.Start(ctx, "ldap "+operation, attribute.String("pinniped.upstream_name", upstreamName))
+	start := time.Now()
+	err := f()
+	operationDuration.WithLabelValues(upstreamName, operation).Observe(time.Since(start).Seconds())
+
+	// A bind with a wrong password is a normal outcome, not a failure of the LDAP server.
+	ldapErr := &ldap.Error{}
+	failed := err != nil && !(errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultInvalidCredentials)
+	if failed {
+		operationErrors.WithLabelValues(upstreamName, operation).Inc()
+		tracing.End(span, err)
+	} else {
+		span.End()
+	}
+	return err
+}
+
+// measuredDial is like dial, but also measures the dial and the operations on the connection which it returns.
+func (p *Provider) measuredDial(ctx context.Context) (Conn, error) {
+	var conn Conn
+	err := measure(ctx, p.GetName(), operationDial, func() error {
+		var err error
+		conn, err = p.dial(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &measuredConn{Conn: conn, ctx: ctx, upstreamName: p.GetName()}, nil
+}
+
+type measuredConn struct {
+	Conn
+	ctx          context.Context // the context of the operation which uses the connection, since Conn does not take one
+	upstreamName string
+}
+
+func (c *measuredConn) Bind(username, password string) error {
+	return measure(c.ctx, c.upstreamName, operationBind, func() error {
+		return c.Conn.Bind(username, password)
+	})
+}
+
+func (c *measuredConn) Search(searchRequest *ldap.SearchRequest) (result *ldap.SearchResult, err error) {
+	err = measure(c.ctx, c.upstreamName, operationSearch, func() error {
+		result, err = c.Conn.Search(searchRequest)
+		return err
+	})
+	return result, err
+}
+
+func (c *measuredConn) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (result *ldap.SearchResult, err error) {
+	err = measure(c.ctx, c.upstreamName, operationSearch, func() error {
+		result, err = c.Conn.SearchWithPaging(searchRequest, pagingSize)
+		return err
+	})
+	return result, err
+}
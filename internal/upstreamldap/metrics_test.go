@@ -0,0 +1,83 @@
+// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"k8s.io/component-base/metrics/testutil"
+
+	"go.pinniped.dev/internal/endpointaddr"
+	"go.pinniped.dev/internal/mocks/mockldapconn"
+	"go.pinniped.dev/internal/testutil/tracingtest"
+	"go.pinniped.dev/internal/tracing"
+)
+
+func TestMeasuredDial(t *testing.T) {
+	const upstreamName = "test-measured-dial-ldap"
+	recorder := tracingtest.Record(t)
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	conn := mockldapconn.NewMockConn(ctrl)
+	conn.EXPECT().Bind("some-user", "wrong-password").Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("wrong password")))
+	conn.EXPECT().Bind("some-user", "some-password").Return(ldap.NewError(ldap.LDAPResultUnavailable, errors.New("some bind error")))
+	conn.EXPECT().Search(gomock.Any()).Return(&ldap.SearchResult{}, nil)
+	conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(5)).Return(nil, errors.New("some search error"))
+	conn.EXPECT().Close()
+
+	provider := New(ProviderConfig{
+		Name:               upstreamName,
+		Host:               "ldap.example.com",
+		ConnectionProtocol: TLS,
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			return conn, nil
+		}),
+	})
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	measured, err := provider.measuredDial(ctx)
+	require.NoError(t, err)
+	defer measured.Close()
+
+	require.Error(t, measured.Bind("some-user", "wrong-password"))
+	require.Error(t, measured.Bind("some-user", "some-password"))
+	_, err = measured.Search(&ldap.SearchRequest{})
+	require.NoError(t, err)
+	_, err = measured.SearchWithPaging(&ldap.SearchRequest{}, 5)
+	require.EqualError(t, err, "some search error")
+
+	requireCounts := func(operation string, wantCount uint64, wantErrors float64) {
+		t.Helper()
+		count, err := testutil.GetHistogramMetricCount(operationDuration.WithLabelValues(upstreamName, operation))
+		require.NoError(t, err)
+		require.Equal(t, wantCount, count)
+		errorCount, err := testutil.GetCounterMetricValue(operationErrors.WithLabelValues(upstreamName, operation))
+		require.NoError(t, err)
+		require.Equal(t, wantErrors, errorCount)
+	}
+	requireCounts(operationDial, 1, 0)
+	// Invalid credentials are not an error of the upstream identity provider.
+	requireCounts(operationBind, 2, 1)
+	requireCounts(operationSearch, 2, 1)
+
+	parent.End()
+	require.Equal(t, []string{"ldap dial", "ldap bind", "ldap bind", "ldap search", "ldap search", "parent"}, tracingtest.SpanNames(recorder))
+	spans := recorder.GetSpans()
+	for _, span := range spans[:5] {
+		require.Equal(t, spans[5].SpanContext.SpanID(), span.Parent.SpanID())
+		require.Contains(t, span.Attributes, attribute.String("pinniped.upstream_name", upstreamName))
+	}
+	wantStatuses := []codes.Code{codes.Unset, codes.Unset, codes.Error, codes.Unset, codes.Error}
+	for i, want := range wantStatuses {
+		require.Equal(t, want, spans[i].StatusCode, "span %d", i)
+	}
+}
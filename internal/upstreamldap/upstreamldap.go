@@ -26,6 +26,7 @@ import (
 	"go.pinniped.dev/internal/endpointaddr"
 	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/tlsprofile"
 )
 
 const (
@@ -246,7 +247,9 @@ func (p *Provider) tlsConfig() (*tls.Config, error) {
 			return nil, fmt.Errorf("could not parse CA bundle")
 		}
 	}
-	return &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: rootCAs}, nil
+	tlsConfig := tlsprofile.ClientConfig()
+	tlsConfig.RootCAs = rootCAs
+	return tlsConfig, nil
 }
 
 // A name for this upstream provider.
@@ -273,7 +276,7 @@ func (p *Provider) TestConnection(ctx context.Context) error {
 		return err
 	}
 
-	conn, err := p.dial(ctx)
+	conn, err := p.measuredDial(ctx)
 	if err != nil {
 		return fmt.Errorf(`error dialing host "%s": %w`, p.c.Host, err)
 	}
@@ -323,7 +326,7 @@ func (p *Provider) authenticateUserImpl(ctx context.Context, username string, bi
 		return nil, false, nil
 	}
 
-	conn, err := p.dial(ctx)
+	conn, err := p.measuredDial(ctx)
 	if err != nil {
 		p.traceAuthFailure(t, err)
 		return nil, false, fmt.Errorf(`error dialing host "%s": %w`, p.c.Host, err)
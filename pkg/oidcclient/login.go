@@ -31,6 +31,7 @@ import (
 	"go.pinniped.dev/internal/httputil/httperr"
 	"go.pinniped.dev/internal/httputil/securityheader"
 	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/tracing"
 	"go.pinniped.dev/internal/upstreamoidc"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
 	"go.pinniped.dev/pkg/oidcclient/oidctypes"
@@ -98,9 +99,12 @@ type handlerState struct {
 	provider     *oidc.Provider
 	oauth2Config *oauth2.Config
 	useFormPost  bool
-	state        state.State
-	nonce        nonce.Nonce
-	pkce         pkce.Code
+	parURL       string
+	// supervisorHost is the host of the issuer, once OIDC discovery has shown that the issuer is a Pinniped Supervisor.
+	supervisorHost string
+	state          state.State
+	nonce          nonce.Nonce
+	pkce           pkce.Code
 
 	// External calls for things.
 	generateState   func() (state.State, error)
@@ -301,8 +305,11 @@ func Login(issuer string, clientID string, opts ...Option) (*oidctypes.Token, er
 	}
 
 	// Copy the configured HTTP client to set a request timeout (the Go default client has no timeout configured).
+	// Its requests to a Pinniped Supervisor also carry the trace context of h.ctx, if any, so the Supervisor can add its
+	// spans to the same trace. Other issuers never get the trace context.
 	httpClientWithTimeout := *h.httpClient
 	httpClientWithTimeout.Timeout = httpRequestTimeout
+	httpClientWithTimeout.Transport = tracing.NewPropagatingTransport(h.httpClient.Transport, h.isSupervisorRequest)
 	h.httpClient = &httpClientWithTimeout
 
 	// Always set a long, but non-infinite timeout for this operation.
@@ -430,7 +437,10 @@ func (h *handlerState) cliBasedAuth(authorizeOptions *[]oauth2.AuthCodeOption) (
 	}).String()
 
 	// Now that we have a redirect URL, we can build the authorize URL.
-	authorizeURL := h.oauth2Config.AuthCodeURL(h.state.String(), *authorizeOptions...)
+	authorizeURL, err := h.authorizeURL(*authorizeOptions)
+	if err != nil {
+		return nil, err
+	}
 
 	// Don't follow redirects automatically because we want to handle redirects here.
 	h.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -564,7 +574,13 @@ func (h *handlerState) webBrowserBasedAuth(authorizeOptions *[]oauth2.AuthCodeOp
 	}
 
 	// Now that we have a redirect URL with the listener port, we can build the authorize URL.
-	authorizeURL := h.oauth2Config.AuthCodeURL(h.state.String(), authParams...)
+	authorizeURL, err := h.authorizeURL(authParams)
+	if err != nil {
+		if listener != nil {
+			_ = listener.Close()
+		}
+		return nil, err
+	}
 
 	// If there is a listener running, start serving the callback handler in a background goroutine.
 	if listener != nil {
@@ -714,17 +730,101 @@ func (h *handlerState) initOIDCDiscovery() error {
 		Scopes:   h.scopes,
 	}
 
-	// Use response_mode=form_post if the provider supports it.
+	// Use response_mode=form_post if the provider supports it, and push the authorize request params to the
+	// provider first if it offers a pushed authorization request endpoint.
 	var discoveryClaims struct {
-		ResponseModesSupported []string `json:"response_modes_supported"`
+		ResponseModesSupported             []string        `json:"response_modes_supported"`
+		PushedAuthorizationRequestEndpoint string          `json:"pushed_authorization_request_endpoint"`
+		SupervisorDiscovery                json.RawMessage `json:"discovery.supervisor.pinniped.dev/v1alpha1"`
 	}
 	if err := h.provider.Claims(&discoveryClaims); err != nil {
 		return fmt.Errorf("could not decode response_modes_supported in OIDC discovery from %q: %w", h.issuer, err)
 	}
 	h.useFormPost = stringSliceContains(discoveryClaims.ResponseModesSupported, "form_post")
+	h.parURL = discoveryClaims.PushedAuthorizationRequestEndpoint
+	if len(discoveryClaims.SupervisorDiscovery) > 0 {
+		if issuerURL, err := url.Parse(h.issuer); err == nil {
+			h.supervisorHost = issuerURL.Host
+		}
+	}
 	return nil
 }
 
+// isSupervisorRequest returns true for the requests which go to the issuer, once OIDC discovery has shown that the
+// issuer is a Pinniped Supervisor.
+func (h *handlerState) isSupervisorRequest(r *http.Request) bool {
+	return h.supervisorHost != "" && r.URL.Host == h.supervisorHost
+}
+
+// authorizeURL builds the URL of the authorize request. When the provider offers a pushed authorization request
+// endpoint, the params are pushed there first and the URL only refers to them, so it stays short and the params
+// do not end up in the browser history.
+func (h *handlerState) authorizeURL(opts []oauth2.AuthCodeOption) (string, error) {
+	authorizeURL := h.oauth2Config.AuthCodeURL(h.state.String(), opts...)
+	if h.parURL == "" {
+		return authorizeURL, nil
+	}
+
+	parsedURL, err := url.Parse(authorizeURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse authorize URL: %w", err)
+	}
+	requestURI, err := h.pushAuthorizationRequest(parsedURL.Query())
+	if err != nil {
+		return "", fmt.Errorf("pushed authorization request failed: %w", err)
+	}
+	parsedURL.RawQuery = url.Values{
+		"client_id":   []string{h.clientID},
+		"request_uri": []string{requestURI},
+	}.Encode()
+	return parsedURL.String(), nil
+}
+
+// pushAuthorizationRequest sends the authorize request params to the pushed authorization request endpoint, as
+// described in RFC 9126, and returns the request_uri which refers to them.
+func (h *handlerState) pushAuthorizationRequest(params url.Values) (string, error) {
+	h.logger.V(debugLogLevel).Info("Pinniped: Pushing authorization request", "endpoint", h.parURL)
+
+	ctx, cancel := context.WithTimeout(h.ctx, httpRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.parURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("could not build request: %w", err)
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var respBody struct {
+		RequestURI       string `json:"request_uri"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&respBody)
+
+	// Expect an HTTP 201 response, or an OAuth error in the body of any other response.
+	if resp.StatusCode != http.StatusCreated {
+		if decodeErr == nil && respBody.Error != "" {
+			if respBody.ErrorDescription == "" {
+				return "", fmt.Errorf("unexpected HTTP response status %d with error %q", resp.StatusCode, respBody.Error)
+			}
+			return "", fmt.Errorf("unexpected HTTP response status %d with error %q: %s", resp.StatusCode, respBody.Error, respBody.ErrorDescription)
+		}
+		return "", fmt.Errorf("unexpected HTTP response status %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	if respBody.RequestURI == "" {
+		return "", fmt.Errorf("response did not include a request_uri")
+	}
+	return respBody.RequestURI, nil
+}
+
 func stringSliceContains(slice []string, s string) bool {
 	for _, item := range slice {
 		if item == s {
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sort"
 	"strings"
 	"syscall"
 	"testing"
@@ -35,6 +36,7 @@ import (
 	"go.pinniped.dev/internal/oidc/provider"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/testlogger"
+	"go.pinniped.dev/internal/tracing"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
 	"go.pinniped.dev/pkg/oidcclient/oidctypes"
 	"go.pinniped.dev/pkg/oidcclient/pkce"
@@ -119,12 +121,16 @@ func TestLogin(t *testing.T) { // nolint:gocyclo
 	})
 	t.Cleanup(brokenTokenURLServer.Close)
 
-	discoveryHandler := func(server *httptest.Server, responseModes []string) http.HandlerFunc {
+	discoveryHandler := func(server *httptest.Server, responseModes []string, parPath string) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			if r.Method != http.MethodGet {
 				http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
 				return
 			}
+			var parURL string
+			if parPath != "" {
+				parURL = server.URL + parPath
+			}
 			w.Header().Set("content-type", "application/json")
 			_ = json.NewEncoder(w).Encode(&struct {
 				Issuer                 string   `json:"issuer"`
@@ -132,15 +138,39 @@ func TestLogin(t *testing.T) { // nolint:gocyclo
 				TokenURL               string   `json:"token_endpoint"`
 				JWKSURL                string   `json:"jwks_uri"`
 				ResponseModesSupported []string `json:"response_modes_supported,omitempty"`
+				PARURL                 string   `json:"pushed_authorization_request_endpoint,omitempty"`
 			}{
 				Issuer:                 server.URL,
 				AuthURL:                server.URL + "/authorize",
 				TokenURL:               server.URL + "/token",
 				JWKSURL:                server.URL + "/keys",
 				ResponseModesSupported: responseModes,
+				PARURL:                 parURL,
 			})
 		}
 	}
+	parHandler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		if r.PostForm.Get("pinniped_idp_name") == "some-invalid-upstream-name" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"some invalid request"}`))
+			return
+		}
+		// Echo the pushed params back in the request_uri so the tests can check them at the authorize endpoint.
+		w.WriteHeader(http.StatusCreated)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"request_uri": "urn:ietf:params:oauth:request_uri:" + r.PostForm.Encode(),
+			"expires_in":  60,
+		}))
+	}
 	tokenHandler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
@@ -228,16 +258,25 @@ func TestLogin(t *testing.T) { // nolint:gocyclo
 	providerMux := http.NewServeMux()
 	successServer := httptest.NewServer(providerMux)
 	t.Cleanup(successServer.Close)
-	providerMux.HandleFunc("/.well-known/openid-configuration", discoveryHandler(successServer, nil))
+	providerMux.HandleFunc("/.well-known/openid-configuration", discoveryHandler(successServer, nil, ""))
 	providerMux.HandleFunc("/token", tokenHandler)
 
 	// Start a test server that returns a real discovery document and answers refresh requests, _and_ supports form_mode=post.
 	formPostProviderMux := http.NewServeMux()
 	formPostSuccessServer := httptest.NewServer(formPostProviderMux)
 	t.Cleanup(formPostSuccessServer.Close)
-	formPostProviderMux.HandleFunc("/.well-known/openid-configuration", discoveryHandler(formPostSuccessServer, []string{"query", "form_post"}))
+	formPostProviderMux.HandleFunc("/.well-known/openid-configuration", discoveryHandler(formPostSuccessServer, []string{"query", "form_post"}, ""))
 	formPostProviderMux.HandleFunc("/token", tokenHandler)
 
+	// Start a test server that returns a real discovery document and answers refresh requests, _and_ offers a
+	// pushed authorization request endpoint.
+	parProviderMux := http.NewServeMux()
+	parSuccessServer := httptest.NewServer(parProviderMux)
+	t.Cleanup(parSuccessServer.Close)
+	parProviderMux.HandleFunc("/.well-known/openid-configuration", discoveryHandler(parSuccessServer, nil, "/par"))
+	parProviderMux.HandleFunc("/par", parHandler)
+	parProviderMux.HandleFunc("/token", tokenHandler)
+
 	defaultDiscoveryResponse := func(req *http.Request) (*http.Response, error) { // nolint:unparam
 		// Call the handler function from the test server to calculate the response.
 		handler, _ := providerMux.Handler(req)
@@ -802,6 +841,102 @@ func TestLogin(t *testing.T) { // nolint:gocyclo
 			wantLogs:  []string{"\"level\"=4 \"msg\"=\"Pinniped: Performing OIDC discovery\"  \"issuer\"=\"" + successServer.URL + "\""},
 			wantToken: &testToken,
 		},
+		{
+			name:     "authorize request params are pushed if the provider offers a pushed authorization request endpoint",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					h.generateState = func() (state.State, error) { return "test-state", nil }
+					h.generatePKCE = func() (pkce.Code, error) { return "test-pkce", nil }
+					h.generateNonce = func() (nonce.Nonce, error) { return "test-nonce", nil }
+
+					cache := &mockSessionCache{t: t, getReturnsToken: nil}
+					cacheKey := SessionCacheKey{
+						Issuer:      parSuccessServer.URL,
+						ClientID:    "test-client-id",
+						Scopes:      []string{"test-scope"},
+						RedirectURI: "http://localhost:0/callback",
+					}
+					t.Cleanup(func() {
+						require.Equal(t, []SessionCacheKey{cacheKey}, cache.sawGetKeys)
+						require.Equal(t, []SessionCacheKey{cacheKey}, cache.sawPutKeys)
+						require.Equal(t, []*oidctypes.Token{&testToken}, cache.sawPutTokens)
+					})
+					require.NoError(t, WithSessionCache(cache)(h))
+					require.NoError(t, WithClient(&http.Client{Timeout: 10 * time.Second})(h))
+
+					h.openURL = func(actualURL string) error {
+						parsedActualURL, err := url.Parse(actualURL)
+						require.NoError(t, err)
+						actualParams := parsedActualURL.Query()
+
+						// Only the client_id and the request_uri should be visible in the authorize URL.
+						require.Equal(t, []string{"client_id", "request_uri"}, sortedKeys(actualParams))
+						require.Equal(t, "test-client-id", actualParams.Get("client_id"))
+						requestURI := actualParams.Get("request_uri")
+						require.True(t, strings.HasPrefix(requestURI, "urn:ietf:params:oauth:request_uri:"))
+						pushedParams, err := url.ParseQuery(strings.TrimPrefix(requestURI, "urn:ietf:params:oauth:request_uri:"))
+						require.NoError(t, err)
+
+						require.Contains(t, pushedParams.Get("redirect_uri"), "http://127.0.0.1:")
+						pushedParams.Del("redirect_uri")
+
+						require.Equal(t, url.Values{
+							"code_challenge":        []string{"VVaezYqum7reIhoavCHD1n2d-piN3r_mywoYj7fCR7g"},
+							"code_challenge_method": []string{"S256"},
+							"response_type":         []string{"code"},
+							"scope":                 []string{"test-scope"},
+							"nonce":                 []string{"test-nonce"},
+							"state":                 []string{"test-state"},
+							"access_type":           []string{"offline"},
+							"client_id":             []string{"test-client-id"},
+						}, pushedParams)
+
+						parsedActualURL.RawQuery = ""
+						require.Equal(t, parSuccessServer.URL+"/authorize", parsedActualURL.String())
+
+						go func() {
+							h.callbacks <- callbackResult{token: &testToken}
+						}()
+						return nil
+					}
+					return nil
+				}
+			},
+			issuer: parSuccessServer.URL,
+			wantLogs: []string{
+				"\"level\"=4 \"msg\"=\"Pinniped: Performing OIDC discovery\"  \"issuer\"=\"" + parSuccessServer.URL + "\"",
+				"\"level\"=4 \"msg\"=\"Pinniped: Pushing authorization request\"  \"endpoint\"=\"" + parSuccessServer.URL + "/par\"",
+			},
+			wantToken: &testToken,
+		},
+		{
+			name:     "pushed authorization request returns an error",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					cache := &mockSessionCache{t: t, getReturnsToken: nil}
+					t.Cleanup(func() {
+						require.Len(t, cache.sawGetKeys, 1)
+						require.Empty(t, cache.sawPutKeys)
+					})
+					require.NoError(t, WithSessionCache(cache)(h))
+					require.NoError(t, WithClient(&http.Client{Timeout: 10 * time.Second})(h))
+					require.NoError(t, WithUpstreamIdentityProvider("some-invalid-upstream-name", "oidc")(h))
+					h.openURL = func(actualURL string) error {
+						require.FailNow(t, "should not have opened the browser")
+						return nil
+					}
+					return nil
+				}
+			},
+			issuer: parSuccessServer.URL,
+			wantLogs: []string{
+				"\"level\"=4 \"msg\"=\"Pinniped: Performing OIDC discovery\"  \"issuer\"=\"" + parSuccessServer.URL + "\"",
+				"\"level\"=4 \"msg\"=\"Pinniped: Pushing authorization request\"  \"endpoint\"=\"" + parSuccessServer.URL + "/par\"",
+			},
+			wantErr: `pushed authorization request failed: unexpected HTTP response status 400 with error "invalid_request": some invalid request`,
+		},
 		{
 			name:     "ldap login when prompting for username returns an error",
 			clientID: "test-client-id",
@@ -1092,6 +1227,84 @@ func TestLogin(t *testing.T) { // nolint:gocyclo
 			wantLogs:  []string{"\"level\"=4 \"msg\"=\"Pinniped: Performing OIDC discovery\"  \"issuer\"=\"" + successServer.URL + "\""},
 			wantToken: &testToken,
 		},
+		{
+			name:     "successful ldap login with pushed authorization request",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					fakeAuthCode := "test-authcode-value"
+
+					h.getProvider = func(_ *oauth2.Config, _ *oidc.Provider, _ *http.Client) provider.UpstreamOIDCIdentityProviderI {
+						mock := mockUpstream(t)
+						mock.EXPECT().
+							ExchangeAuthcodeAndValidateTokens(
+								gomock.Any(), fakeAuthCode, pkce.Code("test-pkce"), nonce.Nonce("test-nonce"), "http://127.0.0.1:0/callback").
+							Return(&testToken, nil)
+						return mock
+					}
+
+					h.generateState = func() (state.State, error) { return "test-state", nil }
+					h.generatePKCE = func() (pkce.Code, error) { return "test-pkce", nil }
+					h.generateNonce = func() (nonce.Nonce, error) { return "test-nonce", nil }
+					h.getEnv = func(_ string) string { return "" }
+					h.promptForValue = func(_ context.Context, _ string) (string, error) { return "some-upstream-username", nil }
+					h.promptForSecret = func(_ string) (string, error) { return "some-upstream-password", nil }
+
+					require.NoError(t, WithSessionCache(&mockSessionCache{t: t, getReturnsToken: nil})(h))
+					require.NoError(t, WithCLISendingCredentials()(h))
+					require.NoError(t, WithUpstreamIdentityProvider("some-upstream-name", "ldap")(h))
+
+					parRequestWasMade := false
+					authorizeRequestWasMade := false
+					t.Cleanup(func() {
+						require.True(t, parRequestWasMade, "should have made a pushed authorization request")
+						require.True(t, authorizeRequestWasMade, "should have made an authorize request")
+					})
+
+					serveFromParProvider := func(req *http.Request) (*http.Response, error) {
+						handler, _ := parProviderMux.Handler(req)
+						recorder := httptest.NewRecorder()
+						handler.ServeHTTP(recorder, req)
+						return recorder.Result(), nil
+					}
+					require.NoError(t, WithClient(&http.Client{
+						Transport: roundtripper.Func(func(req *http.Request) (*http.Response, error) {
+							switch req.URL.Scheme + "://" + req.URL.Host + req.URL.Path {
+							case "http://" + parSuccessServer.Listener.Addr().String() + "/.well-known/openid-configuration":
+								return serveFromParProvider(req)
+							case "http://" + parSuccessServer.Listener.Addr().String() + "/par":
+								parRequestWasMade = true
+								require.NoError(t, req.ParseForm())
+								require.Equal(t, "ldap", req.PostForm.Get("pinniped_idp_type"))
+								require.Equal(t, "http://127.0.0.1:0/callback", req.PostForm.Get("redirect_uri"))
+								return serveFromParProvider(req)
+							case "http://" + parSuccessServer.Listener.Addr().String() + "/authorize":
+								authorizeRequestWasMade = true
+								require.Equal(t, "some-upstream-username", req.Header.Get("Pinniped-Username"))
+								require.Equal(t, "some-upstream-password", req.Header.Get("Pinniped-Password"))
+								require.Equal(t, []string{"client_id", "request_uri"}, sortedKeys(req.URL.Query()))
+								return &http.Response{
+									StatusCode: http.StatusFound,
+									Header: http.Header{"Location": []string{
+										fmt.Sprintf("http://127.0.0.1:0/callback?code=%s&state=test-state", fakeAuthCode),
+									}},
+								}, nil
+							default:
+								require.FailNow(t, fmt.Sprintf("saw unexpected http call from the CLI: %s", req.URL.String()))
+								return nil, nil
+							}
+						}),
+					})(h))
+					return nil
+				}
+			},
+			issuer: parSuccessServer.URL,
+			wantLogs: []string{
+				"\"level\"=4 \"msg\"=\"Pinniped: Performing OIDC discovery\"  \"issuer\"=\"" + parSuccessServer.URL + "\"",
+				"\"level\"=4 \"msg\"=\"Pinniped: Pushing authorization request\"  \"endpoint\"=\"" + parSuccessServer.URL + "/par\"",
+			},
+			wantToken: &testToken,
+		},
 		{
 			name:     "successful ldap login with env vars for username and password",
 			clientID: "test-client-id",
@@ -1902,6 +2115,15 @@ func TestHandleAuthCodeCallback(t *testing.T) {
 	}
 }
 
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func mockUpstream(t *testing.T) *mockupstreamoidcidentityprovider.MockUpstreamOIDCIdentityProviderI {
 	t.Helper()
 	ctrl := gomock.NewController(t)
@@ -1927,3 +2149,62 @@ func (m hasAccessTokenMatcher) String() string {
 func HasAccessToken(expected string) gomock.Matcher {
 	return hasAccessTokenMatcher{expected: expected}
 }
+
+func TestTraceContextIsOnlySentToSupervisors(t *testing.T) {
+	tests := []struct {
+		name           string
+		supervisor     bool
+		wantPropagated bool
+	}{
+		{name: "Pinniped Supervisor", supervisor: true, wantPropagated: true},
+		{name: "other issuer", supervisor: false, wantPropagated: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			traceparents := map[string]string{}
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparents[r.URL.Path] = r.Header.Get("traceparent")
+				if r.URL.Path != "/.well-known/openid-configuration" {
+					return
+				}
+				discovery := map[string]interface{}{
+					"issuer":                 server.URL,
+					"authorization_endpoint": server.URL + "/authorize",
+					"token_endpoint":         server.URL + "/token",
+					"jwks_uri":               server.URL + "/jwks.json",
+				}
+				if tt.supervisor {
+					discovery["discovery.supervisor.pinniped.dev/v1alpha1"] = map[string]string{
+						"pinniped_identity_providers_endpoint": server.URL + "/v1alpha1/pinniped_identity_providers",
+					}
+				}
+				w.Header().Set("content-type", "application/json")
+				require.NoError(t, json.NewEncoder(w).Encode(discovery))
+			}))
+			t.Cleanup(server.Close)
+
+			ctx, traceID, err := tracing.WithNewTrace(context.Background())
+			require.NoError(t, err)
+			h := &handlerState{issuer: server.URL, logger: testlogger.New(t).Logger}
+			h.httpClient = &http.Client{Transport: tracing.NewPropagatingTransport(nil, h.isSupervisorRequest)}
+			h.ctx = oidc.ClientContext(ctx, h.httpClient)
+			require.NoError(t, h.initOIDCDiscovery())
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/token", nil)
+			require.NoError(t, err)
+			resp, err := h.httpClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			// Whether the issuer is a Supervisor is not known before the discovery.
+			require.Empty(t, traceparents["/.well-known/openid-configuration"])
+			if tt.wantPropagated {
+				require.Contains(t, traceparents["/token"], traceID)
+			} else {
+				require.Empty(t, traceparents["/token"])
+			}
+		})
+	}
+}
@@ -99,6 +99,10 @@ func TestE2EFullIntegration(t *testing.T) {
 
 	// Create a JWTAuthenticator that will validate the tokens from the downstream issuer.
 	clusterAudience := "test-cluster-" + testlib.RandHex(t, 8)
+	testlib.CreateTestClusterAudience(ctx, t,
+		configv1alpha1.ClusterAudienceSpec{Audience: clusterAudience},
+		configv1alpha1.SuccessClusterAudienceStatusCondition,
+	)
 	authenticator := testlib.CreateTestJWTAuthenticator(ctx, t, authv1alpha.JWTAuthenticatorSpec{
 		Issuer:   downstream.Spec.Issuer,
 		Audience: clusterAudience,
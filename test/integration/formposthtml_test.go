@@ -20,6 +20,7 @@ import (
 
 	"go.pinniped.dev/internal/httputil/securityheader"
 	"go.pinniped.dev/internal/oidc/provider/formposthtml"
+	"go.pinniped.dev/internal/oidc/provider/locale"
 	"go.pinniped.dev/test/testlib"
 	"go.pinniped.dev/test/testlib/browsertest"
 )
@@ -153,11 +154,11 @@ func formpostCallbackServer(t *testing.T) (string, func(*testing.T, url.Values))
 // formpostTemplateServer runs a test server that serves formposthtml.Template() rendered with test parameters.
 func formpostTemplateServer(t *testing.T, redirectURI string, responseParams url.Values) string {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fosite.WriteAuthorizeFormPostResponse(redirectURI, responseParams, formposthtml.Template(), w)
+		fosite.WriteAuthorizeFormPostResponse(redirectURI, responseParams, formposthtml.Template(nil, locale.Default()), w)
 	})
 	server := httptest.NewServer(securityheader.WrapWithCustomCSP(
 		handler,
-		formposthtml.ContentSecurityPolicy(),
+		formposthtml.ContentSecurityPolicy(nil),
 	))
 	t.Cleanup(server.Close)
 	return server.URL
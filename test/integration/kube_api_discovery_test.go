@@ -137,6 +137,48 @@ func TestGetAPIResourceList(t *testing.T) {
 						Kind:       "FederationDomain",
 						Verbs:      []string{"get", "patch", "update"},
 					},
+					{
+						Name:         "clusteraudiences",
+						SingularName: "clusteraudience",
+						Namespaced:   true,
+						Kind:         "ClusterAudience",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped"},
+					},
+					{
+						Name:       "clusteraudiences/status",
+						Namespaced: true,
+						Kind:       "ClusterAudience",
+						Verbs:      []string{"get", "patch", "update"},
+					},
+					{
+						Name:         "trustedclusters",
+						SingularName: "trustedcluster",
+						Namespaced:   true,
+						Kind:         "TrustedCluster",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped"},
+					},
+					{
+						Name:       "trustedclusters/status",
+						Namespaced: true,
+						Kind:       "TrustedCluster",
+						Verbs:      []string{"get", "patch", "update"},
+					},
+					{
+						Name:         "machineclients",
+						SingularName: "machineclient",
+						Namespaced:   true,
+						Kind:         "MachineClient",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped"},
+					},
+					{
+						Name:       "machineclients/status",
+						Namespaced: true,
+						Kind:       "MachineClient",
+						Verbs:      []string{"get", "patch", "update"},
+					},
 				},
 			},
 		},
@@ -51,3 +51,44 @@ func TestSupervisorHealthz(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "ok", string(responseBody))
 }
+
+// The Supervisor readiness endpoint is used by the readiness probe of its Deployment.
+// This test checks that the Supervisor under test is ready and that the verbose output lists each check.
+func TestSupervisorReadyz(t *testing.T) {
+	env := testlib.IntegrationEnv(t)
+
+	if env.SupervisorHTTPAddress == "" {
+		t.Skip("PINNIPED_TEST_SUPERVISOR_HTTP_ADDRESS not defined")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Other tests may be creating upstreams which are purposefully invalid, so do not depend on their readiness.
+	requestReadyEndpoint, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("http://%s/readyz?verbose&exclude=upstream-identity-providers", env.SupervisorHTTPAddress),
+		nil,
+	)
+	require.NoError(t, err)
+
+	httpClient := &http.Client{}
+	response, err := httpClient.Do(requestReadyEndpoint) //nolint:bodyclose
+	require.NoError(t, err)
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	err = response.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode, string(responseBody))
+	require.Equal(t, ""+
+		"[+]shutdown ok\n"+
+		"[+]informer-sync ok\n"+
+		"[+]federation-domain-keys ok\n"+
+		"[+]upstream-identity-providers excluded: ok\n"+
+		"[+]kube-api ok\n"+
+		"readyz check passed\n",
+		string(responseBody),
+	)
+}
@@ -343,6 +343,13 @@ func testSupervisorLogin(
 		configv1alpha1.SuccessFederationDomainStatusCondition,
 	)
 
+	// Allow the downstream tokens to be exchanged for tokens for a cluster.
+	clusterAudience := "test-cluster-" + testlib.RandHex(t, 8)
+	testlib.CreateTestClusterAudience(ctx, t,
+		configv1alpha1.ClusterAudienceSpec{Audience: clusterAudience},
+		configv1alpha1.SuccessClusterAudienceStatusCondition,
+	)
+
 	// Ensure the the JWKS data is created and ready for the new FederationDomain by waiting for
 	// the `/jwks.json` endpoint to succeed, because there is no point in proceeding and eventually
 	// calling the token endpoint from this test until the JWKS data has been loaded into
@@ -419,7 +426,7 @@ func testSupervisorLogin(
 		expectedIDTokenClaims, wantDownstreamIDTokenSubjectToMatch, wantDownstreamIDTokenUsernameToMatch, wantDownstreamIDTokenGroups)
 
 	// token exchange on the original token
-	doTokenExchange(t, &downstreamOAuth2Config, tokenResponse, httpClient, discovery)
+	doTokenExchange(t, &downstreamOAuth2Config, tokenResponse, httpClient, discovery, clusterAudience)
 
 	// Use the refresh token to get new tokens
 	refreshSource := downstreamOAuth2Config.TokenSource(oidcHTTPClientContext, &oauth2.Token{RefreshToken: tokenResponse.RefreshToken})
@@ -437,7 +444,7 @@ func testSupervisorLogin(
 	require.NotEqual(t, tokenResponse.Extra("id_token"), refreshedTokenResponse.Extra("id_token"))
 
 	// token exchange on the refreshed token
-	doTokenExchange(t, &downstreamOAuth2Config, refreshedTokenResponse, httpClient, discovery)
+	doTokenExchange(t, &downstreamOAuth2Config, refreshedTokenResponse, httpClient, discovery, clusterAudience)
 }
 
 func verifyTokenResponse(
@@ -608,14 +615,14 @@ func (s *localCallbackServer) waitForCallback(timeout time.Duration) *http.Reque
 	}
 }
 
-func doTokenExchange(t *testing.T, config *oauth2.Config, tokenResponse *oauth2.Token, httpClient *http.Client, provider *coreosoidc.Provider) {
+func doTokenExchange(t *testing.T, config *oauth2.Config, tokenResponse *oauth2.Token, httpClient *http.Client, provider *coreosoidc.Provider, audience string) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
 	// Form the HTTP POST request with the parameters specified by RFC8693.
 	reqBody := strings.NewReader(url.Values{
 		"grant_type":           []string{"urn:ietf:params:oauth:grant-type:token-exchange"},
-		"audience":             []string{"cluster-1234"},
+		"audience":             []string{audience},
 		"client_id":            []string{config.ClientID},
 		"subject_token":        []string{tokenResponse.AccessToken},
 		"subject_token_type":   []string{"urn:ietf:params:oauth:token-type:access_token"},
@@ -636,7 +643,7 @@ func doTokenExchange(t *testing.T, config *oauth2.Config, tokenResponse *oauth2.
 	}
 	require.NoError(t, json.NewDecoder(resp.Body).Decode(&respBody))
 
-	var clusterVerifier = provider.Verifier(&coreosoidc.Config{ClientID: "cluster-1234"})
+	var clusterVerifier = provider.Verifier(&coreosoidc.Config{ClientID: audience})
 	exchangedToken, err := clusterVerifier.Verify(ctx, respBody.AccessToken)
 	require.NoError(t, err)
 
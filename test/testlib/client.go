@@ -311,6 +311,48 @@ func CreateTestFederationDomain(ctx context.Context, t *testing.T, issuer string
 	return federationDomain
 }
 
+func CreateTestClusterAudience(ctx context.Context, t *testing.T, spec configv1alpha1.ClusterAudienceSpec, expectStatus configv1alpha1.ClusterAudienceStatusCondition) *configv1alpha1.ClusterAudience {
+	t.Helper()
+	testEnv := IntegrationEnv(t)
+
+	createContext, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	clusterAudiences := NewSupervisorClientset(t).ConfigV1alpha1().ClusterAudiences(testEnv.SupervisorNamespace)
+	clusterAudience, err := clusterAudiences.Create(createContext, &configv1alpha1.ClusterAudience{
+		ObjectMeta: testObjectMeta(t, "cluster-audience"),
+		Spec:       spec,
+	}, metav1.CreateOptions{})
+	require.NoError(t, err, "could not create test ClusterAudience")
+	t.Logf("created test ClusterAudience %s/%s", clusterAudience.Namespace, clusterAudience.Name)
+
+	t.Cleanup(func() {
+		t.Helper()
+		t.Logf("cleaning up test ClusterAudience %s/%s", clusterAudience.Namespace, clusterAudience.Name)
+		deleteCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		err := clusterAudiences.Delete(deleteCtx, clusterAudience.Name, metav1.DeleteOptions{})
+		notFound := k8serrors.IsNotFound(err)
+		// It's okay if it is not found, because it might have been deleted by another part of this test.
+		if !notFound {
+			require.NoErrorf(t, err, "could not cleanup test ClusterAudience %s/%s", clusterAudience.Namespace, clusterAudience.Name)
+		}
+	})
+
+	// If we're not expecting any particular status, just return the new ClusterAudience immediately.
+	if expectStatus == "" {
+		return clusterAudience
+	}
+
+	// Wait for the ClusterAudience to enter the expected phase (or time out).
+	RequireEventuallyf(t, func(requireEventually *require.Assertions) {
+		result, err := clusterAudiences.Get(ctx, clusterAudience.Name, metav1.GetOptions{})
+		requireEventually.NoError(err)
+		requireEventually.Equal(expectStatus, result.Status.Status)
+	}, 60*time.Second, 1*time.Second, "expected the ClusterAudience to have status %q", expectStatus)
+	return clusterAudience
+}
+
 func RandBytes(t *testing.T, numBytes int) []byte {
 	buf := make([]byte, numBytes)
 	_, err := io.ReadFull(rand.Reader, buf)